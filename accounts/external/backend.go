@@ -0,0 +1,271 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package external implements an account backend delegating all signing to an
+// external signer (such as clef) reachable over IPC or HTTP. Keys never enter
+// the node process; every request is subject to the approval of the signer's UI.
+package external
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/vntchain/go-vnt"
+	"github.com/vntchain/go-vnt/accounts"
+	"github.com/vntchain/go-vnt/accounts/typeddata"
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/common/hexutil"
+	"github.com/vntchain/go-vnt/core/types"
+	"github.com/vntchain/go-vnt/event"
+	"github.com/vntchain/go-vnt/log"
+	"github.com/vntchain/go-vnt/rlp"
+	"github.com/vntchain/go-vnt/rpc"
+)
+
+// ExternalBackend is an account backend wrapping a single external signer.
+type ExternalBackend struct {
+	signers []accounts.Wallet
+}
+
+// NewExternalBackend connects to the external signer at the given endpoint (an
+// IPC path or an HTTP URL) and wraps it into an account backend.
+func NewExternalBackend(endpoint string) (*ExternalBackend, error) {
+	signer, err := NewExternalSigner(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &ExternalBackend{
+		signers: []accounts.Wallet{signer},
+	}, nil
+}
+
+// Wallets implements accounts.Backend, returning the external signer.
+func (eb *ExternalBackend) Wallets() []accounts.Wallet {
+	return eb.signers
+}
+
+// Subscribe implements accounts.Backend. External signers never arrive or depart,
+// so the subscription only waits to be torn down.
+func (eb *ExternalBackend) Subscribe(sink chan<- accounts.WalletEvent) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
+// ExternalSigner is a wallet proxying all requests to an external signer. Since
+// the signer manages its own keys and prompts its own user for approval, none of
+// the passphrase based or key derivation operations are supported.
+type ExternalSigner struct {
+	client   *rpc.Client
+	endpoint string
+	status   string
+
+	cache   []accounts.Account // Accounts listed by the signer at the last query
+	cacheMu sync.RWMutex
+}
+
+// NewExternalSigner connects to the external signer at the given endpoint and
+// verifies it is reachable.
+func NewExternalSigner(endpoint string) (*ExternalSigner, error) {
+	client, err := rpc.Dial(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return newExternalSigner(client, endpoint)
+}
+
+// newExternalSigner wraps an already established signer connection.
+func newExternalSigner(client *rpc.Client, endpoint string) (*ExternalSigner, error) {
+	signer := &ExternalSigner{
+		client:   client,
+		endpoint: endpoint,
+	}
+	version, err := signer.pingVersion()
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	signer.status = fmt.Sprintf("ok [version=%v]", version)
+	return signer, nil
+}
+
+// URL implements accounts.Wallet, returning the endpoint of the signer.
+func (api *ExternalSigner) URL() accounts.URL {
+	return accounts.URL{Scheme: "extapi", Path: api.endpoint}
+}
+
+// Status implements accounts.Wallet, returning the version reported by the signer.
+func (api *ExternalSigner) Status() (string, error) {
+	return api.status, nil
+}
+
+// Open implements accounts.Wallet, but is a noop for external signers since the
+// connection is established upon construction.
+func (api *ExternalSigner) Open(passphrase string) error {
+	return fmt.Errorf("operation not supported on external signers")
+}
+
+// Close implements accounts.Wallet, but is a noop for external signers since the
+// connection lives as long as the node.
+func (api *ExternalSigner) Close() error {
+	return fmt.Errorf("operation not supported on external signers")
+}
+
+// Accounts implements accounts.Wallet, returning the accounts the signer's user
+// approved to be listed.
+func (api *ExternalSigner) Accounts() []accounts.Account {
+	res, err := api.listAccounts()
+	if err != nil {
+		log.Error("Account listing failed", "err", err)
+		return nil
+	}
+	accnts := make([]accounts.Account, 0, len(res))
+	for _, addr := range res {
+		accnts = append(accnts, accounts.Account{
+			Address: addr,
+			URL:     api.URL(),
+		})
+	}
+	api.cacheMu.Lock()
+	api.cache = accnts
+	api.cacheMu.Unlock()
+
+	return accnts
+}
+
+// Contains implements accounts.Wallet, returning whether a particular account is
+// among the ones last listed by the signer.
+func (api *ExternalSigner) Contains(account accounts.Account) bool {
+	api.cacheMu.RLock()
+	defer api.cacheMu.RUnlock()
+
+	for _, a := range api.cache {
+		if a.Address == account.Address && (account.URL == (accounts.URL{}) || account.URL == api.URL()) {
+			return true
+		}
+	}
+	return false
+}
+
+// Derive implements accounts.Wallet, but is not supported by external signers.
+func (api *ExternalSigner) Derive(path accounts.DerivationPath, pin bool) (accounts.Account, error) {
+	return accounts.Account{}, fmt.Errorf("operation not supported on external signers")
+}
+
+// SelfDerive implements accounts.Wallet, but is a noop for external signers.
+func (api *ExternalSigner) SelfDerive(base accounts.DerivationPath, chain hubble.ChainStateReader) {
+	log.Error("Operation not supported on external signers")
+}
+
+// SignHash implements accounts.Wallet, but is not supported by external signers:
+// they only sign data they can display to their user, never blind hashes.
+func (api *ExternalSigner) SignHash(account accounts.Account, hash []byte) ([]byte, error) {
+	return nil, fmt.Errorf("operation not supported on external signers")
+}
+
+// SignTx implements accounts.Wallet, sending the transaction to the external
+// signer for approval and signing.
+func (api *ExternalSigner) SignTx(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	args := &sendTxArgs{
+		From:     common.NewMixedcaseAddress(account.Address),
+		Gas:      hexutil.Uint64(tx.Gas()),
+		GasPrice: hexutil.Big(*tx.GasPrice()),
+		Value:    hexutil.Big(*tx.Value()),
+		Nonce:    hexutil.Uint64(tx.Nonce()),
+		Data:     hexutil.Bytes(tx.Data()),
+	}
+	if to := tx.To(); to != nil {
+		recipient := common.NewMixedcaseAddress(*to)
+		args.To = &recipient
+	}
+	var res signTransactionResult
+	if err := api.client.Call(&res, "account_signTransaction", args); err != nil {
+		return nil, err
+	}
+	signed := new(types.Transaction)
+	if err := rlp.DecodeBytes(res.Raw, signed); err != nil {
+		return nil, err
+	}
+	// The signer signs for its own configured chain, make sure it's ours
+	if chainID != nil && signed.ChainId().Cmp(chainID) != 0 {
+		return nil, fmt.Errorf("external signer chain id mismatch: have %v, want %v", signed.ChainId(), chainID)
+	}
+	return signed, nil
+}
+
+// SignTypedData sends typed structured data to the external signer, which shows
+// it to its user for approval before signing its hash.
+func (api *ExternalSigner) SignTypedData(account accounts.Account, data *typeddata.TypedData) ([]byte, error) {
+	var res hexutil.Bytes
+	if err := api.client.Call(&res, "account_signTypedData", common.NewMixedcaseAddress(account.Address), data); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// SignHashWithPassphrase implements accounts.Wallet, but is not supported by
+// external signers.
+func (api *ExternalSigner) SignHashWithPassphrase(account accounts.Account, passphrase string, hash []byte) ([]byte, error) {
+	return nil, fmt.Errorf("password-operations not supported on external signers")
+}
+
+// SignTxWithPassphrase implements accounts.Wallet, but is not supported by
+// external signers.
+func (api *ExternalSigner) SignTxWithPassphrase(account accounts.Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return nil, fmt.Errorf("password-operations not supported on external signers")
+}
+
+// listAccounts retrieves the addresses of the accounts listed by the signer.
+func (api *ExternalSigner) listAccounts() ([]common.Address, error) {
+	var res []struct {
+		Address common.Address `json:"address"`
+	}
+	if err := api.client.Call(&res, "account_list"); err != nil {
+		return nil, err
+	}
+	addrs := make([]common.Address, len(res))
+	for i, acc := range res {
+		addrs[i] = acc.Address
+	}
+	return addrs, nil
+}
+
+// pingVersion retrieves the version of the signer's external API.
+func (api *ExternalSigner) pingVersion() (string, error) {
+	var v string
+	if err := api.client.Call(&v, "account_version"); err != nil {
+		return "", err
+	}
+	return v, nil
+}
+
+// sendTxArgs is the transaction signing request understood by the signer.
+type sendTxArgs struct {
+	From     common.MixedcaseAddress  `json:"from"`
+	To       *common.MixedcaseAddress `json:"to"`
+	Gas      hexutil.Uint64           `json:"gas"`
+	GasPrice hexutil.Big              `json:"gasPrice"`
+	Value    hexutil.Big              `json:"value"`
+	Nonce    hexutil.Uint64           `json:"nonce"`
+	Data     hexutil.Bytes            `json:"data"`
+}
+
+// signTransactionResult is the signed transaction returned by the signer.
+type signTransactionResult struct {
+	Raw hexutil.Bytes `json:"raw"`
+}
@@ -0,0 +1,122 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package external
+
+import (
+	"context"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/vntchain/go-vnt/accounts"
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/core/types"
+	"github.com/vntchain/go-vnt/internal/vntapi"
+	"github.com/vntchain/go-vnt/rpc"
+	"github.com/vntchain/go-vnt/signer/core"
+)
+
+// approvingUI is a signer UI approving every request with a fixed password.
+type approvingUI struct {
+	password string
+}
+
+func (ui *approvingUI) ApproveTx(request *core.SignTxRequest) (core.SignTxResponse, error) {
+	return core.SignTxResponse{Transaction: request.Transaction, Approved: true, Password: ui.password}, nil
+}
+func (ui *approvingUI) ApproveSignData(request *core.SignDataRequest) (core.SignDataResponse, error) {
+	return core.SignDataResponse{Approved: true, Password: ui.password}, nil
+}
+func (ui *approvingUI) ApproveExport(request *core.ExportRequest) (core.ExportResponse, error) {
+	return core.ExportResponse{Approved: false}, nil
+}
+func (ui *approvingUI) ApproveImport(request *core.ImportRequest) (core.ImportResponse, error) {
+	return core.ImportResponse{Approved: false}, nil
+}
+func (ui *approvingUI) ApproveListing(request *core.ListRequest) (core.ListResponse, error) {
+	return core.ListResponse{Accounts: request.Accounts}, nil
+}
+func (ui *approvingUI) ApproveNewAccount(request *core.NewAccountRequest) (core.NewAccountResponse, error) {
+	return core.NewAccountResponse{Approved: true, Password: ui.password}, nil
+}
+func (ui *approvingUI) ShowError(message string)                     {}
+func (ui *approvingUI) ShowInfo(message string)                      {}
+func (ui *approvingUI) OnApprovedTx(tx vntapi.SignTransactionResult) {}
+func (ui *approvingUI) OnSignerStartup(info core.StartupInfo)        {}
+
+// Tests that the external signer lists the accounts of the remote signer and
+// delegates transaction signing to it.
+func TestExternalSigner(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vnt-external-signer-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Start an in-process signer with a single account
+	db, err := core.NewEmptyAbiDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := core.NewSignerAPI(2, dir, &approvingUI{password: "pass"}, db, true)
+	acc, err := api.New(context.Background())
+	if err != nil {
+		t.Fatalf("failed to create signer account: %v", err)
+	}
+	server := rpc.NewServer()
+	if err := server.RegisterName("account", api); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+
+	signer, err := newExternalSigner(rpc.DialInProc(server), "inproc")
+	if err != nil {
+		t.Fatalf("failed to connect to signer: %v", err)
+	}
+	if status, _ := signer.Status(); status != "ok [version="+core.ExternalAPIVersion+"]" {
+		t.Errorf("status mismatch: have %q", status)
+	}
+	// Ensure the signer's account is listed and tracked
+	accs := signer.Accounts()
+	if len(accs) != 1 || accs[0].Address != acc.Address {
+		t.Fatalf("accounts mismatch: have %v, want [%x]", accs, acc.Address)
+	}
+	if !signer.Contains(accounts.Account{Address: acc.Address}) {
+		t.Errorf("listed account not contained")
+	}
+	// Sign a transaction and ensure it's signed by the signer's account
+	tx := types.NewTransaction(1, common.HexToAddress("0x01"), big.NewInt(10), 21000, big.NewInt(1), nil)
+	signed, err := signer.SignTx(accs[0], tx, big.NewInt(2))
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	from, err := types.Sender(types.NewHubbleSigner(big.NewInt(2)), signed)
+	if err != nil {
+		t.Fatalf("failed to recover sender: %v", err)
+	}
+	if from != acc.Address {
+		t.Errorf("sender mismatch: have %x, want %x", from, acc.Address)
+	}
+	if signed.Nonce() != tx.Nonce() || signed.Value().Cmp(tx.Value()) != 0 || *signed.To() != *tx.To() {
+		t.Errorf("signed transaction mismatch: have %v, want %v", signed, tx)
+	}
+	// Signing for a different chain must be refused
+	if _, err := signer.SignTx(accs[0], tx, big.NewInt(3)); err == nil {
+		t.Errorf("chain id mismatch not detected")
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package hdwallet
+
+import (
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha512"
+	"errors"
+	"math/big"
+
+	"github.com/vntchain/go-vnt/accounts"
+	"github.com/vntchain/go-vnt/common/math"
+	"github.com/vntchain/go-vnt/crypto"
+)
+
+// hardenedOffset is the first index of the hardened child keys.
+const hardenedOffset = 0x80000000
+
+// masterSecret is the HMAC key deriving the master key from a seed, as defined
+// by BIP-32.
+var masterSecret = []byte("Bitcoin seed")
+
+// errInvalidKey is returned if a derivation step yields an unusable key, which
+// happens with a probability below 1 in 2^127.
+var errInvalidKey = errors.New("derived key is invalid, try the next index")
+
+// extendedKey is a private key along with the chain code deriving its children.
+type extendedKey struct {
+	key   []byte
+	chain []byte
+}
+
+// newMasterKey derives the root extended key of a seed.
+func newMasterKey(seed []byte) (*extendedKey, error) {
+	mac := hmac.New(sha512.New, masterSecret)
+	mac.Write(seed)
+	sum := mac.Sum(nil)
+
+	if !validKey(new(big.Int).SetBytes(sum[:32])) {
+		return nil, errInvalidKey
+	}
+	return &extendedKey{key: sum[:32], chain: sum[32:]}, nil
+}
+
+// child derives the extended private key of the child at the given index.
+func (k *extendedKey) child(index uint32) (*extendedKey, error) {
+	data := make([]byte, 0, 37)
+	if index >= hardenedOffset {
+		data = append(append(data, 0x00), k.key...)
+	} else {
+		x, y := crypto.S256().ScalarBaseMult(k.key)
+		data = append(data, crypto.CompressPubkey(&ecdsa.PublicKey{Curve: crypto.S256(), X: x, Y: y})...)
+	}
+	data = append(data, byte(index>>24), byte(index>>16), byte(index>>8), byte(index))
+
+	mac := hmac.New(sha512.New, k.chain)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+
+	tweak := new(big.Int).SetBytes(sum[:32])
+	if tweak.Cmp(crypto.S256().Params().N) >= 0 {
+		return nil, errInvalidKey
+	}
+	key := tweak.Add(tweak, new(big.Int).SetBytes(k.key))
+	key.Mod(key, crypto.S256().Params().N)
+	if !validKey(key) {
+		return nil, errInvalidKey
+	}
+	return &extendedKey{key: math.PaddedBigBytes(key, 32), chain: sum[32:]}, nil
+}
+
+// validKey checks whether a number is usable as a secp256k1 private key.
+func validKey(key *big.Int) bool {
+	return key.Sign() > 0 && key.Cmp(crypto.S256().Params().N) < 0
+}
+
+// DeriveKey derives the private key at the given path from a seed.
+func DeriveKey(seed []byte, path accounts.DerivationPath) (*ecdsa.PrivateKey, error) {
+	key, err := newMasterKey(seed)
+	if err != nil {
+		return nil, err
+	}
+	for _, index := range path {
+		if key, err = key.child(index); err != nil {
+			return nil, err
+		}
+	}
+	return crypto.ToECDSA(key.key)
+}
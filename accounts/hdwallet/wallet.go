@@ -0,0 +1,254 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package hdwallet implements a software hierarchical deterministic wallet,
+// restoring the accounts of a BIP-39 mnemonic along standard BIP-32 derivation
+// paths, as used by most external wallets.
+package hdwallet
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/tyler-smith/go-bip39"
+	hubble "github.com/vntchain/go-vnt"
+	"github.com/vntchain/go-vnt/accounts"
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/core/types"
+	"github.com/vntchain/go-vnt/crypto"
+	"github.com/vntchain/go-vnt/log"
+)
+
+// Scheme is the protocol scheme prefixing account and wallet URLs.
+const Scheme = "hd"
+
+// maxDiscoveryGap is the number of derived accounts self-derivation inspects
+// at most in a single run.
+const maxDiscoveryGap = 1024
+
+var (
+	// ErrInvalidMnemonic is returned if a mnemonic has unknown words or a wrong
+	// checksum.
+	ErrInvalidMnemonic = errors.New("invalid mnemonic")
+
+	// ErrUnknownAccount is returned for accounts not derived by the wallet.
+	ErrUnknownAccount = errors.New("unknown account")
+)
+
+// Wallet is a software HD wallet deriving its accounts from a BIP-39 seed, which
+// is held in memory for its whole life.
+type Wallet struct {
+	url  accounts.URL // Textual URL uniquely identifying this wallet
+	seed []byte       // Seed the accounts are derived from
+
+	accounts []accounts.Account                         // List of derived accounts pinned on the wallet
+	paths    map[common.Address]accounts.DerivationPath // Known derivation paths for signing operations
+	keys     map[common.Address]*ecdsa.PrivateKey       // Keys of the pinned accounts
+
+	stateLock sync.RWMutex // Protects read and write access to the wallet struct fields
+}
+
+// NewFromMnemonic creates a wallet from a BIP-39 mnemonic, protected by the
+// given optional passphrase.
+func NewFromMnemonic(mnemonic string, passphrase string) (*Wallet, error) {
+	mnemonic = strings.Join(strings.Fields(mnemonic), " ")
+	if !bip39.IsMnemonicValid(mnemonic) {
+		return nil, ErrInvalidMnemonic
+	}
+	return NewFromSeed(bip39.NewSeed(mnemonic, passphrase))
+}
+
+// NewFromSeed creates a wallet from a BIP-32 seed.
+func NewFromSeed(seed []byte) (*Wallet, error) {
+	if _, err := newMasterKey(seed); err != nil {
+		return nil, err
+	}
+	return &Wallet{
+		url:   accounts.URL{Scheme: Scheme, Path: fmt.Sprintf("%x", crypto.Keccak256(seed)[:4])},
+		seed:  seed,
+		paths: make(map[common.Address]accounts.DerivationPath),
+		keys:  make(map[common.Address]*ecdsa.PrivateKey),
+	}, nil
+}
+
+// URL implements accounts.Wallet, returning the URL of the wallet, unique to
+// its seed.
+func (w *Wallet) URL() accounts.URL {
+	return w.url
+}
+
+// Status implements accounts.Wallet, always reporting the wallet ready as the
+// seed is held in memory.
+func (w *Wallet) Status() (string, error) {
+	return "Online", nil
+}
+
+// Open implements accounts.Wallet, but is a noop as the seed needs no opening.
+func (w *Wallet) Open(passphrase string) error { return nil }
+
+// Close implements accounts.Wallet, but is a noop as the seed needs no opening.
+func (w *Wallet) Close() error { return nil }
+
+// Accounts implements accounts.Wallet, returning the list of accounts pinned on
+// the wallet.
+func (w *Wallet) Accounts() []accounts.Account {
+	w.stateLock.RLock()
+	defer w.stateLock.RUnlock()
+
+	cpy := make([]accounts.Account, len(w.accounts))
+	copy(cpy, w.accounts)
+	return cpy
+}
+
+// Contains implements accounts.Wallet, returning whether a particular account is
+// or is not pinned into this wallet instance.
+func (w *Wallet) Contains(account accounts.Account) bool {
+	w.stateLock.RLock()
+	defer w.stateLock.RUnlock()
+
+	_, exists := w.paths[account.Address]
+	return exists
+}
+
+// Derive implements accounts.Wallet, deriving a new account at the specific
+// derivation path. If pin is set to true, the account will be added to the list
+// of tracked accounts.
+func (w *Wallet) Derive(path accounts.DerivationPath, pin bool) (accounts.Account, error) {
+	key, err := DeriveKey(w.seed, path)
+	if err != nil {
+		return accounts.Account{}, err
+	}
+	account := w.account(crypto.PubkeyToAddress(key.PublicKey), path)
+	if pin {
+		w.pin(account, path, key)
+	}
+	return account, nil
+}
+
+// SelfDerive implements accounts.Wallet, discovering in the background the
+// accounts derived from the base path which have been used on chain, along with
+// the next unused one, and pinning them.
+func (w *Wallet) SelfDerive(base accounts.DerivationPath, chain hubble.ChainStateReader) {
+	if chain == nil {
+		return
+	}
+	go func() {
+		if _, err := w.discover(context.Background(), base, chain); err != nil {
+			log.Warn("HD wallet account discovery failed", "url", w.url, "err", err)
+		}
+	}()
+}
+
+// discover pins the accounts derived from the base path which have a balance or
+// a nonce, stopping at the first empty one, which is pinned too and returned.
+func (w *Wallet) discover(ctx context.Context, base accounts.DerivationPath, chain hubble.ChainStateReader) (accounts.Account, error) {
+	path := make(accounts.DerivationPath, len(base))
+	copy(path, base)
+
+	for i := 0; i < maxDiscoveryGap; i++ {
+		account, err := w.Derive(path, true)
+		if err != nil {
+			return accounts.Account{}, err
+		}
+		balance, err := chain.BalanceAt(ctx, account.Address, nil)
+		if err != nil {
+			return accounts.Account{}, err
+		}
+		nonce, err := chain.NonceAt(ctx, account.Address, nil)
+		if err != nil {
+			return accounts.Account{}, err
+		}
+		if balance.Sign() == 0 && nonce == 0 {
+			return account, nil
+		}
+		log.Debug("HD wallet discovered used account", "address", account.Address, "path", path, "balance", balance, "nonce", nonce)
+		path[len(path)-1]++
+	}
+	return accounts.Account{}, fmt.Errorf("no unused account within %d of %s", maxDiscoveryGap, base)
+}
+
+// PrivateKey returns the key of an account pinned on the wallet, so that it can
+// be imported into other backends.
+func (w *Wallet) PrivateKey(account accounts.Account) (*ecdsa.PrivateKey, error) {
+	w.stateLock.RLock()
+	defer w.stateLock.RUnlock()
+
+	key, ok := w.keys[account.Address]
+	if !ok {
+		return nil, ErrUnknownAccount
+	}
+	return key, nil
+}
+
+// SignHash implements accounts.Wallet, signing the hash with the key of a pinned
+// account.
+func (w *Wallet) SignHash(account accounts.Account, hash []byte) ([]byte, error) {
+	key, err := w.PrivateKey(account)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.Sign(hash, key)
+}
+
+// SignTx implements accounts.Wallet, signing the transaction with the key of a
+// pinned account.
+func (w *Wallet) SignTx(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	key, err := w.PrivateKey(account)
+	if err != nil {
+		return nil, err
+	}
+	return types.SignTx(tx, types.NewHubbleSigner(chainID), key)
+}
+
+// SignHashWithPassphrase implements accounts.Wallet, attempting to sign the given
+// hash with the given account. The passphrase is ignored as the seed is held in
+// memory already.
+func (w *Wallet) SignHashWithPassphrase(account accounts.Account, passphrase string, hash []byte) ([]byte, error) {
+	return w.SignHash(account, hash)
+}
+
+// SignTxWithPassphrase implements accounts.Wallet, attempting to sign the given
+// transaction with the given account. The passphrase is ignored as the seed is
+// held in memory already.
+func (w *Wallet) SignTxWithPassphrase(account accounts.Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return w.SignTx(account, tx, chainID)
+}
+
+// account assembles the account of an address derived at the given path.
+func (w *Wallet) account(address common.Address, path accounts.DerivationPath) accounts.Account {
+	return accounts.Account{
+		Address: address,
+		URL:     accounts.URL{Scheme: w.url.Scheme, Path: fmt.Sprintf("%s/%s", w.url.Path, path)},
+	}
+}
+
+// pin adds a derived account to the tracked ones, unless already known.
+func (w *Wallet) pin(account accounts.Account, path accounts.DerivationPath, key *ecdsa.PrivateKey) {
+	w.stateLock.Lock()
+	defer w.stateLock.Unlock()
+
+	if _, ok := w.paths[account.Address]; ok {
+		return
+	}
+	w.accounts = append(w.accounts, account)
+	w.paths[account.Address] = append(accounts.DerivationPath{}, path...)
+	w.keys[account.Address] = key
+}
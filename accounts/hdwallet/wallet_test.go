@@ -0,0 +1,151 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package hdwallet
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/vntchain/go-vnt/accounts"
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/common/hexutil"
+	"github.com/vntchain/go-vnt/crypto"
+)
+
+// testMnemonic is a well known mnemonic, whose accounts are listed by most
+// development tools.
+const testMnemonic = "test test test test test test test test test test test junk"
+
+// Tests that keys are derived as per the BIP-32 test vector 1.
+func TestDeriveKey(t *testing.T) {
+	seed := hexutil.MustDecode("0x000102030405060708090a0b0c0d0e0f")
+	tests := []struct {
+		path accounts.DerivationPath
+		key  string
+	}{
+		{accounts.DerivationPath{}, "0xe8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35"},
+		{accounts.DerivationPath{0x80000000}, "0xedb2e14f9ee77d26dd93b4ecede8d16ed408ce149b6cd80b0715a2d911a0afea"},
+		{accounts.DerivationPath{0x80000000, 1}, "0x3c6cb8d0f6a264c91ea8b5030fadaa8e538b020f0a387421a12de9319dc93368"},
+		{accounts.DerivationPath{0x80000000, 1, 0x80000002, 2, 1000000000}, "0x471b76e389e528d6de6d816857e012c5455051cad6660850e58372a6c3e6e7c8"},
+	}
+	for i, tt := range tests {
+		key, err := DeriveKey(seed, tt.path)
+		if err != nil {
+			t.Errorf("test %d: failed to derive key: %v", i, err)
+			continue
+		}
+		if have := hexutil.Encode(crypto.FromECDSA(key)); have != tt.key {
+			t.Errorf("test %d: key mismatch: have %s, want %s", i, have, tt.key)
+		}
+	}
+}
+
+// Tests that accounts are restored from mnemonics as by external wallets.
+func TestMnemonicAccounts(t *testing.T) {
+	if _, err := NewFromMnemonic("test test test", ""); err != ErrInvalidMnemonic {
+		t.Errorf("invalid mnemonic error mismatch: have %v, want %v", err, ErrInvalidMnemonic)
+	}
+	wallet, err := NewFromMnemonic("  "+testMnemonic+"\n", "")
+	if err != nil {
+		t.Fatalf("failed to restore wallet: %v", err)
+	}
+	want := []common.Address{
+		common.HexToAddress("0xf39Fd6e51aad88F6F4ce6aB8827279cffFb92266"),
+		common.HexToAddress("0x70997970C51812dc3A010C7d01b50e0d17dc79C8"),
+	}
+	for i, address := range want {
+		path := append(accounts.DerivationPath{}, accounts.DefaultBaseDerivationPath...)
+		path[len(path)-1] = uint32(i)
+
+		account, err := wallet.Derive(path, i == 0)
+		if err != nil {
+			t.Fatalf("account %d: failed to derive: %v", i, err)
+		}
+		if account.Address != address {
+			t.Errorf("account %d: address mismatch: have %x, want %x", i, account.Address, address)
+		}
+	}
+	if accs := wallet.Accounts(); len(accs) != 1 || accs[0].Address != want[0] {
+		t.Errorf("pinned accounts mismatch: have %v, want %x", accs, want[:1])
+	}
+	// Only pinned accounts are signed for
+	hash := crypto.Keccak256([]byte("hash"))
+	if _, err := wallet.SignHash(accounts.Account{Address: want[1]}, hash); err != ErrUnknownAccount {
+		t.Errorf("unpinned signing error mismatch: have %v, want %v", err, ErrUnknownAccount)
+	}
+	sig, err := wallet.SignHash(accounts.Account{Address: want[0]}, hash)
+	if err != nil {
+		t.Fatalf("failed to sign: %v", err)
+	}
+	pubkey, err := crypto.SigToPub(hash, sig)
+	if err != nil || crypto.PubkeyToAddress(*pubkey) != want[0] {
+		t.Errorf("signer mismatch: have %v, want %x", err, want[0])
+	}
+}
+
+// testChainReader is a chain state reader serving the nonces of a few accounts.
+type testChainReader struct {
+	nonces map[common.Address]uint64
+}
+
+func (r *testChainReader) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	return new(big.Int), nil
+}
+
+func (r *testChainReader) StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error) {
+	return nil, nil
+}
+
+func (r *testChainReader) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	return nil, nil
+}
+
+func (r *testChainReader) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	return r.nonces[account], nil
+}
+
+// Tests that self-derivation pins the used accounts and the next unused one.
+func TestDiscover(t *testing.T) {
+	wallet, _ := NewFromMnemonic(testMnemonic, "")
+
+	var used []common.Address
+	for i := 0; i < 3; i++ {
+		path := append(accounts.DerivationPath{}, accounts.DefaultBaseDerivationPath...)
+		path[len(path)-1] = uint32(i)
+		account, _ := wallet.Derive(path, false)
+		used = append(used, account.Address)
+	}
+	chain := &testChainReader{nonces: map[common.Address]uint64{used[0]: 1, used[1]: 5}}
+
+	next, err := wallet.discover(context.Background(), accounts.DefaultBaseDerivationPath, chain)
+	if err != nil {
+		t.Fatalf("failed to discover accounts: %v", err)
+	}
+	if next.Address != used[2] {
+		t.Errorf("next account mismatch: have %x, want %x", next.Address, used[2])
+	}
+	accs := wallet.Accounts()
+	if len(accs) != len(used) {
+		t.Fatalf("pinned account count mismatch: have %d, want %d", len(accs), len(used))
+	}
+	for i, account := range accs {
+		if account.Address != used[i] {
+			t.Errorf("account %d: address mismatch: have %x, want %x", i, account.Address, used[i])
+		}
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/vntchain/go-vnt/accounts"
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/common/hexutil"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	archiveVersion   = 1
+	archiveCipher    = "aes-256-gcm"
+	archiveMaxScrypt = 1 << 20 // Maximum scrypt N accepted from an archive, bounding the work of a decryption
+)
+
+// ErrArchiveCorrupted is returned if a key archive decrypts, but its contents
+// don't match the integrity checks.
+var ErrArchiveCorrupted = errors.New("key archive corrupted")
+
+// keyArchiveJSON is the password protected container of a key archive.
+type keyArchiveJSON struct {
+	Version    int           `json:"version"`
+	Cipher     string        `json:"cipher"`
+	KDF        string        `json:"kdf"`
+	N          int           `json:"n"`
+	R          int           `json:"r"`
+	P          int           `json:"p"`
+	Salt       hexutil.Bytes `json:"salt"`
+	Nonce      hexutil.Bytes `json:"nonce"`
+	Ciphertext hexutil.Bytes `json:"ciphertext"`
+}
+
+// archivedKey is a key file held in a key archive, stored as is and thus still
+// encrypted with the password of its account.
+type archivedKey struct {
+	Address common.Address  `json:"address"`
+	Digest  common.Hash     `json:"sha256"` // SHA-256 of the key file, checked on import
+	Key     json.RawMessage `json:"key"`
+}
+
+// ExportArchive bundles the key files of the given accounts into an archive
+// encrypted with password. The key files are archived as they are on disk, so
+// restoring an account still needs its own password.
+func (ks *KeyStore) ExportArchive(accs []accounts.Account, password string) ([]byte, error) {
+	keys := make([]archivedKey, 0, len(accs))
+	for _, a := range accs {
+		found, err := ks.Find(a)
+		if err != nil {
+			return nil, fmt.Errorf("account %x: %v", a.Address, err)
+		}
+		blob, err := ioutil.ReadFile(found.URL.Path)
+		if err != nil {
+			return nil, fmt.Errorf("account %x: %v", a.Address, err)
+		}
+		if addr, err := keyFileAddress(blob); err != nil || addr != found.Address {
+			return nil, fmt.Errorf("account %x: invalid key file %s", a.Address, found.URL.Path)
+		}
+		keys = append(keys, archivedKey{Address: found.Address, Digest: sha256.Sum256(blob), Key: blob})
+	}
+	plaintext, err := json.Marshal(keys)
+	if err != nil {
+		return nil, err
+	}
+	archive := keyArchiveJSON{
+		Version: archiveVersion,
+		Cipher:  archiveCipher,
+		KDF:     keyHeaderKDF,
+		N:       StandardScryptN,
+		R:       scryptR,
+		P:       StandardScryptP,
+		Salt:    make([]byte, 32),
+		Nonce:   make([]byte, 12),
+	}
+	if store, ok := ks.storage.(*keyStorePassphrase); ok {
+		archive.N, archive.P = store.scryptN, store.scryptP
+	}
+	if _, err := crand.Read(archive.Salt); err != nil {
+		return nil, err
+	}
+	if _, err := crand.Read(archive.Nonce); err != nil {
+		return nil, err
+	}
+	aead, err := archive.aead(password)
+	if err != nil {
+		return nil, err
+	}
+	archive.Ciphertext = aead.Seal(nil, archive.Nonce, plaintext, nil)
+
+	for _, key := range keys {
+		ks.notifyKey(key.Address, KeyExported, "")
+	}
+	return json.MarshalIndent(&archive, "", "  ")
+}
+
+// ImportArchive restores the key files of an archive created by ExportArchive
+// into the key directory, returning the accounts imported. Accounts already in
+// the keystore are skipped and returned separately. Nothing is imported unless
+// the whole archive passes its integrity checks.
+func (ks *KeyStore) ImportArchive(blob []byte, password string) (imported []accounts.Account, skipped []common.Address, err error) {
+	var archive keyArchiveJSON
+	if err := json.Unmarshal(blob, &archive); err != nil {
+		return nil, nil, fmt.Errorf("invalid key archive: %v", err)
+	}
+	if archive.Version != archiveVersion {
+		return nil, nil, fmt.Errorf("unsupported key archive version %d", archive.Version)
+	}
+	if archive.Cipher != archiveCipher || archive.KDF != keyHeaderKDF {
+		return nil, nil, fmt.Errorf("unsupported key archive cipher %s/%s", archive.Cipher, archive.KDF)
+	}
+	if archive.N <= 1 || archive.N > archiveMaxScrypt || archive.R != scryptR || archive.P <= 0 || archive.P > 16 {
+		return nil, nil, fmt.Errorf("unsupported key archive scrypt parameters N=%d r=%d p=%d", archive.N, archive.R, archive.P)
+	}
+	aead, err := archive.aead(password)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(archive.Nonce) != aead.NonceSize() {
+		return nil, nil, ErrArchiveCorrupted
+	}
+	plaintext, err := aead.Open(nil, archive.Nonce, archive.Ciphertext, nil)
+	if err != nil {
+		return nil, nil, ErrDecrypt
+	}
+	var keys []archivedKey
+	if err := json.Unmarshal(plaintext, &keys); err != nil {
+		return nil, nil, ErrArchiveCorrupted
+	}
+	for _, key := range keys {
+		if sha256.Sum256(key.Key) != key.Digest {
+			return nil, nil, ErrArchiveCorrupted
+		}
+		if addr, err := keyFileAddress(key.Key); err != nil || addr != key.Address {
+			return nil, nil, ErrArchiveCorrupted
+		}
+	}
+	for _, key := range keys {
+		if ks.cache.hasAddress(key.Address) {
+			skipped = append(skipped, key.Address)
+			continue
+		}
+		a := accounts.Account{Address: key.Address, URL: accounts.URL{Scheme: KeyStoreScheme, Path: ks.storage.JoinPath(keyFileName(key.Address))}}
+		if err := writeKeyFile(a.URL.Path, key.Key); err != nil {
+			return imported, skipped, err
+		}
+		ks.cache.add(a)
+		ks.notifyKey(a.Address, KeyImported, "")
+		imported = append(imported, a)
+	}
+	ks.refreshWallets()
+	return imported, skipped, nil
+}
+
+// aead derives the key of the archive from the password and returns the cipher
+// sealing its contents.
+func (archive *keyArchiveJSON) aead(password string) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(password), archive.Salt, archive.N, archive.R, archive.P, scryptDKLen)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// keyFileAddress returns the address a JSON key file claims to hold the key of.
+func keyFileAddress(blob []byte) (common.Address, error) {
+	var key struct {
+		Address string `json:"address"`
+	}
+	if err := json.Unmarshal(blob, &key); err != nil {
+		return common.Address{}, err
+	}
+	addr, err := hex.DecodeString(strings.TrimPrefix(key.Address, "0x"))
+	if err != nil || len(addr) != common.AddressLength {
+		return common.Address{}, fmt.Errorf("invalid key address %q", key.Address)
+	}
+	return common.BytesToAddress(addr), nil
+}
@@ -30,6 +30,7 @@ import (
 	"path/filepath"
 	"reflect"
 	"runtime"
+	"strings"
 	"sync"
 	"time"
 
@@ -38,6 +39,7 @@ import (
 	"github.com/vntchain/go-vnt/core/types"
 	"github.com/vntchain/go-vnt/crypto"
 	"github.com/vntchain/go-vnt/event"
+	"github.com/vntchain/go-vnt/log"
 )
 
 var (
@@ -55,6 +57,9 @@ var KeyStoreScheme = "keystore"
 // Maximum time between wallet refreshes (if filesystem notifications don't work).
 const walletRefreshCycle = 3 * time.Second
 
+// Maximum number of key events queued for the subscribers before dropping them.
+const keyEventQueue = 1024
+
 // KeyStore manages a key storage directory on disk.
 type KeyStore struct {
 	storage  keyStore                     // Storage backend, might be cleartext or encrypted
@@ -67,9 +72,36 @@ type KeyStore struct {
 	updateScope event.SubscriptionScope // Subscription scope tracking current live listeners
 	updating    bool                    // Whether the event notification loop is running
 
+	keyFeed   event.Feed              // Event feed to notify key unlocks, locks and signings
+	keyScope  event.SubscriptionScope // Subscription scope tracking current key event listeners
+	keyEvents chan KeyEvent           // Key events queued for delivery, so signing never waits on listeners
+	keyOnce   sync.Once               // Starts the key event delivery on the first subscription
+
+	labelLock sync.Mutex // Serializes updates of the account label index
+
 	mu sync.RWMutex
 }
 
+// KeyEventKind identifies the way a key held by the keystore was used.
+type KeyEventKind string
+
+const (
+	KeyUnlocked KeyEventKind = "unlock" // Key was decrypted and kept in memory
+	KeyLocked   KeyEventKind = "lock"   // Key was removed from memory
+	KeySigned   KeyEventKind = "sign"   // Key was used to sign a hash or transaction
+	KeyExported KeyEventKind = "export" // Key file was bundled into a key archive
+	KeyImported KeyEventKind = "import" // Key file was restored from a key archive
+)
+
+// KeyEvent is fired by the keystore whenever a key is unlocked, locked, used
+// for signing, exported or imported. It never carries any key material.
+type KeyEvent struct {
+	Time    time.Time      // Time the key was used
+	Address common.Address // Account the key belongs to
+	Kind    KeyEventKind   // Type of the key usage
+	Caller  string         // Code location outside the keystore triggering the event
+}
+
 type unlocked struct {
 	*Key
 	abort chan struct{}
@@ -100,6 +132,7 @@ func (ks *KeyStore) init(keydir string) {
 	// Initialize the set of unlocked keys and the account cache
 	ks.unlocked = make(map[common.Address]*unlocked)
 	ks.cache, ks.changes = newAccountCache(keydir)
+	ks.keyEvents = make(chan KeyEvent, keyEventQueue)
 
 	// TODO: In order for this finalizer to work, there must be no references
 	// to ks. addressCache doesn't keep a reference but unlocked keys do,
@@ -248,10 +281,63 @@ func (ks *KeyStore) Delete(a accounts.Account, passphrase string) error {
 	if err == nil {
 		ks.cache.delete(a)
 		ks.refreshWallets()
+		if !ks.HasAddress(a.Address) {
+			if err := ks.updateLabel(a.Address, AccountLabel{}); err != nil {
+				log.Warn("Failed to drop label of deleted account", "address", a.Address, "err", err)
+			}
+		}
 	}
 	return err
 }
 
+// SubscribeKeyEvents creates an async subscription to receive notifications when
+// keys are unlocked, locked or used for signing.
+func (ks *KeyStore) SubscribeKeyEvents(sink chan<- KeyEvent) event.Subscription {
+	ks.keyOnce.Do(func() { go ks.deliverKeyEvents() })
+	return ks.keyScope.Track(ks.keyFeed.Subscribe(sink))
+}
+
+// deliverKeyEvents sends the queued key events to the subscribers in order.
+func (ks *KeyStore) deliverKeyEvents() {
+	for ev := range ks.keyEvents {
+		ks.keyFeed.Send(ev)
+	}
+}
+
+// notifyKey queues a key usage event for the subscribers, dropping it if they
+// fall keyEventQueue events behind rather than holding up the key usage. If no
+// explicit caller is given, the first code location outside of the keystore
+// package is used.
+func (ks *KeyStore) notifyKey(addr common.Address, kind KeyEventKind, caller string) {
+	if ks.keyScope.Count() == 0 {
+		return
+	}
+	if caller == "" {
+		caller = keyEventCaller()
+	}
+	select {
+	case ks.keyEvents <- KeyEvent{Time: time.Now(), Address: addr, Kind: kind, Caller: caller}:
+	default:
+		log.Warn("Dropping key event, listeners fall behind", "address", addr, "event", kind, "caller", caller)
+	}
+}
+
+// keyEventCaller retrieves the first function up the call stack that is not
+// part of the keystore itself.
+func keyEventCaller() string {
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		frame, more := frames.Next()
+		if !strings.Contains(frame.Function, "/accounts/keystore.") {
+			return fmt.Sprintf("%s:%d", frame.Function, frame.Line)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
+
 // SignHash calculates a ECDSA signature for the given hash. The produced
 // signature is in the [R || S || V] format where V is 0 or 1.
 func (ks *KeyStore) SignHash(a accounts.Account, hash []byte) ([]byte, error) {
@@ -264,7 +350,11 @@ func (ks *KeyStore) SignHash(a accounts.Account, hash []byte) ([]byte, error) {
 		return nil, ErrLocked
 	}
 	// Sign the hash using plain ECDSA operations
-	return crypto.Sign(hash, unlockedKey.PrivateKey)
+	signature, err := crypto.Sign(hash, unlockedKey.PrivateKey)
+	if err == nil {
+		ks.notifyKey(a.Address, KeySigned, "")
+	}
+	return signature, err
 }
 
 // SignTx signs the given transaction with the requested account.
@@ -277,7 +367,11 @@ func (ks *KeyStore) SignTx(a accounts.Account, tx *types.Transaction, chainID *b
 	if !found {
 		return nil, ErrLocked
 	}
-	return types.SignTx(tx, types.NewHubbleSigner(chainID), unlockedKey.PrivateKey)
+	signed, err := types.SignTx(tx, types.NewHubbleSigner(chainID), unlockedKey.PrivateKey)
+	if err == nil {
+		ks.notifyKey(a.Address, KeySigned, "")
+	}
+	return signed, err
 }
 
 // SignHashWithPassphrase signs hash if the private key matching the given address
@@ -289,7 +383,12 @@ func (ks *KeyStore) SignHashWithPassphrase(a accounts.Account, passphrase string
 		return nil, err
 	}
 	defer zeroKey(key.PrivateKey)
-	return crypto.Sign(hash, key.PrivateKey)
+
+	signature, err = crypto.Sign(hash, key.PrivateKey)
+	if err == nil {
+		ks.notifyKey(a.Address, KeySigned, "")
+	}
+	return signature, err
 }
 
 // SignTxWithPassphrase signs the transaction if the private key matching the
@@ -301,7 +400,11 @@ func (ks *KeyStore) SignTxWithPassphrase(a accounts.Account, passphrase string,
 	}
 	defer zeroKey(key.PrivateKey)
 
-	return types.SignTx(tx, types.NewHubbleSigner(chainID), key.PrivateKey)
+	signed, err := types.SignTx(tx, types.NewHubbleSigner(chainID), key.PrivateKey)
+	if err == nil {
+		ks.notifyKey(a.Address, KeySigned, "")
+	}
+	return signed, err
 }
 
 // Unlock unlocks the given account indefinitely.
@@ -315,6 +418,7 @@ func (ks *KeyStore) Lock(addr common.Address) error {
 	if unl, found := ks.unlocked[addr]; found {
 		ks.mu.Unlock()
 		ks.expire(addr, unl, time.Duration(0)*time.Nanosecond)
+		ks.notifyKey(addr, KeyLocked, "")
 	} else {
 		ks.mu.Unlock()
 	}
@@ -334,6 +438,8 @@ func (ks *KeyStore) TimedUnlock(a accounts.Account, passphrase string, timeout t
 		return err
 	}
 
+	defer ks.notifyKey(a.Address, KeyUnlocked, keyEventCaller())
+
 	ks.mu.Lock()
 	defer ks.mu.Unlock()
 	u, found := ks.unlocked[a.Address]
@@ -387,11 +493,17 @@ func (ks *KeyStore) expire(addr common.Address, u *unlocked, timeout time.Durati
 		// was launched with. we can check that using pointer equality
 		// because the map stores a new pointer every time the key is
 		// unlocked.
-		if ks.unlocked[addr] == u {
+		dropped := ks.unlocked[addr] == u
+		if dropped {
 			zeroKey(u.PrivateKey)
 			delete(ks.unlocked, addr)
 		}
 		ks.mu.Unlock()
+
+		// Explicit locks are reported by Lock itself with the proper caller
+		if dropped && timeout > 0 {
+			ks.notifyKey(addr, KeyLocked, "timeout")
+		}
 	}
 }
 
@@ -17,9 +17,12 @@
 package keystore
 
 import (
+	"bytes"
+	"encoding/json"
 	"io/ioutil"
 	"math/rand"
 	"os"
+	"reflect"
 	"runtime"
 	"sort"
 	"strings"
@@ -85,6 +88,174 @@ func TestSign(t *testing.T) {
 	}
 }
 
+// Tests that signing doesn't wait on key event subscribers which fall behind,
+// while the events are still delivered in order to the ones keeping up.
+func TestSignKeyEventsNonBlocking(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	a1, err := ks.NewAccount("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stalled, events := make(chan KeyEvent), make(chan KeyEvent, 3)
+	defer ks.SubscribeKeyEvents(stalled).Unsubscribe()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 2*keyEventQueue; i++ {
+			if _, err := ks.SignHashWithPassphrase(a1, "", testSigData); err != nil {
+				t.Errorf("sign %d failed: %v", i, err)
+				return
+			}
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("signing blocked on a stalled key event subscriber")
+	}
+	// Unblock the delivery and ensure new events arrive in order
+	<-stalled
+	for len(ks.keyEvents) > 0 {
+		<-stalled
+	}
+	sub := ks.SubscribeKeyEvents(events)
+	defer sub.Unsubscribe()
+
+	go func() {
+		for range stalled {
+		}
+	}()
+	if err := ks.Unlock(a1, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.SignHash(accounts.Account{Address: a1.Address}, testSigData); err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.Lock(a1.Address); err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []KeyEventKind{KeyUnlocked, KeySigned, KeyLocked} {
+		select {
+		case ev := <-events:
+			if ev.Kind != want || ev.Address != a1.Address {
+				t.Errorf("event %d mismatch: have %s %x, want %s %x", i, ev.Kind, ev.Address, want, a1.Address)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("event %d not delivered", i)
+		}
+	}
+}
+
+// Tests that account labels are stored in the index, survive reopening the
+// keystore and are dropped along with their account.
+func TestAccountLabels(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	a1, err := ks.NewAccount("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a2, err := ks.NewAccount("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	label := AccountLabel{Name: "treasury", Tags: []string{"cold", "ops"}}
+	if err := ks.SetLabel(a1.Address, label); err != nil {
+		t.Fatalf("failed to set label: %v", err)
+	}
+	if err := ks.SetLabel(common.Address{1}, label); err != ErrNoMatch {
+		t.Errorf("error mismatch for unknown account: have %v, want %v", err, ErrNoMatch)
+	}
+	reopened := NewKeyStore(dir, veryLightScryptN, veryLightScryptP)
+	if have, err := reopened.Label(a1.Address); err != nil || !reflect.DeepEqual(have, label) {
+		t.Errorf("label mismatch: have %+v (%v), want %+v", have, err, label)
+	}
+	if have, _ := reopened.Label(a2.Address); !have.Empty() {
+		t.Errorf("unlabelled account has label %+v", have)
+	}
+	if have := label.String(); have != "treasury [cold,ops]" {
+		t.Errorf("label format mismatch: have %q, want %q", have, "treasury [cold,ops]")
+	}
+	if len(ks.Accounts()) != 2 {
+		t.Errorf("label index taken for an account: have %d accounts, want 2", len(ks.Accounts()))
+	}
+	if err := ks.Delete(a1, ""); err != nil {
+		t.Fatalf("failed to delete account: %v", err)
+	}
+	if labels, err := readLabels(ks.labelsPath()); err != nil || len(labels) != 0 {
+		t.Errorf("labels of deleted account kept: have %v (%v)", labels, err)
+	}
+}
+
+// Tests that key archives restore the exported key files only with the archive
+// password and if they pass the integrity checks.
+func TestKeyArchive(t *testing.T) {
+	dir, ks := tmpKeyStore(t, true)
+	defer os.RemoveAll(dir)
+
+	a1, err := ks.NewAccount("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a2, err := ks.NewAccount("bar")
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive, err := ks.ExportArchive([]accounts.Account{a1, a2}, "secret")
+	if err != nil {
+		t.Fatalf("failed to export archive: %v", err)
+	}
+	if _, err := ks.ExportArchive([]accounts.Account{{Address: common.Address{1}}}, "secret"); err == nil {
+		t.Errorf("unknown account exported")
+	}
+	restoreDir, restored := tmpKeyStore(t, true)
+	defer os.RemoveAll(restoreDir)
+
+	if _, _, err := restored.ImportArchive(archive, "wrong"); err != ErrDecrypt {
+		t.Errorf("error mismatch for wrong password: have %v, want %v", err, ErrDecrypt)
+	}
+	// Archives with tampered contents are rejected as a whole
+	var envelope keyArchiveJSON
+	if err := json.Unmarshal(archive, &envelope); err != nil {
+		t.Fatal(err)
+	}
+	aead, _ := envelope.aead("secret")
+	plaintext, _ := aead.Open(nil, envelope.Nonce, envelope.Ciphertext, nil)
+	tampered := bytes.Replace(plaintext, []byte(`"version":3`), []byte(`"version":4`), 1)
+	envelope.Ciphertext = aead.Seal(nil, envelope.Nonce, tampered, nil)
+	blob, _ := json.Marshal(&envelope)
+	if _, _, err := restored.ImportArchive(blob, "secret"); err != ErrArchiveCorrupted {
+		t.Errorf("error mismatch for tampered archive: have %v, want %v", err, ErrArchiveCorrupted)
+	}
+	if accs := restored.Accounts(); len(accs) != 0 {
+		t.Fatalf("accounts imported from rejected archives: %v", accs)
+	}
+	// Intact archives restore the accounts with their own passwords
+	imported, skipped, err := restored.ImportArchive(archive, "secret")
+	if err != nil {
+		t.Fatalf("failed to import archive: %v", err)
+	}
+	if len(imported) != 2 || len(skipped) != 0 {
+		t.Fatalf("import mismatch: have %d imported, %d skipped, want 2 and 0", len(imported), len(skipped))
+	}
+	for _, test := range []struct {
+		account  accounts.Account
+		password string
+	}{{a1, "foo"}, {a2, "bar"}} {
+		if err := restored.Unlock(test.account, test.password); err != nil {
+			t.Errorf("account %x: failed to unlock restored key: %v", test.account.Address, err)
+		}
+	}
+	imported, skipped, err = restored.ImportArchive(archive, "secret")
+	if err != nil || len(imported) != 0 || len(skipped) != 2 {
+		t.Errorf("repeated import mismatch: have %d imported, %d skipped (%v), want 0 and 2", len(imported), len(skipped), err)
+	}
+}
+
 func TestSignWithPassphrase(t *testing.T) {
 	dir, ks := tmpKeyStore(t, true)
 	defer os.RemoveAll(dir)
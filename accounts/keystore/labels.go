@@ -0,0 +1,130 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/vntchain/go-vnt/common"
+)
+
+// labelsFileName is the index of account labels kept next to the key files. It
+// is hidden, so the account cache never takes it for a key file.
+const labelsFileName = ".labels.json"
+
+// AccountLabel is the human-readable metadata attached to a keystore account.
+// It is not part of the key file and never encrypted.
+type AccountLabel struct {
+	Name string   `json:"name,omitempty"`
+	Tags []string `json:"tags,omitempty"`
+}
+
+// Empty reports whether the label carries neither a name nor tags.
+func (l AccountLabel) Empty() bool {
+	return l.Name == "" && len(l.Tags) == 0
+}
+
+// String implements fmt.Stringer, formatting the label as its name followed by
+// the bracketed tags.
+func (l AccountLabel) String() string {
+	if len(l.Tags) == 0 {
+		return l.Name
+	}
+	return strings.TrimSpace(l.Name + " [" + strings.Join(l.Tags, ",") + "]")
+}
+
+// labelsPath returns the location of the label index of the keystore.
+func (ks *KeyStore) labelsPath() string {
+	return filepath.Join(ks.cache.keydir, labelsFileName)
+}
+
+// Labels returns the labels of all the labelled accounts in the keystore.
+func (ks *KeyStore) Labels() (map[common.Address]AccountLabel, error) {
+	labels, err := readLabels(ks.labelsPath())
+	if err != nil {
+		return nil, err
+	}
+	for addr := range labels {
+		if !ks.HasAddress(addr) {
+			delete(labels, addr)
+		}
+	}
+	return labels, nil
+}
+
+// Label returns the label of an account, which is empty if none was set.
+func (ks *KeyStore) Label(addr common.Address) (AccountLabel, error) {
+	labels, err := ks.Labels()
+	if err != nil {
+		return AccountLabel{}, err
+	}
+	return labels[addr], nil
+}
+
+// SetLabel attaches a label to an account of the keystore, replacing any
+// previous one. An empty label removes it.
+func (ks *KeyStore) SetLabel(addr common.Address, label AccountLabel) error {
+	if !ks.HasAddress(addr) {
+		return ErrNoMatch
+	}
+	return ks.updateLabel(addr, label)
+}
+
+// updateLabel rewrites the label of an address in the index.
+func (ks *KeyStore) updateLabel(addr common.Address, label AccountLabel) error {
+	ks.labelLock.Lock()
+	defer ks.labelLock.Unlock()
+
+	path := ks.labelsPath()
+	labels, err := readLabels(path)
+	if err != nil {
+		return err
+	}
+	if _, ok := labels[addr]; !ok && label.Empty() {
+		return nil
+	}
+	if label.Empty() {
+		delete(labels, addr)
+	} else {
+		labels[addr] = label
+	}
+	blob, err := json.MarshalIndent(labels, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeKeyFile(path, blob)
+}
+
+// readLabels loads the label index, which is empty if it doesn't exist yet.
+func readLabels(path string) (map[common.Address]AccountLabel, error) {
+	labels := make(map[common.Address]AccountLabel)
+	blob, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return labels, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(blob, &labels); err != nil {
+		return nil, err
+	}
+	return labels, nil
+}
@@ -24,9 +24,17 @@ import (
 	"github.com/vntchain/go-vnt/event"
 )
 
+// Config contains the settings of the global account manager.
+type Config struct {
+	// InsecureUnlockAllowed allows unlocking accounts while the RPC APIs are
+	// exposed over HTTP or websocket.
+	InsecureUnlockAllowed bool
+}
+
 // Manager is an overarching account manager that can communicate with various
 // backends for signing transactions.
 type Manager struct {
+	config   *Config                    // Global account manager configurations
 	backends map[reflect.Type][]Backend // Index of backends currently registered
 	updaters []event.Subscription       // Wallet update subscriptions for all backends
 	updates  chan WalletEvent           // Subscription sink for backend wallet changes
@@ -40,7 +48,7 @@ type Manager struct {
 
 // NewManager creates a generic account manager to sign transaction via various
 // supported backends.
-func NewManager(backends ...Backend) *Manager {
+func NewManager(config *Config, backends ...Backend) *Manager {
 	// Retrieve the initial list of wallets from the backends and sort by URL
 	var wallets []Wallet
 	for _, backend := range backends {
@@ -55,6 +63,7 @@ func NewManager(backends ...Backend) *Manager {
 	}
 	// Assemble the account manager and return
 	am := &Manager{
+		config:   config,
 		backends: make(map[reflect.Type][]Backend),
 		updaters: subs,
 		updates:  updates,
@@ -70,6 +79,11 @@ func NewManager(backends ...Backend) *Manager {
 	return am
 }
 
+// Config returns the configuration of account manager.
+func (am *Manager) Config() *Config {
+	return am.config
+}
+
 // Close terminates the account manager's internal notification processes.
 func (am *Manager) Close() error {
 	errc := make(chan error)
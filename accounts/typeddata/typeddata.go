@@ -0,0 +1,441 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package typeddata implements the encoding and hashing of typed structured
+// data, as specified by EIP-712, so that wallets can display what a signature
+// is requested for instead of an opaque hash.
+package typeddata
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/common/hexutil"
+	"github.com/vntchain/go-vnt/common/math"
+	"github.com/vntchain/go-vnt/crypto"
+)
+
+// DomainType is the name of the type describing the signing domain.
+const DomainType = "EIP712Domain"
+
+var (
+	errNoDomainType  = errors.New("missing " + DomainType + " type")
+	errNoPrimaryType = errors.New("missing primary type")
+
+	typeNameRegexp = regexp.MustCompile(`^[a-zA-Z_$][a-zA-Z_$0-9]*$`)
+	intTypeRegexp  = regexp.MustCompile(`^(u?)int([0-9]*)$`)
+)
+
+// Type is a single named field of a struct type.
+type Type struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// Types are the struct types referenced by typed data, keyed by name.
+type Types map[string][]Type
+
+// Domain is the signing domain, separating the signatures of different
+// applications, versions, chains and contracts from each other.
+type Domain struct {
+	Name              string   `json:"name,omitempty"`
+	Version           string   `json:"version,omitempty"`
+	ChainId           *Integer `json:"chainId,omitempty"`
+	VerifyingContract string   `json:"verifyingContract,omitempty"`
+	Salt              string   `json:"salt,omitempty"`
+}
+
+// Map returns the set fields of the domain, in the form the domain type is
+// encoded from.
+func (d *Domain) Map() map[string]interface{} {
+	fields := make(map[string]interface{})
+	if d.Name != "" {
+		fields["name"] = d.Name
+	}
+	if d.Version != "" {
+		fields["version"] = d.Version
+	}
+	if d.ChainId != nil {
+		fields["chainId"] = (*big.Int)(d.ChainId)
+	}
+	if d.VerifyingContract != "" {
+		fields["verifyingContract"] = d.VerifyingContract
+	}
+	if d.Salt != "" {
+		fields["salt"] = d.Salt
+	}
+	return fields
+}
+
+// Integer is a big integer decoded from either a JSON number or a decimal or hex
+// string, as dapps use both.
+type Integer big.Int
+
+// MarshalJSON implements json.Marshaler.
+func (i *Integer) MarshalJSON() ([]byte, error) {
+	return (*big.Int)(i).MarshalJSON()
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (i *Integer) UnmarshalJSON(input []byte) error {
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(input))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return err
+	}
+	n, err := parseInteger(value)
+	if err != nil {
+		return err
+	}
+	*i = Integer(*n)
+	return nil
+}
+
+// TypedData is a structured message to sign, along with the types describing
+// it and the domain it is signed for.
+type TypedData struct {
+	Types       Types                  `json:"types"`
+	PrimaryType string                 `json:"primaryType"`
+	Domain      Domain                 `json:"domain"`
+	Message     map[string]interface{} `json:"message"`
+}
+
+// SigHash returns the hash to sign for the typed data:
+//
+//	keccak256("\x19\x01" ‖ hashStruct(domain) ‖ hashStruct(message))
+func (td *TypedData) SigHash() ([]byte, error) {
+	if err := td.Validate(); err != nil {
+		return nil, err
+	}
+	domain, err := td.HashStruct(DomainType, td.Domain.Map())
+	if err != nil {
+		return nil, fmt.Errorf("invalid domain: %v", err)
+	}
+	message, err := td.HashStruct(td.PrimaryType, td.Message)
+	if err != nil {
+		return nil, fmt.Errorf("invalid message: %v", err)
+	}
+	return crypto.Keccak256([]byte("\x19\x01"), domain, message), nil
+}
+
+// Validate checks that the types are well formed, and that both the domain and
+// the primary type are among them.
+func (td *TypedData) Validate() error {
+	if _, ok := td.Types[DomainType]; !ok {
+		return errNoDomainType
+	}
+	if td.PrimaryType == "" {
+		return errNoPrimaryType
+	}
+	if _, ok := td.Types[td.PrimaryType]; !ok {
+		return fmt.Errorf("unknown primary type %q", td.PrimaryType)
+	}
+	for name, fields := range td.Types {
+		if !typeNameRegexp.MatchString(name) {
+			return fmt.Errorf("invalid type name %q", name)
+		}
+		for _, field := range fields {
+			if field.Name == "" {
+				return fmt.Errorf("type %s: unnamed field", name)
+			}
+			base := elementType(field.Type)
+			if _, ok := td.Types[base]; !ok && !isPrimitive(base) {
+				return fmt.Errorf("type %s: field %s has unknown type %q", name, field.Name, field.Type)
+			}
+		}
+	}
+	return nil
+}
+
+// HashStruct returns the hash of a struct of the given type:
+//
+//	keccak256(typeHash ‖ encodeData(data))
+func (td *TypedData) HashStruct(typ string, data map[string]interface{}) (hexutil.Bytes, error) {
+	encoded, err := td.EncodeData(typ, data)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.Keccak256(encoded), nil
+}
+
+// TypeHash returns the hash of the encoded type.
+func (td *TypedData) TypeHash(typ string) hexutil.Bytes {
+	return crypto.Keccak256(td.EncodeType(typ))
+}
+
+// EncodeType returns the signature of a type, followed by the signatures of all
+// the struct types it references in alphabetical order, e.g.
+//
+//	Mail(Person from,Person to,string contents)Person(string name,address wallet)
+func (td *TypedData) EncodeType(typ string) []byte {
+	deps := td.dependencies(typ, nil)
+	sort.Strings(deps[1:])
+
+	var buffer bytes.Buffer
+	for _, dep := range deps {
+		buffer.WriteString(dep)
+		buffer.WriteString("(")
+		for i, field := range td.Types[dep] {
+			if i > 0 {
+				buffer.WriteString(",")
+			}
+			buffer.WriteString(field.Type + " " + field.Name)
+		}
+		buffer.WriteString(")")
+	}
+	return buffer.Bytes()
+}
+
+// dependencies appends to found the struct types referenced by typ, including
+// typ itself first, that haven't been found yet.
+func (td *TypedData) dependencies(typ string, found []string) []string {
+	typ = elementType(typ)
+	for _, dep := range found {
+		if dep == typ {
+			return found
+		}
+	}
+	if _, ok := td.Types[typ]; !ok {
+		return found
+	}
+	found = append(found, typ)
+	for _, field := range td.Types[typ] {
+		found = td.dependencies(field.Type, found)
+	}
+	return found
+}
+
+// EncodeData returns the encoding of a struct of the given type: its type hash
+// followed by the 32 byte encoding of every field in order.
+func (td *TypedData) EncodeData(typ string, data map[string]interface{}) ([]byte, error) {
+	fields, ok := td.Types[typ]
+	if !ok {
+		return nil, fmt.Errorf("unknown type %q", typ)
+	}
+	if len(data) > len(fields) {
+		return nil, fmt.Errorf("type %s: %d values for %d fields", typ, len(data), len(fields))
+	}
+	buffer := bytes.NewBuffer(td.TypeHash(typ))
+	for _, field := range fields {
+		value, ok := data[field.Name]
+		if !ok {
+			return nil, fmt.Errorf("type %s: missing value for field %s", typ, field.Name)
+		}
+		encoded, err := td.encodeValue(field.Type, value)
+		if err != nil {
+			return nil, fmt.Errorf("type %s: field %s: %v", typ, field.Name, err)
+		}
+		buffer.Write(encoded)
+	}
+	return buffer.Bytes(), nil
+}
+
+// encodeValue returns the 32 byte encoding of a value: the hash of the encoded
+// elements for arrays, the hash of the struct for struct types and the padded
+// value, or the hash of dynamic values, for primitive types.
+func (td *TypedData) encodeValue(typ string, value interface{}) ([]byte, error) {
+	if strings.HasSuffix(typ, "]") {
+		elements, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid array value %v", value)
+		}
+		open := strings.LastIndex(typ, "[")
+		if size := typ[open+1 : len(typ)-1]; size != "" {
+			n, err := strconv.Atoi(size)
+			if err != nil {
+				return nil, fmt.Errorf("invalid array type %q", typ)
+			}
+			if len(elements) != n {
+				return nil, fmt.Errorf("array of %d elements for %s", len(elements), typ)
+			}
+		}
+		var buffer bytes.Buffer
+		for _, element := range elements {
+			encoded, err := td.encodeValue(typ[:open], element)
+			if err != nil {
+				return nil, err
+			}
+			buffer.Write(encoded)
+		}
+		return crypto.Keccak256(buffer.Bytes()), nil
+	}
+	if _, ok := td.Types[typ]; ok {
+		data, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid %s value %v", typ, value)
+		}
+		return td.HashStruct(typ, data)
+	}
+	return encodePrimitive(typ, value)
+}
+
+// encodePrimitive returns the 32 byte encoding of a value of an atomic or
+// dynamic solidity type.
+func encodePrimitive(typ string, value interface{}) ([]byte, error) {
+	switch typ {
+	case "address":
+		str, ok := value.(string)
+		if !ok || !common.IsHexAddress(str) {
+			return nil, fmt.Errorf("invalid address %v", value)
+		}
+		return common.LeftPadBytes(common.HexToAddress(str).Bytes(), 32), nil
+
+	case "bool":
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("invalid bool %v", value)
+		}
+		if b {
+			return math.PaddedBigBytes(common.Big1, 32), nil
+		}
+		return math.PaddedBigBytes(common.Big0, 32), nil
+
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid string %v", value)
+		}
+		return crypto.Keccak256([]byte(str)), nil
+
+	case "bytes":
+		blob, err := parseBytes(value)
+		if err != nil {
+			return nil, err
+		}
+		return crypto.Keccak256(blob), nil
+	}
+	if strings.HasPrefix(typ, "bytes") {
+		size, err := strconv.Atoi(typ[len("bytes"):])
+		if err != nil || size < 1 || size > 32 {
+			return nil, fmt.Errorf("invalid type %q", typ)
+		}
+		blob, err := parseBytes(value)
+		if err != nil {
+			return nil, err
+		}
+		if len(blob) != size {
+			return nil, fmt.Errorf("%d bytes for %s", len(blob), typ)
+		}
+		return common.RightPadBytes(blob, 32), nil
+	}
+	if match := intTypeRegexp.FindStringSubmatch(typ); match != nil {
+		bits := 256
+		if match[2] != "" {
+			bits, _ = strconv.Atoi(match[2])
+		}
+		if bits < 8 || bits > 256 || bits%8 != 0 {
+			return nil, fmt.Errorf("invalid type %q", typ)
+		}
+		n, err := parseInteger(value)
+		if err != nil {
+			return nil, err
+		}
+		signed := match[1] == ""
+		if (!signed && (n.Sign() < 0 || n.BitLen() > bits)) || (signed && !fitsSigned(n, bits)) {
+			return nil, fmt.Errorf("integer %v overflows %s", n, typ)
+		}
+		return math.PaddedBigBytes(math.U256(n), 32), nil
+	}
+	return nil, fmt.Errorf("unknown type %q", typ)
+}
+
+// isPrimitive reports whether typ is a solidity type encodable by encodePrimitive.
+func isPrimitive(typ string) bool {
+	switch typ {
+	case "address", "bool", "string", "bytes":
+		return true
+	}
+	if strings.HasPrefix(typ, "bytes") {
+		size, err := strconv.Atoi(typ[len("bytes"):])
+		return err == nil && size >= 1 && size <= 32
+	}
+	if match := intTypeRegexp.FindStringSubmatch(typ); match != nil {
+		if match[2] == "" {
+			return true
+		}
+		bits, err := strconv.Atoi(match[2])
+		return err == nil && bits >= 8 && bits <= 256 && bits%8 == 0
+	}
+	return false
+}
+
+// elementType strips all array dimensions from a type.
+func elementType(typ string) string {
+	if i := strings.Index(typ, "["); i >= 0 {
+		return typ[:i]
+	}
+	return typ
+}
+
+// parseBytes decodes a hex encoded byte slice.
+func parseBytes(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case []byte:
+		return v, nil
+	case hexutil.Bytes:
+		return v, nil
+	case string:
+		blob, err := hexutil.Decode(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bytes %q: %v", v, err)
+		}
+		return blob, nil
+	}
+	return nil, fmt.Errorf("invalid bytes %v", value)
+}
+
+// parseInteger converts a JSON number, or a decimal or hex string, into a new
+// big integer.
+func parseInteger(value interface{}) (*big.Int, error) {
+	switch v := value.(type) {
+	case *big.Int:
+		return new(big.Int).Set(v), nil
+	case float64:
+		if v != float64(int64(v)) {
+			return nil, fmt.Errorf("invalid integer %v", v)
+		}
+		return big.NewInt(int64(v)), nil
+	case json.Number:
+		return parseInteger(v.String())
+	case string:
+		negative := strings.HasPrefix(v, "-")
+		n, ok := math.ParseBig256(strings.TrimPrefix(v, "-"))
+		if !ok || v == "" {
+			return nil, fmt.Errorf("invalid integer %q", v)
+		}
+		if negative {
+			n.Neg(n)
+		}
+		return n, nil
+	}
+	return nil, fmt.Errorf("invalid integer %v", value)
+}
+
+// fitsSigned reports whether n is representable as a signed integer of the
+// given number of bits.
+func fitsSigned(n *big.Int, bits int) bool {
+	limit := new(big.Int).Lsh(common.Big1, uint(bits-1))
+	return n.Cmp(limit) < 0 && n.Cmp(new(big.Int).Neg(limit)) >= 0
+}
@@ -0,0 +1,182 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package typeddata
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/vntchain/go-vnt/common/hexutil"
+)
+
+// mailJSON is the example message of the EIP-712 specification.
+const mailJSON = `{
+	"types": {
+		"EIP712Domain": [
+			{"name": "name", "type": "string"},
+			{"name": "version", "type": "string"},
+			{"name": "chainId", "type": "uint256"},
+			{"name": "verifyingContract", "type": "address"}
+		],
+		"Person": [
+			{"name": "name", "type": "string"},
+			{"name": "wallet", "type": "address"}
+		],
+		"Mail": [
+			{"name": "from", "type": "Person"},
+			{"name": "to", "type": "Person"},
+			{"name": "contents", "type": "string"}
+		]
+	},
+	"primaryType": "Mail",
+	"domain": {
+		"name": "Ether Mail",
+		"version": "1",
+		"chainId": 1,
+		"verifyingContract": "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC"
+	},
+	"message": {
+		"from": {"name": "Cow", "wallet": "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"},
+		"to": {"name": "Bob", "wallet": "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB"},
+		"contents": "Hello, Bob!"
+	}
+}`
+
+func loadMail(t *testing.T) *TypedData {
+	t.Helper()
+	td := new(TypedData)
+	if err := json.Unmarshal([]byte(mailJSON), td); err != nil {
+		t.Fatalf("failed to decode typed data: %v", err)
+	}
+	return td
+}
+
+// Tests the encoding and hashing against the values of the specification.
+func TestMailHashes(t *testing.T) {
+	td := loadMail(t)
+
+	if have, want := string(td.EncodeType("Mail")), "Mail(Person from,Person to,string contents)Person(string name,address wallet)"; have != want {
+		t.Errorf("encoded type mismatch: have %s, want %s", have, want)
+	}
+	if have, want := td.TypeHash("Mail").String(), "0xa0cedeb2dc280ba39b857546d74f5549c3a1d7bdc2dd96bf881f76108e23dac2"; have != want {
+		t.Errorf("type hash mismatch: have %s, want %s", have, want)
+	}
+	domain, err := td.HashStruct(DomainType, td.Domain.Map())
+	if err != nil {
+		t.Fatalf("failed to hash domain: %v", err)
+	}
+	if have, want := domain.String(), "0xf2cee375fa42b42143804025fc449deafd50cc031ca257e0b194a650a912090f"; have != want {
+		t.Errorf("domain separator mismatch: have %s, want %s", have, want)
+	}
+	message, err := td.HashStruct(td.PrimaryType, td.Message)
+	if err != nil {
+		t.Fatalf("failed to hash message: %v", err)
+	}
+	if have, want := message.String(), "0xc52c0ee5d84264471806290a3f2c4cecfc5490626bf912d01f240d7a274b371e"; have != want {
+		t.Errorf("message hash mismatch: have %s, want %s", have, want)
+	}
+	sighash, err := td.SigHash()
+	if err != nil {
+		t.Fatalf("failed to compute signing hash: %v", err)
+	}
+	if have, want := hexutil.Encode(sighash), "0xbe609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2"; have != want {
+		t.Errorf("signing hash mismatch: have %s, want %s", have, want)
+	}
+}
+
+// Tests that malformed types and values are rejected.
+func TestInvalidTypedData(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(td *TypedData)
+	}{
+		{"no domain type", func(td *TypedData) { delete(td.Types, DomainType) }},
+		{"unknown primary type", func(td *TypedData) { td.PrimaryType = "Letter" }},
+		{"unknown field type", func(td *TypedData) { td.Types["Person"][1].Type = "wallet" }},
+		{"missing field", func(td *TypedData) { delete(td.Message, "contents") }},
+		{"extra field", func(td *TypedData) { td.Message["subject"] = "Hi" }},
+		{"invalid address", func(td *TypedData) { td.Message["to"].(map[string]interface{})["wallet"] = "0x1234" }},
+		{"invalid string", func(td *TypedData) { td.Message["contents"] = 42.0 }},
+		{"invalid struct", func(td *TypedData) { td.Message["from"] = "Cow" }},
+	}
+	for _, tt := range tests {
+		td := loadMail(t)
+		tt.modify(td)
+		if _, err := td.SigHash(); err == nil {
+			t.Errorf("%s: no error", tt.name)
+		}
+	}
+}
+
+// Tests the encoding of primitive values.
+func TestEncodePrimitive(t *testing.T) {
+	tests := []struct {
+		typ   string
+		value interface{}
+		want  string
+		fail  bool
+	}{
+		{typ: "uint8", value: 255.0, want: "0x00000000000000000000000000000000000000000000000000000000000000ff"},
+		{typ: "uint8", value: 256.0, fail: true},
+		{typ: "uint256", value: "0x10", want: "0x0000000000000000000000000000000000000000000000000000000000000010"},
+		{typ: "uint", value: "-1", fail: true},
+		{typ: "int8", value: "-128", want: "0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff80"},
+		{typ: "int8", value: "128", fail: true},
+		{typ: "int12", value: 1.0, fail: true},
+		{typ: "bool", value: true, want: "0x0000000000000000000000000000000000000000000000000000000000000001"},
+		{typ: "bytes4", value: "0xdeadbeef", want: "0xdeadbeef00000000000000000000000000000000000000000000000000000000"},
+		{typ: "bytes4", value: "0xdead", fail: true},
+		{typ: "bytes33", value: "0x00", fail: true},
+	}
+	for _, tt := range tests {
+		have, err := encodePrimitive(tt.typ, tt.value)
+		if tt.fail {
+			if err == nil {
+				t.Errorf("%s %v: no error", tt.typ, tt.value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s %v: failed to encode: %v", tt.typ, tt.value, err)
+			continue
+		}
+		if hexutil.Encode(have) != tt.want {
+			t.Errorf("%s %v: encoding mismatch: have %x, want %s", tt.typ, tt.value, have, tt.want)
+		}
+	}
+}
+
+// Tests that arrays are encoded as the hash of their encoded elements.
+func TestEncodeArray(t *testing.T) {
+	td := loadMail(t)
+	td.Types["Group"] = []Type{{Name: "members", Type: "Person[]"}, {Name: "ids", Type: "uint8[2]"}}
+
+	group := map[string]interface{}{
+		"members": []interface{}{td.Message["from"], td.Message["to"]},
+		"ids":     []interface{}{1.0, 2.0},
+	}
+	if _, err := td.HashStruct("Group", group); err != nil {
+		t.Fatalf("failed to hash group: %v", err)
+	}
+	if have, want := string(td.EncodeType("Group")), "Group(Person[] members,uint8[2] ids)Person(string name,address wallet)"; have != want {
+		t.Errorf("encoded type mismatch: have %s, want %s", have, want)
+	}
+	group["ids"] = []interface{}{1.0}
+	if _, err := td.HashStruct("Group", group); err == nil {
+		t.Errorf("fixed size array of wrong length accepted")
+	}
+}
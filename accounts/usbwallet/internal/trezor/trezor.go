@@ -0,0 +1,98 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package trezor contains the wire protocol wrapper in Go.
+//
+// The message definitions are a hand maintained subset of the Trezor protocol
+// buffer specs (https://github.com/trezor/trezor-common), limited to the calls
+// needed to derive accounts and sign transactions.
+package trezor
+
+import (
+	"reflect"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// MessageType is the protocol buffer type number of a Trezor message.
+type MessageType int32
+
+const (
+	MessageType_MessageType_Initialize         MessageType = 0
+	MessageType_MessageType_Ping               MessageType = 1
+	MessageType_MessageType_Success            MessageType = 2
+	MessageType_MessageType_Failure            MessageType = 3
+	MessageType_MessageType_Features           MessageType = 17
+	MessageType_MessageType_PinMatrixRequest   MessageType = 18
+	MessageType_MessageType_PinMatrixAck       MessageType = 19
+	MessageType_MessageType_ButtonRequest      MessageType = 26
+	MessageType_MessageType_ButtonAck          MessageType = 27
+	MessageType_MessageType_EthereumGetAddress MessageType = 56
+	MessageType_MessageType_EthereumAddress    MessageType = 57
+	MessageType_MessageType_EthereumSignTx     MessageType = 58
+	MessageType_MessageType_EthereumTxRequest  MessageType = 59
+	MessageType_MessageType_EthereumTxAck      MessageType = 60
+)
+
+var MessageType_name = map[int32]string{
+	0:  "MessageType_Initialize",
+	1:  "MessageType_Ping",
+	2:  "MessageType_Success",
+	3:  "MessageType_Failure",
+	17: "MessageType_Features",
+	18: "MessageType_PinMatrixRequest",
+	19: "MessageType_PinMatrixAck",
+	26: "MessageType_ButtonRequest",
+	27: "MessageType_ButtonAck",
+	56: "MessageType_EthereumGetAddress",
+	57: "MessageType_EthereumAddress",
+	58: "MessageType_EthereumSignTx",
+	59: "MessageType_EthereumTxRequest",
+	60: "MessageType_EthereumTxAck",
+}
+
+var MessageType_value = map[string]int32{
+	"MessageType_Initialize":         0,
+	"MessageType_Ping":               1,
+	"MessageType_Success":            2,
+	"MessageType_Failure":            3,
+	"MessageType_Features":           17,
+	"MessageType_PinMatrixRequest":   18,
+	"MessageType_PinMatrixAck":       19,
+	"MessageType_ButtonRequest":      26,
+	"MessageType_ButtonAck":          27,
+	"MessageType_EthereumGetAddress": 56,
+	"MessageType_EthereumAddress":    57,
+	"MessageType_EthereumSignTx":     58,
+	"MessageType_EthereumTxRequest":  59,
+	"MessageType_EthereumTxAck":      60,
+}
+
+// Type returns the protocol buffer type number of a specific message. If the
+// message is nil, this method panics!
+func Type(msg proto.Message) uint16 {
+	return uint16(MessageType_value["MessageType_"+reflect.TypeOf(msg).Elem().Name()])
+}
+
+// Name returns the friendly message type name of a specific protocol buffer
+// type number.
+func Name(kind uint16) string {
+	name := MessageType_name[int32(kind)]
+	if len(name) < 12 {
+		return name
+	}
+	return name[12:]
+}
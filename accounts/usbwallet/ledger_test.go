@@ -0,0 +1,155 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package usbwallet
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/binary"
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/vntchain/go-vnt/accounts"
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/core/types"
+	"github.com/vntchain/go-vnt/crypto"
+	"github.com/vntchain/go-vnt/log"
+	"github.com/vntchain/go-vnt/rlp"
+)
+
+// fakeLedger emulates the USB transport and the application protocol of a Ledger
+// device, deriving the same key for any path.
+type fakeLedger struct {
+	key     *ecdsa.PrivateKey
+	chainID *big.Int
+
+	apdu   []byte // APDU currently being received
+	size   int    // Total size of the APDU being received
+	txdata []byte // Transaction RLP streamed for signing
+	out    bytes.Buffer
+}
+
+func (l *fakeLedger) Write(chunk []byte) (int, error) {
+	if binary.BigEndian.Uint16(chunk[3:5]) == 0 {
+		l.size, l.apdu = int(binary.BigEndian.Uint16(chunk[5:7])), nil
+		chunk = chunk[7:]
+	} else {
+		chunk = chunk[5:]
+	}
+	if left := l.size - len(l.apdu); left < len(chunk) {
+		chunk = chunk[:left]
+	}
+	if l.apdu = append(l.apdu, chunk...); len(l.apdu) == l.size {
+		l.reply(l.process(l.apdu[1], ledgerParam1(l.apdu[2]), l.apdu[5:]))
+	}
+	return 64, nil
+}
+
+func (l *fakeLedger) Read(b []byte) (int, error) {
+	return l.out.Read(b)
+}
+
+// process executes a single APDU command, returning the response data.
+func (l *fakeLedger) process(op byte, p1 ledgerParam1, data []byte) []byte {
+	switch ledgerOpcode(op) {
+	case ledgerOpGetConfiguration:
+		return []byte{0x00, 1, 0, 8}
+
+	case ledgerOpRetrieveAddress:
+		pubkey := crypto.FromECDSAPub(&l.key.PublicKey)
+		addr := hex.EncodeToString(crypto.PubkeyToAddress(l.key.PublicKey).Bytes())
+
+		reply := append([]byte{byte(len(pubkey))}, pubkey...)
+		return append(append(reply, byte(len(addr))), addr...)
+
+	case ledgerOpSignTransaction:
+		if p1 == ledgerP1InitTransactionData {
+			l.txdata = append([]byte{}, data[1+4*int(data[0]):]...)
+		} else {
+			l.txdata = append(l.txdata, data...)
+		}
+		// Sign only once the entire transaction arrived
+		if _, _, rest, err := rlp.Split(l.txdata); err != nil || len(rest) != 0 {
+			return nil
+		}
+		sig, err := crypto.Sign(crypto.Keccak256(l.txdata), l.key)
+		if err != nil {
+			panic(err)
+		}
+		v := sig[64] + byte(l.chainID.Uint64()*2+35)
+		return append([]byte{v}, sig[:64]...)
+	}
+	return nil
+}
+
+// reply frames a response with the success status word into USB chunks.
+func (l *fakeLedger) reply(data []byte) {
+	data = append(data, 0x90, 0x00)
+
+	payload := make([]byte, 2, 2+len(data))
+	binary.BigEndian.PutUint16(payload, uint16(len(data)))
+	payload = append(payload, data...)
+
+	for seq := 0; len(payload) > 0; seq++ {
+		chunk := make([]byte, 64)
+		copy(chunk, []byte{0x01, 0x01, 0x05})
+		binary.BigEndian.PutUint16(chunk[3:], uint16(seq))
+
+		n := copy(chunk[5:], payload)
+		payload = payload[n:]
+		l.out.Write(chunk)
+	}
+}
+
+// Tests that accounts can be derived from a Ledger device and that multi-chunk
+// transactions signed by it carry a valid replay protected signature.
+func TestLedgerSignTx(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	chainID := big.NewInt(1337)
+
+	drv := newLedgerDriver(log.New())
+	if err := drv.Open(&fakeLedger{key: key, chainID: chainID}, ""); err != nil {
+		t.Fatalf("failed to open ledger: %v", err)
+	}
+	if status, err := drv.Status(); err != nil || status != "Ethereum app v1.0.8 online" {
+		t.Fatalf("status mismatch: have %q (%v)", status, err)
+	}
+	derived, err := drv.Derive(accounts.DefaultBaseDerivationPath)
+	if err != nil {
+		t.Fatalf("failed to derive account: %v", err)
+	}
+	if derived != addr {
+		t.Fatalf("derived address mismatch: have %x, want %x", derived, addr)
+	}
+	tx := types.NewTransaction(3, common.HexToAddress("0x01"), big.NewInt(1), 100000, big.NewInt(1), bytes.Repeat([]byte{0xaa}, 600))
+
+	sender, signed, err := drv.SignTx(accounts.DefaultBaseDerivationPath, tx, chainID)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	if sender != addr {
+		t.Fatalf("sender mismatch: have %x, want %x", sender, addr)
+	}
+	if from, err := types.Sender(types.NewHubbleSigner(chainID), signed); err != nil || from != addr {
+		t.Fatalf("signature verification failed: have %x (%v), want %x", from, err, addr)
+	}
+	if _, _, err := drv.SignTx(accounts.DefaultBaseDerivationPath, tx, nil); err == nil {
+		t.Fatalf("signed transaction without replay protection")
+	}
+}
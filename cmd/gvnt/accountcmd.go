@@ -18,9 +18,16 @@ package main
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
 	"github.com/vntchain/go-vnt/accounts"
+	"github.com/vntchain/go-vnt/accounts/hdwallet"
 	"github.com/vntchain/go-vnt/accounts/keystore"
 	"github.com/vntchain/go-vnt/cmd/utils"
+	"github.com/vntchain/go-vnt/common"
 	"github.com/vntchain/go-vnt/console"
 	"github.com/vntchain/go-vnt/crypto"
 	"github.com/vntchain/go-vnt/log"
@@ -49,7 +56,8 @@ Note that exporting your key in unencrypted format is NOT supported.
 
 Keys are stored under <DATADIR>/keystore.
 It is safe to transfer the entire directory or the individual keys therein
-between vntchain nodes by simply copying.
+between vntchain nodes by simply copying, though the export command bundles
+them into a single password protected archive checked on import.
 
 Make sure you backup your keys regularly.`,
 		Subcommands: []cli.Command{
@@ -63,6 +71,26 @@ Make sure you backup your keys regularly.`,
 				},
 				Description: `
 Print a short summary of all accounts`,
+			},
+			{
+				Name:      "label",
+				Usage:     "Name and tag an existing account",
+				Action:    utils.MigrateFlags(accountLabel),
+				ArgsUsage: "<address> [<name> [<tag>...]]",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.KeyStoreDirFlag,
+				},
+				Description: `
+    gvnt account label <address> [<name> [<tag>...]]
+
+Attaches a human-readable name and optional tags to an account, replacing any
+previous label. Without a name the label is removed. The address may also be
+given as the index of the account.
+
+Labels are kept in an index next to the key files and shown by the list
+command. They are not encrypted and don't require the account password.
+`,
 			},
 			{
 				Name:   "new",
@@ -73,6 +101,8 @@ Print a short summary of all accounts`,
 					utils.KeyStoreDirFlag,
 					utils.PasswordFileFlag,
 					utils.LightKDFFlag,
+					utils.KeyStoreScryptNFlag,
+					utils.KeyStoreScryptPFlag,
 				},
 				Description: `
     gvnt account new
@@ -98,6 +128,8 @@ password to file or expose in any other way.
 					utils.DataDirFlag,
 					utils.KeyStoreDirFlag,
 					utils.LightKDFFlag,
+					utils.KeyStoreScryptNFlag,
+					utils.KeyStoreScryptPFlag,
 				},
 				Description: `
     gvnt account update <address>
@@ -127,8 +159,15 @@ changing your password is only possible interactively.
 					utils.KeyStoreDirFlag,
 					utils.PasswordFileFlag,
 					utils.LightKDFFlag,
+					utils.KeyStoreScryptNFlag,
+					utils.KeyStoreScryptPFlag,
+					utils.MnemonicFlag,
+					utils.HDPathFlag,
+					utils.HDPasswordFileFlag,
+					utils.KeyArchiveFlag,
+					utils.AccountAuditFlag,
 				},
-				ArgsUsage: "<keyFile>",
+				ArgsUsage: "<keyFile> | --mnemonic [<mnemonicFile>] | --archive <archiveFile>",
 				Description: `
     gvnt account import <keyfile>
 
@@ -145,10 +184,59 @@ For non-interactive use the passphrase can be specified with the -password flag:
 
     gvnt account import [options] <keyfile>
 
+    gvnt account import --mnemonic [--hdpath <path>] [--hdpassword <file>] [<mnemonicFile>]
+
+Restores an account of a wallet created by external tools from its BIP-39
+mnemonic, read from <mnemonicFile> or prompted for. The optional passphrase of
+the mnemonic is read from the first line of the --hdpassword file, or else
+prompted for along with a prompted mnemonic. The account derived at the --hdpath derivation path is imported,
+by default the first one along m/44'/60'/0'/0/0, m/44'/60'/0'/0/1, ... which
+is not in the keystore yet, so that repeated imports restore the accounts of
+the wallet one after the other. Prints the address and its derivation path.
+
+    gvnt account import --archive [--accounts.audit <file>] <archiveFile>
+
+Restores the key files of an archive created by the export command, prompting
+for the password of the archive. The archive is verified as a whole before any
+key file is restored, and accounts already in the keystore are skipped. The
+restored accounts keep the passwords they had when exported.
+
 Note:
 As you can directly copy your encrypted accounts to another gvnt instance,
 this import mechanism is not needed when you transfer an account between
 nodes.
+`,
+			},
+			{
+				Name:      "export",
+				Usage:     "Export key files into a password protected archive",
+				Action:    utils.MigrateFlags(accountExport),
+				ArgsUsage: "<archiveFile> [<address>...]",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.KeyStoreDirFlag,
+					utils.PasswordFileFlag,
+					utils.LightKDFFlag,
+					utils.KeyStoreScryptNFlag,
+					utils.KeyStoreScryptPFlag,
+					utils.AccountAuditFlag,
+				},
+				Description: `
+    gvnt account export [options] <archiveFile> [<address>...]
+
+Bundles the key files of the given accounts, or of all accounts if none are
+given, into a new archive encrypted with a password you are prompted for. The
+addresses may also be given as the indexes of the accounts.
+
+The key files are archived unchanged, so they stay encrypted with the passwords
+of their accounts, and each one is checksummed to detect corruption on import.
+Restore the archive with:
+
+    gvnt account import --archive <archiveFile>
+
+For non-interactive use the archive password can be specified with the
+--password flag. With --accounts.audit an audit record is appended to the
+given file for every exported account.
 `,
 			},
 		},
@@ -157,16 +245,54 @@ nodes.
 
 func accountList(ctx *cli.Context) error {
 	stack, _ := makeConfigNode(ctx)
+	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+	labels, err := ks.Labels()
+	if err != nil {
+		log.Warn("Failed to load account labels", "err", err)
+	}
 	var index int
 	for _, wallet := range stack.AccountManager().Wallets() {
 		for _, account := range wallet.Accounts() {
-			fmt.Printf("Account #%d: {%x} %s\n", index, account.Address, &account.URL)
+			if label, ok := labels[account.Address]; ok {
+				fmt.Printf("Account #%d: {%x} %s %s\n", index, account.Address, &account.URL, label)
+			} else {
+				fmt.Printf("Account #%d: {%x} %s\n", index, account.Address, &account.URL)
+			}
 			index++
 		}
 	}
 	return nil
 }
 
+// accountLabel sets or clears the name and tags of a keystore account.
+func accountLabel(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) == 0 {
+		utils.Fatalf("No account specified to label")
+	}
+	stack, _ := makeConfigNode(ctx)
+	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+
+	account, err := utils.MakeAddress(ks, args.First())
+	if err != nil {
+		utils.Fatalf("Could not find account: %v", err)
+	}
+	var label keystore.AccountLabel
+	if len(args) > 1 {
+		label.Name = args[1]
+		label.Tags = args[2:]
+	}
+	if err := ks.SetLabel(account.Address, label); err != nil {
+		utils.Fatalf("Could not label account: %v", err)
+	}
+	if label.Empty() {
+		fmt.Printf("Cleared label of {%x}\n", account.Address)
+	} else {
+		fmt.Printf("Labelled {%x} %s\n", account.Address, label)
+	}
+	return nil
+}
+
 // tries unlocking the specified account a few times.
 func unlockAccount(ctx *cli.Context, ks *keystore.KeyStore, address string, i int, passwords []string) (accounts.Account, string) {
 	account, err := utils.MakeAddress(ks, address)
@@ -299,6 +425,18 @@ func accountUpdate(ctx *cli.Context) error {
 }
 
 func accountImport(ctx *cli.Context) error {
+	if ctx.Bool(utils.MnemonicFlag.Name) {
+		return accountImportMnemonic(ctx)
+	}
+	if ctx.Bool(utils.KeyArchiveFlag.Name) {
+		return accountImportArchive(ctx)
+	}
+	if ctx.IsSet(utils.HDPathFlag.Name) {
+		utils.Fatalf("Option %q requires --%s", utils.HDPathFlag.Name, utils.MnemonicFlag.Name)
+	}
+	if ctx.IsSet(utils.HDPasswordFileFlag.Name) {
+		utils.Fatalf("Option %q requires --%s", utils.HDPasswordFileFlag.Name, utils.MnemonicFlag.Name)
+	}
 	keyfile := ctx.Args().First()
 	if len(keyfile) == 0 {
 		utils.Fatalf("keyfile must be given as argument")
@@ -318,3 +456,186 @@ func accountImport(ctx *cli.Context) error {
 	fmt.Printf("Address: {%x}\n", acct.Address)
 	return nil
 }
+
+// accountImportMnemonic imports an account derived from a BIP-39 mnemonic, read
+// from the given file or prompted for.
+func accountImportMnemonic(ctx *cli.Context) error {
+	var mnemonic, password string
+	file := ctx.Args().First()
+	if file != "" {
+		blob, err := ioutil.ReadFile(file)
+		if err != nil {
+			utils.Fatalf("Failed to read the mnemonic: %v", err)
+		}
+		mnemonic = string(blob)
+	} else {
+		var err error
+		if mnemonic, err = console.Stdin.PromptPassword("Mnemonic: "); err != nil {
+			utils.Fatalf("Failed to read mnemonic: %v", err)
+		}
+	}
+	if path := ctx.String(utils.HDPasswordFileFlag.Name); path != "" {
+		blob, err := ioutil.ReadFile(path)
+		if err != nil {
+			utils.Fatalf("Failed to read mnemonic passphrase file: %v", err)
+		}
+		password = strings.TrimRight(strings.SplitN(string(blob), "\n", 2)[0], "\r")
+	} else if file == "" {
+		var err error
+		if password, err = console.Stdin.PromptPassword("Mnemonic passphrase (empty for none): "); err != nil {
+			utils.Fatalf("Failed to read mnemonic passphrase: %v", err)
+		}
+	}
+	wallet, err := hdwallet.NewFromMnemonic(mnemonic, password)
+	if err != nil {
+		utils.Fatalf("Failed to restore the wallet: %v", err)
+	}
+	stack, _ := makeConfigNode(ctx)
+	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+
+	// Import the requested account, or discover the next one not imported yet
+	var path accounts.DerivationPath
+	if ctx.IsSet(utils.HDPathFlag.Name) {
+		if path, err = accounts.ParseDerivationPath(ctx.String(utils.HDPathFlag.Name)); err != nil {
+			utils.Fatalf("Option %q: %v", utils.HDPathFlag.Name, err)
+		}
+	} else if path, err = nextMissingPath(wallet, accounts.DefaultBaseDerivationPath, ks.HasAddress); err != nil {
+		utils.Fatalf("Failed to derive account: %v", err)
+	}
+	account, err := wallet.Derive(path, true)
+	if err != nil {
+		utils.Fatalf("Failed to derive account: %v", err)
+	}
+	key, err := wallet.PrivateKey(account)
+	if err != nil {
+		utils.Fatalf("Failed to derive account: %v", err)
+	}
+	passphrase := getPassPhrase("Your new account is locked with a password. Please give a password. Do not forget this password.", true, 0, utils.MakePasswordList(ctx))
+
+	acct, err := ks.ImportECDSA(key, passphrase)
+	if err != nil {
+		utils.Fatalf("Could not create the account: %v", err)
+	}
+	fmt.Printf("Address: {%x}\n", acct.Address)
+	fmt.Printf("Path: %s\n", path)
+	return nil
+}
+
+// nextMissingPath returns the first derivation path from the base on, whose
+// account is not known yet.
+func nextMissingPath(wallet *hdwallet.Wallet, base accounts.DerivationPath, known func(common.Address) bool) (accounts.DerivationPath, error) {
+	path := make(accounts.DerivationPath, len(base))
+	copy(path, base)
+
+	for {
+		account, err := wallet.Derive(path, false)
+		if err != nil {
+			return nil, err
+		}
+		if !known(account.Address) {
+			return path, nil
+		}
+		path[len(path)-1]++
+	}
+}
+
+// accountExport writes the key files of the requested accounts, or of all of
+// them, into a password protected archive.
+func accountExport(ctx *cli.Context) error {
+	args := ctx.Args()
+	if len(args) == 0 {
+		utils.Fatalf("Archive file must be given as argument")
+	}
+	if _, err := os.Stat(args.First()); err == nil {
+		utils.Fatalf("Archive file %s already exists", args.First())
+	}
+	stack, _ := makeConfigNode(ctx)
+	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+
+	accs := ks.Accounts()
+	if len(args) > 1 {
+		accs = accs[:0:0]
+		for _, arg := range args[1:] {
+			account, err := utils.MakeAddress(ks, arg)
+			if err != nil {
+				utils.Fatalf("Could not find account: %v", err)
+			}
+			accs = append(accs, account)
+		}
+	}
+	if len(accs) == 0 {
+		utils.Fatalf("No accounts to export")
+	}
+	password := getPassPhrase("The archive is locked with a password. Please give a password. Do not forget this password.", true, 0, utils.MakePasswordList(ctx))
+
+	archive, err := ks.ExportArchive(accs, password)
+	if err != nil {
+		utils.Fatalf("Could not export the accounts: %v", err)
+	}
+	if err := ioutil.WriteFile(args.First(), archive, 0600); err != nil {
+		utils.Fatalf("Could not write the archive: %v", err)
+	}
+	addrs := make([]common.Address, len(accs))
+	for i, account := range accs {
+		addrs[i] = account.Address
+		fmt.Printf("Exported: {%x}\n", account.Address)
+	}
+	auditArchive(ctx, keystore.KeyExported, addrs)
+	log.Info("Exported accounts", "archive", args.First(), "count", len(accs))
+	return nil
+}
+
+// accountImportArchive restores the key files of an archive created by the
+// export command.
+func accountImportArchive(ctx *cli.Context) error {
+	file := ctx.Args().First()
+	if len(file) == 0 {
+		utils.Fatalf("Archive file must be given as argument")
+	}
+	archive, err := ioutil.ReadFile(file)
+	if err != nil {
+		utils.Fatalf("Failed to read the archive: %v", err)
+	}
+	stack, _ := makeConfigNode(ctx)
+	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+
+	password := getPassPhrase("Please give the password of the archive.", false, 0, utils.MakePasswordList(ctx))
+	imported, skipped, err := ks.ImportArchive(archive, password)
+
+	addrs := make([]common.Address, len(imported))
+	for i, account := range imported {
+		addrs[i] = account.Address
+		fmt.Printf("Imported: {%x}\n", account.Address)
+	}
+	auditArchive(ctx, keystore.KeyImported, addrs)
+	if err != nil {
+		utils.Fatalf("Could not import the archive: %v", err)
+	}
+	for _, addr := range skipped {
+		fmt.Printf("Skipped, already present: {%x}\n", addr)
+	}
+	log.Info("Imported accounts", "archive", file, "count", len(imported), "skipped", len(skipped))
+	return nil
+}
+
+// auditArchive appends an audit record of the exported or imported accounts to
+// the audit file, if one is configured.
+func auditArchive(ctx *cli.Context, kind keystore.KeyEventKind, addrs []common.Address) {
+	path := ctx.GlobalString(utils.AccountAuditFlag.Name)
+	if path == "" || len(addrs) == 0 {
+		return
+	}
+	audit, err := utils.OpenAuditFile(path)
+	if err != nil {
+		utils.Fatalf("Failed to open account audit file: %v", err)
+	}
+	defer audit.Close()
+
+	events := make([]keystore.KeyEvent, len(addrs))
+	for i, addr := range addrs {
+		events[i] = keystore.KeyEvent{Time: time.Now(), Address: addr, Kind: kind, Caller: "account " + string(kind)}
+	}
+	if err := utils.WriteAccountAudit(audit, events); err != nil {
+		utils.Fatalf("Failed to write account audit records: %v", err)
+	}
+}
@@ -17,6 +17,7 @@
 package main
 
 import (
+	"io/ioutil"
 	"path/filepath"
 	"runtime"
 	"strings"
@@ -105,6 +106,84 @@ Repeat passphrase: {{.InputLine "foobar2"}}
 `)
 }
 
+func TestAccountImportMnemonic(t *testing.T) {
+	datadir := tmpdir(t)
+	mnemonic := filepath.Join(datadir, "mnemonic.txt")
+	if err := ioutil.WriteFile(mnemonic, []byte("test test test test test test test test test test test junk\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// Repeated imports restore the accounts of the wallet in order
+	for _, want := range []string{
+		"Address: {f39fd6e51aad88f6f4ce6ab8827279cfffb92266}\nPath: m/44'/60'/0'/0/0\n",
+		"Address: {70997970c51812dc3a010c7d01b50e0d17dc79c8}\nPath: m/44'/60'/0'/0/1\n",
+	} {
+		gvnt := runGvnt(t, "account", "import", "--datadir", datadir, "--lightkdf",
+			"--password", "testdata/passwords.txt", "--mnemonic", mnemonic)
+		gvnt.Expect(want)
+		gvnt.ExpectExit()
+	}
+	gvnt := runGvnt(t, "account", "import", "--datadir", datadir, "--lightkdf",
+		"--password", "testdata/passwords.txt", "--mnemonic", "--hdpath", "m/44'/60'/0'/0/7", mnemonic)
+	gvnt.Expect("Address: {14dc79964da2c08b23698b3d3cc7ca32193d9955}\nPath: m/44'/60'/0'/0/7\n")
+	gvnt.ExpectExit()
+}
+
+func TestAccountImportMnemonicPassphrase(t *testing.T) {
+	datadir := tmpdir(t)
+	mnemonic := filepath.Join(datadir, "mnemonic.txt")
+	if err := ioutil.WriteFile(mnemonic, []byte("test test test test test test test test test test test junk\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	passphrase := filepath.Join(datadir, "passphrase.txt")
+	if err := ioutil.WriteFile(passphrase, []byte("TREZOR\r\nignored\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// The passphrase derives a different wallet from the same mnemonic
+	gvnt := runGvnt(t, "account", "import", "--datadir", datadir, "--lightkdf",
+		"--password", "testdata/passwords.txt", "--mnemonic", "--hdpassword", passphrase, mnemonic)
+	gvnt.Expect("Address: {9313778b3753108128b9c476ebdd42fbd566f4ed}\nPath: m/44'/60'/0'/0/0\n")
+	gvnt.ExpectExit()
+}
+
+func TestAccountExportImport(t *testing.T) {
+	datadir := tmpDatadirWithKeystore(t)
+	archive := filepath.Join(datadir, "backup.json")
+	audit := filepath.Join(datadir, "audit.log")
+
+	gvnt := runGvnt(t, "account", "export", "--datadir", datadir, "--lightkdf",
+		"--password", "testdata/passwords.txt", "--accounts.audit", audit,
+		archive, "f466859ead1932d743d622cb74fc058882e8648a", "2")
+	gvnt.Expect(`
+Exported: {f466859ead1932d743d622cb74fc058882e8648a}
+Exported: {289d485d9771714cce91d3393d764e1311907acc}
+`)
+	gvnt.ExpectExit()
+
+	restored := tmpdir(t)
+	gvnt = runGvnt(t, "account", "import", "--datadir", restored, "--lightkdf",
+		"--password", "testdata/passwords.txt", "--accounts.audit", audit, "--archive", archive)
+	gvnt.Expect(`
+Imported: {f466859ead1932d743d622cb74fc058882e8648a}
+Imported: {289d485d9771714cce91d3393d764e1311907acc}
+`)
+	gvnt.ExpectExit()
+
+	records, err := ioutil.ReadFile(audit)
+	if err != nil {
+		t.Fatalf("failed to read audit file: %v", err)
+	}
+	if have := strings.Count(string(records), `"event":"export"`); have != 2 {
+		t.Errorf("export audit records mismatch: have %d, want 2", have)
+	}
+	if have := strings.Count(string(records), `"event":"import"`); have != 2 {
+		t.Errorf("import audit records mismatch: have %d, want 2", have)
+	}
+	// Exporting never overwrites an existing archive
+	gvnt = runGvnt(t, "account", "export", "--datadir", datadir, archive)
+	gvnt.ExpectRegexp(`Fatal: Archive file .* already exists\n`)
+	gvnt.ExpectExit()
+}
+
 func TestUnlockFlag(t *testing.T) {
 	datadir := tmpDatadirWithKeystore(t)
 	gvnt := runGvnt(t,
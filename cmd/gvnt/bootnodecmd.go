@@ -0,0 +1,103 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of go-vnt.
+//
+// go-vnt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-vnt is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-vnt. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	peerstore "github.com/libp2p/go-libp2p-peerstore"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/vntchain/go-vnt/cmd/utils"
+	"github.com/vntchain/go-vnt/log"
+	"github.com/vntchain/go-vnt/vntp2p"
+	cli "gopkg.in/urfave/cli.v1"
+)
+
+var bootnodeCommand = cli.Command{
+	Action:    utils.MigrateFlags(bootnode),
+	Name:      "bootnode",
+	Usage:     "Run a bootstrap node serving peer discovery only",
+	ArgsUsage: " ",
+	Category:  "MISCELLANEOUS COMMANDS",
+	Flags: []cli.Flag{
+		utils.DataDirFlag,
+		utils.ListenPortFlag,
+		utils.ListenIPFlag,
+		utils.NodeKeyFileFlag,
+		utils.NodeKeyHexFlag,
+		utils.NATFlag,
+		utils.NetrestrictFlag,
+		utils.ProxyFlag,
+		utils.VNTBootnodeFlag,
+	},
+	Description: `
+    gvnt bootnode [options]
+
+The bootnode command runs the discovery DHT of the peer-to-peer layer on its
+own, without opening a chain database or starting any protocol, so that it can
+be used as a lightweight entry point into a network by --vntbootnode.
+
+The node key is taken from --nodekey or --nodekeyhex, or else from the data
+directory, where a new key is generated and stored on the first run so that
+the node URL stays the same across restarts. Further bootnodes given with
+--vntbootnode are added to the routing table of the node.
+
+The URLs the node is reachable at are printed once it's listening.`,
+}
+
+// bootnode runs a standalone discovery node until interrupted.
+func bootnode(ctx *cli.Context) error {
+	cfg := defaultNodeConfig()
+	utils.SetNodeConfig(ctx, &cfg)
+
+	listenAddrs, err := vntp2p.ListenAddrs(cfg.P2P.ListenAddr)
+	if err != nil {
+		utils.Fatalf("Invalid listen address %q: %v", cfg.P2P.ListenAddr, err)
+	}
+	dhtctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	vdht, host, err := vntp2p.ConstructDHT(dhtctx, listenAddrs, cfg.NodeKey(), cfg.DataDir, cfg.P2P.NetRestrict, cfg.P2P.NAT, cfg.P2P.Proxy)
+	if err != nil {
+		utils.Fatalf("Failed to start bootnode: %v", err)
+	}
+	defer host.Close()
+
+	for _, node := range cfg.P2P.BootstrapNodes {
+		if node.Id == host.ID() {
+			continue
+		}
+		host.Peerstore().AddAddrs(node.Id, []ma.Multiaddr{node.Addr}, peerstore.PermanentAddrTTL)
+		vdht.Update(dhtctx, node.Id)
+	}
+	for _, addr := range host.Addrs() {
+		fmt.Printf("Bootnode URL: %s/ipfs/%s\n", addr, host.ID().Pretty())
+	}
+	log.Info("Bootnode started", "id", host.ID().Pretty(), "bootnodes", len(cfg.P2P.BootstrapNodes))
+
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigc)
+	<-sigc
+
+	log.Info("Got interrupt, shutting down...")
+	return nil
+}
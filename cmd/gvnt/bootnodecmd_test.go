@@ -0,0 +1,45 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of go-vnt.
+//
+// go-vnt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-vnt is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-vnt. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+// Tests that the bootnode generates its key on the first run and keeps the
+// same identity across restarts.
+func TestBootnodeKeyPersistence(t *testing.T) {
+	datadir := tmpdir(t)
+	defer os.RemoveAll(datadir)
+
+	var ids []string
+	for i := 0; i < 2; i++ {
+		gvnt := runGvnt(t, "bootnode", "--datadir", datadir, "--port", "0")
+		_, matches := gvnt.ExpectRegexp(`Bootnode URL: \S+/tcp/\d+/ipfs/(\w+)\n`)
+		gvnt.Interrupt()
+		gvnt.WaitExit()
+
+		if len(matches) != 2 {
+			t.Fatalf("run %d: no bootnode URL printed", i)
+		}
+		ids = append(ids, matches[1])
+	}
+	if ids[0] != ids[1] {
+		t.Errorf("node id mismatch after restart: have %s, want %s", ids[1], ids[0])
+	}
+}
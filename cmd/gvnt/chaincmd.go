@@ -17,9 +17,11 @@
 package main
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strconv"
 	"sync/atomic"
@@ -31,6 +33,7 @@ import (
 	"github.com/vntchain/go-vnt/common"
 	"github.com/vntchain/go-vnt/console"
 	"github.com/vntchain/go-vnt/core"
+	"github.com/vntchain/go-vnt/core/rawdb"
 	"github.com/vntchain/go-vnt/core/state"
 	"github.com/vntchain/go-vnt/core/types"
 	"github.com/vntchain/go-vnt/event"
@@ -49,6 +52,7 @@ var (
 		ArgsUsage: "<genesisPath>",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			utils.AncientFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
@@ -57,6 +61,17 @@ This is a destructive action and changes the network in which you will be
 participating.
 
 It expects the genesis file as argument.`,
+	}
+	validateGenesisCommand = cli.Command{
+		Action:    utils.MigrateFlags(validateGenesis),
+		Name:      "validate-genesis",
+		Usage:     "Check a genesis file for problems without initializing it",
+		ArgsUsage: "<genesisPath>",
+		Category:  "BLOCKCHAIN COMMANDS",
+		Description: `
+The validate-genesis command parses a genesis file and checks its DPoS witness
+list, allocations and gas limit, reporting all the problems found at once. It
+exits with a non-zero status if the genesis is invalid.`,
 	}
 	importCommand = cli.Command{
 		Action:    utils.MigrateFlags(importChain),
@@ -65,18 +80,37 @@ It expects the genesis file as argument.`,
 		ArgsUsage: "<filename> (<filename 2> ... <filename N>) ",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			utils.DeveloperMemoryFlag,
+			utils.AncientFlag,
 			utils.CacheFlag,
 			utils.GCModeFlag,
+			utils.NoPreimagesFlag,
 			utils.CacheDatabaseFlag,
 			utils.CacheGCFlag,
+			utils.CacheSnapshotFlag,
+			utils.CachePrefetchFlag,
+			utils.CacheTrieMaxFlag,
+			utils.CacheBlocksFlag,
+			utils.CacheReceiptsFlag,
+			utils.CacheSendersFlag,
+			utils.SnapshotFlag,
+			utils.DatabaseStatsFlag,
+			utils.DposLogForkChoiceFlag,
+			utils.VMMemCapFlag,
+			utils.ChainReceiptsFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
 The import command imports blocks from an RLP-encoded form. The form can be one file
-with several RLP-encoded blocks, or several files can be used.
+with several RLP-encoded blocks, or several files can be used. Files may be gzip
+compressed.
 
 If only one file is used, import error will result in failure. If several files are used,
-processing will proceed even if an individual RLP-file import failure occurs.`,
+processing will proceed even if an individual RLP-file import failure occurs.
+
+Files exported with --receipts must be imported with --receipts, the receipts are
+checked against the blocks they belong to. Blocks already in the chain are skipped,
+so an interrupted import resumes where it stopped when run again.`,
 	}
 	exportCommand = cli.Command{
 		Action:    utils.MigrateFlags(exportChain),
@@ -85,14 +119,18 @@ processing will proceed even if an individual RLP-file import failure occurs.`,
 		ArgsUsage: "<filename> [<blockNumFirst> <blockNumLast>]",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			utils.AncientFlag,
 			utils.CacheFlag,
+			utils.ChainReceiptsFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
 Requires a first argument of the file to write to.
 Optional second and third arguments control the first and
 last block to write. In this mode, the file will be appended
-if already existing.`,
+if already existing. If the file ends with .gz, the output will
+be gzipped. The --receipts flag writes the receipts of each
+block right after it.`,
 	}
 	importPreimagesCommand = cli.Command{
 		Action:    utils.MigrateFlags(importPreimages),
@@ -101,11 +139,16 @@ if already existing.`,
 		ArgsUsage: "<datafile>",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			utils.AncientFlag,
 			utils.CacheFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
-	The import-preimages command imports hash preimages from an RLP encoded stream.`,
+The import-preimages command imports hash preimages from an RLP encoded stream,
+as written by export-preimages, into the database of the node. Each preimage is
+stored under its Keccak256 hash, restoring the ability to resolve hashed trie
+keys, e.g. the storage keys shown by debuggers. If the file name ends with .gz,
+the stream is gunzipped.`,
 	}
 	exportPreimagesCommand = cli.Command{
 		Action:    utils.MigrateFlags(exportPreimages),
@@ -114,11 +157,15 @@ if already existing.`,
 		ArgsUsage: "<dumpfile>",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			utils.AncientFlag,
 			utils.CacheFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
-The export-preimages command export hash preimages to an RLP encoded stream`,
+The export-preimages command streams all the hash preimages of the database of
+the node into an RLP encoded file, so that they can be carried over to another
+node with import-preimages. If the file name ends with .gz, the output is
+gzipped.`,
 	}
 	copydbCommand = cli.Command{
 		Action:    utils.MigrateFlags(copyDb),
@@ -127,6 +174,7 @@ The export-preimages command export hash preimages to an RLP encoded stream`,
 		ArgsUsage: "<sourceChaindataDir>",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			utils.AncientFlag,
 			utils.CacheFlag,
 			utils.SyncModeFlag,
 		},
@@ -141,6 +189,7 @@ The first argument must be the directory containing the blockchain to download f
 		ArgsUsage: " ",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			utils.AncientFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
@@ -149,17 +198,43 @@ Remove blockchain and state databases`,
 	dumpCommand = cli.Command{
 		Action:    utils.MigrateFlags(dump),
 		Name:      "dump",
-		Usage:     "Dump a specific block from storage",
+		Usage:     "Dump the state at specific blocks from storage",
 		ArgsUsage: "[<blockHash> | <blockNum>]...",
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			utils.AncientFlag,
 			utils.CacheFlag,
+			utils.DumpFormatFlag,
 		},
 		Category: "BLOCKCHAIN COMMANDS",
 		Description: `
-The arguments are interpreted as block numbers or hashes.
+The arguments are interpreted as block numbers or hashes, the state of the head
+block is dumped if there are none. Accounts are streamed to the standard output
+one at a time, as a JSON object per line after the state root, or as an RLP
+stream of the state root followed by every account (--dump.format=rlp).
 Use "gvnt dump 0" to dump the genesis block.`,
 	}
+	rollbackCommand = cli.Command{
+		Action:    utils.MigrateFlags(rollback),
+		Name:      "rollback",
+		Usage:     "Rewind the local chain to an earlier block",
+		ArgsUsage: "<blockNum>",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.AncientFlag,
+			utils.CacheFlag,
+		},
+		Category: "BLOCKCHAIN COMMANDS",
+		Description: `
+The rollback command rewinds the canonical chain to the given block, deleting
+all the blocks above it along with their receipts and transaction lookup
+entries. It is meant for recovering from a bad import without wiping the whole
+database, the deleted blocks are downloaded again on the next sync.
+
+If the state of the block is not available (non-archive nodes), the chain is
+rewound further to the newest block that has its state. Blocks already moved
+into the ancient store can't be rolled back.`,
+	}
 )
 
 // initGenesis will initialise the given JSON format genesis file and writes it as
@@ -207,6 +282,20 @@ func initGenesis(ctx *cli.Context) error {
 	return nil
 }
 
+// validateGenesis checks the genesis file given as argument without writing it
+// into any database.
+func validateGenesis(ctx *cli.Context) error {
+	genesisPath := ctx.Args().First()
+	if len(genesisPath) == 0 {
+		utils.Fatalf("Must supply path to genesis JSON file")
+	}
+	if err := utils.ValidateGenesis(genesisPath); err != nil {
+		utils.Fatalf("%v", err)
+	}
+	fmt.Println("Genesis file is valid")
+	return nil
+}
+
 func importChain(ctx *cli.Context) error {
 	if len(ctx.Args()) < 1 {
 		utils.Fatalf("This command requires an argument.")
@@ -233,13 +322,14 @@ func importChain(ctx *cli.Context) error {
 	// Import the chain
 	start := time.Now()
 
+	receipts := ctx.GlobalBool(utils.ChainReceiptsFlag.Name)
 	if len(ctx.Args()) == 1 {
-		if err := utils.ImportChain(chain, ctx.Args().First()); err != nil {
+		if err := utils.ImportChain(chain, ctx.Args().First(), receipts); err != nil {
 			log.Error("Import error", "err", err)
 		}
 	} else {
 		for _, arg := range ctx.Args() {
-			if err := utils.ImportChain(chain, arg); err != nil {
+			if err := utils.ImportChain(chain, arg, receipts); err != nil {
 				log.Error("Import error", "file", arg, "err", err)
 			}
 		}
@@ -247,20 +337,12 @@ func importChain(ctx *cli.Context) error {
 	chain.Stop()
 	fmt.Printf("Import done in %v.\n\n", time.Since(start))
 
-	// Output pre-compaction stats mostly to see the import trashing
-	db := chainDb.(*vntdb.LDBDatabase)
-
-	stats, err := db.LDB().GetProperty("leveldb.stats")
-	if err != nil {
-		utils.Fatalf("Failed to read database stats: %v", err)
+	// Output pre-compaction stats mostly to see the import trashing, memory
+	// databases have no stats to show nor anything to compact
+	db, persistent := keyValueStore(chainDb).(*vntdb.LDBDatabase)
+	if persistent {
+		showLeveldbStats(db)
 	}
-	fmt.Println(stats)
-
-	ioStats, err := db.LDB().GetProperty("leveldb.iostats")
-	if err != nil {
-		utils.Fatalf("Failed to read database iostats: %v", err)
-	}
-	fmt.Println(ioStats)
 
 	fmt.Printf("Trie cache misses:  %d\n", trie.CacheMisses())
 	fmt.Printf("Trie cache unloads: %d\n\n", trie.CacheUnloads())
@@ -274,30 +356,19 @@ func importChain(ctx *cli.Context) error {
 	fmt.Printf("Allocations:   %.3f million\n", float64(mem.Mallocs)/1000000)
 	fmt.Printf("GC pause:      %v\n\n", time.Duration(mem.PauseTotalNs))
 
-	if ctx.GlobalIsSet(utils.NoCompactionFlag.Name) {
+	if ctx.GlobalIsSet(utils.NoCompactionFlag.Name) || !persistent {
 		return nil
 	}
 
 	// Compact the entire database to more accurately measure disk io and print the stats
 	start = time.Now()
 	fmt.Println("Compacting entire database...")
-	if err = db.LDB().CompactRange(util.Range{}); err != nil {
+	if err := db.LDB().CompactRange(util.Range{}); err != nil {
 		utils.Fatalf("Compaction failed: %v", err)
 	}
 	fmt.Printf("Compaction done in %v.\n\n", time.Since(start))
 
-	stats, err = db.LDB().GetProperty("leveldb.stats")
-	if err != nil {
-		utils.Fatalf("Failed to read database stats: %v", err)
-	}
-	fmt.Println(stats)
-
-	ioStats, err = db.LDB().GetProperty("leveldb.iostats")
-	if err != nil {
-		utils.Fatalf("Failed to read database iostats: %v", err)
-	}
-	fmt.Println(ioStats)
-
+	showLeveldbStats(db)
 	return nil
 }
 
@@ -309,10 +380,13 @@ func exportChain(ctx *cli.Context) error {
 	chain, _ := utils.MakeChain(ctx, stack)
 	start := time.Now()
 
-	var err error
-	fp := ctx.Args().First()
+	var (
+		err      error
+		fp       = ctx.Args().First()
+		receipts = ctx.GlobalBool(utils.ChainReceiptsFlag.Name)
+	)
 	if len(ctx.Args()) < 3 {
-		err = utils.ExportChain(chain, fp)
+		err = utils.ExportChain(chain, fp, receipts)
 	} else {
 		// This can be improved to allow for numbers larger than 9223372036854775807
 		first, ferr := strconv.ParseInt(ctx.Args().Get(1), 10, 64)
@@ -323,7 +397,7 @@ func exportChain(ctx *cli.Context) error {
 		if first < 0 || last < 0 {
 			utils.Fatalf("Export error: block number must be greater than 0\n")
 		}
-		err = utils.ExportAppendChain(chain, fp, uint64(first), uint64(last))
+		err = utils.ExportAppendChain(chain, fp, uint64(first), uint64(last), receipts)
 	}
 
 	if err != nil {
@@ -339,23 +413,24 @@ func importPreimages(ctx *cli.Context) error {
 		utils.Fatalf("This command requires an argument.")
 	}
 	stack := makeFullNode(ctx)
-	diskdb := utils.MakeChainDatabase(ctx, stack).(*vntdb.LDBDatabase)
+	diskdb := keyValueStore(utils.MakeChainDatabase(ctx, stack))
 
 	start := time.Now()
 	if err := utils.ImportPreimages(diskdb, ctx.Args().First()); err != nil {
-		utils.Fatalf("Export error: %v\n", err)
+		utils.Fatalf("Import error: %v\n", err)
 	}
-	fmt.Printf("Export done in %v\n", time.Since(start))
+	fmt.Printf("Import done in %v\n", time.Since(start))
 	return nil
 }
 
-// exportPreimages dumps the preimage data to specified json file in streaming way.
+// exportPreimages dumps the preimage data to the specified RLP file in a
+// streaming way.
 func exportPreimages(ctx *cli.Context) error {
 	if len(ctx.Args()) < 1 {
 		utils.Fatalf("This command requires an argument.")
 	}
 	stack := makeFullNode(ctx)
-	diskdb := utils.MakeChainDatabase(ctx, stack).(*vntdb.LDBDatabase)
+	diskdb := keyValueStore(utils.MakeChainDatabase(ctx, stack))
 
 	start := time.Now()
 	if err := utils.ExportPreimages(diskdb, ctx.Args().First()); err != nil {
@@ -405,7 +480,7 @@ func copyDb(ctx *cli.Context) error {
 	// Compact the entire database to remove any sync overhead
 	start = time.Now()
 	fmt.Println("Compacting entire database...")
-	if err = chainDb.(*vntdb.LDBDatabase).LDB().CompactRange(util.Range{}); err != nil {
+	if err = leveldbStore(chainDb).LDB().CompactRange(util.Range{}); err != nil {
 		utils.Fatalf("Compaction failed: %v", err)
 	}
 	fmt.Printf("Compaction done in %v.\n\n", time.Since(start))
@@ -413,14 +488,45 @@ func copyDb(ctx *cli.Context) error {
 	return nil
 }
 
+// keyValueStore returns the database holding the mutable chain data, looking
+// through the ancient store if one is attached.
+func keyValueStore(db vntdb.Database) vntdb.Database {
+	if frdb, ok := db.(*rawdb.FreezerDatabase); ok {
+		return frdb.KeyValueStore()
+	}
+	return db
+}
+
+// leveldbStore returns the LevelDB database holding the mutable chain data,
+// failing if the chain database is kept in memory.
+func leveldbStore(db vntdb.Database) *vntdb.LDBDatabase {
+	ldb, ok := keyValueStore(db).(*vntdb.LDBDatabase)
+	if !ok {
+		utils.Fatalf("Command not supported by memory databases, set a data directory")
+	}
+	return ldb
+}
+
 func removeDB(ctx *cli.Context) error {
-	stack, _ := makeConfigNode(ctx)
+	stack, cfg := makeConfigNode(ctx)
 
-	for _, name := range []string{"chaindata", "lightchaindata"} {
+	dbdirs := map[string]string{
+		"chaindata":      stack.ResolvePath("chaindata"),
+		"lightchaindata": stack.ResolvePath("lightchaindata"),
+	}
+	names := []string{"chaindata", "lightchaindata"}
+	if freezer := cfg.Vnt.DatabaseFreezer; freezer != "" {
+		if !filepath.IsAbs(freezer) {
+			freezer = stack.ResolvePath(freezer)
+		}
+		dbdirs["ancient"] = freezer
+		names = append(names, "ancient")
+	}
+	for _, name := range names {
 		// Ensure the database exists in the first place
 		logger := log.New("database", name)
 
-		dbdir := stack.ResolvePath(name)
+		dbdir := dbdirs[name]
 		if !common.FileExist(dbdir) {
 			logger.Info("Database doesn't exist, skipping", "path", dbdir)
 			continue
@@ -443,9 +549,22 @@ func removeDB(ctx *cli.Context) error {
 }
 
 func dump(ctx *cli.Context) error {
+	format := ctx.String(utils.DumpFormatFlag.Name)
+	if format != "json" && format != "rlp" {
+		utils.Fatalf("Option %q: unknown format %q", utils.DumpFormatFlag.Name, format)
+	}
 	stack := makeFullNode(ctx)
 	chain, chainDb := utils.MakeChain(ctx, stack)
-	for _, arg := range ctx.Args() {
+	defer chainDb.Close()
+
+	args := []string(ctx.Args())
+	if len(args) == 0 {
+		args = []string{chain.CurrentBlock().Number().String()}
+	}
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+
+	for _, arg := range args {
 		var block *types.Block
 		if hashish(arg) {
 			block = chain.GetBlockByHash(common.HexToHash(arg))
@@ -454,17 +573,52 @@ func dump(ctx *cli.Context) error {
 			block = chain.GetBlockByNumber(uint64(num))
 		}
 		if block == nil {
-			fmt.Println("{}")
-			utils.Fatalf("block not found")
+			utils.Fatalf("block %s not found", arg)
+		}
+		statedb, err := state.New(block.Root(), state.NewDatabase(chainDb))
+		if err != nil {
+			utils.Fatalf("could not create new state: %v", err)
+		}
+		if format == "rlp" {
+			err = statedb.RLPDump(out)
 		} else {
-			state, err := state.New(block.Root(), state.NewDatabase(chainDb))
-			if err != nil {
-				utils.Fatalf("could not create new state: %v", err)
-			}
-			fmt.Printf("%s\n", state.Dump())
+			err = statedb.IterativeDump(out)
+		}
+		if err != nil {
+			utils.Fatalf("Failed to dump state at block %s: %v", arg, err)
 		}
 	}
-	chainDb.Close()
+	return nil
+}
+
+// rollback rewinds the canonical chain of the local database to an earlier block.
+func rollback(ctx *cli.Context) error {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("This command requires a block number as argument")
+	}
+	number, err := strconv.ParseUint(ctx.Args().First(), 10, 64)
+	if err != nil {
+		utils.Fatalf("Invalid block number %q: %v", ctx.Args().First(), err)
+	}
+	stack := makeFullNode(ctx)
+	chain, chainDb := utils.MakeChain(ctx, stack)
+	defer chainDb.Close()
+
+	head := chain.CurrentBlock()
+	if number >= head.NumberU64() {
+		utils.Fatalf("Block #%d is not below the current head #%d", number, head.NumberU64())
+	}
+	if irreversible := chain.CurrentIrreversible(); number < irreversible.Number.Uint64() {
+		log.Warn("Rolling back past the irreversible block", "number", irreversible.Number, "hash", irreversible.Hash())
+	}
+	start := time.Now()
+	if err := chain.SetHead(number); err != nil {
+		utils.Fatalf("Rollback failed: %v", err)
+	}
+	chain.Stop()
+
+	head = chain.CurrentBlock()
+	fmt.Printf("Rolled back to block #%d [%x…] in %v\n", head.NumberU64(), head.Hash().Bytes()[:4], time.Since(start))
 	return nil
 }
 
@@ -32,6 +32,7 @@ import (
 	"github.com/vntchain/go-vnt/node"
 	"github.com/vntchain/go-vnt/params"
 	"github.com/vntchain/go-vnt/vnt"
+	"github.com/vntchain/go-vnt/vnt/downloader"
 	whisper "github.com/vntchain/go-vnt/whisper/whisperv6"
 )
 
@@ -40,10 +41,12 @@ var (
 		Action:      utils.MigrateFlags(dumpConfig),
 		Name:        "dumpconfig",
 		Usage:       "Show configuration values",
-		ArgsUsage:   "",
+		ArgsUsage:   "[<file>]",
 		Flags:       append(append(nodeFlags, rpcFlags...), whisperFlags...),
 		Category:    "MISCELLANEOUS COMMANDS",
-		Description: `The dumpconfig command shows configuration values.`,
+		Description: `The dumpconfig command shows configuration values, or saves them into the
+given file. The output can be loaded back with --config, with any flags given
+on the command line overriding the values in the file.`,
 	}
 
 	configFileFlag = cli.StringFlag{
@@ -164,6 +167,14 @@ func makeFullNode(ctx *cli.Context) *node.Node {
 		utils.RegisterShhService(stack, &cfg.Shh)
 	}
 
+	// Add the GraphQL query server if requested.
+	if ctx.GlobalBool(utils.GraphQLEnabledFlag.Name) {
+		if cfg.Vnt.SyncMode == downloader.LightSync {
+			utils.Fatalf("Light clients do not support the GraphQL server")
+		}
+		utils.RegisterGraphQLService(stack, utils.MakeGraphQLEndpoint(ctx))
+	}
+
 	// Add the VNT Stats daemon if requested.
 	if cfg.Ethstats.URL != "" {
 		utils.RegisterEthStatsService(stack, cfg.Ethstats.URL)
@@ -185,7 +196,14 @@ func dumpConfig(ctx *cli.Context) error {
 	if err != nil {
 		return err
 	}
-	io.WriteString(os.Stdout, comment)
-	os.Stdout.Write(out)
+	dump := os.Stdout
+	if file := ctx.Args().First(); file != "" {
+		if dump, err = os.OpenFile(file, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644); err != nil {
+			return err
+		}
+		defer dump.Close()
+	}
+	io.WriteString(dump, comment)
+	dump.Write(out)
 	return nil
 }
@@ -0,0 +1,57 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of go-vnt.
+//
+// go-vnt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-vnt is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-vnt. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Tests that a dumped configuration can be loaded back with --config, and that
+// flags given on the command line override the values loaded from the file.
+func TestConfigFileRoundtrip(t *testing.T) {
+	dir := tmpdir(t)
+	defer os.RemoveAll(dir)
+
+	var (
+		dumped = filepath.Join(dir, "dumped.toml")
+		reload = filepath.Join(dir, "reloaded.toml")
+	)
+	gvnt := runGvnt(t, "--maxpeers", "9", "--networkid", "77", "--vm.memcap", "5", "--gcmode", "archive", "dumpconfig", dumped)
+	gvnt.ExpectExit()
+
+	gvnt = runGvnt(t, "--config", dumped, "--maxpeers", "7", "dumpconfig", reload)
+	gvnt.ExpectExit()
+
+	var cfg gvntConfig
+	if err := loadConfig(reload, &cfg); err != nil {
+		t.Fatalf("failed to load reloaded config: %v", err)
+	}
+	if cfg.Node.P2P.MaxPeers != 7 {
+		t.Errorf("max peers not overridden: have %d, want %d", cfg.Node.P2P.MaxPeers, 7)
+	}
+	if cfg.Vnt.NetworkId != 77 {
+		t.Errorf("network id mismatch: have %d, want %d", cfg.Vnt.NetworkId, 77)
+	}
+	if cfg.Vnt.VMMemoryCap != 5*1024*1024 {
+		t.Errorf("memory cap mismatch: have %d, want %d", cfg.Vnt.VMMemoryCap, 5*1024*1024)
+	}
+	if !cfg.Vnt.NoPruning {
+		t.Errorf("archive mode lost")
+	}
+}
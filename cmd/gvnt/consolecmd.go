@@ -17,8 +17,10 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"os/signal"
 	"strings"
 	"syscall"
@@ -50,13 +52,16 @@ See https://github.com/vntchain/go-vnt/wiki/JavaScript-Console.`,
 		Name:      "attach",
 		Usage:     "Start an interactive JavaScript environment (connect to node)",
 		ArgsUsage: "[endpoint]",
-		Flags:     append(consoleFlags, utils.DataDirFlag),
+		Flags:     append(consoleFlags, utils.DataDirFlag, utils.AttachHeaderFlag, utils.AttachTLSCAFlag, utils.AttachTLSCertFlag, utils.AttachTLSKeyFlag, utils.AttachTLSInsecureFlag),
 		Category:  "CONSOLE COMMANDS",
 		Description: `
 The Gvnt console is an interactive shell for the JavaScript runtime environment
 which exposes a node admin interface as well as the Ðapp JavaScript API.
 See https://github.com/vntchain/go-vnt/wiki/JavaScript-Console.
-This command allows to open a console on a running gvnt node.`,
+This command allows to open a console on a running gvnt node.
+
+The endpoint may be an IPC path or an http://, https://, ws:// or wss:// URL.
+Remote endpoints accept custom headers (--header) and TLS settings (--tls.*).`,
 	}
 
 	javascriptCommand = cli.Command{
@@ -120,9 +125,12 @@ func remoteConsole(ctx *cli.Context) error {
 		if ctx.GlobalIsSet(utils.DataDirFlag.Name) {
 			path = ctx.GlobalString(utils.DataDirFlag.Name)
 		}
+		if path != "" && ctx.GlobalBool(utils.TestnetFlag.Name) {
+			path = filepath.Join(path, "testnet")
+		}
 		endpoint = fmt.Sprintf("%s/gvnt.ipc", path)
 	}
-	client, err := dialRPC(endpoint)
+	client, err := dialRPC(endpoint, utils.MakeDialOptions(ctx))
 	if err != nil {
 		utils.Fatalf("Unable to attach to remote gvnt: %v", err)
 	}
@@ -154,7 +162,7 @@ func remoteConsole(ctx *cli.Context) error {
 // dialRPC returns a RPC client which connects to the given endpoint.
 // The check for empty endpoint implements the defaulting logic
 // for "gvnt attach" and "gvnt monitor" with no argument.
-func dialRPC(endpoint string) (*rpc.Client, error) {
+func dialRPC(endpoint string, opts rpc.DialOptions) (*rpc.Client, error) {
 	if endpoint == "" {
 		endpoint = node.DefaultIPCEndpoint(clientIdentifier)
 	} else if strings.HasPrefix(endpoint, "rpc:") || strings.HasPrefix(endpoint, "ipc:") {
@@ -162,7 +170,7 @@ func dialRPC(endpoint string) (*rpc.Client, error) {
 		// these prefixes.
 		endpoint = endpoint[4:]
 	}
-	return rpc.Dial(endpoint)
+	return rpc.DialWithOptions(context.Background(), endpoint, opts)
 }
 
 // ephemeralConsole starts a new gvnt node, attaches an ephemeral JavaScript
@@ -0,0 +1,206 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of go-vnt.
+//
+// go-vnt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-vnt is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-vnt. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/syndtr/goleveldb/leveldb/util"
+	"github.com/vntchain/go-vnt/cmd/utils"
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/core/rawdb"
+	"github.com/vntchain/go-vnt/vntdb"
+	cli "gopkg.in/urfave/cli.v1"
+)
+
+var (
+	dbCommand = cli.Command{
+		Name:      "db",
+		Usage:     "Low level database operations",
+		ArgsUsage: "",
+		Category:  "DATABASE COMMANDS",
+		Subcommands: []cli.Command{
+			dbInspectCommand,
+			dbStatCommand,
+			dbCompactCommand,
+			dbRecoverCommand,
+		},
+	}
+	dbInspectCommand = cli.Command{
+		Action:    utils.MigrateFlags(inspectDB),
+		Name:      "inspect",
+		Usage:     "Inspect the storage size for each type of data in the database",
+		ArgsUsage: " ",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.AncientFlag,
+			utils.CacheFlag,
+		},
+		Description: `
+The inspect command iterates over the entire chain database and reports the
+number of entries and their total size for each category of data: headers,
+bodies, receipts, trie nodes, preimages and so on. Depending on the size of the
+database this may take a while.`,
+	}
+	dbStatCommand = cli.Command{
+		Action:    utils.MigrateFlags(dbStats),
+		Name:      "stat",
+		Usage:     "Print leveldb statistics",
+		ArgsUsage: " ",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.AncientFlag,
+			utils.CacheFlag,
+		},
+		Description: `
+The stat command prints the internal statistics of the leveldb chain database,
+such as the size and number of tables on each compaction level.`,
+	}
+	dbCompactCommand = cli.Command{
+		Action:    utils.MigrateFlags(dbCompact),
+		Name:      "compact",
+		Usage:     "Compact the leveldb database. WARNING: May take a very long time",
+		ArgsUsage: " ",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.AncientFlag,
+			utils.CacheFlag,
+		},
+		Description: `
+The compact command compacts the entire leveldb chain database, reclaiming the
+disk space of deleted and overwritten entries. The node must not be running.`,
+	}
+	dbRecoverCommand = cli.Command{
+		Action:    utils.MigrateFlags(dbRecover),
+		Name:      "recover",
+		Usage:     "Roll back blocks left partially written in the database",
+		ArgsUsage: " ",
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.AncientFlag,
+			utils.CacheFlag,
+		},
+		Description: `
+The recover command verifies that the header, body and receipts of every block
+of the canonical chain are present in the database. If any is missing, e.g. due
+to a crash in the middle of a write, the chain is rewound to the block preceding
+the oldest incomplete one, and the blocks above it are downloaded again on the
+next sync.
+
+Only the most recent blocks are verified on every startup of the node, this
+command checks the entire chain. The node must not be running.`,
+	}
+)
+
+// inspectDB reports the number and size of the database entries per category.
+func inspectDB(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	chainDb := utils.MakeChainDatabase(ctx, stack)
+	defer chainDb.Close()
+
+	start := time.Now()
+	stats, err := rawdb.InspectDatabase(chainDb)
+	if err != nil {
+		utils.Fatalf("Failed to inspect database: %v", err)
+	}
+	var (
+		total common.StorageSize
+		count uint64
+	)
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetAutoFormatHeaders(false)
+	table.SetHeader([]string{"Category", "Items", "Size"})
+	for _, stat := range stats {
+		table.Append([]string{stat.Category, fmt.Sprintf("%d", stat.Count), stat.Size.String()})
+		total += stat.Size
+		count += stat.Count
+	}
+	table.SetFooter([]string{"Total", fmt.Sprintf("%d", count), total.String()})
+	table.Render()
+
+	if frdb, ok := chainDb.(*rawdb.FreezerDatabase); ok {
+		fmt.Printf("Ancient store: %d blocks\n", frdb.Ancients())
+	}
+	fmt.Printf("Inspection done in %v\n", time.Since(start))
+	return nil
+}
+
+// dbStats prints the internal statistics of the leveldb chain database.
+func dbStats(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	chainDb := utils.MakeChainDatabase(ctx, stack)
+	defer chainDb.Close()
+
+	showLeveldbStats(leveldbStore(chainDb))
+	return nil
+}
+
+// dbCompact compacts the entire leveldb chain database.
+func dbCompact(ctx *cli.Context) error {
+	stack, _ := makeConfigNode(ctx)
+	chainDb := utils.MakeChainDatabase(ctx, stack)
+	defer chainDb.Close()
+
+	db := leveldbStore(chainDb)
+	showLeveldbStats(db)
+
+	start := time.Now()
+	fmt.Println("Compacting entire database...")
+	if err := db.LDB().CompactRange(util.Range{}); err != nil {
+		utils.Fatalf("Compaction failed: %v", err)
+	}
+	fmt.Printf("Compaction done in %v.\n\n", time.Since(start))
+
+	showLeveldbStats(db)
+	return nil
+}
+
+// showLeveldbStats prints the compaction and io statistics of a leveldb database.
+func showLeveldbStats(db *vntdb.LDBDatabase) {
+	for _, property := range []string{"leveldb.stats", "leveldb.iostats"} {
+		stats, err := db.Stat(property)
+		if err != nil {
+			utils.Fatalf("Failed to read database stats: %v", err)
+		}
+		fmt.Println(stats)
+	}
+}
+
+// dbRecover verifies the entire canonical chain, rewinding it to the last block
+// preceding the oldest incomplete one.
+func dbRecover(ctx *cli.Context) error {
+	stack := makeFullNode(ctx)
+	chain, chainDb := utils.MakeChain(ctx, stack)
+	defer chainDb.Close()
+
+	start := time.Now()
+	head, rewound, err := chain.Recover(0)
+	if err != nil {
+		utils.Fatalf("Recovery failed: %v", err)
+	}
+	chain.Stop()
+
+	if !rewound {
+		fmt.Printf("Database consistent up to block #%d, verified in %v\n", head, time.Since(start))
+		return nil
+	}
+	fmt.Printf("Rolled back to block #%d in %v\n", head, time.Since(start))
+	return nil
+}
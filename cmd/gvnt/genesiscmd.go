@@ -0,0 +1,289 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of go-vnt.
+//
+// go-vnt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-vnt is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-vnt. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vntchain/go-vnt/cmd/utils"
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/core"
+	"github.com/vntchain/go-vnt/params"
+	"github.com/vntchain/go-vnt/vntp2p"
+	cli "gopkg.in/urfave/cli.v1"
+)
+
+var (
+	genesisCommand = cli.Command{
+		Name:      "genesis",
+		Usage:     "Create genesis files for new networks",
+		ArgsUsage: "",
+		Category:  "BLOCKCHAIN COMMANDS",
+		Subcommands: []cli.Command{
+			genesisNewCommand,
+		},
+	}
+	genesisNewCommand = cli.Command{
+		Action:    utils.MigrateFlags(newGenesis),
+		Name:      "new",
+		Usage:     "Interactively create a new genesis file",
+		ArgsUsage: "<genesisPath>",
+		Description: `
+    gvnt genesis new <genesisPath>
+
+The new command walks through the settings of a new DPoS network: its chain and
+network ID, the block period, the initial witnesses with their node URLs, the
+missed slot penalty and the pre-funded accounts. The resulting genesis is checked
+like validate-genesis does before being written to the given path, which must
+not exist yet.`,
+	}
+)
+
+// newGenesis runs the genesis wizard and writes the outcome to the given path.
+func newGenesis(ctx *cli.Context) error {
+	path := ctx.Args().First()
+	if len(path) == 0 {
+		utils.Fatalf("Must supply path to write the genesis JSON file to")
+	}
+	if _, err := os.Stat(path); err == nil {
+		utils.Fatalf("Genesis file %s already exists", path)
+	}
+	wizard := &genesisWizard{in: bufio.NewReader(os.Stdin), out: os.Stdout}
+	genesis, err := wizard.makeGenesis()
+	if err != nil {
+		utils.Fatalf("Failed to configure genesis: %v", err)
+	}
+	if err := utils.CheckGenesis(genesis); err != nil {
+		utils.Fatalf("Invalid genesis: %v", err)
+	}
+	blob, err := json.MarshalIndent(genesis, "", "  ")
+	if err != nil {
+		utils.Fatalf("Failed to encode genesis: %v", err)
+	}
+	if err := ioutil.WriteFile(path, blob, 0644); err != nil {
+		utils.Fatalf("Failed to write genesis: %v", err)
+	}
+	fmt.Printf("\nGenesis written to %s\n", path)
+	fmt.Printf("Initialize every node with `gvnt init %s` and run it with --networkid %v\n", path, genesis.Config.ChainID)
+	return nil
+}
+
+// genesisWizard asks the user for the settings of a new network.
+type genesisWizard struct {
+	in  *bufio.Reader // Wrapper around the user input
+	out io.Writer     // Destination of the questions asked
+}
+
+// makeGenesis creates a new DPoS genesis based on the answers of the user.
+func (w *genesisWizard) makeGenesis() (*core.Genesis, error) {
+	genesis := &core.Genesis{
+		Timestamp:  uint64(time.Now().Unix()),
+		GasLimit:   params.GenesisGasLimit,
+		Difficulty: big.NewInt(1),
+		Alloc:      make(core.GenesisAlloc),
+		Config: &params.ChainConfig{
+			Dpos: new(params.DposConfig),
+		},
+	}
+	dpos := genesis.Config.Dpos
+
+	// Identify the network, any ID but the public ones will do
+	fmt.Fprintln(w.out)
+	fmt.Fprintln(w.out, "Which chain/network ID should the network use? (default = random)")
+	id, err := w.readDefaultUint(uint64(1024+rand.Intn(65536-1024)), 1)
+	if err != nil {
+		return nil, err
+	}
+	genesis.Config.ChainID = new(big.Int).SetUint64(id)
+
+	fmt.Fprintln(w.out)
+	fmt.Fprintln(w.out, "How many seconds should blocks take? (default = 2)")
+	if dpos.Period, err = w.readDefaultUint(2, 1); err != nil {
+		return nil, err
+	}
+	// Gather the initial witnesses along with their nodes
+	fmt.Fprintln(w.out)
+	fmt.Fprintf(w.out, "How many witnesses produce blocks? (odd, at least %d, default = %d)\n", utils.MinGenesisWitnesses, utils.MinGenesisWitnesses)
+	for {
+		count, err := w.readDefaultUint(utils.MinGenesisWitnesses, utils.MinGenesisWitnesses)
+		if err != nil {
+			return nil, err
+		}
+		if count%2 == 1 {
+			dpos.WitnessesNum = int(count)
+			break
+		}
+		fmt.Fprintln(w.out, "The witness count must be odd, please retry")
+	}
+	seen := make(map[common.Address]bool)
+	for i := 0; i < dpos.WitnessesNum; i++ {
+		fmt.Fprintln(w.out)
+		fmt.Fprintf(w.out, "Which account is witness #%d?\n", i+1)
+		var witness common.Address
+		for {
+			address, err := w.readAddress()
+			if err != nil {
+				return nil, err
+			}
+			if address == nil || *address == (common.Address{}) {
+				fmt.Fprintln(w.out, "A witness needs a non-zero address, please retry")
+				continue
+			}
+			if seen[*address] {
+				fmt.Fprintln(w.out, "Account is already a witness, please retry")
+				continue
+			}
+			witness = *address
+			break
+		}
+		seen[witness] = true
+
+		fmt.Fprintf(w.out, "What is the node URL of witness #%d? (/ip4/<ip>/tcp/<port>/ipfs/<node id>)\n", i+1)
+		url, err := w.readNodeURL()
+		if err != nil {
+			return nil, err
+		}
+		genesis.Witnesses = append(genesis.Witnesses, witness)
+		dpos.WitnessesUrl = append(dpos.WitnessesUrl, url)
+	}
+	fmt.Fprintln(w.out)
+	fmt.Fprintln(w.out, "How many slots in a row may a witness miss before losing its seat? (default = 0, never)")
+	if dpos.MaxMissedSlots, err = w.readDefaultUint(0, 0); err != nil {
+		return nil, err
+	}
+	fmt.Fprintln(w.out)
+	fmt.Fprintf(w.out, "How many block slots should an epoch between witness list updates last? (default = %d, %d rounds)\n", params.DefaultEpochRounds*dpos.WitnessesNum, params.DefaultEpochRounds)
+	if dpos.EpochLength, err = w.readDefaultUint(0, uint64(dpos.WitnessesNum)); err != nil {
+		return nil, err
+	}
+	fmt.Fprintln(w.out)
+	fmt.Fprintf(w.out, "How many seconds should voters wait between votes? (default = %d)\n", params.DefaultVotingWindow)
+	if dpos.VotingWindow, err = w.readDefaultUint(0, 1); err != nil {
+		return nil, err
+	}
+	fmt.Fprintln(w.out)
+	fmt.Fprintln(w.out, "Which block should Hubble come into effect? (default = 0)")
+	hubble, err := w.readDefaultUint(0, 0)
+	if err != nil {
+		return nil, err
+	}
+	genesis.Config.HubbleBlock = new(big.Int).SetUint64(hubble)
+
+	// Consensus all set, ask for the initial funds
+	fmt.Fprintln(w.out)
+	fmt.Fprintln(w.out, "Which accounts should be pre-funded? (empty line to finish)")
+	for {
+		address, err := w.readAddress()
+		if err != nil {
+			return nil, err
+		}
+		if address == nil {
+			break
+		}
+		fmt.Fprintf(w.out, "How many VNT should 0x%x hold? (default = 1000000000)\n", *address)
+		balance, err := w.readDefaultUint(1000000000, 1)
+		if err != nil {
+			return nil, err
+		}
+		genesis.Alloc[*address] = core.GenesisAccount{
+			Balance: new(big.Int).Mul(new(big.Int).SetUint64(balance), big.NewInt(params.Vnt)),
+		}
+		fmt.Fprintln(w.out, "Next account to pre-fund? (empty line to finish)")
+	}
+	fmt.Fprintln(w.out)
+	fmt.Fprintf(w.out, "What gas limit should the genesis block have? (default = %d)\n", params.GenesisGasLimit)
+	if genesis.GasLimit, err = w.readDefaultUint(params.GenesisGasLimit, params.MinGasLimit); err != nil {
+		return nil, err
+	}
+	return genesis, nil
+}
+
+// read reads a single line of input, trimming it from spaces.
+func (w *genesisWizard) read(prompt string) (string, error) {
+	fmt.Fprint(w.out, prompt)
+	text, err := w.in.ReadString('\n')
+	if err == io.EOF && text != "" {
+		err = nil
+	}
+	if err == io.EOF {
+		return "", errors.New("unexpected end of input")
+	}
+	return strings.TrimSpace(text), err
+}
+
+// readDefaultUint reads an unsigned integer no smaller than min. If an empty
+// line is entered, the default value is returned.
+func (w *genesisWizard) readDefaultUint(def, min uint64) (uint64, error) {
+	for {
+		text, err := w.read("> ")
+		if err != nil || text == "" {
+			return def, err
+		}
+		value, err := strconv.ParseUint(text, 10, 64)
+		if err != nil {
+			fmt.Fprintln(w.out, "Invalid number, please retry")
+			continue
+		}
+		if value < min {
+			fmt.Fprintf(w.out, "Number must be at least %d, please retry\n", min)
+			continue
+		}
+		return value, nil
+	}
+}
+
+// readAddress reads an account address, returning nil on an empty line.
+func (w *genesisWizard) readAddress() (*common.Address, error) {
+	for {
+		text, err := w.read("> 0x")
+		if err != nil || text == "" {
+			return nil, err
+		}
+		if !common.IsHexAddress(text) {
+			fmt.Fprintln(w.out, "Invalid address, please retry")
+			continue
+		}
+		address := common.HexToAddress(text)
+		return &address, nil
+	}
+}
+
+// readNodeURL reads the multiaddress of a node, enforcing it to parse.
+func (w *genesisWizard) readNodeURL() (string, error) {
+	for {
+		text, err := w.read("> ")
+		if err != nil {
+			return "", err
+		}
+		if _, err := vntp2p.ParseNode(text); err != nil {
+			fmt.Fprintf(w.out, "Invalid node URL (%v), please retry\n", err)
+			continue
+		}
+		return text, nil
+	}
+}
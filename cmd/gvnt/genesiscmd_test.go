@@ -0,0 +1,107 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of go-vnt.
+//
+// go-vnt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-vnt is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-vnt. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"io/ioutil"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/vntchain/go-vnt/cmd/utils"
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/params"
+)
+
+var wizardWitnessURLs = []string{
+	"/ip4/127.0.0.1/tcp/30303/ipfs/1kHGG8L1DTrVG3Cad479Q32oGmFAiEjLFwxzNyXH3ehGo73",
+	"/ip4/127.0.0.1/tcp/30303/ipfs/1kHGq5zZFRW5FBJ9YMbbvSiW4AzGg5CKMCtDeg6FNnjCbGS",
+	"/ip4/127.0.0.1/tcp/30303/ipfs/1kHJFKr2bzUnMr1NbeyYbYJa3RXT18cEu7cNDrHWjg8XYKB",
+}
+
+// Tests that the genesis wizard turns the answers, retrying on invalid ones,
+// into a valid genesis.
+func TestGenesisWizard(t *testing.T) {
+	input := strings.Join([]string{
+		"1234", // chain ID
+		"",     // default period
+		"4",    // even witness count, retried
+		"3",
+		"0x0000000000000000000000000000000000000000", "0100000000000000000000000000000000000000", wizardWitnessURLs[0], // zero address, retried
+		"0200000000000000000000000000000000000000", "invalid", wizardWitnessURLs[1], // invalid url, retried
+		"0200000000000000000000000000000000000000", "0300000000000000000000000000000000000000", wizardWitnessURLs[2], // duplicate, retried
+		"10", // max missed slots
+		"2",  // epoch shorter than a round, retried
+		"6",
+		"", // default voting window
+		"", // default hubble block
+		"aa00000000000000000000000000000000000000", "5",
+		"0xbb00000000000000000000000000000000000000", "",
+		"",       // end of allocations
+		"100",    // gas limit too low, retried
+		"800000", // gas limit
+	}, "\n") + "\n"
+
+	wizard := &genesisWizard{in: bufio.NewReader(strings.NewReader(input)), out: ioutil.Discard}
+	genesis, err := wizard.makeGenesis()
+	if err != nil {
+		t.Fatalf("failed to configure genesis: %v", err)
+	}
+	if err := utils.CheckGenesis(genesis); err != nil {
+		t.Fatalf("invalid genesis: %v", err)
+	}
+	if genesis.Config.ChainID.Uint64() != 1234 {
+		t.Errorf("chain ID mismatch: have %v, want %v", genesis.Config.ChainID, 1234)
+	}
+	dpos := genesis.Config.Dpos
+	if dpos.Period != 2 || dpos.WitnessesNum != 3 || dpos.MaxMissedSlots != 10 || dpos.EpochLength != 6 || dpos.VotingWindow != 0 {
+		t.Errorf("dpos config mismatch: have %+v", dpos)
+	}
+	want := []common.Address{{0x01}, {0x02}, {0x03}}
+	for i, witness := range genesis.Witnesses {
+		if witness != want[i] {
+			t.Errorf("witness %d mismatch: have %x, want %x", i, witness, want[i])
+		}
+		if dpos.WitnessesUrl[i] != wizardWitnessURLs[i] {
+			t.Errorf("witness url %d mismatch: have %s, want %s", i, dpos.WitnessesUrl[i], wizardWitnessURLs[i])
+		}
+	}
+	balances := map[common.Address]*big.Int{
+		{0xaa}: new(big.Int).Mul(big.NewInt(5), big.NewInt(params.Vnt)),
+		{0xbb}: new(big.Int).Mul(big.NewInt(1000000000), big.NewInt(params.Vnt)),
+	}
+	if len(genesis.Alloc) != len(balances) {
+		t.Errorf("allocation count mismatch: have %d, want %d", len(genesis.Alloc), len(balances))
+	}
+	for addr, balance := range balances {
+		if have := genesis.Alloc[addr].Balance; have == nil || have.Cmp(balance) != 0 {
+			t.Errorf("balance of %x mismatch: have %v, want %v", addr, have, balance)
+		}
+	}
+	if genesis.GasLimit != 800000 {
+		t.Errorf("gas limit mismatch: have %d, want %d", genesis.GasLimit, 800000)
+	}
+}
+
+// Tests that the genesis wizard fails when the input ends early.
+func TestGenesisWizardTruncated(t *testing.T) {
+	wizard := &genesisWizard{in: bufio.NewReader(strings.NewReader("1234\n2\n")), out: ioutil.Discard}
+	if _, err := wizard.makeGenesis(); err == nil {
+		t.Fatalf("truncated input accepted")
+	}
+}
@@ -20,6 +20,7 @@ package main
 import (
 	"fmt"
 	"math"
+	"math/big"
 	"os"
 	"runtime"
 	godebug "runtime/debug"
@@ -55,23 +56,31 @@ var (
 	nodeFlags = []cli.Flag{
 		utils.IdentityFlag,
 		utils.UnlockedAccountFlag,
+		utils.UnlockMaxFlag,
+		utils.InsecureUnlockAllowedFlag,
 		utils.PasswordFileFlag,
+		utils.ExternalSignerFlag,
+		utils.AccountAuditFlag,
 		utils.FindNodeFlag,
 		utils.VNTBootnodeFlag,
 		utils.BootnodesFlag,
 		utils.BootnodesV4Flag,
 		utils.BootnodesV5Flag,
 		utils.DataDirFlag,
+		utils.AncientFlag,
 		utils.KeyStoreDirFlag,
+		utils.NoUSBFlag,
 		// utils.EthashCacheDirFlag,
 		// utils.EthashCachesInMemoryFlag,
 		// utils.EthashCachesOnDiskFlag,
 		// utils.EthashDatasetDirFlag,
 		// utils.EthashDatasetsInMemoryFlag,
 		// utils.EthashDatasetsOnDiskFlag,
+		utils.TxPoolLocalsFlag,
 		utils.TxPoolNoLocalsFlag,
 		utils.TxPoolJournalFlag,
 		utils.TxPoolRejournalFlag,
+		utils.TxPoolJournalRemotesFlag,
 		utils.TxPoolPriceLimitFlag,
 		utils.TxPoolPriceBumpFlag,
 		utils.TxPoolAccountSlotsFlag,
@@ -79,37 +88,85 @@ var (
 		utils.TxPoolAccountQueueFlag,
 		utils.TxPoolGlobalQueueFlag,
 		utils.TxPoolLifetimeFlag,
+		utils.TxPoolDumpFlag,
 		utils.SyncModeFlag,
+		utils.StateWorkersFlag,
 		utils.GCModeFlag,
+		utils.TxLookupLimitFlag,
+		utils.StateHistoryFlag,
+		utils.NoPreimagesFlag,
+		utils.PruneScheduleFlag,
+		utils.BloomFilterSizeFlag,
 		utils.LightServFlag,
 		utils.LightPeersFlag,
+		utils.LightCheckpointFlag,
 		utils.LightKDFFlag,
+		utils.KeyStoreScryptNFlag,
+		utils.KeyStoreScryptPFlag,
 		utils.CacheFlag,
 		utils.CacheDatabaseFlag,
 		utils.CacheGCFlag,
+		utils.CacheSnapshotFlag,
+		utils.CachePrefetchFlag,
+		utils.CacheTrieMaxFlag,
+		utils.CacheBlocksFlag,
+		utils.CacheReceiptsFlag,
+		utils.CacheSendersFlag,
+		utils.SnapshotFlag,
+		utils.DatabaseStatsFlag,
+		utils.DatabaseReadReplicaFlag,
 		utils.TrieCacheGenFlag,
 		utils.ListenPortFlag,
+		utils.ListenIPFlag,
 		utils.MaxPeersFlag,
 		utils.MaxPendingPeersFlag,
+		utils.P2PMaxMessageSizeFlag,
+		utils.P2PMaxDownloadFlag,
+		utils.P2PMaxUploadFlag,
+		utils.P2PNoCompressionFlag,
 		utils.CoinbaseFlag,
+		utils.DposSignerFlag,
+		utils.DposStandbyFlag,
+		utils.DposStandbyMissesFlag,
+		utils.DposLockFlag,
+		utils.DposSlotToleranceFlag,
+		utils.DposMaxClockDriftFlag,
+		utils.DposSkipUnsyncedFlag,
+		utils.DposLogForkChoiceFlag,
 		utils.GasPriceFlag,
 		utils.ProducingEnabledFlag,
+		utils.TestnetFlag,
+		utils.DeveloperFlag,
+		utils.DeveloperPeriodFlag,
+		utils.DeveloperMemoryFlag,
 		utils.TargetGasLimitFlag,
 		utils.NATFlag,
+		utils.ProxyFlag,
 		utils.NoDiscoverFlag,
 		utils.DiscoveryV5Flag,
 		utils.NetrestrictFlag,
 		utils.NodeKeyFileFlag,
 		utils.NodeKeyHexFlag,
 		utils.VMEnableDebugFlag,
+		utils.VMMemCapFlag,
 		utils.NetworkIdFlag,
 		utils.RPCCORSDomainFlag,
 		utils.RPCVirtualHostsFlag,
 		utils.EthStatsURLFlag,
 		utils.MetricsEnabledFlag,
+		utils.MetricsHTTPFlag,
+		utils.MetricsPortFlag,
+		utils.MetricsEnableInfluxDBFlag,
+		utils.MetricsInfluxDBEndpointFlag,
+		utils.MetricsInfluxDBDatabaseFlag,
+		utils.MetricsInfluxDBUsernameFlag,
+		utils.MetricsInfluxDBPasswordFlag,
+		utils.MetricsInfluxDBTagsFlag,
 		utils.NoCompactionFlag,
 		utils.GpoBlocksFlag,
 		utils.GpoPercentileFlag,
+		utils.GpoMaxGasPriceFlag,
+		utils.GpoIgnoreGasPriceFlag,
 		utils.ExtraDataFlag,
 		configFileFlag,
 	}
@@ -119,11 +176,39 @@ var (
 		utils.RPCListenAddrFlag,
 		utils.RPCPortFlag,
 		utils.RPCApiFlag,
+		utils.RPCAllowMethodsFlag,
+		utils.RPCDenyMethodsFlag,
+		utils.RPCTLSCertFlag,
+		utils.RPCTLSKeyFlag,
+		utils.RPCTLSClientCAFlag,
+		utils.RPCWaitPeersFlag,
+		utils.RPCShutdownTimeoutFlag,
+		utils.RPCBodyLimitFlag,
+		utils.RPCBatchLimitFlag,
+		utils.RPCRateLimitFlag,
+		utils.RPCAllowUnprotectedTxsFlag,
+		utils.RPCGlobalGasCapFlag,
+		utils.RPCGlobalEVMTimeoutFlag,
+		utils.RPCBeamReadsFlag,
 		utils.WSEnabledFlag,
 		utils.WSListenAddrFlag,
 		utils.WSPortFlag,
 		utils.WSApiFlag,
+		utils.WSAllowMethodsFlag,
+		utils.WSDenyMethodsFlag,
 		utils.WSAllowedOriginsFlag,
+		utils.WSReadLimitFlag,
+		utils.WSCompressionFlag,
+		utils.WSTLSCertFlag,
+		utils.WSTLSKeyFlag,
+		utils.WSTLSClientCAFlag,
+		utils.AuthListenFlag,
+		utils.AuthPortFlag,
+		utils.AuthVirtualHostsFlag,
+		utils.JWTSecretFlag,
+		utils.GraphQLEnabledFlag,
+		utils.GraphQLListenAddrFlag,
+		utils.GraphQLPortFlag,
 		utils.IPCDisabledFlag,
 		utils.IPCPathFlag,
 	}
@@ -132,6 +217,12 @@ var (
 		utils.WhisperEnabledFlag,
 		utils.WhisperMaxMessageSizeFlag,
 		utils.WhisperMinPOWFlag,
+		utils.WhisperBloomFilterFlag,
+		utils.WhisperMailServerFlag,
+		utils.WhisperMailServerPasswordFlag,
+		utils.WhisperMailServerPoWFlag,
+		utils.WhisperLightClientFlag,
+		utils.WhisperRestrictLightClientsFlag,
 	}
 )
 
@@ -143,6 +234,7 @@ func init() {
 	app.Commands = []cli.Command{
 		// See chaincmd.go:
 		initCommand,
+		validateGenesisCommand,
 		importCommand,
 		exportCommand,
 		importPreimagesCommand,
@@ -150,6 +242,11 @@ func init() {
 		copydbCommand,
 		removedbCommand,
 		dumpCommand,
+		rollbackCommand,
+		// See genesiscmd.go:
+		genesisCommand,
+		// See dbcmd.go:
+		dbCommand,
 		// See monitorcmd.go:
 		monitorCommand,
 		// See accountcmd.go:
@@ -164,6 +261,12 @@ func init() {
 		licenseCommand,
 		// See config.go
 		dumpConfigCommand,
+		// See snapshot.go
+		snapshotCommand,
+		// See wasmcmd.go
+		wasmCommand,
+		// See bootnodecmd.go
+		bootnodeCommand,
 	}
 	sort.Sort(cli.CommandsByName(app.Commands))
 
@@ -196,6 +299,7 @@ func init() {
 
 		// Start system runtime metrics collection
 		go metrics.CollectProcessMetrics(3 * time.Second)
+		utils.SetupMetrics(ctx)
 
 		utils.SetupNetwork(ctx)
 		return nil
@@ -333,12 +437,23 @@ func startNode(ctx *cli.Context, stack *node.Node) {
 	// Start up the node itself
 	utils.StartNode(stack)
 
-	// Unlock any account specifically requested
+	// Start auditing account usage before any unlocks happen, if requested
 	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
+	if path := ctx.GlobalString(utils.AccountAuditFlag.Name); path != "" {
+		audit, err := utils.OpenAuditFile(path)
+		if err != nil {
+			utils.Fatalf("Failed to open account audit file: %v", err)
+		}
+		utils.EnableAccountAudit(ks, audit)
+	}
+	// Unlock any account specifically requested
 	passwords := utils.MakePasswordList(ctx)
-	unlocks := strings.Split(ctx.GlobalString(utils.UnlockedAccountFlag.Name), ",")
+	unlocks := utils.MakeUnlockList(ctx)
 	for i, account := range unlocks {
 		if trimmed := strings.TrimSpace(account); trimmed != "" {
+			if stack.Config().ExtRPCEnabled() && !stack.Config().InsecureUnlockAllowed {
+				utils.Fatalf("Account unlock with HTTP access is forbidden, use --%s to allow it", utils.InsecureUnlockAllowedFlag.Name)
+			}
 			unlockAccount(ctx, ks, trimmed, i, passwords)
 		}
 	}
@@ -383,8 +498,27 @@ func startNode(ctx *cli.Context, stack *node.Node) {
 			}
 		}
 	}()
+	// Snapshot the transaction pool if requested
+	if path := ctx.GlobalString(utils.TxPoolDumpFlag.Name); path != "" && ctx.GlobalString(utils.SyncModeFlag.Name) != "light" {
+		var vnt *vnt.VNT
+		if err := stack.Service(&vnt); err != nil {
+			utils.Fatalf("VNT service not running: %v", err)
+		}
+		if err := utils.DumpTxPoolFile(vnt.TxPool(), path); err != nil {
+			utils.Fatalf("Failed to dump transaction pool: %v", err)
+		}
+		log.Info("Dumped transaction pool", "path", path)
+	}
+	// Report resolved forks if requested
+	if ctx.GlobalBool(utils.DposLogForkChoiceFlag.Name) && ctx.GlobalString(utils.SyncModeFlag.Name) != "light" {
+		var vnt *vnt.VNT
+		if err := stack.Service(&vnt); err != nil {
+			utils.Fatalf("VNT service not running: %v", err)
+		}
+		utils.LogForkChoice(vnt.BlockChain(), vnt.Engine(), log.Root())
+	}
 	// Start auxiliary services if enabled
-	if ctx.GlobalBool(utils.ProducingEnabledFlag.Name) {
+	if ctx.GlobalBool(utils.ProducingEnabledFlag.Name) || ctx.GlobalBool(utils.DposStandbyFlag.Name) || ctx.GlobalBool(utils.DeveloperFlag.Name) {
 		// Producing only makes sense if a full VNT node is running
 		if ctx.GlobalString(utils.SyncModeFlag.Name) == "light" {
 			utils.Fatalf("Light clients do not support block producing")
@@ -395,7 +529,14 @@ func startNode(ctx *cli.Context, stack *node.Node) {
 		}
 
 		// Set the gas price to the limits from the CLI and start producing
-		vnt.TxPool().SetGasPrice(utils.GlobalBig(ctx, utils.GasPriceFlag.Name))
+		gasprice := utils.GlobalBig(ctx, utils.GasPriceFlag.Name)
+		if ctx.GlobalBool(utils.DeveloperFlag.Name) {
+			if !ctx.GlobalIsSet(utils.GasPriceFlag.Name) {
+				gasprice = new(big.Int)
+			}
+			vnt.Miner().SetSkipEmpty(true)
+		}
+		vnt.TxPool().SetGasPrice(gasprice)
 		if err := vnt.StartProducing(true); err != nil {
 			utils.Fatalf("Failed to start block producing: %v", err)
 		}
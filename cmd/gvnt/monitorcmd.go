@@ -75,7 +75,7 @@ func monitor(ctx *cli.Context) error {
 	)
 	// Attach to an VNT node over IPC or RPC
 	endpoint := ctx.String(monitorCommandAttachFlag.Name)
-	if client, err = dialRPC(endpoint); err != nil {
+	if client, err = dialRPC(endpoint, rpc.DialOptions{}); err != nil {
 		utils.Fatalf("Unable to attach to gvnt node: %v", err)
 	}
 	defer client.Close()
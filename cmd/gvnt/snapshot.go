@@ -0,0 +1,100 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of go-vnt.
+//
+// go-vnt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-vnt is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-vnt. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/syndtr/goleveldb/leveldb/util"
+	"github.com/vntchain/go-vnt/cmd/utils"
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/common/hexutil"
+	"github.com/vntchain/go-vnt/core/state/pruner"
+	cli "gopkg.in/urfave/cli.v1"
+)
+
+var (
+	snapshotCommand = cli.Command{
+		Name:        "snapshot",
+		Usage:       "A set of commands based on the state snapshot",
+		ArgsUsage:   "",
+		Category:    "MISCELLANEOUS COMMANDS",
+		Description: "",
+		Subcommands: []cli.Command{
+			{
+				Name:      "prune-state",
+				Usage:     "Prune stale state data not reachable from the recent chain",
+				ArgsUsage: "[<root>]",
+				Action:    utils.MigrateFlags(pruneState),
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.AncientFlag,
+					utils.CacheFlag,
+					utils.BloomFilterSizeFlag,
+				},
+				Description: `
+    gvnt snapshot prune-state [<root>]
+
+The prune-state command deletes the trie nodes and contract codes that are not
+reachable from the genesis state or the persisted states of the recent 128
+canonical blocks, then compacts the database. An additional state root to keep
+may be given as argument.
+
+Live entries are tracked in a bloom filter whose size is set by --bloomfilter.size;
+a larger filter lets fewer dangling entries survive.
+
+The node must not be running while pruning.`,
+			},
+		},
+	}
+)
+
+// pruneState deletes the state data not reachable from the retained states and
+// compacts the database to reclaim the disk space.
+func pruneState(ctx *cli.Context) error {
+	if len(ctx.Args()) > 1 {
+		utils.Fatalf("Too many arguments given")
+	}
+	var root common.Hash
+	if len(ctx.Args()) == 1 {
+		blob, err := hexutil.Decode(ctx.Args().First())
+		if err != nil || len(blob) != common.HashLength {
+			utils.Fatalf("Invalid state root %q", ctx.Args().First())
+		}
+		root = common.BytesToHash(blob)
+	}
+	stack, _ := makeConfigNode(ctx)
+	chainDb := utils.MakeChainDatabase(ctx, stack)
+	defer chainDb.Close()
+
+	prune, err := pruner.NewPruner(chainDb, ctx.GlobalUint64(utils.BloomFilterSizeFlag.Name)*1024*1024)
+	if err != nil {
+		utils.Fatalf("Failed to create state pruner: %v", err)
+	}
+	if err := prune.Prune(root); err != nil {
+		utils.Fatalf("Failed to prune state: %v", err)
+	}
+	// Compact the entire database to reclaim the space of the deleted entries
+	start := time.Now()
+	fmt.Println("Compacting entire database...")
+	if err := leveldbStore(chainDb).LDB().CompactRange(util.Range{}); err != nil {
+		utils.Fatalf("Compaction failed: %v", err)
+	}
+	fmt.Printf("Compaction done in %v.\n", time.Since(start))
+	return nil
+}
@@ -74,23 +74,37 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			configFileFlag,
 			utils.DataDirFlag,
+			utils.AncientFlag,
 			utils.KeyStoreDirFlag,
+			utils.NoUSBFlag,
 			utils.NetworkIdFlag,
+			utils.TestnetFlag,
 			utils.SyncModeFlag,
+			utils.StateWorkersFlag,
 			utils.GCModeFlag,
+			utils.TxLookupLimitFlag,
+			utils.StateHistoryFlag,
+			utils.NoPreimagesFlag,
+			utils.PruneScheduleFlag,
+			utils.BloomFilterSizeFlag,
 			utils.EthStatsURLFlag,
 			utils.IdentityFlag,
 			utils.LightServFlag,
 			utils.LightPeersFlag,
+			utils.LightCheckpointFlag,
 			utils.LightKDFFlag,
+			utils.KeyStoreScryptNFlag,
+			utils.KeyStoreScryptPFlag,
 		},
 	},
 	{
 		Name: "TRANSACTION POOL",
 		Flags: []cli.Flag{
+			utils.TxPoolLocalsFlag,
 			utils.TxPoolNoLocalsFlag,
 			utils.TxPoolJournalFlag,
 			utils.TxPoolRejournalFlag,
+			utils.TxPoolJournalRemotesFlag,
 			utils.TxPoolPriceLimitFlag,
 			utils.TxPoolPriceBumpFlag,
 			utils.TxPoolAccountSlotsFlag,
@@ -98,6 +112,7 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.TxPoolAccountQueueFlag,
 			utils.TxPoolGlobalQueueFlag,
 			utils.TxPoolLifetimeFlag,
+			utils.TxPoolDumpFlag,
 		},
 	},
 	{
@@ -106,6 +121,15 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.CacheFlag,
 			utils.CacheDatabaseFlag,
 			utils.CacheGCFlag,
+			utils.CacheSnapshotFlag,
+			utils.CachePrefetchFlag,
+			utils.CacheTrieMaxFlag,
+			utils.CacheBlocksFlag,
+			utils.CacheReceiptsFlag,
+			utils.CacheSendersFlag,
+			utils.SnapshotFlag,
+			utils.DatabaseStatsFlag,
+			utils.DatabaseReadReplicaFlag,
 			utils.TrieCacheGenFlag,
 		},
 	},
@@ -113,7 +137,11 @@ var AppHelpFlagGroups = []flagGroup{
 		Name: "ACCOUNT",
 		Flags: []cli.Flag{
 			utils.UnlockedAccountFlag,
+			utils.UnlockMaxFlag,
+			utils.InsecureUnlockAllowedFlag,
 			utils.PasswordFileFlag,
+			utils.AccountAuditFlag,
+			utils.ExternalSignerFlag,
 		},
 	},
 	{
@@ -123,11 +151,39 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.RPCListenAddrFlag,
 			utils.RPCPortFlag,
 			utils.RPCApiFlag,
+			utils.RPCAllowMethodsFlag,
+			utils.RPCDenyMethodsFlag,
+			utils.RPCTLSCertFlag,
+			utils.RPCTLSKeyFlag,
+			utils.RPCTLSClientCAFlag,
+			utils.RPCWaitPeersFlag,
+			utils.RPCShutdownTimeoutFlag,
+			utils.RPCBodyLimitFlag,
+			utils.RPCBatchLimitFlag,
+			utils.RPCRateLimitFlag,
+			utils.RPCAllowUnprotectedTxsFlag,
+			utils.RPCGlobalGasCapFlag,
+			utils.RPCGlobalEVMTimeoutFlag,
+			utils.RPCBeamReadsFlag,
 			utils.WSEnabledFlag,
 			utils.WSListenAddrFlag,
 			utils.WSPortFlag,
 			utils.WSApiFlag,
+			utils.WSAllowMethodsFlag,
+			utils.WSDenyMethodsFlag,
 			utils.WSAllowedOriginsFlag,
+			utils.WSReadLimitFlag,
+			utils.WSCompressionFlag,
+			utils.WSTLSCertFlag,
+			utils.WSTLSKeyFlag,
+			utils.WSTLSClientCAFlag,
+			utils.AuthListenFlag,
+			utils.AuthPortFlag,
+			utils.AuthVirtualHostsFlag,
+			utils.JWTSecretFlag,
+			utils.GraphQLEnabledFlag,
+			utils.GraphQLListenAddrFlag,
+			utils.GraphQLPortFlag,
 			utils.IPCDisabledFlag,
 			utils.IPCPathFlag,
 			utils.RPCCORSDomainFlag,
@@ -144,9 +200,15 @@ var AppHelpFlagGroups = []flagGroup{
 			utils.BootnodesV4Flag,
 			utils.BootnodesV5Flag,
 			utils.ListenPortFlag,
+			utils.ListenIPFlag,
 			utils.MaxPeersFlag,
 			utils.MaxPendingPeersFlag,
+			utils.P2PMaxMessageSizeFlag,
+			utils.P2PMaxDownloadFlag,
+			utils.P2PMaxUploadFlag,
+			utils.P2PNoCompressionFlag,
 			utils.NATFlag,
+			utils.ProxyFlag,
 			utils.NoDiscoverFlag,
 			utils.DiscoveryV5Flag,
 			utils.NetrestrictFlag,
@@ -158,7 +220,18 @@ var AppHelpFlagGroups = []flagGroup{
 		Name: "BLOCK PRODUCE",
 		Flags: []cli.Flag{
 			utils.ProducingEnabledFlag,
+			utils.DeveloperFlag,
+			utils.DeveloperPeriodFlag,
+			utils.DeveloperMemoryFlag,
 			utils.CoinbaseFlag,
+			utils.DposSignerFlag,
+			utils.DposStandbyFlag,
+			utils.DposStandbyMissesFlag,
+			utils.DposLockFlag,
+			utils.DposSlotToleranceFlag,
+			utils.DposMaxClockDriftFlag,
+			utils.DposSkipUnsyncedFlag,
+			utils.DposLogForkChoiceFlag,
 			utils.TargetGasLimitFlag,
 			utils.GasPriceFlag,
 			utils.ExtraDataFlag,
@@ -169,18 +242,29 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			utils.GpoBlocksFlag,
 			utils.GpoPercentileFlag,
+			utils.GpoMaxGasPriceFlag,
+			utils.GpoIgnoreGasPriceFlag,
 		},
 	},
 	{
 		Name: "VIRTUAL MACHINE",
 		Flags: []cli.Flag{
 			utils.VMEnableDebugFlag,
+			utils.VMMemCapFlag,
 		},
 	},
 	{
 		Name: "LOGGING AND DEBUGGING",
 		Flags: append([]cli.Flag{
 			utils.MetricsEnabledFlag,
+			utils.MetricsHTTPFlag,
+			utils.MetricsPortFlag,
+			utils.MetricsEnableInfluxDBFlag,
+			utils.MetricsInfluxDBEndpointFlag,
+			utils.MetricsInfluxDBDatabaseFlag,
+			utils.MetricsInfluxDBUsernameFlag,
+			utils.MetricsInfluxDBPasswordFlag,
+			utils.MetricsInfluxDBTagsFlag,
 			utils.NoCompactionFlag,
 		}, debug.Flags...),
 	},
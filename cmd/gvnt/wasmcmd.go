@@ -0,0 +1,172 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of go-vnt.
+//
+// go-vnt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-vnt is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-vnt. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/vntchain/go-vnt/cmd/utils"
+	"github.com/vntchain/go-vnt/common/hexutil"
+	"github.com/vntchain/go-vnt/core"
+	"github.com/vntchain/go-vnt/core/wavm"
+	wavmutils "github.com/vntchain/go-vnt/core/wavm/utils"
+	cli "gopkg.in/urfave/cli.v1"
+)
+
+// wasmMagic starts every raw WebAssembly module.
+var wasmMagic = []byte{0x00, 0x61, 0x73, 0x6d}
+
+var (
+	wasmCommand = cli.Command{
+		Name:      "wasm",
+		Usage:     "Inspect compiled WebAssembly contracts",
+		ArgsUsage: "",
+		Category:  "MISCELLANEOUS COMMANDS",
+		Subcommands: []cli.Command{
+			wasmValidateCommand,
+			wasmInspectCommand,
+		},
+	}
+	wasmValidateCommand = cli.Command{
+		Action:    utils.MigrateFlags(validateWasm),
+		Name:      "validate",
+		Usage:     "Check that a contract passes the validation done on deployment",
+		ArgsUsage: "<contract>",
+		Flags: []cli.Flag{
+			utils.WasmAbiFlag,
+		},
+		Description: `
+    gvnt wasm validate <contract>
+
+The validate command decodes the deploy code of a contract, links it against
+the host functions of the node, verifies and compiles it the same way contract
+creation does. It exits with an error if the deployment would be rejected.
+
+The contract is either the compressed deploy code, in binary or hex, or a raw
+WebAssembly module along with its ABI given by --abi.`,
+	}
+	wasmInspectCommand = cli.Command{
+		Action:    utils.MigrateFlags(inspectWasm),
+		Name:      "inspect",
+		Usage:     "List the functions of a contract and estimate its deploy gas",
+		ArgsUsage: "<contract>",
+		Flags: []cli.Flag{
+			utils.WasmAbiFlag,
+		},
+		Description: `
+    gvnt wasm inspect <contract>
+
+The inspect command validates a contract like the validate command, then lists
+the functions it exports and the host functions it imports, and estimates the
+gas needed to deploy it. The estimate covers the transaction data, the initial
+memory and the storage of the code, but not the execution of the constructor.`,
+	}
+)
+
+// loadContract reads the deploy code of the contract given on the command line.
+func loadContract(ctx *cli.Context) []byte {
+	if len(ctx.Args()) != 1 {
+		utils.Fatalf("This command requires a contract file as argument")
+	}
+	blob, err := ioutil.ReadFile(ctx.Args().First())
+	if err != nil {
+		utils.Fatalf("Failed to read contract: %v", err)
+	}
+	if text := strings.TrimSpace(string(blob)); strings.HasPrefix(text, "0x") {
+		if blob, err = hexutil.Decode(text); err != nil {
+			utils.Fatalf("Failed to decode contract: %v", err)
+		}
+	}
+	if !bytes.HasPrefix(blob, wasmMagic) {
+		if ctx.IsSet(utils.WasmAbiFlag.Name) {
+			utils.Fatalf("Option %q: only valid for raw WebAssembly modules", utils.WasmAbiFlag.Name)
+		}
+		return blob
+	}
+	if !ctx.IsSet(utils.WasmAbiFlag.Name) {
+		utils.Fatalf("Raw WebAssembly module given, its ABI must be set by --%s", utils.WasmAbiFlag.Name)
+	}
+	abi, err := ioutil.ReadFile(ctx.String(utils.WasmAbiFlag.Name))
+	if err != nil {
+		utils.Fatalf("Option %q: %v", utils.WasmAbiFlag.Name, err)
+	}
+	return wavmutils.CompressWasmAndAbi(abi, blob, nil)
+}
+
+// validateWasm checks that a contract would be accepted on deployment.
+func validateWasm(ctx *cli.Context) error {
+	info, err := wavm.Inspect(loadContract(ctx))
+	if err != nil {
+		utils.Fatalf("Invalid contract: %v", err)
+	}
+	for _, warning := range info.Warnings {
+		fmt.Printf("Warning: %s\n", warning)
+	}
+	fmt.Println("Contract is valid")
+	return nil
+}
+
+// inspectWasm reports the functions of a contract and the gas of its deployment.
+func inspectWasm(ctx *cli.Context) error {
+	code := loadContract(ctx)
+	info, err := wavm.Inspect(code)
+	if err != nil {
+		utils.Fatalf("Invalid contract: %v", err)
+	}
+	intrinsic, err := core.IntrinsicGas(code, true, false)
+	if err != nil {
+		utils.Fatalf("Failed to compute intrinsic gas: %v", err)
+	}
+	fmt.Printf("Constructor: %s\n", info.Constructor)
+
+	fmt.Println("\nExported functions:")
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetAutoFormatHeaders(false)
+	table.SetHeader([]string{"Name", "Index", "ABI", "Constant", "Payable"})
+	for _, fn := range info.Exports {
+		table.Append([]string{fn.Name, fmt.Sprintf("%d", fn.Index), yesNo(fn.Method), yesNo(fn.Constant), yesNo(fn.Payable)})
+	}
+	table.Render()
+
+	fmt.Println("\nImported host functions:")
+	for _, name := range info.Imports {
+		fmt.Printf("  %s\n", name)
+	}
+	if len(info.Warnings) > 0 {
+		fmt.Println("\nWarnings:")
+		for _, warning := range info.Warnings {
+			fmt.Printf("  %s\n", warning)
+		}
+	}
+	fmt.Printf("\nDeploy code size: %d bytes\n", len(code))
+	fmt.Printf("Stored code size: %d bytes\n", info.CodeSize)
+	fmt.Printf("Initial memory:   %d pages\n", info.Memory)
+	fmt.Printf("Deploy gas:       %d (intrinsic %d, creation %d, constructor not included)\n", intrinsic+info.DeployGas, intrinsic, info.DeployGas)
+	return nil
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
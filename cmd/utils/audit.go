@@ -0,0 +1,148 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of go-vnt.
+//
+// go-vnt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-vnt is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-vnt. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"time"
+
+	"github.com/vntchain/go-vnt/accounts/keystore"
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/event"
+	"github.com/vntchain/go-vnt/log"
+)
+
+// accountAuditRecord is a single entry of the account audit trail. It must never
+// contain any key material.
+type accountAuditRecord struct {
+	Time    time.Time      `json:"timestamp"`
+	Address common.Address `json:"address"`
+	Event   string         `json:"event"`
+	Caller  string         `json:"caller"`
+}
+
+// newAccountAuditRecord creates the audit record of a key event.
+func newAccountAuditRecord(ev keystore.KeyEvent) *accountAuditRecord {
+	return &accountAuditRecord{
+		Time:    ev.Time.UTC(),
+		Address: ev.Address,
+		Event:   string(ev.Kind),
+		Caller:  ev.Caller,
+	}
+}
+
+// EnableAccountAudit subscribes to the key events of the keystore, such as
+// unlocks, locks and signings, and appends a JSON record for each of them to w.
+// Unsubscribing the returned subscription stops the auditing.
+func EnableAccountAudit(ks *keystore.KeyStore, w io.Writer) event.Subscription {
+	events := make(chan keystore.KeyEvent, 128)
+	sub := ks.SubscribeKeyEvents(events)
+
+	go func() {
+		enc := json.NewEncoder(w)
+		for {
+			select {
+			case ev := <-events:
+				if err := enc.Encode(newAccountAuditRecord(ev)); err != nil {
+					log.Error("Failed to write account audit record", "err", err)
+				}
+			case <-sub.Err():
+				return
+			}
+		}
+	}()
+	return sub
+}
+
+// WriteAccountAudit appends a JSON audit record for each of the key events to w,
+// for commands auditing their key usage before exiting.
+func WriteAccountAudit(w io.Writer, events []keystore.KeyEvent) error {
+	enc := json.NewEncoder(w)
+	for _, ev := range events {
+		if err := enc.Encode(newAccountAuditRecord(ev)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AuditFile is an append-only file writer which transparently reopens its file
+// if it was moved or removed externally, e.g. by a log rotation tool. It is not
+// safe for concurrent use.
+type AuditFile struct {
+	path string
+	file *os.File
+}
+
+// OpenAuditFile opens (or creates) the audit file at the given path for appending.
+func OpenAuditFile(path string) (*AuditFile, error) {
+	f := &AuditFile{path: path}
+	if err := f.reopen(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// reopen closes the current file handle, if any, and opens the audit path anew.
+func (f *AuditFile) reopen() error {
+	if f.file != nil {
+		f.file.Close()
+	}
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		f.file = nil
+		return err
+	}
+	f.file = file
+	return nil
+}
+
+// Write appends p to the audit file, reopening it first if the file on disk is
+// no longer the one currently held open.
+func (f *AuditFile) Write(p []byte) (int, error) {
+	if f.file == nil {
+		if err := f.reopen(); err != nil {
+			return 0, err
+		}
+	} else if disk, err := os.Stat(f.path); err != nil || !sameFile(disk, f.file) {
+		if err := f.reopen(); err != nil {
+			return 0, err
+		}
+	}
+	return f.file.Write(p)
+}
+
+// Close closes the underlying audit file.
+func (f *AuditFile) Close() error {
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// sameFile reports whether the file info on disk describes the opened file.
+func sameFile(disk os.FileInfo, file *os.File) bool {
+	opened, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return os.SameFile(disk, opened)
+}
@@ -0,0 +1,133 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of go-vnt.
+//
+// go-vnt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-vnt is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-vnt. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Tests that unlocking and locking an account produces correctly shaped audit
+// records without leaking any key material.
+func TestAccountAuditRecords(t *testing.T) {
+	dir, ks := newTestKeyStore(t, 1)
+	defer os.RemoveAll(dir)
+
+	audit, err := OpenAuditFile(filepath.Join(dir, "audit.log"))
+	if err != nil {
+		t.Fatalf("failed to open audit file: %v", err)
+	}
+	defer audit.Close()
+
+	sub := EnableAccountAudit(ks, audit)
+	defer sub.Unsubscribe()
+
+	account := ks.Accounts()[0]
+	if err := ks.Unlock(account, "foo"); err != nil {
+		t.Fatalf("failed to unlock account: %v", err)
+	}
+	if err := ks.Lock(account.Address); err != nil {
+		t.Fatalf("failed to lock account: %v", err)
+	}
+	records := waitAuditRecords(t, filepath.Join(dir, "audit.log"), 2)
+
+	for i, want := range []string{"unlock", "lock"} {
+		record := records[i]
+		if len(record) != 4 {
+			t.Errorf("record %d: field count mismatch: have %v, want timestamp, address, event and caller", i, record)
+		}
+		if record["event"] != want {
+			t.Errorf("record %d: event mismatch: have %v, want %s", i, record["event"], want)
+		}
+		if !strings.EqualFold(record["address"].(string), account.Address.Hex()) {
+			t.Errorf("record %d: address mismatch: have %v, want %s", i, record["address"], account.Address.Hex())
+		}
+		if _, err := time.Parse(time.RFC3339Nano, record["timestamp"].(string)); err != nil {
+			t.Errorf("record %d: invalid timestamp %v: %v", i, record["timestamp"], err)
+		}
+		if caller := record["caller"].(string); !strings.Contains(caller, "TestAccountAuditRecords") {
+			t.Errorf("record %d: caller mismatch: have %s, want test function", i, caller)
+		}
+	}
+}
+
+// Tests that auditing continues into a new file if the old one was moved away.
+func TestAccountAuditRotation(t *testing.T) {
+	dir, ks := newTestKeyStore(t, 1)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "audit.log")
+	audit, err := OpenAuditFile(path)
+	if err != nil {
+		t.Fatalf("failed to open audit file: %v", err)
+	}
+	defer audit.Close()
+
+	sub := EnableAccountAudit(ks, audit)
+	defer sub.Unsubscribe()
+
+	account := ks.Accounts()[0]
+	if err := ks.Unlock(account, "foo"); err != nil {
+		t.Fatalf("failed to unlock account: %v", err)
+	}
+	waitAuditRecords(t, path, 1)
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatalf("failed to rotate audit file: %v", err)
+	}
+	if err := ks.Lock(account.Address); err != nil {
+		t.Fatalf("failed to lock account: %v", err)
+	}
+	if records := waitAuditRecords(t, path, 1); records[0]["event"] != "lock" {
+		t.Errorf("rotated record mismatch: have %v, want lock", records[0]["event"])
+	}
+	if records := waitAuditRecords(t, path+".1", 1); records[0]["event"] != "unlock" {
+		t.Errorf("original record mismatch: have %v, want unlock", records[0]["event"])
+	}
+}
+
+// waitAuditRecords waits until the audit file contains the requested number of
+// records, returning them decoded.
+func waitAuditRecords(t *testing.T, path string, count int) []map[string]interface{} {
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		blob, _ := ioutil.ReadFile(path)
+
+		var records []map[string]interface{}
+		scanner := bufio.NewScanner(strings.NewReader(string(blob)))
+		for scanner.Scan() {
+			record := make(map[string]interface{})
+			if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+				t.Fatalf("invalid audit record %q: %v", scanner.Text(), err)
+			}
+			records = append(records, record)
+		}
+		if len(records) >= count {
+			if len(records) > count {
+				t.Fatalf("audit record count mismatch: have %d, want %d", len(records), count)
+			}
+			return records
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for audit records: have %d, want %d", len(records), count)
+		}
+	}
+}
@@ -18,6 +18,7 @@
 package utils
 
 import (
+	"bufio"
 	"compress/gzip"
 	"fmt"
 	"io"
@@ -26,6 +27,7 @@ import (
 	"runtime"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/vntchain/go-vnt/common"
 	"github.com/vntchain/go-vnt/core"
@@ -85,7 +87,12 @@ func StartNode(stack *node.Node) {
 	}()
 }
 
-func ImportChain(chain *core.BlockChain, fn string) error {
+// ImportChain imports the blocks of an RLP stream into the chain, optionally
+// gzip compressed. If receipts is set, every block in the stream is expected to
+// be followed by its receipts, which are checked against the block before it is
+// imported. Blocks already present in the chain are skipped, so an interrupted
+// import can be resumed by running it again.
+func ImportChain(chain *core.BlockChain, fn string, receipts bool) error {
 	// Watch for Ctrl-C while the import is running.
 	// If a signal is received, the import will stop at the next batch.
 	interrupt := make(chan os.Signal, 1)
@@ -108,7 +115,7 @@ func ImportChain(chain *core.BlockChain, fn string) error {
 		}
 	}
 
-	log.Info("Importing blockchain", "file", fn)
+	log.Info("Importing blockchain", "file", fn, "receipts", receipts)
 
 	// Open the file handle and potentially unwrap the gzip stream
 	fh, err := os.Open(fn)
@@ -117,21 +124,25 @@ func ImportChain(chain *core.BlockChain, fn string) error {
 	}
 	defer fh.Close()
 
-	var reader io.Reader = fh
-	if strings.HasSuffix(fn, ".gz") {
-		if reader, err = gzip.NewReader(reader); err != nil {
-			return err
-		}
+	reader, err := newImportReader(fh)
+	if err != nil {
+		return err
 	}
 	stream := rlp.NewStream(reader, 0)
 
 	// Run actual the import.
-	blocks := make(types.Blocks, importBatchSize)
-	n := 0
+	var (
+		blocks   = make(types.Blocks, importBatchSize)
+		n        = 0
+		skipped  = 0
+		head     = chain.CurrentBlock().NumberU64()
+		start    = time.Now()
+		reported = time.Now()
+	)
 	for batch := 0; ; batch++ {
 		// Load a batch of RLP blocks.
 		if checkInterrupt() {
-			return fmt.Errorf("interrupted")
+			return interruptedImport(chain)
 		}
 		i := 0
 		for ; i < importBatchSize; i++ {
@@ -141,20 +152,42 @@ func ImportChain(chain *core.BlockChain, fn string) error {
 			} else if err != nil {
 				return fmt.Errorf("at block %d: %v", n, err)
 			}
+			if receipts {
+				var list []*types.ReceiptForStorage
+				if err := stream.Decode(&list); err != nil {
+					return fmt.Errorf("at block %d: receipts: %v", n, err)
+				}
+				if err := verifyReceipts(&b, list); err != nil {
+					return fmt.Errorf("at block %d: %v", n, err)
+				}
+			}
 			// don't import first block
 			if b.NumberU64() == 0 {
 				i--
 				continue
 			}
-			blocks[i] = &b
 			n++
+
+			// Fast forward over the blocks of a previous import, state is
+			// available at the head so only the presence of the block matters
+			if b.NumberU64() < head && chain.HasBlock(b.Hash(), b.NumberU64()) {
+				if skipped++; skipped%importBatchSize == 0 && checkInterrupt() {
+					return interruptedImport(chain)
+				}
+				i--
+				continue
+			}
+			blocks[i] = &b
 		}
 		if i == 0 {
 			break
 		}
 		// Import the batch.
 		if checkInterrupt() {
-			return fmt.Errorf("interrupted")
+			return interruptedImport(chain)
+		}
+		if skipped > 0 && batch == 0 {
+			log.Info("Resuming import", "skipped", skipped, "number", blocks[0].NumberU64())
 		}
 		missing := missingBlocks(chain, blocks[:i])
 		if len(missing) == 0 {
@@ -164,6 +197,46 @@ func ImportChain(chain *core.BlockChain, fn string) error {
 		if _, err := chain.InsertChain(missing); err != nil {
 			return fmt.Errorf("invalid block %d: %v", n, err)
 		}
+		if time.Since(reported) >= 8*time.Second {
+			log.Info("Importing blockchain", "blocks", n, "skipped", skipped, "number", blocks[i-1].NumberU64(), "elapsed", common.PrettyDuration(time.Since(start)))
+			reported = time.Now()
+		}
+	}
+	if skipped > 0 {
+		log.Info("Skipped blocks already present", "count", skipped)
+	}
+	return nil
+}
+
+// interruptedImport reports the point a resumed import will continue from.
+func interruptedImport(chain *core.BlockChain) error {
+	head := chain.CurrentBlock()
+	log.Info("Import interrupted, rerun to resume", "number", head.NumberU64(), "hash", head.Hash())
+	return fmt.Errorf("interrupted")
+}
+
+// newImportReader wraps the reader in a gzip stream if the data it holds is
+// gzip compressed, regardless of the name of the file.
+func newImportReader(r io.Reader) (io.Reader, error) {
+	buf := bufio.NewReader(r)
+	if magic, err := buf.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(buf)
+	}
+	return buf, nil
+}
+
+// verifyReceipts checks that the receipts of an export stream belong to the
+// block they accompany.
+func verifyReceipts(block *types.Block, list []*types.ReceiptForStorage) error {
+	if len(list) != len(block.Transactions()) {
+		return fmt.Errorf("receipt count mismatch: have %d, want %d", len(list), len(block.Transactions()))
+	}
+	receipts := make(types.Receipts, len(list))
+	for i, receipt := range list {
+		receipts[i] = (*types.Receipt)(receipt)
+	}
+	if hash := types.DeriveSha(receipts); hash != block.ReceiptHash() {
+		return fmt.Errorf("receipt root mismatch: have %x, want %x", hash, block.ReceiptHash())
 	}
 	return nil
 }
@@ -187,8 +260,9 @@ func missingBlocks(chain *core.BlockChain, blocks []*types.Block) []*types.Block
 }
 
 // ExportChain exports a blockchain into the specified file, truncating any data
-// already present in the file.
-func ExportChain(blockchain *core.BlockChain, fn string) error {
+// already present in the file. If receipts is set, each block is followed by its
+// receipts.
+func ExportChain(blockchain *core.BlockChain, fn string, receipts bool) error {
 	log.Info("Exporting blockchain", "file", fn)
 
 	// Open the file handle and potentially wrap with a gzip stream
@@ -204,7 +278,11 @@ func ExportChain(blockchain *core.BlockChain, fn string) error {
 		defer writer.(*gzip.Writer).Close()
 	}
 	// Iterate over the blocks and export them
-	if err := blockchain.Export(writer); err != nil {
+	export := blockchain.ExportN
+	if receipts {
+		export = blockchain.ExportReceiptsN
+	}
+	if err := export(writer, 0, blockchain.CurrentBlock().NumberU64()); err != nil {
 		return err
 	}
 	log.Info("Exported blockchain", "file", fn)
@@ -214,7 +292,7 @@ func ExportChain(blockchain *core.BlockChain, fn string) error {
 
 // ExportAppendChain exports a blockchain into the specified file, appending to
 // the file if data already exists in it.
-func ExportAppendChain(blockchain *core.BlockChain, fn string, first uint64, last uint64) error {
+func ExportAppendChain(blockchain *core.BlockChain, fn string, first uint64, last uint64, receipts bool) error {
 	log.Info("Exporting blockchain", "file", fn)
 
 	// Open the file handle and potentially wrap with a gzip stream
@@ -230,15 +308,20 @@ func ExportAppendChain(blockchain *core.BlockChain, fn string, first uint64, las
 		defer writer.(*gzip.Writer).Close()
 	}
 	// Iterate over the blocks and export them
-	if err := blockchain.ExportN(writer, first, last); err != nil {
+	export := blockchain.ExportN
+	if receipts {
+		export = blockchain.ExportReceiptsN
+	}
+	if err := export(writer, first, last); err != nil {
 		return err
 	}
 	log.Info("Exported blockchain to", "file", fn)
 	return nil
 }
 
-// ImportPreimages imports a batch of exported hash preimages into the database.
-func ImportPreimages(db *vntdb.LDBDatabase, fn string) error {
+// ImportPreimages imports a stream of exported hash preimages into the database,
+// verifying each of them against its hash.
+func ImportPreimages(db vntdb.Database, fn string) error {
 	log.Info("Importing preimages", "file", fn)
 
 	// Open the file handle and potentially unwrap the gzip stream
@@ -257,8 +340,18 @@ func ImportPreimages(db *vntdb.LDBDatabase, fn string) error {
 	stream := rlp.NewStream(reader, 0)
 
 	// Import the preimages in batches to prevent disk trashing
-	preimages := make(map[common.Hash][]byte)
-
+	var (
+		preimages = make(map[common.Hash][]byte)
+		count     int
+		start     = time.Now()
+		reported  = time.Now()
+	)
+	flush := func() error {
+		batch := db.NewBatch()
+		rawdb.WritePreimages(batch, 0, preimages)
+		preimages = make(map[common.Hash][]byte)
+		return batch.Write()
+	}
 	for {
 		// Read the next entry and ensure it's not junk
 		var blob []byte
@@ -267,25 +360,33 @@ func ImportPreimages(db *vntdb.LDBDatabase, fn string) error {
 			if err == io.EOF {
 				break
 			}
-			return err
+			return fmt.Errorf("preimage %d: %v", count, err)
 		}
-		// Accumulate the preimages and flush when enough ws gathered
-		preimages[crypto.Keccak256Hash(blob)] = common.CopyBytes(blob)
+		// Accumulate the preimages and flush when enough was gathered
+		preimages[crypto.Keccak256Hash(blob)] = blob
+		count++
+
 		if len(preimages) > 1024 {
-			rawdb.WritePreimages(db, 0, preimages)
-			preimages = make(map[common.Hash][]byte)
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		if time.Since(reported) >= 8*time.Second {
+			log.Info("Importing preimages", "count", count, "elapsed", common.PrettyDuration(time.Since(start)))
+			reported = time.Now()
 		}
 	}
 	// Flush the last batch preimage data
-	if len(preimages) > 0 {
-		rawdb.WritePreimages(db, 0, preimages)
+	if err := flush(); err != nil {
+		return err
 	}
+	log.Info("Imported preimages", "file", fn, "count", count, "elapsed", common.PrettyDuration(time.Since(start)))
 	return nil
 }
 
 // ExportPreimages exports all known hash preimages into the specified file,
 // truncating any data already present in the file.
-func ExportPreimages(db *vntdb.LDBDatabase, fn string) error {
+func ExportPreimages(db vntdb.Database, fn string) error {
 	log.Info("Exporting preimages", "file", fn)
 
 	// Open the file handle and potentially wrap with a gzip stream
@@ -301,12 +402,25 @@ func ExportPreimages(db *vntdb.LDBDatabase, fn string) error {
 		defer writer.(*gzip.Writer).Close()
 	}
 	// Iterate over the preimages and export them
-	it := db.NewIteratorWithPrefix([]byte("secure-key-"))
-	for it.Next() {
-		if err := rlp.Encode(writer, it.Value()); err != nil {
+	var (
+		count    int
+		start    = time.Now()
+		reported = time.Now()
+	)
+	err = rawdb.IteratePreimages(db, func(hash common.Hash, preimage []byte) error {
+		if err := rlp.Encode(writer, preimage); err != nil {
 			return err
 		}
+		count++
+		if time.Since(reported) >= 8*time.Second {
+			log.Info("Exporting preimages", "count", count, "elapsed", common.PrettyDuration(time.Since(start)))
+			reported = time.Now()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
-	log.Info("Exported preimages", "file", fn)
+	log.Info("Exported preimages", "file", fn, "count", count, "elapsed", common.PrettyDuration(time.Since(start)))
 	return nil
 }
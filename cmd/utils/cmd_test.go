@@ -0,0 +1,152 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of go-vnt.
+//
+// go-vnt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-vnt is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-vnt. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/consensus/mock"
+	"github.com/vntchain/go-vnt/core"
+	"github.com/vntchain/go-vnt/core/rawdb"
+	"github.com/vntchain/go-vnt/core/types"
+	"github.com/vntchain/go-vnt/core/vm"
+	"github.com/vntchain/go-vnt/crypto"
+	"github.com/vntchain/go-vnt/params"
+	"github.com/vntchain/go-vnt/vntdb"
+)
+
+// newExportChain creates a blockchain on top of the given genesis, inserting
+// the given blocks into it.
+func newExportChain(t *testing.T, gspec *core.Genesis, blocks types.Blocks) *core.BlockChain {
+	db := vntdb.NewMemDatabase()
+	gspec.MustCommit(db)
+
+	chain, err := core.NewBlockChain(db, nil, gspec.Config, mock.NewMock(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert block %d: %v", n, err)
+	}
+	return chain
+}
+
+// Tests that a chain exported along with its receipts into a gzip stream can be
+// imported again, resuming a partial import.
+func TestExportImportReceipts(t *testing.T) {
+	var (
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		gspec  = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{addr: {Balance: big.NewInt(1000000000)}},
+		}
+		signer = types.NewHubbleSigner(gspec.Config.ChainID)
+	)
+	db := vntdb.NewMemDatabase()
+	blocks, _ := core.GenerateChain(gspec.Config, gspec.MustCommit(db), mock.NewMock(), db, 10, func(i int, gen *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(gen.TxNonce(addr), common.Address{0x01}, big.NewInt(1000), params.TxGas, nil, nil), signer, key)
+		gen.AddTx(tx)
+	})
+	src := newExportChain(t, gspec, blocks)
+	defer src.Stop()
+
+	dir, err := ioutil.TempDir("", "gvnt-export")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The file name doesn't end with .gz, the compression must be detected
+	file := filepath.Join(dir, "chain.gz")
+	if err := ExportChain(src, file, true); err != nil {
+		t.Fatalf("failed to export chain: %v", err)
+	}
+	dump := filepath.Join(dir, "chain.dump")
+	if err := os.Rename(file, dump); err != nil {
+		t.Fatalf("failed to rename export: %v", err)
+	}
+	// Streams with receipts can't be imported as plain block streams
+	dst := newExportChain(t, gspec, blocks[:4])
+	defer dst.Stop()
+
+	if err := ImportChain(dst, dump, false); err == nil {
+		t.Fatalf("imported receipt stream as plain blocks")
+	}
+	// Resume the partial import and check the chain is complete
+	if err := ImportChain(dst, dump, true); err != nil {
+		t.Fatalf("failed to import chain: %v", err)
+	}
+	if have, want := dst.CurrentBlock().Hash(), src.CurrentBlock().Hash(); have != want {
+		t.Errorf("head mismatch: have %x, want %x", have, want)
+	}
+	for _, block := range blocks {
+		if have, want := len(dst.GetReceiptsByHash(block.Hash())), len(block.Transactions()); have != want {
+			t.Errorf("block %d: receipt count mismatch: have %d, want %d", block.NumberU64(), have, want)
+		}
+	}
+}
+
+// Tests that preimages exported into a gzip stream are restored by an import,
+// and that corrupt streams are rejected.
+func TestExportImportPreimages(t *testing.T) {
+	preimages := make(map[common.Hash][]byte)
+	for i := 0; i < 2000; i++ {
+		blob := common.BigToHash(big.NewInt(int64(i))).Bytes()
+		preimages[crypto.Keccak256Hash(blob)] = blob
+	}
+	src := vntdb.NewMemDatabase()
+	rawdb.WritePreimages(src, 0, preimages)
+	src.Put([]byte("unrelated"), []byte{0x01})
+
+	dir, err := ioutil.TempDir("", "gvnt-preimages")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "preimages.gz")
+	if err := ExportPreimages(src, file); err != nil {
+		t.Fatalf("failed to export preimages: %v", err)
+	}
+	dst := vntdb.NewMemDatabase()
+	if err := ImportPreimages(dst, file); err != nil {
+		t.Fatalf("failed to import preimages: %v", err)
+	}
+	if have, want := dst.Len(), len(preimages); have != want {
+		t.Errorf("imported entry count mismatch: have %d, want %d", have, want)
+	}
+	for hash, blob := range preimages {
+		if have := rawdb.ReadPreimage(dst, hash); !bytes.Equal(have, blob) {
+			t.Fatalf("preimage %x mismatch: have %x, want %x", hash, have, blob)
+		}
+	}
+	// Truncated streams fail to import
+	junk := filepath.Join(dir, "junk")
+	if err := ioutil.WriteFile(junk, []byte{0xb8, 0xff}, 0600); err != nil {
+		t.Fatalf("failed to write junk: %v", err)
+	}
+	if err := ImportPreimages(vntdb.NewMemDatabase(), junk); err == nil {
+		t.Errorf("truncated stream imported")
+	}
+}
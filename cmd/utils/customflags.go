@@ -219,8 +219,12 @@ func (self *DirectoryFlag) Set(value string) {
 // 1. replace tilde with users home dir
 // 2. expands embedded environment variables
 // 3. cleans the path, e.g. /a/b/../c -> /a/c
-// Note, it has limitations, e.g. ~someuser/tmp will not be expanded
+// Note, it has limitations, e.g. ~someuser/tmp will not be expanded. An empty
+// path stays empty, as it stands for ephemeral storage (e.g. --datadir "").
 func expandPath(p string) string {
+	if p == "" {
+		return ""
+	}
 	if strings.HasPrefix(p, "~/") || strings.HasPrefix(p, "~\\") {
 		if home := homeDir(); home != "" {
 			p = home + p[1:]
@@ -30,6 +30,7 @@ func TestPathExpansion(t *testing.T) {
 		"~thisOtherUser/b/":  "~thisOtherUser/b",
 		"$DDDXXX/a/b":        "/tmp/a/b",
 		"/a/b/":              "/a/b",
+		"":                   "",
 	}
 	os.Setenv("DDDXXX", "/tmp")
 	for test, expected := range tests {
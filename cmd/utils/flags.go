@@ -19,28 +19,44 @@ package utils
 
 import (
 	"crypto/ecdsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"math"
+	"math/big"
+	"net"
+	"net/http"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/vntchain/go-vnt/accounts"
 	"github.com/vntchain/go-vnt/accounts/keystore"
 	"github.com/vntchain/go-vnt/common"
 	"github.com/vntchain/go-vnt/common/fdlimit"
+	"github.com/vntchain/go-vnt/common/hexutil"
 	"github.com/vntchain/go-vnt/consensus"
 	"github.com/vntchain/go-vnt/consensus/dpos"
 	"github.com/vntchain/go-vnt/core"
+	"github.com/vntchain/go-vnt/core/rawdb"
 	"github.com/vntchain/go-vnt/core/state"
 	"github.com/vntchain/go-vnt/core/vm"
 	"github.com/vntchain/go-vnt/crypto"
+	"github.com/vntchain/go-vnt/graphql"
 	"github.com/vntchain/go-vnt/les"
 	"github.com/vntchain/go-vnt/log"
 	"github.com/vntchain/go-vnt/metrics"
+	"github.com/vntchain/go-vnt/metrics/exp"
+	"github.com/vntchain/go-vnt/metrics/influxdb"
 	"github.com/vntchain/go-vnt/node"
 	"github.com/vntchain/go-vnt/params"
+	"github.com/vntchain/go-vnt/rpc"
 	"github.com/vntchain/go-vnt/vnt"
 	"github.com/vntchain/go-vnt/vnt/downloader"
 	"github.com/vntchain/go-vnt/vnt/gasprice"
@@ -50,6 +66,7 @@ import (
 	cli "gopkg.in/urfave/cli.v1"
 
 	// "github.com/vntchain/go-vnt/vntp2p/netutil"
+	"github.com/vntchain/go-vnt/whisper/mailserver"
 	whisper "github.com/vntchain/go-vnt/whisper/whisperv6"
 )
 
@@ -122,15 +139,45 @@ var (
 		Usage: "Data directory for the databases and keystore",
 		Value: DirectoryString{node.DefaultDataDir()},
 	}
+	AncientFlag = DirectoryFlag{
+		Name:  "datadir.ancient",
+		Usage: "Data directory for ancient chain segments, moving old blocks out of the chain database (default = disabled)",
+	}
 	KeyStoreDirFlag = DirectoryFlag{
 		Name:  "keystore",
 		Usage: "Directory for the keystore (default = inside the datadir)",
 	}
+	NoUSBFlag = cli.BoolFlag{
+		Name:  "nousb",
+		Usage: "Disables monitoring for and managing USB hardware wallets",
+	}
+	ExternalSignerFlag = cli.StringFlag{
+		Name:  "signer",
+		Usage: "External signer (url or path to ipc file)",
+		Value: "",
+	}
 	NetworkIdFlag = cli.Uint64Flag{
 		Name:  "networkid",
 		Usage: "Network identifier (integer, 1=Frontier)",
 		Value: vnt.DefaultConfig.NetworkId,
 	}
+	TestnetFlag = cli.BoolFlag{
+		Name:  "testnet",
+		Usage: "Hubble network: pre-configured public test network",
+	}
+	DeveloperFlag = cli.BoolFlag{
+		Name:  "dev",
+		Usage: "Ephemeral single-node DPoS network with a pre-funded developer account, producing blocks for pending transactions",
+	}
+	DeveloperPeriodFlag = cli.Uint64Flag{
+		Name:  "dev.period",
+		Usage: "Block period to use in developer mode (seconds)",
+		Value: 1,
+	}
+	DeveloperMemoryFlag = cli.BoolFlag{
+		Name:  "dev.memory",
+		Usage: "Keep the chain databases in memory, discarding them on exit (the data directory still holds the keys)",
+	}
 	IdentityFlag = cli.StringFlag{
 		Name:  "identity",
 		Usage: "Custom node name",
@@ -143,14 +190,34 @@ var (
 	defaultSyncMode = vnt.DefaultConfig.SyncMode
 	SyncModeFlag    = TextMarshalerFlag{
 		Name:  "syncmode",
-		Usage: `Blockchain sync mode ("fast", "full", or "light")`,
+		Usage: `Blockchain sync mode ("fast", "full", "light" or "snap")`,
 		Value: &defaultSyncMode,
 	}
+	StateWorkersFlag = cli.IntFlag{
+		Name:  "syncmode.stateworkers",
+		Usage: "Maximum number of concurrent state sync requests during fast sync (default = unlimited)",
+	}
 	GCModeFlag = cli.StringFlag{
 		Name:  "gcmode",
 		Usage: `Blockchain garbage collection mode ("full", "archive")`,
 		Value: "full",
 	}
+	TxLookupLimitFlag = cli.Uint64Flag{
+		Name:  "txlookuplimit",
+		Usage: "Number of recent blocks to keep transaction lookup indices for (default = index all blocks)",
+	}
+	StateHistoryFlag = cli.Uint64Flag{
+		Name:  "history.state",
+		Usage: "Number of recent blocks to retain the state of in full GC mode, persisted at shutdown and reclaimed by state pruning (default = 128)",
+	}
+	NoPreimagesFlag = cli.BoolFlag{
+		Name:  "nopreimages",
+		Usage: "Disable recording the preimages of hashed trie keys (debuggers can't resolve storage keys any more)",
+	}
+	PruneScheduleFlag = cli.StringFlag{
+		Name:  "prune.schedule",
+		Usage: `Online state pruning schedule, an interval ("6h") or daily times ("03:00,15:00")`,
+	}
 	LightServFlag = cli.IntFlag{
 		Name:  "lightserv",
 		Usage: "Maximum percentage of time allowed for serving LES requests (0-90)",
@@ -161,11 +228,27 @@ var (
 		Usage: "Maximum number of LES client peers",
 		Value: vnt.DefaultConfig.LightPeers,
 	}
+	LightCheckpointFlag = cli.StringFlag{
+		Name:  "les.checkpoint",
+		Usage: "Trusted checkpoint to start light syncing from, a JSON file or an http(s) URL (default = hardcoded for the chain)",
+	}
 	LightKDFFlag = cli.BoolFlag{
 		Name:  "lightkdf",
 		Usage: "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
 	}
+	KeyStoreScryptNFlag = cli.IntFlag{
+		Name:  "keystore.scrypt.n",
+		Usage: "Scrypt N parameter of the keystore KDF, a power of two (0 = preset)",
+	}
+	KeyStoreScryptPFlag = cli.IntFlag{
+		Name:  "keystore.scrypt.p",
+		Usage: "Scrypt P parameter of the keystore KDF (0 = preset)",
+	}
 	// Transaction pool settings
+	TxPoolLocalsFlag = cli.StringFlag{
+		Name:  "txpool.locals",
+		Usage: "Comma separated accounts to treat as locals (no flush, priority inclusion)",
+	}
 	TxPoolNoLocalsFlag = cli.BoolFlag{
 		Name:  "txpool.nolocals",
 		Usage: "Disables price exemptions for locally submitted transactions",
@@ -180,6 +263,10 @@ var (
 		Usage: "Time interval to regenerate the local transaction journal",
 		Value: core.DefaultTxPoolConfig.Rejournal,
 	}
+	TxPoolJournalRemotesFlag = cli.BoolFlag{
+		Name:  "txpool.journalremotes",
+		Usage: "Journal remote transactions too, next to the local journal, to survive node restarts",
+	}
 	TxPoolPriceLimitFlag = cli.Uint64Flag{
 		Name:  "txpool.pricelimit",
 		Usage: "Minimum gas price limit to enforce for acceptance into the pool",
@@ -215,6 +302,10 @@ var (
 		Usage: "Maximum amount of time non-executable transaction are queued",
 		Value: vnt.DefaultConfig.TxPool.Lifetime,
 	}
+	TxPoolDumpFlag = cli.StringFlag{
+		Name:  "txpool.dump",
+		Usage: "Write a JSON snapshot of the pending and queued transactions to this file once the node started",
+	}
 	// Performance tuning settings
 	CacheFlag = cli.IntFlag{
 		Name:  "cache",
@@ -231,6 +322,65 @@ var (
 		Usage: "Percentage of cache memory allowance to use for trie pruning",
 		Value: 25,
 	}
+	CacheSnapshotFlag = cli.IntFlag{
+		Name:  "cache.snapshot",
+		Usage: "Percentage of cache memory allowance to use for snapshot caching (requires --snapshot)",
+		Value: 10,
+	}
+	CachePrefetchFlag = cli.IntFlag{
+		Name:  "cache.prefetch",
+		Usage: "Percentage of cache memory allowance to use for trie nodes warmed by the block prefetcher (0 = prefetching disabled)",
+		Value: 10,
+	}
+	SnapshotFlag = cli.BoolFlag{
+		Name:  "snapshot",
+		Usage: "Enables the flat state snapshot for faster account and storage reads",
+	}
+	CacheTrieMaxFlag = cli.IntFlag{
+		Name:  "cache.trie.max",
+		Usage: "Megabytes of memory the trie cache may use at most, regardless of --cache.gc (0 = no cap)",
+	}
+	CacheBlocksFlag = cli.IntFlag{
+		Name:  "cache.blocks",
+		Usage: "Number of recent blocks and block bodies to keep in memory",
+		Value: 256,
+	}
+	CacheReceiptsFlag = cli.IntFlag{
+		Name:  "cache.receipts",
+		Usage: "Number of recent block receipts to keep in memory",
+		Value: 32,
+	}
+	CacheSendersFlag = cli.IntFlag{
+		Name:  "cache.senders",
+		Usage: "Number of recent transaction senders to keep in memory, sparing their signature recovery",
+		Value: 16384,
+	}
+	DatabaseStatsFlag = cli.DurationFlag{
+		Name:  "db.stats",
+		Usage: "Interval of periodic database statistics reporting (0 = disabled)",
+	}
+	BloomFilterSizeFlag = cli.Uint64Flag{
+		Name:  "bloomfilter.size",
+		Usage: "Megabytes of memory allocated to bloom-filter for pruning",
+		Value: 2048,
+	}
+	ChainReceiptsFlag = cli.BoolFlag{
+		Name:  "receipts",
+		Usage: "Export or import the receipts of every block along with it",
+	}
+	DumpFormatFlag = cli.StringFlag{
+		Name:  "dump.format",
+		Usage: `Format of the dumped state ("json", "rlp")`,
+		Value: "json",
+	}
+	WasmAbiFlag = cli.StringFlag{
+		Name:  "abi",
+		Usage: "ABI file of a raw WebAssembly contract",
+	}
+	DatabaseReadReplicaFlag = DirectoryFlag{
+		Name:  "db.readreplica",
+		Usage: "Secondary chain database to serve RPC state reads from, falling back to the primary on misses",
+	}
 	TrieCacheGenFlag = cli.IntFlag{
 		Name:  "trie-cache-gens",
 		Usage: "Number of trie node generations to keep in memory",
@@ -251,6 +401,39 @@ var (
 		Usage: "Public address for block producing and witness rewards (default = first account created)",
 		Value: "0",
 	}
+	DposSignerFlag = cli.StringFlag{
+		Name:  "dpos.signer",
+		Usage: "Witness account signing the produced blocks, if different from the coinbase (default = coinbase)",
+	}
+	DposStandbyFlag = cli.BoolFlag{
+		Name:  "dpos.standby",
+		Usage: "Run as a standby witness node, producing only while the primary node with the same signer misses its rounds (requires --dpos.lock)",
+	}
+	DposStandbyMissesFlag = cli.Uint64Flag{
+		Name:  "dpos.standby.misses",
+		Usage: "Number of consecutive rounds the primary witness node may miss before the standby takes over",
+		Value: vnt.DefaultConfig.StandbyMisses,
+	}
+	DposLockFlag = cli.StringFlag{
+		Name:  "dpos.lock",
+		Usage: "Lock file shared by the primary and standby witness nodes, held by the one producing blocks (advisory, not a fencing mechanism)",
+	}
+	DposSlotToleranceFlag = cli.DurationFlag{
+		Name:  "dpos.slottolerance",
+		Usage: "Minimum time left before an own slot to produce in it, closer slots are skipped (0 = produce until the slot starts)",
+	}
+	DposMaxClockDriftFlag = cli.DurationFlag{
+		Name:  "dpos.maxdrift",
+		Usage: "Maximum time a block may be ahead of the local clock before being deferred (0 = unchecked)",
+	}
+	DposSkipUnsyncedFlag = cli.BoolFlag{
+		Name:  "dpos.skipunsynced",
+		Usage: "Skip own slots while the chain is being synchronised with peers",
+	}
+	DposLogForkChoiceFlag = cli.BoolFlag{
+		Name:  "dpos.logforkchoice",
+		Usage: "Log the competing branch heads, their witnesses and the deciding rule whenever a fork is resolved",
+	}
 	GasPriceFlag = BigFlag{
 		Name:  "gasprice",
 		Usage: "Minimal gas price to accept for producing a transactions",
@@ -266,16 +449,49 @@ var (
 		Usage: "Comma separated list of accounts to unlock",
 		Value: "",
 	}
+	UnlockMaxFlag = cli.IntFlag{
+		Name:  "unlock.max",
+		Usage: "Maximum number of accounts --unlock may unlock in one start (0 = unlimited)",
+		Value: 0,
+	}
+	InsecureUnlockAllowedFlag = cli.BoolFlag{
+		Name:  "allow-insecure-unlock",
+		Usage: "Allow insecure account unlocking when account-related RPCs are exposed by http",
+	}
 	PasswordFileFlag = cli.StringFlag{
 		Name:  "password",
 		Usage: "Password file to use for non-interactive password input",
 		Value: "",
 	}
+	MnemonicFlag = cli.BoolFlag{
+		Name:  "mnemonic",
+		Usage: "Import an account derived from a BIP-39 mnemonic instead of a key file",
+	}
+	HDPathFlag = cli.StringFlag{
+		Name:  "hdpath",
+		Usage: "HD derivation path of the account imported from a mnemonic (default = next account missing from the keystore)",
+	}
+	HDPasswordFileFlag = cli.StringFlag{
+		Name:  "hdpassword",
+		Usage: "Password file holding the BIP-39 passphrase of the imported mnemonic (default = none)",
+	}
+	KeyArchiveFlag = cli.BoolFlag{
+		Name:  "archive",
+		Usage: "Import the accounts of a key archive created by account export instead of a key file",
+	}
+	AccountAuditFlag = cli.StringFlag{
+		Name:  "accounts.audit",
+		Usage: "File to append account unlock, lock, signing, export and import audit records to",
+	}
 
 	VMEnableDebugFlag = cli.BoolFlag{
 		Name:  "vmdebug",
 		Usage: "Record information useful for VM and contract debugging",
 	}
+	VMMemCapFlag = cli.Uint64Flag{
+		Name:  "vm.memcap",
+		Usage: "Maximum memory in megabytes a single block's execution may allocate, stopping the node if a block needs more (0 = uncapped)",
+	}
 	// Logging and debug settings
 	EthStatsURLFlag = cli.StringFlag{
 		Name:  "ethstats",
@@ -285,6 +501,43 @@ var (
 		Name:  metrics.MetricsEnabledFlag,
 		Usage: "Enable metrics collection and reporting",
 	}
+	MetricsHTTPFlag = cli.StringFlag{
+		Name:  "metrics.addr",
+		Usage: "Enable stand-alone metrics HTTP server listening interface (requires --metrics)",
+		Value: "",
+	}
+	MetricsPortFlag = cli.IntFlag{
+		Name:  "metrics.port",
+		Usage: "Metrics HTTP server listening port",
+		Value: 6060,
+	}
+	MetricsEnableInfluxDBFlag = cli.BoolFlag{
+		Name:  "metrics.influxdb",
+		Usage: "Enable metrics export/push to an external InfluxDB database (requires --metrics)",
+	}
+	MetricsInfluxDBEndpointFlag = cli.StringFlag{
+		Name:  "metrics.influxdb.endpoint",
+		Usage: "InfluxDB API endpoint to report metrics to",
+		Value: "http://localhost:8086",
+	}
+	MetricsInfluxDBDatabaseFlag = cli.StringFlag{
+		Name:  "metrics.influxdb.database",
+		Usage: "InfluxDB database name to push reported metrics to",
+		Value: "gvnt",
+	}
+	MetricsInfluxDBUsernameFlag = cli.StringFlag{
+		Name:  "metrics.influxdb.username",
+		Usage: "Username to authorize access to the InfluxDB database",
+	}
+	MetricsInfluxDBPasswordFlag = cli.StringFlag{
+		Name:  "metrics.influxdb.password",
+		Usage: "Password to authorize access to the InfluxDB database",
+	}
+	MetricsInfluxDBTagsFlag = cli.StringFlag{
+		Name:  "metrics.influxdb.tags",
+		Usage: "Comma-separated InfluxDB tags (key=value) attached to all measurements",
+		Value: "host=localhost",
+	}
 	NoCompactionFlag = cli.BoolFlag{
 		Name:  "nocompaction",
 		Usage: "Disables db compaction after import",
@@ -314,11 +567,71 @@ var (
 		Usage: "Comma separated list of virtual hostnames from which to accept requests (server enforced). Accepts '*' wildcard.",
 		Value: strings.Join(node.DefaultConfig.HTTPVirtualHosts, ","),
 	}
+	RPCTLSCertFlag = cli.StringFlag{
+		Name:  "rpc.tls.cert",
+		Usage: "PEM file of the certificate to serve the HTTP-RPC interface over HTTPS with (requires --rpc.tls.key)",
+	}
+	RPCTLSKeyFlag = cli.StringFlag{
+		Name:  "rpc.tls.key",
+		Usage: "PEM file of the HTTP-RPC certificate's private key",
+	}
+	RPCTLSClientCAFlag = cli.StringFlag{
+		Name:  "rpc.tls.clientca",
+		Usage: "PEM file of the certificate authorities HTTP-RPC clients must present a certificate of (requires --rpc.tls.cert)",
+	}
 	RPCApiFlag = cli.StringFlag{
 		Name:  "rpcapi",
 		Usage: "API's offered over the HTTP-RPC interface",
 		Value: "",
 	}
+	RPCAllowMethodsFlag = cli.StringFlag{
+		Name:  "rpc.allowmethods",
+		Usage: "Comma separated methods (e.g. vnt_call, debug_*) exclusively served over the HTTP-RPC interface",
+	}
+	RPCDenyMethodsFlag = cli.StringFlag{
+		Name:  "rpc.denymethods",
+		Usage: "Comma separated methods (e.g. vnt_sendTransaction, personal_*) never served over the HTTP-RPC interface",
+	}
+	RPCWaitPeersFlag = cli.IntFlag{
+		Name:  "rpc.waitpeers",
+		Usage: "Minimum number of peers to wait for before opening the HTTP and WebSocket RPC endpoints (0 = open immediately)",
+		Value: 0,
+	}
+	RPCShutdownTimeoutFlag = cli.DurationFlag{
+		Name:  "rpc.shutdowntimeout",
+		Usage: "Maximum time to let in-flight RPC requests finish when shutting down",
+		Value: node.DefaultRPCShutdownTimeout,
+	}
+	RPCBodyLimitFlag = cli.Int64Flag{
+		Name:  "rpc.bodylimit",
+		Usage: "Maximum size in bytes of an HTTP and WebSocket RPC request (0 = default)",
+	}
+	RPCBatchLimitFlag = cli.IntFlag{
+		Name:  "rpc.batchlimit",
+		Usage: "Maximum number of requests in an HTTP and WebSocket RPC batch (0 = unlimited)",
+	}
+	RPCRateLimitFlag = cli.IntFlag{
+		Name:  "rpc.ratelimit",
+		Usage: "Maximum number of HTTP and WebSocket RPC requests per second from a single IP (0 = unlimited)",
+	}
+	RPCAllowUnprotectedTxsFlag = cli.BoolTFlag{
+		Name:  "rpc.allowunprotectedtxs",
+		Usage: "Allow non replay-protected (not chain id signed) raw transactions to be submitted via RPC",
+	}
+	RPCGlobalGasCapFlag = cli.Uint64Flag{
+		Name:  "rpc.gascap",
+		Usage: "Sets a cap on gas that can be used in core_call/estimateGas (0 = infinite)",
+		Value: vnt.DefaultConfig.RPCGasCap,
+	}
+	RPCGlobalEVMTimeoutFlag = cli.DurationFlag{
+		Name:  "rpc.evmtimeout",
+		Usage: "Sets a timeout used for core_call/estimateGas executions (0 = infinite)",
+		Value: vnt.DefaultConfig.RPCEVMTimeout,
+	}
+	RPCBeamReadsFlag = cli.BoolFlag{
+		Name:  "rpc.beam",
+		Usage: "Fetch state missing for RPC reads (balance, storage, call) on demand from peers, serving the head while syncing",
+	}
 	IPCDisabledFlag = cli.BoolFlag{
 		Name:  "ipcdisable",
 		Usage: "Disable the IPC-RPC server",
@@ -346,11 +659,73 @@ var (
 		Usage: "API's offered over the WS-RPC interface",
 		Value: "",
 	}
+	WSAllowMethodsFlag = cli.StringFlag{
+		Name:  "ws.allowmethods",
+		Usage: "Comma separated methods (e.g. vnt_call, debug_*) exclusively served over the WS-RPC interface",
+	}
+	WSDenyMethodsFlag = cli.StringFlag{
+		Name:  "ws.denymethods",
+		Usage: "Comma separated methods (e.g. vnt_sendTransaction, personal_*) never served over the WS-RPC interface",
+	}
 	WSAllowedOriginsFlag = cli.StringFlag{
 		Name:  "wsorigins",
 		Usage: "Origins from which to accept websockets requests",
 		Value: "",
 	}
+	WSTLSCertFlag = cli.StringFlag{
+		Name:  "ws.tls.cert",
+		Usage: "PEM file of the certificate to serve the WS-RPC interface over WSS with (requires --ws.tls.key)",
+	}
+	WSTLSKeyFlag = cli.StringFlag{
+		Name:  "ws.tls.key",
+		Usage: "PEM file of the WS-RPC certificate's private key",
+	}
+	WSTLSClientCAFlag = cli.StringFlag{
+		Name:  "ws.tls.clientca",
+		Usage: "PEM file of the certificate authorities WS-RPC clients must present a certificate of (requires --ws.tls.cert)",
+	}
+	WSReadLimitFlag = cli.Int64Flag{
+		Name:  "ws.readlimit",
+		Usage: "Maximum size in bytes of a message read from a WS-RPC connection (0 = request size limit)",
+	}
+	WSCompressionFlag = cli.BoolFlag{
+		Name:  "ws.compression",
+		Usage: "Enable permessage-deflate compression of WS-RPC connections",
+	}
+	AuthListenFlag = cli.StringFlag{
+		Name:  "authrpc.addr",
+		Usage: "Listening address for the JWT authenticated HTTP and WS-RPC server (disabled if empty)",
+		Value: "",
+	}
+	AuthPortFlag = cli.IntFlag{
+		Name:  "authrpc.port",
+		Usage: "Listening port for the authenticated RPC server",
+		Value: node.DefaultAuthPort,
+	}
+	AuthVirtualHostsFlag = cli.StringFlag{
+		Name:  "authrpc.vhosts",
+		Usage: "Comma separated list of virtual hostnames from which to accept authenticated requests (server enforced). Accepts '*' wildcard.",
+		Value: strings.Join(node.DefaultConfig.AuthVirtualHosts, ","),
+	}
+	JWTSecretFlag = cli.StringFlag{
+		Name:  "authrpc.jwtsecret",
+		Usage: "Path to a hex encoded 32 byte secret used to verify authenticated RPC tokens (default = inside the datadir)",
+		Value: "",
+	}
+	GraphQLEnabledFlag = cli.BoolFlag{
+		Name:  "graphql",
+		Usage: "Enable the GraphQL query server",
+	}
+	GraphQLListenAddrFlag = cli.StringFlag{
+		Name:  "graphql.addr",
+		Usage: "GraphQL server listening interface",
+		Value: node.DefaultGraphQLHost,
+	}
+	GraphQLPortFlag = cli.IntFlag{
+		Name:  "graphql.port",
+		Usage: "GraphQL server listening port",
+		Value: node.DefaultGraphQLPort,
+	}
 	ExecFlag = cli.StringFlag{
 		Name:  "exec",
 		Usage: "Execute JavaScript statement",
@@ -359,6 +734,27 @@ var (
 		Name:  "preload",
 		Usage: "Comma separated list of JavaScript files to preload into the console",
 	}
+	// Remote console settings
+	AttachHeaderFlag = cli.StringSliceFlag{
+		Name:  "header",
+		Usage: "Pass custom headers to the RPC server when attaching over HTTP or WebSocket (e.g. --header \"Authorization: Bearer token\")",
+	}
+	AttachTLSCAFlag = cli.StringFlag{
+		Name:  "tls.cacert",
+		Usage: "PEM file of the certificate authority to verify the RPC server with",
+	}
+	AttachTLSCertFlag = cli.StringFlag{
+		Name:  "tls.cert",
+		Usage: "PEM file of the client certificate to present to the RPC server",
+	}
+	AttachTLSKeyFlag = cli.StringFlag{
+		Name:  "tls.key",
+		Usage: "PEM file of the client certificate's private key",
+	}
+	AttachTLSInsecureFlag = cli.BoolFlag{
+		Name:  "tls.insecure",
+		Usage: "Skip verification of the RPC server's TLS certificate",
+	}
 
 	// Network Settings
 	MaxPeersFlag = cli.IntFlag{
@@ -371,11 +767,32 @@ var (
 		Usage: "Maximum number of pending connection attempts (defaults used if set to 0)",
 		Value: 0,
 	}
+	P2PMaxMessageSizeFlag = cli.IntFlag{
+		Name:  "p2p.maxmessagesize",
+		Usage: "Maximum size of an inbound peer message in bytes, peers exceeding it are dropped",
+		Value: vntp2p.DefaultMaxMessageSize,
+	}
+	P2PMaxDownloadFlag = cli.StringFlag{
+		Name:  "p2p.maxdownload",
+		Usage: "Maximum download bandwidth in bytes/sec shared by all peers, or for each peer with a '/peer' suffix (0 = unlimited)",
+	}
+	P2PMaxUploadFlag = cli.StringFlag{
+		Name:  "p2p.maxupload",
+		Usage: "Maximum upload bandwidth in bytes/sec shared by all peers, or for each peer with a '/peer' suffix (0 = unlimited)",
+	}
+	P2PNoCompressionFlag = cli.BoolFlag{
+		Name:  "p2p.nocompression",
+		Usage: "Disables the snappy compression of large messages exchanged with peers",
+	}
 	ListenPortFlag = cli.IntFlag{
 		Name:  "port",
 		Usage: "Network listening port",
 		Value: 30303,
 	}
+	ListenIPFlag = cli.StringFlag{
+		Name:  "p2p.listenip",
+		Usage: "Network listening IP address, IPv4 or IPv6 (empty = all IPv4 and IPv6 interfaces)",
+	}
 	BootnodesFlag = cli.StringFlag{
 		Name:  "bootnodes",
 		Usage: "Comma separated vnode URLs for P2P discovery bootstrap (set v4+v5 instead for light servers)",
@@ -404,6 +821,10 @@ var (
 		Usage: "NAT port mapping mechanism (any|none)",
 		Value: "any",
 	}
+	ProxyFlag = cli.StringFlag{
+		Name:  "p2p.proxy",
+		Usage: "SOCKS5 proxy to dial outbound peer connections through ([socks5://][user:password@]host:port)",
+	}
 	NoDiscoverFlag = cli.BoolFlag{
 		Name:  "nodiscover",
 		Usage: "Disables the peer discovery mechanism (manual peer addition)",
@@ -435,6 +856,16 @@ var (
 		Usage: "Suggested gas price is the given percentile of a set of recent transaction gas prices",
 		Value: vnt.DefaultConfig.GPO.Percentile,
 	}
+	GpoMaxGasPriceFlag = BigFlag{
+		Name:  "gpo.maxprice",
+		Usage: "Maximum gas price that will be recommended by the gas price oracle",
+		Value: vnt.DefaultConfig.GPO.MaxPrice,
+	}
+	GpoIgnoreGasPriceFlag = BigFlag{
+		Name:  "gpo.ignoreprice",
+		Usage: "Gas price below which transactions are ignored by the gas price oracle",
+		Value: new(big.Int),
+	}
 	WhisperEnabledFlag = cli.BoolFlag{
 		Name:  "shh",
 		Usage: "Enable Whisper",
@@ -449,6 +880,30 @@ var (
 		Usage: "Minimum POW accepted",
 		Value: whisper.DefaultMinimumPoW,
 	}
+	WhisperBloomFilterFlag = cli.StringFlag{
+		Name:  "shh.bloom",
+		Usage: "Hex encoded bloom filter of the envelopes to receive (default = all)",
+	}
+	WhisperMailServerFlag = cli.BoolFlag{
+		Name:  "shh.mailserver",
+		Usage: "Archive envelopes and deliver them to peers requesting history",
+	}
+	WhisperMailServerPasswordFlag = cli.StringFlag{
+		Name:  "shh.mailserver.password",
+		Usage: "Password deriving the symmetric key of history requests",
+	}
+	WhisperMailServerPoWFlag = cli.Float64Flag{
+		Name:  "shh.mailserver.pow",
+		Usage: "Minimum POW of history requests",
+	}
+	WhisperLightClientFlag = cli.BoolFlag{
+		Name:  "shh.lightclient",
+		Usage: "Run as a light client, sending and receiving own envelopes without relaying others",
+	}
+	WhisperRestrictLightClientsFlag = cli.BoolFlag{
+		Name:  "shh.restrict-light",
+		Usage: "Refuse connections with other light clients (requires --shh.lightclient)",
+	}
 )
 
 // MakeDataDir retrieves the currently requested data directory, terminating
@@ -456,6 +911,9 @@ var (
 // the a subdirectory of the specified datadir will be used.
 func MakeDataDir(ctx *cli.Context) string {
 	if path := ctx.GlobalString(DataDirFlag.Name); path != "" {
+		if ctx.GlobalBool(TestnetFlag.Name) {
+			return filepath.Join(path, "testnet")
+		}
 		return path
 	}
 	Fatalf("Cannot determine default data directory, please set manually (--datadir)")
@@ -535,6 +993,8 @@ func setBootstrapNodes(ctx *cli.Context, cfg *vntp2p.Config) {
 		}
 	case cfg.BootstrapNodes != nil:
 		return // already set, don't apply defaults.
+	case ctx.GlobalBool(TestnetFlag.Name):
+		urls = params.TestnetBootnodes
 	}
 
 	log.Debug("[info] setBootstrapNodes()", "urls", urls, "and url length", len(urls))
@@ -579,9 +1039,23 @@ func setBootstrapNodes(ctx *cli.Context, cfg *vntp2p.Config) {
 // setListenAddress creates a TCP listening address string from set command
 // line flags.
 func setListenAddress(ctx *cli.Context, cfg *vntp2p.Config) {
+	if !ctx.GlobalIsSet(ListenPortFlag.Name) && !ctx.GlobalIsSet(ListenIPFlag.Name) {
+		return
+	}
+	host, port, err := net.SplitHostPort(cfg.ListenAddr)
+	if err != nil {
+		host, port = "", strconv.Itoa(ListenPortFlag.Value)
+	}
 	if ctx.GlobalIsSet(ListenPortFlag.Name) {
-		cfg.ListenAddr = fmt.Sprintf(":%d", ctx.GlobalInt(ListenPortFlag.Name))
+		port = strconv.Itoa(ctx.GlobalInt(ListenPortFlag.Name))
+	}
+	if ctx.GlobalIsSet(ListenIPFlag.Name) {
+		host = ctx.GlobalString(ListenIPFlag.Name)
+		if host != "" && net.ParseIP(host) == nil {
+			Fatalf("Option %q: invalid IP address %q", ListenIPFlag.Name, host)
+		}
 	}
+	cfg.ListenAddr = net.JoinHostPort(host, port)
 }
 
 // setNAT creates a port mapper from command line flags.
@@ -595,6 +1069,17 @@ func setNAT(ctx *cli.Context, cfg *vntp2p.Config) {
 	}
 }
 
+// setProxy configures the proxy for outbound dials from command line flags.
+func setProxy(ctx *cli.Context, cfg *vntp2p.Config) {
+	if ctx.GlobalIsSet(ProxyFlag.Name) {
+		proxy, err := vntp2p.ProxyParse(ctx.GlobalString(ProxyFlag.Name))
+		if err != nil {
+			Fatalf("Option %q: %v", ProxyFlag.Name, err)
+		}
+		cfg.Proxy = proxy
+	}
+}
+
 // splitAndTrim splits input separated by a comma
 // and trims excessive white space from the substrings.
 func splitAndTrim(input string) []string {
@@ -624,9 +1109,34 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(RPCApiFlag.Name) {
 		cfg.HTTPModules = splitAndTrim(ctx.GlobalString(RPCApiFlag.Name))
 	}
+	if ctx.GlobalIsSet(RPCAllowMethodsFlag.Name) {
+		cfg.HTTPAllowMethods = methodList(ctx, RPCAllowMethodsFlag)
+	}
+	if ctx.GlobalIsSet(RPCDenyMethodsFlag.Name) {
+		cfg.HTTPDenyMethods = methodList(ctx, RPCDenyMethodsFlag)
+	}
 	if ctx.GlobalIsSet(RPCVirtualHostsFlag.Name) {
 		cfg.HTTPVirtualHosts = splitAndTrim(ctx.GlobalString(RPCVirtualHostsFlag.Name))
 	}
+	if ctx.GlobalIsSet(RPCTLSCertFlag.Name) {
+		cfg.HTTPTLSCert = ctx.GlobalString(RPCTLSCertFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCTLSKeyFlag.Name) {
+		cfg.HTTPTLSKey = ctx.GlobalString(RPCTLSKeyFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCTLSClientCAFlag.Name) {
+		cfg.HTTPTLSClientCA = ctx.GlobalString(RPCTLSClientCAFlag.Name)
+	}
+	checkTLSFlags(cfg.HTTPTLSCert, cfg.HTTPTLSKey, cfg.HTTPTLSClientCA, RPCTLSCertFlag, RPCTLSKeyFlag, RPCTLSClientCAFlag)
+}
+
+// methodList parses the comma separated RPC methods of a method filter flag.
+func methodList(ctx *cli.Context, flag cli.StringFlag) []string {
+	methods := splitAndTrim(ctx.GlobalString(flag.Name))
+	if err := (rpc.MethodFilter{Allow: methods}).Validate(); err != nil {
+		Fatalf("Option %q: %v", flag.Name, err)
+	}
+	return methods
 }
 
 // setWS creates the WebSocket RPC listener interface string from the set
@@ -648,6 +1158,112 @@ func setWS(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(WSApiFlag.Name) {
 		cfg.WSModules = splitAndTrim(ctx.GlobalString(WSApiFlag.Name))
 	}
+	if ctx.GlobalIsSet(WSAllowMethodsFlag.Name) {
+		cfg.WSAllowMethods = methodList(ctx, WSAllowMethodsFlag)
+	}
+	if ctx.GlobalIsSet(WSDenyMethodsFlag.Name) {
+		cfg.WSDenyMethods = methodList(ctx, WSDenyMethodsFlag)
+	}
+	if ctx.GlobalIsSet(WSReadLimitFlag.Name) {
+		limit := ctx.GlobalInt64(WSReadLimitFlag.Name)
+		if limit < 0 {
+			Fatalf("Option %q: size %d must not be negative", WSReadLimitFlag.Name, limit)
+		}
+		cfg.WSReadLimit = limit
+	}
+	if ctx.GlobalIsSet(WSCompressionFlag.Name) {
+		cfg.WSCompression = ctx.GlobalBool(WSCompressionFlag.Name)
+	}
+	if ctx.GlobalIsSet(WSTLSCertFlag.Name) {
+		cfg.WSTLSCert = ctx.GlobalString(WSTLSCertFlag.Name)
+	}
+	if ctx.GlobalIsSet(WSTLSKeyFlag.Name) {
+		cfg.WSTLSKey = ctx.GlobalString(WSTLSKeyFlag.Name)
+	}
+	if ctx.GlobalIsSet(WSTLSClientCAFlag.Name) {
+		cfg.WSTLSClientCA = ctx.GlobalString(WSTLSClientCAFlag.Name)
+	}
+	checkTLSFlags(cfg.WSTLSCert, cfg.WSTLSKey, cfg.WSTLSClientCA, WSTLSCertFlag, WSTLSKeyFlag, WSTLSClientCAFlag)
+}
+
+// checkTLSFlags ensures the TLS settings of an RPC interface are complete, as a
+// certificate is useless without its key and client authentication needs both.
+func checkTLSFlags(cert, key, clientCA string, certFlag, keyFlag, clientCAFlag cli.StringFlag) {
+	switch {
+	case cert != "" && key == "":
+		Fatalf("Option %q: requires %q", certFlag.Name, keyFlag.Name)
+	case key != "" && cert == "":
+		Fatalf("Option %q: requires %q", keyFlag.Name, certFlag.Name)
+	case clientCA != "" && cert == "":
+		Fatalf("Option %q: requires %q", clientCAFlag.Name, certFlag.Name)
+	}
+}
+
+// setAuth creates the JWT authenticated RPC endpoint configuration from the set
+// command line flags. An empty listening address leaves the endpoint disabled.
+func setAuth(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalIsSet(AuthListenFlag.Name) {
+		cfg.AuthAddr = ctx.GlobalString(AuthListenFlag.Name)
+	}
+	if ctx.GlobalIsSet(AuthPortFlag.Name) {
+		cfg.AuthPort = ctx.GlobalInt(AuthPortFlag.Name)
+	}
+	if ctx.GlobalIsSet(AuthVirtualHostsFlag.Name) {
+		cfg.AuthVirtualHosts = splitAndTrim(ctx.GlobalString(AuthVirtualHostsFlag.Name))
+	}
+	if ctx.GlobalIsSet(JWTSecretFlag.Name) {
+		cfg.JWTSecret = ctx.GlobalString(JWTSecretFlag.Name)
+	}
+}
+
+// setRPCWaitPeers configures the number of peers to wait for before the HTTP and
+// WebSocket RPC endpoints are opened.
+func setRPCWaitPeers(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalIsSet(RPCWaitPeersFlag.Name) {
+		peers := ctx.GlobalInt(RPCWaitPeersFlag.Name)
+		if peers < 0 {
+			Fatalf("Option %q: peer count %d must not be negative", RPCWaitPeersFlag.Name, peers)
+		}
+		cfg.RPCWaitPeers = peers
+	}
+}
+
+// setRPCShutdownTimeout configures the time the in-flight RPC requests may take
+// to finish when the node shuts down.
+func setRPCShutdownTimeout(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalIsSet(RPCShutdownTimeoutFlag.Name) {
+		timeout := ctx.GlobalDuration(RPCShutdownTimeoutFlag.Name)
+		if timeout <= 0 {
+			Fatalf("Option %q: timeout %v must be positive", RPCShutdownTimeoutFlag.Name, timeout)
+		}
+		cfg.RPCShutdownTimeout = timeout
+	}
+}
+
+// setRPCLimits configures the request limits of the HTTP and WebSocket RPC
+// endpoints.
+func setRPCLimits(ctx *cli.Context, cfg *node.Config) {
+	if ctx.GlobalIsSet(RPCBodyLimitFlag.Name) {
+		limit := ctx.GlobalInt64(RPCBodyLimitFlag.Name)
+		if limit < 0 {
+			Fatalf("Option %q: size %d must not be negative", RPCBodyLimitFlag.Name, limit)
+		}
+		cfg.RPCBodyLimit = limit
+	}
+	if ctx.GlobalIsSet(RPCBatchLimitFlag.Name) {
+		limit := ctx.GlobalInt(RPCBatchLimitFlag.Name)
+		if limit < 0 {
+			Fatalf("Option %q: batch length %d must not be negative", RPCBatchLimitFlag.Name, limit)
+		}
+		cfg.RPCBatchLimit = limit
+	}
+	if ctx.GlobalIsSet(RPCRateLimitFlag.Name) {
+		limit := ctx.GlobalInt(RPCRateLimitFlag.Name)
+		if limit < 0 {
+			Fatalf("Option %q: rate %d must not be negative", RPCRateLimitFlag.Name, limit)
+		}
+		cfg.RPCRateLimit = limit
+	}
 }
 
 // setIPC creates an IPC path configuration from the set command line flags,
@@ -717,6 +1333,72 @@ func setCoinbase(ctx *cli.Context, ks *keystore.KeyStore, cfg *vnt.Config) {
 	}
 }
 
+// MakeSigner resolves the witness signing account in the same way as MakeAddress,
+// additionally requiring its key to be present in the keystore so that it can be
+// unlocked for block signing.
+func MakeSigner(ks *keystore.KeyStore, account string) (accounts.Account, error) {
+	signer, err := MakeAddress(ks, account)
+	if err != nil {
+		return accounts.Account{}, err
+	}
+	if !ks.HasAddress(signer.Address) {
+		return accounts.Account{}, fmt.Errorf("signer %s not found in keystore", signer.Address.Hex())
+	}
+	return signer, nil
+}
+
+// setSigner retrieves the witness signing account from the command line flags,
+// leaving it unset (and thus defaulting to the coinbase) if not specified.
+func setSigner(ctx *cli.Context, ks *keystore.KeyStore, cfg *vnt.Config) {
+	if ctx.GlobalIsSet(DposSignerFlag.Name) {
+		account, err := MakeSigner(ks, ctx.GlobalString(DposSignerFlag.Name))
+		if err != nil {
+			Fatalf("Option %q: %v", DposSignerFlag.Name, err)
+		}
+		cfg.Signer = account.Address
+	}
+}
+
+// setWitnessStandby applies the witness failover options from the command line
+// flags.
+func setWitnessStandby(ctx *cli.Context, cfg *vnt.Config) {
+	if ctx.GlobalIsSet(DposStandbyFlag.Name) {
+		cfg.Standby = ctx.GlobalBool(DposStandbyFlag.Name)
+	}
+	if ctx.GlobalIsSet(DposStandbyMissesFlag.Name) {
+		cfg.StandbyMisses = ctx.GlobalUint64(DposStandbyMissesFlag.Name)
+		if cfg.StandbyMisses == 0 {
+			Fatalf("Option %q: must be positive", DposStandbyMissesFlag.Name)
+		}
+	}
+	if ctx.GlobalIsSet(DposLockFlag.Name) {
+		cfg.ProducerLock = ctx.GlobalString(DposLockFlag.Name)
+	}
+	if cfg.Standby && cfg.ProducerLock == "" {
+		Fatalf("Option %q: requires %q", DposStandbyFlag.Name, DposLockFlag.Name)
+	}
+}
+
+// setDposTiming applies the block production timing policy from the command
+// line flags.
+func setDposTiming(ctx *cli.Context, cfg *vnt.Config) {
+	if ctx.GlobalIsSet(DposSlotToleranceFlag.Name) {
+		cfg.DposTiming.SlotTolerance = ctx.GlobalDuration(DposSlotToleranceFlag.Name)
+		if cfg.DposTiming.SlotTolerance < 0 {
+			Fatalf("Option %q: must not be negative", DposSlotToleranceFlag.Name)
+		}
+	}
+	if ctx.GlobalIsSet(DposMaxClockDriftFlag.Name) {
+		cfg.DposTiming.MaxClockDrift = ctx.GlobalDuration(DposMaxClockDriftFlag.Name)
+		if cfg.DposTiming.MaxClockDrift < 0 {
+			Fatalf("Option %q: must not be negative", DposMaxClockDriftFlag.Name)
+		}
+	}
+	if ctx.GlobalIsSet(DposSkipUnsyncedFlag.Name) {
+		cfg.DposTiming.SkipUnsynced = ctx.GlobalBool(DposSkipUnsyncedFlag.Name)
+	}
+}
+
 // MakePasswordList reads password lines from the file specified by the global --password flag.
 func MakePasswordList(ctx *cli.Context) []string {
 	path := ctx.GlobalString(PasswordFileFlag.Name)
@@ -735,9 +1417,41 @@ func MakePasswordList(ctx *cli.Context) []string {
 	return lines
 }
 
+// MakeUnlockList splits the accounts requested by the global --unlock flag,
+// failing if more of them are listed than permitted by --unlock.max. Entries
+// are returned untrimmed and including empty ones, keeping their positions
+// aligned with the password list.
+func MakeUnlockList(ctx *cli.Context) []string {
+	unlocks := strings.Split(ctx.GlobalString(UnlockedAccountFlag.Name), ",")
+
+	count := 0
+	for _, account := range unlocks {
+		if strings.TrimSpace(account) != "" {
+			count++
+		}
+	}
+	if err := validateUnlockCount(count, ctx.GlobalInt(UnlockMaxFlag.Name)); err != nil {
+		Fatalf("Option %q: %v", UnlockMaxFlag.Name, err)
+	}
+	return unlocks
+}
+
+// validateUnlockCount checks that the number of accounts to unlock does not
+// exceed the configured maximum, zero meaning unlimited.
+func validateUnlockCount(count, max int) error {
+	if max < 0 {
+		return fmt.Errorf("maximum %d must not be negative", max)
+	}
+	if max > 0 && count > max {
+		return fmt.Errorf("%d accounts requested for unlocking, at most %d allowed", count, max)
+	}
+	return nil
+}
+
 func SetP2PConfig(ctx *cli.Context, cfg *vntp2p.Config) {
 	setNodeKey(ctx, cfg)
 	setNAT(ctx, cfg)
+	setProxy(ctx, cfg)
 	setListenAddress(ctx, cfg)
 	setFindNode(ctx, cfg)    
 	setVNTBootnode(ctx, cfg) 
@@ -773,9 +1487,26 @@ func SetP2PConfig(ctx *cli.Context, cfg *vntp2p.Config) {
 	if ctx.GlobalIsSet(MaxPendingPeersFlag.Name) {
 		cfg.MaxPendingPeers = ctx.GlobalInt(MaxPendingPeersFlag.Name)
 	}
+	if ctx.GlobalIsSet(P2PMaxMessageSizeFlag.Name) {
+		size := ctx.GlobalInt(P2PMaxMessageSizeFlag.Name)
+		if err := validateMaxMessageSize(size); err != nil {
+			Fatalf("Option %q: %v", P2PMaxMessageSizeFlag.Name, err)
+		}
+		cfg.MaxMessageSize = uint32(size)
+	}
+	setBandwidthLimits(ctx, cfg)
+	if ctx.GlobalIsSet(P2PNoCompressionFlag.Name) {
+		cfg.NoCompression = true
+	}
 	if ctx.GlobalIsSet(NoDiscoverFlag.Name) || lightClient {
 		cfg.NoDiscovery = true
 	}
+	if ctx.GlobalBool(DeveloperFlag.Name) {
+		// --dev mode can't use p2p networking.
+		cfg.MaxPeers = 0
+		cfg.ListenAddr = ":0"
+		cfg.NoDiscovery = true
+	}
 
 	// if we're running a light client or server, force enable the v5 peer discovery
 	// unless it is explicitly disabled with --nodiscover note that explicitly specifying
@@ -803,11 +1534,23 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	setIPC(ctx, cfg)
 	setHTTP(ctx, cfg)
 	setWS(ctx, cfg)
+	setAuth(ctx, cfg)
+	setRPCWaitPeers(ctx, cfg)
+	setRPCShutdownTimeout(ctx, cfg)
+	setRPCLimits(ctx, cfg)
 	setNodeUserIdent(ctx, cfg)
 
 	switch {
 	case ctx.GlobalIsSet(DataDirFlag.Name):
 		cfg.DataDir = ctx.GlobalString(DataDirFlag.Name)
+	case ctx.GlobalBool(DeveloperFlag.Name):
+		cfg.DataDir = "" // unless explicitly requested, use memory databases
+	}
+	if ctx.GlobalBool(DeveloperMemoryFlag.Name) {
+		cfg.MemoryDatabases = true
+	}
+	if ctx.GlobalBool(TestnetFlag.Name) && cfg.DataDir != "" {
+		cfg.DataDir = filepath.Join(cfg.DataDir, "testnet")
 	}
 
 	if ctx.GlobalIsSet(KeyStoreDirFlag.Name) {
@@ -816,6 +1559,24 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(LightKDFFlag.Name) {
 		cfg.UseLightweightKDF = ctx.GlobalBool(LightKDFFlag.Name)
 	}
+	if ctx.GlobalIsSet(KeyStoreScryptNFlag.Name) {
+		cfg.KeyStoreScryptN = ctx.GlobalInt(KeyStoreScryptNFlag.Name)
+	}
+	if ctx.GlobalIsSet(KeyStoreScryptPFlag.Name) {
+		cfg.KeyStoreScryptP = ctx.GlobalInt(KeyStoreScryptPFlag.Name)
+	}
+	if _, _, _, err := cfg.AccountConfig(); err != nil {
+		Fatalf("Invalid keystore scrypt parameters: %v", err)
+	}
+	if ctx.GlobalIsSet(NoUSBFlag.Name) {
+		cfg.NoUSB = ctx.GlobalBool(NoUSBFlag.Name)
+	}
+	if ctx.GlobalIsSet(InsecureUnlockAllowedFlag.Name) {
+		cfg.InsecureUnlockAllowed = ctx.GlobalBool(InsecureUnlockAllowedFlag.Name)
+	}
+	if ctx.GlobalIsSet(ExternalSignerFlag.Name) {
+		cfg.ExternalSigner = ctx.GlobalString(ExternalSignerFlag.Name)
+	}
 }
 
 func setGPO(ctx *cli.Context, cfg *gasprice.Config) {
@@ -825,9 +1586,24 @@ func setGPO(ctx *cli.Context, cfg *gasprice.Config) {
 	if ctx.GlobalIsSet(GpoPercentileFlag.Name) {
 		cfg.Percentile = ctx.GlobalInt(GpoPercentileFlag.Name)
 	}
+	if ctx.GlobalIsSet(GpoMaxGasPriceFlag.Name) {
+		cfg.MaxPrice = GlobalBig(ctx, GpoMaxGasPriceFlag.Name)
+	}
+	if ctx.GlobalIsSet(GpoIgnoreGasPriceFlag.Name) {
+		cfg.IgnorePrice = GlobalBig(ctx, GpoIgnoreGasPriceFlag.Name)
+	}
 }
 
 func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
+	if ctx.GlobalIsSet(TxPoolLocalsFlag.Name) {
+		for _, account := range strings.Split(ctx.GlobalString(TxPoolLocalsFlag.Name), ",") {
+			trimmed := strings.TrimSpace(account)
+			if !common.IsHexAddress(trimmed) {
+				Fatalf("Option %q: invalid account %q", TxPoolLocalsFlag.Name, trimmed)
+			}
+			cfg.Locals = append(cfg.Locals, common.HexToAddress(trimmed))
+		}
+	}
 	if ctx.GlobalIsSet(TxPoolNoLocalsFlag.Name) {
 		cfg.NoLocals = ctx.GlobalBool(TxPoolNoLocalsFlag.Name)
 	}
@@ -837,6 +1613,9 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolRejournalFlag.Name) {
 		cfg.Rejournal = ctx.GlobalDuration(TxPoolRejournalFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolJournalRemotesFlag.Name) {
+		cfg.JournalRemotes = ctx.GlobalBool(TxPoolJournalRemotesFlag.Name)
+	}
 	if ctx.GlobalIsSet(TxPoolPriceLimitFlag.Name) {
 		cfg.PriceLimit = ctx.GlobalUint64(TxPoolPriceLimitFlag.Name)
 	}
@@ -860,6 +1639,233 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	}
 }
 
+// validateStateWorkers checks the requested state sync worker count, warning if
+// it is excessive compared to the available CPUs.
+func validateStateWorkers(n int) error {
+	if n < 1 {
+		return fmt.Errorf("state sync workers must be at least 1, got %d", n)
+	}
+	if cpus := runtime.NumCPU(); n > 16*cpus {
+		log.Warn("State sync worker count high relative to CPU count", "workers", n, "cpus", cpus)
+	}
+	return nil
+}
+
+// Bounds of the configurable peer message size limit.
+const (
+	minP2PMessageSize = 64 * 1024
+	maxP2PMessageSize = 256 * 1024 * 1024
+)
+
+// validateMaxMessageSize checks that the peer message size limit is large enough
+// to carry regular protocol traffic and small enough to bound memory use.
+func validateMaxMessageSize(size int) error {
+	if size < minP2PMessageSize {
+		return fmt.Errorf("message size limit %d below minimum of %d bytes", size, minP2PMessageSize)
+	}
+	if size > maxP2PMessageSize {
+		return fmt.Errorf("message size limit %d above maximum of %d bytes", size, maxP2PMessageSize)
+	}
+	return nil
+}
+
+// setBandwidthLimits applies the peer bandwidth throttling from the command line.
+func setBandwidthLimits(ctx *cli.Context, cfg *vntp2p.Config) {
+	for _, opt := range []struct {
+		flag  cli.StringFlag
+		limit *vntp2p.BandwidthLimit
+	}{
+		{P2PMaxDownloadFlag, &cfg.MaxDownload},
+		{P2PMaxUploadFlag, &cfg.MaxUpload},
+	} {
+		if !ctx.GlobalIsSet(opt.flag.Name) {
+			continue
+		}
+		limit, err := parseBandwidthLimit(ctx.GlobalString(opt.flag.Name))
+		if err != nil {
+			Fatalf("Option %q: %v", opt.flag.Name, err)
+		}
+		*opt.limit = limit
+	}
+}
+
+// parseBandwidthLimit parses a rate in bytes per second, optionally suffixed
+// with '/peer' to apply it to each peer separately.
+func parseBandwidthLimit(s string) (vntp2p.BandwidthLimit, error) {
+	var limit vntp2p.BandwidthLimit
+	if strings.HasSuffix(s, "/peer") {
+		s, limit.PerPeer = strings.TrimSuffix(s, "/peer"), true
+	}
+	rate, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return vntp2p.BandwidthLimit{}, fmt.Errorf("invalid bandwidth %q", s)
+	}
+	limit.Rate = rate
+	return limit, nil
+}
+
+// setStateWorkers applies the state sync concurrency limit from the command line.
+func setStateWorkers(ctx *cli.Context, cfg *vnt.Config) {
+	if ctx.GlobalIsSet(StateWorkersFlag.Name) {
+		workers := ctx.GlobalInt(StateWorkersFlag.Name)
+		if err := validateStateWorkers(workers); err != nil {
+			Fatalf("Option %q: %v", StateWorkersFlag.Name, err)
+		}
+		cfg.StateWorkers = workers
+	}
+}
+
+// setLightCheckpoint loads the trusted checkpoint light syncing starts from.
+func setLightCheckpoint(ctx *cli.Context, cfg *vnt.Config) {
+	if ctx.GlobalIsSet(LightCheckpointFlag.Name) {
+		checkpoint, err := loadCheckpoint(ctx.GlobalString(LightCheckpointFlag.Name))
+		if err != nil {
+			Fatalf("Option %q: %v", LightCheckpointFlag.Name, err)
+		}
+		cfg.Checkpoint = checkpoint
+	}
+}
+
+// loadCheckpoint reads a JSON encoded trusted checkpoint, as returned by the
+// les_latestCheckpoint API of a light server, from a file or an http(s) URL.
+func loadCheckpoint(source string) (*params.TrustedCheckpoint, error) {
+	var (
+		data []byte
+		err  error
+	)
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		data, err = downloadCheckpoint(source)
+	} else {
+		data, err = ioutil.ReadFile(source)
+	}
+	if err != nil {
+		return nil, err
+	}
+	checkpoint := &params.TrustedCheckpoint{Name: "custom"}
+	if err := json.Unmarshal(data, checkpoint); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint: %v", err)
+	}
+	if err := checkpoint.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint: %v", err)
+	}
+	return checkpoint, nil
+}
+
+// downloadCheckpoint fetches an encoded checkpoint from a URL.
+func downloadCheckpoint(url string) ([]byte, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	res, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download failed: %s", res.Status)
+	}
+	return ioutil.ReadAll(res.Body)
+}
+
+// setAllowUnprotectedTxs applies whether non replay-protected transactions may be
+// submitted over RPC.
+func setAllowUnprotectedTxs(ctx *cli.Context, cfg *vnt.Config) {
+	if ctx.GlobalIsSet(RPCAllowUnprotectedTxsFlag.Name) {
+		cfg.AllowUnprotectedTxs = ctx.GlobalBoolT(RPCAllowUnprotectedTxsFlag.Name)
+	}
+}
+
+// setRPCCallLimits applies the gas cap and the timeout of RPC executed calls from
+// the command line.
+func setRPCCallLimits(ctx *cli.Context, cfg *vnt.Config) {
+	if ctx.GlobalIsSet(RPCGlobalGasCapFlag.Name) {
+		cfg.RPCGasCap = ctx.GlobalUint64(RPCGlobalGasCapFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCGlobalEVMTimeoutFlag.Name) {
+		cfg.RPCEVMTimeout = ctx.GlobalDuration(RPCGlobalEVMTimeoutFlag.Name)
+	}
+}
+
+// setBeamReads applies whether the state missing for RPC reads is fetched from
+// peers. Light clients always read state that way.
+func setBeamReads(ctx *cli.Context, cfg *vnt.Config) {
+	if ctx.GlobalIsSet(RPCBeamReadsFlag.Name) {
+		if cfg.SyncMode == downloader.LightSync {
+			Fatalf("Option %q: light clients always fetch state on demand", RPCBeamReadsFlag.Name)
+		}
+		cfg.RPCBeamReads = ctx.GlobalBool(RPCBeamReadsFlag.Name)
+	}
+}
+
+// vmMemoryCap retrieves the block execution memory cap in bytes from the
+// command line.
+func vmMemoryCap(ctx *cli.Context) uint64 {
+	capMB := ctx.GlobalUint64(VMMemCapFlag.Name)
+	if err := validateVMMemoryCap(capMB); err != nil {
+		Fatalf("Option %q: %v", VMMemCapFlag.Name, err)
+	}
+	return capMB * 1024 * 1024
+}
+
+// validateVMMemoryCap checks that the memory cap in megabytes is representable
+// in bytes.
+func validateVMMemoryCap(capMB uint64) error {
+	if capMB > math.MaxUint64/(1024*1024) {
+		return fmt.Errorf("memory cap %d MB too large", capMB)
+	}
+	return nil
+}
+
+// validateTrieCacheCap checks that the trie cache cap fits into the total cache
+// allowance.
+func validateTrieCacheCap(max, total int) error {
+	if max < 0 {
+		return fmt.Errorf("trie cache cap must not be negative, got %d", max)
+	}
+	if max > total {
+		return fmt.Errorf("trie cache cap %dMB exceeds total cache %dMB", max, total)
+	}
+	return nil
+}
+
+// snapshotCache returns the megabytes of cache allowance dedicated to the state
+// snapshot, or zero if the snapshot is disabled.
+func snapshotCache(ctx *cli.Context) int {
+	if !ctx.GlobalBool(SnapshotFlag.Name) {
+		return 0
+	}
+	if cache := ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheSnapshotFlag.Name) / 100; cache > 0 {
+		return cache
+	}
+	return 1
+}
+
+// capTrieCache clamps the percentage derived trie cache allowance to the hard
+// cap requested on the command line, if any.
+func capTrieCache(ctx *cli.Context, trieCache int) int {
+	max := ctx.GlobalInt(CacheTrieMaxFlag.Name)
+	if max == 0 {
+		return trieCache
+	}
+	if err := validateTrieCacheCap(max, ctx.GlobalInt(CacheFlag.Name)); err != nil {
+		Fatalf("Option %q: %v", CacheTrieMaxFlag.Name, err)
+	}
+	if trieCache > max {
+		log.Info("Capping trie cache allowance", "derived", trieCache, "cap", max)
+		return max
+	}
+	return trieCache
+}
+
+// positiveCacheLimit returns the item count requested for an in-memory chain
+// cache, rejecting counts that would leave the cache without room.
+func positiveCacheLimit(ctx *cli.Context, name string) int {
+	limit := ctx.GlobalInt(name)
+	if limit <= 0 {
+		Fatalf("Option %q: cache must hold at least one item, have %d", name, limit)
+	}
+	return limit
+}
+
 // checkExclusive verifies that only a single isntance of the provided flags was
 // set by the user. Each flag might optionally be followed by a string type to
 // specialize it further.
@@ -906,15 +1912,64 @@ func SetShhConfig(ctx *cli.Context, stack *node.Node, cfg *whisper.Config) {
 	if ctx.GlobalIsSet(WhisperMinPOWFlag.Name) {
 		cfg.MinimumAcceptedPOW = ctx.GlobalFloat64(WhisperMinPOWFlag.Name)
 	}
+	if ctx.GlobalIsSet(WhisperBloomFilterFlag.Name) {
+		bloom, err := parseWhisperBloom(ctx.GlobalString(WhisperBloomFilterFlag.Name))
+		if err != nil {
+			Fatalf("Option %q: %v", WhisperBloomFilterFlag.Name, err)
+		}
+		cfg.BloomFilter = bloom
+	}
+	if ctx.GlobalIsSet(WhisperMailServerFlag.Name) {
+		cfg.MailServer = ctx.GlobalBool(WhisperMailServerFlag.Name)
+	}
+	if ctx.GlobalIsSet(WhisperMailServerPasswordFlag.Name) {
+		cfg.MailServerPassword = ctx.GlobalString(WhisperMailServerPasswordFlag.Name)
+	}
+	if ctx.GlobalIsSet(WhisperMailServerPoWFlag.Name) {
+		cfg.MailServerPoW = ctx.GlobalFloat64(WhisperMailServerPoWFlag.Name)
+	}
+	if cfg.MailServer && cfg.MailServerPassword == "" {
+		Fatalf("Option %q: password required, use --%s", WhisperMailServerFlag.Name, WhisperMailServerPasswordFlag.Name)
+	}
+	if ctx.GlobalIsSet(WhisperLightClientFlag.Name) {
+		cfg.LightClient = ctx.GlobalBool(WhisperLightClientFlag.Name)
+	}
+	if ctx.GlobalIsSet(WhisperRestrictLightClientsFlag.Name) {
+		cfg.RestrictLightClients = ctx.GlobalBool(WhisperRestrictLightClientsFlag.Name)
+	}
+	if cfg.RestrictLightClients && !cfg.LightClient {
+		Fatalf("Option %q: only valid for light clients, use --%s", WhisperRestrictLightClientsFlag.Name, WhisperLightClientFlag.Name)
+	}
+}
+
+// parseWhisperBloom decodes a hex encoded whisper bloom filter, returning nil
+// (receive everything) for an empty input.
+func parseWhisperBloom(input string) ([]byte, error) {
+	if input == "" {
+		return nil, nil
+	}
+	bloom, err := hexutil.Decode(input)
+	if err != nil {
+		return nil, err
+	}
+	if len(bloom) != whisper.BloomFilterSize {
+		return nil, fmt.Errorf("invalid bloom filter size %d, want %d", len(bloom), whisper.BloomFilterSize)
+	}
+	return bloom, nil
 }
 
 // SetEthConfig applies vnt-related command line flags to the config.
 func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *vnt.Config) {
 	// Avoid conflicting network flags
+	checkExclusive(ctx, DeveloperFlag, TestnetFlag)
 	checkExclusive(ctx, LightServFlag, SyncModeFlag, "light")
+	checkExclusive(ctx, NoPreimagesFlag, VMEnableDebugFlag)
 
 	ks := stack.AccountManager().Backends(keystore.KeyStoreType)[0].(*keystore.KeyStore)
 	setCoinbase(ctx, ks, cfg)
+	setSigner(ctx, ks, cfg)
+	setWitnessStandby(ctx, cfg)
+	setDposTiming(ctx, cfg)
 	setGPO(ctx, &cfg.GPO)
 	setTxPool(ctx, &cfg.TxPool)
 
@@ -922,12 +1977,14 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *vnt.Config) {
 	case ctx.GlobalIsSet(SyncModeFlag.Name):
 		cfg.SyncMode = *GlobalTextMarshaler(ctx, SyncModeFlag.Name).(*downloader.SyncMode)
 	}
+	setStateWorkers(ctx, cfg)
 	if ctx.GlobalIsSet(LightServFlag.Name) {
 		cfg.LightServ = ctx.GlobalInt(LightServFlag.Name)
 	}
 	if ctx.GlobalIsSet(LightPeersFlag.Name) {
 		cfg.LightPeers = ctx.GlobalInt(LightPeersFlag.Name)
 	}
+	setLightCheckpoint(ctx, cfg)
 	if ctx.GlobalIsSet(NetworkIdFlag.Name) {
 		cfg.NetworkId = ctx.GlobalUint64(NetworkIdFlag.Name)
 	}
@@ -936,15 +1993,69 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *vnt.Config) {
 		cfg.DatabaseCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheDatabaseFlag.Name) / 100
 	}
 	cfg.DatabaseHandles = makeDatabaseHandles()
+	if ctx.GlobalIsSet(DatabaseStatsFlag.Name) {
+		cfg.DatabaseStats = ctx.GlobalDuration(DatabaseStatsFlag.Name)
+	}
+	if ctx.GlobalIsSet(DatabaseReadReplicaFlag.Name) {
+		cfg.ReadReplica = ctx.GlobalString(DatabaseReadReplicaFlag.Name)
+	}
+	if ctx.GlobalIsSet(AncientFlag.Name) {
+		cfg.DatabaseFreezer = ctx.GlobalString(AncientFlag.Name)
+	}
 
 	if gcmode := ctx.GlobalString(GCModeFlag.Name); gcmode != "full" && gcmode != "archive" {
 		Fatalf("--%s must be either 'full' or 'archive'", GCModeFlag.Name)
 	}
-	cfg.NoPruning = ctx.GlobalString(GCModeFlag.Name) == "archive"
+	if ctx.GlobalIsSet(GCModeFlag.Name) {
+		cfg.NoPruning = ctx.GlobalString(GCModeFlag.Name) == "archive"
+	}
+	if ctx.GlobalIsSet(TxLookupLimitFlag.Name) {
+		cfg.TxLookupLimit = ctx.GlobalUint64(TxLookupLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(StateHistoryFlag.Name) {
+		if cfg.NoPruning {
+			Fatalf("Option %q: not supported in archive GC mode", StateHistoryFlag.Name)
+		}
+		cfg.StateHistory = ctx.GlobalUint64(StateHistoryFlag.Name)
+		if cfg.StateHistory > 128 && !ctx.GlobalIsSet(PruneScheduleFlag.Name) {
+			log.Warn("State history persisted at shutdown is only reclaimed by state pruning", "history", cfg.StateHistory, "schedule", "--"+PruneScheduleFlag.Name)
+		}
+	}
+	if ctx.GlobalIsSet(PruneScheduleFlag.Name) {
+		spec := ctx.GlobalString(PruneScheduleFlag.Name)
+		if _, err := vnt.ParsePruneSchedule(spec); err != nil {
+			Fatalf("Option %q: %v", PruneScheduleFlag.Name, err)
+		}
+		if cfg.NoPruning {
+			log.Warn("Ignoring state pruning schedule in archive mode", "schedule", spec)
+		} else {
+			cfg.PruneSchedule = spec
+			cfg.PruneBloomSize = ctx.GlobalUint64(BloomFilterSizeFlag.Name)
+		}
+	}
+	if ctx.GlobalIsSet(NoPreimagesFlag.Name) {
+		cfg.NoPreimages = ctx.GlobalBool(NoPreimagesFlag.Name)
+	}
 
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheGCFlag.Name) {
 		cfg.TrieCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheGCFlag.Name) / 100
 	}
+	cfg.TrieCache = capTrieCache(ctx, cfg.TrieCache)
+	if ctx.GlobalIsSet(SnapshotFlag.Name) {
+		cfg.SnapshotCache = snapshotCache(ctx)
+	}
+	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CachePrefetchFlag.Name) {
+		cfg.TriePrefetchCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CachePrefetchFlag.Name) / 100
+	}
+	if ctx.GlobalIsSet(CacheBlocksFlag.Name) {
+		cfg.BlockCache = positiveCacheLimit(ctx, CacheBlocksFlag.Name)
+	}
+	if ctx.GlobalIsSet(CacheReceiptsFlag.Name) {
+		cfg.ReceiptCache = positiveCacheLimit(ctx, CacheReceiptsFlag.Name)
+	}
+	if ctx.GlobalIsSet(CacheSendersFlag.Name) {
+		cfg.SenderCache = positiveCacheLimit(ctx, CacheSendersFlag.Name)
+	}
 	if ctx.GlobalIsSet(DocRootFlag.Name) {
 		cfg.DocRoot = ctx.GlobalString(DocRootFlag.Name)
 	}
@@ -955,9 +2066,25 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *vnt.Config) {
 		cfg.GasPrice = GlobalBig(ctx, GasPriceFlag.Name)
 	}
 	if ctx.GlobalIsSet(VMEnableDebugFlag.Name) {
-		// TODO(fjl): force-enable this in --dev mode
 		cfg.EnablePreimageRecording = ctx.GlobalBool(VMEnableDebugFlag.Name)
 	}
+	setAllowUnprotectedTxs(ctx, cfg)
+	setRPCCallLimits(ctx, cfg)
+	setBeamReads(ctx, cfg)
+	if ctx.GlobalIsSet(VMMemCapFlag.Name) {
+		cfg.VMMemoryCap = vmMemoryCap(ctx)
+	}
+
+	// Override any default configs for hard coded networks
+	switch {
+	case ctx.GlobalBool(TestnetFlag.Name):
+		if !ctx.GlobalIsSet(NetworkIdFlag.Name) {
+			cfg.NetworkId = params.TestnetChainConfig.ChainID.Uint64()
+		}
+		cfg.Genesis = core.DefaultTestnetGenesisBlock()
+	case ctx.GlobalBool(DeveloperFlag.Name):
+		setDeveloper(ctx, ks, cfg)
+	}
 
 	// TODO(fjl): move trie cache generations into config
 	if gen := ctx.GlobalInt(TrieCacheGenFlag.Name); gen > 0 {
@@ -965,6 +2092,50 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *vnt.Config) {
 	}
 }
 
+// setDeveloper configures the single-node developer network: the first account
+// in the keystore (or a freshly created one) becomes both the only witness and
+// the pre-funded faucet, and transactions are accepted without a gas price.
+func setDeveloper(ctx *cli.Context, ks *keystore.KeyStore, cfg *vnt.Config) {
+	period := ctx.GlobalUint64(DeveloperPeriodFlag.Name)
+	if period == 0 {
+		Fatalf("Option %q: block period must be positive", DeveloperPeriodFlag.Name)
+	}
+	// Create a new developer account or reuse existing one
+	var (
+		developer  accounts.Account
+		passphrase string
+		err        error
+	)
+	if list := MakePasswordList(ctx); len(list) > 0 {
+		passphrase = list[0]
+	}
+	if accs := ks.Accounts(); len(accs) > 0 {
+		developer = accs[0]
+	} else {
+		developer, err = ks.NewAccount(passphrase)
+		if err != nil {
+			Fatalf("Failed to create developer account: %v", err)
+		}
+	}
+	if err := ks.Unlock(developer, passphrase); err != nil {
+		Fatalf("Failed to unlock developer account: %v", err)
+	}
+	log.Info("Using developer account", "address", developer.Address)
+
+	cfg.Coinbase = developer.Address
+	cfg.Signer = developer.Address
+	cfg.Genesis = core.DeveloperGenesisBlock(period, developer.Address)
+	if !ctx.GlobalIsSet(NetworkIdFlag.Name) {
+		cfg.NetworkId = 1337
+	}
+	if !ctx.GlobalIsSet(GasPriceFlag.Name) {
+		cfg.GasPrice = new(big.Int)
+	}
+	if !ctx.GlobalIsSet(VMEnableDebugFlag.Name) && !cfg.NoPreimages {
+		cfg.EnablePreimageRecording = true
+	}
+}
+
 // RegisterEthService adds an VNT client to the stack.
 func RegisterEthService(stack *node.Node, cfg *vnt.Config) {
 	var err error
@@ -990,12 +2161,39 @@ func RegisterEthService(stack *node.Node, cfg *vnt.Config) {
 // RegisterShhService configures Whisper and adds it to the given node.
 func RegisterShhService(stack *node.Node, cfg *whisper.Config) {
 	if err := stack.Register(func(n *node.ServiceContext) (node.Service, error) {
-		return whisper.New(cfg), nil
+		shh := whisper.New(cfg)
+		if cfg.MailServer {
+			server := new(mailserver.WMailServer)
+			if err := server.Init(shh, n.ResolvePath("mailserver"), cfg.MailServerPassword, cfg.MailServerPoW); err != nil {
+				return nil, fmt.Errorf("mail server: %v", err)
+			}
+			shh.RegisterServer(server)
+		}
+		return shh, nil
 	}); err != nil {
 		Fatalf("Failed to register the Whisper service: %v", err)
 	}
 }
 
+// RegisterGraphQLService adds the GraphQL query server, answering queries from
+// the full node's chain data, to the stack.
+func RegisterGraphQLService(stack *node.Node, endpoint string) {
+	if err := stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+		var vntServ *vnt.VNT
+		if err := ctx.Service(&vntServ); err != nil {
+			return nil, fmt.Errorf("GraphQL requires a full node: %v", err)
+		}
+		return graphql.New(vntServ.APIBackend, endpoint)
+	}); err != nil {
+		Fatalf("Failed to register the GraphQL service: %v", err)
+	}
+}
+
+// MakeGraphQLEndpoint returns the listening endpoint of the GraphQL server.
+func MakeGraphQLEndpoint(ctx *cli.Context) string {
+	return fmt.Sprintf("%s:%d", ctx.GlobalString(GraphQLListenAddrFlag.Name), ctx.GlobalInt(GraphQLPortFlag.Name))
+}
+
 // RegisterEthStatsService configures the VNT Stats daemon and adds it to
 // th egiven node.
 func RegisterEthStatsService(stack *node.Node, url string) {
@@ -1019,6 +2217,55 @@ func SetupNetwork(ctx *cli.Context) {
 	params.TargetGasLimit = ctx.GlobalUint64(TargetGasLimitFlag.Name)
 }
 
+// SetupMetrics starts the stand-alone metrics HTTP server and the InfluxDB
+// reporter if they were requested.
+func SetupMetrics(ctx *cli.Context) {
+	if !ctx.GlobalIsSet(MetricsHTTPFlag.Name) && !ctx.GlobalBool(MetricsEnableInfluxDBFlag.Name) {
+		return
+	}
+	if !metrics.Enabled {
+		log.Warn("Metrics reporting requested without collection", "flag", "--"+MetricsEnabledFlag.Name)
+		return
+	}
+	if ctx.GlobalIsSet(MetricsHTTPFlag.Name) {
+		address := fmt.Sprintf("%s:%d", ctx.GlobalString(MetricsHTTPFlag.Name), ctx.GlobalInt(MetricsPortFlag.Name))
+		log.Info("Enabling stand-alone metrics HTTP endpoint", "address", address)
+		exp.Setup(address)
+	}
+	if ctx.GlobalBool(MetricsEnableInfluxDBFlag.Name) {
+		var (
+			endpoint = ctx.GlobalString(MetricsInfluxDBEndpointFlag.Name)
+			database = ctx.GlobalString(MetricsInfluxDBDatabaseFlag.Name)
+			username = ctx.GlobalString(MetricsInfluxDBUsernameFlag.Name)
+			password = ctx.GlobalString(MetricsInfluxDBPasswordFlag.Name)
+		)
+		tags, err := parseInfluxDBTags(ctx.GlobalString(MetricsInfluxDBTagsFlag.Name))
+		if err != nil {
+			Fatalf("Option %q: %v", MetricsInfluxDBTagsFlag.Name, err)
+		}
+		log.Info("Enabling metrics export to InfluxDB", "endpoint", endpoint, "database", database)
+		go influxdb.InfluxDBWithTags(metrics.DefaultRegistry, 10*time.Second, endpoint, database, username, password, "gvnt.", tags)
+	}
+}
+
+// parseInfluxDBTags parses a comma separated list of key=value pairs into the
+// tags attached to every InfluxDB measurement.
+func parseInfluxDBTags(s string) (map[string]string, error) {
+	tags := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			return nil, fmt.Errorf("invalid tag %q, want key=value", pair)
+		}
+		tags[kv[0]] = kv[1]
+	}
+	return tags, nil
+}
+
 // MakeChainDatabase open an LevelDB using the flags passed to the client and will hard crash if it fails.
 func MakeChainDatabase(ctx *cli.Context, stack *node.Node) vntdb.Database {
 	var (
@@ -1026,15 +2273,24 @@ func MakeChainDatabase(ctx *cli.Context, stack *node.Node) vntdb.Database {
 		handles = makeDatabaseHandles()
 	)
 	name := "chaindata"
-	chainDb, err := stack.OpenDatabase(name, cache, handles)
+	chainDb, err := stack.OpenDatabaseWithFreezer(name, cache, handles, ctx.GlobalString(AncientFlag.Name))
 	if err != nil {
 		Fatalf("Could not open database: %v", err)
 	}
+	kvdb := chainDb
+	if frdb, ok := chainDb.(*rawdb.FreezerDatabase); ok {
+		kvdb = frdb.KeyValueStore()
+	}
+	vntdb.ReportStats(kvdb, "chaindata/stats/", ctx.GlobalDuration(DatabaseStatsFlag.Name))
 	return chainDb
 }
 
 func MakeGenesis(ctx *cli.Context) *core.Genesis {
 	var genesis *core.Genesis
+	switch {
+	case ctx.GlobalBool(TestnetFlag.Name):
+		genesis = core.DefaultTestnetGenesisBlock()
+	}
 	return genesis
 }
 
@@ -1064,11 +2320,40 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chai
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheGCFlag.Name) {
 		cache.TrieNodeLimit = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheGCFlag.Name) / 100
 	}
-	vmcfg := vm.Config{EnablePreimageRecording: ctx.GlobalBool(VMEnableDebugFlag.Name)}
+	cache.TrieNodeLimit = capTrieCache(ctx, cache.TrieNodeLimit)
+	cache.SnapshotLimit = snapshotCache(ctx)
+	cache.TriePrefetchLimit = vnt.DefaultConfig.TriePrefetchCache
+	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CachePrefetchFlag.Name) {
+		cache.TriePrefetchLimit = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CachePrefetchFlag.Name) / 100
+	}
+	if ctx.GlobalIsSet(CacheBlocksFlag.Name) {
+		cache.BlockCacheLimit = positiveCacheLimit(ctx, CacheBlocksFlag.Name)
+	}
+	if ctx.GlobalIsSet(CacheReceiptsFlag.Name) {
+		cache.ReceiptCacheLimit = positiveCacheLimit(ctx, CacheReceiptsFlag.Name)
+	}
+	if ctx.GlobalIsSet(CacheSendersFlag.Name) {
+		cache.SenderCacheLimit = positiveCacheLimit(ctx, CacheSendersFlag.Name)
+	}
+	cache.TxLookupLimit = ctx.GlobalUint64(TxLookupLimitFlag.Name)
+	if ctx.GlobalIsSet(StateHistoryFlag.Name) {
+		if cache.Disabled {
+			Fatalf("Option %q: not supported in archive GC mode", StateHistoryFlag.Name)
+		}
+		cache.StateHistory = ctx.GlobalUint64(StateHistoryFlag.Name)
+	}
+	cache.NoPreimages = ctx.GlobalBool(NoPreimagesFlag.Name)
+	vmcfg := vm.Config{
+		EnablePreimageRecording: ctx.GlobalBool(VMEnableDebugFlag.Name),
+		MemoryCap:               vmMemoryCap(ctx),
+	}
 	chain, err = core.NewBlockChain(chainDb, cache, config, engine, vmcfg)
 	if err != nil {
 		Fatalf("Can't create BlockChain: %v", err)
 	}
+	if ctx.GlobalBool(DposLogForkChoiceFlag.Name) {
+		LogForkChoice(chain, engine, log.Root())
+	}
 	return chain, chainDb
 }
 
@@ -1089,6 +2374,72 @@ func MakeConsolePreloads(ctx *cli.Context) []string {
 	return preloads
 }
 
+// MakeDialOptions assembles the transport options for attaching to a remote node
+// over HTTP or WebSocket. The flags are only defined on the attach command.
+func MakeDialOptions(ctx *cli.Context) rpc.DialOptions {
+	var opts rpc.DialOptions
+
+	header, err := parseAttachHeaders(ctx.StringSlice(AttachHeaderFlag.Name))
+	if err != nil {
+		Fatalf("Option %q: %v", AttachHeaderFlag.Name, err)
+	}
+	opts.Header = header
+
+	var (
+		ca       = ctx.String(AttachTLSCAFlag.Name)
+		cert     = ctx.String(AttachTLSCertFlag.Name)
+		key      = ctx.String(AttachTLSKeyFlag.Name)
+		insecure = ctx.Bool(AttachTLSInsecureFlag.Name)
+	)
+	if ca != "" || cert != "" || key != "" || insecure {
+		if opts.TLSConfig, err = makeAttachTLSConfig(ca, cert, key, insecure); err != nil {
+			Fatalf("Invalid TLS settings: %v", err)
+		}
+	}
+	return opts
+}
+
+// parseAttachHeaders parses a list of "Name: value" pairs into HTTP headers.
+func parseAttachHeaders(list []string) (http.Header, error) {
+	header := make(http.Header)
+	for _, entry := range list {
+		kv := strings.SplitN(entry, ":", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, fmt.Errorf("invalid header %q, want \"Name: value\"", entry)
+		}
+		header.Add(strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1]))
+	}
+	return header, nil
+}
+
+// makeAttachTLSConfig creates the TLS settings for attaching to a remote node,
+// trusting the given certificate authority and presenting the given client
+// certificate if set.
+func makeAttachTLSConfig(caFile, certFile, keyFile string, insecure bool) (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: insecure}
+	if caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", caFile)
+		}
+	}
+	if (certFile == "") != (keyFile == "") {
+		return nil, errors.New("client certificate and key must be specified together")
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
 // MigrateFlags sets the global flag from a local flag when it's set.
 // This is a temporary function used for migrating old command/flags to the
 // new format.
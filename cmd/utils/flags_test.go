@@ -0,0 +1,577 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of go-vnt.
+//
+// go-vnt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-vnt is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-vnt. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/vntchain/go-vnt/accounts/keystore"
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/common/hexutil"
+	"github.com/vntchain/go-vnt/node"
+	"github.com/vntchain/go-vnt/params"
+	"github.com/vntchain/go-vnt/vnt"
+	"github.com/vntchain/go-vnt/vntp2p"
+	whisper "github.com/vntchain/go-vnt/whisper/whisperv6"
+	cli "gopkg.in/urfave/cli.v1"
+)
+
+// newTestContext creates a cli context with the given flags defined and the
+// given command line arguments parsed into it.
+func newTestContext(t *testing.T, flags []cli.Flag, args ...string) *cli.Context {
+	set := flag.NewFlagSet("test", flag.ContinueOnError)
+	for _, f := range flags {
+		f.Apply(set)
+	}
+	if err := set.Parse(args); err != nil {
+		t.Fatalf("failed to parse flags %v: %v", args, err)
+	}
+	return cli.NewContext(cli.NewApp(), set, nil)
+}
+
+// newTestKeyStore creates a light keystore in a temporary folder holding the
+// requested number of freshly generated accounts.
+func newTestKeyStore(t *testing.T, accounts int) (string, *keystore.KeyStore) {
+	dir, err := ioutil.TempDir("", "utils-keystore-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks := keystore.NewKeyStore(dir, keystore.LightScryptN, keystore.LightScryptP)
+	for i := 0; i < accounts; i++ {
+		if _, err := ks.NewAccount("foo"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir, ks
+}
+
+// Tests that a dedicated witness signing account is resolved apart from the
+// coinbase.
+func TestSignerSeparatedFromCoinbase(t *testing.T) {
+	dir, ks := newTestKeyStore(t, 2)
+	defer os.RemoveAll(dir)
+
+	accs := ks.Accounts()
+	ctx := newTestContext(t, []cli.Flag{CoinbaseFlag, DposSignerFlag},
+		"--coinbase", accs[0].Address.Hex(), "--dpos.signer", accs[1].Address.Hex())
+
+	var cfg vnt.Config
+	setCoinbase(ctx, ks, &cfg)
+	setSigner(ctx, ks, &cfg)
+
+	if cfg.Coinbase != accs[0].Address {
+		t.Errorf("coinbase mismatch: have %x, want %x", cfg.Coinbase, accs[0].Address)
+	}
+	if cfg.Signer != accs[1].Address {
+		t.Errorf("signer mismatch: have %x, want %x", cfg.Signer, accs[1].Address)
+	}
+}
+
+// Tests that the signer is left unset, and thus defaults to the coinbase, if
+// the flag is not specified.
+func TestSignerDefaultsToCoinbase(t *testing.T) {
+	dir, ks := newTestKeyStore(t, 1)
+	defer os.RemoveAll(dir)
+
+	ctx := newTestContext(t, []cli.Flag{CoinbaseFlag, DposSignerFlag}, "--coinbase", ks.Accounts()[0].Address.Hex())
+
+	var cfg vnt.Config
+	setCoinbase(ctx, ks, &cfg)
+	setSigner(ctx, ks, &cfg)
+
+	if cfg.Signer != (common.Address{}) {
+		t.Errorf("signer set without flag: %x", cfg.Signer)
+	}
+}
+
+// Tests that a signer which cannot be unlocked from the local keystore is rejected.
+func TestSignerNotInKeyStore(t *testing.T) {
+	dir, ks := newTestKeyStore(t, 1)
+	defer os.RemoveAll(dir)
+
+	if _, err := MakeSigner(ks, ks.Accounts()[0].Address.Hex()); err != nil {
+		t.Fatalf("failed to resolve local signer: %v", err)
+	}
+	if _, err := MakeSigner(ks, "0x0000000000000000000000000000000000000001"); err == nil {
+		t.Fatalf("unknown signer accepted")
+	}
+	if _, err := MakeSigner(ks, "5"); err == nil {
+		t.Fatalf("out of range signer index accepted")
+	}
+}
+
+// Tests that the state sync worker count is threaded into the VNT config.
+func TestStateWorkersFlag(t *testing.T) {
+	ctx := newTestContext(t, []cli.Flag{StateWorkersFlag}, "--syncmode.stateworkers", "8")
+
+	var cfg vnt.Config
+	setStateWorkers(ctx, &cfg)
+	if cfg.StateWorkers != 8 {
+		t.Fatalf("state workers mismatch: have %d, want %d", cfg.StateWorkers, 8)
+	}
+}
+
+// Tests that invalid state sync worker counts are rejected.
+func TestStateWorkersValidation(t *testing.T) {
+	tests := []struct {
+		workers int
+		valid   bool
+	}{
+		{-1, false},
+		{0, false},
+		{1, true},
+		{64, true},
+	}
+	for _, tt := range tests {
+		if err := validateStateWorkers(tt.workers); (err == nil) != tt.valid {
+			t.Errorf("workers %d: validity mismatch: have %v, want %v", tt.workers, err == nil, tt.valid)
+		}
+	}
+}
+
+// Tests that the whisper bloom filter flag is decoded and validated.
+func TestWhisperBloomFlag(t *testing.T) {
+	bloom := make([]byte, whisper.BloomFilterSize)
+	bloom[0], bloom[whisper.BloomFilterSize-1] = 0x01, 0x80
+
+	ctx := newTestContext(t, []cli.Flag{WhisperBloomFilterFlag}, "--shh.bloom", hexutil.Encode(bloom))
+	var cfg whisper.Config
+	SetShhConfig(ctx, nil, &cfg)
+	if !bytes.Equal(cfg.BloomFilter, bloom) {
+		t.Fatalf("bloom filter mismatch: have %x, want %x", cfg.BloomFilter, bloom)
+	}
+	if b, err := parseWhisperBloom(""); b != nil || err != nil {
+		t.Errorf("empty bloom not accepted as receive-all: %x, %v", b, err)
+	}
+	if _, err := parseWhisperBloom("0x0102"); err == nil {
+		t.Errorf("short bloom filter accepted")
+	}
+	if _, err := parseWhisperBloom("zz"); err == nil {
+		t.Errorf("invalid hex bloom filter accepted")
+	}
+}
+
+// Tests that the whisper mail server flags are applied to the config.
+func TestWhisperMailServerFlags(t *testing.T) {
+	flags := []cli.Flag{WhisperMailServerFlag, WhisperMailServerPasswordFlag, WhisperMailServerPoWFlag}
+	ctx := newTestContext(t, flags, "--shh.mailserver", "--shh.mailserver.password", "secret", "--shh.mailserver.pow", "0.5")
+
+	var cfg whisper.Config
+	SetShhConfig(ctx, nil, &cfg)
+	if !cfg.MailServer {
+		t.Errorf("mail server not enabled")
+	}
+	if cfg.MailServerPassword != "secret" {
+		t.Errorf("mail server password mismatch: have %q, want %q", cfg.MailServerPassword, "secret")
+	}
+	if cfg.MailServerPoW != 0.5 {
+		t.Errorf("mail server pow mismatch: have %v, want %v", cfg.MailServerPoW, 0.5)
+	}
+}
+
+// Tests that the whisper light client flags are applied to the config.
+func TestWhisperLightClientFlags(t *testing.T) {
+	flags := []cli.Flag{WhisperLightClientFlag, WhisperRestrictLightClientsFlag}
+	ctx := newTestContext(t, flags, "--shh.lightclient", "--shh.restrict-light")
+
+	var cfg whisper.Config
+	SetShhConfig(ctx, nil, &cfg)
+	if !cfg.LightClient {
+		t.Errorf("light client mode not enabled")
+	}
+	if !cfg.RestrictLightClients {
+		t.Errorf("light client restriction not enabled")
+	}
+}
+
+// Tests that the trie cache cap clamps large percentage derived allowances and
+// leaves smaller ones untouched.
+func TestTrieCacheCap(t *testing.T) {
+	flags := []cli.Flag{CacheFlag, CacheGCFlag, CacheTrieMaxFlag}
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{[]string{"--cache", "4096", "--cache.gc", "50"}, 2048},                           // no cap
+		{[]string{"--cache", "4096", "--cache.gc", "50", "--cache.trie.max", "512"}, 512}, // clamped
+		{[]string{"--cache", "1024", "--cache.gc", "25", "--cache.trie.max", "512"}, 256}, // below cap
+		{[]string{"--cache", "1024", "--cache.gc", "50", "--cache.trie.max", "512"}, 512}, // at cap
+	}
+	for i, tt := range tests {
+		ctx := newTestContext(t, flags, tt.args...)
+		derived := ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheGCFlag.Name) / 100
+		if have := capTrieCache(ctx, derived); have != tt.want {
+			t.Errorf("test %d: trie cache mismatch: have %d, want %d", i, have, tt.want)
+		}
+	}
+}
+
+// Tests that the trie cache cap is validated against the total cache.
+func TestTrieCacheCapValidation(t *testing.T) {
+	if err := validateTrieCacheCap(512, 1024); err != nil {
+		t.Errorf("valid cap rejected: %v", err)
+	}
+	if err := validateTrieCacheCap(2048, 1024); err == nil {
+		t.Errorf("cap above total cache accepted")
+	}
+	if err := validateTrieCacheCap(-1, 1024); err == nil {
+		t.Errorf("negative cap accepted")
+	}
+}
+
+// Tests that unprotected RPC transactions stay allowed by default and can be
+// turned off via the command line.
+func TestAllowUnprotectedTxsFlag(t *testing.T) {
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{nil, true},
+		{[]string{"--rpc.allowunprotectedtxs=true"}, true},
+		{[]string{"--rpc.allowunprotectedtxs=false"}, false},
+	}
+	for i, tt := range tests {
+		ctx := newTestContext(t, []cli.Flag{RPCAllowUnprotectedTxsFlag}, tt.args...)
+
+		cfg := vnt.DefaultConfig
+		setAllowUnprotectedTxs(ctx, &cfg)
+		if cfg.AllowUnprotectedTxs != tt.want {
+			t.Errorf("test %d: allow unprotected mismatch: have %v, want %v", i, cfg.AllowUnprotectedTxs, tt.want)
+		}
+	}
+}
+
+// Tests that the peer message size limit is threaded into the p2p config and
+// validated against sane bounds.
+func TestP2PMaxMessageSize(t *testing.T) {
+	ctx := newTestContext(t, []cli.Flag{P2PMaxMessageSizeFlag}, "--p2p.maxmessagesize", "1048576")
+
+	var cfg vntp2p.Config
+	SetP2PConfig(ctx, &cfg)
+	if cfg.MaxMessageSize != 1048576 {
+		t.Fatalf("message size mismatch: have %d, want %d", cfg.MaxMessageSize, 1048576)
+	}
+	for _, size := range []int{-1, 0, minP2PMessageSize - 1, maxP2PMessageSize + 1} {
+		if err := validateMaxMessageSize(size); err == nil {
+			t.Errorf("invalid message size %d accepted", size)
+		}
+	}
+	for _, size := range []int{minP2PMessageSize, vntp2p.DefaultMaxMessageSize, maxP2PMessageSize} {
+		if err := validateMaxMessageSize(size); err != nil {
+			t.Errorf("valid message size %d rejected: %v", size, err)
+		}
+	}
+}
+
+// Tests that the listening IP and port are combined into the p2p listen address.
+func TestListenAddressFlags(t *testing.T) {
+	tests := []struct {
+		args []string
+		addr string
+	}{
+		{[]string{"--port", "3001"}, ":3001"},
+		{[]string{"--p2p.listenip", "::"}, "[::]:30303"},
+		{[]string{"--p2p.listenip", "10.0.0.1", "--port", "3001"}, "10.0.0.1:3001"},
+	}
+	for _, tt := range tests {
+		ctx := newTestContext(t, []cli.Flag{ListenPortFlag, ListenIPFlag}, tt.args...)
+
+		cfg := vntp2p.Config{ListenAddr: ":30303"}
+		setListenAddress(ctx, &cfg)
+		if cfg.ListenAddr != tt.addr {
+			t.Errorf("%v: listen address mismatch: have %q, want %q", tt.args, cfg.ListenAddr, tt.addr)
+		}
+	}
+}
+
+// Tests that the RPC peer threshold is threaded into the node config.
+func TestRPCWaitPeersFlag(t *testing.T) {
+	ctx := newTestContext(t, []cli.Flag{RPCEnabledFlag, RPCWaitPeersFlag}, "--rpc", "--rpc.waitpeers", "3")
+
+	var cfg node.Config
+	setHTTP(ctx, &cfg)
+	setRPCWaitPeers(ctx, &cfg)
+	if cfg.RPCWaitPeers != 3 {
+		t.Fatalf("peer threshold mismatch: have %d, want %d", cfg.RPCWaitPeers, 3)
+	}
+}
+
+// Tests that the RPC request limits are threaded into the node config.
+func TestRPCLimitFlags(t *testing.T) {
+	flags := []cli.Flag{RPCBodyLimitFlag, RPCBatchLimitFlag, RPCRateLimitFlag}
+	ctx := newTestContext(t, flags, "--rpc.bodylimit", "1048576", "--rpc.batchlimit", "100", "--rpc.ratelimit", "50")
+
+	var cfg node.Config
+	setRPCLimits(ctx, &cfg)
+	if cfg.RPCBodyLimit != 1048576 {
+		t.Errorf("body limit mismatch: have %d, want %d", cfg.RPCBodyLimit, 1048576)
+	}
+	if cfg.RPCBatchLimit != 100 {
+		t.Errorf("batch limit mismatch: have %d, want %d", cfg.RPCBatchLimit, 100)
+	}
+	if cfg.RPCRateLimit != 50 {
+		t.Errorf("rate limit mismatch: have %d, want %d", cfg.RPCRateLimit, 50)
+	}
+}
+
+// Tests that the RPC method filters are threaded into the node config per
+// transport.
+func TestRPCMethodFlags(t *testing.T) {
+	flags := []cli.Flag{RPCAllowMethodsFlag, RPCDenyMethodsFlag, WSAllowMethodsFlag, WSDenyMethodsFlag}
+	ctx := newTestContext(t, flags, "--rpc.allowmethods", "vnt_call, vnt_getBalance", "--rpc.denymethods", "vnt_sendTransaction", "--ws.denymethods", "personal_*")
+
+	var cfg node.Config
+	setHTTP(ctx, &cfg)
+	setWS(ctx, &cfg)
+	if want := []string{"vnt_call", "vnt_getBalance"}; !reflect.DeepEqual(cfg.HTTPAllowMethods, want) {
+		t.Errorf("HTTP allowed methods mismatch: have %v, want %v", cfg.HTTPAllowMethods, want)
+	}
+	if want := []string{"vnt_sendTransaction"}; !reflect.DeepEqual(cfg.HTTPDenyMethods, want) {
+		t.Errorf("HTTP denied methods mismatch: have %v, want %v", cfg.HTTPDenyMethods, want)
+	}
+	if len(cfg.WSAllowMethods) != 0 {
+		t.Errorf("websocket allowed methods mismatch: have %v, want none", cfg.WSAllowMethods)
+	}
+	if want := []string{"personal_*"}; !reflect.DeepEqual(cfg.WSDenyMethods, want) {
+		t.Errorf("websocket denied methods mismatch: have %v, want %v", cfg.WSDenyMethods, want)
+	}
+}
+
+// Tests that the unlock list is capped by the configured maximum.
+func TestUnlockMax(t *testing.T) {
+	tests := []struct {
+		count, max int
+		valid      bool
+	}{
+		{3, 0, true},   // unlimited
+		{2, 3, true},   // under limit
+		{3, 3, true},   // at limit
+		{4, 3, false},  // over limit
+		{0, -1, false}, // invalid maximum
+	}
+	for i, tt := range tests {
+		if err := validateUnlockCount(tt.count, tt.max); (err == nil) != tt.valid {
+			t.Errorf("test %d: validity mismatch: have %v, want %v", i, err, tt.valid)
+		}
+	}
+	// Empty entries are kept for password alignment but not counted
+	ctx := newTestContext(t, []cli.Flag{UnlockedAccountFlag, UnlockMaxFlag}, "--unlock", "0,,1", "--unlock.max", "2")
+	if unlocks := MakeUnlockList(ctx); len(unlocks) != 3 {
+		t.Errorf("unlock list length mismatch: have %d, want %d", len(unlocks), 3)
+	}
+}
+
+// Tests that the block memory cap is converted from megabytes and threaded into
+// the VNT config.
+func TestVMMemCapFlag(t *testing.T) {
+	ctx := newTestContext(t, []cli.Flag{VMMemCapFlag}, "--vm.memcap", "64")
+	if have := vmMemoryCap(ctx); have != 64*1024*1024 {
+		t.Fatalf("memory cap mismatch: have %d, want %d", have, 64*1024*1024)
+	}
+	if have := vmMemoryCap(newTestContext(t, []cli.Flag{VMMemCapFlag})); have != 0 {
+		t.Fatalf("memory capped by default: %d", have)
+	}
+	if err := validateVMMemoryCap(1 << 60); err == nil {
+		t.Fatalf("overflowing memory cap accepted")
+	}
+}
+
+// Tests that peer bandwidth limits are parsed and threaded into the p2p config.
+func TestBandwidthLimitFlags(t *testing.T) {
+	ctx := newTestContext(t, []cli.Flag{P2PMaxDownloadFlag, P2PMaxUploadFlag},
+		"--p2p.maxdownload", "1048576", "--p2p.maxupload", "65536/peer")
+
+	var cfg vntp2p.Config
+	setBandwidthLimits(ctx, &cfg)
+	if want := (vntp2p.BandwidthLimit{Rate: 1048576}); cfg.MaxDownload != want {
+		t.Errorf("download limit mismatch: have %+v, want %+v", cfg.MaxDownload, want)
+	}
+	if want := (vntp2p.BandwidthLimit{Rate: 65536, PerPeer: true}); cfg.MaxUpload != want {
+		t.Errorf("upload limit mismatch: have %+v, want %+v", cfg.MaxUpload, want)
+	}
+	for _, s := range []string{"", "-1", "1mb", "100/node", "/peer"} {
+		if _, err := parseBandwidthLimit(s); err == nil {
+			t.Errorf("invalid bandwidth %q accepted", s)
+		}
+	}
+	if limit, err := parseBandwidthLimit("0"); err != nil || limit.Rate != 0 {
+		t.Errorf("unlimited bandwidth not accepted: %+v, %v", limit, err)
+	}
+}
+
+// Tests that developer mode sets up a single witness network funding and
+// producing with a freshly created local account.
+func TestDeveloperConfig(t *testing.T) {
+	dir, ks := newTestKeyStore(t, 0)
+	defer os.RemoveAll(dir)
+
+	ctx := newTestContext(t, []cli.Flag{DeveloperFlag, DeveloperPeriodFlag}, "--dev", "--dev.period", "3")
+
+	cfg := vnt.DefaultConfig
+	setDeveloper(ctx, ks, &cfg)
+
+	accs := ks.Accounts()
+	if len(accs) != 1 {
+		t.Fatalf("developer account count mismatch: have %d, want 1", len(accs))
+	}
+	dev := accs[0].Address
+	if cfg.Coinbase != dev || cfg.Signer != dev {
+		t.Errorf("developer account not producing: coinbase %x, signer %x, want %x", cfg.Coinbase, cfg.Signer, dev)
+	}
+	if cfg.Genesis == nil || len(cfg.Genesis.Witnesses) != 1 || cfg.Genesis.Witnesses[0] != dev {
+		t.Fatalf("developer account not the only witness: %v", cfg.Genesis)
+	}
+	if period := cfg.Genesis.Config.Dpos.Period; period != 3 {
+		t.Errorf("block period mismatch: have %d, want 3", period)
+	}
+	if balance := cfg.Genesis.Alloc[dev].Balance; balance == nil || balance.Sign() <= 0 {
+		t.Errorf("developer account not funded: %v", balance)
+	}
+	if cfg.NetworkId != 1337 {
+		t.Errorf("network id mismatch: have %d, want 1337", cfg.NetworkId)
+	}
+	if cfg.GasPrice.Sign() != 0 {
+		t.Errorf("gas price mismatch: have %v, want 0", cfg.GasPrice)
+	}
+}
+
+// Tests that the testnet flag moves the node into a dedicated datadir and
+// selects the built-in testnet genesis.
+func TestTestnetFlag(t *testing.T) {
+	ctx := newTestContext(t, []cli.Flag{TestnetFlag, DataDirFlag}, "--testnet", "--datadir", "/tmp/gvnt")
+
+	cfg := node.Config{DataDir: node.DefaultDataDir()}
+	SetNodeConfig(ctx, &cfg)
+	if want := filepath.Join("/tmp/gvnt", "testnet"); cfg.DataDir != want {
+		t.Errorf("datadir mismatch: have %s, want %s", cfg.DataDir, want)
+	}
+	if want := filepath.Join("/tmp/gvnt", "testnet"); MakeDataDir(ctx) != want {
+		t.Errorf("console datadir mismatch: have %s, want %s", MakeDataDir(ctx), want)
+	}
+	genesis := MakeGenesis(ctx)
+	if genesis == nil || genesis.ToBlock(nil).Hash() != params.TestnetGenesisHash {
+		t.Errorf("testnet genesis not selected")
+	}
+}
+
+// Tests that the InfluxDB tags are parsed into key/value pairs.
+func TestInfluxDBTags(t *testing.T) {
+	tags, err := parseInfluxDBTags("host=localhost, region=eu ,")
+	if err != nil {
+		t.Fatalf("failed to parse tags: %v", err)
+	}
+	want := map[string]string{"host": "localhost", "region": "eu"}
+	if !reflect.DeepEqual(tags, want) {
+		t.Errorf("tags mismatch: have %v, want %v", tags, want)
+	}
+	for _, s := range []string{"host", "=localhost", "host="} {
+		if _, err := parseInfluxDBTags(s); err == nil {
+			t.Errorf("invalid tags %q accepted", s)
+		}
+	}
+}
+
+// Tests that the remote console headers and TLS settings are parsed into the
+// dial options.
+func TestMakeDialOptions(t *testing.T) {
+	flags := []cli.Flag{AttachHeaderFlag, AttachTLSInsecureFlag}
+	ctx := newTestContext(t, flags, "--header", "Authorization: Bearer token", "--header", "X-Node:  gvnt", "--tls.insecure")
+
+	opts := MakeDialOptions(ctx)
+	if have := opts.Header.Get("Authorization"); have != "Bearer token" {
+		t.Errorf("authorization header mismatch: have %q, want %q", have, "Bearer token")
+	}
+	if have := opts.Header.Get("X-Node"); have != "gvnt" {
+		t.Errorf("custom header mismatch: have %q, want %q", have, "gvnt")
+	}
+	if opts.TLSConfig == nil || !opts.TLSConfig.InsecureSkipVerify {
+		t.Errorf("insecure TLS not configured: %+v", opts.TLSConfig)
+	}
+	if opts := MakeDialOptions(newTestContext(t, flags)); opts.TLSConfig != nil {
+		t.Errorf("TLS configured without flags")
+	}
+	for _, h := range []string{"Authorization", ": value"} {
+		if _, err := parseAttachHeaders([]string{h}); err == nil {
+			t.Errorf("invalid header %q accepted", h)
+		}
+	}
+	if _, err := makeAttachTLSConfig("", "cert.pem", "", false); err == nil {
+		t.Errorf("client certificate without key accepted")
+	}
+	if _, err := makeAttachTLSConfig("/nonexistent/ca.pem", "", "", false); err == nil {
+		t.Errorf("missing certificate authority accepted")
+	}
+}
+
+// Tests that trusted checkpoints are loaded from both files and URLs.
+func TestLightCheckpointFlag(t *testing.T) {
+	want := &params.TrustedCheckpoint{
+		Name:         "custom",
+		SectionIndex: 12,
+		SectionHead:  common.HexToHash("0x01"),
+		CHTRoot:      common.HexToHash("0x02"),
+		BloomRoot:    common.HexToHash("0x03"),
+	}
+	blob, _ := json.Marshal(want)
+
+	dir, err := ioutil.TempDir("", "utils-checkpoint-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "checkpoint.json")
+	if err := ioutil.WriteFile(path, blob, 0600); err != nil {
+		t.Fatal(err)
+	}
+	ctx := newTestContext(t, []cli.Flag{LightCheckpointFlag}, "--les.checkpoint", path)
+	var cfg vnt.Config
+	setLightCheckpoint(ctx, &cfg)
+	if !reflect.DeepEqual(cfg.Checkpoint, want) {
+		t.Errorf("file checkpoint mismatch: have %+v, want %+v", cfg.Checkpoint, want)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/checkpoint.json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(blob)
+	}))
+	defer server.Close()
+
+	if have, err := loadCheckpoint(server.URL + "/checkpoint.json"); err != nil || !reflect.DeepEqual(have, want) {
+		t.Errorf("downloaded checkpoint mismatch: have %+v (%v), want %+v", have, err, want)
+	}
+	if _, err := loadCheckpoint(server.URL + "/missing.json"); err == nil {
+		t.Errorf("missing remote checkpoint accepted")
+	}
+	if err := ioutil.WriteFile(path, []byte(`{"sectionIndex": 12}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadCheckpoint(path); err == nil {
+		t.Errorf("checkpoint without roots accepted")
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of go-vnt.
+//
+// go-vnt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-vnt is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-vnt. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/consensus"
+	"github.com/vntchain/go-vnt/core"
+	"github.com/vntchain/go-vnt/core/types"
+	"github.com/vntchain/go-vnt/event"
+	"github.com/vntchain/go-vnt/log"
+)
+
+// LogForkChoice logs every switch of the canonical head to a competing branch,
+// reporting both branch heads, the witnesses which signed them and the rule that
+// decided the winner. Nothing is done while the chain grows without forks.
+// Unsubscribing the returned subscription stops the logging.
+func LogForkChoice(chain *core.BlockChain, engine consensus.Engine, logger log.Logger) event.Subscription {
+	events := make(chan core.ForkChoiceEvent, 16)
+	sub := chain.SubscribeForkChoiceEvent(events)
+
+	go func() {
+		for {
+			select {
+			case ev := <-events:
+				logger.Info("Fork choice resolved", forkChoiceContext(engine, ev)...)
+			case <-sub.Err():
+				return
+			}
+		}
+	}()
+	return sub
+}
+
+// forkChoiceContext assembles the log context describing a fork choice.
+func forkChoiceContext(engine consensus.Engine, ev core.ForkChoiceEvent) []interface{} {
+	return []interface{}{
+		"rule", string(ev.Rule),
+		"number", ev.NewHead.Number, "hash", ev.NewHead.Hash(), "signer", witnessOf(engine, ev.NewHead), "td", ev.NewTd,
+		"oldnumber", ev.OldHead.Number, "oldhash", ev.OldHead.Hash(), "oldsigner", witnessOf(engine, ev.OldHead), "oldtd", ev.OldTd,
+	}
+}
+
+// witnessOf returns the witness which signed the given header, or the zero
+// address if it cannot be recovered.
+func witnessOf(engine consensus.Engine, header *types.Header) common.Address {
+	signer, err := engine.Author(header)
+	if err != nil {
+		return common.Address{}
+	}
+	return signer
+}
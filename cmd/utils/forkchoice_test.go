@@ -0,0 +1,98 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of go-vnt.
+//
+// go-vnt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-vnt is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-vnt. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/consensus/mock"
+	"github.com/vntchain/go-vnt/core"
+	"github.com/vntchain/go-vnt/core/vm"
+	"github.com/vntchain/go-vnt/log"
+	"github.com/vntchain/go-vnt/params"
+	"github.com/vntchain/go-vnt/vntdb"
+)
+
+// Tests that a head switch between competing branches is logged with both heads,
+// their witnesses and the deciding rule, while plain chain growth is not.
+func TestLogForkChoice(t *testing.T) {
+	var (
+		db      = vntdb.NewMemDatabase()
+		gspec   = &core.Genesis{Config: params.TestChainConfig}
+		genesis = gspec.MustCommit(db)
+		engine  = mock.NewMock()
+
+		localWitness = common.HexToAddress("0x01")
+		forkWitness  = common.HexToAddress("0x02")
+	)
+	chain, err := core.NewBlockChain(db, nil, gspec.Config, engine, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	records := make(chan *log.Record, 16)
+	logger := log.New()
+	logger.SetHandler(log.ChannelHandler(records))
+
+	sub := LogForkChoice(chain, engine, logger)
+	defer sub.Unsubscribe()
+
+	local, _ := core.GenerateChain(params.TestChainConfig, genesis, engine, db, 2, func(i int, gen *core.BlockGen) {
+		gen.SetCoinbase(localWitness)
+		if i == 1 {
+			gen.OffsetTime(4)
+		}
+	})
+	if _, err := chain.InsertChain(local); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	select {
+	case r := <-records:
+		t.Fatalf("unexpected log without fork: %s %v", r.Msg, r.Ctx)
+	case <-time.After(100 * time.Millisecond):
+	}
+	fork, _ := core.GenerateChain(params.TestChainConfig, genesis, engine, db, 2, func(i int, gen *core.BlockGen) {
+		gen.SetCoinbase(forkWitness)
+	})
+	if _, err := chain.InsertChain(fork); err != nil {
+		t.Fatalf("failed to insert competing branch: %v", err)
+	}
+	select {
+	case r := <-records:
+		ctx := make(map[interface{}]interface{})
+		for i := 0; i < len(r.Ctx); i += 2 {
+			ctx[r.Ctx[i]] = r.Ctx[i+1]
+		}
+		want := map[string]interface{}{
+			"rule":      string(core.ForkChoiceTime),
+			"hash":      fork[1].Hash(),
+			"signer":    forkWitness,
+			"oldhash":   local[1].Hash(),
+			"oldsigner": localWitness,
+		}
+		for key, value := range want {
+			if ctx[key] != value {
+				t.Errorf("%s mismatch: have %v, want %v", key, ctx[key], value)
+			}
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("fork choice not logged")
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of go-vnt.
+//
+// go-vnt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-vnt is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-vnt. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/core"
+	"github.com/vntchain/go-vnt/params"
+	"github.com/vntchain/go-vnt/vntp2p"
+)
+
+// MinGenesisWitnesses is the smallest witness set a DPoS genesis may define.
+const MinGenesisWitnesses = 3
+
+// GenesisErrors collects all the problems found in a genesis specification.
+type GenesisErrors []error
+
+func (errs GenesisErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d genesis problem(s):\n  %s", len(errs), strings.Join(msgs, "\n  "))
+}
+
+// ValidateGenesis parses the genesis file at path and checks it for problems
+// that would prevent a network from starting. All the problems found are
+// reported at once as GenesisErrors.
+func ValidateGenesis(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to read genesis file: %v", err)
+	}
+	defer file.Close()
+
+	genesis := new(core.Genesis)
+	if err := json.NewDecoder(file).Decode(genesis); err != nil {
+		return fmt.Errorf("invalid genesis file: %v", err)
+	}
+	return CheckGenesis(genesis)
+}
+
+// CheckGenesis checks a genesis specification for the same problems as
+// ValidateGenesis, reporting them as GenesisErrors.
+func CheckGenesis(genesis *core.Genesis) error {
+	if errs := checkGenesis(genesis); len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// checkGenesis validates a decoded genesis specification.
+func checkGenesis(genesis *core.Genesis) GenesisErrors {
+	var errs GenesisErrors
+
+	// Check the DPoS witness list
+	if genesis.Config == nil || genesis.Config.Dpos == nil {
+		errs = append(errs, fmt.Errorf("missing dpos config"))
+	} else {
+		dpos := genesis.Config.Dpos
+		if dpos.WitnessesNum < MinGenesisWitnesses {
+			errs = append(errs, fmt.Errorf("witness count %d below minimum of %d", dpos.WitnessesNum, MinGenesisWitnesses))
+		}
+		if dpos.WitnessesNum%2 == 0 {
+			errs = append(errs, fmt.Errorf("witness count %d must be odd", dpos.WitnessesNum))
+		}
+		if len(genesis.Witnesses) != dpos.WitnessesNum {
+			errs = append(errs, fmt.Errorf("witness list length %d does not match witness count %d", len(genesis.Witnesses), dpos.WitnessesNum))
+		}
+		if dpos.EpochLength != 0 && dpos.EpochLength < uint64(dpos.WitnessesNum) {
+			errs = append(errs, fmt.Errorf("epoch length %d shorter than a round of %d witnesses", dpos.EpochLength, dpos.WitnessesNum))
+		}
+		if len(dpos.WitnessesUrl) != dpos.WitnessesNum {
+			errs = append(errs, fmt.Errorf("witness url list length %d does not match witness count %d", len(dpos.WitnessesUrl), dpos.WitnessesNum))
+		}
+		for i, url := range dpos.WitnessesUrl {
+			if _, err := vntp2p.ParseNode(url); err != nil {
+				errs = append(errs, fmt.Errorf("witness url %d %q invalid: %v", i, url, err))
+			}
+		}
+	}
+	seen := make(map[common.Address]int)
+	for i, witness := range genesis.Witnesses {
+		if witness == (common.Address{}) {
+			errs = append(errs, fmt.Errorf("witness %d is the zero address", i))
+		}
+		if j, ok := seen[witness]; ok {
+			errs = append(errs, fmt.Errorf("witness %d duplicates witness %d (%x)", i, j, witness))
+			continue
+		}
+		seen[witness] = i
+	}
+	// Check the allocations, in a stable order for reproducible reports
+	addrs := make([]common.Address, 0, len(genesis.Alloc))
+	for addr := range genesis.Alloc {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return bytes.Compare(addrs[i][:], addrs[j][:]) < 0 })
+	for _, addr := range addrs {
+		account := genesis.Alloc[addr]
+		if account.Balance == nil {
+			errs = append(errs, fmt.Errorf("allocation %x missing balance", addr))
+		} else if account.Balance.Sign() < 0 {
+			errs = append(errs, fmt.Errorf("allocation %x has negative balance %v", addr, account.Balance))
+		}
+	}
+	// Check the gas limit sanity
+	if genesis.GasLimit < params.MinGasLimit {
+		errs = append(errs, fmt.Errorf("gas limit %d below minimum of %d", genesis.GasLimit, params.MinGasLimit))
+	}
+	if genesis.GasLimit > math.MaxInt64 {
+		errs = append(errs, fmt.Errorf("gas limit %d above maximum of %d", genesis.GasLimit, int64(math.MaxInt64)))
+	}
+	return errs
+}
@@ -0,0 +1,158 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of go-vnt.
+//
+// go-vnt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-vnt is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-vnt. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/core"
+	"github.com/vntchain/go-vnt/params"
+)
+
+// newTestGenesis creates a valid DPoS genesis with three witnesses.
+func newTestGenesis() *core.Genesis {
+	return &core.Genesis{
+		Config: &params.ChainConfig{
+			ChainID: big.NewInt(1),
+			Dpos: &params.DposConfig{
+				Period:       2,
+				WitnessesNum: 3,
+				WitnessesUrl: []string{
+					"/ip4/127.0.0.1/tcp/30303/ipfs/1kHGG8L1DTrVG3Cad479Q32oGmFAiEjLFwxzNyXH3ehGo73",
+					"/ip4/127.0.0.1/tcp/30303/ipfs/1kHGq5zZFRW5FBJ9YMbbvSiW4AzGg5CKMCtDeg6FNnjCbGS",
+					"/ip4/127.0.0.1/tcp/30303/ipfs/1kHJFKr2bzUnMr1NbeyYbYJa3RXT18cEu7cNDrHWjg8XYKB",
+				},
+			},
+		},
+		GasLimit:   params.GenesisGasLimit,
+		Difficulty: big.NewInt(1),
+		Alloc: core.GenesisAlloc{
+			common.Address{0xaa}: {Balance: big.NewInt(1000)},
+		},
+		Witnesses: []common.Address{{0x01}, {0x02}, {0x03}},
+	}
+}
+
+// validateTestGenesis writes the genesis into a temporary file and validates it.
+func validateTestGenesis(t *testing.T, genesis *core.Genesis) error {
+	blob, err := json.Marshal(genesis)
+	if err != nil {
+		t.Fatalf("failed to encode genesis: %v", err)
+	}
+	return validateTestGenesisJSON(t, blob)
+}
+
+// validateTestGenesisJSON writes the raw genesis into a temporary file and
+// validates it.
+func validateTestGenesisJSON(t *testing.T, blob []byte) error {
+	file, err := ioutil.TempFile("", "genesis-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if _, err := file.Write(blob); err != nil {
+		t.Fatal(err)
+	}
+	return ValidateGenesis(file.Name())
+}
+
+// Tests that a well formed genesis passes validation.
+func TestValidateGenesis(t *testing.T) {
+	if err := validateTestGenesis(t, newTestGenesis()); err != nil {
+		t.Fatalf("valid genesis rejected: %v", err)
+	}
+}
+
+// Tests that malformed genesis files are rejected.
+func TestValidateGenesisMalformed(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(g *core.Genesis)
+	}{
+		{"duplicate witness", func(g *core.Genesis) {
+			g.Witnesses[2] = g.Witnesses[0]
+		}},
+		{"even witness count", func(g *core.Genesis) {
+			g.Config.Dpos.WitnessesNum = 4
+			g.Config.Dpos.WitnessesUrl = append(g.Config.Dpos.WitnessesUrl, "/ip4/127.0.0.1/tcp/30303/ipfs/1kHNAAfnqXNsxMwJf6QjJFRmVK7iB32U9owwK9KfeLFxEA7")
+			g.Witnesses = append(g.Witnesses, common.Address{0x04})
+		}},
+		{"too few witnesses", func(g *core.Genesis) {
+			g.Config.Dpos.WitnessesNum = 1
+			g.Config.Dpos.WitnessesUrl = g.Config.Dpos.WitnessesUrl[:1]
+			g.Witnesses = g.Witnesses[:1]
+		}},
+		{"witness count mismatch", func(g *core.Genesis) {
+			g.Witnesses = g.Witnesses[:2]
+		}},
+		{"invalid witness url", func(g *core.Genesis) {
+			g.Config.Dpos.WitnessesUrl[1] = "127.0.0.1:30303"
+		}},
+		{"low gas limit", func(g *core.Genesis) {
+			g.GasLimit = params.MinGasLimit - 1
+		}},
+		{"missing dpos config", func(g *core.Genesis) {
+			g.Config.Dpos = nil
+		}},
+	}
+	for _, tt := range tests {
+		genesis := newTestGenesis()
+		tt.mutate(genesis)
+		if err := checkGenesis(genesis); err == nil {
+			t.Errorf("%s: malformed genesis accepted", tt.name)
+		}
+	}
+}
+
+// Tests that all the problems of a genesis are reported at once.
+func TestValidateGenesisReportsAll(t *testing.T) {
+	genesis := newTestGenesis()
+	genesis.Witnesses[2] = genesis.Witnesses[0]
+	genesis.Alloc[common.Address{0xbb}] = core.GenesisAccount{Balance: big.NewInt(-1)}
+	genesis.GasLimit = 0
+
+	if errs := checkGenesis(genesis); len(errs) != 3 {
+		t.Fatalf("problem count mismatch: have %d, want %d: %v", len(errs), 3, errs)
+	}
+}
+
+// Tests that a negative allocation balance in a genesis file is reported.
+func TestValidateGenesisNegativeBalance(t *testing.T) {
+	blob, err := json.Marshal(newTestGenesis())
+	if err != nil {
+		t.Fatalf("failed to encode genesis: %v", err)
+	}
+	var raw map[string]interface{}
+	if err := json.Unmarshal(blob, &raw); err != nil {
+		t.Fatalf("failed to decode genesis: %v", err)
+	}
+	raw["alloc"].(map[string]interface{})["bb00000000000000000000000000000000000000"] = map[string]string{"balance": "-1"}
+	if blob, err = json.Marshal(raw); err != nil {
+		t.Fatalf("failed to encode genesis: %v", err)
+	}
+	err = validateTestGenesisJSON(t, blob)
+	if errs, ok := err.(GenesisErrors); !ok || len(errs) != 1 {
+		t.Fatalf("negative balance not reported: %v", err)
+	}
+}
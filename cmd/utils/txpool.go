@@ -0,0 +1,51 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of go-vnt.
+//
+// go-vnt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-vnt is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-vnt. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/vntchain/go-vnt/core"
+)
+
+// DumpTxPool writes the pending and queued transactions of the pool to w as
+// JSON, grouped by account and annotated with the nonce gaps holding back the
+// queued ones.
+func DumpTxPool(pool *core.TxPool, w io.Writer) error {
+	pending, queued := pool.Content()
+	nonces := pool.State()
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(core.NewTxPoolDump(pending, queued, nonces.GetNonce))
+}
+
+// DumpTxPoolFile writes a transaction pool snapshot into the file at path,
+// replacing any previous content.
+func DumpTxPoolFile(pool *core.TxPool, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := DumpTxPool(pool, f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
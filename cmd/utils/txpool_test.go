@@ -0,0 +1,135 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of go-vnt.
+//
+// go-vnt is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-vnt is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-vnt. If not, see <http://www.gnu.org/licenses/>.
+
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/core"
+	"github.com/vntchain/go-vnt/core/state"
+	"github.com/vntchain/go-vnt/core/types"
+	"github.com/vntchain/go-vnt/crypto"
+	"github.com/vntchain/go-vnt/event"
+	"github.com/vntchain/go-vnt/params"
+	"github.com/vntchain/go-vnt/vntdb"
+)
+
+// txPoolTestChain is a fake chain backing a transaction pool with a fixed state.
+type txPoolTestChain struct {
+	statedb *state.StateDB
+	feed    event.Feed
+}
+
+func (bc *txPoolTestChain) CurrentBlock() *types.Block {
+	return types.NewBlock(&types.Header{GasLimit: 1000000}, nil, nil)
+}
+
+func (bc *txPoolTestChain) GetBlock(hash common.Hash, number uint64) *types.Block {
+	return bc.CurrentBlock()
+}
+
+func (bc *txPoolTestChain) StateAt(common.Hash) (*state.StateDB, error) {
+	return bc.statedb, nil
+}
+
+func (bc *txPoolTestChain) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return bc.feed.Subscribe(ch)
+}
+
+// newTestTxPool creates a transaction pool on top of a fake chain, funding the
+// given accounts.
+func newTestTxPool(t *testing.T, funded ...common.Address) *core.TxPool {
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(vntdb.NewMemDatabase()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, addr := range funded {
+		statedb.AddBalance(addr, big.NewInt(1000000000))
+	}
+	config := core.DefaultTxPoolConfig
+	config.Journal = ""
+	return core.NewTxPool(config, params.TestChainConfig, &txPoolTestChain{statedb: statedb})
+}
+
+// txPoolDumpEntry mirrors the JSON shape of a single account of a pool dump.
+type txPoolDumpEntry struct {
+	Nonce   uint64            `json:"nonce"`
+	Pending []json.RawMessage `json:"pending"`
+	Queued  []json.RawMessage `json:"queued"`
+	Gaps    []struct {
+		From uint64 `json:"from"`
+		To   uint64 `json:"to"`
+	} `json:"gaps"`
+}
+
+// Tests that the pool dump lists pending and queued transactions per account
+// along with the nonce gaps in between.
+func TestDumpTxPool(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+
+	pool := newTestTxPool(t, addr)
+	defer pool.Stop()
+
+	signer := types.NewHubbleSigner(params.TestChainConfig.ChainID)
+	for _, nonce := range []uint64{0, 1, 4} {
+		tx, _ := types.SignTx(types.NewTransaction(nonce, common.Address{}, big.NewInt(1), 21000, big.NewInt(1), nil), signer, key)
+		if err := pool.AddRemote(tx); err != nil {
+			t.Fatalf("failed to add transaction %d: %v", nonce, err)
+		}
+	}
+	var buf bytes.Buffer
+	if err := DumpTxPool(pool, &buf); err != nil {
+		t.Fatalf("failed to dump pool: %v", err)
+	}
+	var dump map[string]txPoolDumpEntry
+	if err := json.Unmarshal(buf.Bytes(), &dump); err != nil {
+		t.Fatalf("failed to decode dump: %v\n%s", err, buf.String())
+	}
+	if len(dump) != 1 {
+		t.Fatalf("account count mismatch: have %d, want 1", len(dump))
+	}
+	entry, ok := dump[strings.ToLower(addr.Hex())]
+	if !ok {
+		t.Fatalf("account %x missing from dump: %s", addr, buf.String())
+	}
+	if entry.Nonce != 2 || len(entry.Pending) != 2 || len(entry.Queued) != 1 {
+		t.Errorf("content mismatch: nonce %d, pending %d, queued %d", entry.Nonce, len(entry.Pending), len(entry.Queued))
+	}
+	if len(entry.Gaps) != 1 || entry.Gaps[0].From != 2 || entry.Gaps[0].To != 3 {
+		t.Errorf("gap mismatch: have %v, want [{2 3}]", entry.Gaps)
+	}
+}
+
+// Tests that an empty pool dumps into an empty JSON object.
+func TestDumpTxPoolEmpty(t *testing.T) {
+	pool := newTestTxPool(t)
+	defer pool.Stop()
+
+	var buf bytes.Buffer
+	if err := DumpTxPool(pool, &buf); err != nil {
+		t.Fatalf("failed to dump pool: %v", err)
+	}
+	if have := strings.TrimSpace(buf.String()); have != "{}" {
+		t.Errorf("empty dump mismatch: have %s, want {}", have)
+	}
+}
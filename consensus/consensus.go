@@ -102,3 +102,11 @@ type Engine interface {
 	// APIs returns the RPC APIs this consensus engine provides.
 	APIs(chain ChainReader) []rpc.API
 }
+
+// FinalityChecker is implemented by consensus engines able to tell which blocks
+// of a chain can no longer be reverted.
+type FinalityChecker interface {
+	// Irreversible returns the newest header at or below head that can no longer
+	// be reorganised out of the chain, or nil if none is known.
+	Irreversible(chain ChainReader, head *types.Header) *types.Header
+}
@@ -17,7 +17,10 @@
 package dpos
 
 import (
+	"fmt"
+
 	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/common/hexutil"
 	"github.com/vntchain/go-vnt/consensus"
 	"github.com/vntchain/go-vnt/core/types"
 	"github.com/vntchain/go-vnt/rpc"
@@ -104,3 +107,34 @@ func (api *API) GetCurrentHeight() *big.Int {
 func (api *API) GetCurrentRound() uint32 {
 	return api.dpos.bft.r
 }
+
+// PublicAPI is a user facing RPC API exposing the block production record of
+// the witnesses.
+type PublicAPI struct {
+	chain consensus.ChainReader
+	dpos  *Dpos
+}
+
+// GetWitnessPerformance retrieves the block production record of the witnesses
+// over the window blocks ending at the specified block, so that voters can
+// compare them. The window defaults to the last 1000 blocks and is capped at
+// 10000 blocks.
+func (api *PublicAPI) GetWitnessPerformance(window *hexutil.Uint64, number *rpc.BlockNumber) (*WitnessPerformanceReport, error) {
+	blocks := uint64(defaultPerformanceWindow)
+	if window != nil {
+		blocks = uint64(*window)
+	}
+	if blocks > maxPerformanceWindow {
+		return nil, fmt.Errorf("window of %d blocks above the maximum of %d", blocks, maxPerformanceWindow)
+	}
+	var header *types.Header
+	if number == nil || *number == rpc.LatestBlockNumber || *number == rpc.PendingBlockNumber {
+		header = api.chain.CurrentHeader()
+	} else {
+		header = api.chain.GetHeaderByNumber(uint64(number.Int64()))
+	}
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	return api.dpos.WitnessPerformance(api.chain, header, blocks), nil
+}
@@ -46,7 +46,9 @@ import (
 
 const (
 	inMemorySignatures = 4096 // Number of recent block signatures to keep in memory
+	inMemorySealed     = 128  // Number of recent locally sealed blocks to keep in memory
 	updateTimeLen      = 8    // Number of bytes the witnesses list update time take up
+	irreversibleRounds = 3    // Number of witness rounds searched back for an irreversible block
 )
 
 var (
@@ -91,8 +93,19 @@ var (
 
 	// errInvalidExtraLen is returned if extra length is invalid
 	errInvalidExtraLen = errors.New("invalid Extra length")
+
+	// errUnsynced is returned when skipping an own slot while synchronising
+	errUnsynced = errors.New("chain is synchronising")
 )
 
+// Timing is the node local policy of block production timing. Apart from the
+// clock drift, it doesn't affect the validation of the blocks of others.
+type Timing struct {
+	SlotTolerance time.Duration // Minimum time left before a slot to produce in it, closer slots are skipped
+	MaxClockDrift time.Duration // Maximum time a block may be ahead of the local clock (0 = unchecked)
+	SkipUnsynced  bool          // Skip own slots while the chain is being synchronised
+}
+
 type SignerFn func(accounts.Account, []byte) ([]byte, error)
 
 // getHeaderFromParentsFn get header from previous headers
@@ -103,10 +116,14 @@ type Dpos struct {
 	bft            *BftManager
 	db             vntdb.Database // Database to store and retrieve dpos temp data, current not used
 	signatures     *lru.ARCCache  // Signatures of recent blocks to speed up block producing
+	sealed         *lru.ARCCache  // Hashes of the recent blocks sealed by this node
+	performance    *lru.ARCCache  // Witness performance of recent block segments to speed up reports
 	signer         common.Address // VNT address of the signing key
 	signFn         SignerFn       // Signer function to authorize hashes with
-	lock           sync.RWMutex   // Protects the signer fields
+	lock           sync.RWMutex   // Protects the signer and timing fields
 	updateInterval *big.Int       // Duration of update witnesses list
+	timing         Timing         // Block production timing policy
+	synced         func() bool    // Reports whether the chain is in sync, nil if unknown
 	lastBounty     lastBountyInfo // 上次发放激励的信息
 
 	sendBftPeerUpdateFn func(urls []string)
@@ -173,12 +190,16 @@ func ecrecover(header *types.Header, sigcache *lru.ARCCache) (common.Address, er
 // signers set to the ones provided by the user.
 func New(config *params.DposConfig, db vntdb.Database) *Dpos {
 	signatures, _ := lru.NewARC(inMemorySignatures)
+	sealed, _ := lru.NewARC(inMemorySealed)
+	performance, _ := lru.NewARC(inMemoryPerformance)
 
 	d := &Dpos{
 		config:         config,
 		bft:            nil,
 		db:             db,
 		signatures:     signatures,
+		sealed:         sealed,
+		performance:    performance,
 		updateInterval: nil,
 
 		lastBounty: lastBountyInfo{
@@ -255,11 +276,13 @@ func (d *Dpos) verifyHeader(chain consensus.ChainReader, header *types.Header, p
 	}
 	number := header.Number.Uint64()
 
-	// todo verify header.time after implement bft without time trigger produce block
-	// Don't waste time checking blocks from the future
-	// if header.Time.Cmp(big.NewInt(time.Now().Unix())) > 0 {
-	// 	return consensus.ErrFutureBlock
-	// }
+	// Don't waste time checking blocks too far from the future
+	d.lock.RLock()
+	drift := d.timing.MaxClockDrift
+	d.lock.RUnlock()
+	if drift > 0 && time.Until(time.Unix(header.Time.Int64(), 0)) > drift {
+		return consensus.ErrFutureBlock
+	}
 
 	// Ensure extra has correct length' value checked in verify witnesses
 	if len(header.Extra) != updateTimeLen {
@@ -405,6 +428,7 @@ func (d *Dpos) Prepare(chain consensus.ChainReader, header *types.Header) error
 
 	d.lock.RLock()
 	header.Coinbase = d.signer
+	timing, synced := d.timing, d.synced
 	d.lock.RUnlock()
 
 	// Set the correct difficulty
@@ -420,7 +444,7 @@ func (d *Dpos) Prepare(chain consensus.ChainReader, header *types.Header) error
 	}
 
 	// Put next time in header
-	produceTime, nPeriod, err := d.nextProduceTime(parent.Time)
+	produceTime, nPeriod, err := d.nextProduceTime(parent.Time, timing.SlotTolerance)
 	if err != nil {
 		return err
 	}
@@ -443,6 +467,11 @@ func (d *Dpos) Prepare(chain consensus.ChainReader, header *types.Header) error
 		log.Debug("Prepare failed", "err", errOutTurn)
 		return fmt.Errorf("node is out of turn")
 	}
+	// Skip the own slot while catching up, the block would extend a stale head
+	if timing.SkipUnsynced && synced != nil && !synced() {
+		log.Info("Skipping own slot while synchronising", "number", number, "time", header.Time)
+		return errUnsynced
+	}
 
 	// Fill Extra with the update time
 	// If this updated the witnesses list in this block, extra = this header time
@@ -470,6 +499,11 @@ func (d *Dpos) Finalize(chain consensus.ChainReader, header *types.Header, state
 		return nil, err
 	}
 
+	// Record the production slots missed before this block
+	if err := d.recordMissedSlots(chain, header, state); err != nil {
+		return nil, err
+	}
+
 	// Commit db
 	header.Root = state.IntermediateRoot(true)
 
@@ -514,6 +548,38 @@ func (d *Dpos) grantingReward(chain consensus.ChainReader, header *types.Header,
 	return nil
 }
 
+// recordMissedSlots accounts the production slots skipped between the parent
+// block and this one to the witnesses in turn for them, and clears the missed
+// slots of the producer of this block. The records are cleared on every witness
+// list update, so a penalized witness only sits out a single witness list.
+func (d *Dpos) recordMissedSlots(chain consensus.ChainReader, header *types.Header, state *state.StateDB) error {
+	if d.config.MaxMissedSlots == 0 || header.Number.Uint64() <= 1 {
+		return nil
+	}
+	parent := chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	if parent == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	if d.updatedWitnessCheckByTime(header) {
+		return election.ResetMissedSlots(state, parent.Witnesses)
+	}
+
+	manager := NewManager(d.config.Period, header.Witnesses)
+	pIndex := manager.indexOf(parent.Coinbase)
+	if pIndex == -1 {
+		return nil
+	}
+	for _, witness := range manager.skipped(pIndex, parent.Time, header.Time) {
+		if addressEqual(witness, header.Coinbase) {
+			continue
+		}
+		if err := election.AddMissedSlots(state, witness, 1); err != nil {
+			return err
+		}
+	}
+	return election.ResetMissedSlots(state, []common.Address{header.Coinbase})
+}
+
 // Authorize injects a private key into the consensus engine to mint new blocks
 // with.
 func (d *Dpos) Authorize(signer common.Address, signFn SignerFn) {
@@ -524,6 +590,26 @@ func (d *Dpos) Authorize(signer common.Address, signFn SignerFn) {
 	d.signFn = signFn
 }
 
+// SetTiming sets the block production timing policy of the engine.
+func (d *Dpos) SetTiming(timing Timing) {
+	if period := time.Duration(d.config.Period) * time.Second; timing.SlotTolerance >= period {
+		log.Warn("Slot tolerance not below block period, the upcoming slot is always skipped", "tolerance", timing.SlotTolerance, "period", period)
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.timing = timing
+}
+
+// SetSyncStatus sets the function reporting whether the chain is in sync, which
+// is consulted before producing if own slots are skipped while synchronising.
+func (d *Dpos) SetSyncStatus(synced func() bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.synced = synced
+}
+
 // Seal implements consensus.Engine, attempting to create a sealed block using
 // the local signing credentials.
 func (d *Dpos) Seal(chain consensus.ChainReader, block *types.Block, stop <-chan struct{}) (*types.Block, error) {
@@ -547,6 +633,7 @@ func (d *Dpos) Seal(chain consensus.ChainReader, block *types.Block, stop <-chan
 	}
 	header.Signature = make([]byte, len(sighash))
 	copy(header.Signature[:], sighash)
+	d.sealed.Add(header.Hash(), struct{}{})
 
 	log.Info("Seal block", "at time", time.Now().Unix())
 	d.bft.startPrePrepare(block.WithSeal(header))
@@ -554,6 +641,45 @@ func (d *Dpos) Seal(chain consensus.ChainReader, block *types.Block, stop <-chan
 	return nil, nil
 }
 
+// SealedLocally reports whether the block with the given hash was recently
+// sealed by this node, telling it apart from blocks of other nodes sharing the
+// same signing key.
+func (d *Dpos) SealedLocally(hash common.Hash) bool {
+	return d.sealed.Contains(hash)
+}
+
+// MissedRounds returns the number of consecutive rounds, as of now, in which the
+// witness did not produce a block on the chain ending at head. Every witness of
+// head has one slot per round, so a round is assumed to end one round length
+// after the witness last produced. The search back stops at limit rounds, and
+// witnesses outside the witness list never miss a round.
+func (d *Dpos) MissedRounds(chain consensus.ChainReader, head *types.Header, witness common.Address, now time.Time, limit uint64) uint64 {
+	manager := NewManager(d.config.Period, head.Witnesses)
+	if !manager.has(witness) || d.config.Period == 0 {
+		return 0
+	}
+	round := d.config.Period * uint64(len(manager.Witnesses))
+	missed := func(last *big.Int) uint64 {
+		if now.Unix() <= last.Int64() {
+			return 0
+		}
+		return uint64(now.Unix()-last.Int64()) / round
+	}
+	for header := head; ; {
+		if addressEqual(header.Coinbase, witness) || header.Number.Sign() == 0 {
+			return missed(header.Time)
+		}
+		if rounds := missed(header.Time); rounds >= limit {
+			return rounds
+		}
+		parent := chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+		if parent == nil {
+			return missed(header.Time)
+		}
+		header = parent
+	}
+}
+
 // CalcDifficulty is the difficulty adjustment algorithm. It returns the difficulty
 // that a new block should have based on the previous blocks in the chain and the
 // current signer.
@@ -561,14 +687,48 @@ func (d *Dpos) CalcDifficulty(chain consensus.ChainReader, time uint64, parent *
 	return common.Big1
 }
 
+// Irreversible implements consensus.FinalityChecker, returning the newest block
+// on top of which a quorum of the head's witnesses has already produced blocks.
+func (d *Dpos) Irreversible(chain consensus.ChainReader, head *types.Header) *types.Header {
+	witnesses := make(map[common.Address]struct{}, len(head.Witnesses))
+	for _, w := range head.Witnesses {
+		witnesses[w] = struct{}{}
+	}
+	quorum := len(witnesses) - (len(witnesses)-1)/3 // N-f, same as the BFT rounds
+
+	producers := make(map[common.Address]struct{}, quorum)
+	header := head
+	for i := 0; header != nil && i < irreversibleRounds*len(witnesses); i++ {
+		if header.Number.Sign() == 0 {
+			return header
+		}
+		if producer, err := d.Author(header); err == nil {
+			if _, ok := witnesses[producer]; ok {
+				producers[producer] = struct{}{}
+			}
+		}
+		if len(producers) >= quorum {
+			return header
+		}
+		header = chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	}
+	return nil
+}
+
 // APIs implements consensus.Engine, returning the user facing RPC API to allow
-// controlling the signer voting.
+// controlling the signer voting and the public one reporting the performance of
+// the witnesses.
 func (d *Dpos) APIs(chain consensus.ChainReader) []rpc.API {
 	return []rpc.API{{
 		Namespace: "dpos",
 		Version:   "1.0",
 		Service:   &API{chain: chain, dpos: d},
 		Public:    false,
+	}, {
+		Namespace: "dpos",
+		Version:   "1.0",
+		Service:   &PublicAPI{chain: chain, dpos: d},
+		Public:    true,
 	}}
 }
 
@@ -578,10 +738,13 @@ func (d *Dpos) APIs(chain consensus.ChainReader) []rpc.API {
 // 		nPeriod = dur / interval
 // 		// always up bound
 // 		nPeriod++
+// 		// skip the slot if less than the tolerance is left before it
+// 		if parent_time + nPeriod * interval - cur_time < tolerance
+// 			nPeriod++
 // 		return parent_time + diff_index * interval
-func (d *Dpos) nextProduceTime(preBlockTime *big.Int) (produceTime *big.Int, nPeriod *big.Int, err error) {
-	now := time.Now().Unix()
-	dur := new(big.Int).Sub(new(big.Int).SetInt64(now), preBlockTime)
+func (d *Dpos) nextProduceTime(preBlockTime *big.Int, tolerance time.Duration) (produceTime *big.Int, nPeriod *big.Int, err error) {
+	now := time.Now()
+	dur := new(big.Int).Sub(new(big.Int).SetInt64(now.Unix()), preBlockTime)
 	period := new(big.Int).SetUint64(d.config.Period)
 	// the unit is second, even no left of DivMod, but current time is in new period
 	nPeriod = new(big.Int).Div(dur, period)
@@ -590,6 +753,10 @@ func (d *Dpos) nextProduceTime(preBlockTime *big.Int) (produceTime *big.Int, nPe
 	nextTime := new(big.Int).Mul(nPeriod, period)
 	nextTime.Add(nextTime, preBlockTime)
 
+	if tolerance > 0 && time.Unix(nextTime.Int64(), 0).Sub(now) < tolerance {
+		nPeriod.Add(nPeriod, common.Big1)
+		nextTime.Add(nextTime, period)
+	}
 	return nextTime, nPeriod, nil
 }
 
@@ -747,7 +914,7 @@ func (d *Dpos) GetWitnessesFromStateDB(stateDB *state.StateDB) ([]common.Address
 		log.Error("GetWitnessesFromStateDB, stateDB is nil")
 	}
 
-	return election.GetFirstNCandidates(stateDB, d.config.WitnessesNum)
+	return election.GetFirstNLiveCandidates(stateDB, d.config.WitnessesNum, d.config.MaxMissedSlots)
 }
 
 // needUpdateWitnesses weather current time needs update witnesses list
@@ -762,7 +929,7 @@ func (d *Dpos) needUpdateWitnesses(t *big.Int, lastUpdateTime *big.Int) bool {
 
 // setUpdateInterval only called when start up
 func (d *Dpos) setUpdateInterval() {
-	d.updateInterval = new(big.Int).SetUint64(3 * uint64(d.config.WitnessesNum) * d.config.Period)
+	d.updateInterval = new(big.Int).SetUint64(d.config.Epoch())
 }
 
 // coinBase get the address of this miner
@@ -17,13 +17,22 @@
 package dpos
 
 import (
+	"math"
 	"math/big"
 	"testing"
+	"time"
 
+	"github.com/vntchain/go-vnt/accounts"
 	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/common/hexutil"
+	"github.com/vntchain/go-vnt/consensus"
+	"github.com/vntchain/go-vnt/core"
 	"github.com/vntchain/go-vnt/core/types"
+	"github.com/vntchain/go-vnt/core/vm"
 	"github.com/vntchain/go-vnt/core/vm/election"
+	"github.com/vntchain/go-vnt/crypto"
 	"github.com/vntchain/go-vnt/params"
+	"github.com/vntchain/go-vnt/vntdb"
 )
 
 func TestUpdateTime(t *testing.T) {
@@ -201,3 +210,351 @@ func TestCalcVoteBounty(t *testing.T) {
 		}
 	}
 }
+
+// testHeaderReader is a consensus.ChainReader serving a fixed set of headers.
+type testHeaderReader struct {
+	headers map[common.Hash]*types.Header
+}
+
+func (r *testHeaderReader) Config() *params.ChainConfig  { return params.TestChainConfig }
+func (r *testHeaderReader) CurrentHeader() *types.Header { return nil }
+func (r *testHeaderReader) GetHeader(hash common.Hash, number uint64) *types.Header {
+	return r.headers[hash]
+}
+func (r *testHeaderReader) GetHeaderByNumber(number uint64) *types.Header { return nil }
+func (r *testHeaderReader) GetHeaderByHash(hash common.Hash) *types.Header {
+	return r.headers[hash]
+}
+func (r *testHeaderReader) GetBlock(hash common.Hash, number uint64) *types.Block { return nil }
+
+// Tests that the irreversible block is the newest one built upon by a quorum
+// of the witnesses.
+func TestIrreversible(t *testing.T) {
+	ap := newTesterAccountPool()
+	witnesses := ap.stringToAddressSorted([]string{"A", "B", "C", "D"})
+	ap.address("E") // not a witness
+
+	var (
+		reader  = &testHeaderReader{headers: make(map[common.Hash]*types.Header)}
+		headers = []*types.Header{{Number: big.NewInt(0), Witnesses: witnesses}}
+	)
+	reader.headers[headers[0].Hash()] = headers[0]
+	for i, producer := range []string{"A", "B", "E", "C", "A", "B"} {
+		header := &types.Header{
+			ParentHash: headers[i].Hash(),
+			Number:     big.NewInt(int64(i + 1)),
+			Time:       big.NewInt(int64(i + 1)),
+			Witnesses:  witnesses,
+		}
+		sig, err := crypto.Sign(sigHash(header).Bytes(), ap.accounts[producer])
+		if err != nil {
+			t.Fatalf("failed to sign header %d: %v", i+1, err)
+		}
+		header.Signature = sig
+		reader.headers[header.Hash()] = header
+		headers = append(headers, header)
+	}
+	d := New(&params.DposConfig{WitnessesNum: 4}, nil)
+
+	tests := []struct {
+		head, want uint64
+	}{
+		{0, 0}, // genesis is always irreversible
+		{2, 0}, // only A and B produced
+		{3, 0}, // E is not a witness
+		{4, 1}, // A, B and C reached the quorum of 3
+		{6, 4}, // B, A and C on top of block 4
+	}
+	for _, tt := range tests {
+		header := d.Irreversible(reader, headers[tt.head])
+		if header == nil {
+			t.Errorf("head %d: no irreversible block", tt.head)
+			continue
+		}
+		if have := header.Number.Uint64(); have != tt.want {
+			t.Errorf("head %d: irreversible block mismatch: have %d, want %d", tt.head, have, tt.want)
+		}
+	}
+}
+
+// Tests that the rounds missed by a witness are counted from the last block it
+// produced.
+func TestMissedRounds(t *testing.T) {
+	ap := newTesterAccountPool()
+	witnesses := ap.stringToAddressSorted([]string{"A", "B", "C", "D"})
+
+	var (
+		reader  = &testHeaderReader{headers: make(map[common.Hash]*types.Header)}
+		headers = []*types.Header{{Number: big.NewInt(0), Time: big.NewInt(0), Witnesses: witnesses}}
+	)
+	reader.headers[headers[0].Hash()] = headers[0]
+	for i, block := range []struct {
+		producer string
+		time     int64
+	}{{"A", 2}, {"B", 4}, {"C", 6}, {"D", 8}, {"B", 12}} {
+		header := &types.Header{
+			ParentHash: headers[i].Hash(),
+			Number:     big.NewInt(int64(i + 1)),
+			Time:       big.NewInt(block.time),
+			Coinbase:   ap.address(block.producer),
+			Witnesses:  witnesses,
+		}
+		reader.headers[header.Hash()] = header
+		headers = append(headers, header)
+	}
+	d := New(&params.DposConfig{Period: 2, WitnessesNum: 4}, nil) // 8 seconds per round
+
+	tests := []struct {
+		witness string
+		now     int64
+		limit   uint64
+		want    uint64
+	}{
+		{"A", 12, 10, 1}, // skipped at 10
+		{"A", 26, 10, 3},
+		{"A", 26, 1, 1}, // search stopped at the limit
+		{"B", 26, 10, 1},
+		{"D", 26, 10, 2},
+		{"E", 26, 10, 0}, // not a witness
+	}
+	for _, tt := range tests {
+		have := d.MissedRounds(reader, headers[len(headers)-1], ap.address(tt.witness), time.Unix(tt.now, 0), tt.limit)
+		if have != tt.want {
+			t.Errorf("witness %s at %d: missed rounds mismatch: have %d, want %d", tt.witness, tt.now, have, tt.want)
+		}
+	}
+}
+
+// Tests that the witness performance counts the produced blocks, missed slots,
+// fullness and scheduled rewards within the window only.
+func TestWitnessPerformance(t *testing.T) {
+	ap := newTesterAccountPool()
+	witnesses := ap.stringToAddressSorted([]string{"A", "B", "C", "D"})
+
+	var (
+		reader  = &testHeaderReader{headers: make(map[common.Hash]*types.Header)}
+		headers = []*types.Header{{Number: big.NewInt(0), Time: big.NewInt(0), Witnesses: witnesses}}
+	)
+	reader.headers[headers[0].Hash()] = headers[0]
+	for i, block := range []struct {
+		producer int
+		time     int64
+		gasUsed  uint64
+	}{{0, 2, 0}, {1, 4, 500}, {3, 8, 1000}, {1, 20, 250}} {
+		header := &types.Header{
+			ParentHash: headers[i].Hash(),
+			Number:     big.NewInt(int64(i + 1)),
+			Time:       big.NewInt(block.time),
+			Coinbase:   witnesses[block.producer],
+			GasLimit:   1000,
+			GasUsed:    block.gasUsed,
+			Witnesses:  witnesses,
+		}
+		reader.headers[header.Hash()] = header
+		headers = append(headers, header)
+	}
+	d := New(&params.DposConfig{Period: 2, WitnessesNum: 4}, nil)
+
+	tests := []struct {
+		window   uint64
+		from     uint64
+		produced []uint64
+		missed   []uint64
+		fullness []float64
+	}{
+		{10, 1, []uint64{1, 2, 0, 1}, []uint64{2, 1, 2, 1}, []float64{0, 0.375, 0, 1}},
+		{1, 4, []uint64{0, 1, 0, 0}, []uint64{2, 1, 1, 1}, []float64{0, 0.25, 0, 0}}, // a full round skipped before the last block
+	}
+	for _, tt := range tests {
+		report := d.WitnessPerformance(reader, headers[4], tt.window)
+		if report.From != tt.from || report.To != 4 {
+			t.Fatalf("window %d: range mismatch: have [%d, %d], want [%d, 4]", tt.window, report.From, report.To, tt.from)
+		}
+		if len(report.Witnesses) != len(witnesses) {
+			t.Fatalf("window %d: witness count mismatch: have %d, want %d", tt.window, len(report.Witnesses), len(witnesses))
+		}
+		for i, stat := range report.Witnesses {
+			if stat.Witness != witnesses[i] {
+				t.Errorf("window %d: witness %d mismatch: have %x, want %x", tt.window, i, stat.Witness, witnesses[i])
+			}
+			if stat.Produced != tt.produced[i] || stat.Missed != tt.missed[i] || stat.Fullness != tt.fullness[i] {
+				t.Errorf("window %d: witness %d performance mismatch: have %d/%d/%v, want %d/%d/%v", tt.window, i, stat.Produced, stat.Missed, stat.Fullness, tt.produced[i], tt.missed[i], tt.fullness[i])
+			}
+			reward := new(big.Int).Mul(VortexBlockReward, new(big.Int).SetUint64(tt.produced[i]))
+			if stat.ScheduledRewards.ToInt().Cmp(reward) != 0 {
+				t.Errorf("window %d: witness %d rewards mismatch: have %v, want %v", tt.window, i, stat.ScheduledRewards.ToInt(), reward)
+			}
+		}
+	}
+}
+
+// Tests that the witness performance measured through cached segments matches
+// the one measured block by block.
+func TestWitnessPerformanceSegments(t *testing.T) {
+	ap := newTesterAccountPool()
+	witnesses := ap.stringToAddressSorted([]string{"A", "B", "C", "D"})
+
+	var (
+		reader  = &testHeaderReader{headers: make(map[common.Hash]*types.Header)}
+		headers = []*types.Header{{Number: big.NewInt(0), Time: big.NewInt(0), Witnesses: witnesses}}
+	)
+	reader.headers[headers[0].Hash()] = headers[0]
+	for i := 1; i <= 2*performanceSegmentSize+500; i++ {
+		header := &types.Header{
+			ParentHash: headers[i-1].Hash(),
+			Number:     big.NewInt(int64(i)),
+			Time:       big.NewInt(int64(2*i + 2*(i/7))), // Skip a slot every 7 blocks
+			Coinbase:   witnesses[(i+i/7)%len(witnesses)],
+			GasLimit:   1000,
+			GasUsed:    uint64(i * 37 % 1000),
+			Witnesses:  witnesses,
+		}
+		reader.headers[header.Hash()] = header
+		headers = append(headers, header)
+	}
+	d := New(&params.DposConfig{Period: 2, WitnessesNum: 4}, nil)
+
+	// Windows shorter than a segment never span a full one, so sum them up to
+	// measure the longer windows block by block.
+	measure := func(head, window uint64) *WitnessPerformanceReport {
+		const step = performanceSegmentSize / 2
+		want := &WitnessPerformanceReport{From: head - window + 1, To: head}
+		for i := range witnesses {
+			want.Witnesses = append(want.Witnesses, &WitnessPerformance{Witness: witnesses[i], ScheduledRewards: (*hexutil.Big)(new(big.Int))})
+		}
+		for end := head; end > head-window; end -= step {
+			for i, stat := range d.WitnessPerformance(reader, headers[end], step).Witnesses {
+				want.Witnesses[i].Produced += stat.Produced
+				want.Witnesses[i].Missed += stat.Missed
+				want.Witnesses[i].Fullness += stat.Fullness * float64(stat.Produced)
+				want.Witnesses[i].ScheduledRewards.ToInt().Add(want.Witnesses[i].ScheduledRewards.ToInt(), stat.ScheduledRewards.ToInt())
+			}
+		}
+		for _, stat := range want.Witnesses {
+			stat.Fullness /= float64(stat.Produced)
+		}
+		return want
+	}
+	for _, tt := range []struct{ head, window uint64 }{
+		{2000, 2000}, // Two full segments
+		{2500, 2000}, // A full segment between partial ones
+		{2500, 2000}, // Served from the cache
+	} {
+		want := measure(tt.head, tt.window)
+		have := d.WitnessPerformance(reader, headers[tt.head], tt.window)
+		if have.From != want.From || have.To != want.To {
+			t.Fatalf("head %d window %d: range mismatch: have [%d, %d], want [%d, %d]", tt.head, tt.window, have.From, have.To, want.From, want.To)
+		}
+		if len(have.Witnesses) != len(want.Witnesses) {
+			t.Fatalf("head %d window %d: witness count mismatch: have %d, want %d", tt.head, tt.window, len(have.Witnesses), len(want.Witnesses))
+		}
+		for i, stat := range have.Witnesses {
+			exp := want.Witnesses[i]
+			if stat.Witness != exp.Witness || stat.Produced != exp.Produced || stat.Missed != exp.Missed || math.Abs(stat.Fullness-exp.Fullness) > 1e-9 {
+				t.Errorf("head %d window %d: witness %d performance mismatch: have %x %d/%d/%v, want %x %d/%d/%v", tt.head, tt.window, i, stat.Witness, stat.Produced, stat.Missed, stat.Fullness, exp.Witness, exp.Produced, exp.Missed, exp.Fullness)
+			}
+			if stat.ScheduledRewards.ToInt().Cmp(exp.ScheduledRewards.ToInt()) != 0 {
+				t.Errorf("head %d window %d: witness %d rewards mismatch: have %v, want %v", tt.head, tt.window, i, stat.ScheduledRewards.ToInt(), exp.ScheduledRewards.ToInt())
+			}
+		}
+	}
+	if d.performance.Len() != 2 {
+		t.Errorf("cached segment count mismatch: have %d, want %d", d.performance.Len(), 2)
+	}
+}
+
+// Tests that slots closer than the tolerance are skipped when producing.
+func TestSlotTolerance(t *testing.T) {
+	d := New(&params.DposConfig{Period: 10, WitnessesNum: 3}, nil)
+	parent := big.NewInt(time.Now().Unix() - 8) // Next slot starts in 1-2 seconds
+
+	tests := []struct {
+		tolerance time.Duration
+		period    uint64
+	}{
+		{0, 1},
+		{500 * time.Millisecond, 1},
+		{5 * time.Second, 2},
+	}
+	for i, tt := range tests {
+		produce, nPeriod, err := d.nextProduceTime(parent, tt.tolerance)
+		if err != nil {
+			t.Fatalf("test %d: failed to compute produce time: %v", i, err)
+		}
+		if nPeriod.Uint64() != tt.period {
+			t.Errorf("test %d: period mismatch: have %v, want %v", i, nPeriod, tt.period)
+		}
+		if want := parent.Int64() + int64(tt.period)*10; produce.Int64() != want {
+			t.Errorf("test %d: produce time mismatch: have %v, want %v", i, produce, want)
+		}
+	}
+}
+
+// Tests that headers too far ahead of the local clock are deferred only if the
+// clock drift is limited.
+func TestMaxClockDrift(t *testing.T) {
+	d := New(&params.DposConfig{Period: 2, WitnessesNum: 3}, nil)
+	header := &types.Header{Number: big.NewInt(1), Time: big.NewInt(time.Now().Unix() + 60)}
+
+	if err := d.verifyHeader(nil, header, nil); err != errInvalidExtraLen {
+		t.Errorf("unchecked drift error mismatch: have %v, want %v", err, errInvalidExtraLen)
+	}
+	d.SetTiming(Timing{MaxClockDrift: 10 * time.Second})
+	if err := d.verifyHeader(nil, header, nil); err != consensus.ErrFutureBlock {
+		t.Errorf("limited drift error mismatch: have %v, want %v", err, consensus.ErrFutureBlock)
+	}
+	d.SetTiming(Timing{MaxClockDrift: 2 * time.Minute})
+	if err := d.verifyHeader(nil, header, nil); err != errInvalidExtraLen {
+		t.Errorf("tolerated drift error mismatch: have %v, want %v", err, errInvalidExtraLen)
+	}
+}
+
+// Tests that a block produced and sealed by the authorized signer pays the block
+// reward to the signer, which is the coinbase identifying the witness.
+func TestSealedBlockReward(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	signer := crypto.PubkeyToAddress(key.PublicKey)
+
+	db := vntdb.NewMemDatabase()
+	gspec := core.DeveloperGenesisBlock(1, signer)
+	genesis := gspec.MustCommit(db)
+
+	d := New(gspec.Config.Dpos, db)
+	d.Authorize(signer, func(account accounts.Account, hash []byte) ([]byte, error) {
+		return crypto.Sign(hash, key)
+	})
+	chain, err := core.NewBlockChain(db, nil, gspec.Config, d, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	header := &types.Header{ParentHash: genesis.Hash(), Number: big.NewInt(1), GasLimit: genesis.GasLimit()}
+	if err := d.Prepare(chain, header); err != nil {
+		t.Fatalf("failed to prepare header: %v", err)
+	}
+	if header.Coinbase != signer {
+		t.Fatalf("coinbase mismatch: have %x, want %x", header.Coinbase, signer)
+	}
+	statedb, err := chain.StateAt(genesis.Root())
+	if err != nil {
+		t.Fatalf("failed to open state: %v", err)
+	}
+	before := statedb.GetBalance(signer)
+	block, err := d.Finalize(chain, header, statedb, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to finalize block: %v", err)
+	}
+	reward := new(big.Int).Sub(statedb.GetBalance(signer), before)
+	if want := curHeightBonus(header.Number, VortexBlockReward); reward.Cmp(want) != 0 {
+		t.Errorf("signer reward mismatch: have %v, want %v", reward, want)
+	}
+	// The sealed block must verify as produced by the rewarded witness
+	sealed := block.Header()
+	if sealed.Signature, err = crypto.Sign(sigHash(sealed).Bytes(), key); err != nil {
+		t.Fatalf("failed to seal block: %v", err)
+	}
+	if err := d.verifySeal(chain, sealed, nil); err != nil {
+		t.Errorf("sealed block rejected: %v", err)
+	}
+}
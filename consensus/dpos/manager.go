@@ -119,6 +119,64 @@ func (m *Manager) moveIndex(cur int, off int) (int, error) {
 	return cur, nil
 }
 
+// skipped returns the witnesses whose slots passed between the block produced by
+// the witness at pIndex at pWitTime and the next block at witTime. A witness is
+// listed at most once, however many rounds passed.
+func (m *Manager) skipped(pIndex int, pWitTime, witTime *big.Int) []common.Address {
+	if witTime.Cmp(pWitTime) <= 0 || pIndex < 0 || pIndex >= len(m.Witnesses) {
+		return nil
+	}
+	dur := new(big.Int).Sub(witTime, pWitTime)
+	period := new(big.Int).SetUint64(m.blockPeriod)
+	nPeriod, left := new(big.Int).DivMod(dur, period, new(big.Int))
+	if left.Sign() != 0 {
+		nPeriod.Add(nPeriod, common.Big1) // witTime in next period
+	}
+	missed := int64(len(m.Witnesses))
+	if nPeriod.Cmp(big.NewInt(missed)) < 0 {
+		missed = nPeriod.Int64()
+	}
+
+	var witnesses []common.Address
+	for i := int64(1); i < missed; i++ {
+		witnesses = append(witnesses, m.Witnesses[(pIndex+int(i))%len(m.Witnesses)])
+	}
+	return witnesses
+}
+
+// missedSlots returns how many slots of each witness passed between the block
+// produced by the witness at pIndex at pWitTime and the next block at witTime,
+// counting every round that passed.
+func (m *Manager) missedSlots(pIndex int, pWitTime, witTime *big.Int) map[common.Address]uint64 {
+	if witTime.Cmp(pWitTime) <= 0 || pIndex < 0 || pIndex >= len(m.Witnesses) || m.blockPeriod == 0 {
+		return nil
+	}
+	dur := new(big.Int).Sub(witTime, pWitTime)
+	period := new(big.Int).SetUint64(m.blockPeriod)
+	nPeriod, left := new(big.Int).DivMod(dur, period, new(big.Int))
+	if left.Sign() != 0 {
+		nPeriod.Add(nPeriod, common.Big1) // witTime in next period
+	}
+	if nPeriod.Cmp(common.Big1) <= 0 {
+		return nil
+	}
+	// The slots in between are handed out round robin starting after pIndex
+	nWitness := big.NewInt(int64(len(m.Witnesses)))
+	rounds, rest := new(big.Int).DivMod(new(big.Int).Sub(nPeriod, common.Big1), nWitness, new(big.Int))
+
+	missed := make(map[common.Address]uint64)
+	for i := int64(1); i <= int64(len(m.Witnesses)); i++ {
+		slots := rounds.Uint64()
+		if i <= rest.Int64() {
+			slots++
+		}
+		if slots > 0 {
+			missed[m.Witnesses[(pIndex+int(i))%len(m.Witnesses)]] += slots
+		}
+	}
+	return missed
+}
+
 // dump witness list
 func (m *Manager) dump() {
 	fmt.Println("Witness list:")
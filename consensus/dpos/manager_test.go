@@ -23,6 +23,7 @@ import (
 	"github.com/vntchain/go-vnt/crypto"
 	"github.com/vntchain/go-vnt/params"
 	"github.com/stretchr/testify/assert"
+	"math/big"
 	"testing"
 )
 
@@ -67,6 +68,66 @@ func TestManager(t *testing.T) {
 	}
 }
 
+func TestManagerSkipped(t *testing.T) {
+	ap := newTesterAccountPool()
+	ws := ap.stringToAddressSorted([]string{"A", "B", "C", "D", "E"})
+	m := NewManager(2, ws)
+
+	tests := []struct {
+		pIndex int
+		dur    int64
+		result []int
+	}{
+		{0, 2, nil},                // next slot, nothing missed
+		{0, 6, []int{1, 2}},        // B and C missed
+		{3, 5, []int{4, 0}},        // wraps around, time in next period
+		{1, 40, []int{2, 3, 4, 0}}, // several rounds, everyone once
+		{1, 0, nil},                // time not increasing
+	}
+	for i, tt := range tests {
+		pTime := big.NewInt(100)
+		skipped := m.skipped(tt.pIndex, pTime, new(big.Int).Add(pTime, big.NewInt(tt.dur)))
+		if len(skipped) != len(tt.result) {
+			t.Fatalf("test %d: skipped length mismatch: have %d, want %d", i, len(skipped), len(tt.result))
+		}
+		for j, idx := range tt.result {
+			if skipped[j] != ws[idx] {
+				t.Errorf("test %d: skipped witness %d mismatch: have %x, want %x", i, j, skipped[j], ws[idx])
+			}
+		}
+	}
+}
+
+func TestManagerMissedSlots(t *testing.T) {
+	ap := newTesterAccountPool()
+	ws := ap.stringToAddressSorted([]string{"A", "B", "C", "D", "E"})
+	m := NewManager(2, ws)
+
+	tests := []struct {
+		pIndex int
+		dur    int64
+		result map[int]uint64
+	}{
+		{0, 2, nil},                                           // next slot, nothing missed
+		{0, 6, map[int]uint64{1: 1, 2: 1}},                    // B and C missed
+		{3, 5, map[int]uint64{4: 1, 0: 1}},                    // wraps around, time in next period
+		{1, 40, map[int]uint64{2: 4, 3: 4, 4: 4, 0: 4, 1: 3}}, // several rounds, every slot counted
+		{1, 0, nil}, // time not increasing
+	}
+	for i, tt := range tests {
+		pTime := big.NewInt(100)
+		missed := m.missedSlots(tt.pIndex, pTime, new(big.Int).Add(pTime, big.NewInt(tt.dur)))
+		if len(missed) != len(tt.result) {
+			t.Fatalf("test %d: missed witnesses mismatch: have %d, want %d", i, len(missed), len(tt.result))
+		}
+		for idx, slots := range tt.result {
+			if missed[ws[idx]] != slots {
+				t.Errorf("test %d: missed slots of witness %d mismatch: have %d, want %d", i, idx, missed[ws[idx]], slots)
+			}
+		}
+	}
+}
+
 // testerAccountPool maintains current active address
 type testerAccountPool struct {
 	accounts map[string]*ecdsa.PrivateKey
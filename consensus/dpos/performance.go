@@ -0,0 +1,184 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package dpos
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/common/hexutil"
+	"github.com/vntchain/go-vnt/consensus"
+	"github.com/vntchain/go-vnt/core/types"
+)
+
+const (
+	defaultPerformanceWindow = 1000  // Number of blocks the witness performance is measured over by default
+	maxPerformanceWindow     = 10000 // Maximum number of blocks the witness performance is measured over
+	performanceSegmentSize   = 1000  // Number of blocks whose performance is aggregated into a cached segment
+	inMemoryPerformance      = 128   // Number of recent performance segments to keep in memory
+)
+
+// WitnessPerformance is the block production record of a witness over a range
+// of blocks.
+type WitnessPerformance struct {
+	Witness          common.Address `json:"witness"`
+	Produced         uint64         `json:"produced"`         // Number of blocks produced
+	Missed           uint64         `json:"missed"`           // Number of production slots passed without a block
+	Fullness         float64        `json:"fullness"`         // Average ratio of gas used to gas limit of the produced blocks
+	ScheduledRewards *hexutil.Big   `json:"scheduledRewards"` // Rewards scheduled for the produced blocks, in wei
+}
+
+// WitnessPerformanceReport is the performance of all the witnesses producing or
+// in turn to produce within a range of blocks.
+type WitnessPerformanceReport struct {
+	From      uint64                `json:"from"` // First block of the range
+	To        uint64                `json:"to"`   // Last block of the range
+	Witnesses []*WitnessPerformance `json:"witnesses"`
+}
+
+// witnessTally accumulates the production record of a witness.
+type witnessTally struct {
+	produced uint64
+	missed   uint64
+	fullness float64 // Sum of the fullness of the produced blocks
+	rewards  *big.Int
+}
+
+// performanceTally accumulates the production record of the witnesses.
+type performanceTally map[common.Address]*witnessTally
+
+// witness retrieves the tally of a witness, creating it if needed.
+func (t performanceTally) witness(addr common.Address) *witnessTally {
+	if t[addr] == nil {
+		t[addr] = &witnessTally{rewards: new(big.Int)}
+	}
+	return t[addr]
+}
+
+// merge adds the records of another tally, leaving the other one untouched.
+func (t performanceTally) merge(other performanceTally) {
+	for addr, stat := range other {
+		tally := t.witness(addr)
+		tally.produced += stat.produced
+		tally.missed += stat.missed
+		tally.fullness += stat.fullness
+		tally.rewards.Add(tally.rewards, stat.rewards)
+	}
+}
+
+// add records the production of header and the slots missed since its parent,
+// returning the parent or nil if it is not available.
+func (t performanceTally) add(d *Dpos, chain consensus.ChainReader, header *types.Header) *types.Header {
+	number := header.Number.Uint64()
+
+	producer := t.witness(header.Coinbase)
+	producer.produced++
+	if header.GasLimit > 0 {
+		producer.fullness += float64(header.GasUsed) / float64(header.GasLimit)
+	}
+	producer.rewards.Add(producer.rewards, curHeightBonus(header.Number, VortexBlockReward))
+
+	parent := chain.GetHeader(header.ParentHash, number-1)
+	if parent == nil || number == 1 {
+		return parent // The genesis block has no production slot to measure from
+	}
+	manager := NewManager(d.config.Period, header.Witnesses)
+	for addr, slots := range manager.missedSlots(manager.indexOf(parent.Coinbase), parent.Time, header.Time) {
+		t.witness(addr).missed += slots
+	}
+	return parent
+}
+
+// performanceSegment is the production record of the witnesses over the blocks
+// of a segment.
+type performanceSegment struct {
+	parent common.Hash // Parent hash of the first block of the segment
+	tally  performanceTally
+}
+
+// segment retrieves the production record of the segment ending at last, from
+// the cache if it was measured before. Nil is returned if the headers of the
+// segment are not all available.
+func (d *Dpos) segment(chain consensus.ChainReader, last *types.Header) *performanceSegment {
+	if cached, ok := d.performance.Get(last.Hash()); ok {
+		return cached.(*performanceSegment)
+	}
+	segment := &performanceSegment{tally: make(performanceTally)}
+	header := last
+	for i := 0; i < performanceSegmentSize; i++ {
+		if header == nil {
+			return nil
+		}
+		segment.parent = header.ParentHash
+		header = segment.tally.add(d, chain, header)
+	}
+	d.performance.Add(last.Hash(), segment)
+	return segment
+}
+
+// WitnessPerformance measures the performance of the witnesses over the window
+// blocks ending at head. Missed slots are attributed by the witness list of the
+// block following them. The rewards are the ones scheduled for producing a
+// block, which are only granted while the VNT bounty lasts.
+//
+// The record of every full segment of performanceSegmentSize blocks is cached,
+// so that only the blocks outside of them are measured for repeated queries.
+func (d *Dpos) WitnessPerformance(chain consensus.ChainReader, head *types.Header, window uint64) *WitnessPerformanceReport {
+	report := &WitnessPerformanceReport{To: head.Number.Uint64()}
+	if window == 0 || report.To == 0 {
+		report.From = report.To
+		return report
+	}
+	report.From = 1
+	if report.To >= window {
+		report.From = report.To - window + 1
+	}
+
+	tally := make(performanceTally)
+	for _, addr := range head.Witnesses {
+		tally.witness(addr)
+	}
+	for header := head; header != nil && header.Number.Uint64() >= report.From; {
+		number := header.Number.Uint64()
+		if number%performanceSegmentSize == 0 && number-performanceSegmentSize+1 >= report.From {
+			if segment := d.segment(chain, header); segment != nil {
+				tally.merge(segment.tally)
+				header = chain.GetHeader(segment.parent, number-performanceSegmentSize)
+				continue
+			}
+		}
+		header = tally.add(d, chain, header)
+	}
+	for addr, stat := range tally {
+		performance := &WitnessPerformance{
+			Witness:          addr,
+			Produced:         stat.produced,
+			Missed:           stat.missed,
+			ScheduledRewards: (*hexutil.Big)(stat.rewards),
+		}
+		if stat.produced > 0 {
+			performance.Fullness = stat.fullness / float64(stat.produced)
+		}
+		report.Witnesses = append(report.Witnesses, performance)
+	}
+	sort.Slice(report.Witnesses, func(i, j int) bool {
+		return bytes.Compare(report.Witnesses[i].Witness[:], report.Witnesses[j].Witness[:]) < 0
+	})
+	return report
+}
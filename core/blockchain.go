@@ -32,6 +32,8 @@ import (
 	"github.com/vntchain/go-vnt/consensus"
 	"github.com/vntchain/go-vnt/core/rawdb"
 	"github.com/vntchain/go-vnt/core/state"
+	"github.com/vntchain/go-vnt/core/state/pruner"
+	"github.com/vntchain/go-vnt/core/state/snapshot"
 	"github.com/vntchain/go-vnt/core/types"
 	"github.com/vntchain/go-vnt/core/vm"
 	"github.com/vntchain/go-vnt/crypto"
@@ -48,17 +50,47 @@ import (
 var (
 	blockInsertTimer = metrics.NewRegisteredTimer("chain/inserts", nil)
 
+	blockReorgMeter     = metrics.NewRegisteredMeter("chain/reorg/executes", nil)
+	blockReorgDropMeter = metrics.NewRegisteredMeter("chain/reorg/drop", nil)
+	blockReorgAddMeter  = metrics.NewRegisteredMeter("chain/reorg/add", nil)
+	blockReorgDepthHist = metrics.NewRegisteredHistogram("chain/reorg/depth", nil, metrics.NewExpDecaySample(1028, 0.015))
+
 	ErrNoGenesis = errors.New("Genesis not found in chain")
+
+	// ErrPruneArchive is returned if state pruning is requested on an archive node.
+	ErrPruneArchive = errors.New("state pruning disabled in archive mode")
+
+	// ErrPruneRunning is returned if state pruning is requested while a previous
+	// prune is still in progress.
+	ErrPruneRunning = errors.New("state prune already running")
+
+	// ErrRewindAncient is returned if the chain is asked to rewind to a block
+	// that was already moved into the immutable ancient store.
+	ErrRewindAncient = errors.New("cannot rewind into the ancient store")
 )
 
 const (
 	bodyCacheLimit      = 256
 	blockCacheLimit     = 256
+	receiptsCacheLimit  = 32
+	senderCacheLimit    = 16384
 	maxFutureBlocks     = 256
 	maxTimeFutureBlocks = 30
 	badBlockLimit       = 10
 	triesInMemory       = 128
 
+	// txIndexBlocksPerFlush is the number of blocks whose lookup entries are
+	// (un)indexed before the progress is persisted.
+	txIndexBlocksPerFlush = 1024
+
+	// consistencyCheckDepth is the number of recent blocks whose data is verified
+	// to be complete on startup, covering the writes a crash may have interrupted.
+	consistencyCheckDepth = 128
+
+	// eventQueueLimit is the number of events generated while holding the chain
+	// lock which are queued for delivery before blocking the import.
+	eventQueueLimit = 256
+
 	// BlockChainVersion ensures that an incompatible database forces a resync from scratch.
 	BlockChainVersion = 3
 )
@@ -69,6 +101,16 @@ type CacheConfig struct {
 	Disabled      bool          // Whether to disable trie write caching (archive node)
 	TrieNodeLimit int           // Memory limit (MB) at which to flush the current in-memory trie to disk
 	TrieTimeLimit time.Duration // Time limit after which to flush the current in-memory trie to disk
+	SnapshotLimit int           // Memory allowance (MB) to use for caching snapshot entries in memory (0 = snapshot disabled)
+	TxLookupLimit uint64        // Number of recent blocks to keep transaction lookup entries for (0 = index all)
+	StateHistory  uint64        // Number of recent blocks to retain the state of (below 128 = 128)
+	NoPreimages   bool          // Whether to skip recording the preimages of hashed trie keys and VM hashes
+
+	TriePrefetchLimit int // Memory allowance (MB) to use for caching the trie nodes warmed by the block prefetcher (0 = prefetching disabled)
+
+	BlockCacheLimit   int // Number of recent blocks and bodies to keep in memory (0 = default)
+	ReceiptCacheLimit int // Number of recent block receipts to keep in memory (0 = default)
+	SenderCacheLimit  int // Number of recent transaction senders to keep in memory (0 = default)
 }
 
 // BlockChain represents the canonical chain given a database with a genesis
@@ -93,14 +135,17 @@ type BlockChain struct {
 	triegc *prque.Prque   // Priority queue mapping block numbers to tries to gc
 	gcproc time.Duration  // Accumulates canonical block processing for trie dumping
 
-	hc            *HeaderChain
-	rmLogsFeed    event.Feed
-	chainFeed     event.Feed
-	chainSideFeed event.Feed
-	chainHeadFeed event.Feed
-	logsFeed      event.Feed
-	scope         event.SubscriptionScope
-	genesisBlock  *types.Block
+	hc             *HeaderChain
+	rmLogsFeed     event.Feed
+	chainFeed      event.Feed
+	chainSideFeed  event.Feed
+	chainHeadFeed  event.Feed
+	forkChoiceFeed event.Feed
+	reorgFeed      event.Feed
+	logsFeed       event.Feed
+	scope          event.SubscriptionScope
+	events         chan func() // Deliveries of the events generated under the chain lock, in order
+	genesisBlock   *types.Block
 
 	mu      sync.RWMutex // global mutex for locking chain operations
 	chainmu sync.RWMutex // blockchain insertion lock
@@ -109,25 +154,30 @@ type BlockChain struct {
 	checkpoint       int          // checkpoint counts towards the new checkpoint
 	currentBlock     atomic.Value // Current head of the block chain
 	currentFastBlock atomic.Value // Current head of the fast-sync chain (may be above the block chain!)
+	irreversible     atomic.Value // Latest block that can no longer be reorganised out of the chain
 
-	stateCache   state.Database // State database to reuse between imports (contains state cache)
-	bodyCache    *lru.Cache     // Cache for the most recent block bodies
-	bodyRLPCache *lru.Cache     // Cache for the most recent block bodies in RLP encoded format
-	blockCache   *lru.Cache     // Cache for the most recent entire blocks
-	futureBlocks *lru.Cache     // future blocks are blocks added for later processing
+	stateCache    state.Database // State database to reuse between imports (contains state cache)
+	snaps         *snapshot.Tree // Snapshot tree for fast trie leaf access
+	bodyCache     *lru.Cache     // Cache for the most recent block bodies
+	bodyRLPCache  *lru.Cache     // Cache for the most recent block bodies in RLP encoded format
+	blockCache    *lru.Cache     // Cache for the most recent entire blocks
+	receiptsCache *lru.Cache     // Cache for the most recent receipts per block
+	senderCache   *lru.Cache     // Cache for the recovered senders of the most recent transactions
+	futureBlocks  *lru.Cache     // future blocks are blocks added for later processing
 
 	quit    chan struct{} // blockchain quit channel
 	running int32         // running must be called atomically
 	// procInterrupt must be atomically called
 	procInterrupt int32          // interrupt signaler for block processing
 	wg            sync.WaitGroup // chain processing wait group for shutting down
+	pruning       int32          // Whether a state prune is in progress (atomic)
 
 	engine    consensus.Engine
 	processor Processor // block processor interface
 	validator Validator // block and state validator interface
 	vmConfig  vm.Config
 
-	badBlocks *lru.Cache // Bad block cache
+	badBlocks *lru.Cache // Most recent blocks which failed validation, with the reason
 }
 
 // NewBlockChain returns a fully initialised block chain using information
@@ -140,26 +190,46 @@ func NewBlockChain(db vntdb.Database, cacheConfig *CacheConfig, chainConfig *par
 			TrieTimeLimit: 5 * time.Minute,
 		}
 	}
-	bodyCache, _ := lru.New(bodyCacheLimit)
-	bodyRLPCache, _ := lru.New(bodyCacheLimit)
-	blockCache, _ := lru.New(blockCacheLimit)
+	bodyLimit, blockLimit := bodyCacheLimit, blockCacheLimit
+	if cacheConfig.BlockCacheLimit > 0 {
+		bodyLimit, blockLimit = cacheConfig.BlockCacheLimit, cacheConfig.BlockCacheLimit
+	}
+	receiptsLimit := receiptsCacheLimit
+	if cacheConfig.ReceiptCacheLimit > 0 {
+		receiptsLimit = cacheConfig.ReceiptCacheLimit
+	}
+	senderLimit := senderCacheLimit
+	if cacheConfig.SenderCacheLimit > 0 {
+		senderLimit = cacheConfig.SenderCacheLimit
+	}
+	bodyCache, _ := lru.New(bodyLimit)
+	bodyRLPCache, _ := lru.New(bodyLimit)
+	blockCache, _ := lru.New(blockLimit)
+	receiptsCache, _ := lru.New(receiptsLimit)
+	senderCache, _ := lru.New(senderLimit)
 	futureBlocks, _ := lru.New(maxFutureBlocks)
 	badBlocks, _ := lru.New(badBlockLimit)
 
 	bc := &BlockChain{
-		chainConfig:  chainConfig,
-		cacheConfig:  cacheConfig,
-		db:           db,
-		triegc:       prque.New(),
-		stateCache:   state.NewDatabase(db),
-		quit:         make(chan struct{}),
-		bodyCache:    bodyCache,
-		bodyRLPCache: bodyRLPCache,
-		blockCache:   blockCache,
-		futureBlocks: futureBlocks,
-		engine:       engine,
-		vmConfig:     vmConfig,
-		badBlocks:    badBlocks,
+		chainConfig:   chainConfig,
+		cacheConfig:   cacheConfig,
+		db:            db,
+		triegc:        prque.New(),
+		stateCache:    state.NewDatabaseWithCache(db, cacheConfig.TriePrefetchLimit),
+		quit:          make(chan struct{}),
+		events:        make(chan func(), eventQueueLimit),
+		bodyCache:     bodyCache,
+		bodyRLPCache:  bodyRLPCache,
+		blockCache:    blockCache,
+		receiptsCache: receiptsCache,
+		senderCache:   senderCache,
+		futureBlocks:  futureBlocks,
+		engine:        engine,
+		vmConfig:      vmConfig,
+		badBlocks:     badBlocks,
+	}
+	if cacheConfig.NoPreimages {
+		bc.stateCache.TrieDB().DisablePreimages()
 	}
 	bc.SetValidator(NewBlockValidator(chainConfig, bc, engine))
 	bc.SetProcessor(NewStateProcessor(chainConfig, bc, engine))
@@ -176,6 +246,10 @@ func NewBlockChain(db vntdb.Database, cacheConfig *CacheConfig, chainConfig *par
 	if err := bc.loadLastState(); err != nil {
 		return nil, err
 	}
+	// Roll back any recent blocks left partially written by a crash
+	if _, _, err := bc.Recover(consistencyCheckDepth); err != nil {
+		return nil, err
+	}
 	// Check the current state of the block hashes and make sure that we do not have any of the bad blocks in our chain
 	for hash := range BadHashes {
 		if header := bc.GetHeaderByHash(hash); header != nil {
@@ -189,8 +263,22 @@ func NewBlockChain(db vntdb.Database, cacheConfig *CacheConfig, chainConfig *par
 			}
 		}
 	}
+	// Load any existing snapshot, regenerating it if loading failed
+	if bc.cacheConfig.SnapshotLimit > 0 {
+		if bc.snaps, err = snapshot.New(bc.db, bc.stateCache.TrieDB(), bc.cacheConfig.SnapshotLimit, bc.CurrentBlock().Root()); err != nil {
+			log.Warn("State snapshot unavailable", "err", err)
+		}
+	}
+	// Prune or restore the transaction index if a lookup limit is (or was) in effect
+	if bc.cacheConfig.TxLookupLimit > 0 || rawdb.ReadTxIndexTail(bc.db) != nil {
+		bc.wg.Add(1)
+		go bc.maintainTxIndex()
+	}
 	// Take ownership of this particular state
 	go bc.update()
+
+	bc.wg.Add(1)
+	go bc.deliverEvents()
 	return bc, nil
 }
 
@@ -211,9 +299,13 @@ func (bc *BlockChain) loadLastState() error {
 	// Make sure the entire head block is available
 	currentBlock := bc.GetBlockByHash(head)
 	if currentBlock == nil {
-		// Corrupt or empty database, init from scratch
-		log.Warn("Head block missing, resetting chain", "hash", head)
-		return bc.Reset()
+		// Partially written head block, fall back to the newest complete one
+		if currentBlock = bc.lastCompleteBlock(head); currentBlock == nil {
+			// Corrupt or empty database, init from scratch
+			log.Warn("Head block missing, resetting chain", "hash", head)
+			return bc.Reset()
+		}
+		log.Warn("Head block missing, rolling back chain", "hash", head, "number", currentBlock.Number(), "rollback", currentBlock.Hash())
 	}
 	// Make sure the state associated with the block is available
 	if _, err := state.New(currentBlock.Root(), bc.stateCache); err != nil {
@@ -225,6 +317,8 @@ func (bc *BlockChain) loadLastState() error {
 	}
 	// Everything seems to be fine, set as the head block
 	bc.currentBlock.Store(currentBlock)
+	bc.irreversible.Store(bc.genesisBlock.Header())
+	bc.updateIrreversible(currentBlock.Header())
 
 	// Restore the last known head header
 	currentHeader := currentBlock.Header()
@@ -260,15 +354,34 @@ func (bc *BlockChain) loadLastState() error {
 // SetHead rewinds the local chain to a new head. In the case of headers, everything
 // above the new head will be deleted and the new one set. In the case of blocks
 // though, the head may be further rewound if block bodies are missing (non-archive
-// nodes after a fast sync).
+// nodes after a fast sync). The transaction lookup entries and receipts of the
+// deleted blocks are dropped too, and the new head is announced to the chain head
+// subscribers so the transaction pool resets to it.
 func (bc *BlockChain) SetHead(head uint64) error {
+	if err := bc.setHead(head); err != nil {
+		return err
+	}
+	bc.chainHeadFeed.Send(ChainHeadEvent{Block: bc.CurrentBlock()})
+	return nil
+}
+
+func (bc *BlockChain) setHead(head uint64) error {
+	if frdb, ok := bc.db.(*rawdb.FreezerDatabase); ok && head+1 < frdb.Ancients() {
+		return ErrRewindAncient
+	}
 	log.Warn("Rewinding blockchain", "target", head)
 
 	bc.mu.Lock()
 	defer bc.mu.Unlock()
 
-	// Rewind the header chain, deleting all block bodies until then
+	// Rewind the header chain, deleting all block bodies and indexes until then
 	delFn := func(hash common.Hash, num uint64) {
+		if body := rawdb.ReadBody(bc.db, hash, num); body != nil {
+			for _, tx := range body.Transactions {
+				rawdb.DeleteTxLookupEntry(bc.db, tx.Hash())
+			}
+		}
+		rawdb.DeleteReceipts(bc.db, hash, num)
 		rawdb.DeleteBody(bc.db, hash, num)
 	}
 	bc.hc.SetHead(head, delFn)
@@ -278,6 +391,7 @@ func (bc *BlockChain) SetHead(head uint64) error {
 	bc.bodyCache.Purge()
 	bc.bodyRLPCache.Purge()
 	bc.blockCache.Purge()
+	bc.receiptsCache.Purge()
 	bc.futureBlocks.Purge()
 
 	// Rewind the block chain, ensuring we don't end up with a stateless head block
@@ -286,8 +400,19 @@ func (bc *BlockChain) SetHead(head uint64) error {
 	}
 	if currentBlock := bc.CurrentBlock(); currentBlock != nil {
 		if _, err := state.New(currentBlock.Root(), bc.stateCache); err != nil {
-			// Rewound state missing, rolled back to before pivot, reset to genesis
-			bc.currentBlock.Store(bc.genesisBlock)
+			// Rewound state missing, rewind further to the newest block that has its
+			// state, or to genesis if rolled back to before pivot
+			for block := currentBlock; ; {
+				if block = bc.GetBlock(block.ParentHash(), block.NumberU64()-1); block == nil {
+					bc.currentBlock.Store(bc.genesisBlock)
+					break
+				}
+				if _, err := state.New(block.Root(), bc.stateCache); err == nil {
+					log.Warn("Rewound state missing, rewinding further", "number", block.Number(), "hash", block.Hash())
+					bc.currentBlock.Store(block)
+					break
+				}
+			}
 		}
 	}
 	// Rewind the fast block in a simpleton way to the target head
@@ -307,6 +432,15 @@ func (bc *BlockChain) SetHead(head uint64) error {
 	rawdb.WriteHeadBlockHash(bc.db, currentBlock.Hash())
 	rawdb.WriteHeadFastBlockHash(bc.db, currentFastBlock.Hash())
 
+	// The snapshot can't follow the chain below its disk layer, rebuild it then
+	if bc.snaps != nil && bc.snaps.Snapshot(currentBlock.Root()) == nil {
+		bc.snaps.Stop()
+		snaps, err := snapshot.New(bc.db, bc.stateCache.TrieDB(), bc.cacheConfig.SnapshotLimit, currentBlock.Root())
+		if err != nil {
+			return err
+		}
+		bc.snaps = snaps
+	}
 	return bc.loadLastState()
 }
 
@@ -324,6 +458,7 @@ func (bc *BlockChain) FastSyncCommitHead(hash common.Hash) error {
 	// If all checks out, manually set the head block
 	bc.mu.Lock()
 	bc.currentBlock.Store(block)
+	bc.updateIrreversible(block.Header())
 	bc.mu.Unlock()
 
 	log.Info("Committed new head block", "number", block.Number(), "hash", hash)
@@ -341,6 +476,36 @@ func (bc *BlockChain) CurrentBlock() *types.Block {
 	return bc.currentBlock.Load().(*types.Block)
 }
 
+// CurrentIrreversible retrieves the header of the latest block that can no
+// longer be reorganised out of the canonical chain.
+func (bc *BlockChain) CurrentIrreversible() *types.Header {
+	return bc.irreversible.Load().(*types.Header)
+}
+
+// CurrentIrreversibleBlock retrieves the latest block that can no longer be
+// reorganised out of the canonical chain.
+func (bc *BlockChain) CurrentIrreversibleBlock() *types.Block {
+	header := bc.CurrentIrreversible()
+	return bc.GetBlock(header.Hash(), header.Number.Uint64())
+}
+
+// updateIrreversible advances the irreversible block to the newest one the
+// consensus engine considers final on top of the given head.
+//
+// Note, this function assumes that the `mu` mutex is held!
+func (bc *BlockChain) updateIrreversible(head *types.Header) {
+	checker, ok := bc.engine.(consensus.FinalityChecker)
+	if !ok {
+		return
+	}
+	header := checker.Irreversible(bc, head)
+	if header == nil || header.Number.Cmp(bc.CurrentIrreversible().Number) <= 0 {
+		return
+	}
+	bc.irreversible.Store(header)
+	log.Debug("Advanced irreversible block", "number", header.Number, "hash", header.Hash())
+}
+
 // CurrentFastBlock retrieves the current fast-sync head block of the canonical
 // chain. The block is retrieved from the blockchain's internal cache.
 func (bc *BlockChain) CurrentFastBlock() *types.Block {
@@ -382,7 +547,7 @@ func (bc *BlockChain) State() (*state.StateDB, error) {
 
 // StateAt returns a new mutable state based on a particular point in time.
 func (bc *BlockChain) StateAt(root common.Hash) (*state.StateDB, error) {
-	return state.New(root, bc.stateCache)
+	return state.NewWithSnapshot(root, bc.stateCache, bc.snaps)
 }
 
 // Reset purges the entire blockchain, restoring it to its genesis state.
@@ -409,6 +574,7 @@ func (bc *BlockChain) ResetWithGenesisBlock(genesis *types.Block) error {
 	bc.genesisBlock = genesis
 	bc.insert(bc.genesisBlock)
 	bc.currentBlock.Store(bc.genesisBlock)
+	bc.irreversible.Store(bc.genesisBlock.Header())
 	bc.hc.SetGenesis(bc.genesisBlock.Header())
 	bc.hc.SetCurrentHeader(bc.genesisBlock.Header())
 	bc.currentFastBlock.Store(bc.genesisBlock)
@@ -434,6 +600,55 @@ func (bc *BlockChain) repair(head **types.Block) error {
 	}
 }
 
+// lastCompleteBlock returns the newest canonical block at or below the header
+// with the given hash which has its body available, or nil if none is found.
+func (bc *BlockChain) lastCompleteBlock(hash common.Hash) *types.Block {
+	number := rawdb.ReadHeaderNumber(bc.db, hash)
+	if number == nil {
+		return nil
+	}
+	for n := *number; ; n-- {
+		if block := bc.GetBlock(rawdb.ReadCanonicalHash(bc.db, n), n); block != nil {
+			return block
+		}
+		if n == 0 {
+			return nil
+		}
+	}
+}
+
+// Recover verifies that the header, body and receipts of the recent canonical
+// blocks are all present in the database, checking the given number of blocks
+// below the head, or the entire chain if depth is zero. If any is incomplete,
+// the chain is rewound to the block preceding the oldest such one, so that the
+// missing data is synced again. The new head number is returned along with
+// whether a rewind was needed.
+func (bc *BlockChain) Recover(depth uint64) (uint64, bool, error) {
+	var (
+		head   = bc.CurrentBlock().NumberU64()
+		oldest = uint64(0)
+	)
+	limit := uint64(1)
+	if depth != 0 && depth < head {
+		limit = head - depth + 1
+	}
+	for n := head; n >= limit && n > 0; n-- {
+		hash := rawdb.ReadCanonicalHash(bc.db, n)
+		if hash == (common.Hash{}) || !rawdb.HasHeader(bc.db, hash, n) || !rawdb.HasBody(bc.db, hash, n) || !rawdb.HasReceipts(bc.db, hash, n) {
+			log.Warn("Incomplete block found", "number", n, "hash", hash)
+			oldest = n
+		}
+	}
+	if oldest == 0 {
+		return head, false, nil
+	}
+	log.Warn("Rolling back partially written blocks", "number", oldest, "head", head)
+	if err := bc.SetHead(oldest - 1); err != nil {
+		return head, false, err
+	}
+	return bc.CurrentBlock().NumberU64(), true, nil
+}
+
 // Export writes the active chain to the given writer.
 func (bc *BlockChain) Export(w io.Writer) error {
 	return bc.ExportN(w, uint64(0), bc.CurrentBlock().NumberU64())
@@ -441,14 +656,28 @@ func (bc *BlockChain) Export(w io.Writer) error {
 
 // ExportN writes a subset of the active chain to the given writer.
 func (bc *BlockChain) ExportN(w io.Writer, first uint64, last uint64) error {
+	return bc.exportN(w, first, last, false)
+}
+
+// ExportReceiptsN writes a subset of the active chain to the given writer, each
+// block followed by the list of its receipts in storage encoding.
+func (bc *BlockChain) ExportReceiptsN(w io.Writer, first uint64, last uint64) error {
+	return bc.exportN(w, first, last, true)
+}
+
+func (bc *BlockChain) exportN(w io.Writer, first uint64, last uint64, receipts bool) error {
 	bc.mu.RLock()
 	defer bc.mu.RUnlock()
 
 	if first > last {
 		return fmt.Errorf("export failed: first (%d) is greater than last (%d)", first, last)
 	}
-	log.Info("Exporting batch of blocks", "count", last-first+1)
+	log.Info("Exporting batch of blocks", "count", last-first+1, "receipts", receipts)
 
+	var (
+		start    = time.Now()
+		reported = time.Now()
+	)
 	for nr := first; nr <= last; nr++ {
 		block := bc.GetBlockByNumber(nr)
 		if block == nil {
@@ -458,6 +687,23 @@ func (bc *BlockChain) ExportN(w io.Writer, first uint64, last uint64) error {
 		if err := block.EncodeRLP(w); err != nil {
 			return err
 		}
+		if receipts {
+			list := bc.GetReceiptsByHash(block.Hash())
+			if list == nil && len(block.Transactions()) > 0 {
+				return fmt.Errorf("export failed on #%d: receipts not found", nr)
+			}
+			storage := make([]*types.ReceiptForStorage, len(list))
+			for i, receipt := range list {
+				storage[i] = (*types.ReceiptForStorage)(receipt)
+			}
+			if err := rlp.Encode(w, storage); err != nil {
+				return err
+			}
+		}
+		if time.Since(reported) >= statsReportLimit {
+			log.Info("Exporting blocks", "exported", nr-first+1, "number", nr, "elapsed", common.PrettyDuration(time.Since(start)))
+			reported = time.Now()
+		}
 	}
 
 	return nil
@@ -509,6 +755,8 @@ func (bc *BlockChain) GetBody(hash common.Hash) *types.Body {
 	if body == nil {
 		return nil
 	}
+	bc.loadSenders(*number, body.Transactions)
+
 	// Cache the found body for next time and return
 	bc.bodyCache.Add(hash, body)
 	return body
@@ -570,6 +818,8 @@ func (bc *BlockChain) GetBlock(hash common.Hash, number uint64) *types.Block {
 	if block == nil {
 		return nil
 	}
+	bc.loadSenders(number, block.Transactions())
+
 	// Cache the found block for next time and return
 	bc.blockCache.Add(block.Hash(), block)
 	return block
@@ -594,13 +844,45 @@ func (bc *BlockChain) GetBlockByNumber(number uint64) *types.Block {
 	return bc.GetBlock(hash, number)
 }
 
-// GetReceiptsByHash retrieves the receipts for all transactions in a given block.
+// GetReceiptsByHash retrieves the receipts for all transactions in a given block,
+// caching them if found.
 func (bc *BlockChain) GetReceiptsByHash(hash common.Hash) types.Receipts {
+	if receipts, ok := bc.receiptsCache.Get(hash); ok {
+		return receipts.(types.Receipts)
+	}
 	number := rawdb.ReadHeaderNumber(bc.db, hash)
 	if number == nil {
 		return nil
 	}
-	return rawdb.ReadReceipts(bc.db, hash, *number)
+	receipts := rawdb.ReadReceipts(bc.db, hash, *number)
+	if receipts == nil {
+		return nil
+	}
+	bc.receiptsCache.Add(hash, receipts)
+	return receipts
+}
+
+// storeSenders remembers the senders of the transactions of an imported block,
+// already recovered during its processing, so that they don't need recovering
+// again once the block is reloaded from the database.
+func (bc *BlockChain) storeSenders(block *types.Block) {
+	signer := types.MakeSigner(bc.chainConfig, block.Number())
+	for _, tx := range block.Transactions() {
+		if from, err := types.Sender(signer, tx); err == nil {
+			bc.senderCache.Add(tx.Hash(), from)
+		}
+	}
+}
+
+// loadSenders fills in the remembered senders of transactions loaded from the
+// database at the given block number.
+func (bc *BlockChain) loadSenders(number uint64, txs types.Transactions) {
+	signer := types.MakeSigner(bc.chainConfig, new(big.Int).SetUint64(number))
+	for _, tx := range txs {
+		if from, ok := bc.senderCache.Get(tx.Hash()); ok {
+			types.CacheSender(signer, tx, from.(common.Address))
+		}
+	}
 }
 
 // GetBlocksFromHash returns the block corresponding to hash and up to n-1 ancestors.
@@ -629,6 +911,11 @@ func (bc *BlockChain) TrieNode(hash common.Hash) ([]byte, error) {
 	return bc.stateCache.TrieDB().Node(hash)
 }
 
+// StateCache returns the caching database underpinning the blockchain instance.
+func (bc *BlockChain) StateCache() state.Database {
+	return bc.stateCache
+}
+
 // Stop stops the blockchain service. If any imports are currently in progress
 // it will abort them using the procInterrupt.
 func (bc *BlockChain) Stop() {
@@ -642,6 +929,14 @@ func (bc *BlockChain) Stop() {
 
 	bc.wg.Wait()
 
+	// Flatten the snapshot into the disk layer of the head state, which is
+	// persisted below, and persist any pending generation progress.
+	if bc.snaps != nil {
+		if err := bc.snaps.Cap(bc.CurrentBlock().Root(), 0); err != nil {
+			log.Error("Failed to flatten state snapshot", "err", err)
+		}
+		bc.snaps.Stop()
+	}
 	// Ensure the state of a recent block is also stored to disk before exiting.
 	// We're writing three different states to catch different restart scenarios:
 	//  - HEAD:     So we don't need to reprocess any blocks in the general case
@@ -660,6 +955,18 @@ func (bc *BlockChain) Stop() {
 				}
 			}
 		}
+		// Keep the configured canonical state history available after the restart.
+		// It is left to state pruning to reclaim it once out of the window.
+		if retain := bc.stateRetention(); retain > triesInMemory {
+			current := bc.CurrentBlock().NumberU64()
+			for i := uint64(0); i < retain && i <= current; i++ {
+				if header := bc.GetHeaderByNumber(current - i); header != nil {
+					if err := triedb.Commit(header.Root, false); err != nil {
+						log.Error("Failed to commit retained state trie", "number", header.Number, "root", header.Root, "err", err)
+					}
+				}
+			}
+		}
 		for !bc.triegc.Empty() {
 			triedb.Dereference(bc.triegc.PopItem().(common.Hash), common.Hash{})
 		}
@@ -670,6 +977,101 @@ func (bc *BlockChain) Stop() {
 	log.Info("Blockchain manager stopped")
 }
 
+// stateRetention returns the number of recent blocks whose state is kept
+// referenced in the trie cache, persisting it across restarts if above the
+// default in-memory window.
+func (bc *BlockChain) stateRetention() uint64 {
+	if bc.cacheConfig.StateHistory > triesInMemory {
+		return bc.cacheConfig.StateHistory
+	}
+	return triesInMemory
+}
+
+// PruneState deletes the persisted trie nodes and contract codes which are not
+// reachable from any retained state: the tries referenced in memory, the
+// persisted states of the canonical blocks within the retention window, the
+// genesis and the base of the state snapshot. Liveness is tracked with a bloom
+// filter of the given size in bytes.
+//
+// Block imports are only held off while the retained states are collected. The
+// trie nodes persisted afterwards are marked live as they are written, so that
+// the chain keeps running while pruning. It returns the amount of data deleted.
+func (bc *BlockChain) PruneState(bloomSize uint64) (common.StorageSize, error) {
+	if bc.cacheConfig.Disabled {
+		return 0, ErrPruneArchive
+	}
+	if !atomic.CompareAndSwapInt32(&bc.pruning, 0, 1) {
+		return 0, ErrPruneRunning
+	}
+	defer atomic.StoreInt32(&bc.pruning, 0)
+
+	bc.wg.Add(1)
+	defer bc.wg.Done()
+
+	prune, err := pruner.NewPruner(bc.db, bloomSize)
+	if err != nil {
+		return 0, err
+	}
+	triedb := bc.stateCache.TrieDB()
+
+	bc.chainmu.Lock()
+	bc.mu.Lock()
+	roots := bc.retainedRoots()
+	triedb.SetFlushHook(prune.MarkLive)
+	bc.mu.Unlock()
+	bc.chainmu.Unlock()
+
+	defer triedb.SetFlushHook(nil)
+	return prune.PruneRoots(bc.stateCache, roots)
+}
+
+// retainedRoots returns the available state roots a state prune must keep
+// reachable. The caller must hold the chain mutexes.
+func (bc *BlockChain) retainedRoots() []common.Hash {
+	var (
+		roots  []common.Hash
+		seen   = make(map[common.Hash]bool)
+		triedb = bc.stateCache.TrieDB()
+	)
+	retain := func(root common.Hash) {
+		if seen[root] {
+			return
+		}
+		if _, err := triedb.Node(root); err != nil {
+			return
+		}
+		seen[root] = true
+		roots = append(roots, root)
+	}
+	// Retain the tries referenced in memory, including the side chain ones
+	var (
+		cached     []interface{}
+		priorities []float32
+	)
+	for !bc.triegc.Empty() {
+		root, number := bc.triegc.Pop()
+		cached, priorities = append(cached, root), append(priorities, number)
+	}
+	for i, root := range cached {
+		bc.triegc.Push(root, priorities[i])
+		retain(root.(common.Hash))
+	}
+	// Retain the persisted states of the canonical chain within the window
+	current := bc.CurrentBlock().NumberU64()
+	for i := uint64(0); i < bc.stateRetention() && i <= current; i++ {
+		if header := bc.GetHeaderByNumber(current - i); header != nil {
+			retain(header.Root)
+		}
+	}
+	retain(bc.genesisBlock.Root())
+
+	// The snapshot may still be generated from its base state
+	if root := rawdb.ReadSnapshotRoot(bc.db); root != (common.Hash{}) {
+		retain(root)
+	}
+	return roots
+}
+
 func (bc *BlockChain) procFutureBlocks() {
 	blocks := make([]*types.Block, 0, bc.futureBlocks.Len())
 	for _, hash := range bc.futureBlocks.Keys() {
@@ -929,20 +1331,24 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 				lastWrite = chosen
 				bc.gcproc = 0
 			}
-			// Garbage collect anything below our required write retention
-			for !bc.triegc.Empty() {
-				root, number := bc.triegc.Pop()
-				if uint64(-number) > chosen {
-					bc.triegc.Push(root, number)
-					break
+			// Garbage collect anything below our required state retention, which
+			// may reach further back than the commit window
+			if retain := bc.stateRetention(); current > retain {
+				for !bc.triegc.Empty() {
+					root, number := bc.triegc.Pop()
+					if uint64(-number) > current-retain {
+						bc.triegc.Push(root, number)
+						break
+					}
+					triedb.Dereference(root.(common.Hash), common.Hash{})
 				}
-				triedb.Dereference(root.(common.Hash), common.Hash{})
 			}
 		}
 	}
 	rawdb.WriteReceipts(batch, block.Hash(), block.NumberU64(), receipts)
+	bc.storeSenders(block)
 
-	reorg := externTd.Cmp(localTd) > 0
+	reorg, rule := externTd.Cmp(localTd) > 0, ForkChoiceTd
 	currentBlock = bc.CurrentBlock()
 	if !reorg && externTd.Cmp(localTd) == 0 {
 		if block.Hash() == currentBlock.Hash() {
@@ -961,7 +1367,7 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 			return NonStatTy, fmt.Errorf("two blocks have same height and same tiemstamp")
 		}
 
-		reorg = true
+		reorg, rule = true, ForkChoiceTime
 	}
 	if reorg {
 		// Reorganise the chain if the parent is not the head block
@@ -969,10 +1375,20 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 			if err := bc.reorg(currentBlock, block); err != nil {
 				return NonStatTy, err
 			}
+			ev := ForkChoiceEvent{
+				OldHead: currentBlock.Header(),
+				OldTd:   localTd,
+				NewHead: block.Header(),
+				NewTd:   externTd,
+				Rule:    rule,
+			}
+			bc.postEvent(func() { bc.forkChoiceFeed.Send(ev) })
 		}
 		// Write the positional metadata for transaction/receipt lookups and preimages
 		rawdb.WriteTxLookupEntries(batch, block)
-		rawdb.WritePreimages(batch, block.NumberU64(), state.Preimages())
+		if !bc.cacheConfig.NoPreimages {
+			rawdb.WritePreimages(batch, block.NumberU64(), state.Preimages())
+		}
 
 		status = CanonStatTy
 	} else {
@@ -985,6 +1401,7 @@ func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.
 	// Set new head.
 	if status == CanonStatTy {
 		bc.insert(block)
+		bc.updateIrreversible(block.Header())
 	}
 	bc.futureBlocks.Remove(block.Hash())
 	return status, nil
@@ -1049,7 +1466,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 	defer close(abort)
 
 	// Start a parallel signature recovery (signer will fluke on fork transition, minimal perf loss)
-	senderCacher.recoverFromBlocks(types.MakeSigner(bc.chainConfig, chain[0].Number()), chain)
+	senderCacher.recoverFromBlocks(types.MakeSigner(bc.chainConfig, chain[0].Number()), chain, bc.senderCache)
 
 	// Iterate over the blocks and insert when the verifier permits
 	for i, block := range chain {
@@ -1140,24 +1557,31 @@ func (bc *BlockChain) insertChain(chain types.Blocks) (int, []interface{}, []*ty
 		} else {
 			parent = chain[i-1]
 		}
-		stateDb, err := state.New(parent.Root(), bc.stateCache)
+		stateDb, err := state.NewWithSnapshot(parent.Root(), bc.stateCache, bc.snaps)
 		if err != nil {
 			return i, events, coalescedLogs, err
 		}
 
 		// Verify the witness list using the parent's state
 		if err := bc.engine.VerifyWitnesses(block.Header(), stateDb, parent.Header()); err != nil {
+			bc.reportBlock(block, nil, err)
 			return i, events, coalescedLogs, err
 		}
 
 		// Verify commit msg
 		if err := bc.engine.VerifyCommitMsg(block); err != nil {
-			return i, events, coalescedLogs, fmt.Errorf("commit msg error: %s", err)
+			err = fmt.Errorf("commit msg error: %s", err)
+			bc.reportBlock(block, nil, err)
+			return i, events, coalescedLogs, err
 		}
 
 		// Process block using the parent state as reference point.
 		receipts, logs, usedGas, err := bc.processor.Process(block, stateDb, bc.vmConfig)
 		if err != nil {
+			if isMemoryCapExceeded(err) {
+				bc.reportMemoryCap(block, err)
+				return i, events, coalescedLogs, ErrBlockMemoryCap
+			}
 			bc.reportBlock(block, receipts, err)
 			return i, events, coalescedLogs, err
 		}
@@ -1329,6 +1753,12 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 			return fmt.Errorf("Invalid new chain")
 		}
 	}
+	// Never revert blocks that are already irreversible
+	if irreversible := bc.CurrentIrreversible(); commonBlock.NumberU64() < irreversible.Number.Uint64() {
+		log.Warn("Rejected reorg past irreversible block", "number", commonBlock.Number(), "hash", commonBlock.Hash(),
+			"irreversible", irreversible.Number, "drop", len(oldChain), "add", len(newChain))
+		return ErrReorgPastIrreversible
+	}
 	// Ensure the user sees large reorgs
 	if len(oldChain) > 0 && len(newChain) > 0 {
 		logFn := log.Debug
@@ -1365,6 +1795,24 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 				bc.chainSideFeed.Send(ChainSideEvent{Block: block})
 			}
 		}()
+
+		blockReorgMeter.Mark(1)
+		blockReorgDropMeter.Mark(int64(len(oldChain)))
+		blockReorgAddMeter.Mark(int64(len(newChain)))
+		blockReorgDepthHist.Update(int64(len(oldChain)))
+
+		ev := ChainReorgEvent{
+			Ancestor: commonBlock.Header(),
+			Dropped:  make([]common.Hash, len(oldChain)),
+			Added:    make([]common.Hash, len(newChain)),
+		}
+		for i, block := range oldChain {
+			ev.Dropped[i] = block.Hash()
+		}
+		for i, block := range newChain {
+			ev.Added[len(newChain)-1-i] = block.Hash()
+		}
+		bc.postEvent(func() { bc.reorgFeed.Send(ev) })
 	}
 
 	return nil
@@ -1405,42 +1853,173 @@ func (bc *BlockChain) update() {
 	}
 }
 
-// BadBlocks returns a list of the last 'bad blocks' that the client has seen on the network
-func (bc *BlockChain) BadBlocks() []*types.Block {
-	blocks := make([]*types.Block, 0, bc.badBlocks.Len())
+// deliverEvents sends the events queued by postEvent in order, away from the
+// chain lock their subscribers may need.
+func (bc *BlockChain) deliverEvents() {
+	defer bc.wg.Done()
+
+	for {
+		select {
+		case send := <-bc.events:
+			send()
+		case <-bc.quit:
+			return
+		}
+	}
+}
+
+// postEvent queues the delivery of an event generated under the chain lock. It
+// only blocks the import if the subscribers fall eventQueueLimit events behind.
+func (bc *BlockChain) postEvent(send func()) {
+	select {
+	case bc.events <- send:
+	case <-bc.quit:
+	}
+}
+
+// maintainTxIndex keeps the transaction lookup entries of the most recent
+// TxLookupLimit blocks, pruning older ones as the chain progresses and restoring
+// them if the limit was raised since the last run.
+func (bc *BlockChain) maintainTxIndex() {
+	defer bc.wg.Done()
+
+	headCh := make(chan ChainHeadEvent, 1)
+	sub := bc.SubscribeChainHeadEvent(headCh)
+	defer sub.Unsubscribe()
+
+	bc.indexTransactions(bc.CurrentBlock().NumberU64())
+	for {
+		select {
+		case ev := <-headCh:
+			bc.indexTransactions(ev.Block.NumberU64())
+		case <-sub.Err():
+			return
+		case <-bc.quit:
+			return
+		}
+	}
+}
+
+// indexTransactions moves the transaction index tail to honour the lookup limit
+// at the given head, removing the entries of blocks that fell out of the window
+// and writing back the ones of blocks that re-entered it.
+func (bc *BlockChain) indexTransactions(head uint64) {
+	var want uint64
+	if limit := bc.cacheConfig.TxLookupLimit; limit > 0 && head >= limit {
+		want = head - limit + 1
+	}
+	var tail uint64
+	if stored := rawdb.ReadTxIndexTail(bc.db); stored != nil {
+		tail = *stored
+	} else if want == 0 {
+		return // Nothing was ever pruned and nothing needs to be
+	}
+	if tail == want {
+		return
+	}
+	var (
+		start   = time.Now()
+		batch   = bc.db.NewBatch()
+		from    = tail
+		blocks  int
+		entries int
+	)
+	// Batches can't delete, so pruned entries are removed from the database
+	// directly (like during reorgs) and only the restored ones are batched.
+	flush := func(number uint64) bool {
+		rawdb.WriteTxIndexTail(batch, number)
+		if err := batch.Write(); err != nil {
+			log.Error("Failed to update transaction index", "err", err)
+			return false
+		}
+		batch.Reset()
+		return true
+	}
+	for tail != want {
+		// Unindex the oldest indexed block, or index the newest unindexed one
+		number, next := tail, tail+1
+		if want < tail {
+			number, next = tail-1, tail-1
+		}
+		block := rawdb.ReadBlock(bc.db, rawdb.ReadCanonicalHash(bc.db, number), number)
+		if block == nil {
+			log.Warn("Missing block for transaction indexing", "number", number)
+			break
+		}
+		if want > tail {
+			for _, tx := range block.Transactions() {
+				rawdb.DeleteTxLookupEntry(bc.db, tx.Hash())
+			}
+		} else {
+			rawdb.WriteTxLookupEntries(batch, block)
+		}
+		tail = next
+		blocks++
+		entries += len(block.Transactions())
+
+		if blocks%txIndexBlocksPerFlush == 0 || batch.ValueSize() >= vntdb.IdealBatchSize {
+			if !flush(tail) {
+				return
+			}
+			// Bail out if the chain is shutting down, progress is persisted
+			select {
+			case <-bc.quit:
+				return
+			default:
+			}
+		}
+	}
+	if !flush(tail) {
+		return
+	}
+	if blocks > 0 {
+		log.Debug("Updated transaction index", "from", from, "tail", tail, "blocks", blocks, "txs", entries, "elapsed", common.PrettyDuration(time.Since(start)))
+	}
+}
+
+// BadBlock is a block which failed validation, along with the reason why.
+type BadBlock struct {
+	Block    *types.Block
+	Err      error
+	Reported time.Time
+}
+
+// BadBlocks returns the last 'bad blocks' that the client has seen on the
+// network, oldest first.
+func (bc *BlockChain) BadBlocks() []*BadBlock {
+	blocks := make([]*BadBlock, 0, bc.badBlocks.Len())
 	for _, hash := range bc.badBlocks.Keys() {
-		if blk, exist := bc.badBlocks.Peek(hash); exist {
-			block := blk.(*types.Block)
-			blocks = append(blocks, block)
+		if bad, exist := bc.badBlocks.Peek(hash); exist {
+			blocks = append(blocks, bad.(*BadBlock))
 		}
 	}
 	return blocks
 }
 
-// addBadBlock adds a bad block to the bad-block LRU cache
-func (bc *BlockChain) addBadBlock(block *types.Block) {
-	bc.badBlocks.Add(block.Hash(), block)
+// addBadBlock adds a bad block to the bad-block cache, evicting the oldest one
+// when full.
+func (bc *BlockChain) addBadBlock(block *types.Block, err error) {
+	bc.badBlocks.Add(block.Hash(), &BadBlock{Block: block, Err: err, Reported: time.Now()})
 }
 
-// reportBlock logs a bad block error.
+// reportBlock records a bad block and logs why it was rejected.
 func (bc *BlockChain) reportBlock(block *types.Block, receipts types.Receipts, err error) {
-	bc.addBadBlock(block)
+	bc.addBadBlock(block, err)
 
-	var receiptString string
-	for _, receipt := range receipts {
-		receiptString += fmt.Sprintf("\t%v\n", receipt)
+	log.Error("Rejected bad block", "number", block.Number(), "hash", block.Hash(), "parent", block.ParentHash(),
+		"producer", block.Coinbase(), "txs", len(block.Transactions()), "receipts", len(receipts), "err", err)
+	for i, receipt := range receipts {
+		log.Debug("Bad block receipt", "hash", block.Hash(), "index", i, "status", receipt.Status,
+			"gas", receipt.GasUsed, "cumulative", receipt.CumulativeGasUsed, "logs", len(receipt.Logs))
 	}
-	log.Error(fmt.Sprintf(`
-########## BAD BLOCK #########
-Chain config: %v
-
-Number: %v
-Hash: 0x%x
-%v
+}
 
-Error: %v
-##############################
-`, bc.chainConfig, block.Number(), block.Hash(), receiptString, err))
+// reportMemoryCap logs a block that couldn't be executed within the local VM
+// memory cap. The block may well be valid, so it's not recorded as a bad block,
+// but the operator is pointed at the cap, as it stalls the import.
+func (bc *BlockChain) reportMemoryCap(block *types.Block, err error) {
+	log.Error("Block execution exceeded the VM memory cap, raise or disable --vm.memcap to import it",
+		"number", block.Number(), "hash", block.Hash(), "err", err)
 }
 
 // InsertHeaderChain attempts to insert the given header chain in to the local
@@ -1581,6 +2160,16 @@ func (bc *BlockChain) SubscribeChainSideEvent(ch chan<- ChainSideEvent) event.Su
 	return bc.scope.Track(bc.chainSideFeed.Subscribe(ch))
 }
 
+// SubscribeForkChoiceEvent registers a subscription of ForkChoiceEvent.
+func (bc *BlockChain) SubscribeForkChoiceEvent(ch chan<- ForkChoiceEvent) event.Subscription {
+	return bc.scope.Track(bc.forkChoiceFeed.Subscribe(ch))
+}
+
+// SubscribeChainReorgEvent registers a subscription of ChainReorgEvent.
+func (bc *BlockChain) SubscribeChainReorgEvent(ch chan<- ChainReorgEvent) event.Subscription {
+	return bc.scope.Track(bc.reorgFeed.Subscribe(ch))
+}
+
 // SubscribeLogsEvent registers a subscription of []*types.Log.
 func (bc *BlockChain) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription {
 	return bc.scope.Track(bc.logsFeed.Subscribe(ch))
@@ -1597,7 +2186,7 @@ func (bc *BlockChain) VerifyBlock(block *types.Block) (types.Receipts, []*types.
 	}
 
 	// Start a parallel signature recovery (signer will fluke on fork transition, minimal perf loss)
-	senderCacher.recoverFromBlocks(types.MakeSigner(bc.chainConfig, block.Number()), []*types.Block{block})
+	senderCacher.recoverFromBlocks(types.MakeSigner(bc.chainConfig, block.Number()), []*types.Block{block}, bc.senderCache)
 
 	if BadHashes[block.Hash()] {
 		return nil, nil, 0, ErrBlacklistedHash
@@ -1611,7 +2200,7 @@ func (bc *BlockChain) VerifyBlock(block *types.Block) (types.Receipts, []*types.
 	if parent == nil {
 		return nil, nil, 0, fmt.Errorf("parent is nil")
 	}
-	stateDb, err := state.New(parent.Root(), bc.stateCache)
+	stateDb, err := state.NewWithSnapshot(parent.Root(), bc.stateCache, bc.snaps)
 	if err != nil {
 		return nil, nil, 0, err
 	}
@@ -1624,6 +2213,10 @@ func (bc *BlockChain) VerifyBlock(block *types.Block) (types.Receipts, []*types.
 	// Process block using the parent state as reference point.
 	receipts, logs, usedGas, err := bc.processor.Process(block, stateDb, bc.vmConfig)
 	if err != nil {
+		if isMemoryCapExceeded(err) {
+			bc.reportMemoryCap(block, err)
+			return receipts, logs, usedGas, ErrBlockMemoryCap
+		}
 		return receipts, logs, usedGas, err
 	}
 
@@ -1652,6 +2245,10 @@ func (bc *BlockChain) WriteBlock(block *types.Block) error {
 	// Process block using the parent state as reference point.
 	receipts, logs, _, err := bc.processor.Process(block, stateDb, bc.vmConfig)
 	if err != nil {
+		if isMemoryCapExceeded(err) {
+			bc.reportMemoryCap(block, err)
+			return ErrBlockMemoryCap
+		}
 		bc.reportBlock(block, receipts, err)
 		return err
 	}
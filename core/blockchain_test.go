@@ -18,13 +18,16 @@ package core
 
 import (
 	"fmt"
+	"io/ioutil"
 	"math/big"
 	"math/rand"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/consensus"
 	"github.com/vntchain/go-vnt/consensus/mock"
 	"github.com/vntchain/go-vnt/core/rawdb"
 	"github.com/vntchain/go-vnt/core/state"
@@ -419,6 +422,79 @@ func testReorg(t *testing.T, first, second []int64, td int64, full bool) {
 	}
 }
 
+// Tests that replacing the canonical chain with a competing branch posts a reorg
+// event listing the dropped and added blocks, in the order of the reorgs.
+func TestReorgEvent(t *testing.T) {
+	db, blockchain, err := newCanonical(mock.NewMock(), 0, true)
+	if err != nil {
+		t.Fatalf("failed to create pristine chain: %v", err)
+	}
+	defer blockchain.Stop()
+
+	genesis := blockchain.CurrentBlock()
+	oldBlocks, _ := GenerateChain(params.TestChainConfig, genesis, mock.NewMock(), db, 3, func(i int, b *BlockGen) {
+		b.OffsetTime(int64(2*i + 1))
+	})
+	// The branch reaches the same height with earlier blocks, taking over the head
+	newBlocks, _ := GenerateChain(params.TestChainConfig, genesis, mock.NewMock(), db, 3, func(i int, b *BlockGen) {
+		b.OffsetTime(int64(2 * i))
+	})
+	if _, err := blockchain.InsertChain(oldBlocks); err != nil {
+		t.Fatalf("failed to insert old chain: %v", err)
+	}
+	reorgs := make(chan ChainReorgEvent, 1)
+	sub := blockchain.SubscribeChainReorgEvent(reorgs)
+	defer sub.Unsubscribe()
+
+	if _, err := blockchain.InsertChain(newBlocks); err != nil {
+		t.Fatalf("failed to insert new chain: %v", err)
+	}
+	// Extend the old chain to take the head back before reading any event
+	backBlocks, _ := GenerateChain(params.TestChainConfig, oldBlocks[len(oldBlocks)-1], mock.NewMock(), db, 1, func(i int, b *BlockGen) {})
+	if _, err := blockchain.InsertChain(backBlocks); err != nil {
+		t.Fatalf("failed to insert extended old chain: %v", err)
+	}
+	select {
+	case ev := <-reorgs:
+		if ev.Ancestor.Hash() != genesis.Hash() {
+			t.Errorf("ancestor mismatch: have %x, want %x", ev.Ancestor.Hash(), genesis.Hash())
+		}
+		if len(ev.Dropped) != len(oldBlocks) {
+			t.Fatalf("dropped count mismatch: have %d, want %d", len(ev.Dropped), len(oldBlocks))
+		}
+		for i, hash := range ev.Dropped {
+			if want := oldBlocks[len(oldBlocks)-1-i].Hash(); hash != want {
+				t.Errorf("dropped block %d mismatch: have %x, want %x", i, hash, want)
+			}
+		}
+		if len(ev.Added) != len(newBlocks) {
+			t.Fatalf("added count mismatch: have %d, want %d", len(ev.Added), len(newBlocks))
+		}
+		for i, hash := range ev.Added {
+			if want := newBlocks[i].Hash(); hash != want {
+				t.Errorf("added block %d mismatch: have %x, want %x", i, hash, want)
+			}
+		}
+	case <-time.After(time.Second):
+		t.Fatal("reorg event not posted")
+	}
+	// The reorg back must be delivered after the first one
+	select {
+	case ev := <-reorgs:
+		if ev.Ancestor.Hash() != genesis.Hash() {
+			t.Errorf("second ancestor mismatch: have %x, want %x", ev.Ancestor.Hash(), genesis.Hash())
+		}
+		if len(ev.Dropped) != len(newBlocks) || ev.Dropped[0] != newBlocks[len(newBlocks)-1].Hash() {
+			t.Errorf("second reorg dropped mismatch: have %x, want %d blocks from %x", ev.Dropped, len(newBlocks), newBlocks[len(newBlocks)-1].Hash())
+		}
+		if len(ev.Added) != len(oldBlocks)+1 || ev.Added[len(ev.Added)-1] != backBlocks[0].Hash() {
+			t.Errorf("second reorg added mismatch: have %x, want %d blocks to %x", ev.Added, len(oldBlocks)+1, backBlocks[0].Hash())
+		}
+	case <-time.After(time.Second):
+		t.Fatal("second reorg event not posted")
+	}
+}
+
 // Tests that the insertion functions detect banned hashes.
 func TestBadHeaderHashes(t *testing.T) { testBadHashes(t, false) }
 func TestBadBlockHashes(t *testing.T)  { testBadHashes(t, true) }
@@ -843,6 +919,57 @@ func TestChainTxReorgs(t *testing.T) {
 	}
 }
 
+// Tests that a block crossing the execution memory cap fails to process with an
+// error recognised as a local limit, while it processes fine without the cap.
+func TestProcessMemoryCap(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		db      = vntdb.NewMemDatabase()
+		gspec   = &Genesis{Config: params.TestChainConfig, GasLimit: 50000000, Alloc: GenesisAlloc{address: {Balance: big.NewInt(10000000000000)}}}
+		genesis = gspec.MustCommit(db)
+		signer  = types.NewHubbleSigner(gspec.Config.ChainID)
+	)
+	code, err := ioutil.ReadFile("wavm/testdata/event/init.compress")
+	if err != nil {
+		t.Fatalf("failed to read contract: %v", err)
+	}
+	blocks, _ := GenerateChain(gspec.Config, genesis, mock.NewMock(), db, 1, func(i int, gen *BlockGen) {
+		tx, err := types.SignTx(types.NewContractCreation(gen.TxNonce(address), new(big.Int), 10000000, new(big.Int), code), signer, key)
+		if err != nil {
+			t.Fatalf("failed to create tx: %v", err)
+		}
+		gen.AddTx(tx)
+	})
+	chain, _ := NewBlockChain(db, nil, gspec.Config, mock.NewMock(), vm.Config{})
+	defer chain.Stop()
+
+	process := func(cap uint64) error {
+		statedb, _ := state.New(genesis.Root(), chain.StateCache())
+		_, _, _, err := chain.Processor().Process(blocks[0], statedb, vm.Config{MemoryCap: cap})
+		return err
+	}
+	if err := process(0); err != nil {
+		t.Fatalf("uncapped processing failed: %v", err)
+	}
+	if err := process(1024); !isMemoryCapExceeded(err) {
+		t.Fatalf("capped processing error mismatch: have %v, want %v", err, vm.ErrMemoryCapExceeded)
+	}
+	// A capped import stalls with a local error, without marking the block bad
+	capped, _ := NewBlockChain(db, nil, gspec.Config, mock.NewMock(), vm.Config{MemoryCap: 1024})
+	defer capped.Stop()
+
+	if _, err := capped.InsertChain(blocks); err != ErrBlockMemoryCap {
+		t.Fatalf("capped import error mismatch: have %v, want %v", err, ErrBlockMemoryCap)
+	}
+	if bad := capped.BadBlocks(); len(bad) != 0 {
+		t.Errorf("capped block reported bad: %v", bad)
+	}
+	if head := capped.CurrentBlock().NumberU64(); head != 0 {
+		t.Errorf("capped block imported: head #%d", head)
+	}
+}
+
 func TestLogReorgs(t *testing.T) {
 
 	var (
@@ -938,6 +1065,60 @@ func TestCanonicalBlockRetrieval(t *testing.T) {
 	pend.Wait()
 }
 
+// Tests that the gas of sponsored transactions is paid by their fee payer, and
+// that they are only valid after the fee delegation fork.
+func TestSponsoredTransition(t *testing.T) {
+	var (
+		db          = vntdb.NewMemDatabase()
+		key, _      = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		payerKey, _ = crypto.HexToECDSA("8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a")
+		address     = crypto.PubkeyToAddress(key.PublicKey)
+		payer       = crypto.PubkeyToAddress(payerKey.PublicKey)
+		funds       = big.NewInt(1000000000)
+		gspec       = &Genesis{
+			Config: &params.ChainConfig{ChainID: big.NewInt(1), HubbleBlock: new(big.Int), FeeDelegationBlock: big.NewInt(2)},
+			Alloc:  GenesisAlloc{address: {Balance: big.NewInt(1000)}, payer: {Balance: funds}},
+		}
+		genesis = gspec.MustCommit(db)
+		signer  = types.NewHubbleSigner(gspec.Config.ChainID)
+	)
+	blockchain, _ := NewBlockChain(db, nil, gspec.Config, mock.NewMock(), vm.Config{})
+	defer blockchain.Stop()
+
+	sponsoredTx := func(nonce uint64) *types.Transaction {
+		tx, err := types.SignTx(types.NewTransaction(nonce, common.Address{0x01}, big.NewInt(1000), params.TxGas+params.TxFeePayerGas, big.NewInt(10), nil).WithFeePayer(payer), signer, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tx, err = types.SignFeePayerTx(tx, signer, payerKey); err != nil {
+			t.Fatal(err)
+		}
+		return tx
+	}
+	blocks, _ := GenerateChain(gspec.Config, genesis, mock.NewMock(), db, 2, func(i int, block *BlockGen) {
+		if i == 1 {
+			block.AddTx(sponsoredTx(0))
+		}
+	})
+	if _, err := blockchain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert sponsored transaction: %v", err)
+	}
+	statedb, _ := blockchain.State()
+	if balance := statedb.GetBalance(address); balance.Sign() != 0 {
+		t.Errorf("sender balance mismatch: have %v, want 0", balance)
+	}
+	want := new(big.Int).Sub(funds, big.NewInt(int64(params.TxGas+params.TxFeePayerGas)*10))
+	if balance := statedb.GetBalance(payer); balance.Cmp(want) != 0 {
+		t.Errorf("fee payer balance mismatch: have %v, want %v", balance, want)
+	}
+	// Sponsored transactions are rejected before the fork
+	statedb, _ = blockchain.StateAt(genesis.Root())
+	_, _, err := ApplyTransaction(gspec.Config, blockchain, nil, new(GasPool).AddGas(blocks[0].GasLimit()), statedb, blocks[0].Header(), sponsoredTx(0), new(uint64), vm.Config{})
+	if err != ErrFeeDelegationInactive {
+		t.Errorf("pre-fork error mismatch: have %v, want %v", err, ErrFeeDelegationInactive)
+	}
+}
+
 func TestEIP155Transition(t *testing.T) {
 	// Configure and generate a sample block chain
 	var (
@@ -1352,3 +1533,647 @@ func BenchmarkBlockChain_1x1000Executions(b *testing.B) {
 
 	benchmarkLargeNumberOfValueToNonexisting(b, numTxs, numBlocks, recipientFn, dataFn)
 }
+
+// Tests that switching the canonical head to a competing branch posts a fork
+// choice event naming both heads and the deciding rule, in the order of the
+// head switches.
+func TestForkChoiceEvent(t *testing.T) {
+	var (
+		db      = vntdb.NewMemDatabase()
+		gspec   = &Genesis{Config: params.TestChainConfig}
+		genesis = gspec.MustCommit(db)
+	)
+	blockchain, _ := NewBlockChain(db, nil, gspec.Config, mock.NewMock(), vm.Config{})
+	defer blockchain.Stop()
+
+	forkCh := make(chan ForkChoiceEvent, 1)
+	sub := blockchain.SubscribeForkChoiceEvent(forkCh)
+	defer sub.Unsubscribe()
+
+	// Import a two block chain whose head is late, no fork resolved yet
+	local, _ := GenerateChain(params.TestChainConfig, genesis, mock.NewMock(), db, 2, func(i int, gen *BlockGen) {
+		if i == 1 {
+			gen.OffsetTime(4)
+		}
+	})
+	if _, err := blockchain.InsertChain(local); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	select {
+	case ev := <-forkCh:
+		t.Fatalf("fork choice reported without competing branch: %v", ev.Rule)
+	case <-time.After(100 * time.Millisecond):
+	}
+	// Import an equally long branch with an earlier head, winning on timestamp
+	early, _ := GenerateChain(params.TestChainConfig, genesis, mock.NewMock(), db, 2, func(i int, gen *BlockGen) {})
+	if _, err := blockchain.InsertChain(early); err != nil {
+		t.Fatalf("failed to insert early branch: %v", err)
+	}
+	// Extend the abandoned branch, winning back on total difficulty
+	long, _ := GenerateChain(params.TestChainConfig, local[1], mock.NewMock(), db, 1, func(i int, gen *BlockGen) {})
+	if _, err := blockchain.InsertChain(long); err != nil {
+		t.Fatalf("failed to insert long branch: %v", err)
+	}
+	checkForkChoice(t, forkCh, local[1], early[1], ForkChoiceTime)
+	checkForkChoice(t, forkCh, early[1], long[0], ForkChoiceTd)
+}
+
+func checkForkChoice(t *testing.T, forkCh <-chan ForkChoiceEvent, old, new *types.Block, rule ForkChoiceRule) {
+	t.Helper()
+
+	select {
+	case ev := <-forkCh:
+		if ev.OldHead.Hash() != old.Hash() {
+			t.Errorf("old head mismatch: have %x, want %x", ev.OldHead.Hash(), old.Hash())
+		}
+		if ev.NewHead.Hash() != new.Hash() {
+			t.Errorf("new head mismatch: have %x, want %x", ev.NewHead.Hash(), new.Hash())
+		}
+		if ev.Rule != rule {
+			t.Errorf("rule mismatch: have %q, want %q", ev.Rule, rule)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("no fork choice event for new head %x", new.Hash())
+	}
+}
+
+// Tests that state pruning deletes the persisted states which fell out of the
+// retention window while keeping the recent ones alive, refuses to run on
+// archive nodes and refuses to run concurrently.
+func TestPruneState(t *testing.T) {
+	var (
+		engine  = mock.NewMock()
+		db      = vntdb.NewMemDatabase()
+		genesis = new(Genesis).MustCommit(db)
+		stale   = 8
+	)
+	blocks, _ := GenerateChain(params.TestChainConfig, genesis, engine, db, triesInMemory+stale+8, func(i int, b *BlockGen) {
+		b.SetCoinbase(common.Address{1})
+	})
+	diskdb := vntdb.NewMemDatabase()
+	new(Genesis).MustCommit(diskdb)
+
+	// Flush every state leaving the in-memory window to disk
+	cacheConfig := &CacheConfig{TrieNodeLimit: 256, TrieTimeLimit: time.Nanosecond}
+	chain, err := NewBlockChain(diskdb, cacheConfig, params.TestChainConfig, engine, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks[:triesInMemory+stale]); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	for _, block := range blocks[:stale] {
+		if ok, _ := diskdb.Has(block.Root().Bytes()); !ok {
+			t.Fatalf("block %d: stale state not persisted", block.NumberU64())
+		}
+	}
+	freed, err := chain.PruneState(1024 * 1024)
+	if err != nil {
+		t.Fatalf("failed to prune state: %v", err)
+	}
+	if freed == 0 {
+		t.Errorf("no state data pruned")
+	}
+	for _, block := range blocks[:stale] {
+		if ok, _ := diskdb.Has(block.Root().Bytes()); ok {
+			t.Errorf("block %d: stale state not pruned", block.NumberU64())
+		}
+	}
+	for _, block := range blocks[stale : triesInMemory+stale] {
+		if _, err := chain.StateAt(block.Root()); err != nil {
+			t.Fatalf("block %d: recent state pruned: %v", block.NumberU64(), err)
+		}
+	}
+	if _, err := chain.StateAt(chain.Genesis().Root()); err != nil {
+		t.Fatalf("genesis state pruned: %v", err)
+	}
+	// The chain must keep processing blocks on top of the pruned state
+	if _, err := chain.InsertChain(blocks[triesInMemory+stale:]); err != nil {
+		t.Fatalf("failed to extend pruned chain: %v", err)
+	}
+	// Concurrent prunes are rejected
+	atomic.StoreInt32(&chain.pruning, 1)
+	if _, err := chain.PruneState(1024 * 1024); err != ErrPruneRunning {
+		t.Fatalf("concurrent prune error mismatch: have %v, want %v", err, ErrPruneRunning)
+	}
+	atomic.StoreInt32(&chain.pruning, 0)
+
+	// Archive nodes are never pruned
+	archivedb := vntdb.NewMemDatabase()
+	new(Genesis).MustCommit(archivedb)
+
+	archive, err := NewBlockChain(archivedb, &CacheConfig{Disabled: true}, params.TestChainConfig, engine, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create archive chain: %v", err)
+	}
+	defer archive.Stop()
+
+	if _, err := archive.PruneState(1024 * 1024); err != ErrPruneArchive {
+		t.Fatalf("archive prune error mismatch: have %v, want %v", err, ErrPruneArchive)
+	}
+}
+
+// Tests that blocks keep being imported while the state is pruned, without the
+// states they persist meanwhile being pruned.
+func TestPruneStateWhileImporting(t *testing.T) {
+	var (
+		engine  = mock.NewMock()
+		db      = vntdb.NewMemDatabase()
+		genesis = new(Genesis).MustCommit(db)
+	)
+	blocks, _ := GenerateChain(params.TestChainConfig, genesis, engine, db, 2*triesInMemory, func(i int, b *BlockGen) {
+		b.SetCoinbase(common.Address{byte(i)})
+	})
+	diskdb := vntdb.NewMemDatabase()
+	new(Genesis).MustCommit(diskdb)
+
+	cacheConfig := &CacheConfig{TrieNodeLimit: 256, TrieTimeLimit: time.Nanosecond}
+	chain, err := NewBlockChain(diskdb, cacheConfig, params.TestChainConfig, engine, vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks[:triesInMemory]); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	errc := make(chan error, 1)
+	go func() {
+		_, err := chain.PruneState(1024 * 1024)
+		errc <- err
+	}()
+	for _, block := range blocks[triesInMemory:] {
+		if _, err := chain.InsertChain(types.Blocks{block}); err != nil {
+			t.Fatalf("block %d: failed to insert while pruning: %v", block.NumberU64(), err)
+		}
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("failed to prune state: %v", err)
+	}
+	for _, block := range blocks[triesInMemory:] {
+		if _, err := chain.StateAt(block.Root()); err != nil {
+			t.Fatalf("block %d: state imported while pruning missing: %v", block.NumberU64(), err)
+		}
+	}
+}
+
+// Tests that a configured state history keeps the state of older blocks than
+// the default window, both in memory and across restarts.
+func TestStateHistory(t *testing.T) {
+	var (
+		engine  = mock.NewMock()
+		db      = vntdb.NewMemDatabase()
+		genesis = new(Genesis).MustCommit(db)
+		history = uint64(triesInMemory + 32)
+	)
+	blocks, _ := GenerateChain(params.TestChainConfig, genesis, engine, db, int(history)+16, func(i int, b *BlockGen) {
+		b.SetCoinbase(common.Address{1})
+	})
+	newChain := func(db vntdb.Database, history uint64) *BlockChain {
+		config := &CacheConfig{TrieNodeLimit: 256 * 1024 * 1024, TrieTimeLimit: 5 * time.Minute, StateHistory: history}
+		chain, err := NewBlockChain(db, config, params.TestChainConfig, engine, vm.Config{})
+		if err != nil {
+			t.Fatalf("failed to create tester chain: %v", err)
+		}
+		return chain
+	}
+	// The default window drops the state of blocks older than it
+	fulldb := vntdb.NewMemDatabase()
+	new(Genesis).MustCommit(fulldb)
+
+	full := newChain(fulldb, 0)
+	defer full.Stop()
+
+	if _, err := full.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	old := blocks[len(blocks)-triesInMemory-8]
+	if _, err := full.StateAt(old.Root()); err == nil {
+		t.Errorf("block %d: state retained beyond the default window", old.NumberU64())
+	}
+	// The configured history keeps it
+	historydb := vntdb.NewMemDatabase()
+	new(Genesis).MustCommit(historydb)
+
+	chain := newChain(historydb, history)
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	check := func(chain *BlockChain) {
+		for _, block := range blocks {
+			_, err := chain.StateAt(block.Root())
+			if retained := block.NumberU64()+history > uint64(len(blocks)); retained && err != nil {
+				t.Errorf("block %d: state missing: %v", block.NumberU64(), err)
+			}
+		}
+	}
+	check(chain)
+	if _, err := chain.PruneState(1024 * 1024); err != nil {
+		t.Fatalf("failed to prune state: %v", err)
+	}
+	check(chain)
+
+	// The history survives a restart
+	chain.Stop()
+	chain = newChain(historydb, history)
+	defer chain.Stop()
+	check(chain)
+
+	// The history persisted at shutdown is pruned once out of the window
+	head := blocks[len(blocks)-1]
+	more, _ := GenerateChain(params.TestChainConfig, head, engine, db, int(history)+1, func(i int, b *BlockGen) {
+		b.SetCoinbase(common.Address{1})
+	})
+	if _, err := chain.InsertChain(more); err != nil {
+		t.Fatalf("failed to extend chain: %v", err)
+	}
+	if _, err := chain.PruneState(1024 * 1024); err != nil {
+		t.Fatalf("failed to prune state: %v", err)
+	}
+	for _, block := range blocks[len(blocks)-int(history):] {
+		if ok, _ := historydb.Has(block.Root().Bytes()); ok {
+			t.Errorf("block %d: stale history state not pruned", block.NumberU64())
+		}
+	}
+}
+
+// finalityEngine is a mock consensus engine considering every block irreversible
+// once depth blocks have been built on top of it.
+type finalityEngine struct {
+	*mock.Mock
+	depth uint64
+}
+
+func (e *finalityEngine) Irreversible(chain consensus.ChainReader, head *types.Header) *types.Header {
+	if head.Number.Uint64() < e.depth {
+		return nil
+	}
+	header := head
+	for i := uint64(0); header != nil && i < e.depth; i++ {
+		header = chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	}
+	return header
+}
+
+// Tests that the irreversible block follows the chain head and that reorgs
+// reverting it are refused.
+func TestReorgPastIrreversible(t *testing.T) {
+	var (
+		db      = vntdb.NewMemDatabase()
+		gspec   = &Genesis{Config: params.TestChainConfig}
+		genesis = gspec.MustCommit(db)
+		engine  = &finalityEngine{Mock: mock.NewMock(), depth: 3}
+	)
+	blockchain, _ := NewBlockChain(db, nil, gspec.Config, engine, vm.Config{})
+	defer blockchain.Stop()
+
+	if have := blockchain.CurrentIrreversible().Hash(); have != genesis.Hash() {
+		t.Fatalf("initial irreversible block mismatch: have %x, want genesis %x", have, genesis.Hash())
+	}
+	blocks, _ := GenerateChain(params.TestChainConfig, genesis, engine, db, 10, func(i int, gen *BlockGen) {})
+	if _, err := blockchain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	if have := blockchain.CurrentIrreversible().Number.Uint64(); have != 7 {
+		t.Fatalf("irreversible block mismatch: have %d, want %d", have, 7)
+	}
+	// A longer fork branching off below the irreversible block is refused
+	fork, _ := GenerateChain(params.TestChainConfig, blocks[4], engine, db, 8, func(i int, gen *BlockGen) {
+		if i == 0 {
+			gen.OffsetTime(-1)
+		}
+	})
+	if _, err := blockchain.InsertChain(fork); err != ErrReorgPastIrreversible {
+		t.Fatalf("reorg error mismatch: have %v, want %v", err, ErrReorgPastIrreversible)
+	}
+	if have := blockchain.CurrentBlock().Hash(); have != blocks[9].Hash() {
+		t.Fatalf("head block mismatch: have %x, want %x", have, blocks[9].Hash())
+	}
+	// A longer fork branching off at the irreversible block is accepted
+	fork, _ = GenerateChain(params.TestChainConfig, blocks[6], engine, db, 5, func(i int, gen *BlockGen) {
+		if i == 0 {
+			gen.OffsetTime(-1)
+		}
+	})
+	if _, err := blockchain.InsertChain(fork); err != nil {
+		t.Fatalf("failed to insert fork: %v", err)
+	}
+	if have := blockchain.CurrentBlock().Hash(); have != fork[4].Hash() {
+		t.Fatalf("head block mismatch: have %x, want %x", have, fork[4].Hash())
+	}
+	if have := blockchain.CurrentIrreversible().Number.Uint64(); have != 9 {
+		t.Fatalf("irreversible block mismatch: have %d, want %d", have, 9)
+	}
+}
+
+func TestTxLookupLimit(t *testing.T) {
+	var (
+		db      = vntdb.NewMemDatabase()
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  GenesisAlloc{address: {Balance: big.NewInt(1000000000)}},
+		}
+		genesis = gspec.MustCommit(db)
+		signer  = types.NewHubbleSigner(gspec.Config.ChainID)
+	)
+	blocks, _ := GenerateChain(gspec.Config, genesis, mock.NewMock(), db, 10, func(i int, block *BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(block.TxNonce(address), common.Address{0x00}, big.NewInt(1000), params.TxGas, nil, nil), signer, key)
+		if err != nil {
+			panic(err)
+		}
+		block.AddTx(tx)
+	})
+	blockchain, _ := NewBlockChain(db, nil, gspec.Config, mock.NewMock(), vm.Config{})
+	defer blockchain.Stop()
+
+	if _, err := blockchain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	check := func(tail uint64) {
+		if have := rawdb.ReadTxIndexTail(db); have == nil || *have != tail {
+			t.Fatalf("index tail mismatch: have %v, want %d", have, tail)
+		}
+		for _, block := range blocks {
+			hash, _, _ := rawdb.ReadTxLookupEntry(db, block.Transactions()[0].Hash())
+			if indexed := hash != (common.Hash{}); indexed != (block.NumberU64() >= tail) {
+				t.Errorf("block %d: indexed mismatch: have %v, want %v", block.NumberU64(), indexed, !indexed)
+			}
+		}
+	}
+	// Only the last four blocks keep their lookup entries
+	blockchain.cacheConfig.TxLookupLimit = 4
+	blockchain.indexTransactions(blockchain.CurrentBlock().NumberU64())
+	check(7)
+
+	// Raising the limit restores the pruned entries
+	blockchain.cacheConfig.TxLookupLimit = 0
+	blockchain.indexTransactions(blockchain.CurrentBlock().NumberU64())
+	check(0)
+}
+
+// Tests that rewinding the chain drops the indexes of the deleted blocks and
+// announces the new head.
+func TestSetHead(t *testing.T) {
+	var (
+		db      = vntdb.NewMemDatabase()
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  GenesisAlloc{address: {Balance: big.NewInt(1000000000)}},
+		}
+		genesis = gspec.MustCommit(db)
+		signer  = types.NewHubbleSigner(gspec.Config.ChainID)
+	)
+	blocks, _ := GenerateChain(gspec.Config, genesis, mock.NewMock(), db, 10, func(i int, block *BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(block.TxNonce(address), common.Address{0x00}, big.NewInt(1000), params.TxGas, nil, nil), signer, key)
+		if err != nil {
+			panic(err)
+		}
+		block.AddTx(tx)
+	})
+	blockchain, _ := NewBlockChain(db, nil, gspec.Config, mock.NewMock(), vm.Config{})
+	defer blockchain.Stop()
+
+	if _, err := blockchain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	headCh := make(chan ChainHeadEvent, 1)
+	sub := blockchain.SubscribeChainHeadEvent(headCh)
+	defer sub.Unsubscribe()
+
+	if err := blockchain.SetHead(6); err != nil {
+		t.Fatalf("failed to rewind chain: %v", err)
+	}
+	if have := blockchain.CurrentBlock().NumberU64(); have != 6 {
+		t.Fatalf("head block mismatch: have %d, want %d", have, 6)
+	}
+	select {
+	case ev := <-headCh:
+		if ev.Block.Hash() != blocks[5].Hash() {
+			t.Errorf("head event mismatch: have %x, want %x", ev.Block.Hash(), blocks[5].Hash())
+		}
+	default:
+		t.Errorf("no head event posted")
+	}
+	for _, block := range blocks {
+		kept := block.NumberU64() <= 6
+
+		hash, _, _ := rawdb.ReadTxLookupEntry(db, block.Transactions()[0].Hash())
+		if indexed := hash != (common.Hash{}); indexed != kept {
+			t.Errorf("block %d: indexed mismatch: have %v, want %v", block.NumberU64(), indexed, kept)
+		}
+		receipts := rawdb.ReadReceipts(db, block.Hash(), block.NumberU64())
+		if stored := receipts != nil; stored != kept {
+			t.Errorf("block %d: receipts mismatch: have %v, want %v", block.NumberU64(), stored, kept)
+		}
+		if canonical := blockchain.GetBlockByNumber(block.NumberU64()) != nil; canonical != kept {
+			t.Errorf("block %d: canonical mismatch: have %v, want %v", block.NumberU64(), canonical, kept)
+		}
+	}
+	// The removed blocks can be imported again
+	if _, err := blockchain.InsertChain(blocks[6:]); err != nil {
+		t.Fatalf("failed to reimport chain: %v", err)
+	}
+	if have := blockchain.CurrentBlock().NumberU64(); have != 10 {
+		t.Errorf("head block mismatch: have %d, want %d", have, 10)
+	}
+}
+
+// Tests that blocks left partially written in the database are rolled back on
+// startup instead of failing, and can be synced again.
+func TestRecoverIncompleteBlocks(t *testing.T) {
+	var (
+		db      = vntdb.NewMemDatabase()
+		gspec   = &Genesis{Config: params.TestChainConfig}
+		genesis = gspec.MustCommit(db)
+		archive = &CacheConfig{Disabled: true}
+	)
+	blocks, _ := GenerateChain(gspec.Config, genesis, mock.NewMock(), db, 10, nil)
+
+	blockchain, _ := NewBlockChain(db, archive, gspec.Config, mock.NewMock(), vm.Config{})
+	if _, err := blockchain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	if head, rewound, err := blockchain.Recover(0); err != nil || rewound || head != 10 {
+		t.Fatalf("consistent chain recovery mismatch: have #%d/%v/%v, want #10/false/nil", head, rewound, err)
+	}
+	blockchain.Stop()
+
+	// Drop the body of the head block and the receipts of an earlier one
+	rawdb.DeleteBody(db, blocks[9].Hash(), blocks[9].NumberU64())
+	rawdb.DeleteReceipts(db, blocks[7].Hash(), blocks[7].NumberU64())
+
+	blockchain, err := NewBlockChain(db, archive, gspec.Config, mock.NewMock(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to reopen chain: %v", err)
+	}
+	defer blockchain.Stop()
+
+	if have := blockchain.CurrentBlock().NumberU64(); have != 7 {
+		t.Fatalf("head block mismatch: have %d, want %d", have, 7)
+	}
+	if have := blockchain.CurrentHeader().Number.Uint64(); have != 7 {
+		t.Errorf("head header mismatch: have %d, want %d", have, 7)
+	}
+	if _, err := blockchain.InsertChain(blocks[7:]); err != nil {
+		t.Fatalf("failed to resync chain: %v", err)
+	}
+	if have := blockchain.CurrentBlock().NumberU64(); have != 10 {
+		t.Errorf("resynced head block mismatch: have %d, want %d", have, 10)
+	}
+}
+
+// Tests that rejected blocks are recorded along with the reason, keeping only
+// the most recent ones.
+func TestBadBlocks(t *testing.T) {
+	db, blockchain, err := newCanonical(mock.NewMock(), 0, true)
+	if err != nil {
+		t.Fatalf("failed to create pristine chain: %v", err)
+	}
+	defer blockchain.Stop()
+
+	blocks := makeBlockChain(blockchain.CurrentBlock(), badBlockLimit+2, mock.NewMock(), db, 10)
+
+	BadHashes[blocks[0].Hash()] = true
+	defer delete(BadHashes, blocks[0].Hash())
+
+	if _, err := blockchain.InsertChain(blocks); err != ErrBlacklistedHash {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrBlacklistedHash)
+	}
+	bads := blockchain.BadBlocks()
+	if len(bads) != 1 || bads[0].Block.Hash() != blocks[0].Hash() || bads[0].Err != ErrBlacklistedHash {
+		t.Fatalf("bad block mismatch: have %v, want %x rejected as %v", bads, blocks[0].Hash(), ErrBlacklistedHash)
+	}
+	// Overflow the cache, only the most recent blocks should be kept in order
+	for i, block := range blocks {
+		blockchain.reportBlock(block, nil, fmt.Errorf("bad block %d", i))
+	}
+	bads = blockchain.BadBlocks()
+	if len(bads) != badBlockLimit {
+		t.Fatalf("bad block count mismatch: have %d, want %d", len(bads), badBlockLimit)
+	}
+	for i, bad := range bads {
+		want := blocks[i+2]
+		if bad.Block.Hash() != want.Hash() {
+			t.Errorf("bad block %d mismatch: have %x, want %x", i, bad.Block.Hash(), want.Hash())
+		}
+		if have, want := bad.Err.Error(), fmt.Sprintf("bad block %d", i+2); have != want {
+			t.Errorf("bad block %d error mismatch: have %q, want %q", i, have, want)
+		}
+	}
+}
+
+// Tests that receipts and transaction senders of recently accessed blocks are
+// served from memory, within the configured cache sizes.
+func TestReceiptAndSenderCaches(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  GenesisAlloc{address: {Balance: big.NewInt(1000000000)}},
+		}
+		signer = types.NewHubbleSigner(gspec.Config.ChainID)
+	)
+	gendb := vntdb.NewMemDatabase()
+	genesis := gspec.MustCommit(gendb)
+	blocks, _ := GenerateChain(gspec.Config, genesis, mock.NewMock(), gendb, 3, func(i int, block *BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(block.TxNonce(address), common.Address{0x01}, big.NewInt(1000), params.TxGas, nil, nil), signer, key)
+		if err != nil {
+			t.Fatalf("failed to sign transaction: %v", err)
+		}
+		block.AddTx(tx)
+	})
+	db := &readCountingDatabase{Database: vntdb.NewMemDatabase()}
+	gspec.MustCommit(db)
+
+	config := &CacheConfig{TrieNodeLimit: 256, TrieTimeLimit: 5 * time.Minute, ReceiptCacheLimit: 2, SenderCacheLimit: 2}
+	blockchain, err := NewBlockChain(db, config, gspec.Config, mock.NewMock(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer blockchain.Stop()
+
+	if _, err := blockchain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	// Receipts are read from disk once, then served from memory
+	for i, block := range blocks {
+		if receipts := blockchain.GetReceiptsByHash(block.Hash()); len(receipts) != 1 {
+			t.Fatalf("block %d: receipt count mismatch: have %d, want 1", i, len(receipts))
+		}
+	}
+	if have := blockchain.receiptsCache.Len(); have != 2 {
+		t.Errorf("receipt cache size mismatch: have %d, want 2", have)
+	}
+	atomic.StoreInt32(&db.reads, 0)
+	blockchain.GetReceiptsByHash(blocks[2].Hash())
+	if reads := atomic.LoadInt32(&db.reads); reads != 0 {
+		t.Errorf("cached receipt reads mismatch: have %d, want 0", reads)
+	}
+	// Senders of blocks reloaded from disk are known without recovery
+	if have := blockchain.senderCache.Len(); have != 2 {
+		t.Errorf("sender cache size mismatch: have %d, want 2", have)
+	}
+	blockchain.blockCache.Purge()
+	for i, block := range blocks {
+		tx := blockchain.GetBlock(block.Hash(), block.NumberU64()).Transactions()[0]
+		if have, want := blockchain.senderCache.Contains(tx.Hash()), i > 0; have != want {
+			t.Errorf("block %d: sender cached mismatch: have %v, want %v", i, have, want)
+		}
+		if from, err := types.Sender(signer, tx); err != nil || from != address {
+			t.Errorf("block %d: sender mismatch: have %x/%v, want %x", i, from, err, address)
+		}
+	}
+	// Rewinding the chain drops the receipts of the removed blocks
+	blockchain.SetHead(1)
+	if have := blockchain.GetReceiptsByHash(blocks[2].Hash()); have != nil {
+		t.Errorf("rewound receipts mismatch: have %v, want nil", have)
+	}
+}
+
+// Tests that no preimages of hashed trie keys are stored if recording them is
+// disabled.
+func TestNoPreimages(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  GenesisAlloc{address: {Balance: big.NewInt(1000000000)}},
+		}
+		signer = types.NewHubbleSigner(gspec.Config.ChainID)
+	)
+	gendb := vntdb.NewMemDatabase()
+	blocks, _ := GenerateChain(gspec.Config, gspec.MustCommit(gendb), mock.NewMock(), gendb, 2, func(i int, block *BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(address), common.Address{byte(i + 1)}, big.NewInt(1000), params.TxGas, nil, nil), signer, key)
+		block.AddTx(tx)
+	})
+	countPreimages := func(db vntdb.Database) (count int) {
+		rawdb.IteratePreimages(db, func(common.Hash, []byte) error {
+			count++
+			return nil
+		})
+		return count
+	}
+	for _, disabled := range []bool{false, true} {
+		db := vntdb.NewMemDatabase()
+		gspec.MustCommit(db)
+		genesisPreimages := countPreimages(db)
+
+		blockchain, _ := NewBlockChain(db, &CacheConfig{Disabled: true, NoPreimages: disabled}, gspec.Config, mock.NewMock(), vm.Config{})
+		if _, err := blockchain.InsertChain(blocks); err != nil {
+			t.Fatalf("failed to insert chain: %v", err)
+		}
+		blockchain.Stop()
+
+		if have, genesis := countPreimages(db), genesisPreimages; (have == genesis) != disabled {
+			t.Errorf("preimages disabled %v: preimage count mismatch: have %d, genesis %d", disabled, have, genesis)
+		}
+	}
+}
@@ -210,6 +210,10 @@ func (c *ChainIndexer) eventLoop(currentHeader *types.Header, events chan ChainE
 				// potentially also lock up. We need to do with on a different thread somehow.
 				if h := rawdb.FindCommonAncestor(c.chainDb, prevHeader, header); h != nil {
 					c.newHead(h.Number.Uint64(), true)
+				} else if rawdb.ReadHeader(c.chainDb, prevHash, prevHeader.Number.Uint64()) == nil && header.Number.Sign() > 0 {
+					// The previous head was deleted by rewinding the chain, revert to
+					// the parent of the new head, which is still known
+					c.newHead(header.Number.Uint64()-1, true)
 				}
 			}
 			c.newHead(header.Number.Uint64(), false)
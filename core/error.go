@@ -32,4 +32,17 @@ var (
 	// ErrNonceTooHigh is returned if the nonce of a transaction is higher than the
 	// next one expected based on the local chain.
 	ErrNonceTooHigh = errors.New("nonce too high")
+
+	// ErrReorgPastIrreversible is returned if a chain reorganisation would revert
+	// a block the consensus engine already considers irreversible.
+	ErrReorgPastIrreversible = errors.New("reorg past irreversible block")
+
+	// ErrFeeDelegationInactive is returned if a sponsored transaction is included
+	// before the fee delegation fork.
+	ErrFeeDelegationInactive = errors.New("sponsored transactions not yet active")
+
+	// ErrBlockMemoryCap is returned if a block to import can't be executed within
+	// the local VM memory cap. The block isn't invalid, the node just can't import
+	// it until the cap is raised.
+	ErrBlockMemoryCap = errors.New("block exceeds local VM memory cap")
 )
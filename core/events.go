@@ -17,6 +17,8 @@
 package core
 
 import (
+	"math/big"
+
 	"github.com/vntchain/go-vnt/common"
 	"github.com/vntchain/go-vnt/core/types"
 )
@@ -50,6 +52,35 @@ type ChainSideEvent struct {
 
 type ChainHeadEvent struct{ Block *types.Block }
 
+// ChainReorgEvent is posted when blocks of the canonical chain are replaced by
+// the blocks of a competing branch.
+type ChainReorgEvent struct {
+	Ancestor *types.Header // Last block shared by both branches
+	Dropped  []common.Hash // Blocks removed from the canonical chain, newest first
+	Added    []common.Hash // Blocks added to the canonical chain, oldest first
+}
+
+// ForkChoiceRule names the rule which decided between two competing chain heads.
+type ForkChoiceRule string
+
+const (
+	// ForkChoiceTd picks the branch with the higher total difficulty.
+	ForkChoiceTd ForkChoiceRule = "higher total difficulty"
+
+	// ForkChoiceTime picks the branch with the earlier head at equal total difficulty.
+	ForkChoiceTime ForkChoiceRule = "earlier timestamp"
+)
+
+// ForkChoiceEvent is posted when the canonical head moves from one branch to a
+// competing one.
+type ForkChoiceEvent struct {
+	OldHead *types.Header
+	OldTd   *big.Int
+	NewHead *types.Header
+	NewTd   *big.Int
+	Rule    ForkChoiceRule
+}
+
 type SendBftMsgEvent struct{ BftMsg types.BftMsg }
 
 type BftPeerChangeEvent struct{ Urls []string }
@@ -35,6 +35,10 @@ func TestDefaultGenesisBlock(t *testing.T) {
 	if block.Hash() != params.MainnetGenesisHash {
 		t.Errorf("wrong mainnet genesis hash, got %v, want %v", block.Hash(), params.MainnetGenesisHash)
 	}
+	block = DefaultTestnetGenesisBlock().ToBlock(nil)
+	if block.Hash() != params.TestnetGenesisHash {
+		t.Errorf("wrong testnet genesis hash, got %v, want %v", block.Hash(), params.TestnetGenesisHash)
+	}
 }
 
 func TestSetupGenesis(t *testing.T) {
@@ -148,3 +152,61 @@ func TestSetupGenesis(t *testing.T) {
 		}
 	}
 }
+
+// Tests that changing the dpos governance cadence of a chain past genesis is
+// rejected, while a chain without blocks may still be reconfigured.
+func TestSetupGenesisDposCadence(t *testing.T) {
+	newGenesis := func(epoch uint64) *Genesis {
+		return &Genesis{
+			Config: &params.ChainConfig{
+				HubbleBlock: big.NewInt(0),
+				Dpos:        &params.DposConfig{Period: 2, WitnessesNum: 4, EpochLength: epoch},
+			},
+			Alloc: GenesisAlloc{{1}: {Balance: big.NewInt(1)}},
+		}
+	}
+	// An empty chain may change its cadence
+	db := vntdb.NewMemDatabase()
+	newGenesis(12).MustCommit(db)
+	if config, _, err := SetupGenesisBlock(db, newGenesis(16)); err != nil || config.Dpos.EpochLength != 16 {
+		t.Fatalf("empty chain not reconfigured: %v, %v", config, err)
+	}
+	// A chain past genesis must keep it
+	db = vntdb.NewMemDatabase()
+	old := newGenesis(12)
+	genesis := old.MustCommit(db)
+
+	bc, _ := NewBlockChain(db, nil, old.Config, mock.NewMock(), vm.Config{})
+	defer bc.Stop()
+
+	blocks, _ := GenerateChain(old.Config, genesis, mock.NewMock(), db, 2, nil)
+	if _, err := bc.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	if _, _, err := SetupGenesisBlock(db, newGenesis(16)); err == nil {
+		t.Fatalf("dpos epoch length changed past genesis")
+	}
+	if stored := rawdb.ReadChainConfig(db, genesis.Hash()); stored.Dpos.EpochLength != 12 {
+		t.Errorf("stored epoch length mismatch: have %d, want %d", stored.Dpos.EpochLength, 12)
+	}
+	if _, _, err := SetupGenesisBlock(db, newGenesis(12)); err != nil {
+		t.Errorf("unchanged config rejected: %v", err)
+	}
+}
+
+// Tests that the developer genesis elects the faucet as the sole DPoS witness.
+func TestDeveloperGenesisBlock(t *testing.T) {
+	faucet := common.HexToAddress("0x1234567890123456789012345678901234567890")
+	genesis := DeveloperGenesisBlock(5, faucet)
+
+	block := genesis.MustCommit(vntdb.NewMemDatabase())
+	if witnesses := block.Header().Witnesses; len(witnesses) != 1 || witnesses[0] != faucet {
+		t.Fatalf("witnesses mismatch: have %x, want [%x]", witnesses, faucet)
+	}
+	if config := genesis.Config.Dpos; config.WitnessesNum != 1 || config.Period != 5 {
+		t.Errorf("dpos config mismatch: have %+v", config)
+	}
+	if params.AllCliqueProtocolChanges.Dpos.WitnessesNum == 1 {
+		t.Errorf("shared chain config modified")
+	}
+}
@@ -19,10 +19,12 @@ package rawdb
 import (
 	"encoding/json"
 
+	"github.com/syndtr/goleveldb/leveldb/iterator"
 	"github.com/vntchain/go-vnt/common"
 	"github.com/vntchain/go-vnt/log"
 	"github.com/vntchain/go-vnt/params"
 	"github.com/vntchain/go-vnt/rlp"
+	"github.com/vntchain/go-vnt/vntdb"
 )
 
 // ReadDatabaseVersion retrieves the version number of the database.
@@ -88,3 +90,30 @@ func WritePreimages(db DatabaseWriter, number uint64, preimages map[common.Hash]
 	preimageCounter.Inc(int64(len(preimages)))
 	preimageHitCounter.Inc(int64(len(preimages)))
 }
+
+// IteratePreimages calls fn for every preimage stored in the database, stopping
+// at the first error returned by it.
+func IteratePreimages(db vntdb.Database, fn func(hash common.Hash, preimage []byte) error) error {
+	if frdb, ok := db.(*FreezerDatabase); ok {
+		db = frdb.KeyValueStore()
+	}
+	store, ok := db.(interface {
+		NewIteratorWithPrefix(prefix []byte) iterator.Iterator
+	})
+	if !ok {
+		return errNotIterable
+	}
+	it := store.NewIteratorWithPrefix(preimagePrefix)
+	defer it.Release()
+
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(preimagePrefix)+common.HashLength {
+			continue
+		}
+		if err := fn(common.BytesToHash(key[len(preimagePrefix):]), it.Value()); err != nil {
+			return err
+		}
+	}
+	return it.Error()
+}
@@ -0,0 +1,156 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/log"
+	"github.com/vntchain/go-vnt/vntdb"
+)
+
+// FreezerDatabase is a key-value database with an attached ancient store. Old
+// canonical chain segments are periodically moved out of the key-value store
+// into the append-only freezer, and reads of them are transparently served from
+// there. All writes go to the key-value store.
+type FreezerDatabase struct {
+	db      vntdb.Database
+	freezer *freezer
+}
+
+// NewDatabaseWithFreezer creates a high level database on top of a given key-value
+// data store with a freezer moving immutable chain segments into the ancient store
+// located at the given path.
+func NewDatabaseWithFreezer(db vntdb.Database, freezer string) (*FreezerDatabase, error) {
+	frdb, err := newFreezer(freezer)
+	if err != nil {
+		return nil, err
+	}
+	// The key-value store keeps the number index of frozen blocks, so if it does
+	// not know the last frozen block, the two stores were not used together.
+	if frozen := frdb.Ancients(); frozen > 0 {
+		hash, err := frdb.Ancient(freezerHashTable, frozen-1)
+		if err != nil || ReadHeaderNumber(db, common.BytesToHash(hash)) == nil {
+			frdb.Close()
+			return nil, errAncientMismatch
+		}
+	}
+	frdb.wg.Add(1)
+	go frdb.freeze(db)
+
+	return &FreezerDatabase{db: db, freezer: frdb}, nil
+}
+
+// KeyValueStore returns the database holding the recent, mutable chain data.
+func (db *FreezerDatabase) KeyValueStore() vntdb.Database {
+	return db.db
+}
+
+// Ancients returns the number of blocks moved into the ancient store.
+func (db *FreezerDatabase) Ancients() uint64 {
+	return db.freezer.Ancients()
+}
+
+func (db *FreezerDatabase) Put(key []byte, value []byte) error {
+	return db.db.Put(key, value)
+}
+
+func (db *FreezerDatabase) Has(key []byte) (bool, error) {
+	if ok, err := db.db.Has(key); err != nil || ok {
+		return ok, err
+	}
+	_, err := db.ancient(key)
+	return err == nil, nil
+}
+
+func (db *FreezerDatabase) Get(key []byte) ([]byte, error) {
+	value, err := db.db.Get(key)
+	if err == nil {
+		return value, nil
+	}
+	if value, aerr := db.ancient(key); aerr == nil {
+		return value, nil
+	}
+	return nil, err
+}
+
+func (db *FreezerDatabase) Delete(key []byte) error {
+	return db.db.Delete(key)
+}
+
+// Close stops the freezer and closes both the ancient and key-value stores.
+func (db *FreezerDatabase) Close() {
+	if err := db.freezer.Close(); err != nil {
+		log.Error("Failed to close ancient database", "err", err)
+	}
+	db.db.Close()
+}
+
+func (db *FreezerDatabase) NewBatch() vntdb.Batch {
+	return db.db.NewBatch()
+}
+
+// ancient maps a chain data key onto the freezer, retrieving the item if the
+// block it belongs to has already been frozen.
+func (db *FreezerDatabase) ancient(key []byte) ([]byte, error) {
+	kind, number, hash, ok := ancientKey(key)
+	if !ok {
+		return nil, errUnknownTable
+	}
+	if ok, err := db.freezer.HasAncient(kind, number); err != nil || !ok {
+		return nil, errOutOfBounds
+	}
+	// Items stored by hash are only served for the canonical block
+	if kind != freezerHashTable {
+		canonical, err := db.freezer.Ancient(freezerHashTable, number)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(canonical, hash.Bytes()) {
+			return nil, errOutOfBounds
+		}
+	}
+	return db.freezer.Ancient(kind, number)
+}
+
+// ancientKey parses a key of the chain data schema, returning the freezer table
+// holding the item, and the number and hash of the block it belongs to.
+func ancientKey(key []byte) (string, uint64, common.Hash, bool) {
+	const numbered = 1 + 8 // prefix + num (uint64 big endian)
+
+	if len(key) < numbered {
+		return "", 0, common.Hash{}, false
+	}
+	number := binary.BigEndian.Uint64(key[1:numbered])
+	hash := func() common.Hash { return common.BytesToHash(key[numbered : numbered+common.HashLength]) }
+
+	switch {
+	case bytes.HasPrefix(key, headerPrefix) && len(key) == numbered+len(headerHashSuffix) && bytes.HasSuffix(key, headerHashSuffix):
+		return freezerHashTable, number, common.Hash{}, true
+	case bytes.HasPrefix(key, headerPrefix) && len(key) == numbered+common.HashLength:
+		return freezerHeaderTable, number, hash(), true
+	case bytes.HasPrefix(key, headerPrefix) && len(key) == numbered+common.HashLength+len(headerTDSuffix) && bytes.HasSuffix(key, headerTDSuffix):
+		return freezerDifficultyTable, number, hash(), true
+	case bytes.HasPrefix(key, blockBodyPrefix) && len(key) == numbered+common.HashLength:
+		return freezerBodiesTable, number, hash(), true
+	case bytes.HasPrefix(key, blockReceiptsPrefix) && len(key) == numbered+common.HashLength:
+		return freezerReceiptTable, number, hash(), true
+	}
+	return "", 0, common.Hash{}, false
+}
@@ -0,0 +1,332 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/log"
+	"github.com/vntchain/go-vnt/params"
+	"github.com/vntchain/go-vnt/vntdb"
+)
+
+// The list of table names of chain freezer.
+const (
+	// freezerHashTable indicates the name of the freezer canonical hash table.
+	freezerHashTable = "hashes"
+
+	// freezerHeaderTable indicates the name of the freezer header table.
+	freezerHeaderTable = "headers"
+
+	// freezerBodiesTable indicates the name of the freezer block body table.
+	freezerBodiesTable = "bodies"
+
+	// freezerReceiptTable indicates the name of the freezer receipts table.
+	freezerReceiptTable = "receipts"
+
+	// freezerDifficultyTable indicates the name of the freezer total difficulty table.
+	freezerDifficultyTable = "diffs"
+)
+
+// freezerTables is the list of tables every frozen block is split into.
+var freezerTables = []string{freezerHashTable, freezerHeaderTable, freezerBodiesTable, freezerReceiptTable, freezerDifficultyTable}
+
+var (
+	// errUnknownTable is returned if the user attempts to read from a table that is
+	// not tracked by the freezer.
+	errUnknownTable = errors.New("unknown table")
+
+	// errAncientMismatch is returned if the ancient store does not continue the
+	// chain held by the key-value store it is attached to.
+	errAncientMismatch = errors.New("ancient chain segments don't match the key-value database")
+)
+
+const (
+	// freezerRecheckInterval is the frequency to check the key-value database for
+	// chain progression that might permit new blocks to be frozen into immutable
+	// storage.
+	freezerRecheckInterval = time.Minute
+
+	// freezerBatchLimit is the maximum number of blocks to freeze in one batch
+	// before doing an fsync and deleting it from the key-value store.
+	freezerBatchLimit = 30000
+)
+
+// freezer is a memory mapped append-only database to store immutable chain data
+// into flat files:
+//
+// - The append only nature ensures that disk writes are minimized.
+// - The in-order data ensures that disk reads are always optimized.
+type freezer struct {
+	frozen    uint64 // Number of blocks already frozen (atomic)
+	threshold uint64 // Number of recent blocks to keep in the key-value store
+
+	tables map[string]*freezerTable // Data tables for storing everything
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newFreezer creates a chain freezer that moves ancient chain data into
+// append-only flat file containers.
+func newFreezer(datadir string) (*freezer, error) {
+	freezer := &freezer{
+		threshold: params.ImmutabilityThreshold,
+		tables:    make(map[string]*freezerTable),
+		quit:      make(chan struct{}),
+	}
+	for _, name := range freezerTables {
+		table, err := newTable(datadir, name)
+		if err != nil {
+			freezer.Close()
+			return nil, err
+		}
+		freezer.tables[name] = table
+	}
+	if err := freezer.repair(); err != nil {
+		freezer.Close()
+		return nil, err
+	}
+	log.Info("Opened ancient database", "database", datadir, "frozen", freezer.frozen)
+	return freezer, nil
+}
+
+// repair truncates all data tables to the same length, dropping any partially
+// frozen block left behind by a crash.
+func (f *freezer) repair() error {
+	min := uint64(0)
+	for i, name := range freezerTables {
+		if items := f.tables[name].Items(); i == 0 || items < min {
+			min = items
+		}
+	}
+	for _, table := range f.tables {
+		if err := table.truncate(min); err != nil {
+			return err
+		}
+	}
+	atomic.StoreUint64(&f.frozen, min)
+	return nil
+}
+
+// Close terminates the chain freezer, unmapping all the data files.
+func (f *freezer) Close() error {
+	select {
+	case <-f.quit:
+	default:
+		close(f.quit)
+	}
+	f.wg.Wait()
+
+	var errs []error
+	for _, table := range f.tables {
+		if err := table.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if errs != nil {
+		return fmt.Errorf("%v", errs)
+	}
+	return nil
+}
+
+// HasAncient returns an indicator whether the specified ancient data exists
+// in the freezer.
+func (f *freezer) HasAncient(kind string, number uint64) (bool, error) {
+	if _, ok := f.tables[kind]; !ok {
+		return false, errUnknownTable
+	}
+	return number < atomic.LoadUint64(&f.frozen), nil
+}
+
+// Ancient retrieves an ancient binary blob from the append-only immutable files.
+func (f *freezer) Ancient(kind string, number uint64) ([]byte, error) {
+	if table := f.tables[kind]; table != nil {
+		return table.Retrieve(number)
+	}
+	return nil, errUnknownTable
+}
+
+// Ancients returns the number of blocks frozen into the append-only files.
+func (f *freezer) Ancients() uint64 {
+	return atomic.LoadUint64(&f.frozen)
+}
+
+// AppendAncient injects all binary blobs belong to block at the end of the
+// append-only immutable table files.
+//
+// Notably, this function is lock free but kind of thread-safe. All out-of-order
+// injection will be rejected. But if two injections with same number happen at
+// the same time, we can get into the trouble.
+func (f *freezer) AppendAncient(number uint64, hash, header, body, receipts, td []byte) (err error) {
+	// Rollback all inserted data if any insertion below failed to ensure
+	// the tables won't out of sync.
+	defer func() {
+		if err != nil {
+			rerr := f.repair()
+			if rerr != nil {
+				log.Crit("Failed to repair freezer", "err", rerr)
+			}
+			log.Info("Append ancient failed", "number", number, "err", err)
+		}
+	}()
+	blobs := map[string][]byte{
+		freezerHashTable:       hash,
+		freezerHeaderTable:     header,
+		freezerBodiesTable:     body,
+		freezerReceiptTable:    receipts,
+		freezerDifficultyTable: td,
+	}
+	for _, name := range freezerTables {
+		if err := f.tables[name].Append(number, blobs[name]); err != nil {
+			return err
+		}
+	}
+	atomic.AddUint64(&f.frozen, 1) // Only modify atomically
+	return nil
+}
+
+// Sync flushes all data tables to disk.
+func (f *freezer) Sync() error {
+	var errs []error
+	for _, table := range f.tables {
+		if err := table.Sync(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if errs != nil {
+		return fmt.Errorf("%v", errs)
+	}
+	return nil
+}
+
+// freeze is a background thread that periodically checks the blockchain for any
+// import progress and moves ancient data from the fast database into the freezer.
+//
+// This functionality is deliberately broken off from block importing to avoid
+// incurring additional data shuffling delays on block propagation.
+func (f *freezer) freeze(db vntdb.Database) {
+	defer f.wg.Done()
+
+	for {
+		if frozen, err := f.freezeBatch(db); err != nil {
+			log.Error("Failed to freeze ancient blocks", "err", err)
+		} else if frozen == freezerBatchLimit {
+			continue // more blocks waiting, don't sleep
+		}
+		select {
+		case <-time.After(freezerRecheckInterval):
+		case <-f.quit:
+			return
+		}
+	}
+}
+
+// freezeBatch moves the next batch of immutable blocks from the key-value store
+// into the freezer, returning the number of blocks frozen.
+func (f *freezer) freezeBatch(db vntdb.Database) (int, error) {
+	// Retrieve the freezing threshold
+	hash := ReadHeadBlockHash(db)
+	if hash == (common.Hash{}) {
+		return 0, nil
+	}
+	number := ReadHeaderNumber(db, hash)
+	if number == nil || *number < f.threshold {
+		return 0, nil
+	}
+	limit := *number - f.threshold
+	if limit-f.Ancients() > freezerBatchLimit {
+		limit = f.Ancients() + freezerBatchLimit
+	}
+	// Seems we have data ready to be frozen, process in usable batches
+	var (
+		start    = time.Now()
+		first    = f.Ancients()
+		ancients = make([]common.Hash, 0, limit-first)
+	)
+	for f.Ancients() < limit {
+		select {
+		case <-f.quit:
+			return len(ancients), nil
+		default:
+		}
+		// Retrieves all the components of the canonical block
+		number := f.Ancients()
+		hash := ReadCanonicalHash(db, number)
+		if hash == (common.Hash{}) {
+			return len(ancients), fmt.Errorf("canonical hash missing, can't freeze block %d", number)
+		}
+		header, err := db.Get(headerKey(number, hash))
+		if err != nil {
+			return len(ancients), fmt.Errorf("block header missing, can't freeze block %d", number)
+		}
+		body, err := db.Get(blockBodyKey(number, hash))
+		if err != nil {
+			return len(ancients), fmt.Errorf("block body missing, can't freeze block %d", number)
+		}
+		receipts, err := db.Get(blockReceiptsKey(number, hash))
+		if err != nil {
+			return len(ancients), fmt.Errorf("block receipts missing, can't freeze block %d", number)
+		}
+		td, err := db.Get(headerTDKey(number, hash))
+		if err != nil {
+			return len(ancients), fmt.Errorf("total difficulty missing, can't freeze block %d", number)
+		}
+		// Inject all the components into the relevant data tables
+		if err := f.AppendAncient(number, hash.Bytes(), header, body, receipts, td); err != nil {
+			return len(ancients), err
+		}
+		ancients = append(ancients, hash)
+	}
+	// Batch of blocks have been frozen, flush them before wiping from leveldb
+	if err := f.Sync(); err != nil {
+		log.Crit("Failed to flush frozen tables", "err", err)
+	}
+	// Wipe out all data from the active database, apart from the genesis block
+	// which is looked up all over the place
+	for i, hash := range ancients {
+		number := first + uint64(i)
+		if number == 0 {
+			continue
+		}
+		for _, key := range [][]byte{
+			headerHashKey(number),
+			headerKey(number, hash),
+			headerTDKey(number, hash),
+			blockBodyKey(number, hash),
+			blockReceiptsKey(number, hash),
+		} {
+			if err := db.Delete(key); err != nil {
+				log.Crit("Failed to delete frozen canonical blocks", "err", err)
+			}
+		}
+	}
+	// Log something friendly for the user
+	context := []interface{}{
+		"blocks", len(ancients), "elapsed", common.PrettyDuration(time.Since(start)), "number", f.Ancients() - 1,
+	}
+	if n := len(ancients); n > 0 {
+		context = append(context, []interface{}{"hash", ancients[n-1]}...)
+	}
+	log.Info("Deep froze chain segment", context...)
+	return len(ancients), nil
+}
@@ -0,0 +1,157 @@
+// Copyright 2019 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/core/types"
+	"github.com/vntchain/go-vnt/vntdb"
+)
+
+// Tests that a freezer table can be appended to, read back and repaired after
+// a partial write left the data and index files out of sync.
+func TestFreezerTable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	table, err := newTable(dir, "test")
+	if err != nil {
+		t.Fatalf("Failed to create table: %v", err)
+	}
+	for i := uint64(0); i < 10; i++ {
+		if err := table.Append(i, bytes.Repeat([]byte{byte(i)}, int(i)+1)); err != nil {
+			t.Fatalf("Failed to append item %d: %v", i, err)
+		}
+	}
+	if err := table.Append(11, []byte{0x11}); err == nil {
+		t.Fatalf("Out of order insertion succeeded")
+	}
+	if _, err := table.Retrieve(10); err != errOutOfBounds {
+		t.Fatalf("Out of bounds retrieval error mismatch: have %v, want %v", err, errOutOfBounds)
+	}
+	// Corrupt the tail of the data file and make sure reopening drops the item
+	datafile := table.data.Name()
+	table.Close()
+	if err := os.Truncate(datafile, 50); err != nil {
+		t.Fatalf("Failed to truncate data file: %v", err)
+	}
+	if table, err = newTable(dir, "test"); err != nil {
+		t.Fatalf("Failed to reopen table: %v", err)
+	}
+	defer table.Close()
+
+	if items := table.Items(); items != 9 {
+		t.Fatalf("Item count mismatch after repair: have %d, want %d", items, 9)
+	}
+	for i := uint64(0); i < 9; i++ {
+		blob, err := table.Retrieve(i)
+		if err != nil {
+			t.Fatalf("Failed to retrieve item %d: %v", i, err)
+		}
+		if want := bytes.Repeat([]byte{byte(i)}, int(i)+1); !bytes.Equal(blob, want) {
+			t.Fatalf("Item %d mismatch: have %x, want %x", i, blob, want)
+		}
+	}
+	// Truncate the table and check it accepts new items at the new head
+	if err := table.truncate(5); err != nil {
+		t.Fatalf("Failed to truncate table: %v", err)
+	}
+	if err := table.Append(5, []byte{0x55}); err != nil {
+		t.Fatalf("Failed to append after truncation: %v", err)
+	}
+	if blob, _ := table.Retrieve(5); !bytes.Equal(blob, []byte{0x55}) {
+		t.Fatalf("Item mismatch after truncation: have %x, want %x", blob, []byte{0x55})
+	}
+}
+
+// Tests that immutable blocks are moved into the ancient store and can still be
+// read through the database wrapper once removed from the key-value store.
+func TestFreezerDatabase(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ancient")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	frdb, err := newFreezer(dir)
+	if err != nil {
+		t.Fatalf("Failed to create freezer: %v", err)
+	}
+	frdb.threshold = 2
+
+	kvdb := vntdb.NewMemDatabase()
+	db := &FreezerDatabase{db: kvdb, freezer: frdb}
+	defer db.Close()
+
+	// Assemble a short canonical chain with receipts
+	var blocks []*types.Block
+	parent := common.Hash{}
+	for i := uint64(0); i < 6; i++ {
+		block := types.NewBlockWithHeader(&types.Header{
+			Number:     new(big.Int).SetUint64(i),
+			ParentHash: parent,
+			Extra:      []byte("test block"),
+		})
+		receipts := types.Receipts{&types.Receipt{CumulativeGasUsed: i, Logs: []*types.Log{}}}
+
+		WriteBlock(db, block)
+		WriteReceipts(db, block.Hash(), i, receipts)
+		WriteTd(db, block.Hash(), i, big.NewInt(int64(i+1)))
+		WriteCanonicalHash(db, block.Hash(), i)
+		WriteHeadBlockHash(db, block.Hash())
+
+		blocks, parent = append(blocks, block), block.Hash()
+	}
+	frozen, err := frdb.freezeBatch(kvdb)
+	if err != nil {
+		t.Fatalf("Failed to freeze blocks: %v", err)
+	}
+	if frozen != 3 || db.Ancients() != 3 {
+		t.Fatalf("Frozen block count mismatch: have %d/%d, want %d", frozen, db.Ancients(), 3)
+	}
+	for i, block := range blocks {
+		number := uint64(i)
+		if inKV := ReadCanonicalHash(kvdb, number) != (common.Hash{}); inKV != (number == 0 || number >= 3) {
+			t.Errorf("Block %d key-value presence mismatch: have %v", number, inKV)
+		}
+		if hash := ReadCanonicalHash(db, number); hash != block.Hash() {
+			t.Errorf("Block %d canonical hash mismatch: have %x, want %x", number, hash, block.Hash())
+		}
+		if entry := ReadBlock(db, block.Hash(), number); entry == nil || entry.Hash() != block.Hash() {
+			t.Errorf("Block %d not retrievable", number)
+		}
+		if receipts := ReadReceipts(db, block.Hash(), number); len(receipts) != 1 || receipts[0].CumulativeGasUsed != number {
+			t.Errorf("Block %d receipts not retrievable", number)
+		}
+		if td := ReadTd(db, block.Hash(), number); td == nil || td.Uint64() != number+1 {
+			t.Errorf("Block %d total difficulty mismatch: have %v, want %d", number, td, number+1)
+		}
+	}
+	// Non-canonical blocks at frozen heights must not be served from the freezer
+	if entry := ReadHeader(db, common.Hash{0x01}, 1); entry != nil {
+		t.Errorf("Non-canonical header returned from ancient store: %v", entry)
+	}
+}
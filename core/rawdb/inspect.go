@@ -0,0 +1,143 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/vntdb"
+)
+
+// errNotIterable is returned if the database cannot enumerate its content.
+var errNotIterable = errors.New("database does not support iteration")
+
+// DatabaseStat is the number and total size of the entries in one category of
+// the database key space.
+type DatabaseStat struct {
+	Category string
+	Count    uint64
+	Size     common.StorageSize
+}
+
+// Add accounts an entry of the given size to the category.
+func (s *DatabaseStat) Add(size int) {
+	s.Count++
+	s.Size += common.StorageSize(size)
+}
+
+// Categories of the database key space, in the order they are reported.
+const (
+	statHeaders = iota
+	statTDs
+	statCanonical
+	statNumbers
+	statBodies
+	statReceipts
+	statTxLookups
+	statBloomBits
+	statTries
+	statPreimages
+	statSnapshotAccounts
+	statSnapshotStorage
+	statChainIndexes
+	statMetadata
+	statUnaccounted
+	statCount
+)
+
+// metadataKeys are the singleton keys tracking the database and chain status.
+var metadataKeys = [][]byte{
+	databaseVerisionKey, headHeaderKey, headBlockKey, headFastBlockKey,
+	fastTrieProgressKey, snapshotRootKey, snapshotGeneratorKey, txIndexTailKey,
+}
+
+// InspectDatabase traverses the entire key-value store and aggregates the number
+// and size (key and value) of the entries in each known data category. Trie
+// nodes and contract codes are both keyed by their bare hash and are reported
+// together.
+func InspectDatabase(db vntdb.Database) ([]DatabaseStat, error) {
+	if frdb, ok := db.(*FreezerDatabase); ok {
+		db = frdb.KeyValueStore()
+	}
+	store, ok := db.(interface {
+		NewIteratorWithPrefix(prefix []byte) iterator.Iterator
+	})
+	if !ok {
+		return nil, errNotIterable
+	}
+	stats := make([]DatabaseStat, statCount)
+	for i, name := range []string{
+		"Headers", "Total difficulties", "Canonical hashes", "Header numbers",
+		"Bodies", "Receipts", "Transaction lookups", "Bloom bits",
+		"Trie nodes and codes", "Trie preimages", "Snapshot accounts",
+		"Snapshot storage", "Chain indexes", "Metadata", "Unaccounted",
+	} {
+		stats[i].Category = name
+	}
+	it := store.NewIteratorWithPrefix(nil)
+	defer it.Release()
+
+	for it.Next() {
+		key := it.Key()
+		stats[categorize(key)].Add(len(key) + len(it.Value()))
+	}
+	return stats, it.Error()
+}
+
+// categorize returns the category of a database key based on the schema.
+func categorize(key []byte) int {
+	hashlen := common.HashLength
+	switch {
+	case bytes.HasPrefix(key, headerPrefix) && len(key) == 1+8+hashlen:
+		return statHeaders
+	case bytes.HasPrefix(key, headerPrefix) && len(key) == 1+8+hashlen+len(headerTDSuffix) && bytes.HasSuffix(key, headerTDSuffix):
+		return statTDs
+	case bytes.HasPrefix(key, headerPrefix) && len(key) == 1+8+len(headerHashSuffix) && bytes.HasSuffix(key, headerHashSuffix):
+		return statCanonical
+	case bytes.HasPrefix(key, headerNumberPrefix) && len(key) == 1+hashlen:
+		return statNumbers
+	case bytes.HasPrefix(key, blockBodyPrefix) && len(key) == 1+8+hashlen:
+		return statBodies
+	case bytes.HasPrefix(key, blockReceiptsPrefix) && len(key) == 1+8+hashlen:
+		return statReceipts
+	case bytes.HasPrefix(key, txLookupPrefix) && len(key) == 1+hashlen:
+		return statTxLookups
+	case bytes.HasPrefix(key, bloomBitsPrefix) && len(key) == 1+2+8+hashlen:
+		return statBloomBits
+	case bytes.HasPrefix(key, preimagePrefix) && len(key) == len(preimagePrefix)+hashlen:
+		return statPreimages
+	case bytes.HasPrefix(key, SnapshotAccountPrefix) && len(key) == 1+hashlen:
+		return statSnapshotAccounts
+	case bytes.HasPrefix(key, SnapshotStoragePrefix) && len(key) == 1+2*hashlen:
+		return statSnapshotStorage
+	case len(key) == hashlen:
+		return statTries
+	case bytes.HasPrefix(key, []byte("i")):
+		return statChainIndexes
+	case bytes.HasPrefix(key, configPrefix):
+		return statMetadata
+	}
+	for _, meta := range metadataKeys {
+		if bytes.Equal(key, meta) {
+			return statMetadata
+		}
+	}
+	return statUnaccounted
+}
@@ -0,0 +1,65 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/core/types"
+	"github.com/vntchain/go-vnt/vntdb"
+)
+
+// Tests that the database inspection attributes every entry to its category.
+func TestInspectDatabase(t *testing.T) {
+	db := vntdb.NewMemDatabase()
+
+	header := &types.Header{Number: big.NewInt(1), Extra: []byte("inspect")}
+	WriteHeader(db, header)
+	WriteTd(db, header.Hash(), 1, big.NewInt(1))
+	WriteCanonicalHash(db, header.Hash(), 1)
+	WriteHeadBlockHash(db, header.Hash())
+	WritePreimages(db, 1, map[common.Hash][]byte{common.HexToHash("0x01"): {0x01}})
+	WriteAccountSnapshot(db, common.HexToHash("0x02"), []byte{0x02})
+	db.Put(common.HexToHash("0x03").Bytes(), []byte{0x03})
+	db.Put([]byte("unknown"), []byte{0x04})
+
+	stats, err := InspectDatabase(db)
+	if err != nil {
+		t.Fatalf("failed to inspect database: %v", err)
+	}
+	want := map[int]uint64{
+		statHeaders:          1,
+		statTDs:              1,
+		statCanonical:        1,
+		statNumbers:          1,
+		statPreimages:        1,
+		statSnapshotAccounts: 1,
+		statTries:            1,
+		statMetadata:         1,
+		statUnaccounted:      1,
+	}
+	for i, stat := range stats {
+		if stat.Count != want[i] {
+			t.Errorf("%s: count mismatch: have %d, want %d", stat.Category, stat.Count, want[i])
+		}
+		if (stat.Count == 0) != (stat.Size == 0) {
+			t.Errorf("%s: size %v inconsistent with count %d", stat.Category, stat.Size, stat.Count)
+		}
+	}
+}
@@ -83,6 +83,16 @@ func NewDatabase(db vntdb.Database) Database {
 	}
 }
 
+// NewDatabaseWithCache creates a backing store for state like NewDatabase, which
+// additionally keeps up to the given megabytes of clean trie nodes in memory.
+func NewDatabaseWithCache(db vntdb.Database, cache int) Database {
+	csc, _ := lru.New(codeSizeCacheSize)
+	return &cachingDB{
+		db:            trie.NewDatabaseWithCache(db, cache),
+		codeSizeCache: csc,
+	}
+}
+
 type cachingDB struct {
 	db            *trie.Database
 	mu            sync.Mutex
@@ -19,6 +19,8 @@ package state
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"math/big"
 
 	"github.com/vntchain/go-vnt/common"
 	"github.com/vntchain/go-vnt/rlp"
@@ -39,34 +41,81 @@ type Dump struct {
 	Accounts map[string]DumpAccount `json:"accounts"`
 }
 
-func (self *StateDB) RawDump() Dump {
-	dump := Dump{
-		Root:     fmt.Sprintf("%x", self.trie.Hash()),
-		Accounts: make(map[string]DumpAccount),
-	}
+// RLPDumpAccount is the RLP encoding of an account in a streamed state dump.
+type RLPDumpAccount struct {
+	Address  common.Address
+	Nonce    uint64
+	Balance  *big.Int
+	Root     common.Hash
+	CodeHash []byte
+	Code     []byte
+	Storage  []RLPDumpSlot
+}
+
+// RLPDumpSlot is a storage slot of an account in an RLP state dump.
+type RLPDumpSlot struct {
+	Key   []byte
+	Value []byte
+}
 
+// dumpAccounts iterates over all the accounts of the state, calling fn with the
+// address, the decoded account and the object to read code and storage from.
+// Iteration stops at the first error returned by fn.
+func (self *StateDB) dumpAccounts(fn func(addr common.Address, data Account, obj *stateObject) error) error {
 	it := trie.NewIterator(self.trie.NodeIterator(nil))
 	for it.Next() {
-		addr := self.trie.GetKey(it.Key)
 		var data Account
 		if err := rlp.DecodeBytes(it.Value, &data); err != nil {
-			panic(err)
+			return err
 		}
-
-		obj := newObject(nil, common.BytesToAddress(addr), data)
-		account := DumpAccount{
-			Balance:  data.Balance.String(),
-			Nonce:    data.Nonce,
-			Root:     common.Bytes2Hex(data.Root[:]),
-			CodeHash: common.Bytes2Hex(data.CodeHash),
-			Code:     common.Bytes2Hex(obj.Code(self.db)),
-			Storage:  make(map[string]string),
+		addr := common.BytesToAddress(self.trie.GetKey(it.Key))
+		if err := fn(addr, data, newObject(nil, addr, data)); err != nil {
+			return err
 		}
-		storageIt := trie.NewIterator(obj.getTrie(self.db).NodeIterator(nil))
-		for storageIt.Next() {
-			account.Storage[common.Bytes2Hex(self.trie.GetKey(storageIt.Key))] = common.Bytes2Hex(storageIt.Value)
+	}
+	return it.Err
+}
+
+// dumpStorage iterates over all the storage slots of an account.
+func (self *StateDB) dumpStorage(obj *stateObject, fn func(key, value []byte)) error {
+	it := trie.NewIterator(obj.getTrie(self.db).NodeIterator(nil))
+	for it.Next() {
+		fn(self.trie.GetKey(it.Key), it.Value)
+	}
+	return it.Err
+}
+
+// newDumpAccount converts an account into its JSON dump.
+func (self *StateDB) newDumpAccount(data Account, obj *stateObject) (DumpAccount, error) {
+	account := DumpAccount{
+		Balance:  data.Balance.String(),
+		Nonce:    data.Nonce,
+		Root:     common.Bytes2Hex(data.Root[:]),
+		CodeHash: common.Bytes2Hex(data.CodeHash),
+		Code:     common.Bytes2Hex(obj.Code(self.db)),
+		Storage:  make(map[string]string),
+	}
+	err := self.dumpStorage(obj, func(key, value []byte) {
+		account.Storage[common.Bytes2Hex(key)] = common.Bytes2Hex(value)
+	})
+	return account, err
+}
+
+func (self *StateDB) RawDump() Dump {
+	dump := Dump{
+		Root:     fmt.Sprintf("%x", self.trie.Hash()),
+		Accounts: make(map[string]DumpAccount),
+	}
+	err := self.dumpAccounts(func(addr common.Address, data Account, obj *stateObject) error {
+		account, err := self.newDumpAccount(data, obj)
+		if err != nil {
+			return err
 		}
-		dump.Accounts[common.Bytes2Hex(addr)] = account
+		dump.Accounts[common.Bytes2Hex(addr[:])] = account
+		return nil
+	})
+	if err != nil {
+		panic(err)
 	}
 	return dump
 }
@@ -79,3 +128,50 @@ func (self *StateDB) Dump() []byte {
 
 	return json
 }
+
+// IterativeDump streams the state to w as JSON, one object per line: the state
+// root first, then every account along with its address. Unlike Dump, only a
+// single account is held in memory at a time.
+func (self *StateDB) IterativeDump(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(struct {
+		Root string `json:"root"`
+	}{fmt.Sprintf("%x", self.trie.Hash())}); err != nil {
+		return err
+	}
+	return self.dumpAccounts(func(addr common.Address, data Account, obj *stateObject) error {
+		account, err := self.newDumpAccount(data, obj)
+		if err != nil {
+			return err
+		}
+		return enc.Encode(struct {
+			Address string `json:"address"`
+			DumpAccount
+		}{common.Bytes2Hex(addr[:]), account})
+	})
+}
+
+// RLPDump streams the state to w as a sequence of RLP items: the state root
+// first, then an RLPDumpAccount for every account.
+func (self *StateDB) RLPDump(w io.Writer) error {
+	if err := rlp.Encode(w, self.trie.Hash()); err != nil {
+		return err
+	}
+	return self.dumpAccounts(func(addr common.Address, data Account, obj *stateObject) error {
+		account := RLPDumpAccount{
+			Address:  addr,
+			Nonce:    data.Nonce,
+			Balance:  data.Balance,
+			Root:     data.Root,
+			CodeHash: data.CodeHash,
+			Code:     obj.Code(self.db),
+		}
+		err := self.dumpStorage(obj, func(key, value []byte) {
+			account.Storage = append(account.Storage, RLPDumpSlot{Key: key, Value: value})
+		})
+		if err != nil {
+			return err
+		}
+		return rlp.Encode(w, &account)
+	})
+}
@@ -0,0 +1,71 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"encoding/binary"
+
+	"github.com/vntchain/go-vnt/common"
+)
+
+// stateBloom is a bloom filter of the trie nodes and contract codes reachable
+// from the retained states. Since the keys are already cryptographic hashes,
+// the filter indexes are taken directly from the hash bytes.
+//
+// A false positive only means a dangling entry is kept, never that a live one
+// is deleted.
+type stateBloom struct {
+	bits []uint64
+}
+
+// newStateBloom creates a bloom filter occupying the given number of bytes.
+func newStateBloom(size uint64) *stateBloom {
+	words := size / 8
+	if words == 0 {
+		words = 1
+	}
+	return &stateBloom{bits: make([]uint64, words)}
+}
+
+// indexes returns the bit positions of a hash in the filter.
+func (b *stateBloom) indexes(hash common.Hash) [4]uint64 {
+	var (
+		idx  [4]uint64
+		size = uint64(len(b.bits)) * 64
+	)
+	for i := range idx {
+		idx[i] = binary.BigEndian.Uint64(hash[i*8:]) % size
+	}
+	return idx
+}
+
+// add inserts a hash into the filter.
+func (b *stateBloom) add(hash common.Hash) {
+	for _, idx := range b.indexes(hash) {
+		b.bits[idx/64] |= 1 << (idx % 64)
+	}
+}
+
+// contains returns whether the hash was (probably) inserted into the filter.
+func (b *stateBloom) contains(hash common.Hash) bool {
+	for _, idx := range b.indexes(hash) {
+		if b.bits[idx/64]&(1<<(idx%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,248 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package pruner implements the offline removal of state trie data that is no
+// longer reachable from the recent chain.
+package pruner
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/core/rawdb"
+	"github.com/vntchain/go-vnt/core/state"
+	"github.com/vntchain/go-vnt/log"
+	"github.com/vntchain/go-vnt/vntdb"
+)
+
+// recentStates is the number of recent blocks whose persisted states are
+// retained, matching the in-memory trie retention of a running node.
+const recentStates = 128
+
+var (
+	// errNotIterable is returned if the database cannot enumerate its content.
+	errNotIterable = errors.New("database does not support iteration")
+
+	// errNoHead is returned if the database has no head block to prune to.
+	errNoHead = errors.New("head block missing")
+
+	// errNoRecentState is returned if none of the recent blocks has its state
+	// persisted.
+	errNoRecentState = errors.New("no recent state available")
+)
+
+// Pruner is an offline tool to delete the trie nodes and contract codes that
+// are not reachable from the retained states. It must not be used while a node
+// is running on the same database.
+//
+// The retained states are the genesis, every persisted state of the recent
+// canonical blocks, and optionally an explicitly requested state. Liveness is
+// tracked with a bloom filter, so the pruning is conservative: a few dangling
+// entries may survive, but no reachable one is removed.
+type Pruner struct {
+	db    vntdb.Database
+	bloom *stateBloom
+	lock  sync.Mutex // Serialises the marking of live entries with their sweeping
+}
+
+// NewPruner creates a pruner for the given chain database, allocating a bloom
+// filter of the given size in bytes to track the live entries.
+func NewPruner(db vntdb.Database, bloomSize uint64) (*Pruner, error) {
+	if frdb, ok := db.(*rawdb.FreezerDatabase); ok {
+		db = frdb.KeyValueStore()
+	}
+	if _, ok := db.(iteratee); !ok {
+		return nil, errNotIterable
+	}
+	return &Pruner{
+		db:    db,
+		bloom: newStateBloom(bloomSize),
+	}, nil
+}
+
+// iteratee wraps the prefix iteration supported by the leveldb and in-memory
+// databases.
+type iteratee interface {
+	NewIteratorWithPrefix(prefix []byte) iterator.Iterator
+}
+
+// Prune marks all the entries reachable from the retained states and deletes
+// every other trie node and contract code. A non-empty root is retained in
+// addition to the recent states and must be present in the database.
+func (p *Pruner) Prune(root common.Hash) error {
+	roots, err := p.retainedRoots(root)
+	if err != nil {
+		return err
+	}
+	_, err = p.PruneRoots(state.NewDatabase(p.db), roots)
+	return err
+}
+
+// MarkLive marks an entry persisted while pruning as live, so that it is not
+// deleted. It must be called before the entry is written.
+func (p *Pruner) MarkLive(hash common.Hash) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.bloom.add(hash)
+}
+
+// PruneRoots deletes every trie node and contract code not reachable from the
+// given state roots, returning the amount of data deleted.
+//
+// The states are resolved through the given state database, so a running chain
+// can pass its caching database to have the trie nodes not yet flushed to disk
+// followed into the persisted ones they reference. A running chain must pass
+// every entry it persists until PruneRoots returns to MarkLive, and the states
+// it drops from memory meanwhile are skipped.
+func (p *Pruner) PruneRoots(cache state.Database, roots []common.Hash) (common.StorageSize, error) {
+	// Mark everything reachable from the retained states as live
+	start := time.Now()
+	for _, root := range roots {
+		if err := p.mark(cache, root); err != nil {
+			// A state dropped from memory meanwhile is no longer retained
+			if _, nerr := cache.TrieDB().Node(root); nerr == nil {
+				return 0, err
+			}
+			log.Debug("Skipping state released while pruning", "root", root)
+		}
+	}
+	log.Info("Marked live state entries", "roots", len(roots), "elapsed", common.PrettyDuration(time.Since(start)))
+
+	// Sweep all the hash keyed entries not marked live
+	var (
+		pruned, kept int
+		size         common.StorageSize
+		logged       = time.Now()
+	)
+	start = time.Now()
+
+	it := p.db.(iteratee).NewIteratorWithPrefix(nil)
+	defer it.Release()
+
+	for it.Next() {
+		key := it.Key()
+		if len(key) != common.HashLength {
+			continue
+		}
+		live, err := p.sweep(key)
+		if err != nil {
+			return size, err
+		}
+		if live {
+			kept++
+			continue
+		}
+		size += common.StorageSize(len(key) + len(it.Value()))
+		pruned++
+
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Pruning state data", "pruned", pruned, "size", size, "kept", kept, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return size, err
+	}
+	log.Info("Pruned state data", "pruned", pruned, "size", size, "kept", kept, "elapsed", common.PrettyDuration(time.Since(start)))
+	return size, nil
+}
+
+// sweep deletes an entry unless it is marked live, returning whether it was.
+func (p *Pruner) sweep(key []byte) (bool, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.bloom.contains(common.BytesToHash(key)) {
+		return true, nil
+	}
+	return false, p.db.Delete(common.CopyBytes(key))
+}
+
+// retainedRoots collects the state roots to keep: the genesis, the persisted
+// states of the recent canonical blocks and the explicitly requested one.
+func (p *Pruner) retainedRoots(root common.Hash) ([]common.Hash, error) {
+	head := rawdb.ReadHeadBlockHash(p.db)
+	if head == (common.Hash{}) {
+		return nil, errNoHead
+	}
+	number := rawdb.ReadHeaderNumber(p.db, head)
+	if number == nil {
+		return nil, errNoHead
+	}
+	var (
+		roots []common.Hash
+		seen  = make(map[common.Hash]bool)
+	)
+	retain := func(root common.Hash) bool {
+		if !p.exists(root) {
+			return false
+		}
+		if !seen[root] {
+			seen[root] = true
+			roots = append(roots, root)
+		}
+		return true
+	}
+	if root != (common.Hash{}) && !retain(root) {
+		return nil, fmt.Errorf("state %x missing", root)
+	}
+	// Without any recent state the node could not process further blocks, refuse
+	// to prune rather than leave it to resync.
+	recent := false
+	for i := uint64(0); i < recentStates && i <= *number; i++ {
+		hash := rawdb.ReadCanonicalHash(p.db, *number-i)
+		if header := rawdb.ReadHeader(p.db, hash, *number-i); header != nil && retain(header.Root) {
+			recent = true
+		}
+	}
+	if !recent {
+		return nil, errNoRecentState
+	}
+	if genesis := rawdb.ReadHeader(p.db, rawdb.ReadCanonicalHash(p.db, 0), 0); genesis != nil {
+		retain(genesis.Root)
+	}
+	return roots, nil
+}
+
+// exists returns whether the root node of a state is present in the database.
+func (p *Pruner) exists(root common.Hash) bool {
+	ok, _ := p.db.Has(root[:])
+	return ok
+}
+
+// mark adds every trie node and contract code reachable from a state root to
+// the bloom filter.
+func (p *Pruner) mark(cache state.Database, root common.Hash) error {
+	statedb, err := state.New(root, cache)
+	if err != nil {
+		return err
+	}
+	it := state.NewNodeIterator(statedb)
+	for it.Next() {
+		if it.Hash != (common.Hash{}) {
+			p.MarkLive(it.Hash)
+		}
+	}
+	if it.Error != nil {
+		return fmt.Errorf("state %x incomplete: %v", root, it.Error)
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package pruner
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/core/rawdb"
+	"github.com/vntchain/go-vnt/core/state"
+	"github.com/vntchain/go-vnt/core/types"
+	"github.com/vntchain/go-vnt/vntdb"
+)
+
+// commitState applies the given modification on top of a parent state and
+// flushes the result into the database.
+func commitState(t *testing.T, sdb state.Database, parent common.Hash, modify func(*state.StateDB)) common.Hash {
+	statedb, err := state.New(parent, sdb)
+	if err != nil {
+		t.Fatalf("failed to open state %x: %v", parent, err)
+	}
+	modify(statedb)
+	root, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	if err := sdb.TrieDB().Commit(root, false); err != nil {
+		t.Fatalf("failed to flush state: %v", err)
+	}
+	return root
+}
+
+// writeCanonical stores a canonical header with the given state root.
+func writeCanonical(db vntdb.Database, number uint64, root common.Hash) {
+	header := &types.Header{Number: new(big.Int).SetUint64(number), Root: root}
+	rawdb.WriteHeader(db, header)
+	rawdb.WriteCanonicalHash(db, header.Hash(), number)
+	rawdb.WriteHeadBlockHash(db, header.Hash())
+}
+
+// Tests that pruning removes the entries only reachable from stale states,
+// while the genesis and head states stay complete.
+func TestPrune(t *testing.T) {
+	var (
+		db   = vntdb.NewMemDatabase()
+		sdb  = state.NewDatabase(db)
+		acc1 = common.BytesToAddress([]byte{0x01})
+		acc2 = common.BytesToAddress([]byte{0x02})
+	)
+	genesis := commitState(t, sdb, common.Hash{}, func(s *state.StateDB) {
+		s.SetBalance(acc1, big.NewInt(1))
+		s.SetState(acc1, common.HexToHash("0x01"), common.HexToHash("0x01"))
+	})
+	stale := commitState(t, sdb, genesis, func(s *state.StateDB) {
+		s.SetBalance(acc1, big.NewInt(2))
+		s.SetCode(acc2, []byte{0xde, 0xad})
+		s.SetState(acc2, common.HexToHash("0x02"), common.HexToHash("0x02"))
+	})
+	head := commitState(t, sdb, genesis, func(s *state.StateDB) {
+		s.SetBalance(acc1, big.NewInt(3))
+		s.SetCode(acc2, []byte{0xbe, 0xef})
+	})
+	writeCanonical(db, 0, genesis)
+	writeCanonical(db, 1, head)
+
+	pruner, err := NewPruner(db, 1024*1024)
+	if err != nil {
+		t.Fatalf("failed to create pruner: %v", err)
+	}
+	if err := pruner.Prune(common.Hash{}); err != nil {
+		t.Fatalf("failed to prune: %v", err)
+	}
+	// The retained states must be fully iterable
+	for _, root := range []common.Hash{genesis, head} {
+		statedb, err := state.New(root, state.NewDatabase(db))
+		if err != nil {
+			t.Fatalf("retained state %x missing: %v", root, err)
+		}
+		it := state.NewNodeIterator(statedb)
+		for it.Next() {
+		}
+		if it.Error != nil {
+			t.Errorf("retained state %x incomplete: %v", root, it.Error)
+		}
+	}
+	// The stale state must be gone
+	if ok, _ := db.Has(stale[:]); ok {
+		t.Errorf("stale state root not pruned")
+	}
+	// Retaining a missing state must be refused
+	if err := pruner.Prune(stale); err == nil {
+		t.Errorf("pruning with missing retained state succeeded")
+	}
+}
+
+// Tests that pruning is refused if no recent state is available.
+func TestPruneNoRecentState(t *testing.T) {
+	db := vntdb.NewMemDatabase()
+	writeCanonical(db, 0, common.HexToHash("0x01"))
+
+	pruner, _ := NewPruner(db, 1024)
+	if err := pruner.Prune(common.Hash{}); err != errNoRecentState {
+		t.Errorf("error mismatch: have %v, want %v", err, errNoRecentState)
+	}
+}
+
+// Tests that the entries marked live while pruning a running chain are kept,
+// and that the retained states released meanwhile are skipped.
+func TestPruneRootsLive(t *testing.T) {
+	var (
+		db   = vntdb.NewMemDatabase()
+		sdb  = state.NewDatabase(db)
+		acc1 = common.BytesToAddress([]byte{0x01})
+		acc2 = common.BytesToAddress([]byte{0x02})
+	)
+	head := commitState(t, sdb, common.Hash{}, func(s *state.StateDB) {
+		s.SetBalance(acc1, big.NewInt(1))
+	})
+	stale := commitState(t, sdb, head, func(s *state.StateDB) {
+		s.SetBalance(acc1, big.NewInt(2))
+	})
+	pruner, err := NewPruner(db, 1024*1024)
+	if err != nil {
+		t.Fatalf("failed to create pruner: %v", err)
+	}
+	// Persist a new state as if it was imported while pruning
+	sdb.TrieDB().SetFlushHook(pruner.MarkLive)
+	imported := commitState(t, sdb, head, func(s *state.StateDB) {
+		s.SetCode(acc2, []byte{0xde, 0xad})
+		s.SetState(acc2, common.HexToHash("0x02"), common.HexToHash("0x02"))
+	})
+	sdb.TrieDB().SetFlushHook(nil)
+
+	released := common.HexToHash("0xdeadbeef")
+	if _, err := pruner.PruneRoots(sdb, []common.Hash{head, released}); err != nil {
+		t.Fatalf("failed to prune: %v", err)
+	}
+	for _, root := range []common.Hash{head, imported} {
+		statedb, err := state.New(root, state.NewDatabase(db))
+		if err != nil {
+			t.Fatalf("live state %x missing: %v", root, err)
+		}
+		it := state.NewNodeIterator(statedb)
+		for it.Next() {
+		}
+		if it.Error != nil {
+			t.Errorf("live state %x incomplete: %v", root, it.Error)
+		}
+	}
+	if ok, _ := db.Has(stale[:]); ok {
+		t.Errorf("stale state root not pruned")
+	}
+}
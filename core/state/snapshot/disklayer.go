@@ -0,0 +1,243 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/hashicorp/golang-lru"
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/core/rawdb"
+	"github.com/vntchain/go-vnt/log"
+	"github.com/vntchain/go-vnt/trie"
+	"github.com/vntchain/go-vnt/vntdb"
+)
+
+// cacheItemSize is the rough memory footprint of a cached snapshot entry, used
+// to turn the megabyte allowance into an item count.
+const cacheItemSize = 256
+
+// diskLayer is a low level persistent snapshot built on top of a key-value store.
+type diskLayer struct {
+	diskdb vntdb.Database // Key-value store containing the base snapshot
+	triedb *trie.Database // Trie node cache for reconstruction purposes
+	cache  *lru.Cache     // Cache to avoid hitting the disk for direct access
+
+	root  common.Hash // Root hash of the base snapshot
+	stale bool        // Signals that the layer became stale (state progressed)
+
+	genMarker []byte           // Last account fully generated, nil if generation is done
+	genAbort  chan chan []byte // Notification channel to abort generating the snapshot in this layer
+
+	lock sync.RWMutex
+}
+
+// newDiskLayer creates an empty read cache for a disk layer of the given size.
+func newDiskLayer(diskdb vntdb.Database, triedb *trie.Database, cache int, root common.Hash) *diskLayer {
+	items := cache * 1024 * 1024 / cacheItemSize
+	if items < 1 {
+		items = 1
+	}
+	lcache, _ := lru.New(items)
+	return &diskLayer{
+		diskdb: diskdb,
+		triedb: triedb,
+		cache:  lcache,
+		root:   root,
+	}
+}
+
+// Root returns root hash for which this snapshot was made.
+func (dl *diskLayer) Root() common.Hash {
+	return dl.root
+}
+
+// Parent always returns nil as there's no layer below the disk.
+func (dl *diskLayer) Parent() snapshot {
+	return nil
+}
+
+// Stale return whether this layer has become stale (was flattened across) or if
+// it's still live.
+func (dl *diskLayer) Stale() bool {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	return dl.stale
+}
+
+// covered returns whether the generator already processed the given account.
+// The caller must hold the read lock.
+func (dl *diskLayer) covered(hash common.Hash) bool {
+	return dl.genMarker == nil || bytes.Compare(hash[:], dl.genMarker) <= 0
+}
+
+// AccountRLP directly retrieves the account RLP associated with a particular
+// hash in the snapshot slim data format.
+func (dl *diskLayer) AccountRLP(hash common.Hash) ([]byte, error) {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	// If the layer was flattened into, consider it invalid (any live reference to
+	// the original should be marked as unusable).
+	if dl.stale {
+		return nil, ErrSnapshotStale
+	}
+	// If the layer is being generated, ensure the requested hash has already been
+	// covered by the generator.
+	if !dl.covered(hash) {
+		return nil, ErrNotCoveredYet
+	}
+	if blob, ok := dl.cache.Get(string(hash[:])); ok {
+		return blob.([]byte), nil
+	}
+	blob := rawdb.ReadAccountSnapshot(dl.diskdb, hash)
+	dl.cache.Add(string(hash[:]), blob)
+	return blob, nil
+}
+
+// Storage directly retrieves the storage data associated with a particular hash,
+// within a particular account.
+func (dl *diskLayer) Storage(accountHash, storageHash common.Hash) ([]byte, error) {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	if dl.stale {
+		return nil, ErrSnapshotStale
+	}
+	if !dl.covered(accountHash) {
+		return nil, ErrNotCoveredYet
+	}
+	key := string(append(accountHash[:], storageHash[:]...))
+	if blob, ok := dl.cache.Get(key); ok {
+		return blob.([]byte), nil
+	}
+	blob := rawdb.ReadStorageSnapshot(dl.diskdb, accountHash, storageHash)
+	dl.cache.Add(key, blob)
+	return blob, nil
+}
+
+// stopGeneration aborts the background generator of the layer if it's still
+// alive, returning the last account it fully processed (nil if it completed).
+func (dl *diskLayer) stopGeneration() []byte {
+	dl.lock.Lock()
+	abort := dl.genAbort
+	dl.genAbort = nil
+	dl.lock.Unlock()
+
+	if abort != nil {
+		done := make(chan []byte)
+		abort <- done
+		return <-done
+	}
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	return dl.genMarker
+}
+
+// diffToDisk merges a bottom-most diff into the persistent disk layer underneath
+// it. The disk layer is marked stale and a new one returned in its place. If the
+// old layer was still being generated, generation resumes on the new layer from
+// the same position, since entries past it were never written.
+func diffToDisk(bottom *diffLayer, base *diskLayer) *diskLayer {
+	marker := base.stopGeneration()
+
+	// Invalidate the old layer and the persisted root before touching any entry,
+	// a crash midway through will trigger a regeneration on the next start.
+	base.lock.Lock()
+	base.stale = true
+	base.lock.Unlock()
+
+	rawdb.DeleteSnapshotRoot(base.diskdb)
+
+	covered := func(hash common.Hash) bool {
+		return marker == nil || bytes.Compare(hash[:], marker) <= 0
+	}
+	batch := base.diskdb.NewBatch()
+
+	// Destroy all the destructed accounts and their storage
+	for hash := range bottom.destructSet {
+		if !covered(hash) {
+			continue
+		}
+		rawdb.DeleteAccountSnapshot(base.diskdb, hash)
+		base.cache.Remove(string(hash[:]))
+
+		wipeStorage(base.diskdb, hash, base.cache)
+	}
+	// Push all updated accounts into the database
+	for hash, data := range bottom.accountData {
+		if !covered(hash) {
+			continue
+		}
+		rawdb.WriteAccountSnapshot(batch, hash, data)
+		base.cache.Add(string(hash[:]), data)
+	}
+	// Push all the storage slots into the database
+	for accountHash, storage := range bottom.storageData {
+		if !covered(accountHash) {
+			continue
+		}
+		for storageHash, data := range storage {
+			if len(data) > 0 {
+				rawdb.WriteStorageSnapshot(batch, accountHash, storageHash, data)
+			} else {
+				rawdb.DeleteStorageSnapshot(base.diskdb, accountHash, storageHash)
+			}
+			base.cache.Add(string(append(accountHash[:], storageHash[:]...)), data)
+		}
+	}
+	rawdb.WriteSnapshotRoot(batch, bottom.root)
+	journalProgress(batch, marker)
+
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to write flattened snapshot", "err", err)
+	}
+	res := &diskLayer{
+		diskdb:    base.diskdb,
+		triedb:    base.triedb,
+		cache:     base.cache,
+		root:      bottom.root,
+		genMarker: marker,
+	}
+	if marker != nil {
+		res.genAbort = make(chan chan []byte)
+		go res.generate(res.genAbort)
+	}
+	return res
+}
+
+// wipeStorage deletes all the storage snapshot entries of an account.
+func wipeStorage(db vntdb.Database, accountHash common.Hash, cache *lru.Cache) {
+	prefix := rawdb.StorageSnapshotsKey(accountHash)
+
+	it := iterableStore(db).NewIteratorWithPrefix(prefix)
+	defer it.Release()
+
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(prefix)+common.HashLength {
+			continue
+		}
+		rawdb.DeleteStorageSnapshot(db, accountHash, common.BytesToHash(key[len(prefix):]))
+		if cache != nil {
+			cache.Remove(string(key[1:]))
+		}
+	}
+}
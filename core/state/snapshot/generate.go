@@ -0,0 +1,256 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/core/rawdb"
+	"github.com/vntchain/go-vnt/log"
+	"github.com/vntchain/go-vnt/rlp"
+	"github.com/vntchain/go-vnt/trie"
+	"github.com/vntchain/go-vnt/vntdb"
+)
+
+// emptyRoot is the known root hash of an empty trie.
+var emptyRoot = common.HexToHash("56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+
+// account mirrors the consensus representation of accounts stored in the
+// account trie, only needed to find the storage trie of each account.
+type account struct {
+	Nonce    uint64
+	Balance  *big.Int
+	Root     common.Hash
+	CodeHash []byte
+}
+
+// generatorProgress is the persisted state of the snapshot generator.
+type generatorProgress struct {
+	Done   bool   // Whether the generator finished creating the snapshot
+	Marker []byte // Last account fully generated
+}
+
+// journalProgress persists the generator progress into the database. A nil
+// marker means the generation completed.
+func journalProgress(db vntdb.Putter, marker []byte) {
+	blob, err := rlp.EncodeToBytes(generatorProgress{Done: marker == nil, Marker: marker})
+	if err != nil {
+		panic(err) // Cannot happen, here to catch dev errors
+	}
+	rawdb.WriteSnapshotGenerator(db, blob)
+}
+
+// loadSnapshot loads the persisted disk layer if it matches the expected root,
+// resuming its generation if it was interrupted.
+func loadSnapshot(diskdb vntdb.Database, triedb *trie.Database, cache int, root common.Hash) (*diskLayer, error) {
+	if base := rawdb.ReadSnapshotRoot(diskdb); base != root {
+		return nil, fmt.Errorf("head doesn't match snapshot: have %#x, want %#x", base, root)
+	}
+	blob := rawdb.ReadSnapshotGenerator(diskdb)
+	if len(blob) == 0 {
+		return nil, errors.New("missing snapshot generator progress")
+	}
+	var progress generatorProgress
+	if err := rlp.DecodeBytes(blob, &progress); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot generator: %v", err)
+	}
+	base := newDiskLayer(diskdb, triedb, cache, root)
+	if !progress.Done {
+		base.genMarker = append([]byte{}, progress.Marker...)
+		base.genAbort = make(chan chan []byte)
+		go base.generate(base.genAbort)
+
+		log.Info("Resuming state snapshot generation", "root", root, "at", common.BytesToHash(base.genMarker))
+	}
+	return base, nil
+}
+
+// generateSnapshot wipes any previously existing snapshot from the database and
+// starts generating the flat state of the given root in a background thread.
+func generateSnapshot(diskdb vntdb.Database, triedb *trie.Database, cache int, root common.Hash) *diskLayer {
+	rawdb.DeleteSnapshotRoot(diskdb)
+	wipeSnapshot(diskdb)
+
+	batch := diskdb.NewBatch()
+	rawdb.WriteSnapshotRoot(batch, root)
+	journalProgress(batch, []byte{})
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to write initialized state marker", "err", err)
+	}
+	base := newDiskLayer(diskdb, triedb, cache, root)
+	base.genMarker = []byte{}
+	base.genAbort = make(chan chan []byte)
+	go base.generate(base.genAbort)
+
+	log.Info("Started state snapshot generation", "root", root)
+	return base
+}
+
+// wipeSnapshot deletes all the account and storage snapshot entries.
+func wipeSnapshot(db vntdb.Database) {
+	for _, prefix := range [][]byte{rawdb.SnapshotAccountPrefix, rawdb.SnapshotStoragePrefix} {
+		// Trie nodes are keyed by their bare hash and may share the prefix byte,
+		// tell snapshot entries apart by their key length.
+		keylen := len(prefix) + common.HashLength
+		if prefix[0] == rawdb.SnapshotStoragePrefix[0] {
+			keylen += common.HashLength
+		}
+		it := iterableStore(db).NewIteratorWithPrefix(prefix)
+		for it.Next() {
+			if key := it.Key(); len(key) == keylen {
+				db.Delete(common.CopyBytes(key))
+			}
+		}
+		it.Release()
+	}
+}
+
+// nextMarker returns the first account hash after the given generator marker,
+// or nil if the marker is the last possible hash.
+func nextMarker(marker []byte) []byte {
+	if len(marker) == 0 {
+		return nil
+	}
+	next := common.BytesToHash(marker)
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			return next[:]
+		}
+	}
+	return nil
+}
+
+// generate is a background thread that iterates over the state and storage tries
+// of the layer's root and writes the flat entries into the database. Progress is
+// only advanced at account boundaries, so no entry beyond the marker is ever
+// persisted. The abort channel is passed in as the layer's own field is cleared
+// once the generator is stopped.
+func (dl *diskLayer) generate(genAbort chan chan []byte) {
+	var (
+		accounts, slots int
+		start           = time.Now()
+		logged          = time.Now()
+		marker          = dl.genMarker
+		batch           = dl.diskdb.NewBatch()
+	)
+	// fail stops the generation without persisting the pending batch, leaving the
+	// marker at the last committed account until the layer is replaced.
+	fail := func(err error) {
+		log.Error("State snapshot generation stalled", "root", dl.root, "at", common.BytesToHash(marker), "err", err)
+		abort := <-genAbort
+		abort <- marker
+	}
+	accTrie, err := trie.New(dl.root, dl.triedb)
+	if err != nil {
+		fail(err)
+		return
+	}
+	// The account after the marker is the next to generate; a full marker means
+	// there is nothing left, iterating from nil would restart from scratch.
+	origin := nextMarker(marker)
+	if len(marker) > 0 && origin == nil {
+		accTrie = nil
+	}
+	if accTrie != nil {
+		it := trie.NewIterator(accTrie.NodeIterator(origin))
+		for it.Next() {
+			accountHash := common.BytesToHash(it.Key)
+			rawdb.WriteAccountSnapshot(batch, accountHash, it.Value)
+
+			var acc account
+			if err := rlp.DecodeBytes(it.Value, &acc); err != nil {
+				fail(err)
+				return
+			}
+			if acc.Root != emptyRoot {
+				storeTrie, err := trie.New(acc.Root, dl.triedb)
+				if err != nil {
+					fail(err)
+					return
+				}
+				sit := trie.NewIterator(storeTrie.NodeIterator(nil))
+				for sit.Next() {
+					rawdb.WriteStorageSnapshot(batch, accountHash, common.BytesToHash(sit.Key), sit.Value)
+					slots++
+				}
+				if sit.Err != nil {
+					fail(sit.Err)
+					return
+				}
+			}
+			accounts++
+
+			// The account is complete, check in with the layer and flush if needed
+			select {
+			case abort := <-genAbort:
+				dl.commitGeneration(batch, accountHash[:])
+				abort <- dl.genMarkerCopy()
+				return
+			default:
+			}
+			if batch.ValueSize() > vntdb.IdealBatchSize {
+				marker = dl.commitGeneration(batch, accountHash[:])
+			}
+			if time.Since(logged) > 8*time.Second {
+				log.Info("Generating state snapshot", "at", accountHash, "accounts", accounts, "slots", slots, "elapsed", common.PrettyDuration(time.Since(start)))
+				logged = time.Now()
+			}
+		}
+		if it.Err != nil {
+			fail(it.Err)
+			return
+		}
+	}
+	// Snapshot fully generated, persist the completion and wait for shutdown
+	dl.commitGeneration(batch, nil)
+	log.Info("Generated state snapshot", "accounts", accounts, "slots", slots, "elapsed", common.PrettyDuration(time.Since(start)))
+
+	abort := <-genAbort
+	abort <- nil
+}
+
+// commitGeneration flushes the pending generator writes together with the new
+// progress marker and exposes the newly covered range to readers.
+func (dl *diskLayer) commitGeneration(batch vntdb.Batch, marker []byte) []byte {
+	if marker != nil {
+		marker = common.CopyBytes(marker)
+	}
+	journalProgress(batch, marker)
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to write snapshot generation batch", "err", err)
+	}
+	batch.Reset()
+
+	dl.lock.Lock()
+	dl.genMarker = marker
+	dl.lock.Unlock()
+
+	return marker
+}
+
+// genMarkerCopy returns the current generator marker.
+func (dl *diskLayer) genMarkerCopy() []byte {
+	dl.lock.RLock()
+	defer dl.lock.RUnlock()
+
+	return dl.genMarker
+}
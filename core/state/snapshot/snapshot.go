@@ -0,0 +1,264 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package snapshot implements a layered, dynamic dump of the flat state.
+//
+// The persistent disk layer holds the account and storage trie leaves of a
+// single state root keyed by their hashes, so that reads don't need to walk
+// the trie. On top of it, each imported block adds an in-memory diff layer
+// recording the accounts and slots it changed. Once the diff stack grows
+// deeper than the requested limit, the bottom layers are flattened into the
+// disk layer.
+package snapshot
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/syndtr/goleveldb/leveldb/iterator"
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/log"
+	"github.com/vntchain/go-vnt/trie"
+	"github.com/vntchain/go-vnt/vntdb"
+)
+
+var (
+	// ErrSnapshotStale is returned from data accessors if the underlying snapshot
+	// layer had been invalidated due to the chain progressing forward far enough
+	// to not maintain the layer's original state.
+	ErrSnapshotStale = errors.New("snapshot stale")
+
+	// ErrNotCoveredYet is returned from data accessors if the underlying snapshot
+	// is being generated currently and the requested data item is not yet in the
+	// range of accounts covered.
+	ErrNotCoveredYet = errors.New("not covered yet")
+
+	// errSnapshotCycle is returned if a snapshot is attempted to be inserted
+	// that forms a cycle in the snapshot tree.
+	errSnapshotCycle = errors.New("snapshot cycle")
+
+	// errNotIterable is returned if the backing database cannot enumerate keys,
+	// which is needed to wipe and generate the flat state.
+	errNotIterable = errors.New("database does not support prefix iteration")
+)
+
+// Snapshot represents the functionality supported by a snapshot storage layer.
+// Callers should fall back to the state trie whenever an error is returned.
+type Snapshot interface {
+	// Root returns the root hash for which this snapshot was made.
+	Root() common.Hash
+
+	// AccountRLP directly retrieves the RLP encoded account associated with a
+	// particular hash in the snapshot slim data format. A nil slice and nil error
+	// means the account does not exist.
+	AccountRLP(hash common.Hash) ([]byte, error)
+
+	// Storage directly retrieves the storage data associated with a particular
+	// hash, within a particular account. A nil slice and nil error means the
+	// slot is empty.
+	Storage(accountHash, storageHash common.Hash) ([]byte, error)
+}
+
+// snapshot is the internal version of the snapshot data layer that supports
+// the linking of layers together.
+type snapshot interface {
+	Snapshot
+
+	// Parent returns the subsequent layer of a snapshot, or nil if the base was
+	// reached.
+	Parent() snapshot
+
+	// Stale returns whether this layer has become stale (was flattened across)
+	// or if it's still live.
+	Stale() bool
+}
+
+// Tree is a collection of all known layers, organized by the state root each
+// of them represents. The tree has exactly one persistent disk layer at its
+// base, with any number of in-memory diff layers on top, forming possibly
+// several branches for side chains.
+type Tree struct {
+	diskdb vntdb.Database // Persistent database to store the snapshot
+	triedb *trie.Database // In-memory cache to access the trie through
+	cache  int            // Megabytes permitted to use for read caches
+
+	layers map[common.Hash]snapshot // Collection of all known layers
+	lock   sync.RWMutex
+}
+
+// New attempts to load an already existing snapshot from a persistent key-value
+// store, ensuring that the root of the snapshot matches the expected one.
+//
+// If the snapshot is missing or inconsistent, it is wiped and a background
+// generator is started to rebuild it from the trie of the given root.
+func New(diskdb vntdb.Database, triedb *trie.Database, cache int, root common.Hash) (*Tree, error) {
+	if iterableStore(diskdb) == nil {
+		return nil, errNotIterable
+	}
+	snap := &Tree{
+		diskdb: diskdb,
+		triedb: triedb,
+		cache:  cache,
+		layers: make(map[common.Hash]snapshot),
+	}
+	base, err := loadSnapshot(diskdb, triedb, cache, root)
+	if err != nil {
+		log.Warn("Failed to load snapshot, regenerating", "err", err)
+		base = generateSnapshot(diskdb, triedb, cache, root)
+	}
+	snap.layers[base.root] = base
+	return snap, nil
+}
+
+// Snapshot retrieves a snapshot belonging to the given block root, or nil if no
+// snapshot is maintained for that block.
+func (t *Tree) Snapshot(blockRoot common.Hash) Snapshot {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if snap, ok := t.layers[blockRoot]; ok {
+		return snap
+	}
+	return nil
+}
+
+// Update adds a new snapshot into the tree, if that can be linked to an existing
+// old parent. It is disallowed to insert a disk layer (the origin of all).
+func (t *Tree) Update(blockRoot common.Hash, parentRoot common.Hash, destructs map[common.Hash]struct{}, accounts map[common.Hash][]byte, storage map[common.Hash]map[common.Hash][]byte) error {
+	// Reject noop updates to avoid self-loops in the snapshot tree. This is a
+	// special case that can only happen for blocks without state changes.
+	if blockRoot == parentRoot {
+		return errSnapshotCycle
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	// The same state may be committed twice, e.g. by the producer and the
+	// importer, the first layer stays authoritative.
+	if _, ok := t.layers[blockRoot]; ok {
+		return nil
+	}
+	parent, ok := t.layers[parentRoot]
+	if !ok {
+		return fmt.Errorf("parent [%#x] snapshot missing", parentRoot)
+	}
+	t.layers[blockRoot] = newDiffLayer(parent, blockRoot, destructs, accounts, storage)
+	return nil
+}
+
+// Cap traverses downwards the snapshot tree from a head block hash until the
+// number of allowed layers are crossed. All layers beyond the permitted number
+// are flattened downwards into the disk layer, and any layer not descending
+// from the new disk layer is dropped.
+//
+// A layers count of zero flattens the entire stack below and including root.
+func (t *Tree) Cap(root common.Hash, layers int) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	snap, ok := t.layers[root]
+	if !ok {
+		return fmt.Errorf("snapshot [%#x] missing", root)
+	}
+	diff, ok := snap.(*diffLayer)
+	if !ok {
+		return nil // Already the disk layer, nothing to flatten
+	}
+	// Walk down the permitted number of layers to find the newest one to flatten
+	var child *diffLayer
+	for i := 0; i < layers; i++ {
+		parent, ok := diff.Parent().(*diffLayer)
+		if !ok {
+			return nil // Fewer diff layers than permitted, nothing to flatten
+		}
+		child, diff = diff, parent
+	}
+	base := t.flatten(diff)
+
+	// Relink the retained stack onto the new disk layer and drop everything that
+	// no longer descends from it.
+	if child != nil {
+		child.lock.Lock()
+		child.parent = base
+		child.lock.Unlock()
+	}
+	for hash, layer := range t.layers {
+		if bottom(layer) != base {
+			if diff, ok := layer.(*diffLayer); ok {
+				diff.markStale()
+			}
+			delete(t.layers, hash)
+		}
+	}
+	t.layers[base.root] = base
+	return nil
+}
+
+// flatten persists the given diff layer together with all diff layers below it
+// into the disk layer, returning the new disk layer.
+func (t *Tree) flatten(diff *diffLayer) *diskLayer {
+	var base *diskLayer
+	switch parent := diff.Parent().(type) {
+	case *diffLayer:
+		base = t.flatten(parent)
+	case *diskLayer:
+		base = parent
+	}
+	diff.markStale()
+	return diffToDisk(diff, base)
+}
+
+// Stop terminates the background generation of the disk layer if it's running,
+// persisting its progress so it can be resumed on the next startup.
+func (t *Tree) Stop() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for _, layer := range t.layers {
+		if base, ok := layer.(*diskLayer); ok {
+			base.stopGeneration()
+		}
+	}
+}
+
+// bottom returns the disk layer the given snapshot is built on top of.
+func bottom(snap snapshot) *diskLayer {
+	for {
+		if base, ok := snap.(*diskLayer); ok {
+			return base
+		}
+		snap = snap.Parent()
+	}
+}
+
+// iteratee wraps the prefix iteration supported by the leveldb and in-memory
+// databases.
+type iteratee interface {
+	NewIteratorWithPrefix(prefix []byte) iterator.Iterator
+}
+
+// iterableStore returns the iterable key-value store backing the database, or
+// nil if the database cannot be iterated.
+func iterableStore(db vntdb.Database) iteratee {
+	if store, ok := db.(interface{ KeyValueStore() vntdb.Database }); ok {
+		db = store.KeyValueStore()
+	}
+	if it, ok := db.(iteratee); ok {
+		return it
+	}
+	return nil
+}
@@ -0,0 +1,239 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package snapshot
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/core/rawdb"
+	"github.com/vntchain/go-vnt/crypto"
+	"github.com/vntchain/go-vnt/rlp"
+	"github.com/vntchain/go-vnt/trie"
+	"github.com/vntchain/go-vnt/vntdb"
+)
+
+// makeTestState creates a state with a handful of accounts, every second one of
+// them having a few storage slots, and commits it into the given database.
+func makeTestState(t *testing.T, triedb *trie.Database) (common.Hash, map[common.Hash][]byte, map[common.Hash]map[common.Hash][]byte) {
+	accTrie, _ := trie.NewSecure(common.Hash{}, triedb, 0)
+
+	accounts := make(map[common.Hash][]byte)
+	storage := make(map[common.Hash]map[common.Hash][]byte)
+	for i := byte(1); i <= 16; i++ {
+		addr := common.BytesToAddress([]byte{i})
+		acc := account{Nonce: uint64(i), Balance: big.NewInt(int64(i) * 100), Root: emptyRoot, CodeHash: crypto.Keccak256(nil)}
+		if i%2 == 0 {
+			stTrie, _ := trie.NewSecure(common.Hash{}, triedb, 0)
+			slots := make(map[common.Hash][]byte)
+			for j := byte(1); j <= 4; j++ {
+				key := common.BytesToHash([]byte{i, j})
+				val, _ := rlp.EncodeToBytes([]byte{j})
+				stTrie.Update(key[:], val)
+				slots[crypto.Keccak256Hash(key[:])] = val
+			}
+			root, err := stTrie.Commit(nil)
+			if err != nil {
+				t.Fatalf("failed to commit storage trie: %v", err)
+			}
+			acc.Root = root
+			storage[crypto.Keccak256Hash(addr[:])] = slots
+		}
+		blob, _ := rlp.EncodeToBytes(acc)
+		accTrie.Update(addr[:], blob)
+		accounts[crypto.Keccak256Hash(addr[:])] = blob
+	}
+	root, err := accTrie.Commit(nil)
+	if err != nil {
+		t.Fatalf("failed to commit account trie: %v", err)
+	}
+	if err := triedb.Commit(root, false); err != nil {
+		t.Fatalf("failed to flush tries: %v", err)
+	}
+	return root, accounts, storage
+}
+
+// waitGeneration blocks until the disk layer of the tree finished generating.
+func waitGeneration(t *testing.T, snaps *Tree) *diskLayer {
+	for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(10 * time.Millisecond) {
+		snaps.lock.RLock()
+		var base *diskLayer
+		for _, layer := range snaps.layers {
+			if dl, ok := layer.(*diskLayer); ok {
+				base = dl
+			}
+		}
+		snaps.lock.RUnlock()
+
+		if base.genMarkerCopy() == nil {
+			return base
+		}
+	}
+	t.Fatalf("snapshot generation timed out")
+	return nil
+}
+
+// Tests that a snapshot generated from a trie contains all its leaves, and that
+// it's reused on restart instead of being regenerated.
+func TestGeneration(t *testing.T) {
+	diskdb := vntdb.NewMemDatabase()
+	triedb := trie.NewDatabase(diskdb)
+	root, accounts, storage := makeTestState(t, triedb)
+
+	// Plant a stale entry that the generator must wipe
+	stale := common.HexToHash("0xdeadbeef")
+	rawdb.WriteAccountSnapshot(diskdb, stale, []byte{0x01})
+
+	snaps, err := New(diskdb, triedb, 1, root)
+	if err != nil {
+		t.Fatalf("failed to create snapshot tree: %v", err)
+	}
+	waitGeneration(t, snaps)
+
+	snap := snaps.Snapshot(root)
+	for hash, blob := range accounts {
+		if have, err := snap.AccountRLP(hash); err != nil || !bytes.Equal(have, blob) {
+			t.Errorf("account %x: have %x (%v), want %x", hash, have, err, blob)
+		}
+		for slot, val := range storage[hash] {
+			if have, err := snap.Storage(hash, slot); err != nil || !bytes.Equal(have, val) {
+				t.Errorf("slot %x/%x: have %x (%v), want %x", hash, slot, have, err, val)
+			}
+		}
+	}
+	if have, err := snap.AccountRLP(stale); err != nil || have != nil {
+		t.Errorf("stale account: have %x (%v), want nil", have, err)
+	}
+	snaps.Stop()
+
+	// Reopen the snapshot and ensure it's loaded without regeneration
+	snaps, err = New(diskdb, triedb, 1, root)
+	if err != nil {
+		t.Fatalf("failed to reopen snapshot tree: %v", err)
+	}
+	if base := snaps.layers[root].(*diskLayer); base.genMarkerCopy() != nil {
+		t.Errorf("snapshot regenerated on reopen")
+	}
+	snaps.Stop()
+}
+
+// Tests that reads resolve through the diff layers, and that capping the tree
+// flattens the bottom layers into the disk layer.
+func TestDiffLayersCap(t *testing.T) {
+	diskdb := vntdb.NewMemDatabase()
+	triedb := trie.NewDatabase(diskdb)
+	root, accounts, storage := makeTestState(t, triedb)
+
+	snaps, err := New(diskdb, triedb, 1, root)
+	if err != nil {
+		t.Fatalf("failed to create snapshot tree: %v", err)
+	}
+	waitGeneration(t, snaps)
+	defer snaps.Stop()
+
+	// Pick an account with storage to destruct, and one to modify
+	var destructed, modified common.Hash
+	for hash := range storage {
+		if destructed == (common.Hash{}) {
+			destructed = hash
+		} else if modified == (common.Hash{}) {
+			modified = hash
+		}
+	}
+	var slot common.Hash
+	for hash := range storage[modified] {
+		slot = hash
+		break
+	}
+	var (
+		root1 = common.HexToHash("0x01")
+		root2 = common.HexToHash("0x02")
+		root3 = common.HexToHash("0x03")
+	)
+	if err := snaps.Update(root1, root, map[common.Hash]struct{}{destructed: {}}, nil, nil); err != nil {
+		t.Fatalf("failed to add first layer: %v", err)
+	}
+	if err := snaps.Update(root2, root1, nil, map[common.Hash][]byte{modified: {0x02}}, map[common.Hash]map[common.Hash][]byte{modified: {slot: nil}}); err != nil {
+		t.Fatalf("failed to add second layer: %v", err)
+	}
+	if err := snaps.Update(root3, root2, nil, map[common.Hash][]byte{modified: {0x03}}, nil); err != nil {
+		t.Fatalf("failed to add third layer: %v", err)
+	}
+	if err := snaps.Update(root1, common.HexToHash("0xff"), nil, nil, nil); err != nil {
+		t.Errorf("duplicate layer rejected: %v", err)
+	}
+	if err := snaps.Update(common.HexToHash("0x04"), common.HexToHash("0xff"), nil, nil, nil); err == nil {
+		t.Errorf("orphan layer accepted")
+	}
+	check := func(snap Snapshot) {
+		if blob, err := snap.AccountRLP(destructed); err != nil || blob != nil {
+			t.Errorf("destructed account: have %x (%v), want nil", blob, err)
+		}
+		for hash := range storage[destructed] {
+			if blob, err := snap.Storage(destructed, hash); err != nil || blob != nil {
+				t.Errorf("destructed slot: have %x (%v), want nil", blob, err)
+			}
+		}
+		if blob, err := snap.AccountRLP(modified); err != nil || !bytes.Equal(blob, []byte{0x03}) {
+			t.Errorf("modified account: have %x (%v), want 03", blob, err)
+		}
+		if blob, err := snap.Storage(modified, slot); err != nil || blob != nil {
+			t.Errorf("deleted slot: have %x (%v), want nil", blob, err)
+		}
+	}
+	check(snaps.Snapshot(root3))
+
+	// Parent layers must still serve their own view
+	if blob, err := snaps.Snapshot(root1).AccountRLP(modified); err != nil || !bytes.Equal(blob, accounts[modified]) {
+		t.Errorf("parent layer account: have %x (%v), want %x", blob, err, accounts[modified])
+	}
+	// Flatten everything but the topmost layer and recheck
+	oldbase := snaps.Snapshot(root).(*diskLayer)
+	if err := snaps.Cap(root3, 1); err != nil {
+		t.Fatalf("failed to cap snapshot tree: %v", err)
+	}
+	if len(snaps.layers) != 2 {
+		t.Errorf("layer count mismatch: have %d, want 2", len(snaps.layers))
+	}
+	if !oldbase.Stale() {
+		t.Errorf("old disk layer not marked stale")
+	}
+	if _, err := oldbase.AccountRLP(modified); err != ErrSnapshotStale {
+		t.Errorf("stale layer read: have %v, want %v", err, ErrSnapshotStale)
+	}
+	if rawdb.ReadSnapshotRoot(diskdb) != root2 {
+		t.Errorf("persisted root mismatch: have %x, want %x", rawdb.ReadSnapshotRoot(diskdb), root2)
+	}
+	for hash := range storage[destructed] {
+		if blob := rawdb.ReadStorageSnapshot(diskdb, destructed, hash); blob != nil {
+			t.Errorf("destructed slot persisted: %x", blob)
+		}
+	}
+	check(snaps.Snapshot(root3))
+
+	// Flatten the remaining layer as well
+	if err := snaps.Cap(root3, 0); err != nil {
+		t.Fatalf("failed to flatten snapshot tree: %v", err)
+	}
+	if _, ok := snaps.Snapshot(root3).(*diskLayer); !ok {
+		t.Errorf("head not flattened into disk layer")
+	}
+	check(snaps.Snapshot(root3))
+}
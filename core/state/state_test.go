@@ -18,11 +18,15 @@ package state
 
 import (
 	"bytes"
+	"fmt"
+	"io"
 	"math/big"
+	"strings"
 	"testing"
 
 	"github.com/vntchain/go-vnt/common"
 	"github.com/vntchain/go-vnt/crypto"
+	"github.com/vntchain/go-vnt/rlp"
 	"github.com/vntchain/go-vnt/vntdb"
 	checker "gopkg.in/check.v1"
 )
@@ -86,6 +90,52 @@ func (s *StateSuite) TestDump(c *checker.C) {
 	}
 }
 
+func (s *StateSuite) TestStreamedDump(c *checker.C) {
+	obj1 := s.state.GetOrNewStateObject(toAddr([]byte{0x01}))
+	obj1.AddBalance(big.NewInt(22))
+	obj1.SetState(s.state.db, common.BytesToHash([]byte{0x0a}), common.BytesToHash([]byte{0x0b}))
+	obj2 := s.state.GetOrNewStateObject(toAddr([]byte{0x02}))
+	obj2.SetCode(crypto.Keccak256Hash([]byte{3, 3, 3}), []byte{3, 3, 3})
+	root, _ := s.state.Commit(false)
+
+	// check that every account is streamed on its own JSON line after the root
+	var buf bytes.Buffer
+	if err := s.state.IterativeDump(&buf); err != nil {
+		c.Fatalf("json dump failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	c.Assert(lines, checker.HasLen, 3)
+	c.Assert(lines[0], checker.Equals, fmt.Sprintf(`{"root":"%x"}`, root))
+	c.Assert(strings.Contains(lines[1], `"address":"0000000000000000000000000000000000000001"`), checker.Equals, true)
+	c.Assert(strings.Contains(lines[2], `"code":"030303"`), checker.Equals, true)
+
+	// check that the RLP stream decodes back into the same accounts
+	buf.Reset()
+	if err := s.state.RLPDump(&buf); err != nil {
+		c.Fatalf("rlp dump failed: %v", err)
+	}
+	stream := rlp.NewStream(&buf, 0)
+	var dumpRoot common.Hash
+	c.Assert(stream.Decode(&dumpRoot), checker.IsNil)
+	c.Assert(dumpRoot, checker.Equals, root)
+
+	var accounts []RLPDumpAccount
+	for {
+		var account RLPDumpAccount
+		if err := stream.Decode(&account); err == io.EOF {
+			break
+		} else if err != nil {
+			c.Fatalf("failed to decode account: %v", err)
+		}
+		accounts = append(accounts, account)
+	}
+	c.Assert(accounts, checker.HasLen, 2)
+	c.Assert(accounts[0].Address, checker.Equals, toAddr([]byte{0x01}))
+	c.Assert(accounts[0].Balance.Int64(), checker.Equals, int64(22))
+	c.Assert(accounts[0].Storage, checker.HasLen, 1)
+	c.Assert(accounts[1].Code, checker.DeepEquals, []byte{3, 3, 3})
+}
+
 func (s *StateSuite) SetUpTest(c *checker.C) {
 	s.db = vntdb.NewMemDatabase()
 	s.state, _ = New(common.Hash{}, NewDatabase(s.db))
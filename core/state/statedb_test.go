@@ -31,7 +31,11 @@ import (
 	check "gopkg.in/check.v1"
 
 	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/core/state/snapshot"
 	"github.com/vntchain/go-vnt/core/types"
+	"github.com/vntchain/go-vnt/crypto"
+	"github.com/vntchain/go-vnt/rlp"
+	"github.com/vntchain/go-vnt/trie"
 	"github.com/vntchain/go-vnt/vntdb"
 )
 
@@ -435,3 +439,154 @@ func TestCopyOfCopy(t *testing.T) {
 		t.Fatalf("2nd copy fail, expected 42, got %v", got)
 	}
 }
+
+// Tests that states opened on top of a snapshot push their changes into it as a
+// new layer, and that the layer serves the committed accounts and slots.
+func TestSnapshotLayers(t *testing.T) {
+	db := vntdb.NewMemDatabase()
+	sdb := NewDatabase(db)
+
+	var (
+		alive   = common.BytesToAddress([]byte{0x01})
+		killed  = common.BytesToAddress([]byte{0x02})
+		slot    = common.BytesToHash([]byte{0x0a})
+		cleared = common.BytesToHash([]byte{0x0b})
+	)
+	state, _ := New(common.Hash{}, sdb)
+	state.SetBalance(alive, big.NewInt(1))
+	state.SetState(alive, cleared, common.BytesToHash([]byte{0x01}))
+	state.SetBalance(killed, big.NewInt(2))
+	state.SetState(killed, slot, common.BytesToHash([]byte{0x02}))
+	root, _ := state.Commit(false)
+	sdb.TrieDB().Commit(root, false)
+
+	snaps, err := snapshot.New(db, sdb.TrieDB(), 1, root)
+	if err != nil {
+		t.Fatalf("failed to create snapshot tree: %v", err)
+	}
+	defer snaps.Stop()
+
+	state, _ = NewWithSnapshot(root, sdb, snaps)
+	state.SetBalance(alive, big.NewInt(3))
+	state.SetState(alive, slot, common.BytesToHash([]byte{0x03}))
+	state.SetState(alive, cleared, common.Hash{})
+	state.Suicide(killed)
+	next, _ := state.Commit(false)
+
+	if snaps.Snapshot(next) == nil {
+		t.Fatalf("snapshot layer missing for committed state")
+	}
+	state, _ = NewWithSnapshot(next, sdb, snaps)
+	if state.snap == nil {
+		t.Fatalf("state not attached to snapshot layer")
+	}
+	if balance := state.GetBalance(alive); balance.Cmp(big.NewInt(3)) != 0 {
+		t.Errorf("balance mismatch: have %v, want 3", balance)
+	}
+	if value := state.GetState(alive, slot); value != common.BytesToHash([]byte{0x03}) {
+		t.Errorf("slot mismatch: have %x, want 03", value)
+	}
+	if value := state.GetState(alive, cleared); value != (common.Hash{}) {
+		t.Errorf("cleared slot mismatch: have %x, want empty", value)
+	}
+	if state.Exist(killed) {
+		t.Errorf("suicided account still exists")
+	}
+}
+
+// Tests that account and storage proofs verify against the committed state and
+// storage roots.
+func TestProofs(t *testing.T) {
+	var (
+		db    = NewDatabase(vntdb.NewMemDatabase())
+		addr  = common.HexToAddress("0x01")
+		slot  = common.HexToHash("0x02")
+		value = common.HexToHash("0x03")
+	)
+	statedb, _ := New(common.Hash{}, db)
+	for i := byte(0); i < 16; i++ {
+		other := common.BytesToAddress([]byte{0xff, i})
+		statedb.AddBalance(other, big.NewInt(int64(i)+1))
+		statedb.SetState(addr, common.BytesToHash([]byte{0xff, i}), common.BytesToHash([]byte{i + 1}))
+	}
+	statedb.AddBalance(addr, big.NewInt(42))
+	statedb.SetState(addr, slot, value)
+	root, _ := statedb.Commit(false)
+	statedb, _ = New(root, db)
+
+	// Verify the account proof and decode the proven account
+	proof, err := statedb.GetProof(addr)
+	if err != nil {
+		t.Fatalf("failed to prove account: %v", err)
+	}
+	blob, _, err := trie.VerifyProof(root, crypto.Keccak256(addr.Bytes()), proofDatabase(proof))
+	if err != nil {
+		t.Fatalf("failed to verify account proof: %v", err)
+	}
+	var account Account
+	if err := rlp.DecodeBytes(blob, &account); err != nil {
+		t.Fatalf("failed to decode proven account: %v", err)
+	}
+	if account.Balance.Cmp(big.NewInt(42)) != 0 {
+		t.Fatalf("proven balance mismatch: have %v, want 42", account.Balance)
+	}
+	// Verify the storage proof against the proven storage root
+	proof, err = statedb.GetStorageProof(addr, slot)
+	if err != nil {
+		t.Fatalf("failed to prove storage: %v", err)
+	}
+	blob, _, err = trie.VerifyProof(account.Root, crypto.Keccak256(slot.Bytes()), proofDatabase(proof))
+	if err != nil {
+		t.Fatalf("failed to verify storage proof: %v", err)
+	}
+	var content []byte
+	if err := rlp.DecodeBytes(blob, &content); err != nil {
+		t.Fatalf("failed to decode proven storage: %v", err)
+	}
+	if common.BytesToHash(content) != value {
+		t.Fatalf("proven storage mismatch: have %x, want %x", content, value)
+	}
+	// Non-existent accounts don't have storage to prove
+	if _, err := statedb.GetStorageProof(common.HexToAddress("0xdead"), slot); err == nil {
+		t.Fatalf("storage proof of missing account succeeded")
+	}
+}
+
+// proofDatabase assembles a database of proof nodes keyed by their hashes.
+func proofDatabase(proof [][]byte) *vntdb.MemDatabase {
+	db := vntdb.NewMemDatabase()
+	for _, node := range proof {
+		db.Put(crypto.Keccak256(node), node)
+	}
+	return db
+}
+
+// Tests that replacing the storage of an account shadows all its original slots,
+// keeps later writes in the replacement, and leaves the original untouched.
+func TestSetStorage(t *testing.T) {
+	var (
+		db   = NewDatabase(vntdb.NewMemDatabase())
+		addr = common.HexToAddress("0x01")
+		a, b = common.HexToHash("0x0a"), common.HexToHash("0x0b")
+	)
+	statedb, _ := New(common.Hash{}, db)
+	statedb.SetState(addr, a, common.HexToHash("0x01"))
+	statedb.SetState(addr, b, common.HexToHash("0x02"))
+	root, _ := statedb.Commit(false)
+
+	statedb, _ = New(root, db)
+	statedb.SetStorage(addr, map[common.Hash]common.Hash{a: common.HexToHash("0x03")})
+	if have := statedb.GetState(addr, a); have != common.HexToHash("0x03") {
+		t.Errorf("overridden slot mismatch: have %x, want 0x03", have)
+	}
+	if have := statedb.GetState(addr, b); have != (common.Hash{}) {
+		t.Errorf("shadowed slot mismatch: have %x, want empty", have)
+	}
+	statedb.SetState(addr, b, common.HexToHash("0x04"))
+	if have := statedb.GetState(addr, b); have != common.HexToHash("0x04") {
+		t.Errorf("written slot mismatch: have %x, want 0x04", have)
+	}
+	if statedb.IntermediateRoot(false) != root {
+		t.Errorf("storage override leaked into the state root")
+	}
+}
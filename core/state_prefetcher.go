@@ -0,0 +1,95 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"sync/atomic"
+
+	"github.com/vntchain/go-vnt/core/state"
+	"github.com/vntchain/go-vnt/core/types"
+	"github.com/vntchain/go-vnt/core/vm"
+	"github.com/vntchain/go-vnt/metrics"
+	"github.com/vntchain/go-vnt/params"
+)
+
+var (
+	prefetchTxMeter          = metrics.NewRegisteredMeter("chain/prefetch/txs", nil)
+	prefetchInterruptedMeter = metrics.NewRegisteredMeter("chain/prefetch/interrupted", nil)
+)
+
+// statePrefetcher warms the state trie nodes a block touches, by blindly running
+// its transactions on a throwaway copy of the state alongside the processor. The
+// nodes read from disk are kept in the clean cache of the trie database, where
+// the processor finds them when reaching the same transactions.
+type statePrefetcher struct {
+	config *params.ChainConfig // Chain configuration options
+	bc     *BlockChain         // Canonical block chain
+}
+
+// newStatePrefetcher initialises a new statePrefetcher.
+func newStatePrefetcher(config *params.ChainConfig, bc *BlockChain) *statePrefetcher {
+	return &statePrefetcher{
+		config: config,
+		bc:     bc,
+	}
+}
+
+// prefetch runs the transactions of the block on the given state, discarding
+// any change, until done or interrupted. The accounts of all the senders and
+// recipients are loaded first as they are cheap to reach, getting ahead of the
+// processor, before executing the transactions for the storage they access.
+//
+// The transactions run with the vm config of the processor, metering their
+// memory separately if it's capped, but without tracing them.
+func (p *statePrefetcher) prefetch(block *types.Block, statedb *state.StateDB, cfg vm.Config, interrupt *uint32) {
+	var (
+		header  = block.Header()
+		signer  = types.MakeSigner(p.config, header.Number)
+		gaspool = new(GasPool).AddGas(block.GasLimit())
+		usedGas = new(uint64)
+	)
+	cfg.Debug, cfg.Tracer = false, nil
+	if cfg.MemoryCap > 0 {
+		cfg.MemoryMeter = vm.NewMemoryMeter(cfg.MemoryCap)
+	}
+	for _, tx := range block.Transactions() {
+		if atomic.LoadUint32(interrupt) == 1 {
+			prefetchInterruptedMeter.Mark(1)
+			return
+		}
+		if from, err := types.Sender(signer, tx); err == nil {
+			statedb.GetNonce(from)
+		}
+		if to := tx.To(); to != nil {
+			statedb.GetCode(*to)
+		}
+	}
+	for i, tx := range block.Transactions() {
+		if atomic.LoadUint32(interrupt) == 1 {
+			prefetchInterruptedMeter.Mark(1)
+			return
+		}
+		statedb.Prepare(tx.Hash(), block.Hash(), i)
+		if _, _, err := ApplyTransaction(p.config, p.bc, nil, gaspool, statedb, header, tx, usedGas, cfg); err != nil {
+			return // The block is invalid, leave it to the processor to report
+		}
+		if cfg.MemoryMeter != nil && cfg.MemoryMeter.Exceeded() {
+			return // The block crossed the memory cap, leave it to the processor to report
+		}
+		prefetchTxMeter.Mark(1)
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/consensus/mock"
+	"github.com/vntchain/go-vnt/core/state"
+	"github.com/vntchain/go-vnt/core/types"
+	"github.com/vntchain/go-vnt/core/vm"
+	"github.com/vntchain/go-vnt/crypto"
+	"github.com/vntchain/go-vnt/params"
+	"github.com/vntchain/go-vnt/vntdb"
+)
+
+// readCountingDatabase is a database counting the reads hitting it.
+type readCountingDatabase struct {
+	vntdb.Database
+	reads int32
+}
+
+func (db *readCountingDatabase) Get(key []byte) ([]byte, error) {
+	atomic.AddInt32(&db.reads, 1)
+	return db.Database.Get(key)
+}
+
+// Tests that the prefetcher warms all the state a block touches, so that its
+// processing doesn't need to read the disk any more.
+func TestStatePrefetch(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  GenesisAlloc{address: {Balance: big.NewInt(1000000000)}},
+		}
+		signer = types.NewHubbleSigner(gspec.Config.ChainID)
+	)
+	for i := 0; i < 64; i++ {
+		gspec.Alloc[common.BigToAddress(big.NewInt(int64(i+1)))] = GenesisAccount{Balance: big.NewInt(1)}
+	}
+	gendb := vntdb.NewMemDatabase()
+	genesis := gspec.MustCommit(gendb)
+	blocks, _ := GenerateChain(gspec.Config, genesis, mock.NewMock(), gendb, 1, func(i int, block *BlockGen) {
+		for j := 0; j < 8; j++ {
+			tx, err := types.SignTx(types.NewTransaction(block.TxNonce(address), common.BigToAddress(big.NewInt(int64(8*j+1))), big.NewInt(1000), params.TxGas, nil, nil), signer, key)
+			if err != nil {
+				t.Fatalf("failed to sign transaction: %v", err)
+			}
+			block.AddTx(tx)
+		}
+	})
+	db := &readCountingDatabase{Database: vntdb.NewMemDatabase()}
+	gspec.MustCommit(db)
+
+	chain, err := NewBlockChain(db, &CacheConfig{TrieNodeLimit: 256, TrieTimeLimit: 5 * time.Minute, TriePrefetchLimit: 16}, gspec.Config, mock.NewMock(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	prefetcher := newStatePrefetcher(gspec.Config, chain)
+	statedb, _ := state.New(genesis.Root(), chain.stateCache)
+
+	// Interrupted prefetches don't touch the state
+	atomic.StoreInt32(&db.reads, 0)
+	interrupt := uint32(1)
+	prefetcher.prefetch(blocks[0], statedb.Copy(), chain.vmConfig, &interrupt)
+	if reads := atomic.LoadInt32(&db.reads); reads != 0 {
+		t.Errorf("interrupted prefetch reads mismatch: have %d, want 0", reads)
+	}
+	prefetcher.prefetch(blocks[0], statedb.Copy(), chain.vmConfig, new(uint32))
+	if reads := atomic.LoadInt32(&db.reads); reads == 0 {
+		t.Fatalf("prefetch didn't read the disk")
+	}
+	// Processing the block should find all the state in memory
+	atomic.StoreInt32(&db.reads, 0)
+	var (
+		gp      = new(GasPool).AddGas(blocks[0].GasLimit())
+		usedGas = new(uint64)
+	)
+	for i, tx := range blocks[0].Transactions() {
+		statedb.Prepare(tx.Hash(), blocks[0].Hash(), i)
+		if _, _, err := ApplyTransaction(gspec.Config, chain, nil, gp, statedb, blocks[0].Header(), tx, usedGas, vm.Config{}); err != nil {
+			t.Fatalf("tx %d: failed to apply: %v", i, err)
+		}
+	}
+	statedb.IntermediateRoot(true)
+	if reads := atomic.LoadInt32(&db.reads); reads != 0 {
+		t.Errorf("disk reads after prefetch mismatch: have %d, want 0", reads)
+	}
+}
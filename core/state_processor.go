@@ -17,6 +17,8 @@
 package core
 
 import (
+	"sync/atomic"
+
 	"github.com/pkg/errors"
 	"github.com/vntchain/go-vnt/common"
 	"github.com/vntchain/go-vnt/consensus"
@@ -32,18 +34,24 @@ import (
 //
 // StateProcessor implements Processor.
 type StateProcessor struct {
-	config *params.ChainConfig // Chain configuration options
-	bc     *BlockChain         // Canonical block chain
-	engine consensus.Engine    // Consensus engine used for block rewards
+	config     *params.ChainConfig // Chain configuration options
+	bc         *BlockChain         // Canonical block chain
+	engine     consensus.Engine    // Consensus engine used for block rewards
+	prefetcher *statePrefetcher    // State prefetcher warming the trie nodes of blocks (nil = disabled)
 }
 
-// NewStateProcessor initialises a new StateProcessor.
+// NewStateProcessor initialises a new StateProcessor, prefetching the state of
+// the processed blocks if the chain caches clean trie nodes.
 func NewStateProcessor(config *params.ChainConfig, bc *BlockChain, engine consensus.Engine) *StateProcessor {
-	return &StateProcessor{
+	processor := &StateProcessor{
 		config: config,
 		bc:     bc,
 		engine: engine,
 	}
+	if bc != nil && bc.cacheConfig.TriePrefetchLimit > 0 {
+		processor.prefetcher = newStatePrefetcher(config, bc)
+	}
+	return processor
 }
 
 // Process processes the state changes according to the VNT rules by running
@@ -53,6 +61,11 @@ func NewStateProcessor(config *params.ChainConfig, bc *BlockChain, engine consen
 // Process returns the receipts and logs accumulated during the process and
 // returns the amount of gas that was used in the process. If any of the
 // transactions failed to execute due to insufficient gas it will return an error.
+//
+// If cfg caps the execution memory, the contract executions are aborted as soon
+// as the block's allocations would cross the cap, and an error caused by
+// vm.ErrMemoryCapExceeded is returned. As the cap is a local setting, such an
+// error doesn't render the block invalid.
 func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg vm.Config) (types.Receipts, []*types.Log, uint64, error) {
 	var (
 		receipts types.Receipts
@@ -62,6 +75,17 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 		gp       = new(GasPool).AddGas(block.GasLimit())
 	)
 
+	// Warm the state of the block concurrently, until done processing it
+	if p.prefetcher != nil && len(block.Transactions()) > 0 {
+		interrupt := new(uint32)
+		defer atomic.StoreUint32(interrupt, 1)
+
+		go p.prefetcher.prefetch(block, statedb.Copy(), cfg, interrupt)
+	}
+	// Meter the memory allocated by the block's execution if it's capped
+	if cfg.MemoryCap > 0 {
+		cfg.MemoryMeter = vm.NewMemoryMeter(cfg.MemoryCap)
+	}
 	// Iterate over and process the individual transactions
 	for i, tx := range block.Transactions() {
 		statedb.Prepare(tx.Hash(), block.Hash(), i)
@@ -69,6 +93,9 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 		if err != nil {
 			return nil, nil, 0, err
 		}
+		if cfg.MemoryMeter != nil && cfg.MemoryMeter.Exceeded() {
+			return nil, nil, 0, errors.Wrapf(vm.ErrMemoryCapExceeded, "block #%d tx %d: %d bytes allocated, cap %d", block.NumberU64(), i, cfg.MemoryMeter.Used(), cfg.MemoryCap)
+		}
 		receipts = append(receipts, receipt)
 		allLogs = append(allLogs, receipt.Logs...)
 	}
@@ -78,11 +105,20 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 	return receipts, allLogs, *usedGas, nil
 }
 
+// isMemoryCapExceeded reports whether a block failed to process because its
+// execution crossed the local memory cap.
+func isMemoryCapExceeded(err error) bool {
+	return errors.Cause(err) == vm.ErrMemoryCapExceeded
+}
+
 // ApplyTransaction attempts to apply a transaction to the given state database
 // and uses the input parameters for its environment. It returns the receipt
 // for the transaction, gas used and an error if the transaction failed,
 // indicating the block was invalid.
 func ApplyTransaction(config *params.ChainConfig, bc ChainContext, author *common.Address, gp *GasPool, statedb *state.StateDB, header *types.Header, tx *types.Transaction, usedGas *uint64, cfg vm.Config) (*types.Receipt, uint64, error) {
+	if tx.FeePayer() != nil && !config.IsFeeDelegation(header.Number) {
+		return nil, 0, ErrFeeDelegationInactive
+	}
 	msg, err := tx.AsMessage(types.MakeSigner(config, header.Number))
 	if err != nil {
 		return nil, 0, err
@@ -91,11 +127,7 @@ func ApplyTransaction(config *params.ChainConfig, bc ChainContext, author *commo
 	context := NewVMContext(msg, header, bc, author)
 	// Create a new environment which holds all relevant information
 	// about the transaction and calling mechanisms.
-	var (
-		gas    uint64
-		failed bool
-		origin common.Address
-	)
+	var origin common.Address
 
 	vmenv := GetVM(msg, context, statedb, config, cfg)
 
@@ -103,7 +135,7 @@ func ApplyTransaction(config *params.ChainConfig, bc ChainContext, author *commo
 		return nil, 0, errors.New("failed to call contract!")
 	}
 
-	_, gas, failed, err = ApplyMessage(vmenv, msg, gp)
+	result, err := ApplyMessage(vmenv, msg, gp)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -111,13 +143,13 @@ func ApplyTransaction(config *params.ChainConfig, bc ChainContext, author *commo
 	// Update the state with pending changes
 	var root []byte
 	statedb.Finalise(true)
-	*usedGas += gas
+	*usedGas += result.UsedGas
 
 	// Create a new receipt for the transaction, storing the intermediate root and gas used by the tx
 	// based on the eip phase, we're passing wether the root touch-delete accounts.
-	receipt := types.NewReceipt(root, failed, *usedGas)
+	receipt := types.NewReceipt(root, result.Failed(), *usedGas)
 	receipt.TxHash = tx.Hash()
-	receipt.GasUsed = gas
+	receipt.GasUsed = result.UsedGas
 	// if the transaction created a contract, store the creation address in the receipt.
 	if msg.To() == nil {
 		receipt.ContractAddress = crypto.CreateAddress(origin, tx.Nonce())
@@ -126,5 +158,5 @@ func ApplyTransaction(config *params.ChainConfig, bc ChainContext, author *commo
 	receipt.Logs = statedb.GetLogs(tx.Hash())
 	receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
 
-	return receipt, gas, err
+	return receipt, result.UsedGas, err
 }
@@ -19,6 +19,8 @@ package core
 import (
 	"runtime"
 
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/vntchain/go-vnt/common"
 	"github.com/vntchain/go-vnt/core/types"
 )
 
@@ -31,10 +33,14 @@ var senderCacher = newTxSenderCacher(runtime.NumCPU())
 // The inc field defines the number of transactions to skip after each recovery,
 // which is used to feed the same underlying input array to different threads but
 // ensure they process the early transactions fast.
+//
+// The senders recovered are also added to the cache keyed by transaction hash,
+// if there is one.
 type txSenderCacherRequest struct {
 	signer types.Signer
 	txs    []*types.Transaction
 	inc    int
+	cache  *lru.Cache
 }
 
 // txSenderCacher is a helper structure to concurrently ecrecover transaction
@@ -62,7 +68,10 @@ func newTxSenderCacher(threads int) *txSenderCacher {
 func (cacher *txSenderCacher) cache() {
 	for task := range cacher.tasks {
 		for i := 0; i < len(task.txs); i += task.inc {
-			types.Sender(task.signer, task.txs[i])
+			from, err := types.Sender(task.signer, task.txs[i])
+			if err == nil && task.cache != nil {
+				task.cache.Add(task.txs[i].Hash(), from)
+			}
 		}
 	}
 }
@@ -71,6 +80,24 @@ func (cacher *txSenderCacher) cache() {
 // back into the same data structures. There is no validation being done, nor
 // any reaction to invalid signatures. That is up to calling code later.
 func (cacher *txSenderCacher) recover(signer types.Signer, txs []*types.Transaction) {
+	cacher.recoverCached(signer, txs, nil)
+}
+
+// recoverCached recovers the senders from a batch of transactions like recover,
+// but takes the senders found in the cache keyed by transaction hash instead of
+// recovering them, and adds the recovered ones to the cache.
+func (cacher *txSenderCacher) recoverCached(signer types.Signer, txs []*types.Transaction, cache *lru.Cache) {
+	if cache != nil {
+		missing := make([]*types.Transaction, 0, len(txs))
+		for _, tx := range txs {
+			if from, ok := cache.Get(tx.Hash()); ok {
+				types.CacheSender(signer, tx, from.(common.Address))
+				continue
+			}
+			missing = append(missing, tx)
+		}
+		txs = missing
+	}
 	// If there's nothing to recover, abort
 	if len(txs) == 0 {
 		return
@@ -85,14 +112,16 @@ func (cacher *txSenderCacher) recover(signer types.Signer, txs []*types.Transact
 			signer: signer,
 			txs:    txs[i:],
 			inc:    tasks,
+			cache:  cache,
 		}
 	}
 }
 
 // recoverFromBlocks recovers the senders from a batch of blocks and caches them
-// back into the same data structures. There is no validation being done, nor
-// any reaction to invalid signatures. That is up to calling code later.
-func (cacher *txSenderCacher) recoverFromBlocks(signer types.Signer, blocks []*types.Block) {
+// back into the same data structures, as well as into the cache keyed by
+// transaction hash if given one. There is no validation being done, nor any
+// reaction to invalid signatures. That is up to calling code later.
+func (cacher *txSenderCacher) recoverFromBlocks(signer types.Signer, blocks []*types.Block, cache *lru.Cache) {
 	count := 0
 	for _, block := range blocks {
 		count += len(block.Transactions())
@@ -101,5 +130,5 @@ func (cacher *txSenderCacher) recoverFromBlocks(signer types.Signer, blocks []*t
 	for _, block := range blocks {
 		txs = append(txs, block.Transactions()...)
 	}
-	cacher.recover(signer, txs)
+	cacher.recoverCached(signer, txs, cache)
 }
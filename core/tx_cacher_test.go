@@ -0,0 +1,73 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/core/types"
+	"github.com/vntchain/go-vnt/crypto"
+	"github.com/vntchain/go-vnt/params"
+	"github.com/vntchain/go-vnt/rlp"
+)
+
+// Tests that the senders recovered in the background are added to the cache
+// keyed by transaction hash, and that cached senders aren't recovered again.
+func TestSenderCacherCache(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	signer := types.NewHubbleSigner(big.NewInt(1))
+
+	txs := make([]*types.Transaction, 16)
+	for i := range txs {
+		tx, err := types.SignTx(types.NewTransaction(uint64(i), common.Address{0x01}, big.NewInt(1), params.TxGas, big.NewInt(1), nil), signer, key)
+		if err != nil {
+			t.Fatalf("failed to sign transaction %d: %v", i, err)
+		}
+		txs[i] = tx
+	}
+	cache, _ := lru.New(len(txs))
+	cacher := newTxSenderCacher(4)
+	cacher.recoverCached(signer, txs, cache)
+
+	for deadline := time.Now().Add(5 * time.Second); cache.Len() < len(txs); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("cached senders mismatch: have %d, want %d", cache.Len(), len(txs))
+		}
+	}
+	for i, tx := range txs {
+		if from, ok := cache.Get(tx.Hash()); !ok || from.(common.Address) != crypto.PubkeyToAddress(key.PublicKey) {
+			t.Errorf("tx %d: cached sender mismatch: have %v, want %x", i, from, crypto.PubkeyToAddress(key.PublicKey))
+		}
+	}
+	// Decoded copies take their senders from the cache
+	fake := common.Address{0xff}
+	cache.Add(txs[0].Hash(), fake)
+
+	blob, _ := rlp.EncodeToBytes(txs[0])
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(blob, tx); err != nil {
+		t.Fatalf("failed to decode transaction: %v", err)
+	}
+	cacher.recoverCached(signer, []*types.Transaction{tx}, cache)
+	if from, err := types.Sender(signer, tx); err != nil || from != fake {
+		t.Errorf("sender mismatch: have %x/%v, want %x", from, err, fake)
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/core/types"
+)
+
+// NonceGap is an inclusive range of nonces missing from an account's pool
+// content, holding back the queued transactions above it.
+type NonceGap struct {
+	From uint64 `json:"from"`
+	To   uint64 `json:"to"`
+}
+
+// TxPoolAccountDump is the pool content of a single account.
+type TxPoolAccountDump struct {
+	Nonce   uint64             `json:"nonce"` // Next nonce expected from the account
+	Pending types.Transactions `json:"pending"`
+	Queued  types.Transactions `json:"queued"`
+	Gaps    []NonceGap         `json:"gaps"`
+}
+
+// TxPoolDump is a snapshot of the pending and queued transactions of the pool
+// grouped by account.
+type TxPoolDump map[common.Address]*TxPoolAccountDump
+
+// NewTxPoolDump groups the nonce sorted pending and queued transactions by
+// account, detecting the nonce gaps between the next nonce expected from each
+// account and its queued transactions.
+func NewTxPoolDump(pending, queued map[common.Address]types.Transactions, nonce func(common.Address) uint64) TxPoolDump {
+	dump := make(TxPoolDump)
+	account := func(addr common.Address) *TxPoolAccountDump {
+		if _, ok := dump[addr]; !ok {
+			dump[addr] = &TxPoolAccountDump{
+				Nonce:   nonce(addr),
+				Pending: types.Transactions{},
+				Queued:  types.Transactions{},
+				Gaps:    []NonceGap{},
+			}
+		}
+		return dump[addr]
+	}
+	for addr, txs := range pending {
+		account(addr).Pending = append(account(addr).Pending, txs...)
+	}
+	for addr, txs := range queued {
+		acc := account(addr)
+		acc.Queued = append(acc.Queued, txs...)
+
+		next := acc.Nonce
+		for _, tx := range txs {
+			if tx.Nonce() > next {
+				acc.Gaps = append(acc.Gaps, NonceGap{From: next, To: tx.Nonce() - 1})
+			}
+			if tx.Nonce() >= next {
+				next = tx.Nonce() + 1
+			}
+		}
+	}
+	return dump
+}
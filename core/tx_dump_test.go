@@ -0,0 +1,63 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/core/types"
+	"github.com/vntchain/go-vnt/crypto"
+)
+
+// Tests that the pool dump groups transactions by account and reports the
+// nonce gaps holding back queued transactions.
+func TestTxPoolDump(t *testing.T) {
+	key1, _ := crypto.GenerateKey()
+	key2, _ := crypto.GenerateKey()
+	addr1, addr2 := crypto.PubkeyToAddress(key1.PublicKey), crypto.PubkeyToAddress(key2.PublicKey)
+
+	pending := map[common.Address]types.Transactions{
+		addr1: {transaction(0, 100000, key1), transaction(1, 100000, key1)},
+	}
+	queued := map[common.Address]types.Transactions{
+		addr1: {transaction(4, 100000, key1), transaction(5, 100000, key1), transaction(8, 100000, key1)},
+		addr2: {transaction(3, 100000, key2)},
+	}
+	nonces := map[common.Address]uint64{addr1: 2, addr2: 0}
+	dump := NewTxPoolDump(pending, queued, func(addr common.Address) uint64 { return nonces[addr] })
+
+	if len(dump) != 2 {
+		t.Fatalf("account count mismatch: have %d, want %d", len(dump), 2)
+	}
+	if acc := dump[addr1]; acc.Nonce != 2 || len(acc.Pending) != 2 || len(acc.Queued) != 3 {
+		t.Errorf("account 1 content mismatch: nonce %d, pending %d, queued %d", acc.Nonce, len(acc.Pending), len(acc.Queued))
+	}
+	if gaps, want := dump[addr1].Gaps, []NonceGap{{2, 3}, {6, 7}}; !reflect.DeepEqual(gaps, want) {
+		t.Errorf("account 1 gaps mismatch: have %v, want %v", gaps, want)
+	}
+	if acc := dump[addr2]; len(acc.Pending) != 0 || len(acc.Queued) != 1 {
+		t.Errorf("account 2 content mismatch: pending %d, queued %d", len(acc.Pending), len(acc.Queued))
+	}
+	if gaps, want := dump[addr2].Gaps, []NonceGap{{0, 2}}; !reflect.DeepEqual(gaps, want) {
+		t.Errorf("account 2 gaps mismatch: have %v, want %v", gaps, want)
+	}
+	if dump := NewTxPoolDump(nil, nil, nil); len(dump) != 0 {
+		t.Errorf("empty pool dump not empty: %v", dump)
+	}
+}
@@ -60,6 +60,14 @@ var (
 	// is higher than the balance of the user's account.
 	ErrInsufficientFunds = errors.New("insufficient funds for gas * price + value")
 
+	// ErrInvalidFeePayer is returned if a sponsored transaction isn't properly
+	// signed by its fee payer.
+	ErrInvalidFeePayer = errors.New("invalid fee payer")
+
+	// ErrInsufficientFeePayerFunds is returned if the fee payer of a sponsored
+	// transaction can't afford its gas.
+	ErrInsufficientFeePayerFunds = errors.New("insufficient fee payer funds for gas * price")
+
 	// ErrIntrinsicGas is returned if the transaction is specified to use less gas
 	// than required to start the invocation.
 	ErrIntrinsicGas = errors.New("intrinsic gas too low")
@@ -123,9 +131,12 @@ type blockChain interface {
 
 // TxPoolConfig are the configuration parameters of the transaction pool.
 type TxPoolConfig struct {
-	NoLocals  bool          // Whether local transaction handling should be disabled
-	Journal   string        // Journal of local transactions to survive node restarts
-	Rejournal time.Duration // Time interval to regenerate the local transaction journal
+	Locals    []common.Address // Addresses that should be treated by default as local
+	NoLocals  bool             // Whether local transaction handling should be disabled
+	Journal   string           // Journal of local transactions to survive node restarts
+	Rejournal time.Duration    // Time interval to regenerate the local transaction journal
+
+	JournalRemotes bool // Whether remote transactions are journaled too, next to the local journal
 
 	PriceLimit uint64 // Minimum gas price to enforce for acceptance into the pool
 	PriceBump  uint64 // Minimum price bump percentage to replace an already existing transaction (nonce)
@@ -197,8 +208,9 @@ type TxPool struct {
 	pendingState  *state.ManagedState // Pending state tracking virtual nonces
 	currentMaxGas uint64              // Current gas limit for transaction caps
 
-	locals  *accountSet // Set of local transaction to exempt from eviction rules
-	journal *txJournal  // Journal of local transaction to back up to disk
+	locals        *accountSet // Set of local transaction to exempt from eviction rules
+	journal       *txJournal  // Journal of local transaction to back up to disk
+	remoteJournal *txJournal  // Journal of remote transactions to back up to disk, if enabled
 
 	pending map[common.Address]*txList   // All currently processable transactions
 	queue   map[common.Address]*txList   // Queued but non-processable transactions
@@ -230,6 +242,10 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 		gasPrice:    new(big.Int).SetUint64(config.PriceLimit),
 	}
 	pool.locals = newAccountSet(pool.signer)
+	for _, addr := range config.Locals {
+		log.Info("Setting new local account", "address", addr)
+		pool.locals.add(addr)
+	}
 	pool.priced = newTxPricedList(pool.all)
 	pool.reset(nil, chain.CurrentBlock().Header())
 
@@ -244,6 +260,17 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 			log.Warn("Failed to rotate transaction journal", "err", err)
 		}
 	}
+	// If remote transactions are journaled too, load them after the local ones
+	if config.JournalRemotes && config.Journal != "" {
+		pool.remoteJournal = newTxJournal(remoteJournalPath(config.Journal))
+
+		if err := pool.remoteJournal.load(pool.AddRemotes); err != nil {
+			log.Warn("Failed to load remote transaction journal", "err", err)
+		}
+		if err := pool.remoteJournal.rotate(pool.remote()); err != nil {
+			log.Warn("Failed to rotate remote transaction journal", "err", err)
+		}
+	}
 	// Subscribe events from blockchain
 	pool.chainHeadSub = pool.chain.SubscribeChainHeadEvent(pool.chainHeadCh)
 
@@ -320,7 +347,7 @@ func (pool *TxPool) loop() {
 			}
 			pool.mu.Unlock()
 
-		// Handle local transaction journal rotation
+		// Handle local and remote transaction journal rotation
 		case <-journal.C:
 			if pool.journal != nil {
 				pool.mu.Lock()
@@ -329,6 +356,13 @@ func (pool *TxPool) loop() {
 				}
 				pool.mu.Unlock()
 			}
+			if pool.remoteJournal != nil {
+				pool.mu.Lock()
+				if err := pool.remoteJournal.rotate(pool.remote()); err != nil {
+					log.Warn("Failed to rotate remote tx journal", "err", err)
+				}
+				pool.mu.Unlock()
+			}
 		}
 	}
 }
@@ -355,6 +389,14 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 
 		if depth := uint64(math.Abs(float64(oldNum) - float64(newNum))); depth > 64 {
 			log.Debug("Skipping deep transaction reorg", "depth", depth)
+		} else if pool.chain.GetBlock(oldHead.Hash(), oldNum) == nil {
+			// The old head was deleted by rewinding the chain, its transactions are
+			// gone, only the state needs resetting
+			if newNum >= oldNum {
+				log.Warn("Transaction pool reset with missing old head", "old", oldHead.Hash(), "new", newHead.Hash())
+				return
+			}
+			log.Debug("Skipping transaction reinjection after chain rewind", "old", oldNum, "new", newNum)
 		} else {
 			// Reorg seems shallow enough to pull in all transactions into memory
 			var discarded, included types.Transactions
@@ -438,6 +480,9 @@ func (pool *TxPool) Stop() {
 	if pool.journal != nil {
 		pool.journal.close()
 	}
+	if pool.remoteJournal != nil {
+		pool.remoteJournal.close()
+	}
 	log.Info("Transaction pool stopped")
 }
 
@@ -530,6 +575,14 @@ func (pool *TxPool) Pending() (map[common.Address]types.Transactions, error) {
 	return pending, nil
 }
 
+// Locals retrieves the accounts currently considered local by the pool.
+func (pool *TxPool) Locals() []common.Address {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	return pool.locals.flatten()
+}
+
 // local retrieves all currently known local transactions, groupped by origin
 // account and sorted by nonce. The returned transaction set is a copy and can be
 // freely modified by calling code.
@@ -546,6 +599,24 @@ func (pool *TxPool) local() map[common.Address]types.Transactions {
 	return txs
 }
 
+// remote retrieves all currently known remote transactions, grouped by origin
+// account and sorted by nonce. The returned transaction set is a copy and can be
+// freely modified by calling code.
+func (pool *TxPool) remote() map[common.Address]types.Transactions {
+	txs := make(map[common.Address]types.Transactions)
+	for addr, pending := range pool.pending {
+		if !pool.locals.contains(addr) {
+			txs[addr] = append(txs[addr], pending.Flatten()...)
+		}
+	}
+	for addr, queued := range pool.queue {
+		if !pool.locals.contains(addr) {
+			txs[addr] = append(txs[addr], queued.Flatten()...)
+		}
+	}
+	return txs
+}
+
 // validateTx checks whether a transaction is valid according to the consensus
 // rules and adheres to some heuristic limits of the local node (price and size).
 func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
@@ -577,11 +648,34 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 		return ErrNonceTooLow
 	}
 	// Transactor should have enough funds to cover the costs
-	// cost == V + GP * GL
-	if pool.currentState.GetBalance(from).Cmp(tx.Cost()) < 0 {
+	// cost == V + GP * GL, or V alone if sponsored
+	if pool.currentState.GetBalance(from).Cmp(tx.SenderCost()) < 0 {
 		return ErrInsufficientFunds
 	}
-	intrGas, err := IntrinsicGas(tx.Data(), tx.To() == nil)
+	// Sponsored transactions need the fork and a fee payer affording the gas
+	if tx.FeePayer() != nil {
+		next := new(big.Int).Add(pool.chain.CurrentBlock().Number(), big.NewInt(1))
+		if !pool.chainconfig.IsFeeDelegation(next) {
+			return ErrFeeDelegationInactive
+		}
+		payer, err := types.FeePayer(pool.signer, tx)
+		if err != nil {
+			return ErrInvalidFeePayer
+		}
+		// The payer must afford the gas of all its pooled sponsored transactions,
+		// excluding the one this transaction would replace
+		fees := new(big.Int).Add(pool.all.PayerFees(payer), sponsorFee(tx))
+		if old := pool.pooled(from, tx.Nonce()); old != nil && old.FeePayer() != nil && *old.FeePayer() == payer {
+			fees.Sub(fees, sponsorFee(old))
+		}
+		if payer == from {
+			fees.Add(fees, tx.Value())
+		}
+		if pool.currentState.GetBalance(payer).Cmp(fees) < 0 {
+			return ErrInsufficientFeePayerFunds
+		}
+	}
+	intrGas, err := IntrinsicGas(tx.Data(), tx.To() == nil, tx.FeePayer() != nil)
 	if err != nil {
 		return err
 	}
@@ -591,6 +685,20 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	return nil
 }
 
+// pooled returns the pending or queued transaction of the given sender with the
+// given nonce, or nil if there is none.
+func (pool *TxPool) pooled(from common.Address, nonce uint64) *types.Transaction {
+	if list := pool.pending[from]; list != nil {
+		if tx := list.txs.Get(nonce); tx != nil {
+			return tx
+		}
+	}
+	if list := pool.queue[from]; list != nil {
+		return list.txs.Get(nonce)
+	}
+	return nil
+}
+
 // add validates a transaction and inserts it into the non-executable queue for
 // later pending promotion and execution. If the transaction is a replacement for
 // an already pending or queued one, it overwrites the previous and returns this
@@ -612,6 +720,10 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 		invalidTxCounter.Inc(1)
 		return false, err
 	}
+	// The account may be local even if the transaction arrived from the network
+	from, _ := types.Sender(pool.signer, tx) // already validated
+	local = local || pool.locals.contains(from)
+
 	// If the transaction pool is full, discard underpriced transactions
 	if uint64(pool.all.Count()) >= pool.config.GlobalSlots+pool.config.GlobalQueue {
 		// If the new transaction is underpriced, don't accept it
@@ -629,7 +741,6 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (bool, error) {
 		}
 	}
 	// If the transaction is replacing an already pending one, do directly
-	from, _ := types.Sender(pool.signer, tx) // already validated
 	if list := pool.pending[from]; list != nil && list.Overlaps(tx) {
 		// Nonce already pending, check if required price bump is met
 		inserted, old := list.Add(tx, pool.config.PriceBump)
@@ -698,14 +809,24 @@ func (pool *TxPool) enqueueTx(hash common.Hash, tx *types.Transaction) (bool, er
 }
 
 // journalTx adds the specified transaction to the local disk journal if it is
-// deemed to have been sent from a local account.
+// deemed to have been sent from a local account, or to the remote one if remote
+// transactions are journaled too.
 func (pool *TxPool) journalTx(from common.Address, tx *types.Transaction) {
-	// Only journal if it's enabled and the transaction is local
-	if pool.journal == nil || !pool.locals.contains(from) {
+	if pool.locals.contains(from) {
+		// Only journal if it's enabled and the transaction is local
+		if pool.journal == nil {
+			return
+		}
+		if err := pool.journal.insert(tx); err != nil {
+			log.Warn("Failed to journal local transaction", "err", err)
+		}
 		return
 	}
-	if err := pool.journal.insert(tx); err != nil {
-		log.Warn("Failed to journal local transaction", "err", err)
+	if pool.remoteJournal == nil {
+		return
+	}
+	if err := pool.remoteJournal.insert(tx); err != nil {
+		log.Warn("Failed to journal remote transaction", "err", err)
 	}
 }
 
@@ -1117,6 +1238,31 @@ func (pool *TxPool) demoteUnexecutables() {
 			delete(pool.beats, addr)
 		}
 	}
+	// Drop sponsored transactions whose fee payer can't afford all of them anymore
+	pool.dropUnfundedSponsored()
+}
+
+// dropUnfundedSponsored removes sponsored transactions, highest nonces first,
+// until every fee payer affords the gas of all its pooled ones. Otherwise they
+// would fail at block production, blocking their senders' nonces forever.
+func (pool *TxPool) dropUnfundedSponsored() {
+	for payer, fees := range pool.all.Payers() {
+		balance := pool.currentState.GetBalance(payer)
+		if fees.Cmp(balance) <= 0 {
+			continue
+		}
+		txs := pool.all.Sponsored(payer)
+		sort.Sort(sort.Reverse(types.TxByNonce(txs)))
+
+		for _, tx := range txs {
+			if pool.all.PayerFees(payer).Cmp(balance) <= 0 {
+				break
+			}
+			log.Trace("Removed unfunded sponsored transaction", "hash", tx.Hash(), "payer", payer)
+			pool.removeTx(tx.Hash(), true)
+			pendingNofundsCounter.Inc(1)
+		}
+	}
 }
 
 // addressByHeartbeat is an account address tagged with its last activity timestamp.
@@ -1167,6 +1313,15 @@ func (as *accountSet) add(addr common.Address) {
 	as.accounts[addr] = struct{}{}
 }
 
+// flatten returns the list of addresses within this set.
+func (as *accountSet) flatten() []common.Address {
+	accounts := make([]common.Address, 0, len(as.accounts))
+	for account := range as.accounts {
+		accounts = append(accounts, account)
+	}
+	return accounts
+}
+
 // txLookup is used internally by TxPool to track transactions while allowing lookup without
 // mutex contention.
 //
@@ -1177,17 +1332,24 @@ func (as *accountSet) add(addr common.Address) {
 // peeking into the pool in TxPool.Get without having to acquire the widely scoped
 // TxPool.mu mutex.
 type txLookup struct {
-	all  map[common.Hash]*types.Transaction
-	lock sync.RWMutex
+	all    map[common.Hash]*types.Transaction
+	payers map[common.Address]*big.Int // Gas fees of the sponsored transactions by fee payer
+	lock   sync.RWMutex
 }
 
 // newTxLookup returns a new txLookup structure.
 func newTxLookup() *txLookup {
 	return &txLookup{
-		all: make(map[common.Hash]*types.Transaction),
+		all:    make(map[common.Hash]*types.Transaction),
+		payers: make(map[common.Address]*big.Int),
 	}
 }
 
+// sponsorFee returns the gas fee a sponsored transaction charges its fee payer.
+func sponsorFee(tx *types.Transaction) *big.Int {
+	return new(big.Int).Mul(tx.GasPrice(), new(big.Int).SetUint64(tx.Gas()))
+}
+
 // Range calls f on each key and value present in the map.
 func (t *txLookup) Range(f func(hash common.Hash, tx *types.Transaction) bool) {
 	t.lock.RLock()
@@ -1216,12 +1378,61 @@ func (t *txLookup) Count() int {
 	return len(t.all)
 }
 
+// PayerFees returns the gas fees of all the sponsored transactions paid by the
+// given fee payer.
+func (t *txLookup) PayerFees(payer common.Address) *big.Int {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if fees := t.payers[payer]; fees != nil {
+		return new(big.Int).Set(fees)
+	}
+	return new(big.Int)
+}
+
+// Payers returns the gas fees of the sponsored transactions of every fee payer.
+func (t *txLookup) Payers() map[common.Address]*big.Int {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	payers := make(map[common.Address]*big.Int, len(t.payers))
+	for payer, fees := range t.payers {
+		payers[payer] = new(big.Int).Set(fees)
+	}
+	return payers
+}
+
+// Sponsored returns the transactions paid by the given fee payer.
+func (t *txLookup) Sponsored(payer common.Address) types.Transactions {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	var txs types.Transactions
+	for _, tx := range t.all {
+		if p := tx.FeePayer(); p != nil && *p == payer {
+			txs = append(txs, tx)
+		}
+	}
+	return txs
+}
+
 // Add adds a transaction to the lookup.
 func (t *txLookup) Add(tx *types.Transaction) {
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
-	t.all[tx.Hash()] = tx
+	hash := tx.Hash()
+	if _, ok := t.all[hash]; ok {
+		return
+	}
+	t.all[hash] = tx
+	if payer := tx.FeePayer(); payer != nil {
+		fees := t.payers[*payer]
+		if fees == nil {
+			fees = new(big.Int)
+		}
+		t.payers[*payer] = fees.Add(fees, sponsorFee(tx))
+	}
 }
 
 // Remove removes a transaction from the lookup.
@@ -1229,5 +1440,14 @@ func (t *txLookup) Remove(hash common.Hash) {
 	t.lock.Lock()
 	defer t.lock.Unlock()
 
+	tx, ok := t.all[hash]
+	if !ok {
+		return
+	}
 	delete(t.all, hash)
+	if payer := tx.FeePayer(); payer != nil {
+		if fees := t.payers[*payer].Sub(t.payers[*payer], sponsorFee(tx)); fees.Sign() <= 0 {
+			delete(t.payers, *payer)
+		}
+	}
 }
@@ -23,6 +23,7 @@ import (
 	"math/big"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -259,6 +260,104 @@ func TestInvalidTransactions(t *testing.T) {
 	}
 }
 
+// Tests that sponsored transactions are only accepted with a valid fee payer
+// affording their gas, the sender only needing to afford the value.
+func TestSponsoredTransactions(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	payerKey, _ := crypto.GenerateKey()
+	payer := crypto.PubkeyToAddress(payerKey.PublicKey)
+	signer := types.NewHubbleSigner(big.NewInt(1))
+
+	tx, _ := types.SignTx(types.NewTransaction(0, common.Address{}, big.NewInt(100), 100000, big.NewInt(1), nil).WithFeePayer(payer), signer, key)
+	from, _ := deriveSender(tx)
+	pool.currentState.AddBalance(from, tx.Value())
+
+	if err := pool.AddRemote(tx); err != ErrInvalidFeePayer {
+		t.Errorf("unsigned fee payer error mismatch: have %v, want %v", err, ErrInvalidFeePayer)
+	}
+	tx, _ = types.SignFeePayerTx(tx, signer, payerKey)
+	if err := pool.AddRemote(tx); err != ErrInsufficientFeePayerFunds {
+		t.Errorf("poor fee payer error mismatch: have %v, want %v", err, ErrInsufficientFeePayerFunds)
+	}
+	pool.currentState.AddBalance(payer, new(big.Int).Mul(tx.GasPrice(), new(big.Int).SetUint64(tx.Gas())))
+	if err := pool.AddRemote(tx); err != nil {
+		t.Errorf("sponsored transaction rejected: %v", err)
+	}
+	// Sponsored transactions are rejected before the fork
+	config := *params.TestChainConfig
+	config.FeeDelegationBlock = nil
+
+	prefork := NewTxPool(testTxPoolConfig, &config, pool.chain)
+	defer prefork.Stop()
+
+	if err := prefork.AddRemote(tx); err != ErrFeeDelegationInactive {
+		t.Errorf("pre-fork error mismatch: have %v, want %v", err, ErrFeeDelegationInactive)
+	}
+}
+
+// Tests that a fee payer can't sponsor more pooled gas than it affords, and that
+// its sponsored transactions are dropped once it can't afford them anymore.
+func TestSponsoredTransactionPayerFunds(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupTxPool()
+	defer pool.Stop()
+
+	payerKey, _ := crypto.GenerateKey()
+	payer := crypto.PubkeyToAddress(payerKey.PublicKey)
+	signer := types.NewHubbleSigner(big.NewInt(1))
+
+	sponsored := func(nonce uint64, gasprice int64) *types.Transaction {
+		tx, _ := types.SignTx(types.NewTransaction(nonce, common.Address{}, big.NewInt(100), 100000, big.NewInt(gasprice), nil).WithFeePayer(payer), signer, key)
+		tx, _ = types.SignFeePayerTx(tx, signer, payerKey)
+		return tx
+	}
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	pool.currentState.AddBalance(from, big.NewInt(1000))
+	pool.currentState.AddBalance(payer, big.NewInt(200000))
+
+	if err := pool.AddRemote(sponsored(0, 1)); err != nil {
+		t.Fatalf("first sponsored transaction rejected: %v", err)
+	}
+	if err := pool.AddRemote(sponsored(1, 1)); err != nil {
+		t.Fatalf("second sponsored transaction rejected: %v", err)
+	}
+	if err := pool.AddRemote(sponsored(2, 1)); err != ErrInsufficientFeePayerFunds {
+		t.Errorf("over-committed fee payer error mismatch: have %v, want %v", err, ErrInsufficientFeePayerFunds)
+	}
+	// Replacing a sponsored transaction only counts the price difference
+	if err := pool.AddRemote(sponsored(1, 2)); err != ErrInsufficientFeePayerFunds {
+		t.Errorf("over-committed replacement error mismatch: have %v, want %v", err, ErrInsufficientFeePayerFunds)
+	}
+	pool.currentState.AddBalance(payer, big.NewInt(100000))
+	if err := pool.AddRemote(sponsored(1, 2)); err != nil {
+		t.Errorf("funded replacement rejected: %v", err)
+	}
+	if fees := pool.all.PayerFees(payer); fees.Cmp(big.NewInt(300000)) != 0 {
+		t.Errorf("fee payer fees mismatch: have %v, want %v", fees, 300000)
+	}
+	// Drain the fee payer and check that the highest nonces are dropped
+	pool.currentState.SubBalance(payer, big.NewInt(150000))
+	pool.lockedReset(nil, nil)
+
+	if pending, _ := pool.Stats(); pending != 1 {
+		t.Errorf("pending transaction mismatch: have %d, want %d", pending, 1)
+	}
+	if pool.pending[from].txs.Get(0) == nil {
+		t.Errorf("affordable sponsored transaction dropped")
+	}
+	if fees := pool.all.PayerFees(payer); fees.Cmp(big.NewInt(100000)) != 0 {
+		t.Errorf("fee payer fees mismatch: have %v, want %v", fees, 100000)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
 func TestTransactionQueue(t *testing.T) {
 	t.Parallel()
 
@@ -1313,6 +1412,57 @@ func TestTransactionPoolRepricingKeepsLocals(t *testing.T) {
 	validate()
 }
 
+// Tests that the transactions of accounts configured as locals are treated as
+// local ones even if they arrive from the network.
+func TestTransactionPoolConfiguredLocals(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(vntdb.NewMemDatabase()))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	local, _ := crypto.GenerateKey()
+	remote, _ := crypto.GenerateKey()
+
+	config := testTxPoolConfig
+	config.PriceLimit = 2
+	config.GlobalSlots = 1
+	config.GlobalQueue = 1
+	config.Locals = []common.Address{crypto.PubkeyToAddress(local.PublicKey)}
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(local.PublicKey), big.NewInt(1000000))
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(remote.PublicKey), big.NewInt(1000000))
+
+	// Underpriced transactions are only accepted from the configured accounts
+	if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(1), local)); err != nil {
+		t.Fatalf("failed to add underpriced transaction of local account: %v", err)
+	}
+	if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(1), remote)); err != ErrUnderpriced {
+		t.Fatalf("remote underpriced error mismatch: have %v, want %v", err, ErrUnderpriced)
+	}
+	if have := len(pool.local()); have != 1 {
+		t.Fatalf("local account count mismatch: have %d, want %d", have, 1)
+	}
+	if locals := pool.Locals(); len(locals) != 1 || locals[0] != crypto.PubkeyToAddress(local.PublicKey) {
+		t.Fatalf("locals mismatch: have %x, want %x", locals, crypto.PubkeyToAddress(local.PublicKey))
+	}
+	// A full pool still accepts them, evicting remote transactions instead
+	if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(3), remote)); err != nil {
+		t.Fatalf("failed to add remote transaction: %v", err)
+	}
+	if err := pool.AddRemote(pricedTransaction(1, 100000, big.NewInt(1), local)); err != nil {
+		t.Fatalf("failed to add underpriced transaction of local account to full pool: %v", err)
+	}
+	if pending, _ := pool.Stats(); pending != 2 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 2)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
 // Tests that when the pool reaches its global transaction limit, underpriced
 // transactions are gradually shifted out for more expensive ones and any gapped
 // pending transactions are moved into the queue.
@@ -1680,6 +1830,69 @@ func testTransactionJournaling(t *testing.T, nolocals bool) {
 	pool.Stop()
 }
 
+// Tests that remote transactions survive a restart if they are journaled too,
+// and that they are reloaded as remote ones.
+func TestTransactionJournalingRemotes(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "txjournal")
+	if err != nil {
+		t.Fatalf("failed to create temporary journal directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(vntdb.NewMemDatabase()))
+	blockchain := &testBlockChain{statedb, 1000000, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.Journal = filepath.Join(dir, "transactions.rlp")
+	config.JournalRemotes = true
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+
+	local, _ := crypto.GenerateKey()
+	remote, _ := crypto.GenerateKey()
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(local.PublicKey), big.NewInt(1000000000))
+	pool.currentState.AddBalance(crypto.PubkeyToAddress(remote.PublicKey), big.NewInt(1000000000))
+
+	// Add a local and a pending and a queued remote transaction
+	if err := pool.AddLocal(pricedTransaction(0, 100000, big.NewInt(1), local)); err != nil {
+		t.Fatalf("failed to add local transaction: %v", err)
+	}
+	if err := pool.AddRemote(pricedTransaction(0, 100000, big.NewInt(1), remote)); err != nil {
+		t.Fatalf("failed to add remote transaction: %v", err)
+	}
+	if err := pool.AddRemote(pricedTransaction(2, 100000, big.NewInt(1), remote)); err != nil {
+		t.Fatalf("failed to add remote transaction: %v", err)
+	}
+	pool.Stop()
+
+	if _, err := os.Stat(filepath.Join(dir, "transactions.remotes.rlp")); err != nil {
+		t.Fatalf("remote journal missing: %v", err)
+	}
+	// Restart the pool and ensure all transactions survived, the remote ones still remote
+	blockchain = &testBlockChain{statedb, 1000000, new(event.Feed)}
+	pool = NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	pending, queued := pool.Stats()
+	if pending != 2 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 2)
+	}
+	if queued != 1 {
+		t.Fatalf("queued transactions mismatched: have %d, want %d", queued, 1)
+	}
+	if pool.locals.contains(crypto.PubkeyToAddress(remote.PublicKey)) {
+		t.Fatalf("remote account reloaded as local")
+	}
+	if remotes := pool.remote(); len(remotes[crypto.PubkeyToAddress(remote.PublicKey)]) != 2 {
+		t.Fatalf("remote transactions mismatched: have %d, want %d", len(remotes[crypto.PubkeyToAddress(remote.PublicKey)]), 2)
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
 // TestTransactionStatusCheck tests that the pool can correctly retrieve the
 // pending status of individual transactions.
 func TestTransactionStatusCheck(t *testing.T) {
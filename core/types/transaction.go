@@ -33,8 +33,20 @@ import (
 
 var (
 	ErrInvalidSig = errors.New("invalid transaction v, r, s values")
+
+	// ErrNotSponsored is returned when retrieving the fee payer of a transaction
+	// paying for its own gas.
+	ErrNotSponsored = errors.New("transaction not sponsored")
+
+	// ErrInvalidFeePayer is returned if the fee payer signature of a sponsored
+	// transaction is missing or doesn't match its declared fee payer.
+	ErrInvalidFeePayer = errors.New("invalid fee payer signature")
 )
 
+// sponsoredTxFields is the number of fields in the consensus encoding of
+// sponsored transactions, telling them apart from plain ones.
+const sponsoredTxFields = 13
+
 // deriveSigner makes a *best* guess about which signer to use.
 func deriveSigner(V *big.Int) Signer {
 	return NewHubbleSigner(deriveChainId(V))
@@ -43,9 +55,10 @@ func deriveSigner(V *big.Int) Signer {
 type Transaction struct {
 	data txdata
 	// caches
-	hash atomic.Value
-	size atomic.Value
-	from atomic.Value
+	hash  atomic.Value
+	size  atomic.Value
+	from  atomic.Value
+	payer atomic.Value
 }
 
 type txdata struct {
@@ -61,6 +74,13 @@ type txdata struct {
 	R *big.Int `json:"r" gencodec:"required"`
 	S *big.Int `json:"s" gencodec:"required"`
 
+	// Fee payer covering the gas of sponsored transactions, nil for plain ones,
+	// along with its signature values. They are only encoded if set.
+	Payer  *common.Address `json:"feePayer,omitempty"  rlp:"-"`
+	PayerV *big.Int        `json:"feePayerV,omitempty" rlp:"-"`
+	PayerR *big.Int        `json:"feePayerR,omitempty" rlp:"-"`
+	PayerS *big.Int        `json:"feePayerS,omitempty" rlp:"-"`
+
 	// This is only used when marshaling to JSON.
 	Hash *common.Hash `json:"hash" rlp:"-"`
 }
@@ -74,6 +94,24 @@ type txdataMarshaling struct {
 	V            *hexutil.Big
 	R            *hexutil.Big
 	S            *hexutil.Big
+	PayerV       *hexutil.Big
+	PayerR       *hexutil.Big
+	PayerS       *hexutil.Big
+}
+
+// sponsoredTxdata is the consensus encoding of sponsored transactions, which
+// extends the plain one with the fee payer and its signature.
+type sponsoredTxdata struct {
+	AccountNonce uint64
+	Price        *big.Int
+	GasLimit     uint64
+	Recipient    *common.Address `rlp:"nil"`
+	Amount       *big.Int
+	Payload      []byte
+	V, R, S      *big.Int
+
+	Payer                  common.Address
+	PayerV, PayerR, PayerS *big.Int
 }
 
 func NewTransaction(nonce uint64, to common.Address, amount *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte) *Transaction {
@@ -130,18 +168,67 @@ func isProtectedV(V *big.Int) bool {
 
 // EncodeRLP implements rlp.Encoder
 func (tx *Transaction) EncodeRLP(w io.Writer) error {
-	return rlp.Encode(w, &tx.data)
+	if tx.data.Payer == nil {
+		return rlp.Encode(w, &tx.data)
+	}
+	return rlp.Encode(w, &sponsoredTxdata{
+		AccountNonce: tx.data.AccountNonce,
+		Price:        tx.data.Price,
+		GasLimit:     tx.data.GasLimit,
+		Recipient:    tx.data.Recipient,
+		Amount:       tx.data.Amount,
+		Payload:      tx.data.Payload,
+		V:            tx.data.V,
+		R:            tx.data.R,
+		S:            tx.data.S,
+		Payer:        *tx.data.Payer,
+		PayerV:       tx.data.PayerV,
+		PayerR:       tx.data.PayerR,
+		PayerS:       tx.data.PayerS,
+	})
 }
 
 // DecodeRLP implements rlp.Decoder
 func (tx *Transaction) DecodeRLP(s *rlp.Stream) error {
-	_, size, _ := s.Kind()
-	err := s.Decode(&tx.data)
-	if err == nil {
-		tx.size.Store(common.StorageSize(rlp.ListSize(size)))
+	raw, err := s.Raw()
+	if err != nil {
+		return err
 	}
-
-	return err
+	content, _, err := rlp.SplitList(raw)
+	if err != nil {
+		return err
+	}
+	fields, err := rlp.CountValues(content)
+	if err != nil {
+		return err
+	}
+	if fields != sponsoredTxFields {
+		if err := rlp.DecodeBytes(raw, &tx.data); err != nil {
+			return err
+		}
+	} else {
+		var dec sponsoredTxdata
+		if err := rlp.DecodeBytes(raw, &dec); err != nil {
+			return err
+		}
+		tx.data = txdata{
+			AccountNonce: dec.AccountNonce,
+			Price:        dec.Price,
+			GasLimit:     dec.GasLimit,
+			Recipient:    dec.Recipient,
+			Amount:       dec.Amount,
+			Payload:      dec.Payload,
+			V:            dec.V,
+			R:            dec.R,
+			S:            dec.S,
+			Payer:        &dec.Payer,
+			PayerV:       dec.PayerV,
+			PayerR:       dec.PayerR,
+			PayerS:       dec.PayerS,
+		}
+	}
+	tx.size.Store(common.StorageSize(len(raw)))
+	return nil
 }
 
 // MarshalJSON encodes the web3 RPC transaction format.
@@ -164,6 +251,9 @@ func (tx *Transaction) UnmarshalJSON(input []byte) error {
 	if !crypto.ValidateSignatureValues(V, dec.R, dec.S, false) {
 		return ErrInvalidSig
 	}
+	if dec.Payer != nil && (dec.PayerV == nil || dec.PayerR == nil || dec.PayerS == nil) {
+		dec.PayerV, dec.PayerR, dec.PayerS = new(big.Int), new(big.Int), new(big.Int)
+	}
 	*tx = Transaction{data: dec}
 	return nil
 }
@@ -185,6 +275,16 @@ func (tx *Transaction) To() *common.Address {
 	return &to
 }
 
+// FeePayer returns the account declared to pay for the gas of a sponsored
+// transaction, or nil if the sender pays for it.
+func (tx *Transaction) FeePayer() *common.Address {
+	if tx.data.Payer == nil {
+		return nil
+	}
+	payer := *tx.data.Payer
+	return &payer
+}
+
 // Hash hashes the RLP encoding of tx.
 // It uniquely identifies the transaction.
 func (tx *Transaction) Hash() common.Hash {
@@ -203,7 +303,7 @@ func (tx *Transaction) Size() common.StorageSize {
 		return size.(common.StorageSize)
 	}
 	c := writeCounter(0)
-	rlp.Encode(&c, &tx.data)
+	rlp.Encode(&c, tx)
 	tx.size.Store(common.StorageSize(c))
 	return common.StorageSize(c)
 }
@@ -225,7 +325,14 @@ func (tx *Transaction) AsMessage(s Signer) (Message, error) {
 	}
 
 	var err error
-	msg.from, err = Sender(s, tx)
+	if msg.from, err = Sender(s, tx); err != nil {
+		return msg, err
+	}
+	msg.payer = msg.from
+	msg.sponsored = tx.data.Payer != nil
+	if tx.data.Payer != nil {
+		msg.payer, err = FeePayer(s, tx)
+	}
 	return msg, err
 }
 
@@ -241,6 +348,31 @@ func (tx *Transaction) WithSignature(signer Signer, sig []byte) (*Transaction, e
 	return cpy, nil
 }
 
+// WithFeePayer returns an unsigned copy of the transaction, sponsored by the
+// given fee payer. It is to be signed by the sender first, then by the payer.
+func (tx *Transaction) WithFeePayer(payer common.Address) *Transaction {
+	cpy := &Transaction{data: tx.data}
+	cpy.data.V, cpy.data.R, cpy.data.S = new(big.Int), new(big.Int), new(big.Int)
+	cpy.data.Payer = &payer
+	cpy.data.PayerV, cpy.data.PayerR, cpy.data.PayerS = new(big.Int), new(big.Int), new(big.Int)
+	return cpy
+}
+
+// WithFeePayerSignature returns a new sponsored transaction with the given fee
+// payer signature, formatted like the sender one.
+func (tx *Transaction) WithFeePayerSignature(signer Signer, sig []byte) (*Transaction, error) {
+	if tx.data.Payer == nil {
+		return nil, ErrNotSponsored
+	}
+	r, s, v, err := signer.SignatureValues(tx, sig)
+	if err != nil {
+		return nil, err
+	}
+	cpy := &Transaction{data: tx.data}
+	cpy.data.PayerR, cpy.data.PayerS, cpy.data.PayerV = r, s, v
+	return cpy, nil
+}
+
 // Cost returns amount + gasprice * gaslimit.
 func (tx *Transaction) Cost() *big.Int {
 	total := new(big.Int).Mul(tx.data.Price, new(big.Int).SetUint64(tx.data.GasLimit))
@@ -248,10 +380,26 @@ func (tx *Transaction) Cost() *big.Int {
 	return total
 }
 
+// SenderCost returns the part of the cost paid by the sender, which is the
+// amount alone for sponsored transactions, their gas being paid by the fee
+// payer, and the full cost otherwise.
+func (tx *Transaction) SenderCost() *big.Int {
+	if tx.data.Payer == nil {
+		return tx.Cost()
+	}
+	return new(big.Int).Set(tx.data.Amount)
+}
+
 func (tx *Transaction) RawSignatureValues() (*big.Int, *big.Int, *big.Int) {
 	return tx.data.V, tx.data.R, tx.data.S
 }
 
+// RawFeePayerSignatureValues returns the fee payer signature values of a
+// sponsored transaction, or nils for plain ones.
+func (tx *Transaction) RawFeePayerSignatureValues() (*big.Int, *big.Int, *big.Int) {
+	return tx.data.PayerV, tx.data.PayerR, tx.data.PayerS
+}
+
 // Transactions is a Transaction slice type for basic sorting.
 type Transactions []*Transaction
 
@@ -382,6 +530,8 @@ func (t *TransactionsByPriceAndNonce) Pop() {
 type Message struct {
 	to         *common.Address
 	from       common.Address
+	payer      common.Address
+	sponsored  bool
 	nonce      uint64
 	amount     *big.Int
 	gasLimit   uint64
@@ -393,6 +543,7 @@ type Message struct {
 func NewMessage(from common.Address, to *common.Address, nonce uint64, amount *big.Int, gasLimit uint64, gasPrice *big.Int, data []byte, checkNonce bool) Message {
 	return Message{
 		from:       from,
+		payer:      from,
 		to:         to,
 		nonce:      nonce,
 		amount:     amount,
@@ -403,11 +554,13 @@ func NewMessage(from common.Address, to *common.Address, nonce uint64, amount *b
 	}
 }
 
-func (m Message) From() common.Address { return m.from }
-func (m Message) To() *common.Address  { return m.to }
-func (m Message) GasPrice() *big.Int   { return m.gasPrice }
-func (m Message) Value() *big.Int      { return m.amount }
-func (m Message) Gas() uint64          { return m.gasLimit }
-func (m Message) Nonce() uint64        { return m.nonce }
-func (m Message) Data() []byte         { return m.data }
-func (m Message) CheckNonce() bool     { return m.checkNonce }
+func (m Message) From() common.Address  { return m.from }
+func (m Message) Payer() common.Address { return m.payer }
+func (m Message) Sponsored() bool       { return m.sponsored }
+func (m Message) To() *common.Address   { return m.to }
+func (m Message) GasPrice() *big.Int    { return m.gasPrice }
+func (m Message) Value() *big.Int       { return m.amount }
+func (m Message) Gas() uint64           { return m.gasLimit }
+func (m Message) Nonce() uint64         { return m.nonce }
+func (m Message) Data() []byte          { return m.data }
+func (m Message) CheckNonce() bool      { return m.checkNonce }
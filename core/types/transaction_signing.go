@@ -88,6 +88,41 @@ func Sender(signer Signer, tx *Transaction) (common.Address, error) {
 	return addr, nil
 }
 
+// CacheSender primes the sender cache of a transaction with an address already
+// derived by the given signer, sparing Sender the recovery of the signature. It
+// must only be fed addresses previously returned by Sender for the same hash.
+func CacheSender(signer Signer, tx *Transaction, addr common.Address) {
+	tx.from.Store(sigCache{signer: signer, from: addr})
+}
+
+// SignFeePayerTx signs a sponsored transaction as its fee payer, which is to be
+// done after the sender signed it, as the fee payer signature covers it.
+func SignFeePayerTx(tx *Transaction, s Signer, prv *ecdsa.PrivateKey) (*Transaction, error) {
+	h := s.FeePayerHash(tx)
+	sig, err := crypto.Sign(h[:], prv)
+	if err != nil {
+		return nil, err
+	}
+	return tx.WithFeePayerSignature(s, sig)
+}
+
+// FeePayer returns the address paying for the gas of a sponsored transaction,
+// verified against its fee payer signature. Like Sender, it caches the address.
+func FeePayer(signer Signer, tx *Transaction) (common.Address, error) {
+	if sc := tx.payer.Load(); sc != nil {
+		sigCache := sc.(sigCache)
+		if sigCache.signer.Equal(signer) {
+			return sigCache.from, nil
+		}
+	}
+	addr, err := signer.FeePayer(tx)
+	if err != nil {
+		return common.Address{}, err
+	}
+	tx.payer.Store(sigCache{signer: signer, from: addr})
+	return addr, nil
+}
+
 // Signer encapsulates transaction signature handling. Note that this interface is not a
 // stable API and may change at any time to accommodate new protocol rules.
 type Signer interface {
@@ -98,6 +133,10 @@ type Signer interface {
 	SignatureValues(tx *Transaction, sig []byte) (r, s, v *big.Int, err error)
 	// Hash returns the hash to be signed.
 	Hash(tx *Transaction) common.Hash
+	// FeePayer returns the fee payer address of a sponsored transaction.
+	FeePayer(tx *Transaction) (common.Address, error)
+	// FeePayerHash returns the hash to be signed by the fee payer.
+	FeePayerHash(tx *Transaction) common.Hash
 	// Equal returns true if the given signer is the same as the receiver.
 	Equal(Signer) bool
 }
@@ -132,6 +171,30 @@ func (s HubbleSigner) Sender(tx *Transaction) (common.Address, error) {
 	return recoverPlain(s.Hash(tx), tx.data.R, tx.data.S, V, true)
 }
 
+// FeePayer recovers the fee payer of a sponsored transaction from its fee payer
+// signature, ensuring it is the one declared by the sender.
+func (s HubbleSigner) FeePayer(tx *Transaction) (common.Address, error) {
+	if tx.data.Payer == nil {
+		return common.Address{}, ErrNotSponsored
+	}
+	if tx.data.PayerV == nil || tx.data.PayerV.Sign() == 0 {
+		return common.Address{}, ErrInvalidFeePayer
+	}
+	if deriveChainId(tx.data.PayerV).Cmp(s.chainId) != 0 {
+		return common.Address{}, ErrInvalidChainId
+	}
+	V := new(big.Int).Sub(tx.data.PayerV, s.chainIdMul)
+	V.Sub(V, big8)
+	addr, err := recoverPlain(s.FeePayerHash(tx), tx.data.PayerR, tx.data.PayerS, V, true)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if addr != *tx.data.Payer {
+		return common.Address{}, ErrInvalidFeePayer
+	}
+	return addr, nil
+}
+
 // WithSignature returns a new transaction with the given signature. This signature
 // needs to be in the [R || S || V] format where V is 0 or 1.
 func (s HubbleSigner) SignatureValues(tx *Transaction, sig []byte) (R, S, V *big.Int, err error) {
@@ -146,7 +209,37 @@ func (s HubbleSigner) SignatureValues(tx *Transaction, sig []byte) (R, S, V *big
 
 // Hash returns the hash to be signed by the sender.
 // It does not uniquely identify the transaction.
+//
+// The hash of sponsored transactions covers their fee payer too, so that they
+// can't be turned into plain ones charging the sender for the gas.
 func (s HubbleSigner) Hash(tx *Transaction) common.Hash {
+	if tx.data.Payer != nil {
+		return rlpHash([]interface{}{
+			tx.data.AccountNonce,
+			tx.data.Price,
+			tx.data.GasLimit,
+			tx.data.Recipient,
+			tx.data.Amount,
+			tx.data.Payload,
+			*tx.data.Payer,
+			s.chainId, uint(0), uint(0),
+		})
+	}
+	return rlpHash([]interface{}{
+		tx.data.AccountNonce,
+		tx.data.Price,
+		tx.data.GasLimit,
+		tx.data.Recipient,
+		tx.data.Amount,
+		tx.data.Payload,
+		s.chainId, uint(0), uint(0),
+	})
+}
+
+// FeePayerHash returns the hash to be signed by the fee payer of a sponsored
+// transaction, which covers the sender signature so that the payer only pays
+// for the transaction of the intended sender.
+func (s HubbleSigner) FeePayerHash(tx *Transaction) common.Hash {
 	return rlpHash([]interface{}{
 		tx.data.AccountNonce,
 		tx.data.Price,
@@ -154,6 +247,8 @@ func (s HubbleSigner) Hash(tx *Transaction) common.Hash {
 		tx.data.Recipient,
 		tx.data.Amount,
 		tx.data.Payload,
+		tx.data.Payer,
+		tx.data.V, tx.data.R, tx.data.S,
 		s.chainId, uint(0), uint(0),
 	})
 }
@@ -189,6 +284,16 @@ func (hs HomesteadSigner) Hash(tx *Transaction) common.Hash {
 	})
 }
 
+// FeePayer is not supported by homestead, which predates sponsored transactions.
+func (hs HomesteadSigner) FeePayer(tx *Transaction) (common.Address, error) {
+	return common.Address{}, ErrNotSponsored
+}
+
+// FeePayerHash is not supported by homestead, which predates sponsored transactions.
+func (hs HomesteadSigner) FeePayerHash(tx *Transaction) common.Hash {
+	return common.Hash{}
+}
+
 func signatureValues(tx *Transaction, sig []byte) (r, s, v *big.Int, err error) {
 	if len(sig) != 65 {
 		panic(fmt.Sprintf("wrong size for signature: got %d, want 65", len(sig)))
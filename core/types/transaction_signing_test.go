@@ -129,3 +129,80 @@ func TestChainId(t *testing.T) {
 		t.Error("expected no error")
 	}
 }
+
+// Tests that sponsored transactions are signed by both their sender and fee
+// payer, and that the signatures survive encoding.
+func TestSponsoredSigning(t *testing.T) {
+	senderKey, _ := crypto.GenerateKey()
+	payerKey, _ := crypto.GenerateKey()
+	sender := crypto.PubkeyToAddress(senderKey.PublicKey)
+	payer := crypto.PubkeyToAddress(payerKey.PublicKey)
+
+	signer := NewHubbleSigner(big.NewInt(18))
+	plain := NewTransaction(0, common.Address{0x01}, big.NewInt(1), 21000, big.NewInt(1), nil)
+	if signer.Hash(plain) == signer.Hash(plain.WithFeePayer(payer)) {
+		t.Fatalf("sponsored sender hash matches plain one")
+	}
+	tx, err := SignTx(plain.WithFeePayer(payer), signer, senderKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := FeePayer(signer, tx); err != ErrInvalidFeePayer {
+		t.Errorf("unsigned fee payer error mismatch: have %v, want %v", err, ErrInvalidFeePayer)
+	}
+	tx, err = SignFeePayerTx(tx, signer, payerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Round trip the transaction and check both signers
+	enc, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec := new(Transaction)
+	if err := rlp.DecodeBytes(enc, dec); err != nil {
+		t.Fatal(err)
+	}
+	if dec.Hash() != tx.Hash() {
+		t.Errorf("hash mismatch: have %x, want %x", dec.Hash(), tx.Hash())
+	}
+	if from, err := Sender(signer, dec); err != nil || from != sender {
+		t.Errorf("sender mismatch: have %x (%v), want %x", from, err, sender)
+	}
+	if from, err := FeePayer(signer, dec); err != nil || from != payer {
+		t.Errorf("fee payer mismatch: have %x (%v), want %x", from, err, payer)
+	}
+	if cost := dec.SenderCost(); cost.Cmp(big.NewInt(1)) != 0 {
+		t.Errorf("sender cost mismatch: have %v, want %v", cost, 1)
+	}
+	// A fee payer signing for another payer is rejected
+	forged, err := SignFeePayerTx(tx, signer, senderKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := FeePayer(signer, forged); err != ErrInvalidFeePayer {
+		t.Errorf("forged fee payer error mismatch: have %v, want %v", err, ErrInvalidFeePayer)
+	}
+	if _, err := FeePayer(signer, plain); err != ErrNotSponsored {
+		t.Errorf("plain fee payer error mismatch: have %v, want %v", err, ErrNotSponsored)
+	}
+}
+
+func TestCacheSender(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	signer := NewHubbleSigner(big.NewInt(18))
+	tx, err := SignTx(NewTransaction(0, common.Address{}, new(big.Int), 0, new(big.Int), nil), signer, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A primed sender is returned as is, without recovering the signature
+	primed := common.Address{0xaa}
+	CacheSender(signer, tx, primed)
+	if from, err := Sender(signer, tx); err != nil || from != primed {
+		t.Errorf("primed sender mismatch: have %x/%v, want %x", from, err, primed)
+	}
+	// Other signers still recover the actual sender
+	if from, _ := Sender(NewHubbleSigner(big.NewInt(19)), tx); from == primed {
+		t.Errorf("primed sender leaked to another signer")
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"reflect"
 	"sort"
 	"strings"
+	"time"
 	"unicode"
 
 	"github.com/pkg/errors"
@@ -31,6 +32,7 @@ import (
 	"github.com/vntchain/go-vnt/common"
 	inter "github.com/vntchain/go-vnt/core/vm/interface"
 	"github.com/vntchain/go-vnt/log"
+	"github.com/vntchain/go-vnt/params"
 	"github.com/vntchain/go-vnt/vntp2p"
 )
 
@@ -75,6 +77,16 @@ const AbiJSON = `[
 {"name":"extractOwnBounty","inputs":[],"outputs":[],"type":"function"}
 ]`
 
+// PackInput assembles the input of a transaction calling the given method of the
+// election contract with the given arguments.
+func PackInput(method string, args ...interface{}) ([]byte, error) {
+	electionABI, err := abi.JSON(strings.NewReader(AbiJSON))
+	if err != nil {
+		return nil, err
+	}
+	return electionABI.Pack(method, args...)
+}
+
 type Election struct{}
 
 type electionContext struct {
@@ -198,6 +210,12 @@ type Bounty struct {
 	RestTotalBounty *big.Int // 剩余总激励，初始值10亿VNT
 }
 
+// Liveness records the block production slots a witness missed in a row.
+type Liveness struct {
+	Owner       common.Address // 见证人地址
+	MissedSlots uint64         // 连续错过的出块次数
+}
+
 func newElectionContext(ctx inter.ChainContext) electionContext {
 	return electionContext{
 		context: ctx,
@@ -762,9 +780,10 @@ func (ec electionContext) prepareForVote(voter *Voter, address common.Address) (
 		voter.Owner = address
 		voter.TimeStamp = now
 	} else {
-		// 如果距离上次投票时间不足24小时，拒绝投票
-		if now.Cmp(voter.TimeStamp) < 0 || now.Cmp(new(big.Int).Add(voter.TimeStamp, big.NewInt(OneDay))) < 0 {
-			return nil, fmt.Errorf("it's less than 24h after your last vote or setProxy, lastTime: %v, now: %v", voter.TimeStamp, ec.context.GetTime())
+		// 如果距离上次投票时间不足投票间隔，拒绝投票
+		window := ec.votingWindow()
+		if now.Cmp(voter.TimeStamp) < 0 || now.Cmp(new(big.Int).Add(voter.TimeStamp, new(big.Int).SetUint64(window))) < 0 {
+			return nil, fmt.Errorf("it's less than %s after your last vote or setProxy, lastTime: %v, now: %v", formatWindow(window), voter.TimeStamp, ec.context.GetTime())
 		} else {
 			voter.TimeStamp = now
 		}
@@ -780,6 +799,30 @@ func (ec electionContext) prepareForVote(voter *Voter, address common.Address) (
 	return voteCount, nil
 }
 
+// configContext is a chain context exposing the chain configuration.
+type configContext interface {
+	ChainConfig() *params.ChainConfig
+}
+
+// votingWindow returns the number of seconds a voter has to wait between votes,
+// as configured for the chain.
+func (ec electionContext) votingWindow() uint64 {
+	if c, ok := ec.context.(configContext); ok {
+		if config := c.ChainConfig(); config != nil && config.Dpos != nil {
+			return config.Dpos.Window()
+		}
+	}
+	return params.DefaultVotingWindow
+}
+
+// formatWindow formats a voting window given in seconds for error messages.
+func formatWindow(window uint64) string {
+	if window%3600 == 0 {
+		return fmt.Sprintf("%dh", window/3600)
+	}
+	return (time.Duration(window) * time.Second).String()
+}
+
 func (ec electionContext) subVoteFromCandidates(voter *Voter) error {
 	lastVoteCount := new(big.Int).Set(voter.LastVoteCount)
 	if voter.ProxyVoteCount != nil && voter.ProxyVoteCount.Sign() > 0 {
@@ -827,6 +870,14 @@ func (ec electionContext) calculateVoteCount(stakeCount *big.Int) *big.Int {
 
 // GetFirstNCandidates get candidates with most votes as witness from specific stateDB
 func GetFirstNCandidates(stateDB inter.StateDB, witnessesNum int) ([]common.Address, []string) {
+	return GetFirstNLiveCandidates(stateDB, witnessesNum, 0)
+}
+
+// GetFirstNLiveCandidates get candidates with most votes as witness from specific
+// stateDB like GetFirstNCandidates, but candidates which have missed at least
+// maxMissedSlots consecutive production slots are only chosen when there are not
+// enough other valid candidates. Zero maxMissedSlots disables the penalty.
+func GetFirstNLiveCandidates(stateDB inter.StateDB, witnessesNum int, maxMissedSlots uint64) ([]common.Address, []string) {
 	var witnesses []common.Address
 	var urls []string
 	candidates := getAllCandidate(stateDB)
@@ -840,14 +891,26 @@ func GetFirstNCandidates(stateDB inter.StateDB, witnessesNum int) ([]common.Addr
 	}
 
 	candidates.Sort()
+	var penalized CandidateList
 	witnessSet := make(map[common.Address]struct{})
 	for i := 0; i < len(candidates) && len(witnesses) < witnessesNum; i++ {
 		if candidates[i].VoteCount.Cmp(big.NewInt(0)) >= 0 && candidates[i].Active {
+			if maxMissedSlots > 0 && GetMissedSlots(stateDB, candidates[i].Owner) >= maxMissedSlots {
+				penalized = append(penalized, candidates[i])
+				continue
+			}
 			witnesses = append(witnesses, candidates[i].Owner)
 			witnessSet[candidates[i].Owner] = struct{}{}
 			urls = append(urls, string(candidates[i].Url))
 		}
 	}
+	// Fill up with the penalized candidates rather than stall the witness update
+	for i := 0; i < len(penalized) && len(witnesses) < witnessesNum; i++ {
+		log.Debug("Choose penalized witness candidate", "addr", penalized[i].Owner)
+		witnesses = append(witnesses, penalized[i].Owner)
+		witnessSet[penalized[i].Owner] = struct{}{}
+		urls = append(urls, string(penalized[i].Url))
+	}
 	if len(witnessSet) != witnessesNum {
 		log.Warn("Valid witness candidates is too less. If you want to be a witness, please register now.", "num of valid candidates", len(witnessSet), "want", witnessesNum)
 		return nil, nil
@@ -895,6 +958,35 @@ func AddCandidatesBounty(stateDB inter.StateDB, bonus map[common.Address]*big.In
 	return nil
 }
 
+// GetMissedSlots returns the number of consecutive production slots missed by
+// the witness.
+func GetMissedSlots(stateDB inter.StateDB, addr common.Address) uint64 {
+	getFromDB := func(key common.Hash) common.Hash {
+		return stateDB.GetState(contractAddr, key)
+	}
+	return getLivenessFrom(addr, getFromDB).MissedSlots
+}
+
+// AddMissedSlots increases the number of consecutive production slots missed
+// by the witness.
+func AddMissedSlots(stateDB inter.StateDB, addr common.Address, missed uint64) error {
+	missed += GetMissedSlots(stateDB, addr)
+	return setLiveness(stateDB, Liveness{Owner: addr, MissedSlots: missed})
+}
+
+// ResetMissedSlots clears the missed production slots of the witnesses.
+func ResetMissedSlots(stateDB inter.StateDB, addrs []common.Address) error {
+	for _, addr := range addrs {
+		if GetMissedSlots(stateDB, addr) == 0 {
+			continue
+		}
+		if err := setLiveness(stateDB, Liveness{Owner: addr}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // GrantBounty grants VNT bounty. Returns an error, if RestTotalBounty is less
 // than grantAmount.
 func GrantBounty(stateDB inter.StateDB, grantAmount *big.Int) (*big.Int, error) {
@@ -27,6 +27,7 @@ import (
 	"github.com/vntchain/go-vnt/common"
 	"github.com/vntchain/go-vnt/core/state"
 	inter "github.com/vntchain/go-vnt/core/vm/interface"
+	"github.com/vntchain/go-vnt/params"
 	"github.com/vntchain/go-vnt/vntdb"
 )
 
@@ -1485,3 +1486,66 @@ func operate(c electionContext, op string, address common.Address, proxy common.
 	}
 	return err
 }
+
+// Tests that packed inputs are understood by the election contract.
+func TestPackInput(t *testing.T) {
+	var e Election
+	context := newcontext()
+	context.(*testContext).Origin = addr1
+	context.GetStateDb().AddBalance(addr1, big.NewInt(0).Mul(big.NewInt(100), big.NewInt(1e18)))
+
+	input, err := PackInput("registerWitness", candiInfo1.url, candiInfo1.website, candiInfo1.name)
+	if err != nil {
+		t.Fatalf("failed to pack registration: %v", err)
+	}
+	if _, err := e.Run(context, input); err != nil {
+		t.Fatalf("failed to register witness: %v", err)
+	}
+	if candidates := GetAllCandidates(context.GetStateDb(), false); len(candidates) != 1 || candidates[0].Owner != addr1 {
+		t.Errorf("candidates mismatch: have %v, want %x", candidates, addr1)
+	}
+	if input, err = PackInput("stake", big.NewInt(20)); err != nil {
+		t.Fatalf("failed to pack stake: %v", err)
+	}
+	if _, err := e.Run(context, input); err != nil {
+		t.Fatalf("failed to stake: %v", err)
+	}
+	if stake := GetStake(context.GetStateDb(), addr1); stake == nil || stake.StakeCount.Cmp(big.NewInt(20)) != 0 {
+		t.Errorf("stake mismatch: have %v, want %d", stake, 20)
+	}
+	if _, err := PackInput("unknown"); err == nil {
+		t.Errorf("unknown method packed")
+	}
+}
+
+// configTestContext is a test context exposing a chain configuration.
+type configTestContext struct {
+	testContext
+	config *params.ChainConfig
+}
+
+func (tc *configTestContext) ChainConfig() *params.ChainConfig { return tc.config }
+
+// Tests that voters can vote again once the configured voting window passed.
+func TestVotingWindow(t *testing.T) {
+	context := &configTestContext{
+		testContext: *newcontext().(*testContext),
+		config:      &params.ChainConfig{Dpos: &params.DposConfig{VotingWindow: 60}},
+	}
+	c := newElectionContext(context)
+	addr := context.Origin
+
+	voter := Voter{Owner: addr, TimeStamp: new(big.Int).Sub(context.Time, big.NewInt(30))}
+	c.setVoter(voter)
+	c.context.GetStateDb().AddBalance(addr, big.NewInt(1e18))
+	c.stake(addr, big.NewInt(1))
+
+	err := c.voteWitnesses(addr, candidates[:1])
+	if want := fmt.Sprintf("it's less than 1m0s after your last vote or setProxy, lastTime: %v, now: %v", voter.TimeStamp, context.Time); err == nil || err.Error() != want {
+		t.Fatalf("vote within window error mismatch: have %v, want %v", err, want)
+	}
+	context.SetTime(new(big.Int).Add(voter.TimeStamp, big.NewInt(60)))
+	if err := c.voteWitnesses(addr, candidates[:1]); err != nil {
+		t.Errorf("vote after window failed: %v", err)
+	}
+}
@@ -34,6 +34,7 @@ const (
 	CANDIDATEPREFIX = byte(1)
 	STAKEPREFIX     = byte(2)
 	BOUNTYPREFIX    = byte(3)
+	LIVENESSPREFIX  = byte(4)
 	PREFIXLENGTH    = 4 // key的结构为，4位表前缀，20位address，8位的value在struct中的位置
 )
 
@@ -423,3 +424,19 @@ func setRestBounty(stateDB inter.StateDB, restBounty Bounty) error {
 	}
 	return nil
 }
+
+func getLivenessFrom(addr common.Address, getFromDB func(key common.Hash) common.Hash) Liveness {
+	var liveness Liveness
+	if err := convertToStruct(LIVENESSPREFIX, addr, &liveness, getFromDB); err != nil {
+		log.Debug("Get Liveness From DB ", "addr", addr.String(), "err", err)
+		return Liveness{Owner: addr}
+	}
+	return liveness
+}
+
+func setLiveness(stateDB inter.StateDB, liveness Liveness) error {
+	setFn := func(key common.Hash, value common.Hash) {
+		stateDB.SetState(contractAddr, key, value)
+	}
+	return convertToKV(LIVENESSPREFIX, liveness, setFn)
+}
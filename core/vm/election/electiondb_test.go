@@ -539,6 +539,65 @@ func TestGetFirstXCandidates_4(t *testing.T) {
 	}
 }
 
+func TestGetFirstNLiveCandidates(t *testing.T) {
+	db := vntdb.NewMemDatabase()
+	stateDB, _ := state.New(common.Hash{}, state.NewDatabase(db))
+
+	ctx := testContext{StateDB: stateDB}
+	c := newElectionContext(&ctx)
+
+	baseAddr := candidate.Owner
+	addr := func(pre byte) common.Address {
+		can := baseAddr
+		can[0] = pre
+		return can
+	}
+	for i, votes := range []int64{200, 100, 50, 10, 5} {
+		candidate1 := candidate
+		candidate1.Owner = addr(byte(i + 1))
+		candidate1.VoteCount = big.NewInt(votes)
+		candidate1.Active = true
+		c.setCandidate(candidate1)
+	}
+	// Candidate 2 misses too many slots, candidate 3 not yet
+	AddMissedSlots(stateDB, addr(2), 3)
+	AddMissedSlots(stateDB, addr(3), 2)
+	if missed := GetMissedSlots(stateDB, addr(2)); missed != 3 {
+		t.Fatalf("missed slots mismatch: have %d, want %d", missed, 3)
+	}
+
+	tests := []struct {
+		witNum    int
+		maxMissed uint64
+		rets      []byte
+	}{
+		{3, 0, []byte{1, 2, 3}},
+		{3, 3, []byte{1, 3, 4}},
+		{3, 2, []byte{1, 4, 5}},
+		{4, 2, []byte{1, 4, 5, 2}},
+	}
+	for i, tt := range tests {
+		witsAddr, _ := GetFirstNLiveCandidates(stateDB, tt.witNum, tt.maxMissed)
+		if len(witsAddr) != len(tt.rets) {
+			t.Fatalf("test %d: length mismatch: have %d, want %d", i, len(witsAddr), len(tt.rets))
+		}
+		for j, pre := range tt.rets {
+			if witsAddr[j] != addr(pre) {
+				t.Errorf("test %d: witness %d mismatch: have %x, want %x", i, j, witsAddr[j], addr(pre))
+			}
+		}
+	}
+
+	// Resetting makes the candidates choosable again
+	ResetMissedSlots(stateDB, []common.Address{addr(2), addr(3)})
+	witsAddr, _ := GetFirstNLiveCandidates(stateDB, 3, 2)
+	for j, pre := range []byte{1, 2, 3} {
+		if witsAddr[j] != addr(pre) {
+			t.Errorf("witness %d mismatch after reset: have %x, want %x", j, witsAddr[j], addr(pre))
+		}
+	}
+}
+
 func TestAddCandidateBounty(t *testing.T) {
 	db := vntdb.NewMemDatabase()
 	stateDB, _ := state.New(common.Hash{}, state.NewDatabase(db))
@@ -125,14 +125,14 @@ func gasSStore(gt params.GasTable, evm *EVM, contract *Contract, stack *Stack, m
 	// 3. From a non-zero to a non-zero                         (CHANGE)
 	if val == (common.Hash{}) && y.Sign() != 0 {
 		// 0 => non 0
-		return params.SstoreSetGas, nil
+		return gt.SstoreSet, nil
 	} else if val != (common.Hash{}) && y.Sign() == 0 {
 		// non 0 => 0
-		evm.StateDB.AddRefund(params.SstoreRefundGas)
-		return params.SstoreClearGas, nil
+		evm.StateDB.AddRefund(gt.SstoreRefund)
+		return gt.SstoreClear, nil
 	} else {
 		// non 0 => non 0 (or 0 => 0)
-		return params.SstoreResetGas, nil
+		return gt.SstoreReset, nil
 	}
 }
 
@@ -35,6 +35,14 @@ type Config struct {
 	NoRecursion bool
 	// Enable recording of SHA3/keccak preimages
 	EnablePreimageRecording bool
+	// MemoryCap bounds the memory a single block's execution may
+	// allocate. Zero leaves the execution uncapped. The cap is a local
+	// setting rather than a consensus rule, so an imported block crossing
+	// it stalls the import without being marked bad or its peer dropped.
+	MemoryCap uint64
+	// MemoryMeter accounts the memory allocated by the block being
+	// processed. It is set up by the state processor if MemoryCap is set.
+	MemoryMeter *MemoryMeter
 	// JumpTable contains the EVM instruction table. This
 	// may be left uninitialised and will be set to the default
 	// table.
@@ -91,7 +99,7 @@ func (in *Interpreter) enforceRestrictions(op OpCode, operation operation, stack
 //
 // It's important to note that any errors returned by the interpreter should be
 // considered a revert-and-consume-all-gas operation except for
-// errExecutionReverted which means revert-and-keep-gas-left.
+// ErrExecutionReverted which means revert-and-keep-gas-left.
 func (in *Interpreter) Run(contract *Contract, input []byte) (ret []byte, err error) {
 	// Increment the call depth which is restricted to 1024
 	in.evm.depth++
@@ -203,7 +211,7 @@ func (in *Interpreter) Run(contract *Contract, input []byte) (ret []byte, err er
 		case err != nil:
 			return nil, err
 		case operation.reverts:
-			return res, errExecutionReverted
+			return res, ErrExecutionReverted
 		case operation.halts:
 			return res, nil
 		case !operation.jumps:
@@ -0,0 +1,55 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"sync/atomic"
+
+	"github.com/pkg/errors"
+)
+
+// MemoryMeter accounts the memory allocated by the contract executions of a
+// single block against an optional cap.
+type MemoryMeter struct {
+	cap  uint64 // Maximum number of bytes that may be allocated, 0 = uncapped
+	used uint64 // Number of bytes allocated so far
+}
+
+// NewMemoryMeter creates a memory meter enforcing the given cap in bytes.
+func NewMemoryMeter(cap uint64) *MemoryMeter {
+	return &MemoryMeter{cap: cap}
+}
+
+// Allocate charges size bytes against the meter, returning an error caused by
+// ErrMemoryCapExceeded if the total allocation exceeds the cap.
+func (m *MemoryMeter) Allocate(size uint64) error {
+	used := atomic.AddUint64(&m.used, size)
+	if m.cap > 0 && used > m.cap {
+		return errors.Wrapf(ErrMemoryCapExceeded, "allocated %d bytes, cap %d", used, m.cap)
+	}
+	return nil
+}
+
+// Used returns the number of bytes allocated so far.
+func (m *MemoryMeter) Used() uint64 {
+	return atomic.LoadUint64(&m.used)
+}
+
+// Exceeded reports whether the allocations went beyond the cap.
+func (m *MemoryMeter) Exceeded() bool {
+	return m.cap > 0 && m.Used() > m.cap
+}
@@ -0,0 +1,52 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import "testing"
+
+// Tests that the memory meter only fails once allocations cross the cap.
+func TestMemoryMeter(t *testing.T) {
+	meter := NewMemoryMeter(1024)
+	for i := 0; i < 4; i++ {
+		if err := meter.Allocate(256); err != nil {
+			t.Fatalf("allocation %d under the cap failed: %v", i, err)
+		}
+	}
+	if meter.Exceeded() {
+		t.Fatalf("meter exceeded at the cap")
+	}
+	if err := meter.Allocate(1); err == nil {
+		t.Fatalf("allocation over the cap accepted")
+	}
+	if !meter.Exceeded() {
+		t.Fatalf("meter not exceeded over the cap")
+	}
+	if used := meter.Used(); used != 1025 {
+		t.Fatalf("used memory mismatch: have %d, want %d", used, 1025)
+	}
+}
+
+// Tests that a zero cap leaves the meter uncapped.
+func TestMemoryMeterUncapped(t *testing.T) {
+	meter := NewMemoryMeter(0)
+	if err := meter.Allocate(1 << 40); err != nil {
+		t.Fatalf("uncapped allocation failed: %v", err)
+	}
+	if meter.Exceeded() {
+		t.Fatalf("uncapped meter exceeded")
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package wavm
+
+import (
+	"encoding/json"
+
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/vntchain/go-vnt/accounts/abi"
+	"github.com/vntchain/go-vnt/common"
+	wasmcontract "github.com/vntchain/go-vnt/core/wavm/contract"
+	"github.com/vntchain/go-vnt/core/wavm/gas"
+	"github.com/vntchain/go-vnt/core/wavm/utils"
+	"github.com/vntchain/go-vnt/metrics"
+	"github.com/vntchain/go-vnt/params"
+	"github.com/vntchain/vnt-wasm/vnt"
+	"github.com/vntchain/vnt-wasm/wasm"
+)
+
+// codeCacheSize is the number of decoded contracts kept in memory.
+const codeCacheSize = 1024
+
+var (
+	codeCache, _       = lru.New(codeCacheSize)
+	recompiledCache, _ = lru.New(codeCacheSize)
+
+	codeCacheHitMeter  = metrics.NewRegisteredMeter("wavm/codecache/hit", nil)
+	codeCacheMissMeter = metrics.NewRegisteredMeter("wavm/codecache/miss", nil)
+)
+
+// decodedCode holds the parts of a deployed contract which don't depend on the
+// execution context, so they can be shared by all the calls into the contract.
+// The wasm module itself is still read for every call, as the host functions are
+// linked against the context of the call.
+type decodedCode struct {
+	code     wasmcontract.WasmCode
+	abi      abi.ABI
+	compiled []vnt.Compiled
+}
+
+// decodeCode decompresses and decodes a deployed contract along with its ABI and
+// compiled functions, serving it from the cache of recently used contracts if
+// possible. The returned contract is shared and must not be modified.
+func decodeCode(hash common.Hash, raw []byte) (*decodedCode, error) {
+	if hash != (common.Hash{}) {
+		if cached, ok := codeCache.Get(hash); ok {
+			codeCacheHitMeter.Mark(1)
+			return cached.(*decodedCode), nil
+		}
+		codeCacheMissMeter.Mark(1)
+	}
+	code, _, err := utils.DecodeContractCode(raw)
+	if err != nil {
+		return nil, err
+	}
+	abi, err := GetAbi(code.Abi)
+	if err != nil {
+		return nil, err
+	}
+	var compiled []vnt.Compiled
+	if err := json.Unmarshal(code.Compiled, &compiled); err != nil {
+		return nil, err
+	}
+	decoded := &decodedCode{code: code, abi: abi, compiled: compiled}
+	if hash != (common.Hash{}) {
+		codeCache.Add(hash, decoded)
+	}
+	return decoded, nil
+}
+
+// instructionCosts are the WASM instruction costs metered into compiled code.
+type instructionCosts struct {
+	regular, div, mul, mem uint64
+}
+
+// costsOf returns the WASM instruction costs of a gas table.
+func costsOf(gt params.GasTable) instructionCosts {
+	return instructionCosts{gt.WasmRegular, gt.WasmDiv, gt.WasmMul, gt.WasmMem}
+}
+
+// deployedCosts are the instruction costs deployed contracts are stored
+// compiled with. Calls made under any other costs compile the contract again.
+var deployedCosts = costsOf(params.GasTableHubble)
+
+// recompiledKey identifies a deployed contract compiled with repriced costs.
+type recompiledKey struct {
+	hash  common.Hash
+	costs instructionCosts
+}
+
+// recompile compiles a deployed contract again with the instruction costs of
+// the call context, serving it from the cache of recently used contracts if
+// possible. The returned functions are shared and must not be modified.
+func recompile(hash common.Hash, module *wasm.Module, ctx ChainContext, mutable Mutable) ([]vnt.Compiled, error) {
+	key := recompiledKey{hash: hash, costs: costsOf(ctx.GasTable)}
+	if hash != (common.Hash{}) {
+		if cached, ok := recompiledCache.Get(key); ok {
+			return cached.([]vnt.Compiled), nil
+		}
+	}
+	compiled, err := CompileModule(module, ctx, mutable)
+	if err != nil {
+		return nil, err
+	}
+	if hash != (common.Hash{}) {
+		recompiledCache.Add(key, compiled)
+	}
+	return compiled, nil
+}
+
+// compileDeployed compiles a newly created contract for storage with the
+// deployed instruction costs, whatever the costs of its creation.
+func compileDeployed(module *wasm.Module, ctx ChainContext, mutable Mutable, disableFloatingPoint bool) ([]vnt.Compiled, error) {
+	ctx.GasRule = gas.NewGas(disableFloatingPoint, params.GasTableHubble)
+	return CompileModule(module, ctx, mutable)
+}
@@ -0,0 +1,131 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package wavm
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/big"
+	"testing"
+
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/core/state"
+	"github.com/vntchain/go-vnt/core/vm"
+	"github.com/vntchain/go-vnt/core/vm/interface"
+	"github.com/vntchain/go-vnt/core/wavm/utils"
+	"github.com/vntchain/go-vnt/params"
+)
+
+// Tests that decoded contracts are served from the cache once seen, and that
+// contracts without a code hash are never cached.
+func TestDecodeCodeCache(t *testing.T) {
+	abijson := []byte(`[{"name":"get","constant":true,"inputs":[],"outputs":[],"type":"function"}]`)
+	raw := utils.CompressWasmAndAbi(abijson, bytes.Repeat([]byte{0x00, 0x61, 0x73, 0x6d}, 64), []byte("[]"))
+
+	hash := common.HexToHash("0xdeadbeef")
+	first, err := decodeCode(hash, raw)
+	if err != nil {
+		t.Fatalf("failed to decode contract: %v", err)
+	}
+	if _, ok := first.abi.Methods["get"]; !ok {
+		t.Fatalf("decoded abi missing method: have %v", first.abi.Methods)
+	}
+	second, err := decodeCode(hash, nil)
+	if err != nil {
+		t.Fatalf("failed to decode cached contract: %v", err)
+	}
+	if first != second {
+		t.Errorf("cached contract mismatch: have %p, want %p", second, first)
+	}
+	if _, err := decodeCode(common.Hash{}, raw); err != nil {
+		t.Fatalf("failed to decode unhashed contract: %v", err)
+	}
+	if codeCache.Contains(common.Hash{}) {
+		t.Errorf("contract without code hash cached")
+	}
+}
+
+// newScheduleWAVM creates a WAVM executing at the given block number on the
+// given state.
+func newScheduleWAVM(config *params.ChainConfig, statedb *state.StateDB, number int64) *WAVM {
+	ctx := vm.Context{
+		CanTransfer: func(db inter.StateDB, addr common.Address, amount *big.Int) bool {
+			return db.GetBalance(addr).Cmp(amount) >= 0
+		},
+		Transfer:    func(db inter.StateDB, sender, recipient common.Address, amount *big.Int) {},
+		GetHash:     func(uint64) common.Hash { return common.Hash{} },
+		BlockNumber: big.NewInt(number),
+		Time:        big.NewInt(number),
+		Difficulty:  big.NewInt(1),
+		GasLimit:    10000000,
+		GasPrice:    big.NewInt(1),
+	}
+	return NewWAVM(ctx, statedb, config, vm.Config{})
+}
+
+// Tests that a gas schedule repricing the WASM instructions applies to calls into
+// contracts deployed before it, and that contracts are stored the same way
+// whichever costs they were deployed under.
+func TestGasScheduleRepricesDeployed(t *testing.T) {
+	code, err := ioutil.ReadFile(memoryCodePath)
+	if err != nil {
+		t.Fatalf("failed to read contract: %v", err)
+	}
+	wasmRegular := uint64(10)
+	config := &params.ChainConfig{
+		ChainID:      big.NewInt(1),
+		HubbleBlock:  big.NewInt(0),
+		GasSchedules: []params.GasSchedule{{Block: big.NewInt(10), Costs: params.GasCosts{WasmRegular: &wasmRegular}}},
+	}
+	deploy := func(number int64) (*state.StateDB, common.Address) {
+		statedb := prepareState()
+		_, addr, _, err := newScheduleWAVM(config, statedb, number).Create(vm.AccountRef(common.Address{1}), code, 10000000, new(big.Int))
+		if err != nil {
+			t.Fatalf("failed to deploy contract at block %d: %v", number, err)
+		}
+		return statedb, addr
+	}
+	statedb, addr := deploy(1)
+	if forked, forkedAddr := deploy(10); !bytes.Equal(forked.GetCode(forkedAddr), statedb.GetCode(addr)) {
+		t.Fatalf("contract stored differently under repriced costs")
+	}
+	abi, err := GetAbi([]byte(`[{"name":"testEvent1","constant":false,"inputs":[],"outputs":[],"type":"function"}]`))
+	if err != nil {
+		t.Fatalf("failed to parse abi: %v", err)
+	}
+	input, err := abi.Pack("testEvent1")
+	if err != nil {
+		t.Fatalf("failed to pack input: %v", err)
+	}
+	call := func(number int64) uint64 {
+		_, left, err := newScheduleWAVM(config, statedb.Copy(), number).Call(vm.AccountRef(common.Address{1}), addr, input, 10000000, new(big.Int))
+		if err != nil {
+			t.Fatalf("failed to call contract at block %d: %v", number, err)
+		}
+		return 10000000 - left
+	}
+	before, after := call(9), call(10)
+	if after <= before {
+		t.Fatalf("repriced call not charged more: have %d, before fork %d", after, before)
+	}
+	if again := call(10); again != after {
+		t.Errorf("cached repriced call gas mismatch: have %d, want %d", again, after)
+	}
+	if again := call(9); again != before {
+		t.Errorf("call gas changed before fork: have %d, want %d", again, before)
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package wavm
+
+import (
+	"io/ioutil"
+	"math"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/core/vm"
+	"github.com/vntchain/go-vnt/core/vm/interface"
+	errormsg "github.com/vntchain/go-vnt/core/wavm/errors"
+	"github.com/vntchain/go-vnt/params"
+)
+
+// Tests that cancelling a running contract cuts off its execution, even while
+// it loops with plenty of gas left.
+func TestCancelLoopingContract(t *testing.T) {
+	code, err := ioutil.ReadFile("testdata/gas/TestGas.compress")
+	if err != nil {
+		t.Fatalf("failed to read contract: %v", err)
+	}
+	abijson, err := ioutil.ReadFile("testdata/gas/abi.json")
+	if err != nil {
+		t.Fatalf("failed to read abi: %v", err)
+	}
+	abi, err := GetAbi(abijson)
+	if err != nil {
+		t.Fatalf("failed to parse abi: %v", err)
+	}
+	// Print in a loop long enough to never finish within the test
+	input, err := abi.Pack("testPrintf", uint64(math.MaxUint32))
+	if err != nil {
+		t.Fatalf("failed to pack input: %v", err)
+	}
+	ctx := vm.Context{
+		CanTransfer: func(db inter.StateDB, addr common.Address, amount *big.Int) bool {
+			return db.GetBalance(addr).Cmp(amount) >= 0
+		},
+		Transfer:    func(db inter.StateDB, sender, recipient common.Address, amount *big.Int) {},
+		GetHash:     func(uint64) common.Hash { return common.Hash{} },
+		BlockNumber: big.NewInt(1),
+		Time:        big.NewInt(1),
+		Difficulty:  big.NewInt(1),
+		GasLimit:    math.MaxUint64,
+		GasPrice:    big.NewInt(1),
+	}
+	wavm := NewWAVM(ctx, prepareState(), params.TestChainConfig, vm.Config{})
+	_, addr, _, err := wavm.Create(vm.AccountRef(common.Address{1}), code, 10000000, new(big.Int))
+	if err != nil {
+		t.Fatalf("failed to deploy contract: %v", err)
+	}
+	time.AfterFunc(100*time.Millisecond, wavm.Cancel)
+
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := wavm.Call(vm.AccountRef(common.Address{1}), addr, input, math.MaxUint64, new(big.Int))
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != errormsg.ErrExecutionCancelled {
+			t.Fatalf("execution error mismatch: have %v, want %v", err, errormsg.ErrExecutionCancelled)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("cancelled execution still running")
+	}
+}
@@ -1152,9 +1152,22 @@ func (ef *EnvFunctions) Pow(proc *exec.WavmProcess, base, exponent uint64) uint6
 }
 
 func (ef *EnvFunctions) AddGas(proc *exec.WavmProcess, cost uint64) {
+	// Gas is charged on entering every block, loops included, so a cancelled
+	// execution is cut off here
+	if ef.ctx.Wavm != nil && ef.ctx.Wavm.Cancelled() {
+		panic(errormsg.ErrExecutionCancelled)
+	}
 	ef.ctx.GasCounter.AdjustedCharge(cost)
 }
 
+// revertError is thrown by the Revert host function to abort the execution,
+// carrying the message the contract reverted with.
+type revertError []byte
+
+func (e revertError) Error() string {
+	return errormsg.ErrExecutionReverted.Error()
+}
+
 //todo 考虑revert的完整实现 contractcall里需要用到revert
 func (ef *EnvFunctions) Revert(proc *exec.WavmProcess, msgIdx uint64) {
 	ctx := ef.ctx
@@ -1162,7 +1175,7 @@ func (ef *EnvFunctions) Revert(proc *exec.WavmProcess, msgIdx uint64) {
 	msg := proc.ReadAt(msgIdx)
 	ctx.GasCounter.GasMemoryCost(uint64(len(msg)))
 	log.Info("Contract Revert >>>>", "message", string(msg))
-	panic(errormsg.ErrExecutionReverted)
+	panic(revertError(common.CopyBytes(msg)))
 }
 
 func (ef *EnvFunctions) returnPointer(proc *exec.WavmProcess, input []byte) uint64 {
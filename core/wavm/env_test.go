@@ -138,8 +138,8 @@ func getVM(codeFile string, abiPath string) (*exec.Interpreter, EnvFunctions) {
 	addr := common.BytesToAddress([]byte("0xd2be7e0d40c1a73ec1709f00b11cb5e24c784077"))
 
 	chainconfig := &params.ChainConfig{HubbleBlock: big.NewInt(0)}
-	gasRule := g.NewGas(false)
 	gasTable := chainconfig.GasTable(new(big.Int).SetInt64(10000))
+	gasRule := g.NewGas(false, gasTable)
 	contract := contract.NewWASMContract(vm.AccountRef(addr),
 		vm.AccountRef(addr), big.NewInt(100), 200000)
 	gasCounter := g.NewGasCounter(contract, gasTable)
@@ -176,7 +176,7 @@ func getVM(codeFile string, abiPath string) (*exec.Interpreter, EnvFunctions) {
 	//compiled, err := CompileModule(m, cc)
 	//compiled := make([]vnt.Compiled, 0)
 
-	vm, err := exec.NewInterpreter(m, nil, instantiateMemory, cc.Wavm.Wavm.captureOp, cc.Wavm.Wavm.captureEnvFunction, false)
+	vm, err := exec.NewInterpreter(m, nil, instantiateMemory, cc.Wavm.Wavm.captureOp, cc.Wavm.Wavm.captureEnvFunction, false, nil)
 	if err != nil {
 		log.Crit("failed to create vm: ", "error", err)
 	}
@@ -28,4 +28,5 @@ var (
 	ErrExecutionReverted        = errors.New("wavm: execution reverted")
 	ErrMaxCodeSizeExceeded      = errors.New("wavm: max code size exceeded")
 	ErrExecutionAssert          = errors.New("wavm: execution assert")
+	ErrExecutionCancelled       = errors.New("wavm: execution cancelled")
 )
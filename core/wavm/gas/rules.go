@@ -30,10 +30,6 @@ import (
 )
 
 const (
-	WasmCostsRegular       = 1
-	WasmCostsDiv           = 16
-	WasmCostsMul           = 4
-	WasmCostsMem           = 2
 	WasmCostsStaticU256    = 64
 	WasmCostsStaticHash    = 64
 	WasmCostsStaticAddress = 40
@@ -58,8 +54,9 @@ type GasValue struct {
 }
 
 type Gas struct {
-	Ops   map[byte]InstructionType
-	Rules map[InstructionType]GasValue
+	Ops     map[byte]InstructionType
+	Rules   map[InstructionType]GasValue
+	Regular uint64 // Cost of the instructions without a specific rule
 }
 
 type GasCounter struct {
@@ -67,7 +64,9 @@ type GasCounter struct {
 	GasTable params.GasTable
 }
 
-func NewGas(disableFloatingPoint bool) Gas {
+// NewGas creates the instruction metering rules with the WASM instruction costs
+// of the gas table.
+func NewGas(disableFloatingPoint bool, gasTable params.GasTable) Gas {
 	rules := Gas{
 		Ops: map[byte]InstructionType{
 			ops.Unreachable:  InstructionTypeUnreachable,
@@ -260,11 +259,12 @@ func NewGas(disableFloatingPoint bool) Gas {
 			ops.F64ReinterpretI64: InstructionTypeReinterpretation,
 		},
 		Rules: map[InstructionType]GasValue{
-			InstructionTypeLoad:  GasValue{Metering: MeteringFixed, Value: WasmCostsMem},
-			InstructionTypeStore: GasValue{Metering: MeteringFixed, Value: WasmCostsMem},
-			InstructionTypeDiv:   GasValue{Metering: MeteringFixed, Value: WasmCostsDiv},
-			InstructionTypeMul:   GasValue{Metering: MeteringFixed, Value: WasmCostsMul},
+			InstructionTypeLoad:  GasValue{Metering: MeteringFixed, Value: gasTable.WasmMem},
+			InstructionTypeStore: GasValue{Metering: MeteringFixed, Value: gasTable.WasmMem},
+			InstructionTypeDiv:   GasValue{Metering: MeteringFixed, Value: gasTable.WasmDiv},
+			InstructionTypeMul:   GasValue{Metering: MeteringFixed, Value: gasTable.WasmMul},
 		},
+		Regular: gasTable.WasmRegular,
 	}
 	if disableFloatingPoint {
 		rules.Rules[InstructionTypeFloat] = GasValue{
@@ -291,7 +291,7 @@ func (gas Gas) GasCost(op byte) uint64 {
 	case MeteringFixed:
 		return metering.Value
 	default:
-		return WasmCostsRegular
+		return gas.Regular
 	}
 }
 
@@ -485,7 +485,7 @@ func (gas GasCounter) GasToU64() {
 }
 
 func (gas GasCounter) GasConcat(size uint64) {
-	gas.Charge(constGasFunc(WasmCostsMem * size))
+	gas.Charge(constGasFunc(gas.GasTable.WasmMem * size))
 }
 
 func (gas GasCounter) GasEqual() {
@@ -566,15 +566,15 @@ func (gas GasCounter) GasStore(stateDb *state.StateDB, contractAddr common.Addre
 	// 3. From a non-zero to a non-zero                         (CHANGE)
 	if (val == common.Hash{} && y != common.Hash{}) {
 		// 0 => non 0
-		gas.Charge(constGasFunc(params.SstoreSetGas))
+		gas.Charge(constGasFunc(gas.GasTable.SstoreSet))
 		// return params.SstoreSetGas, nil
 	} else if (val != common.Hash{} && y == common.Hash{}) {
-		stateDb.AddRefund(params.SstoreRefundGas)
-		gas.Charge(constGasFunc(params.SstoreClearGas))
+		stateDb.AddRefund(gas.GasTable.SstoreRefund)
+		gas.Charge(constGasFunc(gas.GasTable.SstoreClear))
 		// return params.SstoreClearGas, nil
 	} else {
 		// non 0 => non 0 (or 0 => 0)
-		gas.Charge(constGasFunc(params.SstoreResetGas))
+		gas.Charge(constGasFunc(gas.GasTable.SstoreReset))
 		// return params.SstoreResetGas, nil
 	}
 }
@@ -0,0 +1,132 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package wavm
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/vntchain/go-vnt/core/wavm/gas"
+	"github.com/vntchain/go-vnt/core/wavm/utils"
+	"github.com/vntchain/go-vnt/params"
+	"github.com/vntchain/vnt-wasm/wasm"
+)
+
+// ExportedFunction is a function a contract exports to its callers.
+type ExportedFunction struct {
+	Name     string `json:"name"`
+	Index    uint32 `json:"index"`
+	Method   bool   `json:"method"`   // Whether the ABI declares the function
+	Constant bool   `json:"constant"` // Whether the function leaves the state untouched
+	Payable  bool   `json:"payable"`  // Whether the function accepts value transfers
+}
+
+// ContractInfo is the outcome of the static inspection of a contract's deploy
+// code, gathered without running any of its functions.
+type ContractInfo struct {
+	Constructor string             `json:"constructor"`
+	Exports     []ExportedFunction `json:"exports"`  // Sorted by name
+	Imports     []string           `json:"imports"`  // Host functions, sorted by name
+	Warnings    []string           `json:"warnings"` // Problems which only surface when calling the contract
+	Memory      uint64             `json:"memory"`   // Initial linear memory in pages
+	CodeSize    int                `json:"codeSize"` // Size of the code stored on the chain
+	DeployGas   uint64             `json:"deployGas"`
+}
+
+// Inspect decodes the deploy code of a contract and runs the checks done when
+// creating it: the module is read, linked against the host functions and
+// verified, then compiled. The gas reported is charged by the creation on top
+// of the intrinsic gas of the transaction and the execution of the constructor,
+// neither of which is included.
+func Inspect(code []byte) (*ContractInfo, error) {
+	decoded, _, err := utils.DecodeContractCode(code)
+	if err != nil {
+		return nil, fmt.Errorf("invalid contract encoding: %v", err)
+	}
+	abi, err := GetAbi(decoded.Abi)
+	if err != nil {
+		return nil, fmt.Errorf("invalid abi: %v", err)
+	}
+	ctx := ChainContext{
+		Abi:     abi,
+		GasRule: gas.NewGas(false, params.GasTableHubble),
+	}
+	w := NewWavm(ctx, Config{}, true)
+	if err := w.InstantiateModule(decoded.Code, []uint8{}); err != nil {
+		return nil, err
+	}
+	module := w.Module
+
+	info := &ContractInfo{Constructor: abi.Constructor.Name, Memory: 1}
+	for name, entry := range module.Export.Entries {
+		if entry.Kind != wasm.ExternalFunction {
+			continue
+		}
+		method, ok := abi.Methods[name]
+		info.Exports = append(info.Exports, ExportedFunction{
+			Name:     name,
+			Index:    entry.Index,
+			Method:   ok,
+			Constant: ok && method.Const,
+			Payable:  w.payable(name),
+		})
+	}
+	sort.Slice(info.Exports, func(i, j int) bool { return info.Exports[i].Name < info.Exports[j].Name })
+
+	if module.Import != nil {
+		for _, entry := range module.Import.Entries {
+			if entry.Type.Kind() == wasm.ExternalFunction {
+				info.Imports = append(info.Imports, entry.FieldName)
+			}
+		}
+		sort.Strings(info.Imports)
+	}
+	// Creation runs the constructor, or the fallback function in its absence
+	if _, ok := module.Export.Entries[abi.Constructor.Name]; !ok || abi.Constructor.Name == "" {
+		_, fallback := module.Export.Entries[FallBackFunctionName]
+		_, payable := module.Export.Entries[FallBackPayableFunctionName]
+		if !fallback && !payable {
+			return nil, fmt.Errorf("constructor %q not exported", abi.Constructor.Name)
+		}
+		info.Warnings = append(info.Warnings, fmt.Sprintf("constructor %q not exported, fallback function runs on creation", abi.Constructor.Name))
+	}
+	for name := range abi.Methods {
+		if _, ok := module.Export.Entries[name]; !ok {
+			info.Warnings = append(info.Warnings, fmt.Sprintf("abi method %q not exported", name))
+		}
+	}
+	sort.Strings(info.Warnings)
+
+	compiled, err := CompileModule(module, ctx, MutableFunction(abi, module))
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile module: %v", err)
+	}
+	blob, err := json.Marshal(compiled)
+	if err != nil {
+		return nil, err
+	}
+	if module.Memory != nil && len(module.Memory.Entries) != 0 {
+		info.Memory = uint64(module.Memory.Entries[0].Limits.Initial)
+	}
+	info.CodeSize = len(utils.CompressWasmAndAbi(decoded.Abi, decoded.Code, blob))
+	if info.CodeSize > params.MaxCodeSize {
+		return nil, fmt.Errorf("stored code size %d exceeds limit %d", info.CodeSize, params.MaxCodeSize)
+	}
+	info.DeployGas = info.Memory*gas.WasmCostsInitialMem + uint64(info.CodeSize)*params.CreateDataGas/2
+	return info, nil
+}
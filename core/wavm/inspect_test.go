@@ -0,0 +1,69 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package wavm
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/vntchain/go-vnt/core/wavm/utils"
+)
+
+// Tests that inspecting a contract reports its functions and the gas of its
+// deployment, and that broken contracts are rejected.
+func TestInspect(t *testing.T) {
+	code, err := ioutil.ReadFile(filepath.Join("testdata/erc20", "TokenERC20.compress"))
+	if err != nil {
+		t.Fatalf("failed to read contract: %v", err)
+	}
+	info, err := Inspect(code)
+	if err != nil {
+		t.Fatalf("failed to inspect contract: %v", err)
+	}
+	if info.Constructor != "TokenERC20" {
+		t.Errorf("constructor mismatch: have %q, want %q", info.Constructor, "TokenERC20")
+	}
+	exports := make(map[string]ExportedFunction)
+	for _, fn := range info.Exports {
+		exports[fn.Name] = fn
+	}
+	if fn, ok := exports["transfer"]; !ok || !fn.Method || fn.Constant {
+		t.Errorf("transfer export mismatch: have %+v", fn)
+	}
+	if fn, ok := exports["GetTotalSupply"]; !ok || !fn.Method || !fn.Constant {
+		t.Errorf("constant export mismatch: have %+v", fn)
+	}
+	if len(info.Imports) == 0 {
+		t.Errorf("no host functions imported")
+	}
+	if len(info.Warnings) != 0 {
+		t.Errorf("unexpected warnings: %v", info.Warnings)
+	}
+	if info.CodeSize == 0 || info.DeployGas == 0 {
+		t.Errorf("missing deployment cost: size %d, gas %d", info.CodeSize, info.DeployGas)
+	}
+
+	decoded, _, _ := utils.DecodeContractCode(code)
+	broken := utils.CompressWasmAndAbi(decoded.Abi, decoded.Code[:len(decoded.Code)/2], nil)
+	if _, err := Inspect(broken); err == nil {
+		t.Errorf("truncated module accepted")
+	}
+	if _, err := Inspect(decoded.Code); err == nil {
+		t.Errorf("raw module accepted")
+	}
+}
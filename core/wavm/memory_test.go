@@ -0,0 +1,142 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package wavm
+
+import (
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/core/vm"
+	"github.com/vntchain/go-vnt/core/vm/interface"
+	"github.com/vntchain/go-vnt/params"
+	"github.com/vntchain/vnt-wasm/exec"
+	"github.com/vntchain/vnt-wasm/wasm"
+)
+
+var memoryCodePath = filepath.Join("testdata/event", "init.compress")
+
+// deployWithMeter deploys the test contract on a fresh state, charging its
+// execution memory against the given meter.
+func deployWithMeter(t *testing.T, meter *vm.MemoryMeter) error {
+	code, err := ioutil.ReadFile(memoryCodePath)
+	if err != nil {
+		t.Fatalf("failed to read contract: %v", err)
+	}
+	ctx := vm.Context{
+		CanTransfer: func(db inter.StateDB, addr common.Address, amount *big.Int) bool {
+			return db.GetBalance(addr).Cmp(amount) >= 0
+		},
+		Transfer:    func(db inter.StateDB, sender, recipient common.Address, amount *big.Int) {},
+		GetHash:     func(uint64) common.Hash { return common.Hash{} },
+		BlockNumber: big.NewInt(1),
+		Time:        big.NewInt(1),
+		Difficulty:  big.NewInt(1),
+		GasLimit:    10000000,
+		GasPrice:    big.NewInt(1),
+	}
+	wavm := NewWAVM(ctx, prepareState(), params.TestChainConfig, vm.Config{MemoryMeter: meter})
+	_, _, _, err = wavm.Create(vm.AccountRef(common.Address{1}), code, 10000000, new(big.Int))
+	return err
+}
+
+// Tests that contract execution memory is charged against the block meter and
+// aborts once the cap is crossed.
+func TestMemoryMeterCharged(t *testing.T) {
+	meter := vm.NewMemoryMeter(0)
+	if err := deployWithMeter(t, meter); err != nil {
+		t.Fatalf("failed to deploy contract: %v", err)
+	}
+	used := meter.Used()
+	if used == 0 {
+		t.Fatalf("no memory charged for execution")
+	}
+	// A cap above the allocation must not interfere with the execution
+	if err := deployWithMeter(t, vm.NewMemoryMeter(used)); err != nil {
+		t.Fatalf("execution under the cap failed: %v", err)
+	}
+	// A cap below the allocation must abort it
+	meter = vm.NewMemoryMeter(used - 1)
+	if err := deployWithMeter(t, meter); err == nil {
+		t.Fatalf("execution over the cap succeeded")
+	}
+	if !meter.Exceeded() {
+		t.Fatalf("meter not exceeded over the cap")
+	}
+}
+
+// newGrowModule creates a module with the given initial linear memory pages,
+// whose only function grows the memory by 1024 pages (64MB).
+func newGrowModule(initial uint32) *wasm.Module {
+	code := []byte{
+		0x41, 0x80, 0x08, // i32.const 1024
+		0x40, 0x00, // grow_memory
+	}
+	return &wasm.Module{
+		Memory:                 &wasm.SectionMemories{Entries: []wasm.Memory{{Limits: wasm.ResizableLimits{Initial: initial}}}},
+		LinearMemoryIndexSpace: [][]byte{nil},
+		FunctionIndexSpace: []wasm.Function{{
+			Sig:  &wasm.FunctionSig{ReturnTypes: []wasm.ValueType{wasm.ValueTypeI32}},
+			Body: &wasm.FunctionBody{Code: code},
+		}},
+		Export: &wasm.SectionExports{Entries: map[string]wasm.ExportEntry{}},
+	}
+}
+
+// Tests that linear memory allocations are charged before they are made, so
+// that an execution crossing the cap is aborted without allocating the memory.
+func TestMemoryMeterAbortsAllocation(t *testing.T) {
+	charge := func(meter *vm.MemoryMeter) func(uint64) error {
+		return (&Wavm{WavmConfig: Config{MemoryMeter: meter}}).chargeMemory
+	}
+	// An oversized initial memory must be refused before instantiation
+	meter := vm.NewMemoryMeter(64 * 1024 * 1024)
+	if _, err := exec.NewInterpreter(newGrowModule(1<<16), nil, instantiateMemory, nil, nil, false, charge(meter)); err == nil {
+		t.Fatalf("oversized initial memory instantiated")
+	}
+	if !meter.Exceeded() {
+		t.Fatalf("meter not exceeded by initial memory")
+	}
+	// Growing the memory over the cap must abort the execution
+	meter = vm.NewMemoryMeter(32 * 1024 * 1024)
+	inter, err := exec.NewInterpreter(newGrowModule(1), nil, instantiateMemory, nil, nil, false, charge(meter))
+	if err != nil {
+		t.Fatalf("failed to instantiate module: %v", err)
+	}
+	inter.RecoverPanic = true
+	if _, err := inter.ExecContractCode(0); errors.Cause(err) != vm.ErrMemoryCapExceeded {
+		t.Fatalf("grow error mismatch: have %v, want %v", err, vm.ErrMemoryCapExceeded)
+	}
+	if size := len(inter.Memory.Memory); size != 64*1024 {
+		t.Errorf("memory grown over the cap: have %d bytes, want %d", size, 64*1024)
+	}
+	// Growing within the cap must succeed
+	meter = vm.NewMemoryMeter(128 * 1024 * 1024)
+	if inter, err = exec.NewInterpreter(newGrowModule(1), nil, instantiateMemory, nil, nil, false, charge(meter)); err != nil {
+		t.Fatalf("failed to instantiate module: %v", err)
+	}
+	inter.RecoverPanic = true
+	if _, err := inter.ExecContractCode(0); err != nil {
+		t.Fatalf("grow within the cap failed: %v", err)
+	}
+	if used, want := meter.Used(), uint64(1025*64*1024); used != want {
+		t.Errorf("charged memory mismatch: have %d, want %d", used, want)
+	}
+}
@@ -29,6 +29,7 @@ import (
 	"github.com/vntchain/go-vnt/common/math"
 	mat "github.com/vntchain/go-vnt/common/math"
 	"github.com/vntchain/go-vnt/core/vm"
+	errormsg "github.com/vntchain/go-vnt/core/wavm/errors"
 	"github.com/vntchain/go-vnt/core/wavm/gas"
 	"github.com/vntchain/go-vnt/core/wavm/utils"
 	"github.com/vntchain/go-vnt/log"
@@ -154,6 +155,15 @@ func (wavm *Wavm) captrueFault(pc uint64, err error) error {
 	return nil
 }
 
+// chargeMemory charges a linear memory allocation of the contract against the
+// block memory meter before it's made, if the block's execution is capped.
+func (wavm *Wavm) chargeMemory(size uint64) error {
+	if meter := wavm.WavmConfig.MemoryMeter; meter != nil {
+		return meter.Allocate(size)
+	}
+	return nil
+}
+
 func (wavm *Wavm) Tracer() vm.Tracer {
 	return wavm.ChainContext.Wavm.wavmConfig.Tracer
 }
@@ -228,6 +238,13 @@ func (wavm *Wavm) Apply(input []byte, compiled []vnt.Compiled, mutable Mutable)
 			log.Error("Got error during wasm execution.", "err", r)
 			res = nil
 			err = fmt.Errorf("%s", r)
+			if reason, ok := r.(revertError); ok {
+				// Hand the revert message back to the caller like the EVM does
+				res, err = reason, errormsg.ErrExecutionReverted
+			}
+			if r == errormsg.ErrExecutionCancelled {
+				err = errormsg.ErrExecutionCancelled
+			}
 			if wavm.WavmConfig.Debug == true {
 				wavm.captrueFault(uint64(wavm.VM.Pc()), err)
 			}
@@ -236,7 +253,7 @@ func (wavm *Wavm) Apply(input []byte, compiled []vnt.Compiled, mutable Mutable)
 	wavm.MutableList = mutable
 
 	var vm *exec.Interpreter
-	vm, err = exec.NewInterpreter(wavm.Module, compiled, instantiateMemory, wavm.captureOp, wavm.captureEnvFunction, wavm.WavmConfig.Debug)
+	vm, err = exec.NewInterpreter(wavm.Module, compiled, instantiateMemory, wavm.captureOp, wavm.captureEnvFunction, wavm.WavmConfig.Debug, wavm.chargeMemory)
 	if err != nil {
 		log.Error("Could not create VM: ", "error", err)
 		return nil, fmt.Errorf("Could not create VM: %s", err)
@@ -26,6 +26,7 @@ import (
 	wasmcontract "github.com/vntchain/go-vnt/core/wavm/contract"
 	"github.com/vntchain/go-vnt/core/wavm/gas"
 
+	vntabi "github.com/vntchain/go-vnt/accounts/abi"
 	"github.com/vntchain/go-vnt/common"
 	"github.com/vntchain/go-vnt/core/state"
 	"github.com/vntchain/go-vnt/core/vm"
@@ -94,22 +95,32 @@ func runWavm(wavm *WAVM, contract *wasmcontract.WASMContract, input []byte, isCr
 	if len(contract.Code) == 0 {
 		return nil, nil
 	}
-	var code wasmcontract.WasmCode
-	decode, vmInput, err := utils.DecodeContractCode(contract.Code)
-	if err != nil {
-		return nil, err
-	}
-	code = decode
+	var (
+		code     wasmcontract.WasmCode
+		abi      vntabi.ABI
+		compiled []vnt.Compiled
+		err      error
+	)
 	if isCreate == true {
+		var vmInput []byte
+		if code, vmInput, err = utils.DecodeContractCode(contract.Code); err != nil {
+			return nil, err
+		}
 		input = vmInput
-	}
 
-	abi, err := GetAbi(code.Abi)
-	if err != nil {
-		return nil, err
+		if abi, err = GetAbi(code.Abi); err != nil {
+			return nil, err
+		}
+	} else {
+		// Deployed contracts don't change, reuse their decoded form
+		decoded, err := decodeCode(contract.CodeHash, contract.Code)
+		if err != nil {
+			return nil, err
+		}
+		code, abi, compiled = decoded.code, decoded.abi, decoded.compiled
 	}
-	gasRule := gas.NewGas(wavm.wavmConfig.DisableFloatingPoint)
 	gasTable := wavm.ChainConfig().GasTable(wavm.Context.BlockNumber)
+	gasRule := gas.NewGas(wavm.wavmConfig.DisableFloatingPoint, gasTable)
 	gasCounter := gas.NewGasCounter(contract, gasTable)
 	crx := ChainContext{
 		CanTransfer: wavm.Context.CanTransfer,
@@ -149,23 +160,32 @@ func runWavm(wavm *WAVM, contract *wasmcontract.WASMContract, input []byte, isCr
 		compiled, err := CompileModule(newwawm.Module, crx, mutable)
 		res, err = newwawm.Apply(input, compiled, mutable)
 		if err != nil {
-			return nil, err
+			return res, err
+		}
+		// Contracts are stored metered with the deployed costs, so that any call
+		// can tell whether they must be compiled again for the active costs
+		stored := compiled
+		if costsOf(gasTable) != deployedCosts {
+			if stored, err = compileDeployed(newwawm.Module, crx, mutable, wavm.wavmConfig.DisableFloatingPoint); err != nil {
+				return nil, err
+			}
 		}
-		compileres, err := json.Marshal(compiled)
+		compileres, err := json.Marshal(stored)
 		if err != nil {
 			return nil, err
 		}
 		code.Compiled = compileres
 		res = utils.CompressWasmAndAbi(code.Abi, code.Code, code.Compiled)
 	} else {
-		var compiled []vnt.Compiled
-		err = json.Unmarshal(code.Compiled, &compiled)
-		if err != nil {
-			return nil, err
+		if costsOf(gasTable) != deployedCosts {
+			// The instructions were repriced since the deployed costs, meter them again
+			if compiled, err = recompile(contract.CodeHash, newwawm.Module, crx, mutable); err != nil {
+				return nil, err
+			}
 		}
 		res, err = newwawm.Apply(input, compiled, mutable)
 		if err != nil {
-			return nil, err
+			return res, err
 		}
 	}
 	return res, err
@@ -176,6 +196,7 @@ func NewWAVM(ctx vm.Context, statedb inter.StateDB, chainConfig *params.ChainCon
 		Debug:       vmConfig.Debug,
 		Tracer:      vmConfig.Tracer,
 		NoRecursion: vmConfig.NoRecursion,
+		MemoryMeter: vmConfig.MemoryMeter,
 	}
 	wavm := &WAVM{
 		Context:     ctx,
@@ -188,10 +209,17 @@ func NewWAVM(ctx vm.Context, statedb inter.StateDB, chainConfig *params.ChainCon
 	return wavm
 }
 
+// Cancel aborts any running contract execution at its next gas charge. This may
+// be called concurrently and multiple times.
 func (wavm *WAVM) Cancel() {
 	atomic.StoreInt32(&wavm.abort, 1)
 }
 
+// Cancelled returns whether the execution was cancelled.
+func (wavm *WAVM) Cancelled() bool {
+	return atomic.LoadInt32(&wavm.abort) == 1
+}
+
 func (wavm *WAVM) Create(caller vm.ContractRef, code []byte, gas uint64, value *big.Int) (ret []byte, contractAddr common.Address, leftOverGas uint64, err error) {
 	// Depth check execution. Fail if we're trying to execute above the
 	// limit.
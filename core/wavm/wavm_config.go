@@ -18,4 +18,6 @@ type Config struct {
 	GasLimit                 uint64
 	DisableFloatingPoint     bool
 	ReturnOnGasLimitExceeded bool
+	// MemoryMeter accounts the memory allocated by the block being processed
+	MemoryMeter *vm.MemoryMeter
 }
@@ -0,0 +1,57 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"testing"
+
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/common/hexutil"
+)
+
+// Tests that the schema parses and every field has a matching resolver.
+func TestBuildSchema(t *testing.T) {
+	if _, err := NewHandler(nil); err != nil {
+		t.Fatalf("could not create GraphQL handler: %v", err)
+	}
+}
+
+// Tests that scalar query arguments are decoded from their GraphQL inputs.
+func TestUnmarshalScalars(t *testing.T) {
+	var hash common.Hash
+	if err := hash.UnmarshalGraphQL("0x0000000000000000000000000000000000000000000000000000000000000001"); err != nil || hash != common.BigToHash(common.Big1) {
+		t.Errorf("Bytes32 mismatch: have %x, %v", hash, err)
+	}
+	var addr common.Address
+	if err := addr.UnmarshalGraphQL("0x01"); err == nil {
+		t.Errorf("short Address accepted: %x", addr)
+	}
+	var long hexutil.Uint64
+	if err := long.UnmarshalGraphQL(int32(16)); err != nil || long != 16 {
+		t.Errorf("Long mismatch: have %d, %v", long, err)
+	}
+	if err := long.UnmarshalGraphQL("0x20"); err != nil || long != 32 {
+		t.Errorf("Long mismatch: have %d, %v", long, err)
+	}
+	if err := long.UnmarshalGraphQL(int32(-1)); err == nil {
+		t.Errorf("negative Long accepted")
+	}
+	var big hexutil.Big
+	if err := big.UnmarshalGraphQL(int32(7)); err != nil || big.ToInt().Int64() != 7 {
+		t.Errorf("BigInt mismatch: have %v, %v", big.ToInt(), err)
+	}
+}
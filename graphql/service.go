@@ -0,0 +1,93 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	graphqlgo "github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+	"github.com/vntchain/go-vnt/log"
+	"github.com/vntchain/go-vnt/rpc"
+	"github.com/vntchain/go-vnt/vntp2p"
+)
+
+// Service encapsulates a GraphQL service.
+type Service struct {
+	endpoint string       // The host:port endpoint for this service.
+	handler  http.Handler // The `http.Handler` used to answer queries.
+	listener net.Listener // The listening socket.
+	server   *http.Server // The server answering queries on the socket.
+}
+
+// New constructs a new GraphQL service instance.
+func New(backend Backend, endpoint string) (*Service, error) {
+	handler, err := NewHandler(backend)
+	if err != nil {
+		return nil, err
+	}
+	return &Service{endpoint: endpoint, handler: handler}, nil
+}
+
+// NewHandler returns a new `http.Handler` that will answer GraphQL queries
+// posted as JSON to it.
+func NewHandler(backend Backend) (http.Handler, error) {
+	s, err := graphqlgo.ParseSchema(schema, &Resolver{backend})
+	if err != nil {
+		return nil, err
+	}
+	return &relay.Handler{Schema: s}, nil
+}
+
+// Protocols returns the list of protocols exported by this service.
+func (s *Service) Protocols() []vntp2p.Protocol { return nil }
+
+// APIs returns the list of APIs exported by this service.
+func (s *Service) APIs() []rpc.API { return nil }
+
+// Start is called after all services have been constructed and the networking
+// layer was also initialized to spawn any goroutines required by the service.
+func (s *Service) Start(server *vntp2p.Server) error {
+	listener, err := net.Listen("tcp", s.endpoint)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+	s.server = &http.Server{
+		Handler:      s.handler,
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 10 * time.Second,
+		IdleTimeout:  120 * time.Second,
+	}
+	go s.server.Serve(listener)
+	log.Info("GraphQL endpoint opened", "url", fmt.Sprintf("http://%s", listener.Addr()))
+	return nil
+}
+
+// Stop terminates all goroutines belonging to the service, blocking until they
+// are all terminated.
+func (s *Service) Stop() error {
+	if s.server != nil {
+		s.server.Close()
+		s.server = nil
+		log.Info("GraphQL endpoint closed", "url", fmt.Sprintf("http://%s", s.listener.Addr()))
+	}
+	return nil
+}
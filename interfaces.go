@@ -21,6 +21,7 @@ import (
 	"context"
 	"errors"
 	"math/big"
+	"time"
 
 	"github.com/vntchain/go-vnt/common"
 	"github.com/vntchain/go-vnt/core/types"
@@ -103,6 +104,16 @@ type SyncProgress struct {
 	HighestBlock  uint64 // Highest alleged block number in the chain
 	PulledStates  uint64 // Number of state trie entries already downloaded
 	KnownStates   uint64 // Total number of state trie entries known about
+
+	Stage          string        // Stage the sync is at ("idle", "ancestor", "blocks" or "state")
+	PulledHeaders  uint64        // Number of headers downloaded since sync began
+	PulledBodies   uint64        // Number of block bodies downloaded since sync began
+	PulledReceipts uint64        // Number of block receipts downloaded since sync began
+	HeaderRate     float64       // Headers downloaded per second since sync began
+	BodyRate       float64       // Block bodies downloaded per second since sync began
+	ReceiptRate    float64       // Block receipts downloaded per second since sync began
+	StateRate      float64       // State trie entries downloaded per second since sync began
+	Remaining      time.Duration // Estimated time until the highest block is reached (0 = unknown)
 }
 
 // ChainSyncReader wraps access to the node's current sync status. If there's no
@@ -43,9 +43,33 @@ var (
 	}
 	vmoduleFlag = cli.StringFlag{
 		Name:  "vmodule",
-		Usage: "Per-module verbosity: comma-separated list of <pattern>=<level> (e.g. eth/*=5,p2p=4)",
+		Usage: "Per-module verbosity: comma-separated list of <pattern>=<level> (e.g. core/*=5,vntp2p=4)",
 		Value: "",
 	}
+	logFileFlag = cli.StringFlag{
+		Name:  "log.file",
+		Usage: "Write logs to the given file in addition to the console",
+		Value: "",
+	}
+	logMaxSizeFlag = cli.IntFlag{
+		Name:  "log.maxsize",
+		Usage: "Maximum size in megabytes of the log file before it gets rotated (0 = unlimited)",
+		Value: 100,
+	}
+	logIntervalFlag = cli.DurationFlag{
+		Name:  "log.interval",
+		Usage: "Maximum time to write into the same log file before it gets rotated (e.g. 24h, 0 = unlimited)",
+		Value: 0,
+	}
+	logMaxBackupsFlag = cli.IntFlag{
+		Name:  "log.maxbackups",
+		Usage: "Maximum number of rotated log files to retain (0 = all)",
+		Value: 10,
+	}
+	logJSONFlag = cli.BoolFlag{
+		Name:  "log.json",
+		Usage: "Format logs as JSON records, one per line",
+	}
 	backtraceAtFlag = cli.StringFlag{
 		Name:  "backtrace",
 		Usage: "Request a stack trace at a specific logging statement (e.g. \"block.go:271\")",
@@ -60,15 +84,25 @@ var (
 		Usage: "Enable the pprof HTTP server",
 	}
 	pprofPortFlag = cli.IntFlag{
-		Name:  "pprofport",
+		Name:  "pprof.port",
 		Usage: "pprof HTTP server listening port",
 		Value: 6060,
 	}
 	pprofAddrFlag = cli.StringFlag{
-		Name:  "pprofaddr",
+		Name:  "pprof.addr",
 		Usage: "pprof HTTP server listening interface",
 		Value: "127.0.0.1",
 	}
+	legacyPprofPortFlag = cli.IntFlag{
+		Name:  "pprofport",
+		Usage: "pprof HTTP server listening port (deprecated, use --pprof.port)",
+		Value: 6060,
+	}
+	legacyPprofAddrFlag = cli.StringFlag{
+		Name:  "pprofaddr",
+		Usage: "pprof HTTP server listening interface (deprecated, use --pprof.addr)",
+		Value: "127.0.0.1",
+	}
 	memprofilerateFlag = cli.IntFlag{
 		Name:  "memprofilerate",
 		Usage: "Turn on memory profiling with the given rate",
@@ -91,11 +125,15 @@ var (
 // Flags holds all command-line flags required for debugging.
 var Flags = []cli.Flag{
 	verbosityFlag, vmoduleFlag, backtraceAtFlag, debugFlag,
-	pprofFlag, pprofAddrFlag, pprofPortFlag,
+	logFileFlag, logMaxSizeFlag, logIntervalFlag, logMaxBackupsFlag, logJSONFlag,
+	pprofFlag, pprofAddrFlag, pprofPortFlag, legacyPprofAddrFlag, legacyPprofPortFlag,
 	memprofilerateFlag, blockprofilerateFlag, cpuprofileFlag, traceFlag,
 }
 
-var glogger *log.GlogHandler
+var (
+	ostream log.Handler
+	glogger *log.GlogHandler
+)
 
 func init() {
 	usecolor := term.IsTty(os.Stderr.Fd()) && os.Getenv("TERM") != "dumb"
@@ -103,7 +141,8 @@ func init() {
 	if usecolor {
 		output = colorable.NewColorableStderr()
 	}
-	glogger = log.NewGlogHandler(log.StreamHandler(output, log.TerminalFormat(usecolor)))
+	ostream = log.StreamHandler(output, log.TerminalFormat(usecolor))
+	glogger = log.NewGlogHandler(ostream)
 }
 
 // Setup initializes profiling and logging based on the CLI flags.
@@ -111,8 +150,24 @@ func init() {
 func Setup(ctx *cli.Context) error {
 	// logging
 	log.PrintOrigins(ctx.GlobalBool(debugFlag.Name))
+	format := log.TerminalFormat(false)
+	if ctx.GlobalBool(logJSONFlag.Name) {
+		format = log.JSONFormat()
+		ostream = log.StreamHandler(os.Stderr, format)
+		glogger = log.NewGlogHandler(ostream)
+	}
+	if logFile := ctx.GlobalString(logFileFlag.Name); logFile != "" {
+		maxSize := int64(ctx.GlobalInt(logMaxSizeFlag.Name)) * 1024 * 1024
+		rotating, err := log.RotatingFileHandler(logFile, maxSize, ctx.GlobalDuration(logIntervalFlag.Name), ctx.GlobalInt(logMaxBackupsFlag.Name), format)
+		if err != nil {
+			return fmt.Errorf("failed to open log file: %v", err)
+		}
+		glogger = log.NewGlogHandler(log.MultiHandler(ostream, rotating))
+	}
 	glogger.Verbosity(log.Lvl(ctx.GlobalInt(verbosityFlag.Name)))
-	glogger.Vmodule(ctx.GlobalString(vmoduleFlag.Name))
+	if err := glogger.Vmodule(ctx.GlobalString(vmoduleFlag.Name)); err != nil {
+		return fmt.Errorf("invalid --%s: %v", vmoduleFlag.Name, err)
+	}
 	glogger.BacktraceAt(ctx.GlobalString(backtraceAtFlag.Name))
 	log.Root().SetHandler(glogger)
 
@@ -132,12 +187,26 @@ func Setup(ctx *cli.Context) error {
 
 	// pprof server
 	if ctx.GlobalBool(pprofFlag.Name) {
-		address := fmt.Sprintf("%s:%d", ctx.GlobalString(pprofAddrFlag.Name), ctx.GlobalInt(pprofPortFlag.Name))
-		StartPProf(address)
+		StartPProf(pprofAddress(ctx))
 	}
 	return nil
 }
 
+// pprofAddress returns the listening address of the pprof server, falling back
+// to the deprecated flags unless superseded by their replacements.
+func pprofAddress(ctx *cli.Context) string {
+	addr, port := ctx.GlobalString(pprofAddrFlag.Name), ctx.GlobalInt(pprofPortFlag.Name)
+	if ctx.GlobalIsSet(legacyPprofAddrFlag.Name) && !ctx.GlobalIsSet(pprofAddrFlag.Name) {
+		log.Warn("The flag --pprofaddr is deprecated and will be removed in the future, please use --pprof.addr")
+		addr = ctx.GlobalString(legacyPprofAddrFlag.Name)
+	}
+	if ctx.GlobalIsSet(legacyPprofPortFlag.Name) && !ctx.GlobalIsSet(pprofPortFlag.Name) {
+		log.Warn("The flag --pprofport is deprecated and will be removed in the future, please use --pprof.port")
+		port = ctx.GlobalInt(legacyPprofPortFlag.Name)
+	}
+	return fmt.Sprintf("%s:%d", addr, port)
+}
+
 func StartPProf(address string) {
 	// Hook go-metrics into expvar on any /debug/metrics request, load all vars
 	// from the registry into expvar, and execute regular expvar handler.
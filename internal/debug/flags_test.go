@@ -0,0 +1,51 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package debug
+
+import (
+	"flag"
+	"testing"
+
+	cli "gopkg.in/urfave/cli.v1"
+)
+
+// Tests that the pprof server address is taken from the renamed flags, falling
+// back to the deprecated ones only if the renamed ones are not set.
+func TestPprofAddress(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, "127.0.0.1:6060"},
+		{[]string{"--pprof.addr", "0.0.0.0", "--pprof.port", "7070"}, "0.0.0.0:7070"},
+		{[]string{"--pprofaddr", "0.0.0.0", "--pprofport", "7070"}, "0.0.0.0:7070"},
+		{[]string{"--pprofaddr", "0.0.0.0", "--pprof.port", "7070"}, "0.0.0.0:7070"},
+		{[]string{"--pprofaddr", "0.0.0.0", "--pprof.addr", "10.0.0.1", "--pprofport", "7070", "--pprof.port", "8080"}, "10.0.0.1:8080"},
+	}
+	for i, tt := range tests {
+		set := flag.NewFlagSet("test", flag.ContinueOnError)
+		for _, f := range Flags {
+			f.Apply(set)
+		}
+		if err := set.Parse(tt.args); err != nil {
+			t.Fatalf("test %d: failed to parse flags %v: %v", i, tt.args, err)
+		}
+		if have := pprofAddress(cli.NewContext(cli.NewApp(), set, nil)); have != tt.want {
+			t.Errorf("test %d: address mismatch: have %s, want %s", i, have, tt.want)
+		}
+	}
+}
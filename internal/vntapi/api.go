@@ -30,11 +30,13 @@ import (
 	"github.com/syndtr/goleveldb/leveldb/util"
 	"github.com/vntchain/go-vnt/accounts"
 	"github.com/vntchain/go-vnt/accounts/keystore"
+	"github.com/vntchain/go-vnt/accounts/typeddata"
 	"github.com/vntchain/go-vnt/common"
 	"github.com/vntchain/go-vnt/common/hexutil"
 	"github.com/vntchain/go-vnt/common/math"
 	"github.com/vntchain/go-vnt/core"
 	"github.com/vntchain/go-vnt/core/rawdb"
+	"github.com/vntchain/go-vnt/core/state"
 	"github.com/vntchain/go-vnt/core/types"
 	"github.com/vntchain/go-vnt/core/vm"
 	"github.com/vntchain/go-vnt/core/vm/election"
@@ -52,6 +54,10 @@ const (
 	defaultGasPrice = 50 * params.Gwei
 )
 
+// errUnprotectedTx is returned if a raw transaction without replay protection is
+// submitted while the node is configured to only accept protected ones.
+var errUnprotectedTx = errors.New("only replay-protected (chain id signed) transactions allowed over RPC")
+
 // PublicVntAPI provides an API to access VNT related information.
 // It offers only methods that operate on public data that is freely available to anyone.
 type PublicVntAPI struct {
@@ -81,6 +87,10 @@ func (s *PublicVntAPI) ProtocolVersion() hexutil.Uint {
 // - highestBlock:  block number of the highest block header this node has received from peers
 // - pulledStates:  number of state entries processed until now
 // - knownStates:   number of known state entries that still need to be pulled
+// - stage:         stage of the sync cycle (idle, ancestor, blocks or state)
+// - pulledHeaders, pulledBodies, pulledReceipts: number of items downloaded since the sync started
+// - headerRate, bodyRate, receiptRate, stateRate: items retrieved per second since the sync started
+// - remaining:     estimated number of seconds until the sync completes, 0 if unknown
 func (s *PublicVntAPI) Syncing() (interface{}, error) {
 	progress := s.b.Downloader().Progress()
 
@@ -90,11 +100,20 @@ func (s *PublicVntAPI) Syncing() (interface{}, error) {
 	}
 	// Otherwise gather the block sync stats
 	return map[string]interface{}{
-		"startingBlock": hexutil.Uint64(progress.StartingBlock),
-		"currentBlock":  hexutil.Uint64(progress.CurrentBlock),
-		"highestBlock":  hexutil.Uint64(progress.HighestBlock),
-		"pulledStates":  hexutil.Uint64(progress.PulledStates),
-		"knownStates":   hexutil.Uint64(progress.KnownStates),
+		"startingBlock":  hexutil.Uint64(progress.StartingBlock),
+		"currentBlock":   hexutil.Uint64(progress.CurrentBlock),
+		"highestBlock":   hexutil.Uint64(progress.HighestBlock),
+		"pulledStates":   hexutil.Uint64(progress.PulledStates),
+		"knownStates":    hexutil.Uint64(progress.KnownStates),
+		"stage":          progress.Stage,
+		"pulledHeaders":  hexutil.Uint64(progress.PulledHeaders),
+		"pulledBodies":   hexutil.Uint64(progress.PulledBodies),
+		"pulledReceipts": hexutil.Uint64(progress.PulledReceipts),
+		"headerRate":     progress.HeaderRate,
+		"bodyRate":       progress.BodyRate,
+		"receiptRate":    progress.ReceiptRate,
+		"stateRate":      progress.StateRate,
+		"remaining":      hexutil.Uint64(progress.Remaining / time.Second),
 	}, nil
 }
 
@@ -120,7 +139,7 @@ func (s *PublicTxPoolAPI) Content() map[string]map[string]map[string]*RPCTransac
 	for account, txs := range pending {
 		dump := make(map[string]*RPCTransaction)
 		for _, tx := range txs {
-			dump[fmt.Sprintf("%d", tx.Nonce())] = newRPCPendingTransaction(tx)
+			dump[fmt.Sprintf("%d", tx.Nonce())] = NewRPCPendingTransaction(tx)
 		}
 		content["pending"][account.Hex()] = dump
 	}
@@ -128,13 +147,30 @@ func (s *PublicTxPoolAPI) Content() map[string]map[string]map[string]*RPCTransac
 	for account, txs := range queue {
 		dump := make(map[string]*RPCTransaction)
 		for _, tx := range txs {
-			dump[fmt.Sprintf("%d", tx.Nonce())] = newRPCPendingTransaction(tx)
+			dump[fmt.Sprintf("%d", tx.Nonce())] = NewRPCPendingTransaction(tx)
 		}
 		content["queued"][account.Hex()] = dump
 	}
 	return content
 }
 
+// ContentFrom returns the transactions contained within the transaction pool
+// which were sent by the given account.
+func (s *PublicTxPoolAPI) ContentFrom(addr common.Address) map[string]map[string]*RPCTransaction {
+	content := make(map[string]map[string]*RPCTransaction, 2)
+	pending, queue := s.b.TxPoolContent()
+
+	// Build the pending and queued transactions of the account
+	for kind, txs := range map[string]types.Transactions{"pending": pending[addr], "queued": queue[addr]} {
+		dump := make(map[string]*RPCTransaction, len(txs))
+		for _, tx := range txs {
+			dump[fmt.Sprintf("%d", tx.Nonce())] = NewRPCPendingTransaction(tx)
+		}
+		content[kind] = dump
+	}
+	return content
+}
+
 // Status returns the number of pending and queued transaction in the pool.
 func (s *PublicTxPoolAPI) Status() map[string]hexutil.Uint {
 	pending, queue := s.b.Stats()
@@ -144,6 +180,24 @@ func (s *PublicTxPoolAPI) Status() map[string]hexutil.Uint {
 	}
 }
 
+// Dump returns the pending and queued transactions of the pool grouped by
+// account, along with the nonce gaps holding back the queued ones.
+func (s *PublicTxPoolAPI) Dump(ctx context.Context) (core.TxPoolDump, error) {
+	var (
+		err    error
+		nonces = make(map[common.Address]uint64)
+	)
+	pending, queued := s.b.TxPoolContent()
+	for _, content := range []map[common.Address]types.Transactions{pending, queued} {
+		for account := range content {
+			if nonces[account], err = s.b.GetPoolNonce(ctx, account); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return core.NewTxPoolDump(pending, queued, func(addr common.Address) uint64 { return nonces[addr] }), nil
+}
+
 // Inspect retrieves the content of the transaction pool and flattens it into an
 // easily inspectable list.
 func (s *PublicTxPoolAPI) Inspect() map[string]map[string]map[string]string {
@@ -219,15 +273,47 @@ func NewPrivateAccountAPI(b Backend, nonceLock *AddrLocker) *PrivateAccountAPI {
 	}
 }
 
+// labelledAccount is the address of an account along with its keystore label.
+type labelledAccount struct {
+	Address common.Address `json:"address"`
+	Name    string         `json:"name,omitempty"`
+	Tags    []string       `json:"tags,omitempty"`
+}
+
 // ListAccounts will return a list of addresses for accounts this node manages.
-func (s *PrivateAccountAPI) ListAccounts() []common.Address {
-	addresses := make([]common.Address, 0) // return [] instead of nil if empty
+// If withLabels is set, the accounts are listed along with their names and tags.
+func (s *PrivateAccountAPI) ListAccounts(withLabels *bool) (interface{}, error) {
+	if withLabels == nil || !*withLabels {
+		addresses := make([]common.Address, 0) // return [] instead of nil if empty
+		for _, wallet := range s.am.Wallets() {
+			for _, account := range wallet.Accounts() {
+				addresses = append(addresses, account.Address)
+			}
+		}
+		return addresses, nil
+	}
+	labels, err := fetchKeystore(s.am).Labels()
+	if err != nil {
+		return nil, err
+	}
+	accounts := make([]labelledAccount, 0)
 	for _, wallet := range s.am.Wallets() {
 		for _, account := range wallet.Accounts() {
-			addresses = append(addresses, account.Address)
+			label := labels[account.Address]
+			accounts = append(accounts, labelledAccount{Address: account.Address, Name: label.Name, Tags: label.Tags})
 		}
 	}
-	return addresses
+	return accounts, nil
+}
+
+// SetAccountLabel attaches a name and optional tags to a keystore account. An
+// empty name without tags removes the label.
+func (s *PrivateAccountAPI) SetAccountLabel(addr common.Address, name string, tags *[]string) error {
+	label := keystore.AccountLabel{Name: name}
+	if tags != nil {
+		label.Tags = *tags
+	}
+	return fetchKeystore(s.am).SetLabel(addr, label)
 }
 
 // rawWallet is a JSON representation of an accounts.Wallet interface, with its
@@ -258,6 +344,22 @@ func (s *PrivateAccountAPI) ListWallets() []rawWallet {
 	return wallets
 }
 
+// OpenWallet initiates a hardware wallet opening procedure, establishing a USB
+// connection and attempting to authenticate via the provided passphrase. Note,
+// the method may return an extra challenge requiring a second open (e.g. the
+// Trezor PIN matrix challenge).
+func (s *PrivateAccountAPI) OpenWallet(url string, passphrase *string) error {
+	wallet, err := s.am.Wallet(url)
+	if err != nil {
+		return err
+	}
+	pass := ""
+	if passphrase != nil {
+		pass = *passphrase
+	}
+	return wallet.Open(pass)
+}
+
 // DeriveAccount requests a HD wallet to derive a new account, optionally pinning
 // it for later reuse.
 func (s *PrivateAccountAPI) DeriveAccount(url string, path string, pin *bool) (accounts.Account, error) {
@@ -304,6 +406,12 @@ func (s *PrivateAccountAPI) ImportRawKey(privkey string, password string) (commo
 // the given password for duration seconds. If duration is nil it will use a
 // default of 300 seconds. It returns an indication if the account was unlocked.
 func (s *PrivateAccountAPI) UnlockAccount(addr common.Address, password string, duration *uint64) (bool, error) {
+	// When the API is exposed by external RPC(http, ws etc), unless the user
+	// explicitly specifies to allow the insecure account unlocking, otherwise
+	// it is disabled.
+	if s.b.ExtRPCEnabled() && !s.b.AccountManager().Config().InsecureUnlockAllowed {
+		return false, errors.New("account unlock with HTTP access is forbidden")
+	}
 	const max = uint64(time.Duration(math.MaxInt64) / time.Second)
 	var d time.Duration
 	if duration == nil {
@@ -322,7 +430,8 @@ func (s *PrivateAccountAPI) LockAccount(addr common.Address) bool {
 	return fetchKeystore(s.am).Lock(addr) == nil
 }
 
-// signTransactions sets defaults and signs the given transaction
+// signTransactions sets defaults and signs the given transaction. Unlocked
+// accounts sign without a passphrase.
 // NOTE: the caller needs to ensure that the nonceLock is held, if applicable,
 // and release it after the transaction has been submitted to the tx pool
 func (s *PrivateAccountAPI) signTransaction(ctx context.Context, args SendTxArgs, passwd string) (*types.Transaction, error) {
@@ -340,6 +449,11 @@ func (s *PrivateAccountAPI) signTransaction(ctx context.Context, args SendTxArgs
 	tx := args.toTransaction()
 
 	chainID := s.b.ChainConfig().ChainID
+	if passwd == "" {
+		if signed, err := wallet.SignTx(account, tx, chainID); err == nil {
+			return signed, nil
+		}
+	}
 	return wallet.SignTxWithPassphrase(account, passwd, tx, chainID)
 }
 
@@ -357,13 +471,21 @@ func (s *PrivateAccountAPI) SendTransaction(ctx context.Context, args SendTxArgs
 	if err != nil {
 		return common.Hash{}, err
 	}
+	// Sponsored transactions are signed by their fee payer too
+	if args.FeePayer != nil {
+		if signed, err = signFeePayer(s.b, signed); err != nil {
+			return common.Hash{}, err
+		}
+	}
 	return submitTransaction(ctx, s.b, signed)
 }
 
 // SignTransaction will create a transaction from the given arguments and
-// tries to sign it with the key associated with args.To. If the given passwd isn't
-// able to decrypt the key it fails. The transaction is returned in RLP-form, not broadcast
-// to other nodes
+// tries to sign it with the key associated with args.From. If the given passwd isn't
+// able to decrypt the key it fails, unless it's empty and the account is unlocked.
+// Sponsored transactions are signed by their unlocked fee payer too. The
+// transaction is returned in RLP-form, not broadcast to other nodes, so that it
+// can be submitted later or elsewhere with core_sendRawTransaction.
 func (s *PrivateAccountAPI) SignTransaction(ctx context.Context, args SendTxArgs, passwd string) (*SignTransactionResult, error) {
 	// No need to obtain the noncelock mutex, since we won't be sending this
 	// tx into the transaction pool, but right back to the user
@@ -380,6 +502,11 @@ func (s *PrivateAccountAPI) SignTransaction(ctx context.Context, args SendTxArgs
 	if err != nil {
 		return nil, err
 	}
+	if args.FeePayer != nil {
+		if signed, err = signFeePayer(s.b, signed); err != nil {
+			return nil, err
+		}
+	}
 	data, err := rlp.EncodeToBytes(signed)
 	if err != nil {
 		return nil, err
@@ -425,6 +552,38 @@ func (s *PrivateAccountAPI) Sign(ctx context.Context, data hexutil.Bytes, addr c
 	return signature, nil
 }
 
+// typedDataSigner is implemented by wallets that sign typed data themselves, such
+// as external signers showing the data to their user instead of a blind hash.
+type typedDataSigner interface {
+	SignTypedData(account accounts.Account, data *typeddata.TypedData) ([]byte, error)
+}
+
+// SignTypedData calculates an ECDSA signature over the EIP-712 hash of the typed
+// structured data, with V being 27 or 28 as for Sign.
+//
+// The key used to calculate the signature is decrypted with the given password.
+func (s *PrivateAccountAPI) SignTypedData(ctx context.Context, data typeddata.TypedData, addr common.Address, passwd string) (hexutil.Bytes, error) {
+	account := accounts.Account{Address: addr}
+
+	wallet, err := s.b.AccountManager().Find(account)
+	if err != nil {
+		return nil, err
+	}
+	if signer, ok := wallet.(typedDataSigner); ok {
+		return signer.SignTypedData(account, &data)
+	}
+	hash, err := data.SigHash()
+	if err != nil {
+		return nil, err
+	}
+	signature, err := wallet.SignHashWithPassphrase(account, passwd, hash)
+	if err != nil {
+		return nil, err
+	}
+	signature[64] += 27 // Transform V from 0/1 to 27/28 according to the yellow paper
+	return signature, nil
+}
+
 // EcRecover returns the address for the account that was used to create the signature.
 // Note, this function is compatible with eth_sign and personal_sign. As such it recovers
 // the address of:
@@ -546,6 +705,81 @@ func (s *PublicBlockChainAPI) GetStorageAt(ctx context.Context, address common.A
 	return res[:], state.Error()
 }
 
+// AccountResult is the Merkle proof of an account and some of its storage slots.
+type AccountResult struct {
+	Address      common.Address  `json:"address"`
+	AccountProof []string        `json:"accountProof"`
+	Balance      *hexutil.Big    `json:"balance"`
+	CodeHash     common.Hash     `json:"codeHash"`
+	Nonce        hexutil.Uint64  `json:"nonce"`
+	StorageHash  common.Hash     `json:"storageHash"`
+	StorageProof []StorageResult `json:"storageProof"`
+}
+
+// StorageResult is the Merkle proof of a storage slot.
+type StorageResult struct {
+	Key   string       `json:"key"`
+	Value *hexutil.Big `json:"value"`
+	Proof []string     `json:"proof"`
+}
+
+// GetProof returns the Merkle proof of the account at the given address and of
+// the given storage keys against the state root of the given block.
+func (s *PublicBlockChainAPI) GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNr rpc.BlockNumber) (*AccountResult, error) {
+	state, _, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil || err != nil {
+		return nil, err
+	}
+	var (
+		storageTrie  = state.StorageTrie(address)
+		storageHash  = types.EmptyRootHash
+		codeHash     = state.GetCodeHash(address)
+		storageProof = make([]StorageResult, len(storageKeys))
+	)
+	// If the account has a storage trie, prove the requested keys against it
+	if storageTrie != nil {
+		storageHash = storageTrie.Hash()
+	} else {
+		// No storage trie means the account does not exist, so the code hash is empty
+		codeHash = crypto.Keccak256Hash(nil)
+	}
+	for i, key := range storageKeys {
+		if storageTrie == nil {
+			storageProof[i] = StorageResult{key, &hexutil.Big{}, []string{}}
+			continue
+		}
+		proof, err := state.GetStorageProof(address, common.HexToHash(key))
+		if err != nil {
+			return nil, err
+		}
+		value := state.GetState(address, common.HexToHash(key)).Big()
+		storageProof[i] = StorageResult{key, (*hexutil.Big)(value), toHexSlice(proof)}
+	}
+	// Create the account proof
+	accountProof, err := state.GetProof(address)
+	if err != nil {
+		return nil, err
+	}
+	return &AccountResult{
+		Address:      address,
+		AccountProof: toHexSlice(accountProof),
+		Balance:      (*hexutil.Big)(state.GetBalance(address)),
+		CodeHash:     codeHash,
+		Nonce:        hexutil.Uint64(state.GetNonce(address)),
+		StorageHash:  storageHash,
+		StorageProof: storageProof,
+	}, state.Error()
+}
+
+// toHexSlice creates a slice of hex-strings based on []byte.
+func toHexSlice(b [][]byte) []string {
+	r := make([]string, len(b))
+	for i := range b {
+		r[i] = hexutil.Encode(b[i])
+	}
+	return r
+}
+
 // CallArgs represents the arguments for a call.
 type CallArgs struct {
 	From     common.Address  `json:"from"`
@@ -556,11 +790,59 @@ type CallArgs struct {
 	Data     hexutil.Bytes   `json:"data"`
 }
 
-func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, vmCfg vm.Config, timeout time.Duration) ([]byte, uint64, bool, error) {
+// OverrideAccount indicates the overriding fields of an account during the
+// execution of a message call. The state and stateDiff fields are mutually
+// exclusive: state replaces the whole account storage, while stateDiff only
+// replaces the given slots.
+type OverrideAccount struct {
+	Nonce     *hexutil.Uint64              `json:"nonce"`
+	Code      *hexutil.Bytes               `json:"code"`
+	Balance   *hexutil.Big                 `json:"balance"`
+	State     *map[common.Hash]common.Hash `json:"state"`
+	StateDiff *map[common.Hash]common.Hash `json:"stateDiff"`
+}
+
+// StateOverride is the collection of overridden accounts.
+type StateOverride map[common.Address]OverrideAccount
+
+// Apply overrides the fields of the specified accounts in the given state.
+func (diff *StateOverride) Apply(state *state.StateDB) error {
+	if diff == nil {
+		return nil
+	}
+	for addr, account := range *diff {
+		if account.State != nil && account.StateDiff != nil {
+			return fmt.Errorf("account %s has both 'state' and 'stateDiff'", addr.Hex())
+		}
+		if account.Nonce != nil {
+			state.SetNonce(addr, uint64(*account.Nonce))
+		}
+		if account.Code != nil {
+			state.SetCode(addr, *account.Code)
+		}
+		if account.Balance != nil {
+			state.SetBalance(addr, (*big.Int)(account.Balance))
+		}
+		if account.State != nil {
+			state.SetStorage(addr, *account.State)
+		}
+		if account.StateDiff != nil {
+			for key, value := range *account.StateDiff {
+				state.SetState(addr, key, value)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, overrides *StateOverride, vmCfg vm.Config) (*core.ExecutionResult, error) {
 	defer func(start time.Time) { log.Debug("Executing EVM call finished", "runtime", time.Since(start)) }(time.Now())
 	state, header, err := s.b.StateAndHeaderByNumber(ctx, blockNr)
 	if state == nil || err != nil {
-		return nil, 0, false, err
+		return nil, err
+	}
+	if err := overrides.Apply(state); err != nil {
+		return nil, err
 	}
 	// Set sender address or use a default if none specified
 	addr := args.From
@@ -576,14 +858,20 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	if gas == 0 {
 		gas = math.MaxUint64 / 2
 	}
+	capped := false
+	if gasCap := s.b.RPCGasCap(); gasCap != 0 && gas > gasCap {
+		log.Debug("Caller gas above allowance, capping", "requested", gas, "cap", gasCap)
+		gas, capped = gasCap, true
+	}
 	if gasPrice.Sign() == 0 {
 		gasPrice = new(big.Int).SetUint64(defaultGasPrice)
 	}
 	// Create new call message
 	msg := types.NewMessage(addr, args.To, 0, args.Value.ToInt(), gas, gasPrice, args.Data, false)
 	// Setup context so it may be cancelled the call has completed
-	// or, in case of unmetered gas, setup a context with a timeout.
+	// or, in case of a configured limit, setup a context with a timeout.
 	var cancel context.CancelFunc
+	timeout := s.b.RPCEVMTimeout()
 	if timeout > 0 {
 		ctx, cancel = context.WithTimeout(ctx, timeout)
 	} else {
@@ -595,7 +883,7 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	// Get a new instance of the EVM.
 	evm, vmError, err := s.b.GetVM(ctx, msg, state, header, vmCfg)
 	if err != nil {
-		return nil, 0, false, err
+		return nil, err
 	}
 	// Wait for the context to be done and cancel the evm. Even if the
 	// EVM has finished, cancelling may be done (repeatedly)
@@ -607,18 +895,57 @@ func (s *PublicBlockChainAPI) doCall(ctx context.Context, args CallArgs, blockNr
 	// Setup the gas pool (also for unmetered requests)
 	// and apply the message.
 	gp := new(core.GasPool).AddGas(math.MaxUint64)
-	res, gas, failed, err := core.ApplyMessage(evm, msg, gp)
+	result, err := core.ApplyMessage(evm, msg, gp)
 	if err := vmError(); err != nil {
-		return nil, 0, false, err
+		return nil, err
+	}
+	// Report the node's limits if they aborted the execution
+	if timeout > 0 && ctx.Err() == context.DeadlineExceeded {
+		return nil, &callLimitError{limit: "timeout", value: timeout.String()}
+	}
+	if err != nil {
+		return nil, err
 	}
-	return res, gas, failed, err
+	if capped && result.Failed() && result.UsedGas == gas {
+		return nil, &callLimitError{limit: "gascap", value: fmt.Sprintf("%d", gas)}
+	}
+	return result, nil
+}
+
+// callLimitError is returned if a call or a gas estimation is aborted by the gas
+// cap or the execution timeout the node enforces on RPC requests.
+type callLimitError struct {
+	limit string // Name of the limit hit, gascap or timeout
+	value string // Configured value of the limit
+}
+
+func (e *callLimitError) Error() string {
+	return fmt.Sprintf("execution aborted (%s = %s)", e.limit, e.value)
+}
+
+// ErrorCode returns the JSON-RPC error code of exceeded limits.
+func (e *callLimitError) ErrorCode() int { return -32005 }
+
+// ErrorData returns the limit hit along with its configured value.
+func (e *callLimitError) ErrorData() interface{} {
+	return map[string]string{"limit": e.limit, "value": e.value}
 }
 
 // Call executes the given transaction on the state for the given block number.
 // It doesn't make and changes in the state/blockchain and is useful to execute and retrieve values.
-func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
-	result, _, _, err := s.doCall(ctx, args, blockNr, vm.Config{}, 5*time.Second)
-	return (hexutil.Bytes)(result), err
+//
+// Additionally, the caller can specify a batch of accounts to override before
+// executing the call, e.g. to impersonate an account or to inject mock code.
+func (s *PublicBlockChainAPI) Call(ctx context.Context, args CallArgs, blockNr rpc.BlockNumber, overrides *StateOverride) (hexutil.Bytes, error) {
+	result, err := s.doCall(ctx, args, blockNr, overrides, vm.Config{})
+	if err != nil {
+		return nil, err
+	}
+	// Surface the reason the contract gave if it reverted
+	if len(result.Revert()) > 0 {
+		return nil, newRevertError(result)
+	}
+	return (hexutil.Bytes)(result.Return()), result.Err
 }
 
 // EstimateGas returns an estimate of the amount of gas needed to execute the
@@ -640,22 +967,36 @@ func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (h
 		}
 		hi = block.GasLimit()
 	}
+	// Never search beyond the gas cap of the node
+	capped := false
+	if gasCap := s.b.RPCGasCap(); gasCap != 0 && hi > gasCap {
+		log.Debug("Caller gas above allowance, capping", "requested", hi, "cap", gasCap)
+		hi, capped = gasCap, true
+	}
 	cap = hi
 
-	// Create a helper to check if a gas allowance results in an executable transaction
-	executable := func(gas uint64) bool {
+	// Create a helper to check if a gas allowance results in an executable transaction,
+	// aborting the search altogether if the execution hits the node's limits
+	executable := func(gas uint64) (bool, *core.ExecutionResult, error) {
 		args.Gas = hexutil.Uint64(gas)
 
-		_, _, failed, err := s.doCall(ctx, args, rpc.PendingBlockNumber, vm.Config{}, 0)
-		if err != nil || failed {
-			return false
+		result, err := s.doCall(ctx, args, rpc.PendingBlockNumber, nil, vm.Config{})
+		if _, ok := err.(*callLimitError); ok {
+			return false, nil, err
 		}
-		return true
+		if err != nil || result.Failed() {
+			return false, result, nil
+		}
+		return true, result, nil
 	}
 	// Execute the binary search and hone in on an executable gas limit
 	for lo+1 < hi {
 		mid := (hi + lo) / 2
-		if !executable(mid) {
+		ok, _, err := executable(mid)
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
 			lo = mid
 		} else {
 			hi = mid
@@ -663,7 +1004,17 @@ func (s *PublicBlockChainAPI) EstimateGas(ctx context.Context, args CallArgs) (h
 	}
 	// Reject the transaction as invalid if it still fails at the highest allowance
 	if hi == cap {
-		if !executable(hi) {
+		ok, result, err := executable(hi)
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			if result != nil && len(result.Revert()) > 0 {
+				return 0, newRevertError(result)
+			}
+			if capped {
+				return 0, &callLimitError{limit: "gascap", value: fmt.Sprintf("%d", cap)}
+			}
 			return 0, fmt.Errorf("gas required exceeds allowance or always failing transaction")
 		}
 	}
@@ -684,7 +1035,11 @@ func (s *PublicBlockChainAPI) GetAllCandidates(ctx context.Context) ([]rpc.Candi
 		return nil, nil
 	}
 
-	// Transform to rpc candidate
+	return newRPCCandidates(list), nil
+}
+
+// newRPCCandidates transforms an election candidate list into its RPC form.
+func newRPCCandidates(list election.CandidateList) []rpc.Candidate {
 	rpcCandidates := make([]rpc.Candidate, len(list))
 	for i, ca := range list {
 		rpcCandidates[i].Owner = ca.Owner.String()
@@ -697,7 +1052,7 @@ func (s *PublicBlockChainAPI) GetAllCandidates(ctx context.Context) ([]rpc.Candi
 		rpcCandidates[i].LastExtractTime = (*hexutil.Big)(ca.LastExtractTime)
 		rpcCandidates[i].Website = string(ca.Website)
 	}
-	return rpcCandidates, nil
+	return rpcCandidates
 }
 
 // GetVoter returns a voter's information.
@@ -709,13 +1064,18 @@ func (s *PublicBlockChainAPI) GetVoter(ctx context.Context, address common.Addre
 		return nil, err
 	}
 
+	return newRPCVoter(election.GetVoter(stateDB, address)), nil
+}
+
+// newRPCVoter transforms an election voter into its RPC form, returning nil if
+// the address never voted.
+func newRPCVoter(v *election.Voter) *rpc.Voter {
 	// Fill voter information
 	empty := common.Address{}
-	v := election.GetVoter(stateDB, address)
 	if v == nil || v.Owner == empty {
-		return nil, nil
+		return nil
 	}
-	voter := &rpc.Voter{
+	return &rpc.Voter{
 		Owner:             v.Owner,
 		IsProxy:           v.IsProxy,
 		ProxyVoteCount:    v.ProxyVoteCount,
@@ -724,8 +1084,6 @@ func (s *PublicBlockChainAPI) GetVoter(ctx context.Context, address common.Addre
 		LastVoteTimeStamp: v.TimeStamp,
 		VoteCandidates:    v.VoteCandidates,
 	}
-
-	return voter, nil
 }
 
 // GetStake returns a stake information.
@@ -770,13 +1128,15 @@ func (s *PublicBlockChainAPI) GetRestVNTBounty(ctx context.Context) (*big.Int, e
 
 // ExecutionResult groups all structured logs emitted by the EVM
 // while replaying a transaction in debug mode as well as transaction
-// execution status, the amount of gas used and the return value
+// execution status, the amount of gas used, the return value and the reason
+// the transaction reverted with, if any
 type ExecutionResult struct {
-	Gas         uint64         `json:"gas"`
-	Failed      bool           `json:"failed"`
-	ReturnValue string         `json:"returnValue"`
-	StructLogs  []StructLogRes `json:"structLogs"`
-	DebugLogs   []DebugLogRes  `json:"debugLogs"`
+	Gas          uint64         `json:"gas"`
+	Failed       bool           `json:"failed"`
+	ReturnValue  string         `json:"returnValue"`
+	RevertReason string         `json:"revertReason,omitempty"`
+	StructLogs   []StructLogRes `json:"structLogs"`
+	DebugLogs    []DebugLogRes  `json:"debugLogs"`
 }
 
 // StructLogRes stores a structured log emitted by the EVM while replaying a
@@ -920,6 +1280,10 @@ type RPCTransaction struct {
 	V                *hexutil.Big    `json:"v"`
 	R                *hexutil.Big    `json:"r"`
 	S                *hexutil.Big    `json:"s"`
+	FeePayer         *common.Address `json:"feePayer,omitempty"`
+	FeePayerV        *hexutil.Big    `json:"feePayerV,omitempty"`
+	FeePayerR        *hexutil.Big    `json:"feePayerR,omitempty"`
+	FeePayerS        *hexutil.Big    `json:"feePayerS,omitempty"`
 }
 
 // newRPCTransaction returns a transaction that will serialize to the RPC
@@ -942,6 +1306,11 @@ func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber
 		R:        (*hexutil.Big)(r),
 		S:        (*hexutil.Big)(s),
 	}
+	if payer := tx.FeePayer(); payer != nil {
+		v, r, s := tx.RawFeePayerSignatureValues()
+		result.FeePayer = payer
+		result.FeePayerV, result.FeePayerR, result.FeePayerS = (*hexutil.Big)(v), (*hexutil.Big)(r), (*hexutil.Big)(s)
+	}
 	if blockHash != (common.Hash{}) {
 		result.BlockHash = blockHash
 		result.BlockNumber = (*hexutil.Big)(new(big.Int).SetUint64(blockNumber))
@@ -950,8 +1319,8 @@ func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber
 	return result
 }
 
-// newRPCPendingTransaction returns a pending transaction that will serialize to the RPC representation
-func newRPCPendingTransaction(tx *types.Transaction) *RPCTransaction {
+// NewRPCPendingTransaction returns a pending transaction that will serialize to the RPC representation
+func NewRPCPendingTransaction(tx *types.Transaction) *RPCTransaction {
 	return newRPCTransaction(tx, common.Hash{}, 0, 0)
 }
 
@@ -1063,7 +1432,7 @@ func (s *PublicTransactionPoolAPI) GetTransactionByHash(ctx context.Context, has
 	}
 	// No finalized transaction, try to retrieve it from the pool
 	if tx := s.b.GetPoolTransaction(hash); tx != nil {
-		return newRPCPendingTransaction(tx)
+		return NewRPCPendingTransaction(tx)
 	}
 	// Transaction unknown, return as such
 	return nil
@@ -1158,6 +1527,10 @@ type SendTxArgs struct {
 	// newer name and should be preferred by clients.
 	Data  *hexutil.Bytes `json:"data"`
 	Input *hexutil.Bytes `json:"input"`
+
+	// Account paying for the gas instead of the sender, which makes the
+	// transaction a sponsored one, needing the signature of both.
+	FeePayer *common.Address `json:"feePayer"`
 }
 
 // setDefaults is a helper function that fills in default values for unspecified tx fields.
@@ -1208,16 +1581,43 @@ func (args *SendTxArgs) toTransaction() *types.Transaction {
 	} else if args.Input != nil {
 		input = *args.Input
 	}
+	var tx *types.Transaction
 	if args.To == nil {
-		return types.NewContractCreation(uint64(*args.Nonce), (*big.Int)(args.Value), uint64(*args.Gas), (*big.Int)(args.GasPrice), input)
+		tx = types.NewContractCreation(uint64(*args.Nonce), (*big.Int)(args.Value), uint64(*args.Gas), (*big.Int)(args.GasPrice), input)
+	} else {
+		tx = types.NewTransaction(uint64(*args.Nonce), *args.To, (*big.Int)(args.Value), uint64(*args.Gas), (*big.Int)(args.GasPrice), input)
+	}
+	if args.FeePayer != nil {
+		tx = tx.WithFeePayer(*args.FeePayer)
 	}
-	return types.NewTransaction(uint64(*args.Nonce), *args.To, (*big.Int)(args.Value), uint64(*args.Gas), (*big.Int)(args.GasPrice), input)
+	return tx
+}
+
+// signFeePayer signs a sponsored transaction, already signed by its sender, with
+// the wallet of its fee payer.
+func signFeePayer(b Backend, tx *types.Transaction) (*types.Transaction, error) {
+	payer := tx.FeePayer()
+	if payer == nil {
+		return nil, types.ErrNotSponsored
+	}
+	account := accounts.Account{Address: *payer}
+
+	wallet, err := b.AccountManager().Find(account)
+	if err != nil {
+		return nil, err
+	}
+	signer := types.NewHubbleSigner(b.ChainConfig().ChainID)
+	sig, err := wallet.SignHash(account, signer.FeePayerHash(tx).Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return tx.WithFeePayerSignature(signer, sig)
 }
 
 // submitTransaction is a helper function that submits tx to txPool and logs a message.
 func submitTransaction(ctx context.Context, b Backend, tx *types.Transaction) (common.Hash, error) {
 	if err := b.SendTx(ctx, tx); err != nil {
-		return common.Hash{}, err
+		return common.Hash{}, wrapTxPoolError(err)
 	}
 	if tx.To() == nil {
 		signer := types.MakeSigner(b.ChainConfig(), b.CurrentBlock().Number())
@@ -1263,6 +1663,12 @@ func (s *PublicTransactionPoolAPI) SendTransaction(ctx context.Context, args Sen
 	if err != nil {
 		return common.Hash{}, err
 	}
+	// Sponsored transactions are signed by their fee payer too
+	if args.FeePayer != nil {
+		if signed, err = signFeePayer(s.b, signed); err != nil {
+			return common.Hash{}, err
+		}
+	}
 	return submitTransaction(ctx, s.b, signed)
 }
 
@@ -1273,9 +1679,73 @@ func (s *PublicTransactionPoolAPI) SendRawTransaction(ctx context.Context, encod
 	if err := rlp.DecodeBytes(encodedTx, tx); err != nil {
 		return common.Hash{}, err
 	}
+	if !tx.Protected() && !s.b.UnprotectedAllowed() {
+		return common.Hash{}, errUnprotectedTx
+	}
 	return submitTransaction(ctx, s.b, tx)
 }
 
+// maxRawTransactionBatch is the maximum number of transactions accepted by a
+// single SendRawTransactions call.
+const maxRawTransactionBatch = 1024
+
+// RawTransactionResult is the outcome of a transaction submitted in a batch.
+type RawTransactionResult struct {
+	Hash  *common.Hash `json:"hash"`            // Nil if the transaction failed to decode
+	Error string       `json:"error,omitempty"` // Rejection reason, empty if accepted
+}
+
+// SendRawTransactions will add a batch of signed transactions to the transaction
+// pool. The batch is validated as a whole first, the transactions passing are
+// then added to the pool together, in order. A result is returned for each of
+// the transactions, carrying its hash and rejection reason, if any.
+func (s *PublicTransactionPoolAPI) SendRawTransactions(ctx context.Context, encodedTxs []hexutil.Bytes) ([]*RawTransactionResult, error) {
+	if len(encodedTxs) > maxRawTransactionBatch {
+		return nil, fmt.Errorf("too many transactions: have %d, max %d", len(encodedTxs), maxRawTransactionBatch)
+	}
+	var (
+		results = make([]*RawTransactionResult, len(encodedTxs))
+		seen    = make(map[common.Hash]int)
+		txs     []*types.Transaction
+		indices []int // Batch positions of the transactions submitted to the pool
+	)
+	for i, encodedTx := range encodedTxs {
+		results[i] = new(RawTransactionResult)
+
+		tx := new(types.Transaction)
+		if err := rlp.DecodeBytes(encodedTx, tx); err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		hash := tx.Hash()
+		results[i].Hash = &hash
+
+		if !tx.Protected() && !s.b.UnprotectedAllowed() {
+			results[i].Error = errUnprotectedTx.Error()
+			continue
+		}
+		if j, ok := seen[hash]; ok {
+			results[i].Error = fmt.Sprintf("duplicate of transaction %d", j)
+			continue
+		}
+		seen[hash] = i
+		txs = append(txs, tx)
+		indices = append(indices, i)
+	}
+	accepted := 0
+	if len(txs) > 0 {
+		for j, err := range s.b.SendTxs(ctx, txs) {
+			if err != nil {
+				results[indices[j]].Error = wrapTxPoolError(err).Error()
+				continue
+			}
+			accepted++
+		}
+	}
+	log.Info("Submitted transaction batch", "count", len(encodedTxs), "accepted", accepted)
+	return results, nil
+}
+
 // Sign calculates an ECDSA signature for:
 // keccack256("\x19Ethereum Signed Message:\n" + len(message) + message).
 //
@@ -1301,6 +1771,32 @@ func (s *PublicTransactionPoolAPI) Sign(addr common.Address, data hexutil.Bytes)
 	return signature, err
 }
 
+// SignTypedData calculates an ECDSA signature over the EIP-712 hash of the typed
+// structured data, with V being 27 or 28 as for Sign.
+//
+// The account associated with addr must be unlocked, unless it is managed by an
+// external signer.
+func (s *PublicTransactionPoolAPI) SignTypedData(addr common.Address, data typeddata.TypedData) (hexutil.Bytes, error) {
+	account := accounts.Account{Address: addr}
+
+	wallet, err := s.b.AccountManager().Find(account)
+	if err != nil {
+		return nil, err
+	}
+	if signer, ok := wallet.(typedDataSigner); ok {
+		return signer.SignTypedData(account, &data)
+	}
+	hash, err := data.SigHash()
+	if err != nil {
+		return nil, err
+	}
+	signature, err := wallet.SignHash(account, hash)
+	if err == nil {
+		signature[64] += 27 // Transform V from 0/1 to 27/28 according to the yellow paper
+	}
+	return signature, err
+}
+
 // SignTransactionResult represents a RLP encoded signed transaction.
 type SignTransactionResult struct {
 	Raw hexutil.Bytes      `json:"raw"`
@@ -1334,6 +1830,28 @@ func (s *PublicTransactionPoolAPI) SignTransaction(ctx context.Context, args Sen
 	return &SignTransactionResult{data, tx}, nil
 }
 
+// SignFeePayerTransaction will sign a sponsored transaction, already signed by
+// its sender, with its fee payer account, which needs to be unlocked on the node.
+// The result can be submitted with SendRawTransaction.
+func (s *PublicTransactionPoolAPI) SignFeePayerTransaction(ctx context.Context, encodedTx hexutil.Bytes) (*SignTransactionResult, error) {
+	tx := new(types.Transaction)
+	if err := rlp.DecodeBytes(encodedTx, tx); err != nil {
+		return nil, err
+	}
+	if _, err := types.Sender(types.NewHubbleSigner(s.b.ChainConfig().ChainID), tx); err != nil {
+		return nil, fmt.Errorf("invalid sender signature: %v", err)
+	}
+	tx, err := signFeePayer(s.b, tx)
+	if err != nil {
+		return nil, err
+	}
+	data, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		return nil, err
+	}
+	return &SignTransactionResult{data, tx}, nil
+}
+
 // PendingTransactions returns the transactions that are in the transaction pool
 // and have a from address that is one of the accounts this node manages.
 func (s *PublicTransactionPoolAPI) PendingTransactions() ([]*RPCTransaction, error) {
@@ -1352,7 +1870,7 @@ func (s *PublicTransactionPoolAPI) PendingTransactions() ([]*RPCTransaction, err
 		signer := types.NewHubbleSigner(tx.ChainId())
 		from, _ := types.Sender(signer, tx)
 		if _, exists := accounts[from]; exists {
-			transactions = append(transactions, newRPCPendingTransaction(tx))
+			transactions = append(transactions, NewRPCPendingTransaction(tx))
 		}
 	}
 	return transactions, nil
@@ -1390,7 +1908,7 @@ func (s *PublicTransactionPoolAPI) Resend(ctx context.Context, sendArgs SendTxAr
 				return common.Hash{}, err
 			}
 			if err = s.b.SendTx(ctx, signedTx); err != nil {
-				return common.Hash{}, err
+				return common.Hash{}, wrapTxPoolError(err)
 			}
 			return signedTx.Hash(), nil
 		}
@@ -1489,9 +2007,18 @@ func (api *PrivateDebugAPI) ChaindbCompact() error {
 	return nil
 }
 
-// SetHead rewinds the head of the blockchain to a previous block.
-func (api *PrivateDebugAPI) SetHead(number hexutil.Uint64) {
-	api.b.SetHead(uint64(number))
+// SetHead rewinds the head of the blockchain to a previous block, dropping the
+// blocks above it along with their indexes. Transactions of the dropped blocks
+// are not returned to the transaction pool. Like the rollback command, rewinding
+// below the irreversible block is allowed but logged as a warning.
+func (api *PrivateDebugAPI) SetHead(number hexutil.Uint64) error {
+	if head := api.b.CurrentBlock().NumberU64(); uint64(number) > head {
+		return fmt.Errorf("block #%d is ahead of the current head #%d", uint64(number), head)
+	}
+	if irreversible, _ := api.b.HeaderByNumber(context.Background(), rpc.IrreversibleBlockNumber); irreversible != nil && uint64(number) < irreversible.Number.Uint64() {
+		log.Warn("Rolling back past the irreversible block", "number", irreversible.Number, "hash", irreversible.Hash())
+	}
+	return api.b.SetHead(uint64(number))
 }
 
 // PublicNetAPI offers network related RPC methods
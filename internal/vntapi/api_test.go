@@ -0,0 +1,386 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package vntapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/vntchain/go-vnt/accounts"
+	"github.com/vntchain/go-vnt/accounts/keystore"
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/common/hexutil"
+	"github.com/vntchain/go-vnt/core"
+	"github.com/vntchain/go-vnt/core/state"
+	"github.com/vntchain/go-vnt/core/types"
+	"github.com/vntchain/go-vnt/crypto"
+	"github.com/vntchain/go-vnt/params"
+	"github.com/vntchain/go-vnt/rlp"
+	"github.com/vntchain/go-vnt/vntdb"
+)
+
+// txTestBackend is a minimal backend recording submitted transactions. Any
+// backend method not overridden panics on use.
+type txTestBackend struct {
+	Backend
+
+	unprotected bool
+	reject      error // Error the pool rejects transactions with, if any
+	sent        []*types.Transaction
+}
+
+func (b *txTestBackend) SendTx(ctx context.Context, tx *types.Transaction) error {
+	if b.reject != nil {
+		return b.reject
+	}
+	b.sent = append(b.sent, tx)
+	return nil
+}
+
+func (b *txTestBackend) SendTxs(ctx context.Context, txs []*types.Transaction) []error {
+	errs := make([]error, len(txs))
+	for i, tx := range txs {
+		errs[i] = b.SendTx(ctx, tx)
+	}
+	return errs
+}
+
+func (b *txTestBackend) UnprotectedAllowed() bool { return b.unprotected }
+
+// Tests that raw transactions lacking replay protection are only accepted over
+// RPC if the node permits them, while protected ones are always accepted.
+func TestSendRawTransactionProtection(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	to := common.HexToAddress("0x01")
+
+	protected, err := types.SignTx(types.NewTransaction(0, to, big.NewInt(1), 21000, big.NewInt(1), nil), types.NewHubbleSigner(params.TestChainConfig.ChainID), key)
+	if err != nil {
+		t.Fatalf("failed to sign protected transaction: %v", err)
+	}
+	unprotected, err := types.SignTx(types.NewTransaction(1, to, big.NewInt(1), 21000, big.NewInt(1), nil), types.HomesteadSigner{}, key)
+	if err != nil {
+		t.Fatalf("failed to sign unprotected transaction: %v", err)
+	}
+	tests := []struct {
+		tx    *types.Transaction
+		allow bool
+		err   error
+	}{
+		{protected, true, nil},
+		{protected, false, nil},
+		{unprotected, true, nil},
+		{unprotected, false, errUnprotectedTx},
+	}
+	for i, tt := range tests {
+		backend := &txTestBackend{unprotected: tt.allow}
+		api := NewPublicTransactionPoolAPI(backend, new(AddrLocker))
+
+		blob, err := rlp.EncodeToBytes(tt.tx)
+		if err != nil {
+			t.Fatalf("test %d: failed to encode transaction: %v", i, err)
+		}
+		hash, err := api.SendRawTransaction(context.Background(), hexutil.Bytes(blob))
+		if err != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+			continue
+		}
+		if tt.err != nil {
+			if len(backend.sent) != 0 {
+				t.Errorf("test %d: rejected transaction reached the pool", i)
+			}
+			continue
+		}
+		if hash != tt.tx.Hash() {
+			t.Errorf("test %d: hash mismatch: have %x, want %x", i, hash, tt.tx.Hash())
+		}
+		if len(backend.sent) != 1 {
+			t.Errorf("test %d: pool submissions mismatch: have %d, want 1", i, len(backend.sent))
+		}
+	}
+}
+
+// Tests that a batch of raw transactions is checked as a whole, with only the
+// valid ones reaching the pool and a result reported for each of them.
+func TestSendRawTransactions(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	to := common.HexToAddress("0x01")
+	signer := types.NewHubbleSigner(params.TestChainConfig.ChainID)
+
+	var blobs []hexutil.Bytes
+	for _, signed := range []struct {
+		nonce  uint64
+		signer types.Signer
+	}{{0, signer}, {1, types.HomesteadSigner{}}, {0, signer}, {2, signer}} {
+		tx, err := types.SignTx(types.NewTransaction(signed.nonce, to, big.NewInt(1), 21000, big.NewInt(1), nil), signed.signer, key)
+		if err != nil {
+			t.Fatalf("failed to sign transaction: %v", err)
+		}
+		blob, _ := rlp.EncodeToBytes(tx)
+		blobs = append(blobs, blob)
+	}
+	blobs = append(blobs, hexutil.Bytes{0x01, 0x02})
+
+	backend := &txTestBackend{}
+	api := NewPublicTransactionPoolAPI(backend, new(AddrLocker))
+
+	results, err := api.SendRawTransactions(context.Background(), blobs)
+	if err != nil {
+		t.Fatalf("failed to send batch: %v", err)
+	}
+	accepted := []bool{true, false, false, true, false}
+	for i, result := range results {
+		if (result.Error == "") != accepted[i] {
+			t.Errorf("result %d: acceptance mismatch: have error %q, want accepted %v", i, result.Error, accepted[i])
+		}
+		if (result.Hash == nil) != (i == len(blobs)-1) {
+			t.Errorf("result %d: hash presence mismatch: have %v", i, result.Hash)
+		}
+	}
+	if len(backend.sent) != 2 {
+		t.Errorf("pool submissions mismatch: have %d, want 2", len(backend.sent))
+	}
+	// Pool rejections are reported per transaction
+	backend = &txTestBackend{reject: core.ErrNonceTooLow}
+	api = NewPublicTransactionPoolAPI(backend, new(AddrLocker))
+
+	if results, err = api.SendRawTransactions(context.Background(), blobs[:1]); err != nil {
+		t.Fatalf("failed to send batch: %v", err)
+	}
+	if results[0].Error != core.ErrNonceTooLow.Error() {
+		t.Errorf("rejection mismatch: have %q, want %q", results[0].Error, core.ErrNonceTooLow)
+	}
+	// Oversized batches are refused outright
+	if _, err := api.SendRawTransactions(context.Background(), make([]hexutil.Bytes, maxRawTransactionBatch+1)); err == nil {
+		t.Errorf("oversized batch accepted")
+	}
+}
+
+// poolTestBackend is a minimal backend serving a fixed transaction pool content.
+type poolTestBackend struct {
+	Backend
+
+	pending map[common.Address]types.Transactions
+	queued  map[common.Address]types.Transactions
+}
+
+func (b *poolTestBackend) TxPoolContent() (map[common.Address]types.Transactions, map[common.Address]types.Transactions) {
+	return b.pending, b.queued
+}
+
+// Tests that the pool content of a single account can be retrieved, without
+// leaking the transactions of other accounts.
+func TestTxPoolContentFrom(t *testing.T) {
+	signer := types.NewHubbleSigner(params.TestChainConfig.ChainID)
+	sign := func(key *ecdsa.PrivateKey, nonce uint64) *types.Transaction {
+		tx, err := types.SignTx(types.NewTransaction(nonce, common.HexToAddress("0x01"), big.NewInt(1), 21000, big.NewInt(1), nil), signer, key)
+		if err != nil {
+			t.Fatalf("failed to sign transaction: %v", err)
+		}
+		return tx
+	}
+	key1, _ := crypto.GenerateKey()
+	key2, _ := crypto.GenerateKey()
+	addr1, addr2 := crypto.PubkeyToAddress(key1.PublicKey), crypto.PubkeyToAddress(key2.PublicKey)
+
+	backend := &poolTestBackend{
+		pending: map[common.Address]types.Transactions{
+			addr1: {sign(key1, 0), sign(key1, 1)},
+			addr2: {sign(key2, 0)},
+		},
+		queued: map[common.Address]types.Transactions{
+			addr1: {sign(key1, 3)},
+		},
+	}
+	content := NewPublicTxPoolAPI(backend).ContentFrom(addr1)
+	if len(content["pending"]) != 2 || len(content["queued"]) != 1 {
+		t.Fatalf("content size mismatch: have %d/%d, want 2/1", len(content["pending"]), len(content["queued"]))
+	}
+	for kind, txs := range content {
+		for nonce, tx := range txs {
+			if tx.From != addr1 {
+				t.Errorf("%s tx %s: sender mismatch: have %x, want %x", kind, nonce, tx.From, addr1)
+			}
+		}
+	}
+	if tx := content["queued"]["3"]; tx == nil || uint64(tx.Nonce) != 3 {
+		t.Errorf("queued transaction missing: %v", tx)
+	}
+	if content := NewPublicTxPoolAPI(backend).ContentFrom(common.HexToAddress("0x02")); len(content["pending"])+len(content["queued"]) != 0 {
+		t.Errorf("unknown account returned transactions: %v", content)
+	}
+}
+
+// Tests that state overrides modify the requested account fields and reject
+// conflicting storage overrides.
+func TestStateOverrideApply(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(vntdb.NewMemDatabase()))
+	var (
+		addr    = common.HexToAddress("0x01")
+		slot    = common.HexToHash("0x0a")
+		nonce   = hexutil.Uint64(7)
+		code    = hexutil.Bytes{0x60, 0x00}
+		balance = (*hexutil.Big)(big.NewInt(1000))
+		storage = map[common.Hash]common.Hash{slot: common.HexToHash("0x01")}
+	)
+	statedb.SetState(addr, common.HexToHash("0x0b"), common.HexToHash("0x02"))
+
+	overrides := StateOverride{
+		addr: {Nonce: &nonce, Code: &code, Balance: balance, State: &storage},
+	}
+	if err := overrides.Apply(statedb); err != nil {
+		t.Fatalf("failed to apply overrides: %v", err)
+	}
+	if have := statedb.GetNonce(addr); have != 7 {
+		t.Errorf("nonce mismatch: have %d, want 7", have)
+	}
+	if have := statedb.GetCode(addr); !bytes.Equal(have, code) {
+		t.Errorf("code mismatch: have %x, want %x", have, code)
+	}
+	if have := statedb.GetBalance(addr); have.Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("balance mismatch: have %v, want 1000", have)
+	}
+	if have := statedb.GetState(addr, slot); have != common.HexToHash("0x01") {
+		t.Errorf("overridden slot mismatch: have %x, want 0x01", have)
+	}
+	if have := statedb.GetState(addr, common.HexToHash("0x0b")); have != (common.Hash{}) {
+		t.Errorf("replaced slot mismatch: have %x, want empty", have)
+	}
+	conflict := StateOverride{
+		addr: {State: &storage, StateDiff: &storage},
+	}
+	if err := conflict.Apply(statedb); err == nil {
+		t.Errorf("conflicting storage overrides accepted")
+	}
+	var none *StateOverride
+	if err := none.Apply(statedb); err != nil {
+		t.Errorf("missing overrides rejected: %v", err)
+	}
+}
+
+// unlockTestBackend is a minimal backend serving an account manager.
+type unlockTestBackend struct {
+	Backend
+
+	extRPC bool
+	am     *accounts.Manager
+}
+
+func (b *unlockTestBackend) ExtRPCEnabled() bool               { return b.extRPC }
+func (b *unlockTestBackend) AccountManager() *accounts.Manager { return b.am }
+func (b *unlockTestBackend) ChainConfig() *params.ChainConfig  { return params.TestChainConfig }
+
+// Tests that accounts can't be unlocked over RPC while the APIs are exposed over
+// HTTP or websocket, unless explicitly allowed.
+func TestUnlockAccountInsecure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "unlock-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ks := keystore.NewKeyStore(dir, keystore.LightScryptN, keystore.LightScryptP)
+	account, err := ks.NewAccount("secret")
+	if err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+	tests := []struct {
+		extRPC, insecure bool
+		unlocked         bool
+	}{
+		{false, false, true},
+		{true, false, false},
+		{true, true, true},
+	}
+	for i, tt := range tests {
+		am := accounts.NewManager(&accounts.Config{InsecureUnlockAllowed: tt.insecure}, ks)
+		api := NewPrivateAccountAPI(&unlockTestBackend{extRPC: tt.extRPC, am: am}, new(AddrLocker))
+
+		unlocked, err := api.UnlockAccount(account.Address, "secret", nil)
+		if unlocked != tt.unlocked {
+			t.Errorf("test %d: unlock mismatch: have %v (%v), want %v", i, unlocked, err, tt.unlocked)
+		}
+		ks.Lock(account.Address)
+	}
+}
+
+// Tests that transactions are signed by locked accounts with their passphrase
+// and by unlocked ones without, and returned without being submitted.
+func TestPersonalSignTransaction(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sign-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ks := keystore.NewKeyStore(dir, keystore.LightScryptN, keystore.LightScryptP)
+	account, err := ks.NewAccount("secret")
+	if err != nil {
+		t.Fatalf("failed to create account: %v", err)
+	}
+	api := NewPrivateAccountAPI(&unlockTestBackend{am: accounts.NewManager(&accounts.Config{}, ks)}, new(AddrLocker))
+
+	var (
+		to    = common.HexToAddress("0x01")
+		nonce = hexutil.Uint64(7)
+		gas   = hexutil.Uint64(21000)
+		price = (*hexutil.Big)(big.NewInt(1))
+		args  = SendTxArgs{From: account.Address, To: &to, Nonce: &nonce, Gas: &gas, GasPrice: price}
+	)
+	if _, err := api.SignTransaction(context.Background(), SendTxArgs{From: account.Address, To: &to, Gas: &gas, GasPrice: price}, "secret"); err == nil {
+		t.Errorf("transaction without nonce signed")
+	}
+	if _, err := api.SignTransaction(context.Background(), args, ""); err == nil {
+		t.Errorf("locked account signed without passphrase")
+	}
+	tests := []struct {
+		unlock bool
+		passwd string
+	}{
+		{false, "secret"},
+		{true, ""},
+	}
+	for i, tt := range tests {
+		if tt.unlock {
+			if err := ks.Unlock(account, "secret"); err != nil {
+				t.Fatalf("test %d: failed to unlock account: %v", i, err)
+			}
+		}
+		res, err := api.SignTransaction(context.Background(), args, tt.passwd)
+		if err != nil {
+			t.Fatalf("test %d: failed to sign transaction: %v", i, err)
+		}
+		tx := new(types.Transaction)
+		if err := rlp.DecodeBytes(res.Raw, tx); err != nil {
+			t.Fatalf("test %d: failed to decode signed transaction: %v", i, err)
+		}
+		if tx.Hash() != res.Tx.Hash() {
+			t.Errorf("test %d: hash mismatch: have %x, want %x", i, tx.Hash(), res.Tx.Hash())
+		}
+		from, err := types.Sender(types.NewHubbleSigner(params.TestChainConfig.ChainID), tx)
+		if err != nil || from != account.Address {
+			t.Errorf("test %d: sender mismatch: have %x (%v), want %x", i, from, err, account.Address)
+		}
+		if tx.Nonce() != uint64(nonce) {
+			t.Errorf("test %d: nonce mismatch: have %d, want %d", i, tx.Nonce(), nonce)
+		}
+	}
+}
@@ -20,6 +20,7 @@ package vntapi
 import (
 	"context"
 	"math/big"
+	"time"
 
 	"github.com/vntchain/go-vnt/accounts"
 	"github.com/vntchain/go-vnt/common"
@@ -46,7 +47,7 @@ type Backend interface {
 	AccountManager() *accounts.Manager
 
 	// BlockChain API
-	SetHead(number uint64)
+	SetHead(number uint64) error
 	HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error)
 	BlockByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Block, error)
 	StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, *types.Header, error)
@@ -60,12 +61,17 @@ type Backend interface {
 
 	// TxPool API
 	SendTx(ctx context.Context, signedTx *types.Transaction) error
+	SendTxs(ctx context.Context, signedTxs []*types.Transaction) []error
 	GetPoolTransactions() (types.Transactions, error)
 	GetPoolTransaction(txHash common.Hash) *types.Transaction
 	GetPoolNonce(ctx context.Context, addr common.Address) (uint64, error)
 	Stats() (pending int, queued int)
 	TxPoolContent() (map[common.Address]types.Transactions, map[common.Address]types.Transactions)
 	SubscribeNewTxsEvent(chan<- core.NewTxsEvent) event.Subscription
+	ExtRPCEnabled() bool
+	UnprotectedAllowed() bool
+	RPCGasCap() uint64            // global gas cap for call and estimateGas
+	RPCEVMTimeout() time.Duration // global timeout for call and estimateGas
 
 	ChainConfig() *params.ChainConfig
 	CurrentBlock() *types.Block
@@ -108,6 +114,16 @@ func GetAPIs(apiBackend Backend) []rpc.API {
 			Version:   "1.0",
 			Service:   NewPublicAccountAPI(apiBackend.AccountManager()),
 			Public:    true,
+		}, {
+			Namespace: "dpos",
+			Version:   "1.0",
+			Service:   NewPublicDposAPI(apiBackend),
+			Public:    true,
+		}, {
+			Namespace: "dpos",
+			Version:   "1.0",
+			Service:   NewPublicElectionAPI(apiBackend, nonceLock),
+			Public:    true,
 		}, {
 			Namespace: "personal",
 			Version:   "1.0",
@@ -0,0 +1,257 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package vntapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/common/hexutil"
+	"github.com/vntchain/go-vnt/core/state"
+	"github.com/vntchain/go-vnt/core/types"
+	"github.com/vntchain/go-vnt/core/vm/election"
+	"github.com/vntchain/go-vnt/rpc"
+)
+
+// PublicDposAPI provides read only access to the witness set and the election
+// state of the DPoS consensus engine.
+type PublicDposAPI struct {
+	b Backend
+}
+
+// NewPublicDposAPI creates a new DPoS election API.
+func NewPublicDposAPI(b Backend) *PublicDposAPI {
+	return &PublicDposAPI{b}
+}
+
+// WitnessSlot is an upcoming block production slot of a witness.
+type WitnessSlot struct {
+	Number  hexutil.Uint64 `json:"number"`  // Block number produced in the slot, if no slot is missed before
+	Witness common.Address `json:"witness"` // Witness in turn to produce the block
+	Time    hexutil.Uint64 `json:"time"`    // Timestamp of the slot
+}
+
+// GetWitnesses returns the witnesses active at the given block, or at the latest
+// one if none is given.
+func (api *PublicDposAPI) GetWitnesses(ctx context.Context, blockNr *rpc.BlockNumber) ([]common.Address, error) {
+	header, err := api.header(ctx, blockNr)
+	if err != nil {
+		return nil, err
+	}
+	return header.Witnesses, nil
+}
+
+// GetWitnessSchedule returns the production slots of a full round of witnesses
+// following the given block, or the latest one if none is given. The schedule
+// assumes that the witness set stays unchanged during the round.
+func (api *PublicDposAPI) GetWitnessSchedule(ctx context.Context, blockNr *rpc.BlockNumber) ([]WitnessSlot, error) {
+	config := api.b.ChainConfig().Dpos
+	if config == nil {
+		return nil, errors.New("dpos not configured")
+	}
+	header, err := api.header(ctx, blockNr)
+	if err != nil {
+		return nil, err
+	}
+	witnesses := header.Witnesses
+	if len(witnesses) == 0 {
+		return nil, fmt.Errorf("no witnesses at block #%d", header.Number)
+	}
+	// Find the latest block produced by a current witness, the slots are counted
+	// from it onwards. Without any, the round starts with the first witness.
+	var (
+		prev = -1
+		base = header
+	)
+	for base.Number.Sign() > 0 {
+		if prev = indexOfWitness(witnesses, base.Coinbase); prev >= 0 {
+			break
+		}
+		parent, err := api.b.GetBlock(ctx, base.ParentHash)
+		if err != nil {
+			return nil, err
+		}
+		if parent == nil {
+			return nil, fmt.Errorf("block #%d not found", base.Number.Uint64()-1)
+		}
+		base = parent.Header()
+	}
+	// Assemble the round, skipping the slots already passed by the given block
+	var (
+		schedule = make([]WitnessSlot, 0, len(witnesses))
+		number   = header.Number.Uint64()
+		time     = base.Time.Uint64()
+	)
+	for slot := 1; len(schedule) < len(witnesses); slot++ {
+		at := time + uint64(slot)*config.Period
+		if at <= header.Time.Uint64() {
+			continue
+		}
+		number++
+		schedule = append(schedule, WitnessSlot{
+			Number:  hexutil.Uint64(number),
+			Witness: witnesses[(prev+slot)%len(witnesses)],
+			Time:    hexutil.Uint64(at),
+		})
+	}
+	return schedule, nil
+}
+
+// WitnessLiveness is the record of the production slots missed by a witness.
+type WitnessLiveness struct {
+	Witness     common.Address `json:"witness"`     // Address of the witness
+	MissedSlots hexutil.Uint64 `json:"missedSlots"` // Production slots missed in a row
+	Penalized   bool           `json:"penalized"`   // Whether left out of the next witness list
+}
+
+// GetMissedSlots returns the production slots missed in a row by each witness
+// active at the given block, or at the latest one if none is given.
+func (api *PublicDposAPI) GetMissedSlots(ctx context.Context, blockNr *rpc.BlockNumber) ([]WitnessLiveness, error) {
+	config := api.b.ChainConfig().Dpos
+	if config == nil {
+		return nil, errors.New("dpos not configured")
+	}
+	number := rpc.LatestBlockNumber
+	if blockNr != nil {
+		number = *blockNr
+	}
+	stateDB, header, err := api.b.StateAndHeaderByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	if stateDB == nil || header == nil {
+		return nil, fmt.Errorf("block #%d not found", number)
+	}
+	liveness := make([]WitnessLiveness, 0, len(header.Witnesses))
+	for _, witness := range header.Witnesses {
+		missed := election.GetMissedSlots(stateDB, witness)
+		liveness = append(liveness, WitnessLiveness{
+			Witness:     witness,
+			MissedSlots: hexutil.Uint64(missed),
+			Penalized:   config.MaxMissedSlots > 0 && missed >= config.MaxMissedSlots,
+		})
+	}
+	return liveness, nil
+}
+
+// GetCandidates returns all the witness candidates sorted by their votes at the
+// given block, or the latest one if none is given.
+func (api *PublicDposAPI) GetCandidates(ctx context.Context, blockNr *rpc.BlockNumber) ([]rpc.Candidate, error) {
+	stateDB, err := api.state(ctx, blockNr)
+	if err != nil {
+		return nil, err
+	}
+	return newRPCCandidates(election.GetAllCandidates(stateDB, true)), nil
+}
+
+// CandidateRank is the standing of a witness candidate in the election.
+type CandidateRank struct {
+	Rank      int            `json:"rank"`      // Position by votes, starting from 1
+	Owner     common.Address `json:"owner"`     // Address of the candidate
+	Name      string         `json:"name"`      // Name of the candidate
+	Active    bool           `json:"active"`    // Whether still registered, only active ones are elected
+	VoteCount *hexutil.Big   `json:"voteCount"` // Stake weighted votes received
+	Elected   bool           `json:"elected"`   // Whether chosen as witness by the next election
+}
+
+// GetRankings returns the witness candidates ranked by their stake weighted
+// votes at the given block, or the latest one if none is given, marking the
+// ones the next election would choose as witnesses.
+func (api *PublicDposAPI) GetRankings(ctx context.Context, blockNr *rpc.BlockNumber) ([]CandidateRank, error) {
+	config := api.b.ChainConfig().Dpos
+	if config == nil {
+		return nil, errors.New("dpos not configured")
+	}
+	stateDB, err := api.state(ctx, blockNr)
+	if err != nil {
+		return nil, err
+	}
+	witnesses, _ := election.GetFirstNLiveCandidates(stateDB, config.WitnessesNum, config.MaxMissedSlots)
+	elected := make(map[common.Address]bool, len(witnesses))
+	for _, witness := range witnesses {
+		elected[witness] = true
+	}
+	candidates := election.GetAllCandidates(stateDB, true)
+	rankings := make([]CandidateRank, len(candidates))
+	for i, candidate := range candidates {
+		rankings[i] = CandidateRank{
+			Rank:      i + 1,
+			Owner:     candidate.Owner,
+			Name:      string(candidate.Name),
+			Active:    candidate.Active,
+			VoteCount: (*hexutil.Big)(candidate.VoteCount),
+			Elected:   elected[candidate.Owner],
+		}
+	}
+	return rankings, nil
+}
+
+// GetVotes returns the votes cast by the given address at the given block, or
+// the latest one if none is given.
+func (api *PublicDposAPI) GetVotes(ctx context.Context, address common.Address, blockNr *rpc.BlockNumber) (*rpc.Voter, error) {
+	stateDB, err := api.state(ctx, blockNr)
+	if err != nil {
+		return nil, err
+	}
+	return newRPCVoter(election.GetVoter(stateDB, address)), nil
+}
+
+// header retrieves the header of the requested block, defaulting to the latest
+// one if none was requested.
+func (api *PublicDposAPI) header(ctx context.Context, blockNr *rpc.BlockNumber) (*types.Header, error) {
+	number := rpc.LatestBlockNumber
+	if blockNr != nil {
+		number = *blockNr
+	}
+	header, err := api.b.HeaderByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, fmt.Errorf("block #%d not found", number)
+	}
+	return header, nil
+}
+
+// state retrieves the state of the requested block, defaulting to the latest
+// one if none was requested.
+func (api *PublicDposAPI) state(ctx context.Context, blockNr *rpc.BlockNumber) (*state.StateDB, error) {
+	number := rpc.LatestBlockNumber
+	if blockNr != nil {
+		number = *blockNr
+	}
+	stateDB, _, err := api.b.StateAndHeaderByNumber(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	if stateDB == nil {
+		return nil, fmt.Errorf("block #%d not found", number)
+	}
+	return stateDB, nil
+}
+
+// indexOfWitness returns the position of a witness in the list, or -1 if absent.
+func indexOfWitness(witnesses []common.Address, witness common.Address) int {
+	for i, w := range witnesses {
+		if w == witness {
+			return i
+		}
+	}
+	return -1
+}
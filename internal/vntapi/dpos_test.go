@@ -0,0 +1,208 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package vntapi
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/common/hexutil"
+	"github.com/vntchain/go-vnt/core/state"
+	"github.com/vntchain/go-vnt/core/types"
+	"github.com/vntchain/go-vnt/core/vm/election"
+	inter "github.com/vntchain/go-vnt/core/vm/interface"
+	"github.com/vntchain/go-vnt/params"
+	"github.com/vntchain/go-vnt/rpc"
+	"github.com/vntchain/go-vnt/vntdb"
+)
+
+// dposTestBackend is a minimal backend serving a fixed chain of headers.
+type dposTestBackend struct {
+	Backend
+
+	headers []*types.Header
+}
+
+func (b *dposTestBackend) ChainConfig() *params.ChainConfig { return params.MainnetChainConfig }
+
+func (b *dposTestBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error) {
+	if blockNr == rpc.LatestBlockNumber {
+		return b.headers[len(b.headers)-1], nil
+	}
+	if int(blockNr) >= len(b.headers) {
+		return nil, nil
+	}
+	return b.headers[blockNr], nil
+}
+
+func (b *dposTestBackend) GetBlock(ctx context.Context, hash common.Hash) (*types.Block, error) {
+	for _, header := range b.headers {
+		if header.Hash() == hash {
+			return types.NewBlockWithHeader(header), nil
+		}
+	}
+	return nil, nil
+}
+
+// Tests that the witness schedule continues the round after the last block
+// produced by a current witness, skipping the slots already passed.
+func TestWitnessSchedule(t *testing.T) {
+	var (
+		a, b, c   = common.HexToAddress("0x0a"), common.HexToAddress("0x0b"), common.HexToAddress("0x0c")
+		outsider  = common.HexToAddress("0xff")
+		witnesses = []common.Address{a, b, c}
+		period    = params.MainnetChainConfig.Dpos.Period
+	)
+	// Assemble a chain where the last block was produced by a former witness
+	genesis := &types.Header{Number: big.NewInt(0), Time: big.NewInt(100), Witnesses: witnesses}
+	block1 := &types.Header{Number: big.NewInt(1), Time: big.NewInt(int64(100 + period)), Coinbase: a, ParentHash: genesis.Hash(), Witnesses: witnesses}
+	block2 := &types.Header{Number: big.NewInt(2), Time: big.NewInt(int64(100 + 3*period)), Coinbase: outsider, ParentHash: block1.Hash(), Witnesses: witnesses}
+
+	api := NewPublicDposAPI(&dposTestBackend{headers: []*types.Header{genesis, block1, block2}})
+
+	tests := []struct {
+		number rpc.BlockNumber
+		want   []WitnessSlot
+	}{
+		// Nothing produced yet, the round starts with the first witness
+		{0, []WitnessSlot{{1, a, 102}, {2, b, 104}, {3, c, 106}}},
+		// Block produced by a current witness, the next ones follow in order
+		{1, []WitnessSlot{{2, b, 104}, {3, c, 106}, {4, a, 108}}},
+		// Block produced by an outsider, counting continues from the last witness
+		{2, []WitnessSlot{{3, a, 108}, {4, b, 110}, {5, c, 112}}},
+	}
+	for _, tt := range tests {
+		number := tt.number
+		schedule, err := api.GetWitnessSchedule(context.Background(), &number)
+		if err != nil {
+			t.Fatalf("block %d: failed to retrieve schedule: %v", tt.number, err)
+		}
+		if len(schedule) != len(tt.want) {
+			t.Fatalf("block %d: schedule length mismatch: have %d, want %d", tt.number, len(schedule), len(tt.want))
+		}
+		for i, slot := range schedule {
+			if slot != tt.want[i] {
+				t.Errorf("block %d, slot %d: mismatch: have %+v, want %+v", tt.number, i, slot, tt.want[i])
+			}
+		}
+	}
+	if _, err := api.GetWitnessSchedule(context.Background(), nil); err != nil {
+		t.Errorf("failed to retrieve latest schedule: %v", err)
+	}
+	number := rpc.BlockNumber(3)
+	if _, err := api.GetWitnesses(context.Background(), &number); err == nil {
+		t.Errorf("unknown block accepted")
+	}
+}
+
+// electionTestBackend is a minimal backend serving a fixed election state.
+type electionTestBackend struct {
+	Backend
+
+	config  *params.ChainConfig
+	stateDB *state.StateDB
+}
+
+func (b *electionTestBackend) ChainConfig() *params.ChainConfig { return b.config }
+
+func (b *electionTestBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*state.StateDB, *types.Header, error) {
+	return b.stateDB, &types.Header{Number: new(big.Int)}, nil
+}
+
+// electionTestContext is the context of a call to the election contract.
+type electionTestContext struct {
+	stateDB *state.StateDB
+	origin  common.Address
+}
+
+func (c *electionTestContext) GetStateDb() inter.StateDB { return c.stateDB }
+func (c *electionTestContext) GetOrigin() common.Address { return c.origin }
+func (c *electionTestContext) GetTime() *big.Int         { return big.NewInt(1546272000) }
+
+// Tests that candidates are ranked by their votes, marking the ones which would
+// be elected as witnesses.
+func TestCandidateRankings(t *testing.T) {
+	var (
+		a, b, c = common.HexToAddress("0x0a"), common.HexToAddress("0x0b"), common.HexToAddress("0x0c")
+		voter   = common.HexToAddress("0xff")
+		urls    = []string{
+			"/ip4/127.0.0.1/tcp/30303/ipfs/1kHNAAfnqXNsxMwJf6QjJFRmVK7iB32U9owwK9KfeLFxEA7",
+			"/ip4/127.0.0.1/tcp/30303/ipfs/1kHcch6yuBCgC5nPPSK3Yp7Es4c4eenxAeK167pYwUvNjRo",
+			"/ip4/127.0.0.1/tcp/30303/ipfs/1kHJFKr2bzUnMr1NbeyYbYJa3RXT18cEu7cNDrHWjg8XYKB",
+		}
+	)
+	stateDB, _ := state.New(common.Hash{}, state.NewDatabase(vntdb.NewMemDatabase()))
+	stateDB.AddBalance(voter, new(big.Int).Mul(big.NewInt(100), big.NewInt(1e18)))
+
+	call := func(origin common.Address, method string, args ...interface{}) {
+		input, err := election.PackInput(method, args...)
+		if err != nil {
+			t.Fatalf("failed to pack %s: %v", method, err)
+		}
+		if _, err := new(election.Election).Run(&electionTestContext{stateDB, origin}, input); err != nil {
+			t.Fatalf("failed to call %s: %v", method, err)
+		}
+	}
+	for i, candidate := range []common.Address{a, b, c} {
+		name := fmt.Sprintf("node%d", i)
+		call(candidate, "registerWitness", []byte(urls[i]), []byte("www."+name+".com"), []byte(name))
+	}
+	call(a, "unregisterWitness")
+	call(voter, "stake", big.NewInt(10))
+	call(voter, "voteWitnesses", []common.Address{c})
+
+	config := &params.ChainConfig{Dpos: &params.DposConfig{WitnessesNum: 2}}
+	rankings, err := NewPublicDposAPI(&electionTestBackend{config: config, stateDB: stateDB}).GetRankings(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve rankings: %v", err)
+	}
+	want := []struct {
+		owner   common.Address
+		elected bool
+	}{{c, true}, {a, false}, {b, true}}
+	if len(rankings) != len(want) {
+		t.Fatalf("ranking count mismatch: have %d, want %d", len(rankings), len(want))
+	}
+	for i, rank := range rankings {
+		if rank.Rank != i+1 || rank.Owner != want[i].owner || rank.Elected != want[i].elected {
+			t.Errorf("rank %d mismatch: have %d/%x/%v, want %d/%x/%v", i, rank.Rank, rank.Owner, rank.Elected, i+1, want[i].owner, want[i].elected)
+		}
+	}
+	if rankings[0].VoteCount.ToInt().Sign() <= 0 {
+		t.Errorf("stake weighted votes missing: have %v", rankings[0].VoteCount)
+	}
+}
+
+// Tests that election transactions refuse the fields assembled by the API.
+func TestElectionTxArgs(t *testing.T) {
+	api := NewPublicElectionAPI(&electionTestBackend{}, new(AddrLocker))
+
+	to := common.HexToAddress("0x01")
+	if _, err := api.CancelVote(context.Background(), SendTxArgs{To: &to}); err == nil {
+		t.Errorf("recipient accepted")
+	}
+	data := hexutil.Bytes{0x01}
+	if _, err := api.CancelVote(context.Background(), SendTxArgs{Data: &data}); err == nil {
+		t.Errorf("call data accepted")
+	}
+	if _, err := api.CancelVote(context.Background(), SendTxArgs{Value: (*hexutil.Big)(big.NewInt(1))}); err == nil {
+		t.Errorf("value transfer accepted")
+	}
+}
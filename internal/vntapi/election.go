@@ -0,0 +1,138 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package vntapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/common/hexutil"
+	"github.com/vntchain/go-vnt/core/vm"
+	"github.com/vntchain/go-vnt/core/vm/election"
+	"github.com/vntchain/go-vnt/rpc"
+)
+
+// electionAddress is the address of the election contract.
+var electionAddress = common.HexToAddress(election.ContractAddr)
+
+// PublicElectionAPI provides the transactions taking part in the witness
+// election, calling the election contract on behalf of the local accounts.
+type PublicElectionAPI struct {
+	b     Backend
+	chain *PublicBlockChainAPI
+	txs   *PublicTransactionPoolAPI
+}
+
+// NewPublicElectionAPI creates a new witness election API.
+func NewPublicElectionAPI(b Backend, nonceLock *AddrLocker) *PublicElectionAPI {
+	return &PublicElectionAPI{
+		b:     b,
+		chain: NewPublicBlockChainAPI(b),
+		txs:   NewPublicTransactionPoolAPI(b, nonceLock),
+	}
+}
+
+// RegisterWitness registers the sender as a witness candidate, reachable at the
+// given node URL.
+func (api *PublicElectionAPI) RegisterWitness(ctx context.Context, args SendTxArgs, nodeUrl string, website string, name string) (common.Hash, error) {
+	return api.send(ctx, args, "registerWitness", []byte(nodeUrl), []byte(website), []byte(name))
+}
+
+// UnregisterWitness withdraws the candidacy of the sender.
+func (api *PublicElectionAPI) UnregisterWitness(ctx context.Context, args SendTxArgs) (common.Hash, error) {
+	return api.send(ctx, args, "unregisterWitness")
+}
+
+// VoteWitnesses casts the votes of the sender for the given candidates, replacing
+// any earlier votes.
+func (api *PublicElectionAPI) VoteWitnesses(ctx context.Context, args SendTxArgs, candidates []common.Address) (common.Hash, error) {
+	return api.send(ctx, args, "voteWitnesses", candidates)
+}
+
+// CancelVote cancels the votes cast by the sender.
+func (api *PublicElectionAPI) CancelVote(ctx context.Context, args SendTxArgs) (common.Hash, error) {
+	return api.send(ctx, args, "cancelVote")
+}
+
+// StartProxy makes the sender a proxy, voting on behalf of the accounts setting
+// it as their proxy.
+func (api *PublicElectionAPI) StartProxy(ctx context.Context, args SendTxArgs) (common.Hash, error) {
+	return api.send(ctx, args, "startProxy")
+}
+
+// StopProxy stops the sender from being a proxy.
+func (api *PublicElectionAPI) StopProxy(ctx context.Context, args SendTxArgs) (common.Hash, error) {
+	return api.send(ctx, args, "stopProxy")
+}
+
+// SetProxy delegates the votes of the sender to the given proxy.
+func (api *PublicElectionAPI) SetProxy(ctx context.Context, args SendTxArgs, proxy common.Address) (common.Hash, error) {
+	return api.send(ctx, args, "setProxy", proxy)
+}
+
+// CancelProxy takes back the votes the sender delegated to its proxy.
+func (api *PublicElectionAPI) CancelProxy(ctx context.Context, args SendTxArgs) (common.Hash, error) {
+	return api.send(ctx, args, "cancelProxy")
+}
+
+// Stake locks the given amount of whole VNT of the sender, which weighs its votes.
+func (api *PublicElectionAPI) Stake(ctx context.Context, args SendTxArgs, amount hexutil.Big) (common.Hash, error) {
+	return api.send(ctx, args, "stake", amount.ToInt())
+}
+
+// UnStake returns the stake of the sender, once it has been locked long enough.
+func (api *PublicElectionAPI) UnStake(ctx context.Context, args SendTxArgs) (common.Hash, error) {
+	return api.send(ctx, args, "unStake")
+}
+
+// ExtractOwnBounty withdraws the bounty earned by the sender as a witness.
+func (api *PublicElectionAPI) ExtractOwnBounty(ctx context.Context, args SendTxArgs) (common.Hash, error) {
+	return api.send(ctx, args, "extractOwnBounty")
+}
+
+// send calls a method of the election contract in a transaction of the sender.
+// The call is executed on the pending state first, returning the error of the
+// election contract rather than wasting gas on a transaction bound to fail.
+func (api *PublicElectionAPI) send(ctx context.Context, args SendTxArgs, method string, params ...interface{}) (common.Hash, error) {
+	if args.To != nil || args.Data != nil || args.Input != nil {
+		return common.Hash{}, errors.New("election transactions can't set the recipient or the call data")
+	}
+	if args.Value != nil && args.Value.ToInt().Sign() != 0 {
+		return common.Hash{}, errors.New("election transactions can't transfer value")
+	}
+	input, err := election.PackInput(method, params...)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	to, data := electionAddress, hexutil.Bytes(input)
+	args.To, args.Data = &to, &data
+
+	call := CallArgs{From: args.From, To: &to, Data: data}
+	if args.Gas != nil {
+		call.Gas = *args.Gas
+	}
+	result, err := api.chain.doCall(ctx, call, rpc.PendingBlockNumber, nil, vm.Config{})
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if result.Err != nil {
+		return common.Hash{}, fmt.Errorf("election %s failed: %v", method, result.Err)
+	}
+	return api.txs.SendTransaction(ctx, args)
+}
@@ -0,0 +1,100 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package vntapi
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/vntchain/go-vnt/common/hexutil"
+	"github.com/vntchain/go-vnt/core"
+	"github.com/vntchain/go-vnt/crypto"
+)
+
+// revertSelector is the selector of the Error(string) payload EVM contracts
+// revert with.
+var revertSelector = crypto.Keccak256([]byte("Error(string)"))[:4]
+
+// revertError is returned by calls the contract reverted, carrying the raw
+// payload it reverted with as the error data.
+type revertError struct {
+	error
+	reason string // Revert payload, hex encoded
+}
+
+// newRevertError creates a revertError from the result of a reverted execution,
+// including the reason in the message if it is readable.
+func newRevertError(result *core.ExecutionResult) *revertError {
+	payload := result.Revert()
+
+	err := errors.New("execution reverted")
+	if reason, ok := unpackRevert(payload); ok {
+		err = fmt.Errorf("execution reverted: %v", reason)
+	}
+	return &revertError{
+		error:  err,
+		reason: hexutil.Encode(payload),
+	}
+}
+
+// ErrorCode returns the JSON-RPC error code of a reverted execution.
+func (e *revertError) ErrorCode() int { return 3 }
+
+// ErrorData returns the hex encoded revert payload.
+func (e *revertError) ErrorData() interface{} { return e.reason }
+
+// RevertReason returns the message a reverted execution was aborted with, or the
+// hex encoded payload if it doesn't hold a readable message.
+func RevertReason(payload []byte) string {
+	if reason, ok := unpackRevert(payload); ok {
+		return reason
+	}
+	return hexutil.Encode(payload)
+}
+
+// unpackRevert extracts the readable message from a revert payload. Wasm
+// contracts revert with the plain message, while EVM ones abi encode it as an
+// Error(string) call.
+func unpackRevert(payload []byte) (string, bool) {
+	if len(payload) >= 4+64 && bytes.Equal(payload[:4], revertSelector) {
+		data := payload[4:]
+
+		offset := new(big.Int).SetBytes(data[:32])
+		if !offset.IsUint64() || offset.Uint64() > uint64(len(data)-32) {
+			return "", false
+		}
+		start := offset.Uint64() + 32
+		size := new(big.Int).SetBytes(data[start-32 : start])
+		if !size.IsUint64() || size.Uint64() > uint64(len(data))-start {
+			return "", false
+		}
+		payload = data[start : start+size.Uint64()]
+	}
+	if len(payload) == 0 || !utf8.Valid(payload) {
+		return "", false
+	}
+	for _, r := range string(payload) {
+		if !unicode.IsPrint(r) && !unicode.IsSpace(r) {
+			return "", false
+		}
+	}
+	return string(payload), true
+}
@@ -0,0 +1,80 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package vntapi
+
+import (
+	"testing"
+
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/common/hexutil"
+	"github.com/vntchain/go-vnt/core"
+	"github.com/vntchain/go-vnt/core/vm"
+	wavmerrors "github.com/vntchain/go-vnt/core/wavm/errors"
+)
+
+// Tests that revert payloads of both wasm and EVM contracts are unpacked into
+// readable messages.
+func TestUnpackRevert(t *testing.T) {
+	tests := []struct {
+		payload []byte
+		reason  string
+		ok      bool
+	}{
+		{nil, "", false},
+		{[]byte("insufficient funds"), "insufficient funds", true},
+		{[]byte{0x00, 0xff, 0x01}, "", false},
+		// Error("revert reason") as emitted by solidity
+		{common.FromHex("0x08c379a0" +
+			"0000000000000000000000000000000000000000000000000000000000000020" +
+			"000000000000000000000000000000000000000000000000000000000000000d" +
+			"72657665727420726561736f6e00000000000000000000000000000000000000"), "revert reason", true},
+		// Error(string) with an out of bounds length
+		{common.FromHex("0x08c379a0" +
+			"0000000000000000000000000000000000000000000000000000000000000020" +
+			"00000000000000000000000000000000000000000000000000000000000000ff" +
+			"72657665727420726561736f6e00000000000000000000000000000000000000"), "", false},
+	}
+	for i, tt := range tests {
+		reason, ok := unpackRevert(tt.payload)
+		if ok != tt.ok || reason != tt.reason {
+			t.Errorf("test %d: reason mismatch: have (%q, %v), want (%q, %v)", i, reason, ok, tt.reason, tt.ok)
+		}
+	}
+}
+
+// Tests that reverted executions are reported with their reason and payload,
+// regardless of the virtual machine they were run on.
+func TestRevertError(t *testing.T) {
+	for _, vmerr := range []error{vm.ErrExecutionReverted, wavmerrors.ErrExecutionReverted} {
+		result := &core.ExecutionResult{Err: vmerr, ReturnData: []byte("not the owner")}
+
+		err := newRevertError(result)
+		if have, want := err.Error(), "execution reverted: not the owner"; have != want {
+			t.Errorf("error message mismatch: have %q, want %q", have, want)
+		}
+		if have, want := err.ErrorData(), hexutil.Encode([]byte("not the owner")); have != want {
+			t.Errorf("error data mismatch: have %v, want %v", have, want)
+		}
+		if result.Return() != nil {
+			t.Errorf("return data of reverted execution: have %x, want nil", result.Return())
+		}
+	}
+	result := &core.ExecutionResult{Err: vm.ErrOutOfGas, ReturnData: []byte("garbage")}
+	if result.Revert() != nil {
+		t.Errorf("revert payload of failed execution: have %x, want nil", result.Revert())
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package vntapi
+
+import (
+	"github.com/vntchain/go-vnt/core"
+)
+
+// JSON-RPC error codes of the transactions rejected by the pool, so clients can
+// tell the reasons apart without matching messages.
+const (
+	errCodeInsufficientFunds  = -32010
+	errCodeGasLimit           = -32011
+	errCodeIntrinsicGas       = -32012
+	errCodeNonceTooLow        = -32013
+	errCodeUnderpriced        = -32014
+	errCodeReplaceUnderpriced = -32015
+	errCodeOversizedData      = -32016
+	errCodeNegativeValue      = -32017
+	errCodeInvalidSender      = -32018
+)
+
+var txPoolErrorCodes = map[error]int{
+	core.ErrInsufficientFunds:  errCodeInsufficientFunds,
+	core.ErrGasLimit:           errCodeGasLimit,
+	core.ErrIntrinsicGas:       errCodeIntrinsicGas,
+	core.ErrNonceTooLow:        errCodeNonceTooLow,
+	core.ErrUnderpriced:        errCodeUnderpriced,
+	core.ErrReplaceUnderpriced: errCodeReplaceUnderpriced,
+	core.ErrOversizedData:      errCodeOversizedData,
+	core.ErrNegativeValue:      errCodeNegativeValue,
+	core.ErrInvalidSender:      errCodeInvalidSender,
+}
+
+// txPoolError is returned by calls submitting a transaction the pool rejected
+// for a known reason.
+type txPoolError struct {
+	error
+	code int
+}
+
+// ErrorCode returns the JSON-RPC error code of the rejection reason.
+func (e *txPoolError) ErrorCode() int { return e.code }
+
+// wrapTxPoolError attaches the error code of the rejection reason to an error of
+// the transaction pool, returning other errors unchanged.
+func wrapTxPoolError(err error) error {
+	if code, ok := txPoolErrorCodes[err]; ok {
+		return &txPoolError{error: err, code: code}
+	}
+	return err
+}
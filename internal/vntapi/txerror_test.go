@@ -0,0 +1,66 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package vntapi
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/common/hexutil"
+	"github.com/vntchain/go-vnt/core/types"
+	"github.com/vntchain/go-vnt/crypto"
+	"github.com/vntchain/go-vnt/params"
+	"github.com/vntchain/go-vnt/rlp"
+	"github.com/vntchain/go-vnt/rpc"
+)
+
+// Tests that transactions rejected by the pool are reported over RPC with an
+// error code distinct for every rejection reason.
+func TestTxPoolErrorCodes(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	tx, err := types.SignTx(types.NewTransaction(0, common.HexToAddress("0x01"), big.NewInt(1), 21000, big.NewInt(1), nil), types.NewHubbleSigner(params.TestChainConfig.ChainID), key)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	blob, _ := rlp.EncodeToBytes(tx)
+
+	codes := make(map[int]error)
+	for reason := range txPoolErrorCodes {
+		api := NewPublicTransactionPoolAPI(&txTestBackend{reject: reason}, new(AddrLocker))
+
+		_, err := api.SendRawTransaction(context.Background(), hexutil.Bytes(blob))
+		rpcErr, ok := err.(rpc.Error)
+		if !ok {
+			t.Errorf("%v: error without code: %v", reason, err)
+			continue
+		}
+		if rpcErr.Error() != reason.Error() {
+			t.Errorf("%v: error message mismatch: have %q, want %q", reason, rpcErr.Error(), reason.Error())
+		}
+		if prev, ok := codes[rpcErr.ErrorCode()]; ok {
+			t.Errorf("%v: error code %d shared with %v", reason, rpcErr.ErrorCode(), prev)
+		}
+		codes[rpcErr.ErrorCode()] = reason
+	}
+	unknown := errors.New("known transaction")
+	if err := wrapTxPoolError(unknown); err != unknown {
+		t.Errorf("unknown error wrapped: have %v, want %v", err, unknown)
+	}
+}
@@ -22,6 +22,7 @@ var Modules = map[string]string{
 	"chequebook": Chequebook_JS,
 	"debug":      Debug_JS,
 	"core":       Core_JS,
+	"les":        LES_JS,
 	"bp":         Bp_JS,
 	"net":        Net_JS,
 	"personal":   Personal_JS,
@@ -77,6 +78,16 @@ vnt._extend({
 			call: 'admin_removePeer',
 			params: 1
 		}),
+		new vnt._extend.Method({
+			name: 'addTrustedPeer',
+			call: 'admin_addTrustedPeer',
+			params: 1
+		}),
+		new vnt._extend.Method({
+			name: 'removeTrustedPeer',
+			call: 'admin_removeTrustedPeer',
+			params: 1
+		}),
 		new vnt._extend.Method({
 			name: 'exportChain',
 			call: 'admin_exportChain',
@@ -160,6 +171,11 @@ vnt._extend({
 			call: 'debug_dumpBlock',
 			params: 1
 		}),
+		new vnt._extend.Method({
+			name: 'dumpBlockRLP',
+			call: 'debug_dumpBlockRLP',
+			params: 2
+		}),
 		new vnt._extend.Method({
 			name: 'chaindbProperty',
 			call: 'debug_chaindbProperty',
@@ -311,6 +327,12 @@ vnt._extend({
 			params: 2,
 			inputFormatter: [null, null]
 		}),
+		new vnt._extend.Method({
+			name: 'traceCall',
+			call: 'debug_traceCall',
+			params: 3,
+			inputFormatter: [null, null, null]
+		}),
 		new vnt._extend.Method({
 			name: 'preimage',
 			call: 'debug_preimage',
@@ -359,6 +381,16 @@ vnt._extend({
 			call: 'core_getRawTransactionByHash',
 			params: 1
 		}),
+		new vnt._extend.Method({
+			name: 'sendRawTransactions',
+			call: 'core_sendRawTransactions',
+			params: 1
+		}),
+		new vnt._extend.Method({
+			name: 'signFeePayerTransaction',
+			call: 'core_signFeePayerTransaction',
+			params: 1
+		}),
 		new vnt._extend.Method({
 			name: 'getRawTransactionFromBlock',
 			call: function(args) {
@@ -367,6 +399,12 @@ vnt._extend({
 			params: 2,
 			inputFormatter: [vnt._extend.formatters.inputBlockNumberFormatter, vnt._extend.utils.toHex]
 		}),
+		new vnt._extend.Method({
+			name: 'getProof',
+			call: 'core_getProof',
+			params: 3,
+			inputFormatter: [vnt._extend.formatters.inputAddressFormatter, null, vnt._extend.formatters.inputBlockNumberFormatter]
+		}),
 	],
 	properties: [
 		new vnt._extend.Property({
@@ -454,6 +492,11 @@ vnt._extend({
 			call: 'personal_ecRecover',
 			params: 2
 		}),
+		new vnt._extend.Method({
+			name: 'openWallet',
+			call: 'personal_openWallet',
+			params: 2
+		}),
 		new vnt._extend.Method({
 			name: 'deriveAccount',
 			call: 'personal_deriveAccount',
@@ -465,6 +508,18 @@ vnt._extend({
 			params: 2,
 			inputFormatter: [vnt._extend.formatters.inputTransactionFormatter, null]
 		}),
+		new vnt._extend.Method({
+			name: 'signTypedData',
+			call: 'personal_signTypedData',
+			params: 3,
+			inputFormatter: [null, vnt._extend.formatters.inputAddressFormatter, null]
+		}),
+		new vnt._extend.Method({
+			name: 'setAccountLabel',
+			call: 'personal_setAccountLabel',
+			params: 3,
+			inputFormatter: [vnt._extend.formatters.inputAddressFormatter, null, null]
+		}),
 	],
 	properties: [
 		new vnt._extend.Property({
@@ -492,6 +547,11 @@ const Shh_JS = `
 vnt._extend({
 	property: 'shh',
 	methods: [
+		new vnt._extend.Method({
+			name: 'requestHistoricMessages',
+			call: 'shh_requestHistoricMessages',
+			params: 1
+		}),
 	],
 	properties:
 	[
@@ -532,10 +592,39 @@ vnt._extend({
 });
 `
 
+const LES_JS = `
+vnt._extend({
+	property: 'les',
+	methods:
+	[
+		new vnt._extend.Method({
+			name: 'getCheckpoint',
+			call: 'les_getCheckpoint',
+			params: 1
+		}),
+	],
+	properties:
+	[
+		new vnt._extend.Property({
+			name: 'latestCheckpoint',
+			getter: 'les_latestCheckpoint'
+		}),
+	]
+});
+`
+
 const TxPool_JS = `
 vnt._extend({
 	property: 'txpool',
-	methods: [],
+	methods:
+	[
+		new vnt._extend.Method({
+			name: 'contentFrom',
+			call: 'txpool_contentFrom',
+			params: 1,
+			inputFormatter: [vnt._extend.formatters.inputAddressFormatter]
+		}),
+	],
 	properties:
 	[
 		new vnt._extend.Property({
@@ -546,6 +635,10 @@ vnt._extend({
 			name: 'inspect',
 			getter: 'txpool_inspect'
 		}),
+		new vnt._extend.Property({
+			name: 'dump',
+			getter: 'txpool_dump'
+		}),
 		new vnt._extend.Property({
 			name: 'status',
 			getter: 'txpool_status',
@@ -573,6 +666,114 @@ vnt._extend({
 			call: 'dpos_getSignersAtHash',
 			params: 1
 		}),
+		new vnt._extend.Method({
+			name: 'getWitnesses',
+			call: 'dpos_getWitnesses',
+			params: 1,
+			inputFormatter: [vnt._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new vnt._extend.Method({
+			name: 'getWitnessSchedule',
+			call: 'dpos_getWitnessSchedule',
+			params: 1,
+			inputFormatter: [vnt._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new vnt._extend.Method({
+			name: 'getMissedSlots',
+			call: 'dpos_getMissedSlots',
+			params: 1,
+			inputFormatter: [vnt._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new vnt._extend.Method({
+			name: 'getWitnessPerformance',
+			call: 'dpos_getWitnessPerformance',
+			params: 2,
+			inputFormatter: [vnt._extend.utils.fromDecimal, vnt._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new vnt._extend.Method({
+			name: 'getCandidates',
+			call: 'dpos_getCandidates',
+			params: 1,
+			inputFormatter: [vnt._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new vnt._extend.Method({
+			name: 'getVotes',
+			call: 'dpos_getVotes',
+			params: 2,
+			inputFormatter: [vnt._extend.formatters.inputAddressFormatter, vnt._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new vnt._extend.Method({
+			name: 'getRankings',
+			call: 'dpos_getRankings',
+			params: 1,
+			inputFormatter: [vnt._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new vnt._extend.Method({
+			name: 'registerWitness',
+			call: 'dpos_registerWitness',
+			params: 4,
+			inputFormatter: [vnt._extend.formatters.inputTransactionFormatter, null, null, null]
+		}),
+		new vnt._extend.Method({
+			name: 'unregisterWitness',
+			call: 'dpos_unregisterWitness',
+			params: 1,
+			inputFormatter: [vnt._extend.formatters.inputTransactionFormatter]
+		}),
+		new vnt._extend.Method({
+			name: 'voteWitnesses',
+			call: 'dpos_voteWitnesses',
+			params: 2,
+			inputFormatter: [vnt._extend.formatters.inputTransactionFormatter, null]
+		}),
+		new vnt._extend.Method({
+			name: 'cancelVote',
+			call: 'dpos_cancelVote',
+			params: 1,
+			inputFormatter: [vnt._extend.formatters.inputTransactionFormatter]
+		}),
+		new vnt._extend.Method({
+			name: 'startProxy',
+			call: 'dpos_startProxy',
+			params: 1,
+			inputFormatter: [vnt._extend.formatters.inputTransactionFormatter]
+		}),
+		new vnt._extend.Method({
+			name: 'stopProxy',
+			call: 'dpos_stopProxy',
+			params: 1,
+			inputFormatter: [vnt._extend.formatters.inputTransactionFormatter]
+		}),
+		new vnt._extend.Method({
+			name: 'setProxy',
+			call: 'dpos_setProxy',
+			params: 2,
+			inputFormatter: [vnt._extend.formatters.inputTransactionFormatter, vnt._extend.formatters.inputAddressFormatter]
+		}),
+		new vnt._extend.Method({
+			name: 'cancelProxy',
+			call: 'dpos_cancelProxy',
+			params: 1,
+			inputFormatter: [vnt._extend.formatters.inputTransactionFormatter]
+		}),
+		new vnt._extend.Method({
+			name: 'stake',
+			call: 'dpos_stake',
+			params: 2,
+			inputFormatter: [vnt._extend.formatters.inputTransactionFormatter, vnt._extend.utils.fromDecimal]
+		}),
+		new vnt._extend.Method({
+			name: 'unStake',
+			call: 'dpos_unStake',
+			params: 1,
+			inputFormatter: [vnt._extend.formatters.inputTransactionFormatter]
+		}),
+		new vnt._extend.Method({
+			name: 'extractOwnBounty',
+			call: 'dpos_extractOwnBounty',
+			params: 1,
+			inputFormatter: [vnt._extend.formatters.inputTransactionFormatter]
+		}),
 		new vnt._extend.Method({
 			name: 'getPrePrepareMsg',
 			call: 'dpos_getPrePrepareMsg',
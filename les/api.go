@@ -0,0 +1,48 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package les
+
+import (
+	"errors"
+
+	"github.com/vntchain/go-vnt/common/hexutil"
+	"github.com/vntchain/go-vnt/params"
+)
+
+var errCheckpointUnavailable = errors.New("checkpoint not available yet")
+
+// PublicLesServerAPI provides the checkpoints generated by a light server, for
+// light clients to start syncing from (--les.checkpoint).
+type PublicLesServerAPI struct {
+	server *LesServer
+}
+
+// NewPublicLesServerAPI creates a new API for the given light server.
+func NewPublicLesServerAPI(server *LesServer) *PublicLesServerAPI {
+	return &PublicLesServerAPI{server: server}
+}
+
+// LatestCheckpoint returns the checkpoint of the most recent section of the
+// chain both the CHT and the bloom trie are available for.
+func (api *PublicLesServerAPI) LatestCheckpoint() (*params.TrustedCheckpoint, error) {
+	return api.server.latestCheckpoint()
+}
+
+// GetCheckpoint returns the checkpoint of the given section of the chain.
+func (api *PublicLesServerAPI) GetCheckpoint(section hexutil.Uint64) (*params.TrustedCheckpoint, error) {
+	return api.server.checkpoint(uint64(section))
+}
@@ -19,6 +19,7 @@ package les
 import (
 	"context"
 	"math/big"
+	"time"
 
 	"github.com/vntchain/go-vnt/accounts"
 	"github.com/vntchain/go-vnt/common"
@@ -39,8 +40,9 @@ import (
 )
 
 type LesApiBackend struct {
-	vnt *LightVnt
-	gpo *gasprice.Oracle
+	extRPCEnabled bool
+	vnt           *LightVnt
+	gpo           *gasprice.Oracle
 }
 
 func (b *LesApiBackend) ChainConfig() *params.ChainConfig {
@@ -51,15 +53,19 @@ func (b *LesApiBackend) CurrentBlock() *types.Block {
 	return types.NewBlockWithHeader(b.vnt.BlockChain().CurrentHeader())
 }
 
-func (b *LesApiBackend) SetHead(number uint64) {
+func (b *LesApiBackend) SetHead(number uint64) error {
 	b.vnt.protocolManager.downloader.Cancel()
 	b.vnt.blockchain.SetHead(number)
+	return nil
 }
 
 func (b *LesApiBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error) {
 	if blockNr == rpc.LatestBlockNumber || blockNr == rpc.PendingBlockNumber {
 		return b.vnt.blockchain.CurrentHeader(), nil
 	}
+	if blockNr == rpc.IrreversibleBlockNumber {
+		return b.vnt.blockchain.CurrentIrreversible(), nil
+	}
 
 	return b.vnt.blockchain.GetHeaderByNumberOdr(ctx, uint64(blockNr))
 }
@@ -114,6 +120,30 @@ func (b *LesApiBackend) SendTx(ctx context.Context, signedTx *types.Transaction)
 	return b.vnt.txPool.Add(ctx, signedTx)
 }
 
+func (b *LesApiBackend) SendTxs(ctx context.Context, signedTxs []*types.Transaction) []error {
+	errs := make([]error, len(signedTxs))
+	for i, tx := range signedTxs {
+		errs[i] = b.vnt.txPool.Add(ctx, tx)
+	}
+	return errs
+}
+
+func (b *LesApiBackend) ExtRPCEnabled() bool {
+	return b.extRPCEnabled
+}
+
+func (b *LesApiBackend) UnprotectedAllowed() bool {
+	return b.vnt.config.AllowUnprotectedTxs
+}
+
+func (b *LesApiBackend) RPCGasCap() uint64 {
+	return b.vnt.config.RPCGasCap
+}
+
+func (b *LesApiBackend) RPCEVMTimeout() time.Duration {
+	return b.vnt.config.RPCEVMTimeout
+}
+
 func (b *LesApiBackend) RemoveTx(txHash common.Hash) {
 	b.vnt.txPool.RemoveTx(txHash)
 }
@@ -154,6 +184,10 @@ func (b *LesApiBackend) SubscribeChainSideEvent(ch chan<- core.ChainSideEvent) e
 	return b.vnt.blockchain.SubscribeChainSideEvent(ch)
 }
 
+func (b *LesApiBackend) SubscribeChainReorgEvent(ch chan<- core.ChainReorgEvent) event.Subscription {
+	return b.vnt.blockchain.SubscribeChainReorgEvent(ch)
+}
+
 func (b *LesApiBackend) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription {
 	return b.vnt.blockchain.SubscribeLogsEvent(ch)
 }
@@ -20,6 +20,7 @@ package les
 import (
 	"crypto/ecdsa"
 	"encoding/binary"
+	"fmt"
 	"math"
 	"sync"
 
@@ -30,7 +31,9 @@ import (
 	"github.com/vntchain/go-vnt/les/flowcontrol"
 	"github.com/vntchain/go-vnt/light"
 	"github.com/vntchain/go-vnt/log"
+	"github.com/vntchain/go-vnt/params"
 	"github.com/vntchain/go-vnt/rlp"
+	"github.com/vntchain/go-vnt/rpc"
 	"github.com/vntchain/go-vnt/vnt"
 	"github.com/vntchain/go-vnt/vntdb"
 	"github.com/vntchain/go-vnt/vntp2p"
@@ -129,6 +132,57 @@ func (s *LesServer) SetBloomBitsIndexer(bloomIndexer *core.ChainIndexer) {
 	bloomIndexer.AddChildIndexer(s.bloomTrieIndexer)
 }
 
+// APIs returns the RPC services of the LES server.
+func (s *LesServer) APIs() []rpc.API {
+	return []rpc.API{
+		{
+			Namespace: "les",
+			Version:   "1.0",
+			Service:   NewPublicLesServerAPI(s),
+			Public:    true,
+		},
+	}
+}
+
+// checkpoint returns the trusted checkpoint of the given LES/2 section, made of
+// the roots of the CHT and of the bloom trie generated for it.
+func (s *LesServer) checkpoint(section uint64) (*params.TrustedCheckpoint, error) {
+	chtSections, _, _ := s.chtIndexer.Sections()
+	bloomSections, _, _ := s.bloomTrieIndexer.Sections()
+	if section >= chtSections/(light.CHTFrequencyClient/light.CHTFrequencyServer) || section >= bloomSections {
+		return nil, errCheckpointUnavailable
+	}
+	// The CHT indexer still uses the LES/1 section size, convert the index
+	head := s.chtIndexer.SectionHead((section+1)*(light.CHTFrequencyClient/light.CHTFrequencyServer) - 1)
+	if bloomHead := s.bloomTrieIndexer.SectionHead(section); bloomHead != head {
+		return nil, fmt.Errorf("section head mismatch: CHT %x, bloom trie %x", head, bloomHead)
+	}
+	checkpoint := &params.TrustedCheckpoint{
+		SectionIndex: section,
+		SectionHead:  head,
+		CHTRoot:      light.GetChtV2Root(s.protocolManager.chainDb, section, head),
+		BloomRoot:    light.GetBloomTrieRoot(s.protocolManager.chainDb, section, head),
+	}
+	if err := checkpoint.Validate(); err != nil {
+		return nil, err
+	}
+	return checkpoint, nil
+}
+
+// latestCheckpoint returns the trusted checkpoint of the most recent section
+// both the CHT and the bloom trie were generated for.
+func (s *LesServer) latestCheckpoint() (*params.TrustedCheckpoint, error) {
+	chtSections, _, _ := s.chtIndexer.Sections()
+	sections, _, _ := s.bloomTrieIndexer.Sections()
+	if cht := chtSections / (light.CHTFrequencyClient / light.CHTFrequencyServer); cht < sections {
+		sections = cht
+	}
+	if sections == 0 {
+		return nil, errCheckpointUnavailable
+	}
+	return s.checkpoint(sections - 1)
+}
+
 // Stop stops the LES service
 func (s *LesServer) Stop() {
 	s.chtIndexer.Close()
@@ -55,7 +55,7 @@ func newCanonical(n int) (vntdb.Database, *LightChain, error) {
 	db := vntdb.NewMemDatabase()
 	gspec := core.Genesis{Config: params.TestChainConfig}
 	genesis := gspec.MustCommit(db)
-	blockchain, _ := NewLightChain(&dummyOdr{db: db}, gspec.Config, mock.NewMock())
+	blockchain, _ := NewLightChain(&dummyOdr{db: db}, gspec.Config, mock.NewMock(), nil)
 
 	// Create and inject the requested chain
 	if n == 0 {
@@ -75,7 +75,7 @@ func newTestLightChain() *LightChain {
 		Config:     params.TestChainConfig,
 	}
 	gspec.MustCommit(db)
-	lc, err := NewLightChain(&dummyOdr{db: db}, gspec.Config, mock.NewMock())
+	lc, err := NewLightChain(&dummyOdr{db: db}, gspec.Config, mock.NewMock(), nil)
 	if err != nil {
 		panic(err)
 	}
@@ -340,7 +340,7 @@ func TestReorgBadHeaderHashes(t *testing.T) {
 	defer func() { delete(core.BadHashes, headers[3].Hash()) }()
 
 	// Create a new LightChain and check that it rolled back the state.
-	ncm, err := NewLightChain(&dummyOdr{db: bc.chainDb}, params.TestChainConfig, mock.NewMock())
+	ncm, err := NewLightChain(&dummyOdr{db: bc.chainDb}, params.TestChainConfig, mock.NewMock(), nil)
 	if err != nil {
 		t.Fatalf("failed to create new chain manager: %v", err)
 	}
@@ -193,8 +193,9 @@ func odrContractCall(ctx context.Context, db vntdb.Database, bc *core.BlockChain
 		context := core.NewVMContext(msg, header, chain, nil)
 		vmenv := vm.NewEVM(context, st, config, vm.Config{})
 		gp := new(core.GasPool).AddGas(math.MaxUint64)
-		ret, _, _, _ := core.ApplyMessage(vmenv, msg, gp)
-		res = append(res, ret...)
+		if result, err := core.ApplyMessage(vmenv, msg, gp); err == nil {
+			res = append(res, result.Return()...)
+		}
 		if st.Error() != nil {
 			return res, st.Error()
 		}
@@ -248,7 +249,7 @@ func testChainOdr(t *testing.T, protocol int, fn odrTestFn) {
 	}
 
 	odr := &testOdr{sdb: sdb, ldb: ldb}
-	lightchain, err := NewLightChain(odr, params.TestChainConfig, mock.NewMock())
+	lightchain, err := NewLightChain(odr, params.TestChainConfig, mock.NewMock(), nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -19,6 +19,7 @@ package light
 import (
 	"context"
 	"fmt"
+	"math/big"
 	"sync"
 	"time"
 
@@ -367,13 +368,30 @@ func (pool *TxPool) validateTx(ctx context.Context, tx *types.Transaction) error
 	}
 
 	// Transactor should have enough funds to cover the costs
-	// cost == V + GP * GL
-	if b := currentState.GetBalance(from); b.Cmp(tx.Cost()) < 0 {
+	// cost == V + GP * GL, or V alone if sponsored
+	if b := currentState.GetBalance(from); b.Cmp(tx.SenderCost()) < 0 {
 		return core.ErrInsufficientFunds
 	}
+	// Sponsored transactions need the fork and a fee payer affording the gas
+	if tx.FeePayer() != nil {
+		if !pool.config.IsFeeDelegation(new(big.Int).Add(header.Number, big.NewInt(1))) {
+			return core.ErrFeeDelegationInactive
+		}
+		payer, err := types.FeePayer(pool.signer, tx)
+		if err != nil {
+			return core.ErrInvalidFeePayer
+		}
+		fee := new(big.Int).Mul(tx.GasPrice(), new(big.Int).SetUint64(tx.Gas()))
+		if payer == from {
+			fee = tx.Cost()
+		}
+		if b := currentState.GetBalance(payer); b.Cmp(fee) < 0 {
+			return core.ErrInsufficientFeePayerFunds
+		}
+	}
 
 	// Should supply enough intrinsic gas
-	gas, err := core.IntrinsicGas(tx.Data(), tx.To() == nil)
+	gas, err := core.IntrinsicGas(tx.Data(), tx.To() == nil, tx.FeePayer() != nil)
 	if err != nil {
 		return err
 	}
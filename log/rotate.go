@@ -0,0 +1,173 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package log
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat is the timestamp layout appended to the name of rotated log
+// files. It sorts lexically in chronological order.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// RotatingFileHandler returns a handler which writes log records to the given
+// file using the given format, rotating it out once it would grow beyond maxSize
+// bytes or once it has been open for longer than interval. Rotated files are
+// renamed after the time of their rotation and only the newest maxBackups of
+// them are kept around. A zero value disables the respective limit.
+func RotatingFileHandler(path string, maxSize int64, interval time.Duration, maxBackups int, fmtr Format) (Handler, error) {
+	w, err := newRotatingWriter(path, maxSize, interval, maxBackups)
+	if err != nil {
+		return nil, err
+	}
+	return closingHandler{w, StreamHandler(w, fmtr)}, nil
+}
+
+// rotatingWriter is an io.WriteCloser appending to a file, which it rotates
+// based on the file's size and age.
+type rotatingWriter struct {
+	path       string
+	maxSize    int64
+	interval   time.Duration
+	maxBackups int
+	now        func() time.Time // Clock source, replaceable in tests
+
+	file   *os.File
+	size   int64     // Number of bytes in the current file
+	opened time.Time // Time the current file was opened at
+	lock   sync.Mutex
+}
+
+// newRotatingWriter creates a rotating writer, opening or creating the file at
+// the given path.
+func newRotatingWriter(path string, maxSize int64, interval time.Duration, maxBackups int) (*rotatingWriter, error) {
+	w := &rotatingWriter{
+		path:       path,
+		maxSize:    maxSize,
+		interval:   interval,
+		maxBackups: maxBackups,
+		now:        time.Now,
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write implements io.Writer, rotating the log file first if the write would
+// exceed the size limit or the file is already past its lifetime. If the file
+// can't be rotated, the data is still appended to it and the failure returned.
+func (w *rotatingWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+	oversized := w.maxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.maxSize
+	expired := w.interval > 0 && w.now().Sub(w.opened) >= w.interval
+	var rotateErr error
+	if oversized || expired {
+		if rotateErr = w.rotate(); w.file == nil {
+			return 0, rotateErr
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	if err == nil {
+		err = rotateErr
+	}
+	return n, err
+}
+
+// Close implements io.Closer, closing the current log file.
+func (w *rotatingWriter) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+// open opens the log file for appending, creating it if it doesn't exist yet.
+func (w *rotatingWriter) open() error {
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	w.file, w.size, w.opened = file, info.Size(), w.now()
+	return nil
+}
+
+// rotate moves the current log file aside, opens a fresh one and removes the
+// backups beyond the configured limit. If the file can't be moved aside, it is
+// reopened to keep logging into it.
+func (w *rotatingWriter) rotate() error {
+	err := w.file.Close()
+	w.file = nil
+
+	if err == nil {
+		ext := filepath.Ext(w.path)
+		backup := strings.TrimSuffix(w.path, ext) + "-" + w.now().Format(backupTimeFormat) + ext
+		err = os.Rename(w.path, backup)
+	}
+	if err != nil {
+		if oerr := w.open(); oerr != nil {
+			return oerr
+		}
+		return err
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+	if w.maxBackups > 0 {
+		backups := w.backups()
+		for len(backups) > w.maxBackups {
+			os.Remove(backups[0])
+			backups = backups[1:]
+		}
+	}
+	return nil
+}
+
+// backups returns the rotated log files, oldest first.
+func (w *rotatingWriter) backups() []string {
+	ext := filepath.Ext(w.path)
+	matches, err := filepath.Glob(strings.TrimSuffix(w.path, ext) + "-*" + ext)
+	if err != nil {
+		return nil
+	}
+	sort.Strings(matches)
+	return matches
+}
@@ -0,0 +1,99 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package log
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Tests that log files are rotated once they would grow too large or once they
+// have been written to for too long, and that old backups are cleaned up.
+func TestRotatingWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "log-rotate")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "logs", "gvnt.log")
+	w, err := newRotatingWriter(path, 10, time.Hour, 2)
+	if err != nil {
+		t.Fatalf("failed to create writer: %v", err)
+	}
+	defer w.Close()
+
+	clock := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	w.now = func() time.Time { return clock }
+	w.opened = clock
+
+	write := func(data string) {
+		if _, err := w.Write([]byte(data)); err != nil {
+			t.Fatalf("failed to write %q: %v", data, err)
+		}
+		clock = clock.Add(time.Second)
+	}
+	check := func(backups int, current string) {
+		if have := len(w.backups()); have != backups {
+			t.Fatalf("backup count mismatch: have %d, want %d", have, backups)
+		}
+		blob, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatalf("failed to read log file: %v", err)
+		}
+		if string(blob) != current {
+			t.Fatalf("log content mismatch: have %q, want %q", blob, current)
+		}
+	}
+	// Fill the file up to its size limit, overflowing into a new one
+	write("12345")
+	write("67890")
+	check(0, "1234567890")
+	write("abc")
+	check(1, "abc")
+
+	// Oversized writes still go into a file of their own
+	write("0123456789abcdef")
+	check(2, "0123456789abcdef")
+
+	// Let the file expire and ensure the oldest backup is dropped
+	clock = clock.Add(time.Hour)
+	write("x")
+	check(2, "x")
+
+	if blob, err := ioutil.ReadFile(w.backups()[0]); err != nil || string(blob) != "abc" {
+		t.Fatalf("oldest backup mismatch: have %q, want %q (err: %v)", blob, "abc", err)
+	}
+	// Block the backup name with a non-empty directory and ensure a failed
+	// rotation keeps logging into the current file
+	clock = clock.Add(time.Hour)
+	blocked := filepath.Join(dir, "logs", "gvnt-"+clock.Format(backupTimeFormat)+".log")
+	if err := os.MkdirAll(filepath.Join(blocked, "file"), 0755); err != nil {
+		t.Fatalf("failed to block backup: %v", err)
+	}
+	if n, err := w.Write([]byte("y")); err == nil || n != 1 {
+		t.Fatalf("failed rotation result mismatch: have %d bytes, err %v", n, err)
+	}
+	os.RemoveAll(blocked)
+	check(2, "xy")
+
+	write("0123456789")
+	check(2, "0123456789")
+}
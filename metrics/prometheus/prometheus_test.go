@@ -0,0 +1,69 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package prometheus
+
+import (
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/vntchain/go-vnt/metrics"
+)
+
+func TestMain(m *testing.M) {
+	metrics.Enabled = true
+	os.Exit(m.Run())
+}
+
+func TestHandler(t *testing.T) {
+	reg := metrics.NewRegistry()
+
+	counter := metrics.NewRegisteredCounter("test/counter", reg)
+	counter.Inc(12345)
+
+	gauge := metrics.NewRegisteredGaugeFloat64("test/gauge.float64", reg)
+	gauge.Update(34567.89)
+
+	timer := metrics.NewRegisteredTimer("test/timer", reg)
+	timer.Update(120 * time.Millisecond)
+	timer.Update(23 * time.Millisecond)
+
+	emptyTimer := metrics.NewRegisteredResettingTimer("test/empty_resetting_timer", reg)
+	emptyTimer.Update(time.Millisecond)
+	emptyTimer.Snapshot() // resets the timer
+
+	rec := httptest.NewRecorder()
+	Handler(reg).ServeHTTP(rec, httptest.NewRequest("GET", "/debug/metrics/prometheus", nil))
+
+	want := `# TYPE test_counter gauge
+test_counter 12345
+# TYPE test_gauge_float64 gauge
+test_gauge_float64 34567.89
+# TYPE test_timer summary
+test_timer{quantile="0.5"} 7.15e+07
+test_timer{quantile="0.75"} 1.2e+08
+test_timer{quantile="0.95"} 1.2e+08
+test_timer{quantile="0.99"} 1.2e+08
+test_timer{quantile="0.999"} 1.2e+08
+test_timer{quantile="0.9999"} 1.2e+08
+test_timer_count 2
+`
+	if have := rec.Body.String(); have != want {
+		t.Fatalf("prometheus output mismatch:\nhave:\n%s\nwant:\n%s", have, want)
+	}
+}
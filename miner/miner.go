@@ -147,6 +147,12 @@ func (self *Miner) SetExtra(extra []byte) error {
 	return nil
 }
 
+// SetSkipEmpty toggles whether rounds without any pending transactions are let
+// pass without producing a block, as done in developer mode.
+func (self *Miner) SetSkipEmpty(skip bool) {
+	self.worker.setSkipEmpty(skip)
+}
+
 // Pending returns the currently pending block and associated state.
 func (self *Miner) Pending() (*types.Block, *state.StateDB) {
 	return self.worker.pending()
@@ -107,8 +107,9 @@ type worker struct {
 	proc    core.Validator
 	chainDb vntdb.Database
 
-	coinbase common.Address
-	extra    []byte
+	coinbase  common.Address
+	extra     []byte
+	skipEmpty bool // Don't produce blocks without transactions
 
 	currentMu sync.Mutex
 	current   *Work
@@ -178,6 +179,12 @@ func (self *worker) setExtra(extra []byte) {
 	self.extra = extra
 }
 
+func (self *worker) setSkipEmpty(skip bool) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.skipEmpty = skip
+}
+
 func (self *worker) pending() (*types.Block, *state.StateDB) {
 	if atomic.LoadInt32(&self.producing) == 0 {
 		// return a snapshot to avoid contention on currentMu mutex
@@ -509,8 +516,22 @@ func (self *worker) commitNewWork() {
 		log.Error("Failed to fetch pending transactions", "err", err)
 		return
 	}
-	txs := types.NewTransactionsByPriceAndNonce(self.current.signer, pending)
-	work.commitTransactions(self.mux, txs, self.chain, self.coinbase)
+	// Fill the block with the pending transactions, the local ones first
+	localTxs, remoteTxs := make(map[common.Address]types.Transactions), pending
+	for _, account := range self.vnt.TxPool().Locals() {
+		if txs := remoteTxs[account]; len(txs) > 0 {
+			delete(remoteTxs, account)
+			localTxs[account] = txs
+		}
+	}
+	if len(localTxs) > 0 {
+		txs := types.NewTransactionsByPriceAndNonce(self.current.signer, localTxs)
+		work.commitTransactions(self.mux, txs, self.chain, self.coinbase)
+	}
+	if len(remoteTxs) > 0 {
+		txs := types.NewTransactionsByPriceAndNonce(self.current.signer, remoteTxs)
+		work.commitTransactions(self.mux, txs, self.chain, self.coinbase)
+	}
 
 	// Create the new block to seal with the consensus engine
 	if work.Block, err = self.engine.Finalize(self.chain, header, work.state, work.txs, work.receipts); err != nil {
@@ -521,7 +542,8 @@ func (self *worker) commitNewWork() {
 	blocktxjson, _ := json.Marshal(work.Block.Transactions())
 	log.Debug("worker", "func", "commitNewWork", "block header", string(blockheaderjson), "block tx", string(blocktxjson))
 	// We only care about logging if we're actually producing.
-	if atomic.LoadInt32(&self.producing) == 1 {
+	idle := self.skipEmpty && work.tcount == 0
+	if atomic.LoadInt32(&self.producing) == 1 && !idle {
 		log.Info("Commit new producing work", "number", work.Block.Number(), "txs", work.tcount, "elapsed", common.PrettyDuration(time.Since(tstart)))
 		self.unconfirmed.Shift(work.Block.NumberU64() - 1)
 	}
@@ -533,6 +555,10 @@ func (self *worker) commitNewWork() {
 		log.Debug("Failed to prepare header for producing", "preErr", preErr)
 		return
 	}
+	if idle {
+		log.Trace("No pending transactions, skip producing", "number", work.Block.Number())
+		return
+	}
 
 	// updateSnapshot() is time consuming. If push() before updateSnapshot(), Gvnt may be
 	// stop for concurrent map iteration and map write. After push(), a block generated
@@ -20,6 +20,7 @@ package gvnt
 
 import (
 	"errors"
+	"time"
 
 	hubble "github.com/vntchain/go-vnt"
 	"github.com/vntchain/go-vnt/common"
@@ -77,11 +78,16 @@ type SyncProgress struct {
 	progress hubble.SyncProgress
 }
 
-func (p *SyncProgress) GetStartingBlock() int64 { return int64(p.progress.StartingBlock) }
-func (p *SyncProgress) GetCurrentBlock() int64  { return int64(p.progress.CurrentBlock) }
-func (p *SyncProgress) GetHighestBlock() int64  { return int64(p.progress.HighestBlock) }
-func (p *SyncProgress) GetPulledStates() int64  { return int64(p.progress.PulledStates) }
-func (p *SyncProgress) GetKnownStates() int64   { return int64(p.progress.KnownStates) }
+func (p *SyncProgress) GetStartingBlock() int64    { return int64(p.progress.StartingBlock) }
+func (p *SyncProgress) GetCurrentBlock() int64     { return int64(p.progress.CurrentBlock) }
+func (p *SyncProgress) GetHighestBlock() int64     { return int64(p.progress.HighestBlock) }
+func (p *SyncProgress) GetPulledStates() int64     { return int64(p.progress.PulledStates) }
+func (p *SyncProgress) GetKnownStates() int64      { return int64(p.progress.KnownStates) }
+func (p *SyncProgress) GetStage() string           { return p.progress.Stage }
+func (p *SyncProgress) GetPulledHeaders() int64    { return int64(p.progress.PulledHeaders) }
+func (p *SyncProgress) GetPulledBodies() int64     { return int64(p.progress.PulledBodies) }
+func (p *SyncProgress) GetPulledReceipts() int64   { return int64(p.progress.PulledReceipts) }
+func (p *SyncProgress) GetRemainingSeconds() int64 { return int64(p.progress.Remaining / time.Second) }
 
 // Topics is a set of topic lists to filter events with.
 type Topics struct{ topics [][]common.Hash }
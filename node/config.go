@@ -18,18 +18,27 @@ package node
 
 import (
 	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/vntchain/go-vnt/accounts"
+	"github.com/vntchain/go-vnt/accounts/external"
 	"github.com/vntchain/go-vnt/accounts/keystore"
+	"github.com/vntchain/go-vnt/accounts/usbwallet"
 	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/common/hexutil"
 	"github.com/vntchain/go-vnt/crypto"
 	"github.com/vntchain/go-vnt/log"
+	"github.com/vntchain/go-vnt/rpc"
 	"github.com/vntchain/go-vnt/vntp2p"
 )
 
@@ -39,6 +48,7 @@ const (
 	datadirStaticNodes     = "static-nodes.json"  // Path within the datadir to the static node list
 	datadirTrustedNodes    = "trusted-nodes.json" // Path within the datadir to the trusted node list
 	datadirNodeDatabase    = "nodes"              // Path within the datadir to store the node infos
+	datadirJWTSecret       = "jwtsecret"          // Path within the datadir to the authenticated RPC secret
 )
 
 // Config represents a small collection of configuration values to fine tune the
@@ -80,6 +90,22 @@ type Config struct {
 	// scrypt KDF at the expense of security.
 	UseLightweightKDF bool `toml:",omitempty"`
 
+	// KeyStoreScryptN and KeyStoreScryptP override the scrypt parameters of the
+	// key store KDF selected by UseLightweightKDF. Zero keeps the preset value.
+	KeyStoreScryptN int `toml:",omitempty"`
+	KeyStoreScryptP int `toml:",omitempty"`
+
+	// NoUSB disables hardware wallet monitoring and connectivity.
+	NoUSB bool `toml:",omitempty"`
+
+	// InsecureUnlockAllowed allows unlocking accounts while the RPC APIs are
+	// exposed over HTTP or websocket.
+	InsecureUnlockAllowed bool `toml:",omitempty"`
+
+	// ExternalSigner specifies an external URI for a clef-type signer, reachable
+	// over IPC or HTTP. Its accounts are made available next to the local ones.
+	ExternalSigner string `toml:",omitempty"`
+
 	// IPCPath is the requested location to place the IPC endpoint. If the path is
 	// a simple file name, it is placed inside the data directory (or on the root
 	// pipe path on Windows), whereas if it's a resolvable path name (absolute or
@@ -109,11 +135,29 @@ type Config struct {
 	// Requests using ip address directly are not affected
 	HTTPVirtualHosts []string `toml:",omitempty"`
 
+	// HTTPTLSCert and HTTPTLSKey are the paths of the PEM encoded certificate and
+	// private key to serve the HTTP RPC interface over TLS with. If empty, the
+	// interface is served in plain text.
+	HTTPTLSCert string `toml:",omitempty"`
+	HTTPTLSKey  string `toml:",omitempty"`
+
+	// HTTPTLSClientCA is the path of a PEM encoded bundle of certificate
+	// authorities. If set, HTTPS clients must authenticate with a certificate
+	// signed by one of them.
+	HTTPTLSClientCA string `toml:",omitempty"`
+
 	// HTTPModules is a list of API modules to expose via the HTTP RPC interface.
 	// If the module list is empty, all RPC API endpoints designated public will be
 	// exposed.
 	HTTPModules []string `toml:",omitempty"`
 
+	// HTTPAllowMethods and HTTPDenyMethods restrict the methods of the exposed
+	// modules served via the HTTP RPC interface. Entries are method names such
+	// as vnt_call or namespace wildcards such as debug_*. If the allow list is
+	// empty all methods are allowed, and denied methods are never served.
+	HTTPAllowMethods []string `toml:",omitempty"`
+	HTTPDenyMethods  []string `toml:",omitempty"`
+
 	// WSHost is the host interface on which to start the websocket RPC server. If
 	// this field is empty, no websocket API endpoint will be started.
 	WSHost string `toml:",omitempty"`
@@ -128,11 +172,28 @@ type Config struct {
 	// cannot verify the validity of the request header.
 	WSOrigins []string `toml:",omitempty"`
 
+	// WSTLSCert and WSTLSKey are the paths of the PEM encoded certificate and
+	// private key to serve the websocket RPC interface over TLS with. If empty,
+	// the interface is served in plain text.
+	WSTLSCert string `toml:",omitempty"`
+	WSTLSKey  string `toml:",omitempty"`
+
+	// WSTLSClientCA is the path of a PEM encoded bundle of certificate
+	// authorities. If set, WSS clients must authenticate with a certificate
+	// signed by one of them.
+	WSTLSClientCA string `toml:",omitempty"`
+
 	// WSModules is a list of API modules to expose via the websocket RPC interface.
 	// If the module list is empty, all RPC API endpoints designated public will be
 	// exposed.
 	WSModules []string `toml:",omitempty"`
 
+	// WSAllowMethods and WSDenyMethods restrict the methods of the exposed
+	// modules served via the websocket RPC interface, the same way as
+	// HTTPAllowMethods and HTTPDenyMethods.
+	WSAllowMethods []string `toml:",omitempty"`
+	WSDenyMethods  []string `toml:",omitempty"`
+
 	// WSExposeAll exposes all API modules via the WebSocket RPC interface rather
 	// than just the public ones.
 	//
@@ -140,10 +201,182 @@ type Config struct {
 	// private APIs to untrusted users is a major security risk.
 	WSExposeAll bool `toml:",omitempty"`
 
+	// WSReadLimit is the maximum size in bytes of a message read from a websocket
+	// RPC connection. Zero means RPCBodyLimit.
+	WSReadLimit int64 `toml:",omitempty"`
+
+	// WSCompression enables the permessage-deflate compression of websocket RPC
+	// connections with clients supporting it.
+	WSCompression bool `toml:",omitempty"`
+
+	// AuthAddr is the host interface on which to start the JWT authenticated RPC
+	// server, serving both HTTP and websocket requests. If this field is empty,
+	// no authenticated endpoint will be started.
+	AuthAddr string `toml:",omitempty"`
+
+	// AuthPort is the TCP port number on which to start the authenticated RPC
+	// server.
+	AuthPort int `toml:",omitempty"`
+
+	// AuthVirtualHosts is the list of virtual hostnames which are allowed on
+	// incoming requests to the authenticated RPC server.
+	AuthVirtualHosts []string `toml:",omitempty"`
+
+	// JWTSecret is the path to a file holding the hex encoded 32 byte secret used
+	// to verify the tokens of authenticated RPC requests. If empty, a secret is
+	// loaded from, or generated into, the data directory.
+	JWTSecret string `toml:",omitempty"`
+
+	// RPCWaitPeers is the minimum number of connected peers to wait for before
+	// opening the HTTP and websocket RPC endpoints. Zero opens them right away.
+	RPCWaitPeers int `toml:",omitempty"`
+
+	// RPCWaitTimeout is the maximum time to wait for RPCWaitPeers before opening
+	// the HTTP and websocket RPC endpoints regardless. Zero means
+	// DefaultRPCWaitTimeout.
+	RPCWaitTimeout time.Duration `toml:",omitempty"`
+
+	// RPCShutdownTimeout is the maximum time the requests in flight on the RPC
+	// endpoints may take to finish once the node stops, before their connections
+	// are closed. Zero means DefaultRPCShutdownTimeout.
+	RPCShutdownTimeout time.Duration `toml:",omitempty"`
+
+	// RPCBodyLimit is the maximum size in bytes of a request body or websocket
+	// message accepted by the HTTP and websocket RPC servers. Zero means the
+	// default of the rpc package.
+	RPCBodyLimit int64 `toml:",omitempty"`
+
+	// RPCBatchLimit is the maximum number of requests in a batch accepted by the
+	// HTTP and websocket RPC servers. Zero means unlimited.
+	RPCBatchLimit int `toml:",omitempty"`
+
+	// RPCRateLimit is the maximum number of requests per second a single IP may
+	// send to the HTTP and websocket RPC servers. Zero means unlimited.
+	RPCRateLimit int `toml:",omitempty"`
+
+	// MemoryDatabases keeps the databases opened by the services in memory even
+	// if a data directory is configured, so that the chain is discarded on exit
+	// while the keys in the data directory persist.
+	MemoryDatabases bool `toml:",omitempty"`
+
 	// Logger is a custom logger to use with the p2p.Server.
 	Logger log.Logger `toml:",omitempty"`
 }
 
+// rpcWaitTimeout returns the effective time to wait for peers before opening the
+// HTTP and websocket RPC endpoints.
+func (c *Config) rpcWaitTimeout() time.Duration {
+	if c.RPCWaitTimeout == 0 {
+		return DefaultRPCWaitTimeout
+	}
+	return c.RPCWaitTimeout
+}
+
+// memoryDatabases reports whether the databases of the services are kept in
+// memory instead of the data directory.
+func (c *Config) memoryDatabases() bool {
+	return c.DataDir == "" || c.MemoryDatabases
+}
+
+// rpcShutdownTimeout returns the effective time to let the in-flight RPC
+// requests finish at shutdown.
+func (c *Config) rpcShutdownTimeout() time.Duration {
+	if c.RPCShutdownTimeout == 0 {
+		return DefaultRPCShutdownTimeout
+	}
+	return c.RPCShutdownTimeout
+}
+
+// rpcLimits returns the request limits of the HTTP and websocket RPC servers.
+func (c *Config) rpcLimits() rpc.Limits {
+	return rpc.Limits{
+		BodySize:    c.RPCBodyLimit,
+		BatchSize:   c.RPCBatchLimit,
+		RequestRate: c.RPCRateLimit,
+	}
+}
+
+// httpMethodFilter returns the methods served by the HTTP RPC server.
+func (c *Config) httpMethodFilter() rpc.MethodFilter {
+	return rpc.MethodFilter{Allow: c.HTTPAllowMethods, Deny: c.HTTPDenyMethods}
+}
+
+// wsMethodFilter returns the methods served by the websocket RPC server.
+func (c *Config) wsMethodFilter() rpc.MethodFilter {
+	return rpc.MethodFilter{Allow: c.WSAllowMethods, Deny: c.WSDenyMethods}
+}
+
+// wsConfig returns the per-connection settings of the websocket RPC server.
+func (c *Config) wsConfig() rpc.WebsocketConfig {
+	return rpc.WebsocketConfig{
+		ReadLimit:   c.WSReadLimit,
+		Compression: c.WSCompression,
+	}
+}
+
+// httpTLSConfig returns the TLS settings of the HTTP RPC server, or nil if it
+// serves plain text.
+func (c *Config) httpTLSConfig() (*tls.Config, error) {
+	return loadTLSConfig(c.HTTPTLSCert, c.HTTPTLSKey, c.HTTPTLSClientCA)
+}
+
+// wsTLSConfig returns the TLS settings of the websocket RPC server, or nil if
+// it serves plain text.
+func (c *Config) wsTLSConfig() (*tls.Config, error) {
+	return loadTLSConfig(c.WSTLSCert, c.WSTLSKey, c.WSTLSClientCA)
+}
+
+// httpScheme returns the URL scheme the HTTP RPC server is reachable at.
+func (c *Config) httpScheme() string {
+	if c.HTTPTLSCert != "" {
+		return "https"
+	}
+	return "http"
+}
+
+// wsScheme returns the URL scheme the websocket RPC server is reachable at.
+func (c *Config) wsScheme() string {
+	if c.WSTLSCert != "" {
+		return "wss"
+	}
+	return "ws"
+}
+
+// loadTLSConfig assembles the TLS settings of an RPC server from the files of
+// its certificate, key and optional client certificate authorities.
+func loadTLSConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	if certFile == "" && keyFile == "" {
+		if clientCAFile != "" {
+			return nil, errors.New("tls client authentication requires a server certificate")
+		}
+		return nil, nil
+	}
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("tls requires both a certificate and a key")
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if clientCAFile != "" {
+		data, err := ioutil.ReadFile(clientCAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in %s", clientCAFile)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
+
 // IPCEndpoint resolves an IPC endpoint based on a configured value, taking into
 // account the set data folders as well as the designated platform we're currently
 // running on.
@@ -213,12 +446,27 @@ func (c *Config) WSEndpoint() string {
 	return fmt.Sprintf("%s:%d", c.WSHost, c.WSPort)
 }
 
+// ExtRPCEnabled returns whether the RPC APIs are reachable over HTTP or
+// websocket, that is by anyone able to connect to the node.
+func (c *Config) ExtRPCEnabled() bool {
+	return c.HTTPHost != "" || c.WSHost != ""
+}
+
 // DefaultWSEndpoint returns the websocket endpoint used by default.
 func DefaultWSEndpoint() string {
 	config := &Config{WSHost: DefaultWSHost, WSPort: DefaultWSPort}
 	return config.WSEndpoint()
 }
 
+// AuthEndpoint resolves the authenticated RPC endpoint based on the configured
+// host interface and port parameters.
+func (c *Config) AuthEndpoint() string {
+	if c.AuthAddr == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d", c.AuthAddr, c.AuthPort)
+}
+
 // NodeName returns the devp2p node identifier.
 func (c *Config) NodeName() string {
 	name := c.name()
@@ -325,6 +573,41 @@ func (c *Config) NodeKey() *ecdsa.PrivateKey {
 	return key
 }
 
+// jwtSecret retrieves the secret used to authenticate RPC requests, reading it
+// from the configured file or from the data folder. If no file was configured
+// and none is found in the data folder, a new secret is generated and stored.
+func (c *Config) jwtSecret() ([]byte, error) {
+	path := c.JWTSecret
+	if path == "" {
+		if c.DataDir == "" {
+			return nil, errors.New("no jwt secret configured for ephemeral node")
+		}
+		path = c.resolvePath(datadirJWTSecret)
+	}
+	if data, err := ioutil.ReadFile(path); err == nil {
+		secret := common.FromHex(strings.TrimSpace(string(data)))
+		if len(secret) != 32 {
+			return nil, fmt.Errorf("invalid jwt secret in %s: want 32 bytes, have %d", path, len(secret))
+		}
+		return secret, nil
+	} else if c.JWTSecret != "" {
+		return nil, err
+	}
+	// No persistent secret found, generate and store a new one.
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(path, []byte(hexutil.Encode(secret)), 0600); err != nil {
+		return nil, err
+	}
+	log.Info("Generated JWT secret", "path", path)
+	return secret, nil
+}
+
 // StaticNodes returns a list of node vnode URLs configured as static nodes.
 func (c *Config) StaticNodes() []*vntp2p.Node {
 	return c.parsePersistentNodes(c.resolvePath(datadirStaticNodes))
@@ -375,6 +658,18 @@ func (c *Config) AccountConfig() (int, int, string, error) {
 		scryptN = keystore.LightScryptN
 		scryptP = keystore.LightScryptP
 	}
+	if c.KeyStoreScryptN != 0 {
+		if c.KeyStoreScryptN < 2 || c.KeyStoreScryptN&(c.KeyStoreScryptN-1) != 0 {
+			return 0, 0, "", fmt.Errorf("invalid scrypt N %d: must be a power of two above 1", c.KeyStoreScryptN)
+		}
+		scryptN = c.KeyStoreScryptN
+	}
+	if c.KeyStoreScryptP != 0 {
+		if c.KeyStoreScryptP < 0 {
+			return 0, 0, "", fmt.Errorf("invalid scrypt P %d: must be positive", c.KeyStoreScryptP)
+		}
+		scryptP = c.KeyStoreScryptP
+	}
 
 	var (
 		keydir string
@@ -414,5 +709,27 @@ func makeAccountManager(conf *Config) (*accounts.Manager, string, error) {
 	backends := []accounts.Backend{
 		keystore.NewKeyStore(keydir, scryptN, scryptP),
 	}
-	return accounts.NewManager(backends...), ephemeral, nil
+	if conf.ExternalSigner != "" {
+		log.Info("Using external signer", "url", conf.ExternalSigner)
+		extapi, err := external.NewExternalBackend(conf.ExternalSigner)
+		if err != nil {
+			return nil, "", fmt.Errorf("error connecting to external signer: %v", err)
+		}
+		backends = append(backends, extapi)
+	}
+	if !conf.NoUSB {
+		// Start a USB hub for Ledger hardware wallets
+		if ledgerhub, err := usbwallet.NewLedgerHub(); err != nil {
+			log.Warn(fmt.Sprintf("Failed to start Ledger hub, disabling: %v", err))
+		} else {
+			backends = append(backends, ledgerhub)
+		}
+		// Start a USB hub for Trezor hardware wallets
+		if trezorhub, err := usbwallet.NewTrezorHub(); err != nil {
+			log.Warn(fmt.Sprintf("Failed to start Trezor hub, disabling: %v", err))
+		} else {
+			backends = append(backends, trezorhub)
+		}
+	}
+	return accounts.NewManager(&accounts.Config{InsecureUnlockAllowed: conf.InsecureUnlockAllowed}, backends...), ephemeral, nil
 }
@@ -18,12 +18,14 @@ package node
 
 import (
 	"bytes"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"runtime"
 	"testing"
 
+	"github.com/vntchain/go-vnt/accounts/keystore"
 	"github.com/vntchain/go-vnt/crypto"
 	p2p "github.com/vntchain/go-vnt/vntp2p"
 )
@@ -148,3 +150,122 @@ func TestNodeKeyPersistency(t *testing.T) {
 		t.Fatalf("ephemeral node key persisted to disk")
 	}
 }
+
+// Tests that the authenticated RPC secret is generated and persisted if none is
+// configured, and that invalid or missing configured secrets are rejected.
+func TestJWTSecretPersistency(t *testing.T) {
+	dir, err := ioutil.TempDir("", "node-test")
+	if err != nil {
+		t.Fatalf("failed to create temporary data directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Ephemeral nodes need an explicitly configured secret
+	if _, err := (&Config{Name: "unit-test"}).jwtSecret(); err == nil {
+		t.Fatalf("ephemeral node without secret accepted")
+	}
+	// Ensure a fresh secret is generated and loaded afterwards
+	config := &Config{Name: "unit-test", DataDir: dir}
+	secret1, err := config.jwtSecret()
+	if err != nil {
+		t.Fatalf("failed to generate secret: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "unit-test", datadirJWTSecret)); err != nil {
+		t.Fatalf("secret not persisted to data directory: %v", err)
+	}
+	secret2, err := config.jwtSecret()
+	if err != nil {
+		t.Fatalf("failed to load persisted secret: %v", err)
+	}
+	if !bytes.Equal(secret1, secret2) {
+		t.Fatalf("persisted secret mismatch: have %x, want %x", secret2, secret1)
+	}
+	// Ensure explicitly configured secrets are used, but never generated
+	path := filepath.Join(dir, "secret.hex")
+	if _, err := (&Config{JWTSecret: path}).jwtSecret(); err == nil {
+		t.Fatalf("missing configured secret accepted")
+	}
+	if err := ioutil.WriteFile(path, []byte("0x1234"), 0600); err != nil {
+		t.Fatalf("failed to write secret: %v", err)
+	}
+	if _, err := (&Config{JWTSecret: path}).jwtSecret(); err == nil {
+		t.Fatalf("short configured secret accepted")
+	}
+	if err := ioutil.WriteFile(path, []byte(fmt.Sprintf("%x\n", secret1)), 0600); err != nil {
+		t.Fatalf("failed to write secret: %v", err)
+	}
+	secret3, err := (&Config{JWTSecret: path}).jwtSecret()
+	if err != nil {
+		t.Fatalf("failed to load configured secret: %v", err)
+	}
+	if !bytes.Equal(secret1, secret3) {
+		t.Fatalf("configured secret mismatch: have %x, want %x", secret3, secret1)
+	}
+}
+
+// Tests that the static and trusted node lists are loaded from the data
+// directory, skipping the entries which can't be parsed.
+func TestPersistentNodes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "node-test")
+	if err != nil {
+		t.Fatalf("failed to create temporary data directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := &Config{Name: "unit-test", DataDir: dir}
+	if nodes := config.StaticNodes(); nodes != nil {
+		t.Fatalf("static nodes loaded without node list: %v", nodes)
+	}
+	url := "/ip4/127.0.0.1/tcp/5210/ipfs/1kHcch6yuBCgC5nPPSK3Yp7Es4c4eenxAeK167pYwUvNjRo"
+	list := fmt.Sprintf(`["%s", "", "invalid"]`, url)
+
+	if err := os.MkdirAll(filepath.Join(dir, "unit-test"), 0700); err != nil {
+		t.Fatalf("failed to create instance directory: %v", err)
+	}
+	for _, file := range []string{datadirStaticNodes, datadirTrustedNodes} {
+		if err := ioutil.WriteFile(filepath.Join(dir, "unit-test", file), []byte(list), 0600); err != nil {
+			t.Fatalf("failed to write %s: %v", file, err)
+		}
+	}
+	want := p2p.MustParseNode(url)
+	for name, nodes := range map[string][]*p2p.Node{"static": config.StaticNodes(), "trusted": config.TrustedNodes()} {
+		if len(nodes) != 1 {
+			t.Fatalf("%s node count mismatch: have %d, want 1", name, len(nodes))
+		}
+		if nodes[0].Id != want.Id {
+			t.Errorf("%s node mismatch: have %v, want %v", name, nodes[0].Id, want.Id)
+		}
+	}
+}
+
+// Tests that the keystore scrypt parameters override the KDF presets and that
+// invalid values are rejected.
+func TestAccountConfigScrypt(t *testing.T) {
+	tests := []struct {
+		config     Config
+		wantN      int
+		wantP      int
+		shouldFail bool
+	}{
+		{Config{}, keystore.StandardScryptN, keystore.StandardScryptP, false},
+		{Config{UseLightweightKDF: true}, keystore.LightScryptN, keystore.LightScryptP, false},
+		{Config{KeyStoreScryptN: 1 << 20, KeyStoreScryptP: 2}, 1 << 20, 2, false},
+		{Config{UseLightweightKDF: true, KeyStoreScryptP: 4}, keystore.LightScryptN, 4, false},
+		{Config{KeyStoreScryptN: 1000}, 0, 0, true},
+		{Config{KeyStoreScryptN: 1}, 0, 0, true},
+		{Config{KeyStoreScryptP: -1}, 0, 0, true},
+	}
+	for i, tt := range tests {
+		n, p, _, err := tt.config.AccountConfig()
+		if (err != nil) != tt.shouldFail {
+			t.Errorf("test %d: error mismatch: have %v, want failure %v", i, err, tt.shouldFail)
+			continue
+		}
+		if tt.shouldFail {
+			continue
+		}
+		if n != tt.wantN || p != tt.wantP {
+			t.Errorf("test %d: scrypt parameters mismatch: have N=%d P=%d, want N=%d P=%d", i, n, p, tt.wantN, tt.wantP)
+		}
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"os/user"
 	"path/filepath"
 	"runtime"
+	"time"
 
 	libp2p "github.com/libp2p/go-libp2p"
 	"github.com/vntchain/go-vnt/vntp2p"
@@ -31,6 +32,15 @@ const (
 	DefaultHTTPPort = 8545        // Default TCP port for the HTTP RPC server
 	DefaultWSHost   = "localhost" // Default host interface for the websocket RPC server
 	DefaultWSPort   = 8546        // Default TCP port for the websocket RPC server
+
+	DefaultAuthHost = "localhost" // Default host interface for the authenticated RPC server
+	DefaultAuthPort = 8551        // Default TCP port for the authenticated RPC server
+
+	DefaultGraphQLHost = "localhost" // Default host interface for the GraphQL server
+	DefaultGraphQLPort = 8547        // Default TCP port for the GraphQL server
+
+	DefaultRPCWaitTimeout     = 5 * time.Minute // Default time to wait for peers before opening RPC
+	DefaultRPCShutdownTimeout = 5 * time.Second // Default time to let in-flight RPC requests finish at shutdown
 )
 
 // DefaultConfig contains reasonable default settings.
@@ -41,6 +51,8 @@ var DefaultConfig = Config{
 	HTTPVirtualHosts: []string{"localhost"},
 	WSPort:           DefaultWSPort,
 	WSModules:        []string{"net", "web3"},
+	AuthPort:         DefaultAuthPort,
+	AuthVirtualHosts: []string{"localhost"},
 	P2P: vntp2p.Config{
 		ListenAddr: ":30303",
 		MaxPeers:   25,
@@ -25,9 +25,11 @@ import (
 	"reflect"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/prometheus/prometheus/util/flock"
 	"github.com/vntchain/go-vnt/accounts"
+	"github.com/vntchain/go-vnt/core/rawdb"
 	"github.com/vntchain/go-vnt/event"
 	"github.com/vntchain/go-vnt/internal/debug"
 	"github.com/vntchain/go-vnt/log"
@@ -67,6 +69,10 @@ type Node struct {
 	wsListener net.Listener // Websocket RPC listener socket to server API requests
 	wsHandler  *rpc.Server  // Websocket RPC request handler to process the API requests
 
+	authEndpoint string       // Authenticated RPC endpoint (interface + port) to listen at (empty = disabled)
+	authListener net.Listener // Authenticated RPC listener socket to serve API requests
+	authHandler  *rpc.Server  // Authenticated RPC request handler to process the API requests
+
 	stop chan struct{} // Channel to wait for termination notifications
 	lock sync.RWMutex
 
@@ -116,6 +122,7 @@ func New(conf *Config) (*Node, error) {
 		ipcEndpoint:       conf.IPCEndpoint(),
 		httpEndpoint:      conf.HTTPEndpoint(),
 		wsEndpoint:        conf.WSEndpoint(),
+		authEndpoint:      conf.AuthEndpoint(),
 		eventmux:          new(event.TypeMux),
 		log:               conf.Logger,
 	}, nil
@@ -154,12 +161,12 @@ func (n *Node) Start() error {
 	n.serverConfig.PrivateKey = n.config.NodeKey()
 	n.serverConfig.Name = n.config.NodeName()
 	n.serverConfig.Logger = n.log
-	// if n.serverConfig.StaticNodes == nil {
-	// 	n.serverConfig.StaticNodes = n.config.StaticNodes()
-	// }
-	// if n.serverConfig.TrustedNodes == nil {
-	// 	n.serverConfig.TrustedNodes = n.config.TrustedNodes()
-	// }
+	if n.serverConfig.StaticNodes == nil {
+		n.serverConfig.StaticNodes = n.config.StaticNodes()
+	}
+	if n.serverConfig.TrustedNodes == nil {
+		n.serverConfig.TrustedNodes = n.config.TrustedNodes()
+	}
 	if n.serverConfig.NodeDatabase == "" {
 		//n.serverConfig.NodeDatabase = n.config.NodeDB()
 		n.serverConfig.NodeDatabase = n.config.DataDir
@@ -229,6 +236,10 @@ func (n *Node) Start() error {
 	n.server = running
 	n.stop = make(chan struct{})
 
+	if n.delayRPC() {
+		go n.startDelayedRPC(running.PeerCount, n.stop)
+	}
+
 	return nil
 }
 
@@ -268,13 +279,25 @@ func (n *Node) startRPC(services map[reflect.Type]Service) error {
 		n.stopInProc()
 		return err
 	}
+	if err := n.startAuth(apis); err != nil {
+		n.stopIPC()
+		n.stopInProc()
+		return err
+	}
+	if n.delayRPC() {
+		// HTTP and websocket endpoints are opened once enough peers joined
+		n.rpcAPIs = apis
+		return nil
+	}
 	if err := n.startHTTP(n.httpEndpoint, apis, n.config.HTTPModules, n.config.HTTPCors, n.config.HTTPVirtualHosts); err != nil {
+		n.stopAuth()
 		n.stopIPC()
 		n.stopInProc()
 		return err
 	}
 	if err := n.startWS(n.wsEndpoint, apis, n.config.WSModules, n.config.WSOrigins, n.config.WSExposeAll); err != nil {
 		n.stopHTTP()
+		n.stopAuth()
 		n.stopIPC()
 		n.stopInProc()
 		return err
@@ -284,6 +307,76 @@ func (n *Node) startRPC(services map[reflect.Type]Service) error {
 	return nil
 }
 
+// peerWaitInterval is the frequency at which the peer count is polled while
+// delaying the opening of the RPC endpoints.
+var peerWaitInterval = time.Second
+
+// delayRPC reports whether the HTTP and websocket RPC endpoints should only be
+// opened once the configured number of peers is connected.
+func (n *Node) delayRPC() bool {
+	return n.config.RPCWaitPeers > 0 && (n.httpEndpoint != "" || n.wsEndpoint != "")
+}
+
+// startDelayedRPC waits until the configured number of peers is connected, or
+// the wait times out, and opens the HTTP and websocket RPC endpoints afterwards
+// unless the node was stopped in the meantime.
+func (n *Node) startDelayedRPC(peerCount func() int, quit <-chan struct{}) {
+	if !waitForPeers(n.log, peerCount, n.config.RPCWaitPeers, n.config.rpcWaitTimeout(), quit) {
+		select {
+		case <-quit:
+			return
+		default:
+			n.log.Warn("Timed out waiting for peers, opening RPC", "peers", peerCount(), "want", n.config.RPCWaitPeers)
+		}
+	}
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	select {
+	case <-quit:
+		return
+	default:
+	}
+	if err := n.startHTTP(n.httpEndpoint, n.rpcAPIs, n.config.HTTPModules, n.config.HTTPCors, n.config.HTTPVirtualHosts); err != nil {
+		n.log.Error("Failed to open HTTP endpoint", "err", err)
+		return
+	}
+	if err := n.startWS(n.wsEndpoint, n.rpcAPIs, n.config.WSModules, n.config.WSOrigins, n.config.WSExposeAll); err != nil {
+		n.log.Error("Failed to open WebSocket endpoint", "err", err)
+		n.stopHTTP()
+	}
+}
+
+// waitForPeers blocks until peerCount reports at least min peers, returning true,
+// or until the timeout elapses or quit is closed, returning false.
+func waitForPeers(logger log.Logger, peerCount func() int, min int, timeout time.Duration, quit <-chan struct{}) bool {
+	ticker := time.NewTicker(peerWaitInterval)
+	defer ticker.Stop()
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	last := -1
+	for {
+		peers := peerCount()
+		if peers >= min {
+			logger.Info("Peer threshold reached, opening RPC", "peers", peers)
+			return true
+		}
+		if peers != last {
+			logger.Info("Waiting for peers before opening RPC", "peers", peers, "want", min)
+			last = peers
+		}
+		select {
+		case <-ticker.C:
+		case <-deadline.C:
+			return false
+		case <-quit:
+			return false
+		}
+	}
+}
+
 // startInProc initializes an in-process RPC endpoint.
 func (n *Node) startInProc(apis []rpc.API) error {
 	// Register all the APIs exposed by the services
@@ -321,6 +414,40 @@ func (n *Node) startIPC(apis []rpc.API) error {
 	return nil
 }
 
+// startAuth initializes and starts the JWT authenticated RPC endpoint, which
+// exposes all the APIs over both HTTP and websocket.
+func (n *Node) startAuth(apis []rpc.API) error {
+	if n.authEndpoint == "" {
+		return nil // Authenticated RPC disabled.
+	}
+	secret, err := n.config.jwtSecret()
+	if err != nil {
+		return err
+	}
+	listener, handler, err := rpc.StartAuthEndpoint(n.authEndpoint, apis, n.config.AuthVirtualHosts, secret)
+	if err != nil {
+		return err
+	}
+	n.authListener = listener
+	n.authHandler = handler
+	n.log.Info("Authenticated RPC endpoint opened", "url", fmt.Sprintf("http://%s", n.authEndpoint), "vhosts", strings.Join(n.config.AuthVirtualHosts, ","))
+	return nil
+}
+
+// stopAuth terminates the authenticated RPC endpoint.
+func (n *Node) stopAuth() {
+	if n.authListener != nil {
+		n.authListener.Close()
+		n.authListener = nil
+
+		n.log.Info("Authenticated RPC endpoint closed", "url", fmt.Sprintf("http://%s", n.authEndpoint))
+	}
+	if n.authHandler != nil {
+		n.authHandler.Shutdown(n.config.rpcShutdownTimeout())
+		n.authHandler = nil
+	}
+}
+
 // stopIPC terminates the IPC RPC endpoint.
 func (n *Node) stopIPC() {
 	if n.ipcListener != nil {
@@ -330,7 +457,7 @@ func (n *Node) stopIPC() {
 		n.log.Info("IPC endpoint closed", "endpoint", n.ipcEndpoint)
 	}
 	if n.ipcHandler != nil {
-		n.ipcHandler.Stop()
+		n.ipcHandler.Shutdown(n.config.rpcShutdownTimeout())
 		n.ipcHandler = nil
 	}
 }
@@ -341,11 +468,15 @@ func (n *Node) startHTTP(endpoint string, apis []rpc.API, modules []string, cors
 	if endpoint == "" {
 		return nil
 	}
-	listener, handler, err := rpc.StartHTTPEndpoint(endpoint, apis, modules, cors, vhosts)
+	tlsConfig, err := n.config.httpTLSConfig()
+	if err != nil {
+		return err
+	}
+	listener, handler, err := rpc.StartHTTPEndpoint(endpoint, apis, modules, cors, vhosts, n.config.rpcLimits(), n.config.httpMethodFilter(), tlsConfig)
 	if err != nil {
 		return err
 	}
-	n.log.Info("HTTP endpoint opened", "url", fmt.Sprintf("http://%s", endpoint), "cors", strings.Join(cors, ","), "vhosts", strings.Join(vhosts, ","))
+	n.log.Info("HTTP endpoint opened", "url", fmt.Sprintf("%s://%s", n.config.httpScheme(), endpoint), "cors", strings.Join(cors, ","), "vhosts", strings.Join(vhosts, ","), "clientauth", n.config.HTTPTLSClientCA != "")
 	// All listeners booted successfully
 	n.httpEndpoint = endpoint
 	n.httpListener = listener
@@ -360,10 +491,10 @@ func (n *Node) stopHTTP() {
 		n.httpListener.Close()
 		n.httpListener = nil
 
-		n.log.Info("HTTP endpoint closed", "url", fmt.Sprintf("http://%s", n.httpEndpoint))
+		n.log.Info("HTTP endpoint closed", "url", fmt.Sprintf("%s://%s", n.config.httpScheme(), n.httpEndpoint))
 	}
 	if n.httpHandler != nil {
-		n.httpHandler.Stop()
+		n.httpHandler.Shutdown(n.config.rpcShutdownTimeout())
 		n.httpHandler = nil
 	}
 }
@@ -374,11 +505,15 @@ func (n *Node) startWS(endpoint string, apis []rpc.API, modules []string, wsOrig
 	if endpoint == "" {
 		return nil
 	}
-	listener, handler, err := rpc.StartWSEndpoint(endpoint, apis, modules, wsOrigins, exposeAll)
+	tlsConfig, err := n.config.wsTLSConfig()
+	if err != nil {
+		return err
+	}
+	listener, handler, err := rpc.StartWSEndpoint(endpoint, apis, modules, wsOrigins, exposeAll, n.config.rpcLimits(), n.config.wsMethodFilter(), n.config.wsConfig(), tlsConfig)
 	if err != nil {
 		return err
 	}
-	n.log.Info("WebSocket endpoint opened", "url", fmt.Sprintf("ws://%s", listener.Addr()))
+	n.log.Info("WebSocket endpoint opened", "url", fmt.Sprintf("%s://%s", n.config.wsScheme(), listener.Addr()), "clientauth", n.config.WSTLSClientCA != "")
 	// All listeners booted successfully
 	n.wsEndpoint = endpoint
 	n.wsListener = listener
@@ -393,10 +528,10 @@ func (n *Node) stopWS() {
 		n.wsListener.Close()
 		n.wsListener = nil
 
-		n.log.Info("WebSocket endpoint closed", "url", fmt.Sprintf("ws://%s", n.wsEndpoint))
+		n.log.Info("WebSocket endpoint closed", "url", fmt.Sprintf("%s://%s", n.config.wsScheme(), n.wsEndpoint))
 	}
 	if n.wsHandler != nil {
-		n.wsHandler.Stop()
+		n.wsHandler.Shutdown(n.config.rpcShutdownTimeout())
 		n.wsHandler = nil
 	}
 }
@@ -412,11 +547,18 @@ func (n *Node) Stop() error {
 		return ErrNodeStopped
 	}
 
-	// Terminate the API, services and the p2p server.
-	n.stopWS()
-
-	n.stopHTTP()
-	n.stopIPC()
+	// Terminate the API, services and the p2p server. The RPC endpoints stop
+	// accepting connections and drain their in-flight requests in parallel, so
+	// the services are only stopped once no request uses them any more.
+	var pend sync.WaitGroup
+	for _, stop := range []func(){n.stopWS, n.stopHTTP, n.stopAuth, n.stopIPC} {
+		pend.Add(1)
+		go func(stop func()) {
+			defer pend.Done()
+			stop()
+		}(stop)
+	}
+	pend.Wait()
 
 	n.rpcAPIs = nil
 	failure := &StopError{
@@ -573,6 +715,12 @@ func (n *Node) WSEndpoint() string {
 	return n.wsEndpoint
 }
 
+// AuthEndpoint retrieves the current authenticated RPC endpoint used by the
+// protocol stack.
+func (n *Node) AuthEndpoint() string {
+	return n.authEndpoint
+}
+
 // EventMux retrieves the event multiplexer used by all the network services in
 // the current protocol stack.
 func (n *Node) EventMux() *event.TypeMux {
@@ -581,14 +729,36 @@ func (n *Node) EventMux() *event.TypeMux {
 
 // OpenDatabase opens an existing database with the given name (or creates one if no
 // previous can be found) from within the node's instance directory. If the node is
-// ephemeral, a memory database is returned.
+// ephemeral or keeps its databases in memory, a memory database is returned.
 func (n *Node) OpenDatabase(name string, cache, handles int) (vntdb.Database, error) {
-	if n.config.DataDir == "" {
+	if n.config.memoryDatabases() {
 		return vntdb.NewMemDatabase(), nil
 	}
 	return vntdb.NewLDBDatabase(n.config.resolvePath(name), cache, handles)
 }
 
+// OpenDatabaseWithFreezer opens an existing database with the given name (or
+// creates one if no previous can be found) from within the node's data directory,
+// also attaching an ancient store to it at the given path. A relative freezer
+// path is resolved into the instance directory, while an empty one disables the
+// ancient store. If the node is ephemeral or keeps its databases in memory, a
+// memory database is returned.
+func (n *Node) OpenDatabaseWithFreezer(name string, cache, handles int, freezer string) (vntdb.Database, error) {
+	db, err := n.OpenDatabase(name, cache, handles)
+	if err != nil || n.config.memoryDatabases() || freezer == "" {
+		return db, err
+	}
+	if !filepath.IsAbs(freezer) {
+		freezer = n.config.resolvePath(freezer)
+	}
+	frdb, err := rawdb.NewDatabaseWithFreezer(db, freezer)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return frdb, nil
+}
+
 // ResolvePath returns the absolute path of a resource in the instance directory.
 func (n *Node) ResolvePath(x string) string {
 	return n.config.resolvePath(x)
@@ -17,10 +17,22 @@
 package node
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"errors"
 	"io/ioutil"
+	"math/big"
+	"net"
 	"os"
+	"path/filepath"
 	"reflect"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -575,3 +587,187 @@ func TestAPIGather(t *testing.T) {
 		}
 	}
 }
+
+// httpOpened reports whether the node's HTTP RPC endpoint is listening.
+func httpOpened(n *Node) bool {
+	n.lock.RLock()
+	defer n.lock.RUnlock()
+
+	return n.httpListener != nil
+}
+
+// Tests that delayed RPC endpoints are opened once the peer threshold is reached.
+func TestDelayedRPCPeerThreshold(t *testing.T) {
+	defer func(interval time.Duration) { peerWaitInterval = interval }(peerWaitInterval)
+	peerWaitInterval = 10 * time.Millisecond
+
+	stack, err := New(&Config{HTTPHost: "127.0.0.1", RPCWaitPeers: 3, RPCWaitTimeout: time.Minute})
+	if err != nil {
+		t.Fatalf("failed to create protocol stack: %v", err)
+	}
+	if !stack.delayRPC() {
+		t.Fatalf("RPC not delayed with peer threshold set")
+	}
+	var peers int32
+	quit, done := make(chan struct{}), make(chan struct{})
+	defer close(quit)
+
+	go func() {
+		stack.startDelayedRPC(func() int { return int(atomic.LoadInt32(&peers)) }, quit)
+		close(done)
+	}()
+	for i := 0; i < 3; i++ {
+		time.Sleep(5 * peerWaitInterval)
+		if httpOpened(stack) {
+			t.Fatalf("HTTP endpoint opened with %d peers", i)
+		}
+		atomic.AddInt32(&peers, 1)
+	}
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("HTTP endpoint not opened at peer threshold")
+	}
+	if !httpOpened(stack) {
+		t.Fatalf("HTTP endpoint not opened at peer threshold")
+	}
+	stack.stopHTTP()
+}
+
+// Tests that delayed RPC endpoints are opened after the timeout even if the
+// peer threshold is never reached.
+func TestDelayedRPCTimeout(t *testing.T) {
+	defer func(interval time.Duration) { peerWaitInterval = interval }(peerWaitInterval)
+	peerWaitInterval = 10 * time.Millisecond
+
+	stack, err := New(&Config{HTTPHost: "127.0.0.1", RPCWaitPeers: 3, RPCWaitTimeout: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("failed to create protocol stack: %v", err)
+	}
+	quit := make(chan struct{})
+	defer close(quit)
+
+	start := time.Now()
+	stack.startDelayedRPC(func() int { return 1 }, quit)
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Fatalf("HTTP endpoint opened before timeout: %v", elapsed)
+	}
+	if !httpOpened(stack) {
+		t.Fatalf("HTTP endpoint not opened after timeout")
+	}
+	stack.stopHTTP()
+}
+
+// Tests that delayed RPC endpoints are not opened if the node stops while
+// waiting for peers.
+func TestDelayedRPCStopped(t *testing.T) {
+	stack, err := New(&Config{HTTPHost: "127.0.0.1", RPCWaitPeers: 3, RPCWaitTimeout: time.Minute})
+	if err != nil {
+		t.Fatalf("failed to create protocol stack: %v", err)
+	}
+	quit := make(chan struct{})
+	close(quit)
+
+	stack.startDelayedRPC(func() int { return 0 }, quit)
+	if httpOpened(stack) {
+		t.Fatalf("HTTP endpoint opened after stop")
+	}
+}
+
+// writeTestCertificate stores a self signed certificate for 127.0.0.1 and its
+// key into the given folder, usable both by servers and clients.
+func writeTestCertificate(t *testing.T, dir string) (string, string, tls.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test node"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to encode key: %v", err)
+	}
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	if err := ioutil.WriteFile(certFile, certPEM, 0600); err != nil {
+		t.Fatalf("failed to write certificate: %v", err)
+	}
+	if err := ioutil.WriteFile(keyFile, keyPEM, 0600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("failed to load certificate: %v", err)
+	}
+	return certFile, keyFile, cert
+}
+
+// Tests that the HTTP RPC endpoint is served over TLS if configured, requiring
+// client certificates signed by the configured authorities.
+func TestHTTPSEndpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary folder: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	certFile, keyFile, cert := writeTestCertificate(t, dir)
+
+	// Incomplete TLS settings are rejected
+	if _, err := loadTLSConfig(certFile, "", ""); err == nil {
+		t.Errorf("certificate without key accepted")
+	}
+	if _, err := loadTLSConfig("", "", certFile); err == nil {
+		t.Errorf("client authentication without certificate accepted")
+	}
+	stack, err := New(&Config{HTTPHost: "127.0.0.1", HTTPTLSCert: certFile, HTTPTLSKey: keyFile, HTTPTLSClientCA: certFile})
+	if err != nil {
+		t.Fatalf("failed to create protocol stack: %v", err)
+	}
+	if err := stack.startHTTP("127.0.0.1:0", nil, nil, nil, []string{"*"}); err != nil {
+		t.Fatalf("failed to start HTTPS endpoint: %v", err)
+	}
+	defer stack.stopHTTP()
+
+	addr := stack.httpListener.Addr().String()
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(leaf)
+	tests := []struct {
+		url    string
+		config *tls.Config
+		ok     bool
+	}{
+		{"http://" + addr, nil, false},                                                                // plain text
+		{"https://" + addr, &tls.Config{RootCAs: roots}, false},                                       // no client certificate
+		{"https://" + addr, &tls.Config{RootCAs: roots, Certificates: []tls.Certificate{cert}}, true}, // authenticated
+	}
+	for i, tt := range tests {
+		client, err := rpc.DialWithOptions(context.Background(), tt.url, rpc.DialOptions{TLSConfig: tt.config})
+		if err != nil {
+			t.Fatalf("test %d: failed to dial: %v", i, err)
+		}
+		_, err = client.SupportedModules()
+		client.Close()
+		if (err == nil) != tt.ok {
+			t.Errorf("test %d: request success mismatch: have %v, want %v", i, err, tt.ok)
+		}
+	}
+}
@@ -17,9 +17,11 @@
 package node
 
 import (
+	"path/filepath"
 	"reflect"
 
 	"github.com/vntchain/go-vnt/accounts"
+	"github.com/vntchain/go-vnt/core/rawdb"
 	"github.com/vntchain/go-vnt/vntdb"
 	"github.com/vntchain/go-vnt/event"
 	"github.com/vntchain/go-vnt/rpc"
@@ -38,9 +40,10 @@ type ServiceContext struct {
 
 // OpenDatabase opens an existing database with the given name (or creates one
 // if no previous can be found) from within the node's data directory. If the
-// node is an ephemeral one, a memory database is returned.
+// node is ephemeral or keeps its databases in memory, a memory database is
+// returned.
 func (ctx *ServiceContext) OpenDatabase(name string, cache int, handles int) (vntdb.Database, error) {
-	if ctx.config.DataDir == "" {
+	if ctx.config.memoryDatabases() {
 		return vntdb.NewMemDatabase(), nil
 	}
 	db, err := vntdb.NewLDBDatabase(ctx.config.resolvePath(name), cache, handles)
@@ -50,6 +53,28 @@ func (ctx *ServiceContext) OpenDatabase(name string, cache int, handles int) (vn
 	return db, nil
 }
 
+// OpenDatabaseWithFreezer opens an existing database with the given name (or
+// creates one if no previous can be found) from within the node's data directory,
+// also attaching an ancient store to it at the given path. A relative freezer
+// path is resolved into the instance directory, while an empty one disables the
+// ancient store. If the node is ephemeral or keeps its databases in memory, a
+// memory database is returned.
+func (ctx *ServiceContext) OpenDatabaseWithFreezer(name string, cache int, handles int, freezer string) (vntdb.Database, error) {
+	db, err := ctx.OpenDatabase(name, cache, handles)
+	if err != nil || ctx.config.memoryDatabases() || freezer == "" {
+		return db, err
+	}
+	if !filepath.IsAbs(freezer) {
+		freezer = ctx.config.resolvePath(freezer)
+	}
+	frdb, err := rawdb.NewDatabaseWithFreezer(db, freezer)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return frdb, nil
+}
+
 // ResolvePath resolves a user path into the data directory if that was relative
 // and if the user actually uses persistent storage. It will return an empty string
 // for emphemeral storage and the user's own input for absolute paths.
@@ -57,6 +82,12 @@ func (ctx *ServiceContext) ResolvePath(path string) string {
 	return ctx.config.resolvePath(path)
 }
 
+// ExtRPCEnabled returns whether the RPC APIs of the node are reachable over HTTP
+// or websocket.
+func (ctx *ServiceContext) ExtRPCEnabled() bool {
+	return ctx.config.ExtRPCEnabled()
+}
+
 // Service retrieves a currently running service registered of a specific type.
 func (ctx *ServiceContext) Service(service interface{}) error {
 	element := reflect.ValueOf(service).Elem()
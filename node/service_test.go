@@ -22,6 +22,8 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+
+	"github.com/vntchain/go-vnt/vntdb"
 )
 
 // Tests that databases are correctly created persistent or ephemeral based on
@@ -59,6 +61,20 @@ func TestContextDatabases(t *testing.T) {
 	if _, err := os.Stat(filepath.Join(dir, "ephemeral")); err == nil {
 		t.Fatalf("ephemeral database exists")
 	}
+	// Request a database of a node keeping them in memory and ensure it's not persisted
+	ctx = &ServiceContext{config: &Config{Name: "unit-test", DataDir: dir, MemoryDatabases: true}}
+	db, err = ctx.OpenDatabaseWithFreezer("memory", 0, 0, "ancient")
+	if err != nil {
+		t.Fatalf("failed to open memory database: %v", err)
+	}
+	db.Close()
+
+	if _, ok := db.(*vntdb.MemDatabase); !ok {
+		t.Fatalf("database type mismatch: have %T, want %T", db, new(vntdb.MemDatabase))
+	}
+	if _, err := os.Stat(filepath.Join(dir, "unit-test", "memory")); err == nil {
+		t.Fatalf("memory database exists")
+	}
 }
 
 // Tests that already constructed services can be retrieves by later ones.
@@ -30,6 +30,12 @@ var MainnetBootnodes = []string{
 	// "vnode://979b7fa28feeb35a4741660a16076f1943202cb72b6af70d327f053e248bab9ba81760f39d0701ef1d8f89cc1fbd2cacba0710a12cd5314d5e0c9021aa3637f9@5.1.83.226:30303", // DE
 }
 
+// TestnetBootnodes are the vnode URLs of the P2P bootstrap nodes running on
+// the Hubble test network.
+var TestnetBootnodes = []string{
+	// VNT Foundation Go Bootnodes
+}
+
 // DiscoveryV5Bootnodes are the vnode URLs of the P2P bootstrap nodes for the
 // experimental RLPx v5 topic-discovery network.
 var DiscoveryV5Bootnodes = []string{
@@ -0,0 +1,49 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package params
+
+import (
+	"errors"
+
+	"github.com/vntchain/go-vnt/common"
+)
+
+// TrustedCheckpoint represents a set of post-processed trie roots (CHT and
+// BloomTrie) associated with the appropriate section index and head hash. It is
+// used to start light syncing from this checkpoint and avoid downloading the
+// entire header chain while still being able to securely access old headers
+// and logs.
+type TrustedCheckpoint struct {
+	Name         string      `json:"-" toml:",omitempty"`
+	SectionIndex uint64      `json:"sectionIndex"`
+	SectionHead  common.Hash `json:"sectionHead"`
+	CHTRoot      common.Hash `json:"chtRoot"`
+	BloomRoot    common.Hash `json:"bloomRoot"`
+}
+
+// Validate checks that none of the hashes of the checkpoint are missing.
+func (c *TrustedCheckpoint) Validate() error {
+	switch {
+	case c.SectionHead == (common.Hash{}):
+		return errors.New("missing section head")
+	case c.CHTRoot == (common.Hash{}):
+		return errors.New("missing CHT root")
+	case c.BloomRoot == (common.Hash{}):
+		return errors.New("missing bloom trie root")
+	}
+	return nil
+}
@@ -26,6 +26,7 @@ import (
 // Genesis hashes to enforce below configs on.
 var (
 	MainnetGenesisHash = common.HexToHash("0x9c62e96e22812c31f4b8b74e7556410fdc79104a237dd163f755d1ca471deaf2")
+	TestnetGenesisHash = common.HexToHash("0x3e2fa8261a43bfe965e8d7033ea8a28e96526f34adc2a4daa9c7115b08bb34ff")
 )
 
 var (
@@ -38,7 +39,7 @@ var (
 		},
 	}
 
-	// TestnetChainConfig contains the chain parameters to run a node on the Ropsten test network.
+	// TestnetChainConfig contains the chain parameters to run a node on the Hubble test network.
 	TestnetChainConfig = &ChainConfig{
 		ChainID: big.NewInt(3),
 		Dpos: &DposConfig{
@@ -55,6 +56,8 @@ var (
 	AllCliqueProtocolChanges = &ChainConfig{
 		big.NewInt(1337),
 		big.NewInt(0),
+		big.NewInt(0),
+		nil,
 		&DposConfig{
 			Period:       2,
 			WitnessesNum: 4,
@@ -64,6 +67,8 @@ var (
 	TestChainConfig = &ChainConfig{
 		big.NewInt(1),
 		big.NewInt(0),
+		big.NewInt(0),
+		nil,
 		&DposConfig{
 			Period:       2,
 			WitnessesNum: 4,
@@ -79,7 +84,11 @@ var (
 type ChainConfig struct {
 	ChainID *big.Int `json:"chainId"` // chainId identifies the current chain and is used for replay protection
 
-	HubbleBlock *big.Int `json:"HubbleBlock,omitempty"` // Hubble switch block (nil = no fork, 0 = already hubble)
+	HubbleBlock        *big.Int `json:"HubbleBlock,omitempty"`        // Hubble switch block (nil = no fork, 0 = already hubble)
+	FeeDelegationBlock *big.Int `json:"feeDelegationBlock,omitempty"` // Sponsored transactions switch block (nil = no fork, 0 = already activated)
+
+	// GasSchedules reprice the gas costs at fork blocks, in ascending block order
+	GasSchedules []GasSchedule `json:"gasSchedules,omitempty"`
 
 	// Various consensus engines
 	Dpos *DposConfig `json:"dpos,omitempty"`
@@ -89,6 +98,52 @@ type DposConfig struct {
 	Period       uint64   `json:"period"`       // Number of seconds between blocks to enforce
 	WitnessesNum int      `json:"witnessesnum"` // Number of witnesses
 	WitnessesUrl []string `json:"witnessesUrl"`
+
+	// MaxMissedSlots is the number of consecutive production slots a witness
+	// may miss before it is left out of the next witness list (0 = no penalty)
+	MaxMissedSlots uint64 `json:"maxMissedSlots,omitempty"`
+
+	// EpochLength is the number of block slots between two updates of the
+	// witness list (0 = three rounds of witnesses)
+	EpochLength uint64 `json:"epochLength,omitempty"`
+
+	// VotingWindow is the number of seconds a voter has to wait before voting
+	// or setting a proxy again (0 = one day)
+	VotingWindow uint64 `json:"votingWindow,omitempty"`
+}
+
+// Default DPoS governance cadence, used if not configured otherwise.
+const (
+	DefaultEpochRounds  = 3     // Number of witness rounds in an epoch
+	DefaultVotingWindow = 86400 // Seconds between two votes of a voter
+)
+
+// Epoch returns the number of seconds between two updates of the witness list.
+func (c *DposConfig) Epoch() uint64 {
+	length := c.EpochLength
+	if length == 0 {
+		length = DefaultEpochRounds * uint64(c.WitnessesNum)
+	}
+	return length * c.Period
+}
+
+// Window returns the number of seconds a voter has to wait between votes.
+func (c *DposConfig) Window() uint64 {
+	if c.VotingWindow == 0 {
+		return DefaultVotingWindow
+	}
+	return c.VotingWindow
+}
+
+// Validate checks that the witness set can be rotated as configured.
+func (c *DposConfig) Validate() error {
+	if c.WitnessesNum <= 0 {
+		return fmt.Errorf("invalid dpos witness count %d", c.WitnessesNum)
+	}
+	if c.EpochLength != 0 && c.EpochLength < uint64(c.WitnessesNum) {
+		return fmt.Errorf("dpos epoch length %d shorter than a round of %d witnesses", c.EpochLength, c.WitnessesNum)
+	}
+	return nil
 }
 
 // String implements the stringer interface, returning the consensus engine details.
@@ -106,9 +161,15 @@ func (c *ChainConfig) String() string {
 		engine = "unknown"
 	}
 
-	return fmt.Sprintf("{ChainID: %v Hubble: %v Engine: %v}",
+	schedules := make([]*big.Int, len(c.GasSchedules))
+	for i, schedule := range c.GasSchedules {
+		schedules[i] = schedule.Block
+	}
+	return fmt.Sprintf("{ChainID: %v Hubble: %v FeeDelegation: %v GasSchedules: %v Engine: %v}",
 		c.ChainID,
 		c.HubbleBlock,
+		c.FeeDelegationBlock,
+		schedules,
 		engine,
 	)
 }
@@ -118,17 +179,66 @@ func (c *ChainConfig) IsHubble(num *big.Int) bool {
 	return isForked(c.HubbleBlock, num)
 }
 
-// GasTable returns the gas table corresponding to the current phase .
+// IsFeeDelegation returns whether num is either equal to the fee delegation
+// block or greater, from which on sponsored transactions are accepted.
+func (c *ChainConfig) IsFeeDelegation(num *big.Int) bool {
+	return isForked(c.FeeDelegationBlock, num)
+}
+
+// GasTable returns the gas table corresponding to the current phase, with the
+// gas schedules activated until num applied.
 //
 // The returned GasTable's fields shouldn't, under any circumstances, be changed.
 func (c *ChainConfig) GasTable(num *big.Int) GasTable {
+	gt := GasTableHubble
 	if num == nil {
-		return GasTableHubble
+		return gt
 	}
-	switch {
-	default:
-		return GasTableHubble
+	for _, schedule := range c.GasSchedules {
+		if !isForked(schedule.Block, num) {
+			break
+		}
+		gt = gt.override(schedule.Costs)
 	}
+	return gt
+}
+
+// CheckGasSchedules checks that the gas schedules are activated at ascending
+// block numbers.
+func (c *ChainConfig) CheckGasSchedules() error {
+	var last *big.Int
+	for i, schedule := range c.GasSchedules {
+		if schedule.Block == nil {
+			return fmt.Errorf("gas schedule %d has no activation block", i)
+		}
+		if last != nil && schedule.Block.Cmp(last) <= 0 {
+			return fmt.Errorf("gas schedule %d activates at block %v, not after the previous one at %v", i, schedule.Block, last)
+		}
+		last = schedule.Block
+	}
+	return nil
+}
+
+// CheckDposCompatible checks that the dpos governance cadence is not changed on a
+// chain with blocks beyond genesis. The cadence is not fork scheduled, it applies
+// from genesis on, so the imported blocks were produced and verified with it.
+func (c *ChainConfig) CheckDposCompatible(newcfg *ChainConfig, height uint64) error {
+	if height == 0 || c.Dpos == nil || newcfg.Dpos == nil {
+		return nil
+	}
+	for _, param := range []struct {
+		what         string
+		stored, next uint64
+	}{
+		{"epoch length", c.Dpos.EpochLength, newcfg.Dpos.EpochLength},
+		{"voting window", c.Dpos.VotingWindow, newcfg.Dpos.VotingWindow},
+		{"max missed slots", c.Dpos.MaxMissedSlots, newcfg.Dpos.MaxMissedSlots},
+	} {
+		if param.stored != param.next {
+			return fmt.Errorf("mismatching dpos %s in database (have %d, want %d), cannot be changed past genesis", param.what, param.stored, param.next)
+		}
+	}
+	return nil
 }
 
 // CheckCompatible checks whether scheduled fork transitions have been imported
@@ -153,6 +263,41 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *Confi
 	if isForkIncompatible(c.HubbleBlock, newcfg.HubbleBlock, head) {
 		return newCompatError("Hubble fork block", c.HubbleBlock, newcfg.HubbleBlock)
 	}
+	if isForkIncompatible(c.FeeDelegationBlock, newcfg.FeeDelegationBlock, head) {
+		return newCompatError("fee delegation fork block", c.FeeDelegationBlock, newcfg.FeeDelegationBlock)
+	}
+	for i := 0; i < len(c.GasSchedules) || i < len(newcfg.GasSchedules); i++ {
+		var stored, next *GasSchedule
+		if i < len(c.GasSchedules) {
+			stored = &c.GasSchedules[i]
+		}
+		if i < len(newcfg.GasSchedules) {
+			next = &newcfg.GasSchedules[i]
+		}
+		if err := checkGasScheduleCompatible(i, stored, next, head); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkGasScheduleCompatible returns an error if a gas schedule activated at or
+// before head was rescheduled or repriced.
+func checkGasScheduleCompatible(index int, stored, next *GasSchedule, head *big.Int) *ConfigCompatError {
+	var storedBlock, nextBlock *big.Int
+	if stored != nil {
+		storedBlock = stored.Block
+	}
+	if next != nil {
+		nextBlock = next.Block
+	}
+	what := fmt.Sprintf("gas schedule %d fork block", index)
+	if isForkIncompatible(storedBlock, nextBlock, head) {
+		return newCompatError(what, storedBlock, nextBlock)
+	}
+	if isForked(storedBlock, head) && !stored.Costs.equal(&next.Costs) {
+		return newCompatError(what, storedBlock, nextBlock)
+	}
 	return nil
 }
 
@@ -41,6 +41,49 @@ func TestCheckCompatible(t *testing.T) {
 				RewindTo:     0,
 			},
 		},
+		{
+			stored: &ChainConfig{GasSchedules: []GasSchedule{{Block: big.NewInt(10), Costs: GasCosts{SLoad: newUint64(800)}}}},
+			new:    &ChainConfig{GasSchedules: []GasSchedule{{Block: big.NewInt(10), Costs: GasCosts{SLoad: newUint64(800)}}}},
+			head:   20,
+		},
+		{
+			stored: &ChainConfig{GasSchedules: []GasSchedule{{Block: big.NewInt(10), Costs: GasCosts{SLoad: newUint64(800)}}}},
+			new:    &ChainConfig{GasSchedules: []GasSchedule{{Block: big.NewInt(10), Costs: GasCosts{SLoad: newUint64(900)}}}},
+			head:   5,
+		},
+		{
+			stored: &ChainConfig{GasSchedules: []GasSchedule{{Block: big.NewInt(10), Costs: GasCosts{SLoad: newUint64(800)}}}},
+			new:    &ChainConfig{GasSchedules: []GasSchedule{{Block: big.NewInt(10), Costs: GasCosts{SLoad: newUint64(900)}}}},
+			head:   20,
+			wantErr: &ConfigCompatError{
+				What:         "gas schedule 0 fork block",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    big.NewInt(10),
+				RewindTo:     9,
+			},
+		},
+		{
+			stored: &ChainConfig{GasSchedules: []GasSchedule{{Block: big.NewInt(10), Costs: GasCosts{}}}},
+			new:    &ChainConfig{GasSchedules: []GasSchedule{{Block: big.NewInt(10), Costs: GasCosts{SLoad: newUint64(0)}}}},
+			head:   20,
+			wantErr: &ConfigCompatError{
+				What:         "gas schedule 0 fork block",
+				StoredConfig: big.NewInt(10),
+				NewConfig:    big.NewInt(10),
+				RewindTo:     9,
+			},
+		},
+		{
+			stored: &ChainConfig{},
+			new:    &ChainConfig{GasSchedules: []GasSchedule{{Block: big.NewInt(10), Costs: GasCosts{SLoad: newUint64(800)}}}},
+			head:   20,
+			wantErr: &ConfigCompatError{
+				What:         "gas schedule 0 fork block",
+				StoredConfig: nil,
+				NewConfig:    big.NewInt(10),
+				RewindTo:     9,
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -50,3 +93,93 @@ func TestCheckCompatible(t *testing.T) {
 		}
 	}
 }
+
+func newUint64(v uint64) *uint64 { return &v }
+
+// Tests that the gas schedules reprice the costs from their activation blocks on,
+// down to zero if set so.
+func TestGasTableSchedules(t *testing.T) {
+	config := &ChainConfig{
+		GasSchedules: []GasSchedule{
+			{Block: big.NewInt(10), Costs: GasCosts{SLoad: newUint64(800), WasmDiv: newUint64(32)}},
+			{Block: big.NewInt(20), Costs: GasCosts{SLoad: newUint64(1000), SstoreSet: newUint64(40000)}},
+			{Block: big.NewInt(30), Costs: GasCosts{SLoad: newUint64(0)}},
+		},
+	}
+	if err := config.CheckGasSchedules(); err != nil {
+		t.Fatalf("failed to check gas schedules: %v", err)
+	}
+	tests := []struct {
+		block                     int64
+		sload, wasmDiv, sstoreSet uint64
+	}{
+		{0, GasTableHubble.SLoad, GasTableHubble.WasmDiv, GasTableHubble.SstoreSet},
+		{9, GasTableHubble.SLoad, GasTableHubble.WasmDiv, GasTableHubble.SstoreSet},
+		{10, 800, 32, GasTableHubble.SstoreSet},
+		{20, 1000, 32, 40000},
+		{30, 0, 32, 40000}, // Repriced to zero
+	}
+	for i, tt := range tests {
+		gt := config.GasTable(big.NewInt(tt.block))
+		if gt.SLoad != tt.sload || gt.WasmDiv != tt.wasmDiv || gt.SstoreSet != tt.sstoreSet {
+			t.Errorf("test %d: costs mismatch: have %d/%d/%d, want %d/%d/%d", i, gt.SLoad, gt.WasmDiv, gt.SstoreSet, tt.sload, tt.wasmDiv, tt.sstoreSet)
+		}
+		if gt.Calls != GasTableHubble.Calls {
+			t.Errorf("test %d: unchanged cost mismatch: have %d, want %d", i, gt.Calls, GasTableHubble.Calls)
+		}
+	}
+	config.GasSchedules[1].Block = big.NewInt(10)
+	if err := config.CheckGasSchedules(); err == nil {
+		t.Errorf("unordered gas schedules accepted")
+	}
+}
+
+// Tests that the DPoS governance cadence falls back to the defaults and that
+// epochs shorter than a round of witnesses are rejected.
+func TestDposCadence(t *testing.T) {
+	tests := []struct {
+		config        DposConfig
+		epoch, window uint64
+		valid         bool
+	}{
+		{DposConfig{Period: 2, WitnessesNum: 4}, 24, DefaultVotingWindow, true},
+		{DposConfig{Period: 2, WitnessesNum: 4, EpochLength: 10, VotingWindow: 3600}, 20, 3600, true},
+		{DposConfig{Period: 2, WitnessesNum: 4, EpochLength: 3}, 6, DefaultVotingWindow, false},
+		{DposConfig{Period: 2}, 0, DefaultVotingWindow, false},
+	}
+	for i, tt := range tests {
+		if epoch := tt.config.Epoch(); epoch != tt.epoch {
+			t.Errorf("test %d: epoch mismatch: have %d, want %d", i, epoch, tt.epoch)
+		}
+		if window := tt.config.Window(); window != tt.window {
+			t.Errorf("test %d: voting window mismatch: have %d, want %d", i, window, tt.window)
+		}
+		if err := tt.config.Validate(); (err == nil) != tt.valid {
+			t.Errorf("test %d: validity mismatch: have %v, want %v", i, err, tt.valid)
+		}
+	}
+}
+
+// Tests that the dpos governance cadence may only change on a chain without blocks
+// beyond genesis.
+func TestCheckDposCompatible(t *testing.T) {
+	stored := &ChainConfig{Dpos: &DposConfig{Period: 2, WitnessesNum: 4, EpochLength: 12, VotingWindow: 3600, MaxMissedSlots: 5}}
+	tests := []struct {
+		dpos    DposConfig
+		head    uint64
+		wantErr bool
+	}{
+		{*stored.Dpos, 10, false},
+		{DposConfig{Period: 2, WitnessesNum: 4, EpochLength: 16, VotingWindow: 3600, MaxMissedSlots: 5}, 0, false},
+		{DposConfig{Period: 2, WitnessesNum: 4, EpochLength: 16, VotingWindow: 3600, MaxMissedSlots: 5}, 10, true},
+		{DposConfig{Period: 2, WitnessesNum: 4, EpochLength: 12, VotingWindow: 7200, MaxMissedSlots: 5}, 10, true},
+		{DposConfig{Period: 2, WitnessesNum: 4, EpochLength: 12, VotingWindow: 3600}, 10, true},
+	}
+	for i, tt := range tests {
+		dpos := tt.dpos
+		err := stored.CheckDposCompatible(&ChainConfig{Dpos: &dpos}, tt.head)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("test %d: error mismatch: have %v, want error %v", i, err, tt.wantErr)
+		}
+	}
+}
@@ -16,23 +16,39 @@
 
 package params
 
+import "math/big"
+
 // GasTable organizes gas prices for different ethereum phases.
 type GasTable struct {
-	ExtcodeSize uint64
-	ExtcodeCopy uint64
-	Balance     uint64
-	SLoad       uint64
-	Calls       uint64
-	Suicide     uint64
+	ExtcodeSize uint64 `json:"extcodeSize,omitempty"`
+	ExtcodeCopy uint64 `json:"extcodeCopy,omitempty"`
+	Balance     uint64 `json:"balance,omitempty"`
+	SLoad       uint64 `json:"sload,omitempty"`
+	Calls       uint64 `json:"calls,omitempty"`
+	Suicide     uint64 `json:"suicide,omitempty"`
 
-	ExpByte uint64
+	ExpByte uint64 `json:"expByte,omitempty"`
 
 	// CreateBySuicide occurs when the
 	// refunded account is one that does
 	// not exist. This logic is similar
 	// to call. May be left nil. Nil means
 	// not charged.
-	CreateBySuicide uint64
+	CreateBySuicide uint64 `json:"createBySuicide,omitempty"`
+
+	// Storage write costs and refunds.
+	SstoreSet    uint64 `json:"sstoreSet,omitempty"`
+	SstoreReset  uint64 `json:"sstoreReset,omitempty"`
+	SstoreClear  uint64 `json:"sstoreClear,omitempty"`
+	SstoreRefund uint64 `json:"sstoreRefund,omitempty"`
+
+	// WASM instruction costs, metered into contracts when they are compiled.
+	// Deployed contracts are stored compiled with the hubble costs and compiled
+	// again for calls made under different costs.
+	WasmRegular uint64 `json:"wasmRegular,omitempty"`
+	WasmDiv     uint64 `json:"wasmDiv,omitempty"`
+	WasmMul     uint64 `json:"wasmMul,omitempty"`
+	WasmMem     uint64 `json:"wasmMem,omitempty"`
 }
 
 // Variables containing gas prices for different phases.
@@ -49,5 +65,84 @@ var (
 		ExpByte:     50,
 
 		CreateBySuicide: 25000,
+
+		SstoreSet:    SstoreSetGas,
+		SstoreReset:  SstoreResetGas,
+		SstoreClear:  SstoreClearGas,
+		SstoreRefund: SstoreRefundGas,
+
+		WasmRegular: 1,
+		WasmDiv:     16,
+		WasmMul:     4,
+		WasmMem:     2,
 	}
 )
+
+// GasSchedule is a repricing of gas costs activated at a fork block.
+type GasSchedule struct {
+	Block *big.Int `json:"block"` // Block number the schedule activates at
+	Costs GasCosts `json:"costs"` // Costs changed from the block on
+}
+
+// GasCosts are the costs of a GasTable changed by a gas schedule. Unset costs
+// keep the price in effect before the schedule, set ones may be zero.
+type GasCosts struct {
+	ExtcodeSize     *uint64 `json:"extcodeSize,omitempty"`
+	ExtcodeCopy     *uint64 `json:"extcodeCopy,omitempty"`
+	Balance         *uint64 `json:"balance,omitempty"`
+	SLoad           *uint64 `json:"sload,omitempty"`
+	Calls           *uint64 `json:"calls,omitempty"`
+	Suicide         *uint64 `json:"suicide,omitempty"`
+	ExpByte         *uint64 `json:"expByte,omitempty"`
+	CreateBySuicide *uint64 `json:"createBySuicide,omitempty"`
+
+	SstoreSet    *uint64 `json:"sstoreSet,omitempty"`
+	SstoreReset  *uint64 `json:"sstoreReset,omitempty"`
+	SstoreClear  *uint64 `json:"sstoreClear,omitempty"`
+	SstoreRefund *uint64 `json:"sstoreRefund,omitempty"`
+
+	WasmRegular *uint64 `json:"wasmRegular,omitempty"`
+	WasmDiv     *uint64 `json:"wasmDiv,omitempty"`
+	WasmMul     *uint64 `json:"wasmMul,omitempty"`
+	WasmMem     *uint64 `json:"wasmMem,omitempty"`
+}
+
+// costs returns the costs of the table, in the order of costs of GasCosts.
+func (gt *GasTable) costs() []*uint64 {
+	return []*uint64{
+		&gt.ExtcodeSize, &gt.ExtcodeCopy, &gt.Balance, &gt.SLoad, &gt.Calls, &gt.Suicide, &gt.ExpByte, &gt.CreateBySuicide,
+		&gt.SstoreSet, &gt.SstoreReset, &gt.SstoreClear, &gt.SstoreRefund,
+		&gt.WasmRegular, &gt.WasmDiv, &gt.WasmMul, &gt.WasmMem,
+	}
+}
+
+// costs returns the changed costs, nil for the unset ones.
+func (c *GasCosts) costs() []*uint64 {
+	return []*uint64{
+		c.ExtcodeSize, c.ExtcodeCopy, c.Balance, c.SLoad, c.Calls, c.Suicide, c.ExpByte, c.CreateBySuicide,
+		c.SstoreSet, c.SstoreReset, c.SstoreClear, c.SstoreRefund,
+		c.WasmRegular, c.WasmDiv, c.WasmMul, c.WasmMem,
+	}
+}
+
+// equal returns whether both change the same costs to the same prices.
+func (c *GasCosts) equal(o *GasCosts) bool {
+	have, want := c.costs(), o.costs()
+	for i := range have {
+		if (have[i] == nil) != (want[i] == nil) || (have[i] != nil && *have[i] != *want[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// override returns a copy of the gas table with the set costs of o applied.
+func (gt GasTable) override(o GasCosts) GasTable {
+	costs := gt.costs()
+	for i, cost := range o.costs() {
+		if cost != nil {
+			*costs[i] = *cost
+		}
+	}
+	return gt
+}
@@ -0,0 +1,113 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+// jwtIssuedAtLeeway is the maximum allowed difference between the issuance time
+// of a token and the local clock. Tokens are expected to be freshly minted for
+// each connection, which keeps captured ones from being replayed later on.
+const jwtIssuedAtLeeway = 60 * time.Second
+
+// jwtHandler is a handler which authenticates incoming requests with an HMAC
+// signed JSON web token carried in the Authorization header.
+type jwtHandler struct {
+	secret []byte
+	next   http.Handler
+}
+
+// newJWTHandler creates a http.Handler with jwt authentication support.
+func newJWTHandler(secret []byte, next http.Handler) http.Handler {
+	return &jwtHandler{
+		secret: secret,
+		next:   next,
+	}
+}
+
+// ServeHTTP implements http.Handler, passing only authenticated requests on.
+func (h *jwtHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Bearer ") {
+		http.Error(w, "missing token", http.StatusUnauthorized)
+		return
+	}
+	if err := h.validate(strings.TrimPrefix(auth, "Bearer ")); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	h.next.ServeHTTP(w, r)
+}
+
+// validate checks the signature and the issuance time of a token.
+func (h *jwtHandler) validate(raw string) error {
+	var (
+		claims = jwt.MapClaims{}
+		parser = &jwt.Parser{ValidMethods: []string{jwt.SigningMethodHS256.Alg()}, SkipClaimsValidation: true}
+	)
+	token, err := parser.ParseWithClaims(raw, claims, func(*jwt.Token) (interface{}, error) {
+		return h.secret, nil
+	})
+	if err != nil {
+		return err
+	}
+	if !token.Valid {
+		return errors.New("invalid token")
+	}
+	now := time.Now()
+	if !claims.VerifyExpiresAt(now.Unix(), false) {
+		return errors.New("token is expired")
+	}
+	iat, ok := claims["iat"].(float64)
+	if !ok || math.IsNaN(iat) || math.IsInf(iat, 0) {
+		return errors.New("missing issued-at")
+	}
+	// Compare in seconds, far off issuance times overflow a time.Duration
+	if diff := math.Abs(float64(now.Unix()) - iat); diff > jwtIssuedAtLeeway.Seconds() {
+		return fmt.Errorf("stale token: issued %.0fs away from local time", diff)
+	}
+	return nil
+}
+
+// isWebsocket checks the header of a http request for a websocket upgrade.
+func isWebsocket(r *http.Request) bool {
+	return strings.ToLower(r.Header.Get("Upgrade")) == "websocket" &&
+		strings.Contains(strings.ToLower(r.Header.Get("Connection")), "upgrade")
+}
+
+// NewAuthHandler creates a handler serving both HTTP and websocket JSON-RPC
+// requests, accepting only those authenticated with a token signed by the
+// given secret and sent to one of the allowed virtual hosts.
+func NewAuthHandler(vhosts []string, secret []byte, srv *Server) http.Handler {
+	ws := srv.WebsocketHandler([]string{"*"})
+	mux := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isWebsocket(r) {
+			ws.ServeHTTP(w, r)
+			return
+		}
+		srv.ServeHTTP(w, r)
+	})
+	return newVHostHandler(vhosts, newJWTHandler(secret, mux))
+}
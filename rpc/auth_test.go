@@ -0,0 +1,76 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+// Tests that only requests carrying a fresh token signed with the configured
+// secret get through the authenticated handler.
+func TestJWTAuthentication(t *testing.T) {
+	var (
+		secret = []byte("0123456789abcdef0123456789abcdef")
+		other  = []byte("fedcba9876543210fedcba9876543210")
+	)
+	sign := func(key []byte, method jwt.SigningMethod, claims jwt.MapClaims) string {
+		token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+		return "Bearer " + token
+	}
+	now := time.Now()
+	tests := []struct {
+		name   string
+		header string
+		code   int
+	}{
+		{"valid", sign(secret, jwt.SigningMethodHS256, jwt.MapClaims{"iat": now.Unix()}), http.StatusOK},
+		{"missing", "", http.StatusUnauthorized},
+		{"no bearer", strings.TrimPrefix(sign(secret, jwt.SigningMethodHS256, jwt.MapClaims{"iat": now.Unix()}), "Bearer "), http.StatusUnauthorized},
+		{"bad signature", sign(other, jwt.SigningMethodHS256, jwt.MapClaims{"iat": now.Unix()}), http.StatusUnauthorized},
+		{"wrong method", sign(secret, jwt.SigningMethodHS512, jwt.MapClaims{"iat": now.Unix()}), http.StatusUnauthorized},
+		{"no iat", sign(secret, jwt.SigningMethodHS256, jwt.MapClaims{}), http.StatusUnauthorized},
+		{"stale", sign(secret, jwt.SigningMethodHS256, jwt.MapClaims{"iat": now.Add(-2 * jwtIssuedAtLeeway).Unix()}), http.StatusUnauthorized},
+		{"future", sign(secret, jwt.SigningMethodHS256, jwt.MapClaims{"iat": now.Add(2 * jwtIssuedAtLeeway).Unix()}), http.StatusUnauthorized},
+		{"far future", sign(secret, jwt.SigningMethodHS256, jwt.MapClaims{"iat": 1e20}), http.StatusUnauthorized},
+		{"far future overflow", sign(secret, jwt.SigningMethodHS256, jwt.MapClaims{"iat": float64(now.Unix()) + 1e10}), http.StatusUnauthorized},
+		{"far past", sign(secret, jwt.SigningMethodHS256, jwt.MapClaims{"iat": -1e20}), http.StatusUnauthorized},
+		{"expired", sign(secret, jwt.SigningMethodHS256, jwt.MapClaims{"iat": now.Unix(), "exp": now.Add(-time.Second).Unix()}), http.StatusUnauthorized},
+	}
+	handler := newJWTHandler(secret, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "http://localhost", nil)
+		if tt.header != "" {
+			req.Header.Set("Authorization", tt.header)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != tt.code {
+			t.Errorf("%s: response code mismatch: have %d, want %d", tt.name, w.Code, tt.code)
+		}
+	}
+}
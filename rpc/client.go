@@ -20,10 +20,12 @@ import (
 	"bytes"
 	"container/list"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net"
+	"net/http"
 	"net/url"
 	"os"
 	"reflect"
@@ -163,15 +165,32 @@ func Dial(rawurl string) (*Client, error) {
 // The context is used to cancel or time out the initial connection establishment. It does
 // not affect subsequent interactions with the client.
 func DialContext(ctx context.Context, rawurl string) (*Client, error) {
+	return DialWithOptions(ctx, rawurl, DialOptions{})
+}
+
+// DialOptions are transport settings applied when dialing HTTP and websocket
+// endpoints. They are ignored for IPC and stdio connections.
+type DialOptions struct {
+	Header    http.Header // Extra headers sent with every HTTP request and the websocket handshake
+	TLSConfig *tls.Config // TLS settings for https and wss endpoints (nil = system defaults)
+}
+
+// DialWithOptions creates a new RPC client, just like DialContext, using the given
+// transport options for HTTP and websocket connections.
+func DialWithOptions(ctx context.Context, rawurl string, opts DialOptions) (*Client, error) {
 	u, err := url.Parse(rawurl)
 	if err != nil {
 		return nil, err
 	}
 	switch u.Scheme {
 	case "http", "https":
-		return DialHTTP(rawurl)
+		client := new(http.Client)
+		if opts.TLSConfig != nil {
+			client.Transport = &http.Transport{Proxy: http.ProxyFromEnvironment, TLSClientConfig: opts.TLSConfig}
+		}
+		return dialHTTP(rawurl, client, opts.Header)
 	case "ws", "wss":
-		return DialWebsocket(ctx, rawurl, "")
+		return dialWebsocket(ctx, rawurl, "", opts)
 	case "stdio":
 		return DialStdIO(ctx)
 	case "":
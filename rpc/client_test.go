@@ -18,6 +18,8 @@ package rpc
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"math/rand"
 	"net"
@@ -485,6 +487,56 @@ func TestClientReconnect(t *testing.T) {
 	}
 }
 
+// Tests that extra headers and TLS settings are applied when dialing HTTP and
+// websocket endpoints.
+func TestDialWithOptions(t *testing.T) {
+	server := newTestServer("service", new(Service))
+	defer server.Stop()
+
+	for _, transport := range []string{"https", "wss"} {
+		var (
+			seen    = make(chan string, 16)
+			handler = http.Handler(server)
+		)
+		if transport == "wss" {
+			handler = server.WebsocketHandler([]string{"*"})
+		}
+		hs := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			seen <- r.Header.Get("Authorization")
+			handler.ServeHTTP(w, r)
+		}))
+		roots := x509.NewCertPool()
+		roots.AddCert(hs.Certificate())
+
+		endpoint := transport + "://" + hs.Listener.Addr().String()
+		opts := DialOptions{
+			Header:    http.Header{"Authorization": []string{"Bearer secret"}},
+			TLSConfig: &tls.Config{RootCAs: roots},
+		}
+		client, err := DialWithOptions(context.Background(), endpoint, opts)
+		if err != nil {
+			t.Fatalf("%s: failed to dial: %v", transport, err)
+		}
+		var resp Result
+		if err := client.Call(&resp, "service_echo", "hello", 10, &Args{"world"}); err != nil {
+			t.Fatalf("%s: call failed: %v", transport, err)
+		}
+		if have := <-seen; have != "Bearer secret" {
+			t.Errorf("%s: header mismatch: have %q, want %q", transport, have, "Bearer secret")
+		}
+		client.Close()
+
+		// Without the server certificate being trusted the dial must fail
+		if client, err := DialWithOptions(context.Background(), endpoint, DialOptions{}); err == nil {
+			if err := client.Call(&resp, "service_echo", "hello", 10, &Args{"world"}); err == nil {
+				t.Errorf("%s: untrusted certificate accepted", transport)
+			}
+			client.Close()
+		}
+		hs.Close()
+	}
+}
+
 func newTestServer(serviceName string, service interface{}) *Server {
 	server := NewServer()
 	if err := server.RegisterName(serviceName, service); err != nil {
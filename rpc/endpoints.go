@@ -17,13 +17,19 @@
 package rpc
 
 import (
+	"crypto/tls"
 	"net"
+	"net/http"
 
 	"github.com/vntchain/go-vnt/log"
 )
 
-// StartHTTPEndpoint starts the HTTP RPC endpoint, configured with cors/vhosts/modules
-func StartHTTPEndpoint(endpoint string, apis []API, modules []string, cors []string, vhosts []string) (net.Listener, *Server, error) {
+// StartHTTPEndpoint starts the HTTP RPC endpoint, configured with cors/vhosts/modules,
+// the request limits and the method filter, serving HTTPS if given a TLS configuration
+func StartHTTPEndpoint(endpoint string, apis []API, modules []string, cors []string, vhosts []string, limits Limits, methods MethodFilter, tlsConfig *tls.Config) (net.Listener, *Server, error) {
+	if err := methods.Validate(); err != nil {
+		return nil, nil, err
+	}
 	// Generate the whitelist based on the allowed modules
 	whitelist := make(map[string]bool)
 	for _, module := range modules {
@@ -31,6 +37,8 @@ func StartHTTPEndpoint(endpoint string, apis []API, modules []string, cors []str
 	}
 	// Register all the APIs exposed by the services
 	handler := NewServer()
+	handler.SetLimits(limits)
+	handler.SetMethodFilter(methods)
 	for _, api := range apis {
 		if whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
 			if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
@@ -44,15 +52,20 @@ func StartHTTPEndpoint(endpoint string, apis []API, modules []string, cors []str
 		listener net.Listener
 		err      error
 	)
-	if listener, err = net.Listen("tcp", endpoint); err != nil {
+	if listener, err = listen(endpoint, tlsConfig); err != nil {
 		return nil, nil, err
 	}
 	go NewHTTPServer(cors, vhosts, handler).Serve(listener)
 	return listener, handler, err
 }
 
-// StartWSEndpoint starts a websocket endpoint
-func StartWSEndpoint(endpoint string, apis []API, modules []string, wsOrigins []string, exposeAll bool) (net.Listener, *Server, error) {
+// StartWSEndpoint starts a websocket endpoint, configured with the request limits, the
+// method filter and the per-connection websocket settings, serving WSS if given a TLS
+// configuration
+func StartWSEndpoint(endpoint string, apis []API, modules []string, wsOrigins []string, exposeAll bool, limits Limits, methods MethodFilter, wsConfig WebsocketConfig, tlsConfig *tls.Config) (net.Listener, *Server, error) {
+	if err := methods.Validate(); err != nil {
+		return nil, nil, err
+	}
 
 	// Generate the whitelist based on the allowed modules
 	whitelist := make(map[string]bool)
@@ -61,6 +74,9 @@ func StartWSEndpoint(endpoint string, apis []API, modules []string, wsOrigins []
 	}
 	// Register all the APIs exposed by the services
 	handler := NewServer()
+	handler.SetLimits(limits)
+	handler.SetMethodFilter(methods)
+	handler.SetWebsocketConfig(wsConfig)
 	for _, api := range apis {
 		if exposeAll || whitelist[api.Namespace] || (len(whitelist) == 0 && api.Public) {
 			if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
@@ -74,7 +90,7 @@ func StartWSEndpoint(endpoint string, apis []API, modules []string, wsOrigins []
 		listener net.Listener
 		err      error
 	)
-	if listener, err = net.Listen("tcp", endpoint); err != nil {
+	if listener, err = listen(endpoint, tlsConfig); err != nil {
 		return nil, nil, err
 	}
 	go NewWSServer(wsOrigins, handler).Serve(listener)
@@ -82,6 +98,40 @@ func StartWSEndpoint(endpoint string, apis []API, modules []string, wsOrigins []
 
 }
 
+// listen opens a TCP listener on the endpoint, terminating TLS on the accepted
+// connections if a configuration is given.
+func listen(endpoint string, tlsConfig *tls.Config) (net.Listener, error) {
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+	return listener, nil
+}
+
+// StartAuthEndpoint starts a combined HTTP and websocket endpoint exposing all
+// the APIs, including the private ones, to requests authenticated with a JWT
+// signed by the given secret.
+func StartAuthEndpoint(endpoint string, apis []API, vhosts []string, secret []byte) (net.Listener, *Server, error) {
+	// Register all the APIs exposed by the services
+	handler := NewServer()
+	for _, api := range apis {
+		if err := handler.RegisterName(api.Namespace, api.Service); err != nil {
+			return nil, nil, err
+		}
+		log.Debug("Authenticated RPC registered", "namespace", api.Namespace)
+	}
+	// All APIs registered, start the listener
+	listener, err := net.Listen("tcp", endpoint)
+	if err != nil {
+		return nil, nil, err
+	}
+	go (&http.Server{Handler: NewAuthHandler(vhosts, secret, handler)}).Serve(listener)
+	return listener, handler, nil
+}
+
 // StartIPCEndpoint starts an IPC endpoint.
 func StartIPCEndpoint(ipcEndpoint string, apis []API) (net.Listener, *Server, error) {
 	// Register all the APIs exposed by the services.
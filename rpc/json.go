@@ -82,7 +82,7 @@ type jsonCodec struct {
 	decode func(v interface{}) error // decoder to allow multiple transports
 	encMu  sync.Mutex                // guards the encoder
 	encode func(v interface{}) error // encoder to allow multiple transports
-	rw     io.ReadWriteCloser        // connection
+	rw     io.Closer                 // connection
 }
 
 func (err *jsonError) Error() string {
@@ -96,9 +96,13 @@ func (err *jsonError) ErrorCode() int {
 	return err.Code
 }
 
+func (err *jsonError) ErrorData() interface{} {
+	return err.Data
+}
+
 // NewCodec creates a new RPC server codec with support for JSON-RPC 2.0 based
 // on explicitly given encoding and decoding methods.
-func NewCodec(rwc io.ReadWriteCloser, encode, decode func(v interface{}) error) ServerCodec {
+func NewCodec(rwc io.Closer, encode, decode func(v interface{}) error) ServerCodec {
 	return &jsonCodec{
 		closed: make(chan interface{}),
 		encode: encode,
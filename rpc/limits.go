@@ -0,0 +1,117 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// Limits bounds the resources a single client can take up on an HTTP or
+// websocket RPC server.
+type Limits struct {
+	BodySize    int64 // Maximum size of a request body or websocket message in bytes (0 = default)
+	BatchSize   int   // Maximum number of requests in a batch (0 = unlimited)
+	RequestRate int   // Maximum number of requests per second from a single IP (0 = unlimited)
+}
+
+// rateLimiterIdle is the time after which the bucket of an idle IP is dropped.
+const rateLimiterIdle = time.Minute
+
+// ipRateLimiter is a token bucket rate limiter keyed by remote IP. Each bucket
+// refills at rate tokens per second and holds at most rate tokens.
+type ipRateLimiter struct {
+	rate    float64
+	buckets map[string]*rateBucket
+	swept   time.Time
+	lock    sync.Mutex
+}
+
+type rateBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newIPRateLimiter(rate int) *ipRateLimiter {
+	return &ipRateLimiter{
+		rate:    float64(rate),
+		buckets: make(map[string]*rateBucket),
+		swept:   time.Now(),
+	}
+}
+
+// allow takes a token from the bucket of the IP, returning false without
+// taking any if the bucket is empty.
+func (l *ipRateLimiter) allow(ip string) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	b := l.bucket(ip, time.Now())
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// wait takes a token from the bucket of the IP, going into debt if the bucket
+// is empty, and returns the time to wait until the debt is paid off.
+func (l *ipRateLimiter) wait(ip string) time.Duration {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	b := l.bucket(ip, time.Now())
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / l.rate * float64(time.Second))
+}
+
+// bucket returns the refilled bucket of the IP, creating a full one if absent.
+// The buckets idle for long are dropped now and then. The lock must be held.
+func (l *ipRateLimiter) bucket(ip string, now time.Time) *rateBucket {
+	if now.Sub(l.swept) > rateLimiterIdle {
+		for key, b := range l.buckets {
+			if now.Sub(b.last) > rateLimiterIdle {
+				delete(l.buckets, key)
+			}
+		}
+		l.swept = now
+	}
+	b, ok := l.buckets[ip]
+	if !ok {
+		b = &rateBucket{tokens: l.rate, last: now}
+		l.buckets[ip] = b
+		return b
+	}
+	b.tokens += now.Sub(b.last).Seconds() * l.rate
+	if b.tokens > l.rate {
+		b.tokens = l.rate
+	}
+	b.last = now
+	return b
+}
+
+// remoteIP strips the port from a remote address.
+func remoteIP(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
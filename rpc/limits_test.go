@@ -0,0 +1,143 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func newLimitedServer(t *testing.T, limits Limits) *httptest.Server {
+	server := NewServer()
+	if err := server.RegisterName("test", new(Service)); err != nil {
+		t.Fatal(err)
+	}
+	server.SetLimits(limits)
+	return httptest.NewServer(server)
+}
+
+func postJSON(t *testing.T, url, body string) (int, string) {
+	resp, err := http.Post(url, contentType, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	out, _ := ioutil.ReadAll(resp.Body)
+	return resp.StatusCode, string(out)
+}
+
+func TestHTTPBatchLimit(t *testing.T) {
+	srv := newLimitedServer(t, Limits{BatchSize: 2})
+	defer srv.Close()
+
+	call := `{"jsonrpc":"2.0","id":1,"method":"test_rets"}`
+	if _, out := postJSON(t, srv.URL, "["+call+","+call+"]"); strings.Contains(out, "batch too large") {
+		t.Fatalf("batch within limit rejected: %s", out)
+	}
+	if _, out := postJSON(t, srv.URL, "["+call+","+call+","+call+"]"); !strings.Contains(out, "batch too large") {
+		t.Fatalf("oversized batch accepted: %s", out)
+	}
+}
+
+func TestHTTPBodyLimit(t *testing.T) {
+	srv := newLimitedServer(t, Limits{BodySize: 64})
+	defer srv.Close()
+
+	if code, _ := postJSON(t, srv.URL, `{"jsonrpc":"2.0","id":1,"method":"test_rets"}`); code != http.StatusOK {
+		t.Fatalf("status mismatch: have %d, want %d", code, http.StatusOK)
+	}
+	body := `{"jsonrpc":"2.0","id":1,"method":"test_echo","params":["` + strings.Repeat("x", 64) + `",1,{}]}`
+	if code, _ := postJSON(t, srv.URL, body); code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status mismatch: have %d, want %d", code, http.StatusRequestEntityTooLarge)
+	}
+}
+
+// Tests that websocket messages above the read limit close the connection and
+// that compression is negotiated when enabled.
+func TestWebsocketConfig(t *testing.T) {
+	server := NewServer()
+	if err := server.RegisterName("test", new(Service)); err != nil {
+		t.Fatal(err)
+	}
+	server.SetWebsocketConfig(WebsocketConfig{ReadLimit: 128, Compression: true})
+	srv := httptest.NewServer(server.WebsocketHandler([]string{"*"}))
+	defer srv.Close()
+
+	dialer := websocket.Dialer{EnableCompression: true}
+	conn, resp, err := dialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+	if ext := resp.Header.Get("Sec-Websocket-Extensions"); !strings.Contains(ext, "permessage-deflate") {
+		t.Errorf("compression not negotiated: have %q", ext)
+	}
+	call := `{"jsonrpc":"2.0","id":1,"method":"test_echo","params":["` + strings.Repeat("x", 64) + `",1,{}]}`
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(call)); err != nil {
+		t.Fatalf("failed to send call: %v", err)
+	}
+	if _, out, err := conn.ReadMessage(); err != nil || !strings.Contains(string(out), `"result"`) {
+		t.Fatalf("call within limit failed: %s, %v", out, err)
+	}
+	call = `{"jsonrpc":"2.0","id":2,"method":"test_echo","params":["` + strings.Repeat("x", 128) + `",1,{}]}`
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(call)); err != nil {
+		t.Fatalf("failed to send call: %v", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, out, err := conn.ReadMessage(); err == nil && !strings.Contains(string(out), websocket.ErrReadLimit.Error()) {
+		t.Fatalf("oversized message accepted: %s", out)
+	}
+	if _, out, err := conn.ReadMessage(); err == nil {
+		t.Fatalf("connection not closed: %s", out)
+	}
+}
+
+func TestHTTPRateLimit(t *testing.T) {
+	srv := newLimitedServer(t, Limits{RequestRate: 2})
+	defer srv.Close()
+
+	call := `{"jsonrpc":"2.0","id":1,"method":"test_rets"}`
+	for i := 0; i < 2; i++ {
+		if code, _ := postJSON(t, srv.URL, call); code != http.StatusOK {
+			t.Fatalf("request %d: status mismatch: have %d, want %d", i, code, http.StatusOK)
+		}
+	}
+	if code, _ := postJSON(t, srv.URL, call); code != http.StatusTooManyRequests {
+		t.Fatalf("status mismatch: have %d, want %d", code, http.StatusTooManyRequests)
+	}
+}
+
+func TestIPRateLimiterWait(t *testing.T) {
+	limiter := newIPRateLimiter(10)
+	for i := 0; i < 10; i++ {
+		if wait := limiter.wait("1.2.3.4"); wait != 0 {
+			t.Fatalf("request %d: wait mismatch: have %v, want 0", i, wait)
+		}
+	}
+	if wait := limiter.wait("1.2.3.4"); wait <= 0 || wait > 100*time.Millisecond {
+		t.Fatalf("wait out of range: have %v, want (0, %v]", wait, 100*time.Millisecond)
+	}
+	if !limiter.allow("5.6.7.8") {
+		t.Fatalf("other IP throttled")
+	}
+}
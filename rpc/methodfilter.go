@@ -0,0 +1,69 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	methodWildcard        = "*"         // Matches all the methods of a namespace in a method filter
+	subscribeFilterMethod = "subscribe" // Method name subscriptions are filtered by
+)
+
+// MethodFilter restricts the methods a server executes beyond the namespaces it
+// has registered. Entries are full method names such as vnt_call, or a
+// namespace followed by a wildcard such as debug_*. Subscriptions are matched
+// as <namespace>_subscribe.
+type MethodFilter struct {
+	Allow []string // Methods to execute exclusively (empty = all)
+	Deny  []string // Methods never to execute, taking precedence over Allow
+}
+
+// Validate checks that all the entries of the filter are well formed.
+func (f MethodFilter) Validate() error {
+	for _, list := range [][]string{f.Allow, f.Deny} {
+		for _, entry := range list {
+			elems := strings.SplitN(entry, serviceMethodSeparator, 2)
+			if len(elems) != 2 || elems[0] == "" || elems[1] == "" {
+				return fmt.Errorf("invalid method %q, want <namespace>%s<method>", entry, serviceMethodSeparator)
+			}
+		}
+	}
+	return nil
+}
+
+// allowed reports whether the filter lets the method of the service through.
+func (f MethodFilter) allowed(service, method string) bool {
+	if matchMethod(f.Deny, service, method) {
+		return false
+	}
+	return len(f.Allow) == 0 || matchMethod(f.Allow, service, method)
+}
+
+// matchMethod reports whether any of the entries matches the method of the
+// service.
+func matchMethod(entries []string, service, method string) bool {
+	name := service + serviceMethodSeparator + method
+	for _, entry := range entries {
+		if entry == name || entry == service+serviceMethodSeparator+methodWildcard {
+			return true
+		}
+	}
+	return false
+}
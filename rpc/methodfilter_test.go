@@ -0,0 +1,89 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMethodFilterValidate(t *testing.T) {
+	tests := []struct {
+		filter MethodFilter
+		valid  bool
+	}{
+		{MethodFilter{}, true},
+		{MethodFilter{Allow: []string{"vnt_call", "debug_*"}, Deny: []string{"vnt_sendTransaction"}}, true},
+		{MethodFilter{Allow: []string{"vnt"}}, false},
+		{MethodFilter{Deny: []string{"_call"}}, false},
+		{MethodFilter{Deny: []string{"vnt_"}}, false},
+	}
+	for i, tt := range tests {
+		if err := tt.filter.Validate(); (err == nil) != tt.valid {
+			t.Errorf("test %d: validity mismatch: have %v, want %v", i, err, tt.valid)
+		}
+	}
+}
+
+func TestMethodFilterAllowed(t *testing.T) {
+	filter := MethodFilter{
+		Allow: []string{"test_echo", "test_rets", "debug_*"},
+		Deny:  []string{"test_rets", "debug_setHead"},
+	}
+	tests := []struct {
+		service, method string
+		allowed         bool
+	}{
+		{"test", "echo", true},
+		{"test", "rets", false}, // denied methods take precedence
+		{"test", "sleep", false},
+		{"debug", "traceBlock", true},
+		{"debug", "setHead", false},
+		{"vnt", "call", false},
+	}
+	for _, tt := range tests {
+		if allowed := filter.allowed(tt.service, tt.method); allowed != tt.allowed {
+			t.Errorf("%s_%s: allowed mismatch: have %v, want %v", tt.service, tt.method, allowed, tt.allowed)
+		}
+	}
+	if !(MethodFilter{}).allowed("test", "echo") {
+		t.Errorf("empty filter rejected a method")
+	}
+}
+
+// Tests that the server answers the methods rejected by its filter with an
+// error, while serving the others.
+func TestHTTPMethodFilter(t *testing.T) {
+	server := NewServer()
+	if err := server.RegisterName("test", new(Service)); err != nil {
+		t.Fatal(err)
+	}
+	server.SetMethodFilter(MethodFilter{Deny: []string{"test_rets", "test_subscribe"}})
+	srv := httptest.NewServer(server)
+	defer srv.Close()
+
+	if _, out := postJSON(t, srv.URL, `{"jsonrpc":"2.0","id":1,"method":"test_noArgsRets"}`); !strings.Contains(out, `"result"`) {
+		t.Errorf("allowed method rejected: %s", out)
+	}
+	if _, out := postJSON(t, srv.URL, `{"jsonrpc":"2.0","id":1,"method":"test_rets"}`); !strings.Contains(out, "test_rets is not allowed") {
+		t.Errorf("denied method served: %s", out)
+	}
+	if _, out := postJSON(t, srv.URL, `{"jsonrpc":"2.0","id":1,"method":"test_subscribe","params":["someSubscription",1,2]}`); !strings.Contains(out, "test_subscribe is not allowed") {
+		t.Errorf("denied subscription served: %s", out)
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/vntchain/go-vnt/log"
 	"gopkg.in/fatih/set.v0"
@@ -58,6 +59,31 @@ func NewServer() *Server {
 	return server
 }
 
+// SetLimits bounds the request body size, the batch length and the per IP
+// request rate of the server. It must be called before serving requests.
+func (s *Server) SetLimits(limits Limits) {
+	s.bodyLimit = limits.BodySize
+	s.batchLimit = limits.BatchSize
+	s.limiter = nil
+	if limits.RequestRate > 0 {
+		s.limiter = newIPRateLimiter(limits.RequestRate)
+	}
+}
+
+// SetMethodFilter restricts the methods the server executes, answering the
+// rejected ones with an error. It must be called before serving requests.
+func (s *Server) SetMethodFilter(filter MethodFilter) {
+	s.methods = filter
+}
+
+// maxBodySize returns the maximum size of a request body or websocket message.
+func (s *Server) maxBodySize() int64 {
+	if s.bodyLimit > 0 {
+		return s.bodyLimit
+	}
+	return maxRequestContentLength
+}
+
 // RPCService gives meta information about the server.
 // e.g. gives information about the loaded modules.
 type RPCService struct {
@@ -172,9 +198,23 @@ func (s *Server) serveRequest(ctx context.Context, codec ServerCodec, singleShot
 			return nil
 		}
 
+		// reject oversized batches as a whole, without executing any part
+		if batch && s.batchLimit > 0 && len(reqs) > s.batchLimit {
+			err = &invalidRequestError{fmt.Sprintf("batch too large (%d>%d)", len(reqs), s.batchLimit)}
+			codec.Write(codec.CreateErrorResponse(nil, err))
+			if singleShot {
+				return nil
+			}
+			continue
+		}
+
 		// check if server is ordered to shutdown and return an error
-		// telling the client that his request failed.
+		// telling the client that his request failed. The request is counted
+		// as in flight beforehand, so a draining server either waits for it
+		// or rejects it.
+		atomic.AddInt32(&s.inflight, 1)
 		if atomic.LoadInt32(&s.run) != 1 {
+			atomic.AddInt32(&s.inflight, -1)
 			err = &shutdownError{}
 			if batch {
 				resps := make([]interface{}, len(reqs))
@@ -189,6 +229,7 @@ func (s *Server) serveRequest(ctx context.Context, codec ServerCodec, singleShot
 		}
 		// If a single shot request is executing, run and return immediately
 		if singleShot {
+			defer atomic.AddInt32(&s.inflight, -1)
 			if batch {
 				s.execBatch(ctx, codec, reqs)
 			} else {
@@ -201,6 +242,7 @@ func (s *Server) serveRequest(ctx context.Context, codec ServerCodec, singleShot
 
 		go func(reqs []*serverRequest, batch bool) {
 			defer pend.Done()
+			defer atomic.AddInt32(&s.inflight, -1)
 			if batch {
 				s.execBatch(ctx, codec, reqs)
 			} else {
@@ -231,15 +273,53 @@ func (s *Server) ServeSingleRequest(ctx context.Context, codec ServerCodec, opti
 func (s *Server) Stop() {
 	if atomic.CompareAndSwapInt32(&s.run, 1, 0) {
 		log.Debug("RPC Server shutdown initiatied")
-		s.codecsMu.Lock()
-		defer s.codecsMu.Unlock()
-		s.codecs.Each(func(c interface{}) bool {
-			c.(ServerCodec).Close()
-			return true
-		})
+		s.closeCodecs()
 	}
 }
 
+// drainInterval is the frequency at which the in-flight requests are polled
+// while the server shuts down.
+var drainInterval = 10 * time.Millisecond
+
+// Shutdown stops reading new requests like Stop, but lets the requests being
+// executed finish and send their responses until the timeout expires. The
+// connections are closed afterwards, cancelling the requests still running
+// and the subscriptions.
+func (s *Server) Shutdown(timeout time.Duration) {
+	if !atomic.CompareAndSwapInt32(&s.run, 1, 0) {
+		return
+	}
+	log.Debug("RPC Server graceful shutdown initiated", "timeout", timeout)
+
+	ticker := time.NewTicker(drainInterval)
+	defer ticker.Stop()
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for atomic.LoadInt32(&s.inflight) > 0 {
+		select {
+		case <-ticker.C:
+		case <-deadline.C:
+			log.Warn("Aborting in-flight RPC requests", "count", atomic.LoadInt32(&s.inflight), "timeout", timeout)
+			s.closeCodecs()
+			return
+		}
+	}
+	s.closeCodecs()
+}
+
+// closeCodecs closes all the connections served.
+func (s *Server) closeCodecs() {
+	s.codecsMu.Lock()
+	defer s.codecsMu.Unlock()
+
+	s.codecs.Each(func(c interface{}) bool {
+		c.(ServerCodec).Close()
+		return true
+	})
+}
+
 // createSubscription will call the subscription callback and returns the subscription id or error.
 func (s *Server) createSubscription(ctx context.Context, c ServerCodec, req *serverRequest) (ID, error) {
 	// subscription have as first argument the context following optional arguments
@@ -316,8 +396,15 @@ func (s *Server) handle(ctx context.Context, codec ServerCodec, req *serverReque
 	if req.callb.errPos >= 0 { // test if method returned an error
 		if !reply[req.callb.errPos].IsNil() {
 			e := reply[req.callb.errPos].Interface().(error)
-			res := codec.CreateErrorResponse(&req.id, &callbackError{e.Error()})
-			return res, nil
+			// Preserve the code and data of structured errors
+			rpcErr, ok := e.(Error)
+			if !ok {
+				rpcErr = &callbackError{e.Error()}
+			}
+			if de, ok := e.(DataError); ok {
+				return codec.CreateErrorResponseWithInfo(&req.id, rpcErr, de.ErrorData()), nil
+			}
+			return codec.CreateErrorResponse(&req.id, rpcErr), nil
 		}
 	}
 	return codec.CreateResponse(req.id, reply[0].Interface()), nil
@@ -403,6 +490,15 @@ func (s *Server) readRequest(codec ServerCodec) ([]*serverRequest, bool, Error)
 			continue
 		}
 
+		method := r.method
+		if r.isPubSub { // subscriptions are filtered as a whole by the subscribe method
+			method = subscribeFilterMethod
+		}
+		if !s.methods.allowed(r.service, method) {
+			requests[i] = &serverRequest{id: r.id, err: &methodNotAllowedError{r.service, method}}
+			continue
+		}
+
 		if svc, ok = s.services[r.service]; !ok { // rpc method isn't available
 			requests[i] = &serverRequest{id: r.id, err: &methodNotFoundError{r.service, r.method}}
 			continue
@@ -19,10 +19,16 @@ package rpc
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net"
+	"net/http/httptest"
 	"reflect"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/gorilla/websocket"
 )
 
 type Service struct{}
@@ -160,3 +166,114 @@ func TestServerMethodExecution(t *testing.T) {
 func TestServerMethodWithCtx(t *testing.T) {
 	testServerMethodExecution(t, "echoWithCtx")
 }
+
+type ErrorService struct{}
+
+type codedError struct{}
+
+func (codedError) Error() string          { return "coded error" }
+func (codedError) ErrorCode() int         { return -32005 }
+func (codedError) ErrorData() interface{} { return "error data" }
+
+func (s *ErrorService) Coded() error { return codedError{} }
+
+func (s *ErrorService) Plain() error { return errors.New("plain error") }
+
+// Tests that the code and data of structured errors returned by callbacks are
+// sent to the client, while plain errors fall back to the generic error code.
+func TestServerStructuredErrors(t *testing.T) {
+	server := NewServer()
+	if err := server.RegisterName("test", new(ErrorService)); err != nil {
+		t.Fatalf("%v", err)
+	}
+	client := DialInProc(server)
+	defer client.Close()
+
+	err := client.Call(nil, "test_coded")
+	if rpcErr, ok := err.(Error); !ok || rpcErr.ErrorCode() != -32005 || rpcErr.Error() != "coded error" {
+		t.Fatalf("coded error mismatch: %v", err)
+	}
+	if dataErr, ok := err.(DataError); !ok || dataErr.ErrorData() != "error data" {
+		t.Fatalf("error data mismatch: %v", err)
+	}
+	err = client.Call(nil, "test_plain")
+	if rpcErr, ok := err.(Error); !ok || rpcErr.ErrorCode() != -32000 || rpcErr.Error() != "plain error" {
+		t.Fatalf("plain error mismatch: %v", err)
+	}
+	if dataErr, ok := err.(DataError); !ok || dataErr.ErrorData() != nil {
+		t.Fatalf("plain error carries data: %v", err)
+	}
+}
+
+// waitInflight blocks until the server executes at least one request.
+func waitInflight(t *testing.T, server *Server) {
+	for i := 0; atomic.LoadInt32(&server.inflight) == 0; i++ {
+		if i == 500 {
+			t.Fatal("request not executed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Tests that a gracefully shut down server lets the running requests finish,
+// but aborts them once the timeout expires.
+func TestServerShutdown(t *testing.T) {
+	tests := []struct {
+		sleep, timeout time.Duration
+		finished       bool
+	}{
+		{sleep: 200 * time.Millisecond, timeout: 5 * time.Second, finished: true},
+		{sleep: time.Second, timeout: 50 * time.Millisecond, finished: false},
+	}
+	for i, tt := range tests {
+		server := newTestServer("test", new(Service))
+		client := DialInProc(server)
+
+		errc := make(chan error, 1)
+		go func() { errc <- client.Call(nil, "test_sleep", tt.sleep) }()
+		waitInflight(t, server)
+
+		start := time.Now()
+		server.Shutdown(tt.timeout)
+		if elapsed := time.Since(start); elapsed >= tt.sleep+tt.timeout {
+			t.Errorf("test %d: shutdown took too long: %v", i, elapsed)
+		}
+		if err := <-errc; (err == nil) != tt.finished {
+			t.Errorf("test %d: request result mismatch: have error %v, want finished %v", i, err, tt.finished)
+		}
+		// New requests are refused
+		if err := client.Call(nil, "test_echo", "x", 1, nil); err == nil {
+			t.Errorf("test %d: request accepted after shutdown", i)
+		}
+		client.Close()
+	}
+}
+
+// Tests that websocket clients are sent a close frame when the server shuts down.
+func TestServerShutdownWebsocket(t *testing.T) {
+	server := newTestServer("test", new(Service))
+	hs := httptest.NewServer(server.WebsocketHandler([]string{"*"}))
+	defer hs.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(hs.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+
+	if err := conn.WriteJSON(map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": "test_sleep", "params": []interface{}{100 * time.Millisecond}}); err != nil {
+		t.Fatalf("failed to send request: %v", err)
+	}
+	waitInflight(t, server)
+	go server.Shutdown(5 * time.Second)
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var resp jsonSuccessResponse
+	if err := conn.ReadJSON(&resp); err != nil {
+		t.Fatalf("in-flight request dropped: %v", err)
+	}
+	_, _, err = conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Fatalf("close frame mismatch: have %v, want going away", err)
+	}
+}
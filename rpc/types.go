@@ -75,8 +75,16 @@ type Server struct {
 	services serviceRegistry
 
 	run      int32
+	inflight int32 // Number of requests being executed
 	codecsMu sync.Mutex
 	codecs   *set.Set
+
+	bodyLimit  int64          // Maximum request body size, zero for maxRequestContentLength
+	batchLimit int            // Maximum number of requests in a batch, zero for unlimited
+	limiter    *ipRateLimiter // Per IP request rate limiter, nil for unlimited
+
+	wsConfig WebsocketConfig // Settings of the websocket connections
+	methods  MethodFilter    // Methods allowed to be executed
 }
 
 // rpcRequest represents a raw incoming RPC request
@@ -95,6 +103,13 @@ type Error interface {
 	ErrorCode() int // returns the code
 }
 
+// DataError is an error carrying additional data, which is returned to the
+// client along with the error message.
+type DataError interface {
+	Error() string          // returns the message
+	ErrorData() interface{} // returns the error data
+}
+
 // ServerCodec implements reading, parsing and writing RPC messages for the server side of
 // a RPC session. Implementations must be go-routine safe since the codec can be called in
 // multiple go-routines concurrently.
@@ -122,13 +137,14 @@ type ServerCodec interface {
 type BlockNumber int64
 
 const (
-	PendingBlockNumber  = BlockNumber(-2)
-	LatestBlockNumber   = BlockNumber(-1)
-	EarliestBlockNumber = BlockNumber(0)
+	IrreversibleBlockNumber = BlockNumber(-3)
+	PendingBlockNumber      = BlockNumber(-2)
+	LatestBlockNumber       = BlockNumber(-1)
+	EarliestBlockNumber     = BlockNumber(0)
 )
 
 // UnmarshalJSON parses the given JSON fragment into a BlockNumber. It supports:
-// - "latest", "earliest" or "pending" as string arguments
+// - "latest", "earliest", "pending" or "irreversible" (alias "finalized") as string arguments
 // - the block number
 // Returned errors:
 // - an invalid block number error when the given argument isn't a known strings
@@ -149,6 +165,9 @@ func (bn *BlockNumber) UnmarshalJSON(data []byte) error {
 	case "pending":
 		*bn = PendingBlockNumber
 		return nil
+	case "irreversible", "finalized":
+		*bn = IrreversibleBlockNumber
+		return nil
 	}
 
 	blckNum, err := hexutil.DecodeUint64(input)
@@ -46,6 +46,8 @@ func TestBlockNumberJSONUnmarshal(t *testing.T) {
 		14: {`someString`, true, BlockNumber(0)},
 		15: {`""`, true, BlockNumber(0)},
 		16: {``, true, BlockNumber(0)},
+		17: {`"irreversible"`, false, IrreversibleBlockNumber},
+		18: {`"finalized"`, false, IrreversibleBlockNumber},
 	}
 
 	for i, test := range tests {
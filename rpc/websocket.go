@@ -17,38 +17,48 @@
 package rpc
 
 import (
-	"bytes"
 	"context"
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"net/url"
 	"os"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/gorilla/websocket"
 	"github.com/vntchain/go-vnt/log"
-	"golang.org/x/net/websocket"
+	xwebsocket "golang.org/x/net/websocket"
 	"gopkg.in/fatih/set.v0"
 )
 
-// websocketJSONCodec is a custom JSON codec with payload size enforcement and
-// special number parsing.
-var websocketJSONCodec = websocket.Codec{
-	// Marshal is the stock JSON marshaller used by the websocket library too.
-	Marshal: func(v interface{}) ([]byte, byte, error) {
-		msg, err := json.Marshal(v)
-		return msg, websocket.TextFrame, err
-	},
-	// Unmarshal is a specialized unmarshaller to properly convert numbers.
-	Unmarshal: func(msg []byte, payloadType byte, v interface{}) error {
-		dec := json.NewDecoder(bytes.NewReader(msg))
-		dec.UseNumber()
-
-		return dec.Decode(v)
-	},
+const (
+	wsReadBuffer  = 1024
+	wsWriteBuffer = 1024
+)
+
+// WebsocketConfig holds the per-connection settings of the websocket server.
+type WebsocketConfig struct {
+	ReadLimit   int64 // Maximum size of a message read from a client in bytes (0 = request body limit)
+	Compression bool  // Whether to negotiate permessage-deflate compression with clients
+}
+
+// SetWebsocketConfig sets the settings applied to every websocket connection. It
+// must be called before serving requests.
+func (srv *Server) SetWebsocketConfig(config WebsocketConfig) {
+	srv.wsConfig = config
+}
+
+// wsReadLimit returns the maximum size of a message read from a websocket client.
+func (srv *Server) wsReadLimit() int64 {
+	if srv.wsConfig.ReadLimit > 0 {
+		return srv.wsConfig.ReadLimit
+	}
+	return srv.maxBodySize()
 }
 
 // WebsocketHandler returns a handler that serves JSON-RPC to WebSocket connections.
@@ -56,21 +66,68 @@ var websocketJSONCodec = websocket.Codec{
 // allowedOrigins should be a comma-separated list of allowed origin URLs.
 // To allow connections with any origin, pass "*".
 func (srv *Server) WebsocketHandler(allowedOrigins []string) http.Handler {
-	return websocket.Server{
-		Handshake: wsHandshakeValidator(allowedOrigins),
-		Handler: func(conn *websocket.Conn) {
-			// Create a custom encode/decode pair to enforce payload size and number encoding
-			conn.MaxPayloadBytes = maxRequestContentLength
-
-			encoder := func(v interface{}) error {
-				return websocketJSONCodec.Send(conn, v)
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:    wsReadBuffer,
+		WriteBufferSize:   wsWriteBuffer,
+		EnableCompression: srv.wsConfig.Compression,
+		CheckOrigin:       wsHandshakeValidator(allowedOrigins),
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			log.Debug("WebSocket upgrade failed", "err", err)
+			return
+		}
+		// Enforce the message size and compress the responses if negotiated
+		conn.SetReadLimit(srv.wsReadLimit())
+		conn.EnableWriteCompression(srv.wsConfig.Compression)
+
+		ip := remoteIP(r.RemoteAddr)
+		encoder := func(v interface{}) error {
+			return conn.WriteJSON(v)
+		}
+		decoder := func(v interface{}) error {
+			// Throttle the connection rather than dropping messages
+			if srv.limiter != nil {
+				time.Sleep(srv.limiter.wait(ip))
 			}
-			decoder := func(v interface{}) error {
-				return websocketJSONCodec.Receive(conn, v)
+			_, msg, err := conn.NextReader()
+			if err != nil {
+				return err
 			}
-			srv.ServeCodec(NewCodec(conn, encoder, decoder), OptionMethodInvocation|OptionSubscriptions)
-		},
+			// The frame limit only bounds compressed messages, bound the inflated
+			// one too. Numbers are decoded as json.Number to keep their precision.
+			limited := &io.LimitedReader{R: msg, N: srv.wsReadLimit() + 1}
+			dec := json.NewDecoder(limited)
+			dec.UseNumber()
+			err = dec.Decode(v)
+			if limited.N <= 0 {
+				return websocket.ErrReadLimit
+			}
+			return err
+		}
+		srv.ServeCodec(NewCodec(&wsConnCloser{conn: conn, srv: srv}, encoder, decoder), OptionMethodInvocation|OptionSubscriptions)
+	})
+}
+
+// wsCloseTimeout is the maximum time spent sending the close frame to a
+// websocket client.
+const wsCloseTimeout = time.Second
+
+// wsConnCloser closes a websocket connection with a close frame, telling the
+// client whether the server is going away or just ended the connection.
+type wsConnCloser struct {
+	conn *websocket.Conn
+	srv  *Server
+}
+
+func (c *wsConnCloser) Close() error {
+	code, reason := websocket.CloseNormalClosure, ""
+	if atomic.LoadInt32(&c.srv.run) != 1 {
+		code, reason = websocket.CloseGoingAway, "server shutting down"
 	}
+	c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(wsCloseTimeout))
+	return c.conn.Close()
 }
 
 // NewWSServer creates a new websocket RPC server around an API provider.
@@ -83,7 +140,7 @@ func NewWSServer(allowedOrigins []string, srv *Server) *http.Server {
 // wsHandshakeValidator returns a handler that verifies the origin during the
 // websocket upgrade process. When a '*' is specified as an allowed origins all
 // connections are accepted.
-func wsHandshakeValidator(allowedOrigins []string) func(*websocket.Config, *http.Request) error {
+func wsHandshakeValidator(allowedOrigins []string) func(*http.Request) bool {
 	origins := set.New()
 	allowAllOrigins := false
 
@@ -106,13 +163,13 @@ func wsHandshakeValidator(allowedOrigins []string) func(*websocket.Config, *http
 
 	log.Debug(fmt.Sprintf("Allowed origin(s) for WS RPC interface %v\n", origins.List()))
 
-	f := func(cfg *websocket.Config, req *http.Request) error {
+	f := func(req *http.Request) bool {
 		origin := strings.ToLower(req.Header.Get("Origin"))
 		if allowAllOrigins || origins.Has(origin) {
-			return nil
+			return true
 		}
 		log.Warn(fmt.Sprintf("origin '%s' not allowed on WS-RPC interface\n", origin))
-		return fmt.Errorf("origin %s not allowed", origin)
+		return false
 	}
 
 	return f
@@ -124,6 +181,12 @@ func wsHandshakeValidator(allowedOrigins []string) func(*websocket.Config, *http
 // The context is used for the initial connection establishment. It does not
 // affect subsequent interactions with the client.
 func DialWebsocket(ctx context.Context, endpoint, origin string) (*Client, error) {
+	return dialWebsocket(ctx, endpoint, origin, DialOptions{})
+}
+
+// dialWebsocket creates a new RPC client over websocket, applying the extra
+// handshake headers and TLS settings of the given options.
+func dialWebsocket(ctx context.Context, endpoint, origin string, opts DialOptions) (*Client, error) {
 	if origin == "" {
 		var err error
 		if origin, err = os.Hostname(); err != nil {
@@ -135,17 +198,21 @@ func DialWebsocket(ctx context.Context, endpoint, origin string) (*Client, error
 			origin = "http://" + strings.ToLower(origin)
 		}
 	}
-	config, err := websocket.NewConfig(endpoint, origin)
+	config, err := xwebsocket.NewConfig(endpoint, origin)
 	if err != nil {
 		return nil, err
 	}
+	for key, values := range opts.Header {
+		config.Header[http.CanonicalHeaderKey(key)] = values
+	}
+	config.TlsConfig = opts.TLSConfig
 
 	return newClient(ctx, func(ctx context.Context) (net.Conn, error) {
 		return wsDialContext(ctx, config)
 	})
 }
 
-func wsDialContext(ctx context.Context, config *websocket.Config) (*websocket.Conn, error) {
+func wsDialContext(ctx context.Context, config *xwebsocket.Config) (*xwebsocket.Conn, error) {
 	var conn net.Conn
 	var err error
 	switch config.Location.Scheme {
@@ -155,12 +222,12 @@ func wsDialContext(ctx context.Context, config *websocket.Config) (*websocket.Co
 		dialer := contextDialer(ctx)
 		conn, err = tls.DialWithDialer(dialer, "tcp", wsDialAddress(config.Location), config.TlsConfig)
 	default:
-		err = websocket.ErrBadScheme
+		err = xwebsocket.ErrBadScheme
 	}
 	if err != nil {
 		return nil, err
 	}
-	ws, err := websocket.NewClient(config, conn)
+	ws, err := xwebsocket.NewClient(config, conn)
 	if err != nil {
 		conn.Close()
 		return nil, err
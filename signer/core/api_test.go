@@ -19,6 +19,7 @@ package core
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"math/big"
@@ -28,10 +29,12 @@ import (
 	"time"
 
 	"github.com/vntchain/go-vnt/accounts/keystore"
+	"github.com/vntchain/go-vnt/accounts/typeddata"
 	"github.com/vntchain/go-vnt/cmd/utils"
 	"github.com/vntchain/go-vnt/common"
 	"github.com/vntchain/go-vnt/common/hexutil"
 	"github.com/vntchain/go-vnt/core/types"
+	"github.com/vntchain/go-vnt/crypto"
 	"github.com/vntchain/go-vnt/internal/vntapi"
 	"github.com/vntchain/go-vnt/rlp"
 )
@@ -253,6 +256,53 @@ func TestSignData(t *testing.T) {
 		t.Errorf("Expected 65 byte signature (got %d bytes)", len(h))
 	}
 }
+
+func TestSignTypedData(t *testing.T) {
+	api, control := setup(t)
+	createAccount(control, api, t)
+	control <- "A"
+	list, err := api.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := common.NewMixedcaseAddress(list[0].Address)
+
+	var data typeddata.TypedData
+	if err := json.Unmarshal([]byte(`{
+		"types": {
+			"EIP712Domain": [{"name": "name", "type": "string"}, {"name": "chainId", "type": "uint256"}],
+			"Greeting": [{"name": "text", "type": "string"}]
+		},
+		"primaryType": "Greeting",
+		"domain": {"name": "Test", "chainId": "0x2"},
+		"message": {"text": "EHLO world"}
+	}`), &data); err != nil {
+		t.Fatal(err)
+	}
+	control <- "No way"
+	if _, err := api.SignTypedData(context.Background(), a, data); err != ErrRequestDenied {
+		t.Errorf("Expected ErrRequestDenied! %v", err)
+	}
+	control <- "Y"
+	control <- "apassword"
+	sig, err := api.SignTypedData(context.Background(), a, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sig) != 65 || (sig[64] != 27 && sig[64] != 28) {
+		t.Fatalf("Expected 65 byte signature with V 27 or 28, got %x", sig)
+	}
+	sighash, _ := data.SigHash()
+	sig[64] -= 27
+	pub, err := crypto.SigToPub(sighash, sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have := crypto.PubkeyToAddress(*pub); have != a.Address() {
+		t.Errorf("Signer mismatch: have %x, want %x", have, a.Address())
+	}
+}
+
 func mkTestTx(from common.MixedcaseAddress) SendTxArgs {
 	to := common.NewMixedcaseAddress(common.HexToAddress("0x1337"))
 	gas := hexutil.Uint64(21000)
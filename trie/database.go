@@ -20,6 +20,7 @@ import (
 	"sync"
 	"time"
 
+	lru "github.com/hashicorp/golang-lru"
 	"github.com/vntchain/go-vnt/common"
 	"github.com/vntchain/go-vnt/vntdb"
 	"github.com/vntchain/go-vnt/log"
@@ -27,6 +28,9 @@ import (
 )
 
 var (
+	memcacheCleanHitMeter  = metrics.NewRegisteredMeter("trie/memcache/clean/hit", nil)
+	memcacheCleanMissMeter = metrics.NewRegisteredMeter("trie/memcache/clean/miss", nil)
+
 	memcacheFlushTimeTimer  = metrics.NewRegisteredResettingTimer("trie/memcache/flush/time", nil)
 	memcacheFlushNodesMeter = metrics.NewRegisteredMeter("trie/memcache/flush/nodes", nil)
 	memcacheFlushSizeMeter  = metrics.NewRegisteredMeter("trie/memcache/flush/size", nil)
@@ -40,6 +44,10 @@ var (
 	memcacheCommitSizeMeter  = metrics.NewRegisteredMeter("trie/memcache/commit/size", nil)
 )
 
+// cleanNodeSize is the rough memory footprint of a clean trie node, used to turn
+// the allowance of the clean cache into a node count.
+const cleanNodeSize = 256
+
 // secureKeyPrefix is the database key prefix used to store trie node preimages.
 var secureKeyPrefix = []byte("secure-key-")
 
@@ -60,13 +68,15 @@ type DatabaseReader interface {
 // periodically flush a couple tries to disk, garbage collecting the remainder.
 type Database struct {
 	diskdb vntdb.Database // Persistent storage for matured trie nodes
+	cleans *lru.Cache     // Clean nodes read from disk, nil if not caching
 
 	nodes  map[common.Hash]*cachedNode // Data and references relationships of a node
 	oldest common.Hash                 // Oldest tracked node, flush-list head
 	newest common.Hash                 // Newest tracked node, flush-list tail
 
-	preimages map[common.Hash][]byte // Preimages of nodes from the secure trie
-	seckeybuf [secureKeyLength]byte  // Ephemeral buffer for calculating preimage keys
+	preimages   map[common.Hash][]byte // Preimages of nodes from the secure trie
+	seckeybuf   [secureKeyLength]byte  // Ephemeral buffer for calculating preimage keys
+	nopreimages bool                   // Whether preimage recording is disabled
 
 	gctime  time.Duration      // Time spent on garbage collection since last commit
 	gcnodes uint64             // Nodes garbage collected since last commit
@@ -79,6 +89,8 @@ type Database struct {
 	nodesSize     common.StorageSize // Storage size of the nodes cache (exc. flushlist)
 	preimagesSize common.StorageSize // Storage size of the preimages cache
 
+	flushHook func(hash common.Hash) // Callback for every node about to be persisted, nil if none
+
 	lock sync.RWMutex
 }
 
@@ -105,11 +117,40 @@ func NewDatabase(diskdb vntdb.Database) *Database {
 	}
 }
 
+// NewDatabaseWithCache creates a new trie database which additionally keeps up
+// to the given megabytes of clean nodes read from disk in memory.
+func NewDatabaseWithCache(diskdb vntdb.Database, cache int) *Database {
+	db := NewDatabase(diskdb)
+	if cache > 0 {
+		db.cleans, _ = lru.New(cache * 1024 * 1024 / cleanNodeSize)
+	}
+	return db
+}
+
+// SetFlushHook installs a callback invoked with the hash of every node before it
+// is written to disk, or removes it if nil.
+func (db *Database) SetFlushHook(hook func(hash common.Hash)) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.flushHook = hook
+}
+
 // DiskDB retrieves the persistent storage backing the trie database.
 func (db *Database) DiskDB() DatabaseReader {
 	return db.diskdb
 }
 
+// DisablePreimages stops recording the preimages of hashed secure trie keys,
+// saving their disk space at the expense of not being able to resolve the keys
+// any more.
+func (db *Database) DisablePreimages() {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.nopreimages = true
+}
+
 // Insert writes a new trie node to the memory database if it's yet unknown. The
 // method will make a copy of the slice.
 func (db *Database) Insert(hash common.Hash, blob []byte) {
@@ -144,6 +185,9 @@ func (db *Database) insert(hash common.Hash, blob []byte) {
 //
 // Note, this method assumes that the database's lock is held!
 func (db *Database) insertPreimage(hash common.Hash, preimage []byte) {
+	if db.nopreimages {
+		return
+	}
 	if _, ok := db.preimages[hash]; ok {
 		return
 	}
@@ -162,8 +206,19 @@ func (db *Database) Node(hash common.Hash) ([]byte, error) {
 	if node != nil {
 		return node.blob, nil
 	}
+	if db.cleans != nil {
+		if blob, ok := db.cleans.Get(hash); ok {
+			memcacheCleanHitMeter.Mark(1)
+			return blob.([]byte), nil
+		}
+		memcacheCleanMissMeter.Mark(1)
+	}
 	// Content unavailable in memory, attempt to retrieve from disk
-	return db.diskdb.Get(hash[:])
+	blob, err := db.diskdb.Get(hash[:])
+	if err == nil && blob != nil && db.cleans != nil {
+		db.cleans.Add(hash, blob)
+	}
+	return blob, err
 }
 
 // preimage retrieves a cached trie node pre-image from memory. If it cannot be
@@ -323,6 +378,9 @@ func (db *Database) Cap(limit common.StorageSize) error {
 	for size > limit && oldest != (common.Hash{}) {
 		// Fetch the oldest referenced node and push into the batch
 		node := db.nodes[oldest]
+		if db.flushHook != nil {
+			db.flushHook(oldest)
+		}
 		if err := batch.Put(oldest[:], node.blob); err != nil {
 			db.lock.RUnlock()
 			return err
@@ -465,6 +523,9 @@ func (db *Database) commit(hash common.Hash, batch vntdb.Batch) error {
 			return err
 		}
 	}
+	if db.flushHook != nil {
+		db.flushHook(hash)
+	}
 	if err := batch.Put(hash[:], node.blob); err != nil {
 		return err
 	}
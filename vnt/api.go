@@ -17,6 +17,7 @@
 package vnt
 
 import (
+	"bufio"
 	"compress/gzip"
 	"context"
 	"errors"
@@ -25,6 +26,7 @@ import (
 	"math/big"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/vntchain/go-vnt/common"
 	"github.com/vntchain/go-vnt/common/hexutil"
@@ -262,27 +264,42 @@ func NewPublicDebugAPI(vnt *VNT) *PublicDebugAPI {
 
 // DumpBlock retrieves the entire state of the database at a given block.
 func (api *PublicDebugAPI) DumpBlock(blockNr rpc.BlockNumber) (state.Dump, error) {
+	stateDb, err := api.vnt.stateAtBlock(blockNr)
+	if err != nil {
+		return state.Dump{}, err
+	}
+	return stateDb.RawDump(), nil
+}
+
+// blockByNumber retrieves the block with the given number, resolving the pending,
+// latest and irreversible block numbers. Nil is returned if it's not available.
+func (s *VNT) blockByNumber(number rpc.BlockNumber) *types.Block {
+	switch number {
+	case rpc.PendingBlockNumber:
+		return s.miner.PendingBlock()
+	case rpc.LatestBlockNumber:
+		return s.blockchain.CurrentBlock()
+	case rpc.IrreversibleBlockNumber:
+		return s.blockchain.CurrentIrreversibleBlock()
+	default:
+		return s.blockchain.GetBlockByNumber(uint64(number))
+	}
+}
+
+// stateAtBlock returns the state of the database at a given block.
+func (s *VNT) stateAtBlock(blockNr rpc.BlockNumber) (*state.StateDB, error) {
 	if blockNr == rpc.PendingBlockNumber {
 		// If we're dumping the pending state, we need to request
 		// both the pending block as well as the pending state from
 		// the miner and operate on those
-		_, stateDb := api.vnt.miner.Pending()
-		return stateDb.RawDump(), nil
-	}
-	var block *types.Block
-	if blockNr == rpc.LatestBlockNumber {
-		block = api.vnt.blockchain.CurrentBlock()
-	} else {
-		block = api.vnt.blockchain.GetBlockByNumber(uint64(blockNr))
+		_, stateDb := s.miner.Pending()
+		return stateDb, nil
 	}
+	block := s.blockByNumber(blockNr)
 	if block == nil {
-		return state.Dump{}, fmt.Errorf("block #%d not found", blockNr)
+		return nil, fmt.Errorf("block #%d not found", blockNr)
 	}
-	stateDb, err := api.vnt.BlockChain().StateAt(block.Root())
-	if err != nil {
-		return state.Dump{}, err
-	}
-	return stateDb.RawDump(), nil
+	return s.BlockChain().StateAt(block.Root())
 }
 
 // PrivateDebugAPI is the collection of VNT full node APIs exposed over
@@ -298,6 +315,30 @@ func NewPrivateDebugAPI(config *params.ChainConfig, vnt *VNT) *PrivateDebugAPI {
 	return &PrivateDebugAPI{config: config, vnt: vnt}
 }
 
+// DumpBlockRLP writes the entire state of the database at a given block to
+// file as an RLP stream of the state root followed by every account. Accounts
+// are written as they are iterated, so the dump is never held in memory.
+func (api *PrivateDebugAPI) DumpBlockRLP(blockNr rpc.BlockNumber, file string) error {
+	stateDb, err := api.vnt.stateAtBlock(blockNr)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	if err := stateDb.RLPDump(w); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
 // Preimage is a debug API function that returns the preimage for a sha3 hash, if known.
 func (api *PrivateDebugAPI) Preimage(ctx context.Context, hash common.Hash) (hexutil.Bytes, error) {
 	if preimage := rawdb.ReadPreimage(api.vnt.ChainDb(), hash); preimage != nil {
@@ -308,21 +349,28 @@ func (api *PrivateDebugAPI) Preimage(ctx context.Context, hash common.Hash) (hex
 
 // BadBlockArgs represents the entries in the list returned when bad blocks are queried.
 type BadBlockArgs struct {
-	Hash  common.Hash            `json:"hash"`
-	Block map[string]interface{} `json:"block"`
-	RLP   string                 `json:"rlp"`
+	Hash     common.Hash            `json:"hash"`
+	Error    string                 `json:"error"`
+	Reported time.Time              `json:"reported"`
+	Block    map[string]interface{} `json:"block"`
+	RLP      string                 `json:"rlp"`
 }
 
-// GetBadBLocks returns a list of the last 'bad blocks' that the client has seen on the network
-// and returns them as a JSON list of block-hashes
+// GetBadBLocks returns a list of the last 'bad blocks' that the client has seen on the network,
+// along with the reason they were rejected and their RLP encoding, oldest first.
 func (api *PrivateDebugAPI) GetBadBlocks(ctx context.Context) ([]*BadBlockArgs, error) {
-	blocks := api.vnt.BlockChain().BadBlocks()
-	results := make([]*BadBlockArgs, len(blocks))
+	bads := api.vnt.BlockChain().BadBlocks()
+	results := make([]*BadBlockArgs, len(bads))
 
 	var err error
-	for i, block := range blocks {
+	for i, bad := range bads {
+		block := bad.Block
 		results[i] = &BadBlockArgs{
-			Hash: block.Hash(),
+			Hash:     block.Hash(),
+			Reported: bad.Reported,
+		}
+		if bad.Err != nil {
+			results[i].Error = bad.Err.Error()
 		}
 		if rlpBytes, err := rlp.EncodeToBytes(block); err != nil {
 			results[i].RLP = err.Error() // Hacky, but hey, it works
@@ -336,6 +384,10 @@ func (api *PrivateDebugAPI) GetBadBlocks(ctx context.Context) ([]*BadBlockArgs,
 	return results, nil
 }
 
+// maxStorageRangeResult is the maximum number of storage entries returned by a
+// single debug_storageRangeAt call, larger ranges need to be paged by NextKey.
+const maxStorageRangeResult = 1024
+
 // StorageRangeResult is the result of a debug_storageRangeAt API call.
 type StorageRangeResult struct {
 	Storage storageMap   `json:"storage"`
@@ -350,11 +402,28 @@ type storageEntry struct {
 }
 
 // StorageRangeAt returns the storage at the given block height and transaction index.
+// The state is the one the transaction was executed on, or the final state of the
+// block if the index equals the number of transactions in it.
 func (api *PrivateDebugAPI) StorageRangeAt(ctx context.Context, blockHash common.Hash, txIndex int, contractAddress common.Address, keyStart hexutil.Bytes, maxResult int) (StorageRangeResult, error) {
-	_, _, statedb, err := api.computeTxEnv(blockHash, txIndex, 0)
+	block := api.vnt.blockchain.GetBlockByHash(blockHash)
+	if block == nil {
+		return StorageRangeResult{}, fmt.Errorf("block %x not found", blockHash)
+	}
+	var (
+		statedb *state.StateDB
+		err     error
+	)
+	if txIndex == len(block.Transactions()) {
+		statedb, err = api.computeStateDB(block, defaultTraceReexec)
+	} else {
+		_, _, statedb, err = api.computeTxEnv(blockHash, txIndex, defaultTraceReexec)
+	}
 	if err != nil {
 		return StorageRangeResult{}, err
 	}
+	if maxResult > maxStorageRangeResult {
+		maxResult = maxStorageRangeResult
+	}
 	st := statedb.StorageTrie(contractAddress)
 	if st == nil {
 		return StorageRangeResult{}, fmt.Errorf("account %x doesn't exist", contractAddress)
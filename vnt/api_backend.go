@@ -19,13 +19,13 @@ package vnt
 import (
 	"context"
 	"math/big"
+	"time"
 
 	"github.com/vntchain/go-vnt/accounts"
 	"github.com/vntchain/go-vnt/common"
 	"github.com/vntchain/go-vnt/common/math"
 	"github.com/vntchain/go-vnt/core"
 	"github.com/vntchain/go-vnt/core/bloombits"
-	"github.com/vntchain/go-vnt/core/rawdb"
 	"github.com/vntchain/go-vnt/core/state"
 	"github.com/vntchain/go-vnt/core/types"
 	"github.com/vntchain/go-vnt/core/vm"
@@ -39,8 +39,9 @@ import (
 
 // VntAPIBackend implements vntapi.Backend for full nodes
 type VntAPIBackend struct {
-	vnt *VNT
-	gpo *gasprice.Oracle
+	extRPCEnabled bool
+	vnt           *VNT
+	gpo           *gasprice.Oracle
 }
 
 func (b *VntAPIBackend) ChainConfig() *params.ChainConfig {
@@ -51,9 +52,9 @@ func (b *VntAPIBackend) CurrentBlock() *types.Block {
 	return b.vnt.blockchain.CurrentBlock()
 }
 
-func (b *VntAPIBackend) SetHead(number uint64) {
+func (b *VntAPIBackend) SetHead(number uint64) error {
 	b.vnt.protocolManager.downloader.Cancel()
-	b.vnt.blockchain.SetHead(number)
+	return b.vnt.blockchain.SetHead(number)
 }
 
 func (b *VntAPIBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error) {
@@ -64,7 +65,10 @@ func (b *VntAPIBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNum
 	}
 	// Otherwise resolve and return the block
 	if blockNr == rpc.LatestBlockNumber {
-		return b.vnt.blockchain.CurrentBlock().Header(), nil
+		return b.currentBlock().Header(), nil
+	}
+	if blockNr == rpc.IrreversibleBlockNumber {
+		return b.vnt.blockchain.CurrentIrreversible(), nil
 	}
 	return b.vnt.blockchain.GetHeaderByNumber(uint64(blockNr)), nil
 }
@@ -77,7 +81,10 @@ func (b *VntAPIBackend) BlockByNumber(ctx context.Context, blockNr rpc.BlockNumb
 	}
 	// Otherwise resolve and return the block
 	if blockNr == rpc.LatestBlockNumber {
-		return b.vnt.blockchain.CurrentBlock(), nil
+		return b.currentBlock(), nil
+	}
+	if blockNr == rpc.IrreversibleBlockNumber {
+		return b.vnt.blockchain.CurrentIrreversibleBlock(), nil
 	}
 	return b.vnt.blockchain.GetBlockByNumber(uint64(blockNr)), nil
 }
@@ -93,27 +100,42 @@ func (b *VntAPIBackend) StateAndHeaderByNumber(ctx context.Context, blockNr rpc.
 	if header == nil || err != nil {
 		return nil, nil, err
 	}
+	// Recent states may not have reached the replica yet, or not even the disk
+	if b.vnt.replicaState != nil {
+		if stateDb, err := state.New(header.Root, b.vnt.replicaState); err == nil {
+			return stateDb, header, nil
+		}
+	}
 	stateDb, err := b.vnt.BlockChain().StateAt(header.Root)
+	if err != nil && b.vnt.beamState != nil {
+		stateDb, err = state.New(header.Root, b.vnt.beamState)
+	}
 	return stateDb, header, err
 }
 
+// currentBlock returns the head block served as latest. With beam reads, the
+// head of a fast sync is served as soon as it's ahead of the full chain, its
+// state being fetched on demand.
+func (b *VntAPIBackend) currentBlock() *types.Block {
+	head := b.vnt.blockchain.CurrentBlock()
+	if b.vnt.beamState != nil {
+		if fast := b.vnt.blockchain.CurrentFastBlock(); fast.NumberU64() > head.NumberU64() {
+			return fast
+		}
+	}
+	return head
+}
+
 func (b *VntAPIBackend) GetBlock(ctx context.Context, hash common.Hash) (*types.Block, error) {
 	return b.vnt.blockchain.GetBlockByHash(hash), nil
 }
 
 func (b *VntAPIBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
-	if number := rawdb.ReadHeaderNumber(b.vnt.chainDb, hash); number != nil {
-		return rawdb.ReadReceipts(b.vnt.chainDb, hash, *number), nil
-	}
-	return nil, nil
+	return b.vnt.blockchain.GetReceiptsByHash(hash), nil
 }
 
 func (b *VntAPIBackend) GetLogs(ctx context.Context, hash common.Hash) ([][]*types.Log, error) {
-	number := rawdb.ReadHeaderNumber(b.vnt.chainDb, hash)
-	if number == nil {
-		return nil, nil
-	}
-	receipts := rawdb.ReadReceipts(b.vnt.chainDb, hash, *number)
+	receipts := b.vnt.blockchain.GetReceiptsByHash(hash)
 	if receipts == nil {
 		return nil, nil
 	}
@@ -153,6 +175,10 @@ func (b *VntAPIBackend) SubscribeChainSideEvent(ch chan<- core.ChainSideEvent) e
 	return b.vnt.BlockChain().SubscribeChainSideEvent(ch)
 }
 
+func (b *VntAPIBackend) SubscribeChainReorgEvent(ch chan<- core.ChainReorgEvent) event.Subscription {
+	return b.vnt.BlockChain().SubscribeChainReorgEvent(ch)
+}
+
 func (b *VntAPIBackend) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription {
 	return b.vnt.BlockChain().SubscribeLogsEvent(ch)
 }
@@ -161,6 +187,26 @@ func (b *VntAPIBackend) SendTx(ctx context.Context, signedTx *types.Transaction)
 	return b.vnt.txPool.AddLocal(signedTx)
 }
 
+func (b *VntAPIBackend) SendTxs(ctx context.Context, signedTxs []*types.Transaction) []error {
+	return b.vnt.txPool.AddLocals(signedTxs)
+}
+
+func (b *VntAPIBackend) ExtRPCEnabled() bool {
+	return b.extRPCEnabled
+}
+
+func (b *VntAPIBackend) UnprotectedAllowed() bool {
+	return b.vnt.config.AllowUnprotectedTxs
+}
+
+func (b *VntAPIBackend) RPCGasCap() uint64 {
+	return b.vnt.config.RPCGasCap
+}
+
+func (b *VntAPIBackend) RPCEVMTimeout() time.Duration {
+	return b.vnt.config.RPCEVMTimeout
+}
+
 func (b *VntAPIBackend) GetPoolTransactions() (types.Transactions, error) {
 	pending, err := b.vnt.txPool.Pending()
 	if err != nil {
@@ -205,6 +251,9 @@ func (b *VntAPIBackend) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	return b.gpo.SuggestPrice(ctx)
 }
 
+// ChainDb returns the chain database. The read replica is not served from here,
+// as its chain indices (e.g. canonical hashes, transaction lookups) may be stale,
+// it only serves the content addressed state.
 func (b *VntAPIBackend) ChainDb() vntdb.Database {
 	return b.vnt.ChainDb()
 }
@@ -17,12 +17,26 @@
 package vnt
 
 import (
+	"context"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/consensus/mock"
+	"github.com/vntchain/go-vnt/core"
 	"github.com/vntchain/go-vnt/core/state"
+	"github.com/vntchain/go-vnt/core/types"
+	"github.com/vntchain/go-vnt/core/vm"
+	"github.com/vntchain/go-vnt/crypto"
+	"github.com/vntchain/go-vnt/params"
+	"github.com/vntchain/go-vnt/rlp"
+	"github.com/vntchain/go-vnt/rpc"
 	"github.com/vntchain/go-vnt/vntdb"
 )
 
@@ -88,3 +102,111 @@ func TestStorageRangeAt(t *testing.T) {
 		}
 	}
 }
+
+// Tests that storage ranges are served for every transaction of a block, as
+// well as for the final state of it.
+func TestStorageRangeAtBlock(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		account = common.Address{0x01}
+		gspec   = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{
+				address: {Balance: big.NewInt(1000000000)},
+				account: {Balance: big.NewInt(1), Storage: map[common.Hash]common.Hash{{0x01}: {0x02}, {0x03}: {0x04}}},
+			},
+		}
+		signer = types.NewHubbleSigner(gspec.Config.ChainID)
+		db     = vntdb.NewMemDatabase()
+	)
+	genesis := gspec.MustCommit(db)
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, mock.NewMock(), db, 1, func(i int, block *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(address), account, big.NewInt(1000), params.TxGas, nil, nil), signer, key)
+		block.AddTx(tx)
+	})
+	chain, err := core.NewBlockChain(db, nil, gspec.Config, mock.NewMock(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	api := NewPrivateDebugAPI(gspec.Config, &VNT{chainDb: db, blockchain: chain})
+
+	for index := 0; index <= 1; index++ {
+		result, err := api.StorageRangeAt(context.Background(), blocks[0].Hash(), index, account, nil, 1)
+		if err != nil {
+			t.Fatalf("index %d: failed to retrieve storage range: %v", index, err)
+		}
+		if len(result.Storage) != 1 || result.NextKey == nil {
+			t.Errorf("index %d: first page mismatch: have %d entries, next %v, want 1 entry and next key", index, len(result.Storage), result.NextKey)
+			continue
+		}
+		result, err = api.StorageRangeAt(context.Background(), blocks[0].Hash(), index, account, result.NextKey[:], 1)
+		if err != nil {
+			t.Fatalf("index %d: failed to retrieve storage range: %v", index, err)
+		}
+		if len(result.Storage) != 1 || result.NextKey != nil {
+			t.Errorf("index %d: last page mismatch: have %d entries, next %v, want 1 entry and no next key", index, len(result.Storage), result.NextKey)
+		}
+	}
+	if _, err := api.StorageRangeAt(context.Background(), blocks[0].Hash(), 2, account, nil, 1); err == nil {
+		t.Errorf("out of range transaction index accepted")
+	}
+}
+
+// Tests that the state dump of a block is streamed to the requested file as the
+// state root followed by every account.
+func TestDumpBlockRLP(t *testing.T) {
+	var (
+		addr  = common.Address{0x01}
+		gspec = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{addr: {Balance: big.NewInt(1000000000)}},
+		}
+		db = vntdb.NewMemDatabase()
+	)
+	genesis := gspec.MustCommit(db)
+	chain, err := core.NewBlockChain(db, nil, gspec.Config, mock.NewMock(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+	api := NewPrivateDebugAPI(gspec.Config, &VNT{chainDb: db, blockchain: chain})
+
+	dir, err := ioutil.TempDir("", "dump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "state.rlp")
+	if err := api.DumpBlockRLP(rpc.LatestBlockNumber, file); err != nil {
+		t.Fatalf("failed to dump state: %v", err)
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	stream := rlp.NewStream(f, 0)
+	var root common.Hash
+	if err := stream.Decode(&root); err != nil {
+		t.Fatalf("failed to decode state root: %v", err)
+	}
+	if root != genesis.Root() {
+		t.Errorf("state root mismatch: have %x, want %x", root, genesis.Root())
+	}
+	var account state.RLPDumpAccount
+	if err := stream.Decode(&account); err != nil {
+		t.Fatalf("failed to decode account: %v", err)
+	}
+	if account.Address != addr || account.Balance.Cmp(big.NewInt(1000000000)) != 0 {
+		t.Errorf("account mismatch: have %x with %v, want %x with %v", account.Address, account.Balance, addr, 1000000000)
+	}
+	if err := stream.Decode(&account); err != io.EOF {
+		t.Errorf("trailing data mismatch: have %v, want %v", err, io.EOF)
+	}
+}
@@ -95,24 +95,8 @@ type txTraceTask struct {
 // between two blocks (excluding start) and returns them as a JSON object.
 func (api *PrivateDebugAPI) TraceChain(ctx context.Context, start, end rpc.BlockNumber, config *TraceConfig) (*rpc.Subscription, error) {
 	// Fetch the block interval that we want to trace
-	var from, to *types.Block
+	from, to := api.vnt.blockByNumber(start), api.vnt.blockByNumber(end)
 
-	switch start {
-	case rpc.PendingBlockNumber:
-		from = api.vnt.miner.PendingBlock()
-	case rpc.LatestBlockNumber:
-		from = api.vnt.blockchain.CurrentBlock()
-	default:
-		from = api.vnt.blockchain.GetBlockByNumber(uint64(start))
-	}
-	switch end {
-	case rpc.PendingBlockNumber:
-		to = api.vnt.miner.PendingBlock()
-	case rpc.LatestBlockNumber:
-		to = api.vnt.blockchain.CurrentBlock()
-	default:
-		to = api.vnt.blockchain.GetBlockByNumber(uint64(end))
-	}
 	// Trace the chain if we've found all our blocks
 	if from == nil {
 		return nil, fmt.Errorf("starting block #%d not found", start)
@@ -340,16 +324,8 @@ func (api *PrivateDebugAPI) traceChain(ctx context.Context, start, end *types.Bl
 // EVM and returns them as a JSON object.
 func (api *PrivateDebugAPI) TraceBlockByNumber(ctx context.Context, number rpc.BlockNumber, config *TraceConfig) ([]*txTraceResult, error) {
 	// Fetch the block that we want to trace
-	var block *types.Block
+	block := api.vnt.blockByNumber(number)
 
-	switch number {
-	case rpc.PendingBlockNumber:
-		block = api.vnt.miner.PendingBlock()
-	case rpc.LatestBlockNumber:
-		block = api.vnt.blockchain.CurrentBlock()
-	default:
-		block = api.vnt.blockchain.GetBlockByNumber(uint64(number))
-	}
 	// Trace the block if it was found
 	if block == nil {
 		return nil, fmt.Errorf("block #%d not found", number)
@@ -451,7 +427,7 @@ func (api *PrivateDebugAPI) traceBlock(ctx context.Context, block *types.Block,
 		vmctx := core.NewVMContext(msg, block.Header(), api.vnt.blockchain, nil)
 		vmenv := core.GetVM(msg, vmctx, statedb, api.config, vm.Config{})
 		//vmenv := vm.NewEVM(vmctx, statedb, api.config, vm.Config{})
-		if _, _, _, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(msg.Gas())); err != nil {
+		if _, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(msg.Gas())); err != nil {
 			failed = err
 			break
 		}
@@ -555,6 +531,43 @@ func (api *PrivateDebugAPI) TraceTransaction(ctx context.Context, hash common.Ha
 	return api.traceTx(ctx, msg, vmctx, statedb, config)
 }
 
+// TraceCall executes the given call on top of the state of the requested block
+// without committing it and returns the structured logs created during the
+// execution, or the result of the requested JavaScript tracer.
+func (api *PrivateDebugAPI) TraceCall(ctx context.Context, args vntapi.CallArgs, number rpc.BlockNumber, config *TraceConfig) (interface{}, error) {
+	// Retrieve the block and the state to run the call on top of
+	var (
+		block   *types.Block
+		statedb *state.StateDB
+		err     error
+	)
+	if number == rpc.PendingBlockNumber {
+		block, statedb = api.vnt.miner.Pending()
+	} else {
+		if block = api.vnt.blockByNumber(number); block == nil {
+			return nil, fmt.Errorf("block #%d not found", number)
+		}
+		reexec := defaultTraceReexec
+		if config != nil && config.Reexec != nil {
+			reexec = *config.Reexec
+		}
+		statedb, err = api.computeStateDB(block, reexec)
+	}
+	if block == nil || statedb == nil || err != nil {
+		return nil, fmt.Errorf("state of block #%d not available: %v", number, err)
+	}
+	// Assemble the call message, leaving it unpriced unless requested so that
+	// arbitrary senders can be traced
+	gas := uint64(args.Gas)
+	if gas == 0 {
+		gas = block.GasLimit()
+	}
+	msg := types.NewMessage(args.From, args.To, 0, args.Value.ToInt(), gas, args.GasPrice.ToInt(), args.Data, false)
+	vmctx := core.NewVMContext(msg, block.Header(), api.vnt.blockchain, nil)
+
+	return api.traceTx(ctx, msg, vmctx, statedb, config)
+}
+
 // traceTx configures a new tracer according to the provided configuration, and
 // executes the given message in the provided environment. The return value will
 // be tracer dependent.
@@ -595,7 +608,7 @@ func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, v
 	vmenv := core.GetVM(message, vmctx, statedb, api.config, vm.Config{Debug: true, Tracer: tracer})
 	//vmenv := vm.NewEVM(vmctx, statedb, api.config, vm.Config{Debug: true, Tracer: tracer})
 
-	ret, gas, failed, err := core.ApplyMessage(vmenv, message, new(core.GasPool).AddGas(message.Gas()))
+	result, err := core.ApplyMessage(vmenv, message, new(core.GasPool).AddGas(message.Gas()))
 	if err != nil {
 		return nil, fmt.Errorf("tracing failed: %v", err)
 	}
@@ -603,13 +616,17 @@ func (api *PrivateDebugAPI) traceTx(ctx context.Context, message core.Message, v
 	switch tracer := tracer.(type) {
 	case *wavm.WasmLogger:
 		slogs, dlogs := vntapi.FormatLogs(tracer.StructLogs(), tracer.DebugLogs())
-		return &vntapi.ExecutionResult{
-			Gas:         gas,
-			Failed:      failed,
-			ReturnValue: fmt.Sprintf("%x", ret),
+		res := &vntapi.ExecutionResult{
+			Gas:         result.UsedGas,
+			Failed:      result.Failed(),
+			ReturnValue: fmt.Sprintf("%x", result.ReturnData),
 			StructLogs:  slogs,
 			DebugLogs:   dlogs,
-		}, nil
+		}
+		if revert := result.Revert(); len(revert) > 0 {
+			res.RevertReason = vntapi.RevertReason(revert)
+		}
+		return res, nil
 
 	case *tracers.Tracer:
 		return tracer.GetResult()
@@ -647,7 +664,7 @@ func (api *PrivateDebugAPI) computeTxEnv(blockHash common.Hash, txIndex int, ree
 		// Not yet the searched for transaction, execute on top of the current state
 		vmenv := core.GetVM(msg, context, statedb, api.config, vm.Config{})
 		//vmenv := vm.NewEVM(context, statedb, api.config, vm.Config{})
-		if _, _, _, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(tx.Gas())); err != nil {
+		if _, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(tx.Gas())); err != nil {
 			return nil, vm.Context{}, nil, fmt.Errorf("tx %x failed: %v", tx.Hash(), err)
 		}
 		// Ensure any modifications are committed to the state
@@ -0,0 +1,109 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vnt
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/common/hexutil"
+	"github.com/vntchain/go-vnt/consensus/mock"
+	"github.com/vntchain/go-vnt/core"
+	"github.com/vntchain/go-vnt/core/types"
+	"github.com/vntchain/go-vnt/core/vm"
+	"github.com/vntchain/go-vnt/crypto"
+	"github.com/vntchain/go-vnt/internal/vntapi"
+	"github.com/vntchain/go-vnt/params"
+	"github.com/vntchain/go-vnt/rpc"
+	"github.com/vntchain/go-vnt/vntdb"
+)
+
+// Tests that calls are traced on top of the state of the requested block, with
+// the struct logger as well as with JavaScript tracers.
+func TestTraceCall(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		account = common.Address{0x01}
+		gspec   = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{address: {Balance: big.NewInt(1000000000)}},
+		}
+		signer = types.NewHubbleSigner(gspec.Config.ChainID)
+		db     = vntdb.NewMemDatabase()
+	)
+	// Fund the traced account in the first block only
+	genesis := gspec.MustCommit(db)
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, mock.NewMock(), db, 1, func(i int, block *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(address), account, big.NewInt(1000), params.TxGas, nil, nil), signer, key)
+		block.AddTx(tx)
+	})
+	chain, err := core.NewBlockChain(db, nil, gspec.Config, mock.NewMock(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	api := NewPrivateDebugAPI(gspec.Config, &VNT{chainDb: db, blockchain: chain})
+
+	to := common.Address{0x02}
+	args := vntapi.CallArgs{From: account, To: &to, Value: hexutil.Big(*big.NewInt(500))}
+
+	// The struct logger traces the call on top of the latest state
+	result, err := api.TraceCall(context.Background(), args, rpc.LatestBlockNumber, nil)
+	if err != nil {
+		t.Fatalf("failed to trace call: %v", err)
+	}
+	exec, ok := result.(*vntapi.ExecutionResult)
+	if !ok {
+		t.Fatalf("struct logger result type mismatch: have %T, want %T", result, exec)
+	}
+	if exec.Failed || exec.Gas != params.TxGas {
+		t.Errorf("struct logger result mismatch: have failed %v, gas %d, want success with gas %d", exec.Failed, exec.Gas, params.TxGas)
+	}
+	// The account is not funded yet in the state of the genesis block
+	result, err = api.TraceCall(context.Background(), args, rpc.BlockNumber(0), nil)
+	if err != nil {
+		t.Fatalf("failed to trace call on genesis: %v", err)
+	}
+	if exec := result.(*vntapi.ExecutionResult); !exec.Failed {
+		t.Errorf("unfunded transfer succeeded on top of genesis")
+	}
+	// A JavaScript tracer returns its own result
+	tracer := `{step: function() {}, fault: function() {}, result: function(ctx) { return ctx.type + " " + ctx.value.toString(); }}`
+	result, err = api.TraceCall(context.Background(), args, rpc.BlockNumber(1), &TraceConfig{Tracer: &tracer})
+	if err != nil {
+		t.Fatalf("failed to trace call with javascript tracer: %v", err)
+	}
+	raw, ok := result.(json.RawMessage)
+	if !ok {
+		t.Fatalf("javascript tracer result type mismatch: have %T, want %T", result, raw)
+	}
+	if have, want := string(raw), `"CALL 500"`; have != want {
+		t.Errorf("javascript tracer result mismatch: have %s, want %s", have, want)
+	}
+	// Calls on top of unknown blocks are rejected
+	if _, err := api.TraceCall(context.Background(), args, rpc.BlockNumber(2), nil); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("unknown block error mismatch: have %v, want block not found", err)
+	}
+}
@@ -33,6 +33,7 @@ import (
 	"github.com/vntchain/go-vnt/core"
 	"github.com/vntchain/go-vnt/core/bloombits"
 	"github.com/vntchain/go-vnt/core/rawdb"
+	"github.com/vntchain/go-vnt/core/state"
 	"github.com/vntchain/go-vnt/core/types"
 	"github.com/vntchain/go-vnt/core/vm"
 	"github.com/vntchain/go-vnt/event"
@@ -55,6 +56,7 @@ type LesServer interface {
 	Stop()
 	Protocols() []vntp2p.Protocol
 	SetBloomBitsIndexer(bbIndexer *core.ChainIndexer)
+	APIs() []rpc.API
 }
 
 // VNT implements the VNT full node service.
@@ -72,7 +74,10 @@ type VNT struct {
 	lesServer       LesServer
 
 	// DB interfaces
-	chainDb vntdb.Database // Block chain database
+	chainDb      vntdb.Database         // Block chain database
+	replica      *vntdb.ReplicaDatabase // Read replica backed database serving RPC state reads (nil = chainDb)
+	replicaState state.Database         // State access through the read replica
+	beamState    state.Database         // State access fetching missing entries from peers (nil = disabled)
 
 	eventMux       *event.TypeMux
 	engine         consensus.Engine
@@ -87,6 +92,11 @@ type VNT struct {
 	gasPrice *big.Int
 	coinbase common.Address
 
+	standby      *witnessStandby // Watcher of the primary node on a standby witness, nil if not standing by
+	producerLock *producerLock   // Lock shared by the nodes of the witness, nil if unset
+
+	pruneScheduler *pruneScheduler // Scheduler of the state prunes, nil if not scheduled
+
 	networkId     uint64
 	netRPCService *vntapi.PublicNetAPI
 
@@ -131,6 +141,12 @@ func New(ctx *node.ServiceContext, config *Config, node *node.Node) (*VNT, error
 		bloomRequests:  make(chan chan *bloombits.Retrieval),
 		bloomIndexer:   NewBloomIndexer(chainDb, params.BloomBitsBlocks),
 	}
+	if config.ProducerLock != "" {
+		vnt.producerLock = &producerLock{path: ctx.ResolvePath(config.ProducerLock)}
+	}
+	if engine, ok := vnt.engine.(*dpos.Dpos); ok {
+		engine.SetTiming(config.DposTiming)
+	}
 
 	log.Info("Initialising VNT protocol", "versions", ProtocolVersions, "network", config.NetworkId)
 
@@ -142,8 +158,8 @@ func New(ctx *node.ServiceContext, config *Config, node *node.Node) (*VNT, error
 		rawdb.WriteDatabaseVersion(chainDb, core.BlockChainVersion)
 	}
 	var (
-		vmConfig    = vm.Config{EnablePreimageRecording: config.EnablePreimageRecording}
-		cacheConfig = &core.CacheConfig{Disabled: config.NoPruning, TrieNodeLimit: config.TrieCache, TrieTimeLimit: config.TrieTimeout}
+		vmConfig    = vm.Config{EnablePreimageRecording: config.EnablePreimageRecording, MemoryCap: config.VMMemoryCap}
+		cacheConfig = &core.CacheConfig{Disabled: config.NoPruning, TrieNodeLimit: config.TrieCache, TrieTimeLimit: config.TrieTimeout, SnapshotLimit: config.SnapshotCache, TxLookupLimit: config.TxLookupLimit, StateHistory: config.StateHistory, TriePrefetchLimit: config.TriePrefetchCache, BlockCacheLimit: config.BlockCache, ReceiptCacheLimit: config.ReceiptCache, SenderCacheLimit: config.SenderCache, NoPreimages: config.NoPreimages}
 	)
 	vnt.blockchain, err = core.NewBlockChain(chainDb, cacheConfig, vnt.chainConfig, vnt.engine, vmConfig)
 	if err != nil {
@@ -157,6 +173,21 @@ func New(ctx *node.ServiceContext, config *Config, node *node.Node) (*VNT, error
 	}
 	vnt.bloomIndexer.Start(vnt.blockchain)
 
+	if config.PruneSchedule != "" && !config.NoPruning {
+		schedule, err := ParsePruneSchedule(config.PruneSchedule)
+		if err != nil {
+			return nil, err
+		}
+		vnt.pruneScheduler = newPruneScheduler(vnt.blockchain, schedule, config.PruneBloomSize*1024*1024, log.Root())
+	}
+	if config.ReadReplica != "" {
+		if vnt.replica, err = openReadReplica(ctx, config, chainDb); err != nil {
+			return nil, err
+		}
+		vnt.replicaState = state.NewDatabase(vnt.replica)
+		go vnt.replicaLagLoop(vnt.replica.Replica())
+	}
+
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = ctx.ResolvePath(config.TxPool.Journal)
 	}
@@ -165,10 +196,20 @@ func New(ctx *node.ServiceContext, config *Config, node *node.Node) (*VNT, error
 	if vnt.protocolManager, err = NewProtocolManager(vnt.chainConfig, config.SyncMode, config.NetworkId, vnt.eventMux, vnt.txPool, vnt.engine, vnt.blockchain, chainDb, node); err != nil {
 		return nil, err
 	}
+	vnt.protocolManager.downloader.SetStateWorkers(config.StateWorkers)
+	if engine, ok := vnt.engine.(*dpos.Dpos); ok {
+		downloader := vnt.protocolManager.downloader
+		engine.SetSyncStatus(func() bool { return !downloader.Synchronising() })
+	}
+	if config.RPCBeamReads {
+		vnt.protocolManager.beam = newBeamFetcher(vnt.protocolManager.peers, vnt.shutdownChan)
+		vnt.beamState = state.NewDatabase(newBeamDatabase(chainDb, vnt.protocolManager.beam))
+		log.Info("Fetching missing state of RPC reads from peers")
+	}
 	vnt.miner = miner.New(vnt, vnt.chainConfig, vnt.EventMux(), vnt.engine)
 	vnt.miner.SetExtra(makeExtraData(config.ExtraData))
 
-	vnt.APIBackend = &VntAPIBackend{vnt, nil}
+	vnt.APIBackend = &VntAPIBackend{ctx.ExtRPCEnabled(), vnt, nil}
 	gpoParams := config.GPO
 	if gpoParams.Default == nil {
 		gpoParams.Default = config.GasPrice
@@ -198,13 +239,18 @@ func makeExtraData(extra []byte) []byte {
 // CreateDB creates the chain database.
 func CreateDB(ctx *node.ServiceContext, config *Config, name string) (vntdb.Database, error) {
 	log.Debug("backend", "func", "CreateDB", "ctx", ctx, "config", config, "name", name)
-	db, err := ctx.OpenDatabase(name, config.DatabaseCache, config.DatabaseHandles)
+	db, err := ctx.OpenDatabaseWithFreezer(name, config.DatabaseCache, config.DatabaseHandles, config.DatabaseFreezer)
 	if err != nil {
 		return nil, err
 	}
-	if db, ok := db.(*vntdb.LDBDatabase); ok {
-		db.Meter("vnt/db/chaindata/")
+	kvdb := db
+	if frdb, ok := db.(*rawdb.FreezerDatabase); ok {
+		kvdb = frdb.KeyValueStore()
+	}
+	if kvdb, ok := kvdb.(*vntdb.LDBDatabase); ok {
+		kvdb.Meter("vnt/db/chaindata/")
 	}
+	vntdb.ReportStats(kvdb, "vnt/db/chaindata/stats/", config.DatabaseStats)
 	return db, nil
 }
 
@@ -232,8 +278,8 @@ func (s *VNT) APIs() []rpc.API {
 	// Append any APIs exposed explicitly by the consensus engine
 	apis = append(apis, s.engine.APIs(s.BlockChain())...)
 
-	// Append all the local APIs and return
-	return append(apis, []rpc.API{
+	// Append all the local APIs
+	apis = append(apis, []rpc.API{
 		{
 			Namespace: "core",
 			Version:   "1.0",
@@ -279,6 +325,12 @@ func (s *VNT) APIs() []rpc.API {
 			Public:    true,
 		},
 	}...)
+
+	// Append the APIs of the light server if it is enabled and return
+	if s.lesServer != nil {
+		apis = append(apis, s.lesServer.APIs()...)
+	}
+	return apis
 }
 
 func (s *VNT) ResetWithGenesisBlock(gb *types.Block) {
@@ -317,21 +369,64 @@ func (s *VNT) SetCoinbase(coinbase common.Address) {
 	s.miner.SetCoinbase(coinbase)
 }
 
+// Signer returns the account used to sign produced blocks, falling back to the
+// given coinbase if no dedicated witness signing account was configured. Being
+// the witness, the signer is also the account the blocks are credited to.
+func (s *VNT) Signer(coinbase common.Address) common.Address {
+	if s.config.Signer != (common.Address{}) {
+		return s.config.Signer
+	}
+	return coinbase
+}
+
+// StartProducing starts producing blocks with the coinbase, or, on a standby
+// witness node, starts watching for the primary node to go down.
 func (s *VNT) StartProducing(local bool) error {
 	eb, err := s.Coinbase()
 	if err != nil {
 		log.Error("Cannot start block producing without coinbase", "err", err)
 		return fmt.Errorf("coinbase missing: %v", err)
 	}
+	signer := s.Signer(eb)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
 
-	if dpos, ok := s.engine.(*dpos.Dpos); ok {
-		wallet, err := s.accountManager.Find(accounts.Account{Address: eb})
+	engine, ok := s.engine.(*dpos.Dpos)
+	if ok {
+		wallet, err := s.accountManager.Find(accounts.Account{Address: signer})
 		if wallet == nil || err != nil {
-			log.Error("Coinbase account unavailable locally", "err", err)
+			log.Error("Signer account unavailable locally", "signer", signer, "err", err)
 			return fmt.Errorf("signer missing: %v", err)
 		}
-		dpos.Authorize(eb, wallet.SignHash)
+		engine.Authorize(signer, wallet.SignHash)
+
+		// Dpos credits blocks to the signing witness, produce them for it
+		eb = signer
+	}
+	if s.config.Standby {
+		if !ok {
+			return errors.New("standby witness requires the dpos engine")
+		}
+		if s.producerLock == nil {
+			return errors.New("standby witness requires a producer lock")
+		}
+		if s.standby == nil {
+			s.standby = newWitnessStandby(s, engine, signer, local)
+			go s.standby.loop()
+		}
+		return nil
 	}
+	if err := s.producerLock.acquire(); err != nil {
+		log.Error("Another node is producing for this witness", "err", err)
+		return err
+	}
+	s.startProducing(eb, local)
+	return nil
+}
+
+// startProducing starts the miner with the given coinbase.
+func (s *VNT) startProducing(coinbase common.Address, local bool) {
 	if local {
 		// If local (CPU) block producing is started, we can disable the transaction rejection
 		// mechanism introduced to speed sync times. CPU block producing on mainnet is ludicrous
@@ -339,11 +434,25 @@ func (s *VNT) StartProducing(local bool) error {
 		// will ensure that private networks work in single miner mode too.
 		atomic.StoreUint32(&s.protocolManager.acceptTxs, 1)
 	}
-	go s.miner.Start(eb)
-	return nil
+	go s.miner.Start(coinbase)
+}
+
+// StopProducing stops producing blocks, and watching the primary node on a
+// standby witness node.
+func (s *VNT) StopProducing() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.standby != nil {
+		// The standby stops producing by itself if it took over
+		s.standby.stop()
+		s.standby = nil
+		return
+	}
+	s.miner.Stop()
+	s.producerLock.unlock()
 }
 
-func (s *VNT) StopProducing()      { s.miner.Stop() }
 func (s *VNT) IsProducing() bool   { return s.miner.Producing() }
 func (s *VNT) Miner() *miner.Miner { return s.miner }
 
@@ -390,12 +499,18 @@ func (s *VNT) Start(srvr *vntp2p.Server) error {
 	if s.lesServer != nil {
 		s.lesServer.Start(srvr)
 	}
+	if s.pruneScheduler != nil {
+		s.pruneScheduler.Start()
+	}
 	return nil
 }
 
 // Stop implements node.Service, terminating all internal goroutines used by the
 // VNT protocol.
 func (s *VNT) Stop() error {
+	if s.pruneScheduler != nil {
+		s.pruneScheduler.Stop()
+	}
 	s.bloomIndexer.Close()
 	s.blockchain.Stop()
 	s.protocolManager.Stop()
@@ -403,9 +518,12 @@ func (s *VNT) Stop() error {
 		s.lesServer.Stop()
 	}
 	s.txPool.Stop()
-	s.miner.Stop()
+	s.StopProducing()
 	s.eventMux.Stop()
 
+	if s.replica != nil {
+		s.replica.Close()
+	}
 	s.chainDb.Close()
 	close(s.shutdownChan)
 
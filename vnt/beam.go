@@ -0,0 +1,239 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package vnt
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru"
+	libp2p "github.com/libp2p/go-libp2p-peer"
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/crypto"
+	"github.com/vntchain/go-vnt/log"
+	"github.com/vntchain/go-vnt/vntdb"
+)
+
+const (
+	beamFetchTimeout  = 3 * time.Second // Time a peer is given to deliver a requested state entry
+	beamFetchAttempts = 3               // Number of peers asked for a state entry before giving up
+	beamCacheItems    = 16384           // Number of fetched state entries kept in memory
+)
+
+var (
+	errBeamStopped     = errors.New("beam fetcher stopped")
+	errBeamUnrequested = errors.New("unrequested beam response")
+)
+
+// beamRequest is a state entry requested from a peer by the beam fetcher.
+type beamRequest struct {
+	peer libp2p.ID   // Peer the entry was requested from
+	hash common.Hash // Hash of the requested entry
+}
+
+// beamFetcher retrieves state trie nodes and contract codes missing from the
+// local database on demand from the connected peers, so that RPC reads of
+// recent state can be served before the state sync completes.
+//
+// The entries are requested with dedicated messages carrying a request id, so
+// the responses are never confused with the node data of the downloader.
+type beamFetcher struct {
+	peers    *peerSet
+	pending  map[common.Hash][]chan []byte // Requested entries with their waiting readers
+	requests map[uint64]beamRequest        // Requests not yet answered, by request id
+	nextID   uint64                        // Id of the next request
+	lock     sync.Mutex
+	quit     chan bool
+}
+
+// newBeamFetcher creates a fetcher requesting state from the given peers until
+// quit is closed.
+func newBeamFetcher(peers *peerSet, quit chan bool) *beamFetcher {
+	return &beamFetcher{
+		peers:    peers,
+		pending:  make(map[common.Hash][]chan []byte),
+		requests: make(map[uint64]beamRequest),
+		quit:     quit,
+	}
+}
+
+// fetch retrieves the state entry of the given hash, asking the best peers in
+// turn until one of them delivers it.
+func (f *beamFetcher) fetch(hash common.Hash) ([]byte, error) {
+	ch := f.watch(hash)
+	defer f.forget(hash, ch)
+
+	asked := make(map[libp2p.ID]bool)
+	for i := 0; i < beamFetchAttempts; i++ {
+		p := f.peers.BestBeamPeer(asked)
+		if p == nil {
+			break
+		}
+		asked[p.id] = true
+		id := f.request(p.id, hash)
+		if err := p.RequestBeamNodeData(id, []common.Hash{hash}); err != nil {
+			f.cancel(id)
+			continue
+		}
+		timer := time.NewTimer(beamFetchTimeout)
+		select {
+		case blob := <-ch:
+			timer.Stop()
+			return blob, nil
+		case <-timer.C:
+			log.Debug("Beam state fetch timed out", "hash", hash, "peer", p.id)
+			f.cancel(id)
+		case <-f.quit:
+			timer.Stop()
+			return nil, errBeamStopped
+		}
+	}
+	return nil, fmt.Errorf("state entry %x unavailable from %d peers", hash, len(asked))
+}
+
+// watch registers a reader waiting for the state entry of a hash.
+func (f *beamFetcher) watch(hash common.Hash) chan []byte {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	ch := make(chan []byte, 1)
+	f.pending[hash] = append(f.pending[hash], ch)
+	return ch
+}
+
+// forget unregisters a reader of a state entry which is no longer waiting.
+func (f *beamFetcher) forget(hash common.Hash, ch chan []byte) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	waiters := f.pending[hash]
+	for i, waiter := range waiters {
+		if waiter == ch {
+			waiters = append(waiters[:i], waiters[i+1:]...)
+			break
+		}
+	}
+	if len(waiters) == 0 {
+		delete(f.pending, hash)
+	} else {
+		f.pending[hash] = waiters
+	}
+}
+
+// request records a state entry requested from a peer, returning the id the
+// request is sent with.
+func (f *beamFetcher) request(peer libp2p.ID, hash common.Hash) uint64 {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	id := f.nextID
+	f.nextID++
+	f.requests[id] = beamRequest{peer: peer, hash: hash}
+	return id
+}
+
+// cancel forgets a request which is no longer waited for, so a late answer to
+// it is dropped.
+func (f *beamFetcher) cancel(id uint64) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	delete(f.requests, id)
+}
+
+// dropPeer forgets the requests to a peer which won't answer them anymore.
+func (f *beamFetcher) dropPeer(peer libp2p.ID) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	for id, req := range f.requests {
+		if req.peer == peer {
+			delete(f.requests, id)
+		}
+	}
+}
+
+// deliver hands the answer of a peer to the request of the given id over to the
+// readers waiting for the requested entry. Answers to requests not made to the
+// peer, or not waited for anymore, are rejected.
+func (f *beamFetcher) deliver(peer libp2p.ID, id uint64, data [][]byte) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	req, ok := f.requests[id]
+	if !ok || req.peer != peer {
+		return errBeamUnrequested
+	}
+	delete(f.requests, id)
+
+	for _, blob := range data {
+		if crypto.Keccak256Hash(blob) != req.hash {
+			continue
+		}
+		for _, ch := range f.pending[req.hash] {
+			ch <- blob
+		}
+		delete(f.pending, req.hash)
+		break
+	}
+	return nil
+}
+
+// beamDatabase is a view of the chain database falling back to the beam fetcher
+// for missing entries keyed by hash, which are the state trie nodes and the
+// contract codes. Fetched entries are cached in memory but never persisted, as
+// the state sync takes the subtries of stored nodes to be complete.
+type beamDatabase struct {
+	vntdb.Database
+	fetcher *beamFetcher
+	cache   *lru.Cache
+}
+
+// newBeamDatabase wraps the chain database to fetch missing state from peers.
+func newBeamDatabase(db vntdb.Database, fetcher *beamFetcher) *beamDatabase {
+	cache, _ := lru.New(beamCacheItems)
+	return &beamDatabase{Database: db, fetcher: fetcher, cache: cache}
+}
+
+// Get retrieves the given key from the chain database, fetching it from the
+// peers if it's a missing state entry.
+func (db *beamDatabase) Get(key []byte) ([]byte, error) {
+	blob, err := db.Database.Get(key)
+	if err == nil || len(key) != common.HashLength {
+		return blob, err
+	}
+	hash := common.BytesToHash(key)
+	if cached, ok := db.cache.Get(hash); ok {
+		return cached.([]byte), nil
+	}
+	if blob, err = db.fetcher.fetch(hash); err != nil {
+		return nil, err
+	}
+	db.cache.Add(hash, blob)
+	return blob, nil
+}
+
+// Has checks whether the given key is in the chain database or among the state
+// entries already fetched.
+func (db *beamDatabase) Has(key []byte) (bool, error) {
+	if len(key) == common.HashLength && db.cache.Contains(common.BytesToHash(key)) {
+		return true, nil
+	}
+	return db.Database.Has(key)
+}
@@ -0,0 +1,259 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package vnt
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/crypto"
+	"github.com/vntchain/go-vnt/event"
+	"github.com/vntchain/go-vnt/vnt/downloader"
+	"github.com/vntchain/go-vnt/vntdb"
+	"github.com/vntchain/go-vnt/vntp2p"
+)
+
+// Tests that the answer to a request is handed to the readers waiting for the
+// requested entry, and only if it comes from the requested peer.
+func TestBeamFetcherDeliver(t *testing.T) {
+	fetcher := newBeamFetcher(newPeerSet(), make(chan bool))
+
+	wanted, unwanted := []byte("wanted"), []byte("unwanted")
+	hash := crypto.Keccak256Hash(wanted)
+	first, second := fetcher.watch(hash), fetcher.watch(hash)
+
+	id := fetcher.request("peer", hash)
+	if err := fetcher.deliver("other", id, [][]byte{wanted}); err != errBeamUnrequested {
+		t.Fatalf("other peer answer mismatch: have %v, want %v", err, errBeamUnrequested)
+	}
+	if err := fetcher.deliver("peer", id+1, [][]byte{wanted}); err != errBeamUnrequested {
+		t.Fatalf("unknown request answer mismatch: have %v, want %v", err, errBeamUnrequested)
+	}
+	if err := fetcher.deliver("peer", id, [][]byte{unwanted, wanted}); err != nil {
+		t.Fatalf("failed to deliver answer: %v", err)
+	}
+	for i, ch := range []chan []byte{first, second} {
+		select {
+		case blob := <-ch:
+			if !bytes.Equal(blob, wanted) {
+				t.Errorf("reader %d: entry mismatch: have %q, want %q", i, blob, wanted)
+			}
+		default:
+			t.Errorf("reader %d: entry not delivered", i)
+		}
+	}
+	if len(fetcher.pending) != 0 {
+		t.Errorf("pending entries mismatch: have %d, want 0", len(fetcher.pending))
+	}
+	// Requests are answered only once
+	if err := fetcher.deliver("peer", id, [][]byte{wanted}); err != errBeamUnrequested {
+		t.Errorf("repeated answer mismatch: have %v, want %v", err, errBeamUnrequested)
+	}
+}
+
+// Tests that answers to requests given up on, or sent to dropped peers, are
+// rejected.
+func TestBeamFetcherResponses(t *testing.T) {
+	fetcher := newBeamFetcher(newPeerSet(), make(chan bool))
+
+	stale, missing := []byte("stale"), []byte("missing")
+	ch := fetcher.watch(crypto.Keccak256Hash(stale))
+
+	// A late answer is not delivered once the request was given up on
+	id := fetcher.request("peer", crypto.Keccak256Hash(stale))
+	fetcher.cancel(id)
+	if err := fetcher.deliver("peer", id, [][]byte{stale}); err != errBeamUnrequested {
+		t.Fatalf("stale answer mismatch: have %v, want %v", err, errBeamUnrequested)
+	}
+	select {
+	case <-ch:
+		t.Fatalf("stale answer delivered")
+	default:
+	}
+	// An empty answer is accepted, leaving the readers waiting
+	id = fetcher.request("peer", crypto.Keccak256Hash(stale))
+	if err := fetcher.deliver("peer", id, nil); err != nil {
+		t.Fatalf("empty answer rejected: %v", err)
+	}
+	if len(fetcher.pending) != 1 {
+		t.Fatalf("pending entries mismatch: have %d, want 1", len(fetcher.pending))
+	}
+	// Requests to dropped peers are forgotten, the others kept
+	dropped := fetcher.request("peer", crypto.Keccak256Hash(missing))
+	kept := fetcher.request("other", crypto.Keccak256Hash(missing))
+	fetcher.dropPeer("peer")
+	if err := fetcher.deliver("peer", dropped, nil); err != errBeamUnrequested {
+		t.Errorf("dropped peer answer mismatch: have %v, want %v", err, errBeamUnrequested)
+	}
+	if err := fetcher.deliver("other", kept, nil); err != nil {
+		t.Errorf("remaining peer answer rejected: %v", err)
+	}
+}
+
+// beamTestRW is a message pipe to a fake remote peer.
+type beamTestRW struct {
+	in  chan vntp2p.Msg // Messages sent by the remote peer
+	out chan vntp2p.Msg // Messages sent to the remote peer
+}
+
+func (rw *beamTestRW) ReadMsg() (vntp2p.Msg, error)  { return <-rw.in, nil }
+func (rw *beamTestRW) WriteMsg(msg vntp2p.Msg) error { rw.out <- msg; return nil }
+
+// beamTestReply is a message sent by the fake remote peer.
+type beamTestReply chan vntp2p.Msg
+
+func (ch beamTestReply) WriteMsg(msg vntp2p.Msg) error { ch <- msg; return nil }
+
+// Tests that state requested concurrently by the beam fetcher and the downloader
+// from the same peer is delivered to the requester of each, whatever the order
+// the answers arrive in.
+func TestBeamFetcherConcurrentDownload(t *testing.T) {
+	rw := &beamTestRW{in: make(chan vntp2p.Msg, 1), out: make(chan vntp2p.Msg, 3)}
+	p := &peer{id: "peer", version: vnt64, rw: rw, td: big.NewInt(1)}
+
+	peers := newPeerSet()
+	peers.peers[p.id] = p
+
+	quit := make(chan bool)
+	defer close(quit)
+
+	pm := &ProtocolManager{
+		peers:      peers,
+		beam:       newBeamFetcher(peers, quit),
+		downloader: downloader.New(downloader.FullSync, vntdb.NewMemDatabase(), new(event.TypeMux), nil, nil, nil),
+	}
+	defer pm.downloader.Terminate()
+
+	// Fetch two entries through the beam fetcher while the downloader syncs them
+	blobs := [][]byte{[]byte("first"), []byte("second")}
+	hashes := []common.Hash{crypto.Keccak256Hash(blobs[0]), crypto.Keccak256Hash(blobs[1])}
+
+	results := make([]chan []byte, len(blobs))
+	for i, hash := range hashes {
+		results[i] = make(chan []byte, 1)
+		go func(hash common.Hash, res chan []byte) {
+			blob, err := pm.beam.fetch(hash)
+			if err != nil {
+				t.Errorf("failed to fetch %x: %v", hash, err)
+			}
+			res <- blob
+		}(hash, results[i])
+	}
+	if err := vntp2p.Send(p.rw, ProtocolName, GetNodeDataMsg, hashes); err != nil {
+		t.Fatalf("failed to request node data: %v", err)
+	}
+	// Collect the requests reaching the remote peer
+	ids := make(map[common.Hash]uint64)
+	for i := 0; i < 3; i++ {
+		select {
+		case msg := <-rw.out:
+			switch msg.Body.Type {
+			case GetNodeDataMsg:
+			case GetBeamNodeDataMsg:
+				var query getBeamNodeDataData
+				if err := msg.Decode(&query); err != nil {
+					t.Fatalf("failed to decode beam request: %v", err)
+				}
+				if len(query.Hashes) != 1 {
+					t.Fatalf("beam request size mismatch: have %d, want 1", len(query.Hashes))
+				}
+				ids[query.Hashes[0]] = query.ID
+			default:
+				t.Fatalf("unexpected request: %v", msg.Body.Type)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("request %d not sent", i)
+		}
+	}
+	if len(ids) != len(hashes) {
+		t.Fatalf("beam requests mismatch: have %d, want %d", len(ids), len(hashes))
+	}
+	reply := func(code vntp2p.MessageType, data interface{}) {
+		if err := vntp2p.Send(beamTestReply(rw.in), ProtocolName, code, data); err != nil {
+			t.Fatalf("failed to send reply: %v", err)
+		}
+		if err := pm.handleMsg(p); err != nil {
+			t.Fatalf("failed to handle reply: %v", err)
+		}
+	}
+	// The node data of the downloader are not taken for the beam requests
+	reply(NodeDataMsg, blobs)
+	time.Sleep(50 * time.Millisecond)
+	for i, res := range results {
+		select {
+		case <-res:
+			t.Fatalf("fetch %d satisfied by downloader response", i)
+		default:
+		}
+	}
+	// Beam answers are delivered by id, unknown ones dropped
+	unknown := ids[hashes[0]] + ids[hashes[1]] + 1
+	reply(BeamNodeDataMsg, &beamNodeDataData{ID: unknown, Data: blobs})
+	reply(BeamNodeDataMsg, &beamNodeDataData{ID: ids[hashes[1]], Data: blobs[1:]})
+	reply(BeamNodeDataMsg, &beamNodeDataData{ID: ids[hashes[0]], Data: blobs[:1]})
+
+	for i, res := range results {
+		select {
+		case blob := <-res:
+			if !bytes.Equal(blob, blobs[i]) {
+				t.Errorf("fetch %d: entry mismatch: have %q, want %q", i, blob, blobs[i])
+			}
+		case <-time.After(time.Second):
+			t.Errorf("fetch %d: entry not delivered", i)
+		}
+	}
+	// Late answers are dropped without failing the peer
+	reply(BeamNodeDataMsg, &beamNodeDataData{ID: ids[hashes[0]], Data: blobs[:1]})
+	if len(pm.beam.requests) != 0 {
+		t.Errorf("requests left mismatch: have %d, want 0", len(pm.beam.requests))
+	}
+}
+
+// Tests that the beam database serves local and cached entries, only fetching
+// missing state entries from peers.
+func TestBeamDatabase(t *testing.T) {
+	chainDb := vntdb.NewMemDatabase()
+	db := newBeamDatabase(chainDb, newBeamFetcher(newPeerSet(), make(chan bool)))
+
+	local := []byte("local")
+	chainDb.Put(crypto.Keccak256(local), local)
+	if blob, err := db.Get(crypto.Keccak256(local)); err != nil || !bytes.Equal(blob, local) {
+		t.Errorf("local entry mismatch: have %q, %v, want %q", blob, err, local)
+	}
+	// Missing entries fail without peers, unless fetched before
+	fetched := []byte("fetched")
+	if _, err := db.Get(crypto.Keccak256(fetched)); err == nil {
+		t.Errorf("missing entry retrieved without peers")
+	}
+	db.cache.Add(crypto.Keccak256Hash(fetched), fetched)
+	if blob, err := db.Get(crypto.Keccak256(fetched)); err != nil || !bytes.Equal(blob, fetched) {
+		t.Errorf("fetched entry mismatch: have %q, %v, want %q", blob, err, fetched)
+	}
+	if has, _ := db.Has(crypto.Keccak256(fetched)); !has {
+		t.Errorf("fetched entry not reported present")
+	}
+	if ok, _ := chainDb.Has(crypto.Keccak256(fetched)); ok {
+		t.Errorf("fetched entry persisted into the chain database")
+	}
+	// Other keys are never fetched
+	if _, err := db.Get([]byte("key")); err == nil {
+		t.Errorf("missing non-state key retrieved")
+	}
+}
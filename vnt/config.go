@@ -24,6 +24,7 @@ import (
 
 	"github.com/vntchain/go-vnt/common"
 	"github.com/vntchain/go-vnt/common/hexutil"
+	"github.com/vntchain/go-vnt/consensus/dpos"
 	"github.com/vntchain/go-vnt/core"
 	"github.com/vntchain/go-vnt/params"
 	"github.com/vntchain/go-vnt/vnt/downloader"
@@ -39,11 +40,19 @@ var DefaultConfig = Config{
 	TrieCache:     256,
 	TrieTimeout:   60 * time.Minute,
 	GasPrice:      big.NewInt(18 * params.Gwei),
+	StandbyMisses: 3,
+
+	TriePrefetchCache: 102,
+
+	AllowUnprotectedTxs: true,
+	RPCGasCap:           50000000,
+	RPCEVMTimeout:       5 * time.Second,
 
 	TxPool: core.DefaultTxPoolConfig,
 	GPO: gasprice.Config{
 		Blocks:     20,
 		Percentile: 60,
+		MaxPrice:   gasprice.DefaultMaxPrice,
 	},
 }
 
@@ -68,22 +77,62 @@ type Config struct {
 	SyncMode  downloader.SyncMode
 	NoPruning bool
 
+	// TxLookupLimit is the number of recent blocks to keep transaction lookup
+	// entries for (0 = index the entire chain)
+	TxLookupLimit uint64 `toml:",omitempty"`
+
+	// StateHistory is the number of recent blocks to retain the state of when
+	// pruning (0 = the default in-memory window of 128 blocks)
+	StateHistory uint64 `toml:",omitempty"`
+
+	// PruneSchedule is when to prune the state of the running node, either an
+	// interval ("6h") or daily times ("03:00,15:00") (empty = never)
+	PruneSchedule string `toml:",omitempty"`
+
+	// PruneBloomSize is the megabytes of memory allocated to the bloom filter of
+	// a scheduled state prune
+	PruneBloomSize uint64 `toml:",omitempty"`
+
+	// Maximum number of concurrent state sync requests (0 = unlimited)
+	StateWorkers int `toml:",omitempty"`
+
 	// Light client options
 	LightServ  int `toml:",omitempty"` // Maximum percentage of time allowed for serving LES requests
 	LightPeers int `toml:",omitempty"` // Maximum number of LES client peers
 
+	// Checkpoint light clients start syncing from (nil = hardcoded for the chain)
+	Checkpoint *params.TrustedCheckpoint `toml:",omitempty"`
+
 	// Database options
 	SkipBcVersionCheck bool `toml:"-"`
 	DatabaseHandles    int  `toml:"-"`
 	DatabaseCache      int
 	TrieCache          int
 	TrieTimeout        time.Duration
+	TriePrefetchCache  int           // Megabytes of trie nodes warmed by the block prefetcher (0 = prefetching disabled)
+	SnapshotCache      int           `toml:",omitempty"` // Megabytes of flat state snapshot cache (0 = snapshot disabled)
+	BlockCache         int           `toml:",omitempty"` // Number of recent blocks and bodies kept in memory (0 = default)
+	ReceiptCache       int           `toml:",omitempty"` // Number of recent block receipts kept in memory (0 = default)
+	SenderCache        int           `toml:",omitempty"` // Number of recent transaction senders kept in memory (0 = default)
+	NoPreimages        bool          `toml:",omitempty"` // Skip recording the preimages of hashed trie keys
+	DatabaseStats      time.Duration `toml:",omitempty"` // Interval of database statistics reporting (0 = disabled)
+	ReadReplica        string        `toml:",omitempty"` // Secondary database serving RPC state reads
+	DatabaseFreezer    string        `toml:",omitempty"` // Ancient store for immutable chain segments
 
 	// Producing-related options
 	Coinbase  common.Address `toml:",omitempty"`
+	Signer    common.Address `toml:",omitempty"` // Witness signing account (default = coinbase)
 	ExtraData []byte         `toml:",omitempty"`
 	GasPrice  *big.Int
 
+	// Witness failover options
+	Standby       bool   `toml:",omitempty"` // Produce only while the primary node of the witness is down
+	StandbyMisses uint64 `toml:",omitempty"` // Consecutive rounds the primary may miss before the standby takes over
+	ProducerLock  string `toml:",omitempty"` // Lock file shared by the nodes of a witness, held while producing
+
+	// Block production timing policy
+	DposTiming dpos.Timing
+
 	// Transaction pool options
 	TxPool core.TxPoolConfig
 
@@ -93,6 +142,16 @@ type Config struct {
 	// Enables tracking of SHA3 preimages in the VM
 	EnablePreimageRecording bool
 
+	// Maximum memory in bytes a single block's execution may allocate (0 = uncapped).
+	// A block crossing it stops the node, as it may still be valid.
+	VMMemoryCap uint64
+
+	// RPC options
+	AllowUnprotectedTxs bool          // Accept non replay-protected raw transactions over RPC
+	RPCGasCap           uint64        // Global gas cap for call and estimateGas (0 = no cap)
+	RPCEVMTimeout       time.Duration // Global timeout for call and estimateGas executions (0 = no timeout)
+	RPCBeamReads        bool          `toml:",omitempty"` // Fetch state missing for RPC reads on demand from peers
+
 	// Miscellaneous options
 	DocRoot string `toml:"-"`
 }
@@ -27,6 +27,7 @@ import (
 
 	hubble "github.com/vntchain/go-vnt"
 	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/core"
 	"github.com/vntchain/go-vnt/core/rawdb"
 	"github.com/vntchain/go-vnt/core/types"
 	"github.com/vntchain/go-vnt/event"
@@ -69,6 +70,17 @@ var (
 	fsMinFullBlocks        = 64              // Number of blocks to retrieve fully even in fast sync
 )
 
+// Stages of a sync cycle, as reported in the progress of the downloader.
+const (
+	StageIdle     = "idle"     // No sync cycle running
+	StageAncestor = "ancestor" // Looking up the common ancestor with the sync peer
+	StageBlocks   = "blocks"   // Downloading and importing headers, bodies and receipts
+	StageState    = "state"    // Waiting for the state of the fast sync pivot block
+)
+
+// stageIndices are the values the sync stage gauge reports for each stage.
+var stageIndices = map[string]int64{StageIdle: 0, StageAncestor: 1, StageBlocks: 2, StageState: 3}
+
 var (
 	errBusy                    = errors.New("busy")
 	errUnknownPeer             = errors.New("peer is unknown or unhealthy")
@@ -105,10 +117,18 @@ type Downloader struct {
 	rttEstimate   uint64 // Round trip time to target for download requests
 	rttConfidence uint64 // Confidence in the estimated RTT (unit: millionths to allow atomic ops)
 
+	stateWorkers int32 // Maximum number of concurrent state retrieval requests (0 = unlimited)
+
 	// Statistics
 	syncStatsChainOrigin uint64 // Origin block number where syncing started at
 	syncStatsChainHeight uint64 // Highest block number known when syncing started
 	syncStatsState       stateSyncStats
+	syncStatsStage       string       // Stage the current sync cycle is at
+	syncStatsStart       time.Time    // Time the sync from the origin block started at
+	syncStatsHeaders     uint64       // Number of headers downloaded since the sync started
+	syncStatsBodies      uint64       // Number of block bodies downloaded since the sync started
+	syncStatsReceipts    uint64       // Number of block receipts downloaded since the sync started
+	syncStatsStates      uint64       // Number of state entries processed when the sync started
 	syncStatsLock        sync.RWMutex // Lock protecting the sync stats fields
 
 	lightchain LightChain
@@ -135,6 +155,7 @@ type Downloader struct {
 	stateSyncStart chan *stateSync
 	trackStateReq  chan *stateReq
 	stateCh        chan dataPack // [vnt/63] Channel receiving inbound node state data
+	snapCh         chan dataPack // [vnt/63] Channel receiving inbound state ranges and codes
 
 	// Cancellation and termination
 	cancelPeer libp2p.ID      // Identifier of the peer currently being used as the master (cancel on drop)
@@ -224,6 +245,7 @@ func New(mode SyncMode, stateDb vntdb.Database, mux *event.TypeMux, chain BlockC
 		headerProcCh:   make(chan []*types.Header, 1),
 		quitCh:         make(chan struct{}),
 		stateCh:        make(chan dataPack),
+		snapCh:         make(chan dataPack, snapDeliveryBuffer),
 		stateSyncStart: make(chan *stateSync),
 		syncStatsState: stateSyncStats{
 			processed: rawdb.ReadFastTrieProgress(stateDb),
@@ -235,6 +257,15 @@ func New(mode SyncMode, stateDb vntdb.Database, mux *event.TypeMux, chain BlockC
 	return dl
 }
 
+// SetStateWorkers bounds the number of state trie retrieval requests kept in
+// flight concurrently during fast sync. A non-positive value removes the bound.
+func (d *Downloader) SetStateWorkers(n int) {
+	if n < 0 {
+		n = 0
+	}
+	atomic.StoreInt32(&d.stateWorkers, int32(n))
+}
+
 // Progress retrieves the synchronisation boundaries, specifically the origin
 // block where synchronisation started at (may have failed/suspended); the block
 // or header sync is currently at; and the latest known block which the sync targets.
@@ -251,17 +282,74 @@ func (d *Downloader) Progress() hubble.SyncProgress {
 	switch d.mode {
 	case FullSync:
 		current = d.blockchain.CurrentBlock().NumberU64()
-	case FastSync:
+	case FastSync, SnapSync:
 		current = d.blockchain.CurrentFastBlock().NumberU64()
 	case LightSync:
 		current = d.lightchain.CurrentHeader().Number.Uint64()
 	}
-	return hubble.SyncProgress{
-		StartingBlock: d.syncStatsChainOrigin,
-		CurrentBlock:  current,
-		HighestBlock:  d.syncStatsChainHeight,
-		PulledStates:  d.syncStatsState.processed,
-		KnownStates:   d.syncStatsState.processed + d.syncStatsState.pending,
+	progress := hubble.SyncProgress{
+		StartingBlock:  d.syncStatsChainOrigin,
+		CurrentBlock:   current,
+		HighestBlock:   d.syncStatsChainHeight,
+		PulledStates:   d.syncStatsState.processed,
+		KnownStates:    d.syncStatsState.processed + d.syncStatsState.pending,
+		Stage:          d.syncStatsStage,
+		PulledHeaders:  d.syncStatsHeaders,
+		PulledBodies:   d.syncStatsBodies,
+		PulledReceipts: d.syncStatsReceipts,
+	}
+	if progress.Stage == "" {
+		progress.Stage = StageIdle
+	}
+	// Derive the rates and the remaining time from the progress since the start
+	if d.syncStatsStart.IsZero() {
+		return progress
+	}
+	elapsed := time.Since(d.syncStatsStart).Seconds()
+	if elapsed <= 0 {
+		return progress
+	}
+	progress.HeaderRate = float64(d.syncStatsHeaders) / elapsed
+	progress.BodyRate = float64(d.syncStatsBodies) / elapsed
+	progress.ReceiptRate = float64(d.syncStatsReceipts) / elapsed
+	if d.syncStatsState.processed > d.syncStatsStates {
+		progress.StateRate = float64(d.syncStatsState.processed-d.syncStatsStates) / elapsed
+	}
+	if current > progress.StartingBlock && progress.HighestBlock > current {
+		rate := float64(current-progress.StartingBlock) / elapsed
+		progress.Remaining = time.Duration(float64(progress.HighestBlock-current) / rate * float64(time.Second))
+	}
+	return progress
+}
+
+// setSyncStage records the stage the current sync cycle reached.
+func (d *Downloader) setSyncStage(stage string) {
+	d.syncStatsLock.Lock()
+	d.syncStatsStage = stage
+	d.syncStatsLock.Unlock()
+
+	syncStageGauge.Update(stageIndices[stage])
+}
+
+// addPulled accounts a number of downloaded items of the given kind in the
+// progress of the current sync.
+func (d *Downloader) addPulled(kind string, items int) {
+	if items <= 0 {
+		return
+	}
+	d.syncStatsLock.Lock()
+	defer d.syncStatsLock.Unlock()
+
+	switch kind {
+	case "headers":
+		d.syncStatsHeaders += uint64(items)
+		syncHeadersGauge.Update(int64(d.syncStatsHeaders))
+	case "bodies":
+		d.syncStatsBodies += uint64(items)
+		syncBodiesGauge.Update(int64(d.syncStatsBodies))
+	case "receipts":
+		d.syncStatsReceipts += uint64(items)
+		syncReceiptsGauge.Update(int64(d.syncStatsReceipts))
 	}
 }
 
@@ -332,6 +420,9 @@ func (d *Downloader) Synchronise(id libp2p.ID, head common.Hash, td *big.Int, mo
 		} else {
 			d.dropPeer(id)
 		}
+	case core.ErrBlockMemoryCap:
+		// The blocks of the peer may well be valid, stall until the cap is raised
+		log.Error("Synchronisation stalled on the local VM memory cap", "peer", id, "err", err)
 	default:
 		log.Warn("Synchronisation failed, retrying", "err", err)
 	}
@@ -422,6 +513,9 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash, td *big.I
 		log.Debug("Synchronisation terminated", "elapsed", time.Since(start))
 	}(time.Now())
 
+	d.setSyncStage(StageAncestor)
+	defer d.setSyncStage(StageIdle)
+
 	// Look up the sync boundaries: the common ancestor and the target block
 	latest, err := d.fetchHeight(p)
 	if err != nil {
@@ -435,14 +529,21 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash, td *big.I
 	}
 	d.syncStatsLock.Lock()
 	if d.syncStatsChainHeight <= origin || d.syncStatsChainOrigin > origin {
+		// A new sync from the origin starts, restart the progress statistics
 		d.syncStatsChainOrigin = origin
+		d.syncStatsStart = time.Now()
+		d.syncStatsHeaders, d.syncStatsBodies, d.syncStatsReceipts = 0, 0, 0
+		d.syncStatsStates = d.syncStatsState.processed
 	}
 	d.syncStatsChainHeight = height
 	d.syncStatsLock.Unlock()
 
+	syncHighestGauge.Update(int64(height))
+	d.setSyncStage(StageBlocks)
+
 	// Ensure our origin point is below any fast sync pivot point
 	pivot := uint64(0)
-	if d.mode == FastSync {
+	if d.mode == FastSync || d.mode == SnapSync {
 		if height <= uint64(fsMinFullBlocks) {
 			origin = 0
 		} else {
@@ -453,7 +554,7 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash, td *big.I
 		}
 	}
 	d.committed = 1
-	if d.mode == FastSync && pivot != 0 {
+	if (d.mode == FastSync || d.mode == SnapSync) && pivot != 0 {
 		d.committed = 0
 	}
 	// Initiate the sync using a concurrent header and content retrieval algorithm
@@ -468,7 +569,7 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash, td *big.I
 		func() error { return d.fetchReceipts(origin + 1) },        // Receipts are retrieved during fast sync
 		func() error { return d.processHeaders(origin+1, pivot, td) },
 	}
-	if d.mode == FastSync {
+	if d.mode == FastSync || d.mode == SnapSync {
 		fetchers = append(fetchers, func() error { return d.processFastSyncContent(latest) })
 	} else if d.mode == FullSync {
 		fetchers = append(fetchers, d.processFullSyncContent)
@@ -597,7 +698,7 @@ func (d *Downloader) findAncestor(p *peerConnection, height uint64) (uint64, err
 
 	if d.mode == FullSync {
 		ceil = d.blockchain.CurrentBlock().NumberU64()
-	} else if d.mode == FastSync {
+	} else if d.mode == FastSync || d.mode == SnapSync {
 		ceil = d.blockchain.CurrentFastBlock().NumberU64()
 	}
 	if ceil >= MaxForkAncestry {
@@ -1023,6 +1124,9 @@ func (d *Downloader) fetchParts(errCancel error, deliveryCh chan dataPack, deliv
 				if err == errInvalidChain {
 					return err
 				}
+				if kind != "headers" {
+					d.addPulled(kind, accepted)
+				}
 				// Unless a peer delivered something completely else than requested (usually
 				// caused by a timed out request which came through in the end), set it to
 				// idle. If the delivery's stale, the peer should have already been idled.
@@ -1230,7 +1334,7 @@ func (d *Downloader) processHeaders(origin uint64, pivot uint64, td *big.Int) er
 				// This check cannot be executed "as is" for full imports, since blocks may still be
 				// queued for processing when the header download completes. However, as long as the
 				// peer gave us something useful, we're already happy/progressed (above check).
-				if d.mode == FastSync || d.mode == LightSync {
+				if d.mode == FastSync || d.mode == SnapSync || d.mode == LightSync {
 					head := d.lightchain.CurrentHeader()
 					if td.Cmp(d.lightchain.GetTd(head.Hash(), head.Number.Uint64())) > 0 {
 						return errStallingPeer
@@ -1242,6 +1346,7 @@ func (d *Downloader) processHeaders(origin uint64, pivot uint64, td *big.Int) er
 			}
 			// Otherwise split the chunk of headers into batches and process them
 			gotHeaders = true
+			d.addPulled("headers", len(headers))
 
 			for len(headers) > 0 {
 				// Terminate if something failed in between processing chunks
@@ -1258,7 +1363,7 @@ func (d *Downloader) processHeaders(origin uint64, pivot uint64, td *big.Int) er
 				chunk := headers[:limit]
 
 				// In case of header only syncing, validate the chunk immediately
-				if d.mode == FastSync || d.mode == LightSync {
+				if d.mode == FastSync || d.mode == SnapSync || d.mode == LightSync {
 					// Collect the yet unknown headers to mark them as uncertain
 					unknown := make([]*types.Header, 0, len(headers))
 					for _, header := range chunk {
@@ -1286,7 +1391,7 @@ func (d *Downloader) processHeaders(origin uint64, pivot uint64, td *big.Int) er
 					}
 				}
 				// Unless we're doing light chains, schedule the headers for associated content retrieval
-				if d.mode == FullSync || d.mode == FastSync {
+				if d.mode == FullSync || d.mode == FastSync || d.mode == SnapSync {
 					// If we've reached the allowed number of pending headers, stall a bit
 					for d.queue.PendingBlocks() >= maxQueuedHeaders || d.queue.PendingReceipts() >= maxQueuedHeaders {
 						select {
@@ -1363,6 +1468,9 @@ func (d *Downloader) importBlockResults(results []*fetchResult) error {
 
 	if index, err := d.blockchain.InsertChain(blocks); err != nil {
 		log.Debug("Downloaded item processing failed", "number", results[index].Header.Number, "hash", results[index].Header.Hash(), "err", err)
+		if err == core.ErrBlockMemoryCap {
+			return err
+		}
 		return errInvalidChain
 	}
 	return nil
@@ -1373,7 +1481,12 @@ func (d *Downloader) importBlockResults(results []*fetchResult) error {
 func (d *Downloader) processFastSyncContent(latest *types.Header) error {
 	// Start syncing state of the reported head block. This should get us most of
 	// the state of the pivot block.
-	stateSync := d.syncState(latest.Root)
+	var stateSync *stateSync
+	if d.mode == SnapSync {
+		stateSync = d.syncSnapState(latest.Root)
+	} else {
+		stateSync = d.syncState(latest.Root)
+	}
 	defer stateSync.Cancel()
 	go func() {
 		if err := stateSync.Wait(); err != nil && err != errCancelStateFetch {
@@ -1441,6 +1554,7 @@ func (d *Downloader) processFastSyncContent(latest *types.Header) error {
 				oldPivot = P
 			}
 			// Wait for completion, occasionally checking for pivot staleness
+			d.setSyncStage(StageState)
 			select {
 			case <-stateSync.done:
 				if stateSync.err != nil {
@@ -1450,6 +1564,7 @@ func (d *Downloader) processFastSyncContent(latest *types.Header) error {
 					return err
 				}
 				oldPivot = nil
+				d.setSyncStage(StageBlocks)
 
 			case <-time.After(time.Second):
 				oldTail = afterP
@@ -1545,6 +1660,23 @@ func (d *Downloader) DeliverNodeData(id libp2p.ID, data [][]byte) (err error) {
 	return d.deliver(id, d.stateCh, &statePack{id, data}, stateInMeter, stateDropMeter)
 }
 
+// DeliverAccountRange injects a new range of accounts received from a remote node.
+func (d *Downloader) DeliverAccountRange(id libp2p.ID, hashes []common.Hash, accounts [][]byte, proof [][]byte) (err error) {
+	return d.deliverSnap(&accountRangePack{id, hashes, accounts, proof})
+}
+
+// DeliverStorageRanges injects a new batch of storage ranges received from a
+// remote node.
+func (d *Downloader) DeliverStorageRanges(id libp2p.ID, hashes [][]common.Hash, slots [][][]byte, more bool) (err error) {
+	return d.deliverSnap(&storageRangesPack{id, hashes, slots, more})
+}
+
+// DeliverByteCodes injects a new batch of contract codes received from a remote
+// node.
+func (d *Downloader) DeliverByteCodes(id libp2p.ID, codes [][]byte) (err error) {
+	return d.deliverSnap(&byteCodesPack{id, codes})
+}
+
 // deliver injects a new batch of data received from a remote node.
 func (d *Downloader) deliver(id libp2p.ID, destCh chan dataPack, packet dataPack, inMeter, dropMeter metrics.Meter) (err error) {
 	// Update the delivery metrics for both good and failed deliveries
@@ -29,6 +29,7 @@ import (
 	"github.com/vntchain/go-vnt/common"
 	"github.com/vntchain/go-vnt/consensus/mock"
 	"github.com/vntchain/go-vnt/core"
+	"github.com/vntchain/go-vnt/core/state"
 	"github.com/vntchain/go-vnt/core/types"
 	"github.com/vntchain/go-vnt/crypto"
 	"github.com/vntchain/go-vnt/event"
@@ -42,6 +43,9 @@ var (
 	testAddress = crypto.PubkeyToAddress(testKey.PublicKey)
 )
 
+// testSnapBytes is the size cap of the state ranges served by the tester peers.
+const testSnapBytes = 1024
+
 // Reduce some of the parameters to make the tester faster.
 func init() {
 	MaxForkAncestry = uint64(10000)
@@ -70,6 +74,8 @@ type downloadTester struct {
 	peerChainTds map[libp2p.ID]map[common.Hash]*big.Int       // Total difficulties of the blocks in the peer chains
 
 	peerMissingStates map[libp2p.ID]map[common.Hash]bool // State entries that fast sync should not return
+	insertErr         error                              // Error failing block insertion with, if set
+	nodeDataRequested int32                              // Number of state entries requested via node data
 
 	lock sync.RWMutex
 }
@@ -329,6 +335,9 @@ func (dl *downloadTester) InsertChain(blocks types.Blocks) (int, error) {
 	dl.lock.Lock()
 	defer dl.lock.Unlock()
 
+	if dl.insertErr != nil {
+		return 0, dl.insertErr
+	}
 	for i, block := range blocks {
 		if parent, ok := dl.ownBlocks[block.ParentHash()]; !ok {
 			return i, errors.New("unknown parent")
@@ -582,6 +591,8 @@ func (dlp *downloadTesterPeer) RequestNodeData(hashes []common.Hash) error {
 	dlp.dl.lock.RLock()
 	defer dlp.dl.lock.RUnlock()
 
+	atomic.AddInt32(&dlp.dl.nodeDataRequested, int32(len(hashes)))
+
 	results := make([][]byte, 0, len(hashes))
 	for _, hash := range hashes {
 		if data, err := dlp.dl.peerDb.Get(hash.Bytes()); err == nil {
@@ -595,6 +606,45 @@ func (dlp *downloadTesterPeer) RequestNodeData(hashes []common.Hash) error {
 	return nil
 }
 
+// RequestAccountRange constructs a getAccountRange method associated with a
+// particular peer in the download tester. Responses are deliberately kept small
+// to exercise the continuation of ranges.
+func (dlp *downloadTesterPeer) RequestAccountRange(root common.Hash, origin common.Hash, bytes uint64) error {
+	dlp.waitDelay()
+
+	hashes, accounts, proof := ServeAccountRange(trie.NewDatabase(dlp.dl.peerDb), root, origin, testSnapBytes)
+	go dlp.dl.downloader.DeliverAccountRange(dlp.id, hashes, accounts, proof)
+
+	return nil
+}
+
+// RequestStorageRanges constructs a getStorageRanges method associated with a
+// particular peer in the download tester.
+func (dlp *downloadTesterPeer) RequestStorageRanges(roots []common.Hash, origin common.Hash, bytes uint64) error {
+	dlp.waitDelay()
+
+	hashes, slots, more := ServeStorageRanges(trie.NewDatabase(dlp.dl.peerDb), roots, origin, testSnapBytes)
+	go dlp.dl.downloader.DeliverStorageRanges(dlp.id, hashes, slots, more)
+
+	return nil
+}
+
+// RequestByteCodes constructs a getByteCodes method associated with a particular
+// peer in the download tester.
+func (dlp *downloadTesterPeer) RequestByteCodes(hashes []common.Hash, bytes uint64) error {
+	dlp.waitDelay()
+
+	var codes [][]byte
+	for _, hash := range hashes {
+		if code, err := dlp.dl.peerDb.Get(hash.Bytes()); err == nil {
+			codes = append(codes, code)
+		}
+	}
+	go dlp.dl.downloader.DeliverByteCodes(dlp.id, codes)
+
+	return nil
+}
+
 // assertOwnChain checks if the local chain contains the correct number of items
 // of the various chain components.
 func assertOwnChain(t *testing.T, tester *downloadTester, length int) {
@@ -657,6 +707,7 @@ func TestCanonicalSynchronisation63Fast(t *testing.T)  { testCanonicalSynchronis
 func TestCanonicalSynchronisation64Full(t *testing.T)  { testCanonicalSynchronisation(t, 64, FullSync) }
 func TestCanonicalSynchronisation64Fast(t *testing.T)  { testCanonicalSynchronisation(t, 64, FastSync) }
 func TestCanonicalSynchronisation64Light(t *testing.T) { testCanonicalSynchronisation(t, 64, LightSync) }
+func TestCanonicalSynchronisation64Snap(t *testing.T)  { testCanonicalSynchronisation(t, 64, SnapSync) }
 
 func testCanonicalSynchronisation(t *testing.T, protocol int, mode SyncMode) {
 	t.Parallel()
@@ -1010,6 +1061,33 @@ func testMultiSynchronisation(t *testing.T, protocol int, mode SyncMode) {
 	assertOwnChain(t, tester, targetBlocks+1)
 }
 
+// Tests that fast sync still completes if the number of concurrent state sync
+// requests is bounded below the number of available peers.
+func TestBoundedStateWorkers63(t *testing.T) { testBoundedStateWorkers(t, 63) }
+func TestBoundedStateWorkers64(t *testing.T) { testBoundedStateWorkers(t, 64) }
+
+func testBoundedStateWorkers(t *testing.T, protocol int) {
+	t.Parallel()
+
+	tester := newTester()
+	defer tester.terminate()
+
+	tester.downloader.SetStateWorkers(1)
+	if workers := atomic.LoadInt32(&tester.downloader.stateWorkers); workers != 1 {
+		t.Fatalf("state workers mismatch: have %d, want %d", workers, 1)
+	}
+	targetBlocks := blockCacheItems - 15
+	hashes, headers, blocks, receipts := tester.makeChain(targetBlocks, 0, tester.genesis, nil, false)
+
+	for i := 0; i < 4; i++ {
+		tester.newPeer(libp2p.ID(fmt.Sprintf("peer #%d", i)), protocol, hashes, headers, blocks, receipts)
+	}
+	if err := tester.sync("peer #0", nil, FastSync); err != nil {
+		t.Fatalf("failed to synchronise blocks: %v", err)
+	}
+	assertOwnChain(t, tester, targetBlocks+1)
+}
+
 // Tests that synchronisations behave well in multi-version protocol environments
 // and not wreak havoc on other nodes in the network.
 func TestMultiProtoSynchronisation62(t *testing.T)      { testMultiProtoSync(t, 62, FullSync) }
@@ -1321,6 +1399,7 @@ func testBlockHeaderAttackerDropping(t *testing.T, protocol int) {
 		{errCancelReceiptFetch, false},      // Synchronisation was canceled, origin may be innocent, don't drop
 		{errCancelHeaderProcessing, false},  // Synchronisation was canceled, origin may be innocent, don't drop
 		{errCancelContentProcessing, false}, // Synchronisation was canceled, origin may be innocent, don't drop
+		{core.ErrBlockMemoryCap, false},     // Blocks exceed the local memory cap, origin may be innocent, don't drop
 	}
 	// Run the tests and check disconnection status
 	tester := newTester()
@@ -1345,6 +1424,36 @@ func testBlockHeaderAttackerDropping(t *testing.T, protocol int) {
 	}
 }
 
+// Tests that blocks failing to import because of the local VM memory cap stall
+// the synchronisation without dropping the peer that served them.
+func TestMemoryCapStall62(t *testing.T) { testMemoryCapStall(t, 62) }
+func TestMemoryCapStall63(t *testing.T) { testMemoryCapStall(t, 63) }
+func TestMemoryCapStall64(t *testing.T) { testMemoryCapStall(t, 64) }
+
+func testMemoryCapStall(t *testing.T, protocol int) {
+	t.Parallel()
+
+	tester := newTester()
+	defer tester.terminate()
+
+	hashes, headers, blocks, receipts := tester.makeChain(blockCacheItems-15, 0, tester.genesis, nil, false)
+	tester.newPeer("peer", protocol, hashes, headers, blocks, receipts)
+
+	tester.lock.Lock()
+	tester.insertErr = core.ErrBlockMemoryCap
+	tester.lock.Unlock()
+
+	if err := tester.downloader.Synchronise("peer", hashes[0], tester.peerChainTds["peer"][hashes[0]], FullSync); err != core.ErrBlockMemoryCap {
+		t.Fatalf("synchronisation error mismatch: have %v, want %v", err, core.ErrBlockMemoryCap)
+	}
+	tester.lock.RLock()
+	_, ok := tester.peerHashes["peer"]
+	tester.lock.RUnlock()
+	if !ok {
+		t.Errorf("peer dropped for blocks exceeding the local memory cap")
+	}
+}
+
 // Tests that synchronisation progress (origin block number, current block number
 // and highest block number) is tracked and updated correctly.
 func TestSyncProgress62(t *testing.T)      { testSyncProgress(t, 62, FullSync) }
@@ -1376,6 +1485,9 @@ func testSyncProgress(t *testing.T, protocol int, mode SyncMode) {
 	if progress := tester.downloader.Progress(); progress.StartingBlock != 0 || progress.CurrentBlock != 0 || progress.HighestBlock != 0 {
 		t.Fatalf("Pristine progress mismatch: have %v/%v/%v, want %v/%v/%v", progress.StartingBlock, progress.CurrentBlock, progress.HighestBlock, 0, 0, 0)
 	}
+	if progress := tester.downloader.Progress(); progress.Stage != StageIdle {
+		t.Fatalf("Pristine stage mismatch: have %v, want %v", progress.Stage, StageIdle)
+	}
 	// Synchronise half the blocks and check initial progress
 	tester.newPeer("peer-half", protocol, hashes[targetBlocks/2:], headers, blocks, receipts)
 	pending := new(sync.WaitGroup)
@@ -1408,6 +1520,9 @@ func testSyncProgress(t *testing.T, protocol int, mode SyncMode) {
 	if progress := tester.downloader.Progress(); progress.StartingBlock != uint64(targetBlocks/2+1) || progress.CurrentBlock != uint64(targetBlocks/2+1) || progress.HighestBlock != uint64(targetBlocks) {
 		t.Fatalf("Completing progress mismatch: have %v/%v/%v, want %v/%v/%v", progress.StartingBlock, progress.CurrentBlock, progress.HighestBlock, targetBlocks/2+1, targetBlocks/2+1, targetBlocks)
 	}
+	if progress := tester.downloader.Progress(); progress.Stage != StageBlocks || progress.PulledHeaders != 0 {
+		t.Fatalf("Completing stage mismatch: have %v/%v, want %v/%v", progress.Stage, progress.PulledHeaders, StageBlocks, 0)
+	}
 	progress <- struct{}{}
 	pending.Wait()
 
@@ -1415,6 +1530,16 @@ func testSyncProgress(t *testing.T, protocol int, mode SyncMode) {
 	if progress := tester.downloader.Progress(); progress.StartingBlock != uint64(targetBlocks/2+1) || progress.CurrentBlock != uint64(targetBlocks) || progress.HighestBlock != uint64(targetBlocks) {
 		t.Fatalf("Final progress mismatch: have %v/%v/%v, want %v/%v/%v", progress.StartingBlock, progress.CurrentBlock, progress.HighestBlock, targetBlocks/2+1, targetBlocks, targetBlocks)
 	}
+	final := tester.downloader.Progress()
+	if final.Stage != StageIdle {
+		t.Errorf("Final stage mismatch: have %v, want %v", final.Stage, StageIdle)
+	}
+	if want := uint64(targetBlocks - targetBlocks/2 - 1); final.PulledHeaders < want {
+		t.Errorf("Final pulled headers mismatch: have %v, want at least %v", final.PulledHeaders, want)
+	}
+	if final.HeaderRate <= 0 {
+		t.Errorf("Final header rate mismatch: have %v, want positive", final.HeaderRate)
+	}
 }
 
 // Tests that synchronisation progress (origin block number and highest block
@@ -1745,3 +1870,81 @@ func testDeliverHeadersHang(t *testing.T, protocol int, mode SyncMode) {
 		tester.downloader.peers.peers["peer"].peer.(*floodingTestPeer).pend.Wait()
 	}
 }
+
+// Tests that snap sync retrieves the entire state as flat ranges, leaving nothing
+// but the root for the trie node sync to heal, and that the trie node sync still
+// completes the state if the peers don't serve ranges or predate them.
+func TestSnapSyncState(t *testing.T)         { testSnapSyncState(t, 64, true) }
+func TestSnapSyncStateHealed(t *testing.T)   { testSnapSyncState(t, 64, false) }
+func TestSnapSyncStateHealed63(t *testing.T) { testSnapSyncState(t, 63, true) }
+
+func testSnapSyncState(t *testing.T, protocol int, ranges bool) {
+	t.Parallel()
+
+	tester := newTester()
+	defer tester.terminate()
+
+	// Assemble a state large enough to need multiple ranges, with storage and code
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(tester.peerDb))
+	for i := 0; i < 256; i++ {
+		addr := common.BigToAddress(big.NewInt(int64(i + 1)))
+		statedb.AddBalance(addr, big.NewInt(int64(i+1)))
+		if i%16 == 0 {
+			statedb.SetCode(addr, []byte{byte(i), 0x01, 0x02})
+			for j := 0; j < 64; j++ {
+				statedb.SetState(addr, common.BigToHash(big.NewInt(int64(j))), common.BigToHash(big.NewInt(int64(i*j+1))))
+			}
+		}
+	}
+	root, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	if err := statedb.Database().TrieDB().Commit(root, false); err != nil {
+		t.Fatalf("failed to flush state: %v", err)
+	}
+	// Register a peer either serving state ranges or only trie nodes
+	peer := Peer(&downloadTesterPeer{dl: tester, id: "peer"})
+	if !ranges {
+		peer = struct{ Peer }{peer}
+	}
+	if err := tester.downloader.RegisterPeer("peer", protocol, peer); err != nil {
+		t.Fatalf("failed to register peer: %v", err)
+	}
+	tester.downloader.cancelLock.Lock()
+	tester.downloader.cancelCh = make(chan struct{})
+	tester.downloader.cancelLock.Unlock()
+
+	if err := tester.downloader.syncSnapState(root).Wait(); err != nil {
+		t.Fatalf("failed to sync state: %v", err)
+	}
+	requested := atomic.LoadInt32(&tester.nodeDataRequested)
+	if ranges && protocol >= 64 && requested > 1 {
+		t.Errorf("healed state entries mismatch: have %d, want at most 1", requested)
+	}
+	if protocol < 64 && requested <= 1 {
+		t.Errorf("state ranges requested from vnt/%d peer", protocol)
+	}
+	// Verify that the entire state was retrieved
+	synced, err := state.New(root, state.NewDatabase(tester.stateDb))
+	if err != nil {
+		t.Fatalf("failed to open synced state: %v", err)
+	}
+	for i := 0; i < 256; i++ {
+		addr := common.BigToAddress(big.NewInt(int64(i + 1)))
+		if balance := synced.GetBalance(addr); balance.Int64() != int64(i+1) {
+			t.Fatalf("account %d: balance mismatch: have %v, want %v", i, balance, i+1)
+		}
+		if i%16 == 0 {
+			if code := synced.GetCode(addr); len(code) != 3 || code[0] != byte(i) {
+				t.Fatalf("account %d: code mismatch: have %x", i, code)
+			}
+			for j := 0; j < 64; j++ {
+				want := common.BigToHash(big.NewInt(int64(i*j + 1)))
+				if have := synced.GetState(addr, common.BigToHash(big.NewInt(int64(j)))); have != want {
+					t.Fatalf("account %d, slot %d: value mismatch: have %x, want %x", i, j, have, want)
+				}
+			}
+		}
+	}
+}
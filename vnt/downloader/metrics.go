@@ -40,4 +40,14 @@ var (
 
 	stateInMeter   = metrics.NewRegisteredMeter("vnt/downloader/states/in", nil)
 	stateDropMeter = metrics.NewRegisteredMeter("vnt/downloader/states/drop", nil)
+
+	snapInMeter   = metrics.NewRegisteredMeter("vnt/downloader/snap/in", nil)
+	snapDropMeter = metrics.NewRegisteredMeter("vnt/downloader/snap/drop", nil)
+
+	syncStageGauge    = metrics.NewRegisteredGauge("vnt/downloader/sync/stage", nil)
+	syncHighestGauge  = metrics.NewRegisteredGauge("vnt/downloader/sync/highest", nil)
+	syncHeadersGauge  = metrics.NewRegisteredGauge("vnt/downloader/sync/headers", nil)
+	syncBodiesGauge   = metrics.NewRegisteredGauge("vnt/downloader/sync/bodies", nil)
+	syncReceiptsGauge = metrics.NewRegisteredGauge("vnt/downloader/sync/receipts", nil)
+	syncStatesGauge   = metrics.NewRegisteredGauge("vnt/downloader/sync/states", nil)
 )
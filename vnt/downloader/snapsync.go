@@ -0,0 +1,513 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package downloader
+
+import (
+	"bytes"
+	"errors"
+	"time"
+
+	libp2p "github.com/libp2p/go-libp2p-peer"
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/core/state"
+	"github.com/vntchain/go-vnt/core/types"
+	"github.com/vntchain/go-vnt/crypto"
+	"github.com/vntchain/go-vnt/log"
+	"github.com/vntchain/go-vnt/rlp"
+	"github.com/vntchain/go-vnt/trie"
+	"github.com/vntchain/go-vnt/vntdb"
+)
+
+const (
+	snapRangeBytes     = 512 * 1024 // Soft cap on the size of a single range response to request
+	snapStorageBatch   = 64         // Maximum number of storage tries to request at once
+	snapCodesBatch     = 64         // Maximum number of contract codes to request at once
+	snapDeliveryBuffer = 16         // Number of snap responses to buffer before dropping
+)
+
+var (
+	errInvalidSnapRange = errors.New("retrieved state range is invalid")
+	errSnapDeliveryFull = errors.New("snap delivery buffer full")
+
+	emptyCode = crypto.Keccak256Hash(nil)
+)
+
+// SnapPeer encapsulates the methods required to retrieve flat state ranges from
+// a remote peer during snap sync. Peers not implementing it are only used for
+// the trie node healing phase.
+type SnapPeer interface {
+	RequestAccountRange(root common.Hash, origin common.Hash, bytes uint64) error
+	RequestStorageRanges(roots []common.Hash, origin common.Hash, bytes uint64) error
+	RequestByteCodes(hashes []common.Hash, bytes uint64) error
+}
+
+// storageTask is a storage trie being assembled from one or more storage ranges.
+type storageTask struct {
+	root   common.Hash // Root hash of the storage trie to retrieve
+	origin common.Hash // First slot hash not yet retrieved
+	trie   *trie.Trie  // Partially filled storage trie (nil if nothing retrieved yet)
+}
+
+// syncSnapState starts downloading state with the given root hash, retrieving
+// the flat account and storage ranges first and healing the trie afterwards.
+func (d *Downloader) syncSnapState(root common.Hash) *stateSync {
+	s := newStateSync(d, root)
+	s.snap = true
+	select {
+	case d.stateSyncStart <- s:
+	case <-d.quitCh:
+		s.err = errCancelStateFetch
+		close(s.done)
+	}
+	return s
+}
+
+// deliverSnap injects a state range or code response received from a remote
+// node. Responses are dropped instead of blocking the peer if nobody is waiting
+// for them, the snap sync requesting them having already moved on.
+func (d *Downloader) deliverSnap(packet dataPack) (err error) {
+	snapInMeter.Mark(int64(packet.Items()))
+	defer func() {
+		if err != nil {
+			snapDropMeter.Mark(int64(packet.Items()))
+		}
+	}()
+	d.cancelLock.RLock()
+	cancel := d.cancelCh
+	d.cancelLock.RUnlock()
+	if cancel == nil {
+		return errNoSyncActive
+	}
+	select {
+	case d.snapCh <- packet:
+		return nil
+	case <-cancel:
+		return errNoSyncActive
+	default:
+		return errSnapDeliveryFull
+	}
+}
+
+// snapSync downloads the flat account and storage ranges of the state being
+// synced, along with all the referenced contract codes, and writes them into
+// the local database. The downloaded data is not trusted: anything missing or
+// bogus is detected and fixed up by the trie node sync that follows.
+func (s *stateSync) snapSync() error {
+	if s.root == types.EmptyRootHash {
+		return nil
+	}
+	var (
+		start  = time.Now()
+		triedb = trie.NewDatabase(s.d.stateDB)
+		failed = make(map[libp2p.ID]struct{})
+	)
+	storage, codes, complete, err := s.snapAccounts(triedb, failed)
+	if err != nil || !complete {
+		return err
+	}
+	if complete, err = s.snapStorage(triedb, failed, storage); err != nil || !complete {
+		return err
+	}
+	if complete, err = s.snapCodes(failed, codes); err != nil || !complete {
+		return err
+	}
+	log.Info("Downloaded state ranges", "root", s.root, "storage", len(storage), "codes", len(codes), "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// snapAccounts retrieves all the accounts of the state trie range by range,
+// collecting the storage tries and contract codes they reference.
+func (s *stateSync) snapAccounts(triedb *trie.Database, failed map[libp2p.ID]struct{}) ([]*storageTask, map[common.Hash]struct{}, bool, error) {
+	var (
+		storage = []*storageTask{}
+		roots   = make(map[common.Hash]struct{})
+		codes   = make(map[common.Hash]struct{})
+		root    = common.Hash{}
+	)
+	for origin := (common.Hash{}); ; {
+		p, packet, err := s.snapRequest(failed, func(peer SnapPeer) error {
+			return peer.RequestAccountRange(s.root, origin, snapRangeBytes)
+		})
+		if err != nil || packet == nil {
+			return nil, nil, false, err
+		}
+		res, ok := packet.(*accountRangePack)
+		if !ok || verifyAccountRange(s.root, origin, res) != nil {
+			log.Debug("Invalid account range", "peer", p.id, "origin", origin)
+			failed[p.id] = struct{}{}
+			continue
+		}
+		if len(res.hashes) == 0 {
+			break
+		}
+		// Insert the accounts into the local trie and schedule their contents
+		accounts, err := trie.New(root, triedb)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		for i, hash := range res.hashes {
+			if err := accounts.TryUpdate(hash[:], res.accounts[i]); err != nil {
+				return nil, nil, false, err
+			}
+			var account state.Account
+			if err := rlp.DecodeBytes(res.accounts[i], &account); err != nil {
+				continue
+			}
+			if _, ok := roots[account.Root]; !ok && account.Root != types.EmptyRootHash {
+				if ok, _ := s.d.stateDB.Has(account.Root[:]); !ok {
+					storage = append(storage, &storageTask{root: account.Root})
+				}
+				roots[account.Root] = struct{}{}
+			}
+			if code := common.BytesToHash(account.CodeHash); code != emptyCode {
+				codes[code] = struct{}{}
+			}
+		}
+		if root, err = accounts.Commit(nil); err != nil {
+			return nil, nil, false, err
+		}
+		if err := triedb.Commit(root, false); err != nil {
+			return nil, nil, false, err
+		}
+		s.updateSnapStats(len(res.hashes))
+
+		// Continue after the last retrieved account, unless the key space is exhausted
+		var overflow bool
+		if origin, overflow = incHash(res.hashes[len(res.hashes)-1]); overflow {
+			break
+		}
+	}
+	if root != s.root {
+		log.Debug("Account ranges incomplete, leaving to healing", "have", root, "want", s.root)
+	}
+	return storage, codes, true, nil
+}
+
+// snapStorage retrieves the slot ranges of all the given storage tries.
+func (s *stateSync) snapStorage(triedb *trie.Database, failed map[libp2p.ID]struct{}, tasks []*storageTask) (bool, error) {
+	for len(tasks) > 0 {
+		batch := tasks
+		if len(batch) > snapStorageBatch {
+			batch = batch[:snapStorageBatch]
+		}
+		roots := make([]common.Hash, len(batch))
+		for i, task := range batch {
+			roots[i] = task.root
+		}
+		p, packet, err := s.snapRequest(failed, func(peer SnapPeer) error {
+			return peer.RequestStorageRanges(roots, batch[0].origin, snapRangeBytes)
+		})
+		if err != nil || packet == nil {
+			return false, err
+		}
+		res, ok := packet.(*storageRangesPack)
+		if !ok || len(res.hashes) == 0 || len(res.hashes) > len(batch) || len(res.slots) != len(res.hashes) {
+			log.Debug("Invalid storage ranges", "peer", p.id)
+			failed[p.id] = struct{}{}
+			continue
+		}
+		done, slots := 0, 0
+		for i, hashes := range res.hashes {
+			task := batch[i]
+			if len(hashes) != len(res.slots[i]) || (res.more && i == len(res.hashes)-1 && len(hashes) == 0) {
+				failed[p.id] = struct{}{}
+				break
+			}
+			if task.trie == nil {
+				if task.trie, err = trie.New(common.Hash{}, triedb); err != nil {
+					return false, err
+				}
+			}
+			for j, hash := range hashes {
+				if err := task.trie.TryUpdate(hash[:], res.slots[i][j]); err != nil {
+					return false, err
+				}
+			}
+			slots += len(hashes)
+
+			// If the last storage trie was cut short, continue it in the next request
+			if res.more && i == len(res.hashes)-1 {
+				if next, overflow := incHash(hashes[len(hashes)-1]); !overflow {
+					task.origin = next
+					break
+				}
+			}
+			root, err := task.trie.Commit(nil)
+			if err != nil {
+				return false, err
+			}
+			if root != task.root {
+				log.Debug("Storage range incomplete, leaving to healing", "have", root, "want", task.root)
+			}
+			if err := triedb.Commit(root, false); err != nil {
+				return false, err
+			}
+			task.trie = nil
+			done++
+		}
+		tasks = tasks[done:]
+		s.updateSnapStats(slots)
+	}
+	return true, nil
+}
+
+// snapCodes retrieves all the given contract codes.
+func (s *stateSync) snapCodes(failed map[libp2p.ID]struct{}, codes map[common.Hash]struct{}) (bool, error) {
+	pending := make(map[common.Hash]struct{}, len(codes))
+	for hash := range codes {
+		if ok, _ := s.d.stateDB.Has(hash[:]); !ok {
+			pending[hash] = struct{}{}
+		}
+	}
+	for len(pending) > 0 {
+		hashes := make([]common.Hash, 0, snapCodesBatch)
+		for hash := range pending {
+			if hashes = append(hashes, hash); len(hashes) == snapCodesBatch {
+				break
+			}
+		}
+		p, packet, err := s.snapRequest(failed, func(peer SnapPeer) error {
+			return peer.RequestByteCodes(hashes, snapRangeBytes)
+		})
+		if err != nil || packet == nil {
+			return false, err
+		}
+		res, ok := packet.(*byteCodesPack)
+		if !ok || len(res.codes) == 0 {
+			failed[p.id] = struct{}{}
+			continue
+		}
+		batch := s.d.stateDB.NewBatch()
+		for _, code := range res.codes {
+			hash := crypto.Keccak256Hash(code)
+			if _, ok := pending[hash]; !ok {
+				continue
+			}
+			if err := batch.Put(hash[:], code); err != nil {
+				return false, err
+			}
+			delete(pending, hash)
+		}
+		if err := batch.Write(); err != nil {
+			return false, err
+		}
+		s.updateSnapStats(len(res.codes))
+	}
+	return true, nil
+}
+
+// snapRequest sends a request to a snap capable peer not yet marked as failed
+// and waits for its response. Peers timing out are marked as failed and the
+// request is retried with the next one. A nil response is returned if no more
+// peers are left to ask.
+func (s *stateSync) snapRequest(failed map[libp2p.ID]struct{}, send func(SnapPeer) error) (*peerConnection, dataPack, error) {
+	for {
+		p := s.snapPeer(failed)
+		if p == nil {
+			log.Warn("No peers left to serve state ranges, healing the rest", "root", s.root)
+			return nil, nil, nil
+		}
+		if err := send(p.peer.(SnapPeer)); err != nil {
+			failed[p.id] = struct{}{}
+			continue
+		}
+		timeout := time.NewTimer(s.d.requestTTL())
+	wait:
+		for {
+			select {
+			case <-s.cancel:
+				timeout.Stop()
+				return nil, nil, errCancelStateFetch
+
+			case <-s.d.cancelCh:
+				timeout.Stop()
+				return nil, nil, errCancelStateFetch
+
+			case <-timeout.C:
+				log.Debug("State range request timed out", "peer", p.id)
+				failed[p.id] = struct{}{}
+				break wait
+
+			case packet := <-s.d.snapCh:
+				// Responses of peers given up on earlier are simply discarded
+				if packet.PeerId() == p.id {
+					timeout.Stop()
+					return p, packet, nil
+				}
+			}
+		}
+	}
+}
+
+// snapPeer returns a peer capable of serving state ranges which is not marked as
+// failed yet, or nil if there is none. Only vnt/64 peers know the range messages.
+func (s *stateSync) snapPeer(failed map[libp2p.ID]struct{}) *peerConnection {
+	for _, p := range s.d.peers.AllPeers() {
+		if _, ok := failed[p.id]; ok {
+			continue
+		}
+		if p.version < 64 {
+			continue
+		}
+		if _, ok := p.peer.(SnapPeer); ok {
+			return p
+		}
+	}
+	return nil
+}
+
+// updateSnapStats bumps the state sync progress counters with the number of
+// flat state entries retrieved.
+func (s *stateSync) updateSnapStats(written int) {
+	s.d.syncStatsLock.Lock()
+	defer s.d.syncStatsLock.Unlock()
+
+	s.d.syncStatsState.processed += uint64(written)
+	syncStatesGauge.Update(int64(s.d.syncStatsState.processed))
+}
+
+// verifyAccountRange checks that the returned accounts are strictly ordered and
+// start at or after the requested origin, and that the proof attached proves the
+// last account (or the origin for an empty range) against the state root.
+func verifyAccountRange(root common.Hash, origin common.Hash, res *accountRangePack) error {
+	if len(res.hashes) != len(res.accounts) {
+		return errInvalidSnapRange
+	}
+	for i, hash := range res.hashes {
+		if bytes.Compare(hash[:], origin[:]) < 0 || (i > 0 && bytes.Compare(hash[:], res.hashes[i-1][:]) <= 0) {
+			return errInvalidSnapRange
+		}
+	}
+	proof := vntdb.NewMemDatabase()
+	for _, node := range res.proof {
+		proof.Put(crypto.Keccak256(node), node)
+	}
+	key, want := origin, []byte(nil)
+	if len(res.hashes) > 0 {
+		key, want = res.hashes[len(res.hashes)-1], res.accounts[len(res.accounts)-1]
+	}
+	value, _, err := trie.VerifyProof(root, key[:], proof)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(value, want) {
+		return errInvalidSnapRange
+	}
+	return nil
+}
+
+// incHash returns the hash directly following h, and whether it wrapped around.
+func incHash(h common.Hash) (common.Hash, bool) {
+	for i := len(h) - 1; i >= 0; i-- {
+		h[i]++
+		if h[i] != 0 {
+			return h, false
+		}
+	}
+	return h, true
+}
+
+// proofList collects the nodes of a Merkle proof in the order they are written.
+type proofList [][]byte
+
+func (l *proofList) Put(key []byte, value []byte) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// ServeAccountRange gathers the consecutive accounts of the state trie rooted at
+// root starting at origin, until roughly the given number of bytes is collected.
+// The response is accompanied by the Merkle proof of the last account returned,
+// or of the origin if there are no more accounts. Nothing is returned if the
+// state is not available.
+func ServeAccountRange(db *trie.Database, root common.Hash, origin common.Hash, bytes uint64) ([]common.Hash, [][]byte, [][]byte) {
+	tr, err := trie.New(root, db)
+	if err != nil {
+		return nil, nil, nil
+	}
+	var (
+		hashes   []common.Hash
+		accounts [][]byte
+		size     uint64
+	)
+	it := trie.NewIterator(tr.NodeIterator(origin[:]))
+	for size < bytes && it.Next() {
+		hashes = append(hashes, common.BytesToHash(it.Key))
+		accounts = append(accounts, common.CopyBytes(it.Value))
+		size += uint64(common.HashLength + len(it.Value))
+	}
+	if it.Err != nil {
+		return nil, nil, nil
+	}
+	key := origin
+	if len(hashes) > 0 {
+		key = hashes[len(hashes)-1]
+	}
+	var proof proofList
+	if err := tr.Prove(key[:], 0, &proof); err != nil {
+		return nil, nil, nil
+	}
+	return hashes, accounts, proof
+}
+
+// ServeStorageRanges gathers the slots of the storage tries with the given roots,
+// starting at origin for the first one, until roughly the given number of bytes
+// is collected. The returned flag reports whether the last storage range was cut
+// short. Retrieval stops at the first storage trie not available.
+func ServeStorageRanges(db *trie.Database, roots []common.Hash, origin common.Hash, bytes uint64) ([][]common.Hash, [][][]byte, bool) {
+	var (
+		hashes [][]common.Hash
+		slots  [][][]byte
+		size   uint64
+	)
+	for i, root := range roots {
+		tr, err := trie.New(root, db)
+		if err != nil {
+			break
+		}
+		var start []byte
+		if i == 0 {
+			start = origin[:]
+		}
+		var (
+			keys []common.Hash
+			vals [][]byte
+			more bool
+		)
+		it := trie.NewIterator(tr.NodeIterator(start))
+		for it.Next() {
+			if size >= bytes {
+				more = true
+				break
+			}
+			keys = append(keys, common.BytesToHash(it.Key))
+			vals = append(vals, common.CopyBytes(it.Value))
+			size += uint64(common.HashLength + len(it.Value))
+		}
+		if it.Err != nil {
+			break
+		}
+		hashes, slots = append(hashes, keys), append(slots, vals)
+		if more {
+			return hashes, slots, true
+		}
+		if size >= bytes {
+			break
+		}
+	}
+	return hashes, slots, false
+}
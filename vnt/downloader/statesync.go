@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"hash"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/vntchain/go-vnt/common"
@@ -213,7 +214,9 @@ func (d *Downloader) runStateSync(s *stateSync) *stateSync {
 // stateSync schedules requests for downloading a particular state trie defined
 // by a given state root.
 type stateSync struct {
-	d *Downloader // Downloader instance to access and manage current peerset
+	d    *Downloader // Downloader instance to access and manage current peerset
+	root common.Hash // State root currently being synced
+	snap bool        // Whether to download flat state ranges before the trie node sync
 
 	sched  *trie.Sync                 // State trie sync scheduler defining the tasks
 	keccak hash.Hash                  // Keccak256 hasher to verify deliveries with
@@ -221,6 +224,7 @@ type stateSync struct {
 
 	numUncommitted   int
 	bytesUncommitted int
+	inflight         int // Number of state requests currently being retrieved
 
 	deliver    chan *stateReq // Delivery channel multiplexing peer responses
 	cancel     chan struct{}  // Channel to signal a termination request
@@ -240,6 +244,7 @@ type stateTask struct {
 func newStateSync(d *Downloader, root common.Hash) *stateSync {
 	return &stateSync{
 		d:       d,
+		root:    root,
 		sched:   state.NewStateSync(root, d.stateDB),
 		keccak:  sha3.NewKeccak256(),
 		tasks:   make(map[common.Hash]*stateTask),
@@ -276,6 +281,13 @@ func (s *stateSync) Cancel() error {
 // pushed here async. The reason is to decouple processing from data receipt
 // and timeouts.
 func (s *stateSync) loop() (err error) {
+	// In snap mode, pull the bulk of the state as flat ranges first and leave the
+	// trie node sync below to heal whatever is still missing
+	if s.snap {
+		if err := s.snapSync(); err != nil {
+			return err
+		}
+	}
 	// Listen for new peer events to assign tasks to them
 	newPeer := make(chan *peerConnection, 1024)
 	peerSub := s.d.peers.SubscribeNewPeers(newPeer)
@@ -305,6 +317,8 @@ func (s *stateSync) loop() (err error) {
 			return errCancelStateFetch
 
 		case req := <-s.deliver:
+			s.inflight--
+
 			// Response, disconnect or timeout triggered, drop the peer if stalling
 			log.Trace("Received node data response", "peer", req.peer.id, "count", len(req.response), "dropped", req.dropped, "timeout", !req.dropped && req.timedOut())
 			if len(req.items) <= 2 && !req.dropped && req.timedOut() {
@@ -346,8 +360,13 @@ func (s *stateSync) commit(force bool) error {
 // batch currently being retried, or fetching new data from the trie sync itself.
 func (s *stateSync) assignTasks() {
 	// Iterate over all idle peers and try to assign them state fetches
+	workers := int(atomic.LoadInt32(&s.d.stateWorkers))
 	peers, _ := s.d.peers.NodeDataIdlePeers()
 	for _, p := range peers {
+		// Stop assigning if the concurrent request allowance is exhausted
+		if workers > 0 && s.inflight >= workers {
+			break
+		}
 		// Assign a batch of fetches proportional to the estimated latency/bandwidth
 		cap := p.NodeDataCapacity(s.d.requestRTT())
 		req := &stateReq{peer: p, timeout: s.d.requestTTL()}
@@ -359,6 +378,7 @@ func (s *stateSync) assignTasks() {
 			select {
 			case s.d.trackStateReq <- req:
 				req.peer.FetchNodeData(req.items)
+				s.inflight++
 			case <-s.cancel:
 			case <-s.d.cancelCh:
 			}
@@ -472,6 +492,7 @@ func (s *stateSync) updateStats(written, duplicate, unexpected int, duration tim
 	s.d.syncStatsState.processed += uint64(written)
 	s.d.syncStatsState.duplicate += uint64(duplicate)
 	s.d.syncStatsState.unexpected += uint64(unexpected)
+	syncStatesGauge.Update(int64(s.d.syncStatsState.processed))
 
 	if written > 0 || duplicate > 0 || unexpected > 0 {
 		log.Info("Imported new state entries", "count", written, "elapsed", common.PrettyDuration(duration), "processed", s.d.syncStatsState.processed, "pending", s.d.syncStatsState.pending, "retry", len(s.tasks), "duplicate", s.d.syncStatsState.duplicate, "unexpected", s.d.syncStatsState.unexpected)
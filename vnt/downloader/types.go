@@ -20,6 +20,7 @@ import (
 	"fmt"
 
 	libp2p "github.com/libp2p/go-libp2p-peer"
+	"github.com/vntchain/go-vnt/common"
 	"github.com/vntchain/go-vnt/core/types"
 )
 
@@ -74,3 +75,37 @@ type statePack struct {
 func (p *statePack) PeerId() libp2p.ID { return p.peerId }
 func (p *statePack) Items() int        { return len(p.states) }
 func (p *statePack) Stats() string     { return fmt.Sprintf("%d", len(p.states)) }
+
+// accountRangePack is a range of consecutive accounts returned by a peer.
+type accountRangePack struct {
+	peerId   libp2p.ID
+	hashes   []common.Hash
+	accounts [][]byte
+	proof    [][]byte
+}
+
+func (p *accountRangePack) PeerId() libp2p.ID { return p.peerId }
+func (p *accountRangePack) Items() int        { return len(p.hashes) }
+func (p *accountRangePack) Stats() string     { return fmt.Sprintf("%d", len(p.hashes)) }
+
+// storageRangesPack is a batch of storage slot ranges returned by a peer.
+type storageRangesPack struct {
+	peerId libp2p.ID
+	hashes [][]common.Hash
+	slots  [][][]byte
+	more   bool
+}
+
+func (p *storageRangesPack) PeerId() libp2p.ID { return p.peerId }
+func (p *storageRangesPack) Items() int        { return len(p.hashes) }
+func (p *storageRangesPack) Stats() string     { return fmt.Sprintf("%d", len(p.hashes)) }
+
+// byteCodesPack is a batch of contract codes returned by a peer.
+type byteCodesPack struct {
+	peerId libp2p.ID
+	codes  [][]byte
+}
+
+func (p *byteCodesPack) PeerId() libp2p.ID { return p.peerId }
+func (p *byteCodesPack) Items() int        { return len(p.codes) }
+func (p *byteCodesPack) Stats() string     { return fmt.Sprintf("%d", len(p.codes)) }
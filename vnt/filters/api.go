@@ -28,8 +28,10 @@ import (
 	hubble "github.com/vntchain/go-vnt"
 	"github.com/vntchain/go-vnt/common"
 	"github.com/vntchain/go-vnt/common/hexutil"
+	"github.com/vntchain/go-vnt/core"
 	"github.com/vntchain/go-vnt/core/types"
 	"github.com/vntchain/go-vnt/event"
+	"github.com/vntchain/go-vnt/internal/vntapi"
 	"github.com/vntchain/go-vnt/rpc"
 	"github.com/vntchain/go-vnt/vntdb"
 )
@@ -104,7 +106,7 @@ func (api *PublicFilterAPI) timeoutLoop() {
 // https://github.com/ethereum/wiki/wiki/JSON-RPC#eth_newpendingtransactionfilter
 func (api *PublicFilterAPI) NewPendingTransactionFilter() rpc.ID {
 	var (
-		pendingTxs   = make(chan []common.Hash)
+		pendingTxs   = make(chan []*types.Transaction)
 		pendingTxSub = api.events.SubscribePendingTxs(pendingTxs)
 	)
 
@@ -118,7 +120,9 @@ func (api *PublicFilterAPI) NewPendingTransactionFilter() rpc.ID {
 			case ph := <-pendingTxs:
 				api.filtersMu.Lock()
 				if f, found := api.filters[pendingTxSub.ID]; found {
-					f.hashes = append(f.hashes, ph...)
+					for _, tx := range ph {
+						f.hashes = append(f.hashes, tx.Hash())
+					}
 				}
 				api.filtersMu.Unlock()
 			case <-pendingTxSub.Err():
@@ -135,7 +139,8 @@ func (api *PublicFilterAPI) NewPendingTransactionFilter() rpc.ID {
 
 // NewPendingTransactions creates a subscription that is triggered each time a transaction
 // enters the transaction pool and was signed from one of the transactions this nodes manages.
-func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context) (*rpc.Subscription, error) {
+// If fullTx is true the full transaction objects are sent instead of just their hashes.
+func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context, fullTx *bool) (*rpc.Subscription, error) {
 	notifier, supported := rpc.NotifierFromContext(ctx)
 	if !supported {
 		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
@@ -144,16 +149,20 @@ func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context) (*rpc.Su
 	rpcSub := notifier.CreateSubscription()
 
 	go func() {
-		txHashes := make(chan []common.Hash, 128)
-		pendingTxSub := api.events.SubscribePendingTxs(txHashes)
+		pendingTxs := make(chan []*types.Transaction, 128)
+		pendingTxSub := api.events.SubscribePendingTxs(pendingTxs)
 
 		for {
 			select {
-			case hashes := <-txHashes:
+			case txs := <-pendingTxs:
 				// To keep the original behaviour, send a single tx hash in one notification.
 				// TODO(rjl493456442) Send a batch of tx hashes in one notification
-				for _, h := range hashes {
-					notifier.Notify(rpcSub.ID, h)
+				for _, tx := range txs {
+					if fullTx != nil && *fullTx {
+						notifier.Notify(rpcSub.ID, vntapi.NewRPCPendingTransaction(tx))
+					} else {
+						notifier.Notify(rpcSub.ID, tx.Hash())
+					}
 				}
 			case <-rpcSub.Err():
 				pendingTxSub.Unsubscribe()
@@ -233,6 +242,53 @@ func (api *PublicFilterAPI) NewHeads(ctx context.Context) (*rpc.Subscription, er
 	return rpcSub, nil
 }
 
+// Reorg is the notification of a chain reorg, listing the blocks dropped from
+// and added to the canonical chain after their last common ancestor.
+type Reorg struct {
+	Number  hexutil.Uint64 `json:"number"`
+	Hash    common.Hash    `json:"hash"`
+	Depth   hexutil.Uint64 `json:"depth"`
+	Dropped []common.Hash  `json:"dropped"`
+	Added   []common.Hash  `json:"added"`
+}
+
+// Reorgs send a notification each time blocks of the canonical chain are
+// replaced by the blocks of a competing branch.
+func (api *PublicFilterAPI) Reorgs(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		reorgs := make(chan core.ChainReorgEvent)
+		reorgsSub := api.events.SubscribeReorgs(reorgs)
+
+		for {
+			select {
+			case ev := <-reorgs:
+				notifier.Notify(rpcSub.ID, &Reorg{
+					Number:  hexutil.Uint64(ev.Ancestor.Number.Uint64()),
+					Hash:    ev.Ancestor.Hash(),
+					Depth:   hexutil.Uint64(len(ev.Dropped)),
+					Dropped: ev.Dropped,
+					Added:   ev.Added,
+				})
+			case <-rpcSub.Err():
+				reorgsSub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				reorgsSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
 // Logs creates a subscription that fires for all new log that match the given filter criteria.
 func (api *PublicFilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc.Subscription, error) {
 	notifier, supported := rpc.NotifierFromContext(ctx)
@@ -321,6 +377,9 @@ func (api *PublicFilterAPI) NewFilter(crit FilterCriteria) (rpc.ID, error) {
 }
 
 // GetLogs returns logs matching the given argument that are stored within the state.
+// A log matches if it was emitted by any of the addresses, and its topics match
+// the topic positions, each being a list of alternatives or null for any topic.
+// In case "fromBlock" > "toBlock" an error is returned.
 //
 // https://github.com/ethereum/wiki/wiki/JSON-RPC#eth_getlogs
 func (api *PublicFilterAPI) GetLogs(ctx context.Context, crit FilterCriteria) ([]*types.Log, error) {
@@ -462,6 +521,11 @@ func (args *FilterCriteria) UnmarshalJSON(data []byte) error {
 	if raw.ToBlock != nil {
 		args.ToBlock = big.NewInt(raw.ToBlock.Int64())
 	}
+	if raw.From != nil && raw.ToBlock != nil {
+		if err := checkBlockRange(raw.From.Int64(), raw.ToBlock.Int64()); err != nil {
+			return err
+		}
+	}
 
 	args.Addresses = []common.Address{}
 
@@ -491,8 +555,8 @@ func (args *FilterCriteria) UnmarshalJSON(data []byte) error {
 		}
 	}
 
-	// topics is an array consisting of strings and/or arrays of strings.
-	// JSON null values are converted to common.Hash{} and ignored by the filter manager.
+	// topics is an array consisting of strings and/or arrays of strings. A
+	// null position, or a null within an array, matches any topic there.
 	if len(raw.Topics) > 0 {
 		args.Topics = make([][]common.Hash, len(raw.Topics))
 		for i, t := range raw.Topics {
@@ -504,30 +568,33 @@ func (args *FilterCriteria) UnmarshalJSON(data []byte) error {
 				// match specific topic
 				top, err := decodeTopic(topic)
 				if err != nil {
-					return err
+					return fmt.Errorf("invalid topic at position %d: %v", i, err)
 				}
 				args.Topics[i] = []common.Hash{top}
 
 			case []interface{}:
-				// or case e.g. [null, "topic0", "topic1"]
-				for _, rawTopic := range topic {
-					if rawTopic == nil {
-						// null component, match all
-						args.Topics[i] = nil
-						break
-					}
-					if topic, ok := rawTopic.(string); ok {
-						parsed, err := decodeTopic(topic)
+				// or case e.g. [null, "topic0", "topic1"], all the alternatives
+				// are validated even if a null makes the position a wildcard
+				var wildcard bool
+				for j, rawTopic := range topic {
+					switch rawTopic := rawTopic.(type) {
+					case nil:
+						wildcard = true
+					case string:
+						parsed, err := decodeTopic(rawTopic)
 						if err != nil {
-							return err
+							return fmt.Errorf("invalid topic at position %d, index %d: %v", i, j, err)
 						}
 						args.Topics[i] = append(args.Topics[i], parsed)
-					} else {
-						return fmt.Errorf("invalid topic(s)")
+					default:
+						return fmt.Errorf("non-string topic at position %d, index %d", i, j)
 					}
 				}
+				if wildcard {
+					args.Topics[i] = nil
+				}
 			default:
-				return fmt.Errorf("invalid topic(s)")
+				return fmt.Errorf("invalid topics at position %d: want string, array or null", i)
 			}
 		}
 	}
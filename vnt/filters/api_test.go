@@ -183,3 +183,32 @@ func TestUnmarshalJSONNewFilterArgs(t *testing.T) {
 		t.Fatalf("expected 0 topics, got %d topics", len(test7.Topics[2]))
 	}
 }
+
+// Tests that malformed filter criteria are rejected with an error telling what's wrong.
+func TestUnmarshalJSONInvalidFilterArgs(t *testing.T) {
+	topic := common.HexToHash("3ac225168df54212a25c1c01fd35bebfea408fdac2e31ddd6f80a4bbf9a5f1ca").Hex()
+
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{`{"fromBlock":"0x10","toBlock":"0x1"}`, "invalid block range: fromBlock 16 > toBlock 1"},
+		{`{"address":["0x70c87d191324e6712a591f304b4eedef6ad9bb9d", 1]}`, "non-string address at index 1"},
+		{`{"address":"0x1234"}`, "invalid address: hex has invalid length 2 after decoding"},
+		{`{"topics":["0x1234"]}`, "invalid topic at position 0: hex has invalid length 2 after decoding"},
+		{fmt.Sprintf(`{"topics":[null, [null, "%s", "0x12"]]}`, topic), "invalid topic at position 1, index 2: hex has invalid length 1 after decoding"},
+		{fmt.Sprintf(`{"topics":[["%s", ["%s"]]]}`, topic, topic), "non-string topic at position 0, index 1"},
+		{`{"topics":[1]}`, "invalid topics at position 0: want string, array or null"},
+	}
+	for i, tt := range tests {
+		var crit FilterCriteria
+		if err := json.Unmarshal([]byte(tt.input), &crit); err == nil || err.Error() != tt.err {
+			t.Errorf("test %d: error mismatch: have %v, want %q", i, err, tt.err)
+		}
+	}
+	// Symbolic block numbers are resolved when the filter runs
+	var crit FilterCriteria
+	if err := json.Unmarshal([]byte(`{"fromBlock":"0x10","toBlock":"latest"}`), &crit); err != nil {
+		t.Errorf("open block range rejected: %v", err)
+	}
+}
@@ -18,6 +18,7 @@ package filters
 
 import (
 	"context"
+	"fmt"
 	"math/big"
 
 	"github.com/vntchain/go-vnt/common"
@@ -40,11 +41,28 @@ type Backend interface {
 	SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription
 	SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent) event.Subscription
 	SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription
+	SubscribeChainReorgEvent(ch chan<- core.ChainReorgEvent) event.Subscription
 
 	BloomStatus() (uint64, uint64)
 	ServiceFilter(ctx context.Context, session *bloombits.MatcherSession)
 }
 
+// checkBlockRange returns an error if a bound of a block range is neither a block
+// number nor a known symbolic one, or if the numbered bounds are reversed.
+// Symbolic bounds like "latest" are resolved against the chain head only once
+// the filter runs.
+func checkBlockRange(from, to int64) error {
+	for _, number := range []int64{from, to} {
+		if number < rpc.IrreversibleBlockNumber.Int64() {
+			return fmt.Errorf("invalid block number %d", number)
+		}
+	}
+	if from >= 0 && to >= 0 && from > to {
+		return fmt.Errorf("invalid block range: fromBlock %d > toBlock %d", from, to)
+	}
+	return nil
+}
+
 // Filter can be used to retrieve and filter logs.
 type Filter struct {
 	backend Backend
@@ -95,20 +113,36 @@ func New(backend Backend, begin, end int64, addresses []common.Address, topics [
 // Logs searches the blockchain for matching log entries, returning all from the
 // first block that contains matches, updating the start of the filter accordingly.
 func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
-	// Figure out the limits of the filter range
+	if err := checkBlockRange(f.begin, f.end); err != nil {
+		return nil, err
+	}
+	// Figure out the limits of the filter range, pending logs aren't stored so
+	// the pending block stands for the latest one
 	header, _ := f.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
 	if header == nil {
 		return nil, nil
 	}
 	head := header.Number.Uint64()
 
-	if f.begin == -1 {
+	if f.begin == rpc.LatestBlockNumber.Int64() || f.begin == rpc.PendingBlockNumber.Int64() {
 		f.begin = int64(head)
 	}
 	end := uint64(f.end)
-	if f.end == -1 {
+	if f.end == rpc.LatestBlockNumber.Int64() || f.end == rpc.PendingBlockNumber.Int64() {
 		end = head
 	}
+	if f.begin == rpc.IrreversibleBlockNumber.Int64() || f.end == rpc.IrreversibleBlockNumber.Int64() {
+		header, _ := f.backend.HeaderByNumber(ctx, rpc.IrreversibleBlockNumber)
+		if header == nil {
+			return nil, nil
+		}
+		if f.begin == rpc.IrreversibleBlockNumber.Int64() {
+			f.begin = header.Number.Int64()
+		}
+		if f.end == rpc.IrreversibleBlockNumber.Int64() {
+			end = header.Number.Uint64()
+		}
+	}
 	// Gather all indexed logs, and finish with non indexed ones
 	var (
 		logs []*types.Log
@@ -33,11 +33,16 @@ import (
 	"github.com/vntchain/go-vnt/core/rawdb"
 	"github.com/vntchain/go-vnt/core/types"
 	"github.com/vntchain/go-vnt/event"
+	"github.com/vntchain/go-vnt/internal/vntapi"
 	"github.com/vntchain/go-vnt/params"
 	"github.com/vntchain/go-vnt/rpc"
 	"github.com/vntchain/go-vnt/vntdb"
 )
 
+// testIrreversibleDepth is the number of blocks behind the head at which the
+// test backend considers blocks irreversible.
+const testIrreversibleDepth = 100
+
 type testBackend struct {
 	mux        *event.TypeMux
 	db         vntdb.Database
@@ -46,6 +51,7 @@ type testBackend struct {
 	rmLogsFeed *event.Feed
 	logsFeed   *event.Feed
 	chainFeed  *event.Feed
+	reorgFeed  *event.Feed
 }
 
 func (b *testBackend) ChainDb() vntdb.Database {
@@ -61,13 +67,21 @@ func (b *testBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumbe
 		hash common.Hash
 		num  uint64
 	)
-	if blockNr == rpc.LatestBlockNumber {
+	if blockNr == rpc.LatestBlockNumber || blockNr == rpc.IrreversibleBlockNumber {
 		hash = rawdb.ReadHeadBlockHash(b.db)
 		number := rawdb.ReadHeaderNumber(b.db, hash)
 		if number == nil {
 			return nil, nil
 		}
 		num = *number
+		if blockNr == rpc.IrreversibleBlockNumber {
+			// Blocks become irreversible a fixed depth behind the head
+			if num < testIrreversibleDepth {
+				return nil, nil
+			}
+			num -= testIrreversibleDepth
+			hash = rawdb.ReadCanonicalHash(b.db, num)
+		}
 	} else {
 		num = uint64(blockNr)
 		hash = rawdb.ReadCanonicalHash(b.db, num)
@@ -112,6 +126,10 @@ func (b *testBackend) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subsc
 	return b.chainFeed.Subscribe(ch)
 }
 
+func (b *testBackend) SubscribeChainReorgEvent(ch chan<- core.ChainReorgEvent) event.Subscription {
+	return b.reorgFeed.Subscribe(ch)
+}
+
 func (b *testBackend) BloomStatus() (uint64, uint64) {
 	return params.BloomBitsBlocks, b.sections
 }
@@ -158,7 +176,7 @@ func TestBlockSubscription(t *testing.T) {
 		rmLogsFeed  = new(event.Feed)
 		logsFeed    = new(event.Feed)
 		chainFeed   = new(event.Feed)
-		backend     = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend     = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
 		api         = NewPublicFilterAPI(backend, false)
 		genesis     = new(core.Genesis).MustCommit(db)
 		chain, _    = core.GenerateChain(params.TestChainConfig, genesis, mock.NewMock(), db, 10, func(i int, gen *core.BlockGen) {})
@@ -204,6 +222,39 @@ func TestBlockSubscription(t *testing.T) {
 	<-sub1.Err()
 }
 
+// TestReorgSubscription tests if a reorg subscription receives the blocks
+// replaced by every chain reorg.
+func TestReorgSubscription(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mux       = new(event.TypeMux)
+		db        = vntdb.NewMemDatabase()
+		reorgFeed = new(event.Feed)
+		backend   = &testBackend{mux, db, 0, new(event.Feed), new(event.Feed), new(event.Feed), new(event.Feed), reorgFeed}
+		api       = NewPublicFilterAPI(backend, false)
+		genesis   = new(core.Genesis).MustCommit(db)
+	)
+	want := core.ChainReorgEvent{
+		Ancestor: genesis.Header(),
+		Dropped:  []common.Hash{common.HexToHash("0x02"), common.HexToHash("0x01")},
+		Added:    []common.Hash{common.HexToHash("0x11"), common.HexToHash("0x12"), common.HexToHash("0x13")},
+	}
+	reorgs := make(chan core.ChainReorgEvent)
+	sub := api.events.SubscribeReorgs(reorgs)
+	defer sub.Unsubscribe()
+
+	reorgFeed.Send(want)
+	select {
+	case have := <-reorgs:
+		if !reflect.DeepEqual(have, want) {
+			t.Errorf("reorg mismatch: have %+v, want %+v", have, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("reorg not delivered")
+	}
+}
+
 // TestPendingTxFilter tests whether pending tx filters retrieve all pending transactions that are posted to the event mux.
 func TestPendingTxFilter(t *testing.T) {
 	t.Parallel()
@@ -215,7 +266,7 @@ func TestPendingTxFilter(t *testing.T) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
 		api        = NewPublicFilterAPI(backend, false)
 
 		transactions = []*types.Transaction{
@@ -265,6 +316,73 @@ func TestPendingTxFilter(t *testing.T) {
 	}
 }
 
+// TestPendingTxSubscription tests whether pending tx subscriptions deliver
+// either transaction hashes or full transactions as requested.
+func TestPendingTxSubscription(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mux        = new(event.TypeMux)
+		db         = vntdb.NewMemDatabase()
+		txFeed     = new(event.Feed)
+		rmLogsFeed = new(event.Feed)
+		logsFeed   = new(event.Feed)
+		chainFeed  = new(event.Feed)
+		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
+		api        = NewPublicFilterAPI(backend, false)
+
+		transactions = []*types.Transaction{
+			types.NewTransaction(0, common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268"), big.NewInt(1), 0, new(big.Int), nil),
+			types.NewTransaction(1, common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268"), big.NewInt(2), 0, new(big.Int), nil),
+		}
+	)
+	server := rpc.NewServer()
+	if err := server.RegisterName("core", api); err != nil {
+		t.Fatalf("failed to register filter API: %v", err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	hashCh := make(chan common.Hash, len(transactions))
+	hashSub, err := client.VntSubscribe(context.Background(), hashCh, "newPendingTransactions")
+	if err != nil {
+		t.Fatalf("failed to subscribe to hashes: %v", err)
+	}
+	defer hashSub.Unsubscribe()
+
+	fullCh := make(chan *vntapi.RPCTransaction, len(transactions))
+	fullSub, err := client.VntSubscribe(context.Background(), fullCh, "newPendingTransactions", true)
+	if err != nil {
+		t.Fatalf("failed to subscribe to full transactions: %v", err)
+	}
+	defer fullSub.Unsubscribe()
+
+	time.Sleep(1 * time.Second)
+	txFeed.Send(core.NewTxsEvent{Txs: transactions})
+
+	for i, tx := range transactions {
+		select {
+		case hash := <-hashCh:
+			if hash != tx.Hash() {
+				t.Errorf("tx %d: hash mismatch: have %x, want %x", i, hash, tx.Hash())
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("tx %d: hash not delivered", i)
+		}
+		select {
+		case full := <-fullCh:
+			if full.Hash != tx.Hash() {
+				t.Errorf("tx %d: hash mismatch: have %x, want %x", i, full.Hash, tx.Hash())
+			}
+			if full.Value.ToInt().Cmp(tx.Value()) != 0 {
+				t.Errorf("tx %d: value mismatch: have %v, want %v", i, full.Value, tx.Value())
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("tx %d: full transaction not delivered", i)
+		}
+	}
+}
+
 // TestLogFilterCreation test whether a given filter criteria makes sense.
 // If not it must return an error.
 func TestLogFilterCreation(t *testing.T) {
@@ -275,7 +393,7 @@ func TestLogFilterCreation(t *testing.T) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
 		api        = NewPublicFilterAPI(backend, false)
 
 		testCases = []struct {
@@ -324,7 +442,7 @@ func TestInvalidLogFilterCreation(t *testing.T) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
 		api        = NewPublicFilterAPI(backend, false)
 	)
 
@@ -354,7 +472,7 @@ func TestLogFilter(t *testing.T) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
 		api        = NewPublicFilterAPI(backend, false)
 
 		firstAddr      = common.HexToAddress("0x1111111111111111111111111111111111111111")
@@ -473,7 +591,7 @@ func TestPendingLogsSubscription(t *testing.T) {
 		rmLogsFeed = new(event.Feed)
 		logsFeed   = new(event.Feed)
 		chainFeed  = new(event.Feed)
-		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed}
+		backend    = &testBackend{mux, db, 0, txFeed, rmLogsFeed, logsFeed, chainFeed, new(event.Feed)}
 		api        = NewPublicFilterAPI(backend, false)
 
 		firstAddr      = common.HexToAddress("0x1111111111111111111111111111111111111111")
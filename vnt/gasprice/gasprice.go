@@ -22,36 +22,55 @@ import (
 	"sort"
 	"sync"
 
+	lru "github.com/hashicorp/golang-lru"
 	"github.com/vntchain/go-vnt/common"
 	"github.com/vntchain/go-vnt/core/types"
-	"github.com/vntchain/go-vnt/internal/vntapi"
+	"github.com/vntchain/go-vnt/log"
 	"github.com/vntchain/go-vnt/params"
 	"github.com/vntchain/go-vnt/rpc"
 )
 
-var maxPrice = big.NewInt(500 * params.Gwei)
+// DefaultMaxPrice is the highest gas price suggested unless configured otherwise.
+var DefaultMaxPrice = big.NewInt(500 * params.Gwei)
+
+// blockPriceCacheSize is the number of recent blocks whose lowest price is kept,
+// enough to cover the blocks checked for a few heads in a row.
+const blockPriceCacheSize = 1024
 
 type Config struct {
-	Blocks     int
-	Percentile int
-	Default    *big.Int `toml:",omitempty"`
+	Blocks      int
+	Percentile  int
+	Default     *big.Int `toml:",omitempty"`
+	MaxPrice    *big.Int `toml:",omitempty"` // Highest price suggested
+	IgnorePrice *big.Int `toml:",omitempty"` // Transactions priced lower are not sampled
+}
+
+// OracleBackend is the chain access needed by the oracle, implemented by both
+// the full and the light client API backends.
+type OracleBackend interface {
+	HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error)
+	BlockByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Block, error)
+	ChainConfig() *params.ChainConfig
 }
 
 // Oracle recommends gas prices based on the content of recent
 // blocks. Suitable for both light and full clients.
 type Oracle struct {
-	backend   vntapi.Backend
+	backend   OracleBackend
 	lastHead  common.Hash
 	lastPrice *big.Int
 	cacheLock sync.RWMutex
 	fetchLock sync.Mutex
 
+	blockPrices *lru.Cache // Lowest sampled price of recent blocks, nil for blocks without any
+
 	checkBlocks, maxEmpty, maxBlocks int
 	percentile                       int
+	maxPrice, ignorePrice            *big.Int
 }
 
 // NewOracle returns a new oracle.
-func NewOracle(backend vntapi.Backend, params Config) *Oracle {
+func NewOracle(backend OracleBackend, params Config) *Oracle {
 	blocks := params.Blocks
 	if blocks < 1 {
 		blocks = 1
@@ -63,13 +82,26 @@ func NewOracle(backend vntapi.Backend, params Config) *Oracle {
 	if percent > 100 {
 		percent = 100
 	}
+	maxPrice := params.MaxPrice
+	if maxPrice == nil || maxPrice.Sign() <= 0 {
+		maxPrice = DefaultMaxPrice
+		log.Warn("Sanitizing invalid gasprice oracle price cap", "provided", params.MaxPrice, "updated", maxPrice)
+	}
+	ignorePrice := params.IgnorePrice
+	if ignorePrice == nil || ignorePrice.Sign() < 0 {
+		ignorePrice = new(big.Int)
+	}
+	cache, _ := lru.New(blockPriceCacheSize)
 	return &Oracle{
 		backend:     backend,
 		lastPrice:   params.Default,
+		blockPrices: cache,
 		checkBlocks: blocks,
 		maxEmpty:    blocks / 2,
 		maxBlocks:   blocks * 5,
 		percentile:  percent,
+		maxPrice:    maxPrice,
+		ignorePrice: ignorePrice,
 	}
 }
 
@@ -80,7 +112,10 @@ func (gpo *Oracle) SuggestPrice(ctx context.Context) (*big.Int, error) {
 	lastPrice := gpo.lastPrice
 	gpo.cacheLock.RUnlock()
 
-	head, _ := gpo.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	head, err := gpo.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if head == nil {
+		return lastPrice, err
+	}
 	headHash := head.Hash()
 	if headHash == lastHead {
 		return lastPrice, nil
@@ -136,8 +171,8 @@ func (gpo *Oracle) SuggestPrice(ctx context.Context) (*big.Int, error) {
 		sort.Sort(bigIntArray(blockPrices))
 		price = blockPrices[(len(blockPrices)-1)*gpo.percentile/100]
 	}
-	if price.Cmp(maxPrice) > 0 {
-		price = new(big.Int).Set(maxPrice)
+	if price.Cmp(gpo.maxPrice) > 0 {
+		price = new(big.Int).Set(gpo.maxPrice)
 	}
 
 	gpo.cacheLock.Lock()
@@ -160,7 +195,18 @@ func (t transactionsByGasPrice) Less(i, j int) bool { return t[i].GasPrice().Cmp
 
 // getBlockPrices calculates the lowest transaction gas price in a given block
 // and sends it to the result channel. If the block is empty, price is nil.
+// Transactions priced below the ignore price are skipped. Blocks seen before
+// are served from the cache without fetching their transactions again.
 func (gpo *Oracle) getBlockPrices(ctx context.Context, signer types.Signer, blockNum uint64, ch chan getBlockPricesResult) {
+	header, err := gpo.backend.HeaderByNumber(ctx, rpc.BlockNumber(blockNum))
+	if header == nil {
+		ch <- getBlockPricesResult{nil, err}
+		return
+	}
+	if price, ok := gpo.blockPrices.Get(header.Hash()); ok {
+		ch <- getBlockPricesResult{price.(*big.Int), nil}
+		return
+	}
 	block, err := gpo.backend.BlockByNumber(ctx, rpc.BlockNumber(blockNum))
 	if block == nil {
 		ch <- getBlockPricesResult{nil, err}
@@ -172,14 +218,19 @@ func (gpo *Oracle) getBlockPrices(ctx context.Context, signer types.Signer, bloc
 	copy(txs, blockTxs)
 	sort.Sort(transactionsByGasPrice(txs))
 
+	var price *big.Int
 	for _, tx := range txs {
+		if tx.GasPrice().Cmp(gpo.ignorePrice) < 0 {
+			continue
+		}
 		sender, err := types.Sender(signer, tx)
 		if err == nil && sender != block.Coinbase() {
-			ch <- getBlockPricesResult{tx.GasPrice(), nil}
-			return
+			price = tx.GasPrice()
+			break
 		}
 	}
-	ch <- getBlockPricesResult{nil, nil}
+	gpo.blockPrices.Add(block.Hash(), price)
+	ch <- getBlockPricesResult{price, nil}
 }
 
 type bigIntArray []*big.Int
@@ -0,0 +1,119 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/core/types"
+	"github.com/vntchain/go-vnt/crypto"
+	"github.com/vntchain/go-vnt/params"
+	"github.com/vntchain/go-vnt/rpc"
+)
+
+// testBackend serves a chain of blocks, counting the blocks fetched.
+type testBackend struct {
+	blocks  []*types.Block
+	fetched int
+}
+
+func (b *testBackend) block(number rpc.BlockNumber) *types.Block {
+	if number == rpc.LatestBlockNumber {
+		return b.blocks[len(b.blocks)-1]
+	}
+	if int(number) >= len(b.blocks) {
+		return nil
+	}
+	return b.blocks[number]
+}
+
+func (b *testBackend) HeaderByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Header, error) {
+	if block := b.block(number); block != nil {
+		return block.Header(), nil
+	}
+	return nil, nil
+}
+
+func (b *testBackend) BlockByNumber(ctx context.Context, number rpc.BlockNumber) (*types.Block, error) {
+	b.fetched++
+	return b.block(number), nil
+}
+
+func (b *testBackend) ChainConfig() *params.ChainConfig {
+	return params.TestChainConfig
+}
+
+// newTestBackend creates a chain whose blocks each hold a single transaction
+// with the given gas price.
+func newTestBackend(t *testing.T, prices ...int64) *testBackend {
+	key, _ := crypto.GenerateKey()
+	signer := types.NewHubbleSigner(params.TestChainConfig.ChainID)
+
+	backend := &testBackend{blocks: []*types.Block{types.NewBlockWithHeader(&types.Header{Number: new(big.Int)})}}
+	for i, price := range prices {
+		tx, err := types.SignTx(types.NewTransaction(uint64(i), common.Address{}, new(big.Int), params.TxGas, big.NewInt(price), nil), signer, key)
+		if err != nil {
+			t.Fatalf("failed to sign transaction: %v", err)
+		}
+		header := &types.Header{Number: big.NewInt(int64(i + 1)), ParentHash: backend.blocks[i].Hash()}
+		backend.blocks = append(backend.blocks, types.NewBlock(header, []*types.Transaction{tx}, nil))
+	}
+	return backend
+}
+
+// Tests that suggestions are capped, ignore dust priced transactions and reuse
+// the prices of blocks already seen.
+func TestSuggestPrice(t *testing.T) {
+	tests := []struct {
+		config Config
+		want   int64
+	}{
+		{Config{Blocks: 4, Percentile: 50, MaxPrice: big.NewInt(1000)}, 10},
+		{Config{Blocks: 4, Percentile: 100, MaxPrice: big.NewInt(30)}, 30},
+		{Config{Blocks: 4, Percentile: 0, MaxPrice: big.NewInt(1000), IgnorePrice: big.NewInt(5)}, 10},
+		{Config{Blocks: 4, Percentile: 100}, 40},
+	}
+	for i, tt := range tests {
+		backend := newTestBackend(t, 1, 10, 20, 40)
+		oracle := NewOracle(backend, tt.config)
+		price, err := oracle.SuggestPrice(context.Background())
+		if err != nil {
+			t.Fatalf("test %d: failed to suggest price: %v", i, err)
+		}
+		if price.Int64() != tt.want {
+			t.Errorf("test %d: price mismatch: have %v, want %v", i, price, tt.want)
+		}
+	}
+
+	backend := newTestBackend(t, 1, 10, 20, 40)
+	oracle := NewOracle(backend, Config{Blocks: 4, Percentile: 50})
+	if _, err := oracle.SuggestPrice(context.Background()); err != nil {
+		t.Fatalf("failed to suggest price: %v", err)
+	}
+	extended := newTestBackend(t, 1, 10, 20, 40, 50)
+	backend.blocks = append(backend.blocks, extended.blocks[len(extended.blocks)-1])
+	backend.fetched = 0
+	if _, err := oracle.SuggestPrice(context.Background()); err != nil {
+		t.Fatalf("failed to suggest price: %v", err)
+	}
+	if backend.fetched != 1 {
+		t.Errorf("fetched blocks mismatch: have %d, want %d", backend.fetched, 1)
+	}
+}
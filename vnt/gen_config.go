@@ -4,10 +4,13 @@ package vnt
 
 import (
 	"math/big"
+	"time"
 
 	"github.com/vntchain/go-vnt/common"
 	"github.com/vntchain/go-vnt/common/hexutil"
+	"github.com/vntchain/go-vnt/consensus/dpos"
 	"github.com/vntchain/go-vnt/core"
+	"github.com/vntchain/go-vnt/params"
 	"github.com/vntchain/go-vnt/vnt/downloader"
 	"github.com/vntchain/go-vnt/vnt/gasprice"
 )
@@ -19,34 +22,90 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		Genesis                 *core.Genesis `toml:",omitempty"`
 		NetworkId               uint64
 		SyncMode                downloader.SyncMode
-		LightServ               int  `toml:",omitempty"`
-		LightPeers              int  `toml:",omitempty"`
-		SkipBcVersionCheck      bool `toml:"-"`
-		DatabaseHandles         int  `toml:"-"`
+		NoPruning               bool
+		TxLookupLimit           uint64                    `toml:",omitempty"`
+		StateHistory            uint64                    `toml:",omitempty"`
+		PruneSchedule           string                    `toml:",omitempty"`
+		PruneBloomSize          uint64                    `toml:",omitempty"`
+		StateWorkers            int                       `toml:",omitempty"`
+		LightServ               int                       `toml:",omitempty"`
+		LightPeers              int                       `toml:",omitempty"`
+		Checkpoint              *params.TrustedCheckpoint `toml:",omitempty"`
+		SkipBcVersionCheck      bool                      `toml:"-"`
+		DatabaseHandles         int                       `toml:"-"`
 		DatabaseCache           int
+		TrieCache               int
+		TrieTimeout             time.Duration
+		TriePrefetchCache       int
+		SnapshotCache           int            `toml:",omitempty"`
+		BlockCache              int            `toml:",omitempty"`
+		ReceiptCache            int            `toml:",omitempty"`
+		SenderCache             int            `toml:",omitempty"`
+		NoPreimages             bool           `toml:",omitempty"`
+		DatabaseStats           time.Duration  `toml:",omitempty"`
+		ReadReplica             string         `toml:",omitempty"`
+		DatabaseFreezer         string         `toml:",omitempty"`
 		Coinbase                common.Address `toml:",omitempty"`
+		Signer                  common.Address `toml:",omitempty"`
 		ExtraData               hexutil.Bytes  `toml:",omitempty"`
 		GasPrice                *big.Int
+		Standby                 bool   `toml:",omitempty"`
+		StandbyMisses           uint64 `toml:",omitempty"`
+		ProducerLock            string `toml:",omitempty"`
+		DposTiming              dpos.Timing
 		TxPool                  core.TxPoolConfig
 		GPO                     gasprice.Config
 		EnablePreimageRecording bool
+		VMMemoryCap             uint64
+		AllowUnprotectedTxs     bool
+		RPCGasCap               uint64
+		RPCEVMTimeout           time.Duration
+		RPCBeamReads            bool   `toml:",omitempty"`
 		DocRoot                 string `toml:"-"`
 	}
 	var enc Config
 	enc.Genesis = c.Genesis
 	enc.NetworkId = c.NetworkId
 	enc.SyncMode = c.SyncMode
+	enc.NoPruning = c.NoPruning
+	enc.TxLookupLimit = c.TxLookupLimit
+	enc.StateHistory = c.StateHistory
+	enc.PruneSchedule = c.PruneSchedule
+	enc.PruneBloomSize = c.PruneBloomSize
+	enc.StateWorkers = c.StateWorkers
 	enc.LightServ = c.LightServ
 	enc.LightPeers = c.LightPeers
+	enc.Checkpoint = c.Checkpoint
 	enc.SkipBcVersionCheck = c.SkipBcVersionCheck
 	enc.DatabaseHandles = c.DatabaseHandles
 	enc.DatabaseCache = c.DatabaseCache
+	enc.TrieCache = c.TrieCache
+	enc.TrieTimeout = c.TrieTimeout
+	enc.TriePrefetchCache = c.TriePrefetchCache
+	enc.SnapshotCache = c.SnapshotCache
+	enc.BlockCache = c.BlockCache
+	enc.ReceiptCache = c.ReceiptCache
+	enc.SenderCache = c.SenderCache
+	enc.NoPreimages = c.NoPreimages
+	enc.DatabaseStats = c.DatabaseStats
+	enc.ReadReplica = c.ReadReplica
+	enc.DatabaseFreezer = c.DatabaseFreezer
 	enc.Coinbase = c.Coinbase
+	enc.Signer = c.Signer
 	enc.ExtraData = c.ExtraData
 	enc.GasPrice = c.GasPrice
+	enc.Standby = c.Standby
+	enc.StandbyMisses = c.StandbyMisses
+	enc.ProducerLock = c.ProducerLock
+	enc.DposTiming = c.DposTiming
 	enc.TxPool = c.TxPool
 	enc.GPO = c.GPO
 	enc.EnablePreimageRecording = c.EnablePreimageRecording
+	enc.VMMemoryCap = c.VMMemoryCap
+	enc.AllowUnprotectedTxs = c.AllowUnprotectedTxs
+	enc.RPCGasCap = c.RPCGasCap
+	enc.RPCEVMTimeout = c.RPCEVMTimeout
+	enc.RPCBeamReads = c.RPCBeamReads
 	enc.DocRoot = c.DocRoot
 	return &enc, nil
 }
@@ -56,18 +115,46 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		Genesis                 *core.Genesis `toml:",omitempty"`
 		NetworkId               *uint64
 		SyncMode                *downloader.SyncMode
-		LightServ               *int  `toml:",omitempty"`
-		LightPeers              *int  `toml:",omitempty"`
-		SkipBcVersionCheck      *bool `toml:"-"`
-		DatabaseHandles         *int  `toml:"-"`
+		NoPruning               *bool
+		TxLookupLimit           *uint64                   `toml:",omitempty"`
+		StateHistory            *uint64                   `toml:",omitempty"`
+		PruneSchedule           *string                   `toml:",omitempty"`
+		PruneBloomSize          *uint64                   `toml:",omitempty"`
+		StateWorkers            *int                      `toml:",omitempty"`
+		LightServ               *int                      `toml:",omitempty"`
+		LightPeers              *int                      `toml:",omitempty"`
+		Checkpoint              *params.TrustedCheckpoint `toml:",omitempty"`
+		SkipBcVersionCheck      *bool                     `toml:"-"`
+		DatabaseHandles         *int                      `toml:"-"`
 		DatabaseCache           *int
+		TrieCache               *int
+		TrieTimeout             *time.Duration
+		TriePrefetchCache       *int
+		SnapshotCache           *int            `toml:",omitempty"`
+		BlockCache              *int            `toml:",omitempty"`
+		ReceiptCache            *int            `toml:",omitempty"`
+		SenderCache             *int            `toml:",omitempty"`
+		NoPreimages             *bool           `toml:",omitempty"`
+		DatabaseStats           *time.Duration  `toml:",omitempty"`
+		ReadReplica             *string         `toml:",omitempty"`
+		DatabaseFreezer         *string         `toml:",omitempty"`
 		Coinbase                *common.Address `toml:",omitempty"`
+		Signer                  *common.Address `toml:",omitempty"`
 		MinerThreads            *int            `toml:",omitempty"`
 		ExtraData               *hexutil.Bytes  `toml:",omitempty"`
 		GasPrice                *big.Int
+		Standby                 *bool   `toml:",omitempty"`
+		StandbyMisses           *uint64 `toml:",omitempty"`
+		ProducerLock            *string `toml:",omitempty"`
+		DposTiming              *dpos.Timing
 		TxPool                  *core.TxPoolConfig
 		GPO                     *gasprice.Config
 		EnablePreimageRecording *bool
+		VMMemoryCap             *uint64
+		AllowUnprotectedTxs     *bool
+		RPCGasCap               *uint64
+		RPCEVMTimeout           *time.Duration
+		RPCBeamReads            *bool   `toml:",omitempty"`
 		DocRoot                 *string `toml:"-"`
 	}
 	var dec Config
@@ -83,12 +170,33 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.SyncMode != nil {
 		c.SyncMode = *dec.SyncMode
 	}
+	if dec.NoPruning != nil {
+		c.NoPruning = *dec.NoPruning
+	}
+	if dec.TxLookupLimit != nil {
+		c.TxLookupLimit = *dec.TxLookupLimit
+	}
+	if dec.StateHistory != nil {
+		c.StateHistory = *dec.StateHistory
+	}
+	if dec.PruneSchedule != nil {
+		c.PruneSchedule = *dec.PruneSchedule
+	}
+	if dec.PruneBloomSize != nil {
+		c.PruneBloomSize = *dec.PruneBloomSize
+	}
+	if dec.StateWorkers != nil {
+		c.StateWorkers = *dec.StateWorkers
+	}
 	if dec.LightServ != nil {
 		c.LightServ = *dec.LightServ
 	}
 	if dec.LightPeers != nil {
 		c.LightPeers = *dec.LightPeers
 	}
+	if dec.Checkpoint != nil {
+		c.Checkpoint = dec.Checkpoint
+	}
 	if dec.SkipBcVersionCheck != nil {
 		c.SkipBcVersionCheck = *dec.SkipBcVersionCheck
 	}
@@ -98,15 +206,63 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.DatabaseCache != nil {
 		c.DatabaseCache = *dec.DatabaseCache
 	}
+	if dec.TrieCache != nil {
+		c.TrieCache = *dec.TrieCache
+	}
+	if dec.TrieTimeout != nil {
+		c.TrieTimeout = *dec.TrieTimeout
+	}
+	if dec.TriePrefetchCache != nil {
+		c.TriePrefetchCache = *dec.TriePrefetchCache
+	}
+	if dec.SnapshotCache != nil {
+		c.SnapshotCache = *dec.SnapshotCache
+	}
+	if dec.BlockCache != nil {
+		c.BlockCache = *dec.BlockCache
+	}
+	if dec.ReceiptCache != nil {
+		c.ReceiptCache = *dec.ReceiptCache
+	}
+	if dec.SenderCache != nil {
+		c.SenderCache = *dec.SenderCache
+	}
+	if dec.NoPreimages != nil {
+		c.NoPreimages = *dec.NoPreimages
+	}
+	if dec.DatabaseStats != nil {
+		c.DatabaseStats = *dec.DatabaseStats
+	}
+	if dec.ReadReplica != nil {
+		c.ReadReplica = *dec.ReadReplica
+	}
+	if dec.DatabaseFreezer != nil {
+		c.DatabaseFreezer = *dec.DatabaseFreezer
+	}
 	if dec.Coinbase != nil {
 		c.Coinbase = *dec.Coinbase
 	}
+	if dec.Signer != nil {
+		c.Signer = *dec.Signer
+	}
 	if dec.ExtraData != nil {
 		c.ExtraData = *dec.ExtraData
 	}
 	if dec.GasPrice != nil {
 		c.GasPrice = dec.GasPrice
 	}
+	if dec.Standby != nil {
+		c.Standby = *dec.Standby
+	}
+	if dec.StandbyMisses != nil {
+		c.StandbyMisses = *dec.StandbyMisses
+	}
+	if dec.ProducerLock != nil {
+		c.ProducerLock = *dec.ProducerLock
+	}
+	if dec.DposTiming != nil {
+		c.DposTiming = *dec.DposTiming
+	}
 	if dec.TxPool != nil {
 		c.TxPool = *dec.TxPool
 	}
@@ -116,6 +272,21 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.EnablePreimageRecording != nil {
 		c.EnablePreimageRecording = *dec.EnablePreimageRecording
 	}
+	if dec.VMMemoryCap != nil {
+		c.VMMemoryCap = *dec.VMMemoryCap
+	}
+	if dec.AllowUnprotectedTxs != nil {
+		c.AllowUnprotectedTxs = *dec.AllowUnprotectedTxs
+	}
+	if dec.RPCGasCap != nil {
+		c.RPCGasCap = *dec.RPCGasCap
+	}
+	if dec.RPCEVMTimeout != nil {
+		c.RPCEVMTimeout = *dec.RPCEVMTimeout
+	}
+	if dec.RPCBeamReads != nil {
+		c.RPCBeamReads = *dec.RPCBeamReads
+	}
 	if dec.DocRoot != nil {
 		c.DocRoot = *dec.DocRoot
 	}
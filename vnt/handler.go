@@ -68,6 +68,7 @@ type ProtocolManager struct {
 	networkId uint64
 
 	fastSync  uint32 // Flag whether fast sync is enabled (gets disabled if we already have blocks)
+	snapSync  uint32 // Flag whether fast sync should retrieve the state as flat ranges
 	acceptTxs uint32 // Flag whether we're considered synchronised (enables transaction processing)
 
 	txpool      txPool
@@ -79,6 +80,7 @@ type ProtocolManager struct {
 	fetcher    *fetcher.Fetcher
 	peers      *peerSet
 	node       *node.Node
+	beam       *beamFetcher // Fetcher of state missing for RPC reads (nil = disabled)
 
 	SubProtocols []vntp2p.Protocol
 
@@ -121,19 +123,22 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 
 		urlsCh: make(chan []string),
 	}
-	// Figure out whether to allow fast sync or not
-	if mode == downloader.FastSync && blockchain.CurrentBlock().NumberU64() > 0 {
+	// Figure out whether to allow fast (or snap) sync or not
+	if (mode == downloader.FastSync || mode == downloader.SnapSync) && blockchain.CurrentBlock().NumberU64() > 0 {
 		log.Warn("Blockchain not empty, fast sync disabled")
 		mode = downloader.FullSync
 	}
-	if mode == downloader.FastSync {
+	if mode == downloader.FastSync || mode == downloader.SnapSync {
 		manager.fastSync = uint32(1)
 	}
+	if mode == downloader.SnapSync {
+		manager.snapSync = uint32(1)
+	}
 	// Initiate a sub-protocol for every implemented version we can handle
 	manager.SubProtocols = make([]vntp2p.Protocol, 0, len(ProtocolVersions))
 	for i, version := range ProtocolVersions {
 		// Skip protocol version if incompatible with the mode of operation
-		if mode == downloader.FastSync && version < vnt63 {
+		if (mode == downloader.FastSync || mode == downloader.SnapSync) && version < vnt63 {
 			continue
 		}
 		// Compatible; initialise the sub-protocol
@@ -200,6 +205,9 @@ func (pm *ProtocolManager) removePeer(id libp2p.ID) {
 
 	// Unregister the peer from the downloader and VNT peer set
 	pm.downloader.UnregisterPeer(id)
+	if pm.beam != nil {
+		pm.beam.dropPeer(id)
+	}
 	if err := pm.peers.Unregister(id); err != nil {
 		log.Error("Peer removal failed", "peer", id, "err", err)
 	}
@@ -556,7 +564,6 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 		if err := msg.Decode(&data); err != nil {
 			return errResp(ErrDecode, "msg %v: %v", msg, err)
 		}
-		// Deliver all to the downloader
 		if err := pm.downloader.DeliverNodeData(p.id, data); err != nil {
 			log.Debug("Failed to deliver node state data", "err", err)
 		}
@@ -608,6 +615,118 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 			log.Debug("Failed to deliver receipts", "err", err)
 		}
 
+	case p.version >= vnt64 && msg.Body.Type == GetAccountRangeMsg:
+		// Decode the account range query and serve it from the state trie
+		var query getAccountRangeData
+		if err := msg.Decode(&query); err != nil {
+			return errResp(ErrDecode, "%v: %v", msg, err)
+		}
+		if query.Bytes > softResponseLimit {
+			query.Bytes = softResponseLimit
+		}
+		hashes, accounts, proof := downloader.ServeAccountRange(pm.blockchain.StateCache().TrieDB(), query.Root, query.Origin, query.Bytes)
+		return p.SendAccountRange(&accountRangeData{Hashes: hashes, Accounts: accounts, Proof: proof})
+
+	case p.version >= vnt64 && msg.Body.Type == AccountRangeMsg:
+		// A range of accounts arrived to one of our previous requests
+		var res accountRangeData
+		if err := msg.Decode(&res); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		if err := pm.downloader.DeliverAccountRange(p.id, res.Hashes, res.Accounts, res.Proof); err != nil {
+			log.Debug("Failed to deliver account range", "err", err)
+		}
+
+	case p.version >= vnt64 && msg.Body.Type == GetStorageRangesMsg:
+		// Decode the storage ranges query and serve it from the storage tries
+		var query getStorageRangesData
+		if err := msg.Decode(&query); err != nil {
+			return errResp(ErrDecode, "%v: %v", msg, err)
+		}
+		if query.Bytes > softResponseLimit {
+			query.Bytes = softResponseLimit
+		}
+		hashes, slots, more := downloader.ServeStorageRanges(pm.blockchain.StateCache().TrieDB(), query.Roots, query.Origin, query.Bytes)
+		return p.SendStorageRanges(&storageRangesData{Hashes: hashes, Slots: slots, More: more})
+
+	case p.version >= vnt64 && msg.Body.Type == StorageRangesMsg:
+		// A batch of storage ranges arrived to one of our previous requests
+		var res storageRangesData
+		if err := msg.Decode(&res); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		if err := pm.downloader.DeliverStorageRanges(p.id, res.Hashes, res.Slots, res.More); err != nil {
+			log.Debug("Failed to deliver storage ranges", "err", err)
+		}
+
+	case p.version >= vnt64 && msg.Body.Type == GetByteCodesMsg:
+		// Decode the code query and gather the codes until the limits are reached
+		var query getByteCodesData
+		if err := msg.Decode(&query); err != nil {
+			return errResp(ErrDecode, "%v: %v", msg, err)
+		}
+		if query.Bytes > softResponseLimit {
+			query.Bytes = softResponseLimit
+		}
+		var (
+			bytes uint64
+			codes [][]byte
+		)
+		for _, hash := range query.Hashes {
+			if bytes >= query.Bytes || len(codes) >= downloader.MaxStateFetch {
+				break
+			}
+			if code, err := pm.blockchain.TrieNode(hash); err == nil {
+				codes = append(codes, code)
+				bytes += uint64(len(code))
+			}
+		}
+		return p.SendByteCodes(codes)
+
+	case p.version >= vnt64 && msg.Body.Type == ByteCodesMsg:
+		// A batch of contract codes arrived to one of our previous requests
+		var codes [][]byte
+		if err := msg.Decode(&codes); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		if err := pm.downloader.DeliverByteCodes(p.id, codes); err != nil {
+			log.Debug("Failed to deliver contract codes", "err", err)
+		}
+
+	case p.version >= vnt64 && msg.Body.Type == GetBeamNodeDataMsg:
+		// Decode the beam state query and gather the entries until the limits are reached
+		var query getBeamNodeDataData
+		if err := msg.Decode(&query); err != nil {
+			return errResp(ErrDecode, "%v: %v", msg, err)
+		}
+		var (
+			bytes int
+			data  [][]byte
+		)
+		for _, hash := range query.Hashes {
+			if bytes >= softResponseLimit || len(data) >= downloader.MaxStateFetch {
+				break
+			}
+			if entry, err := pm.blockchain.TrieNode(hash); err == nil {
+				data = append(data, entry)
+				bytes += len(entry)
+			}
+		}
+		return p.SendBeamNodeData(query.ID, data)
+
+	case p.version >= vnt64 && msg.Body.Type == BeamNodeDataMsg:
+		// A batch of state entries arrived to one of the beam fetcher requests
+		var res beamNodeDataData
+		if err := msg.Decode(&res); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		if pm.beam == nil {
+			break
+		}
+		if err := pm.beam.deliver(p.id, res.ID, res.Data); err != nil {
+			log.Debug("Failed to deliver beam state data", "id", res.ID, "err", err)
+		}
+
 	case msg.Body.Type == NewBlockHashesMsg:
 		var announces newBlockHashesData
 		if err := msg.Decode(&announces); err != nil {
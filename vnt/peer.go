@@ -26,6 +26,7 @@ import (
 	libp2p "github.com/libp2p/go-libp2p-peer"
 	"github.com/vntchain/go-vnt/common"
 	"github.com/vntchain/go-vnt/core/types"
+	"github.com/vntchain/go-vnt/log"
 	"github.com/vntchain/go-vnt/rlp"
 	"github.com/vntchain/go-vnt/vntp2p"
 	set "gopkg.in/fatih/set.v0"
@@ -316,6 +317,54 @@ func (p *peer) RequestNodeData(hashes []common.Hash) error {
 	return vntp2p.Send(p.rw, ProtocolName, GetNodeDataMsg, hashes)
 }
 
+// RequestAccountRange fetches a range of consecutive accounts of the state trie
+// rooted at root, starting at origin.
+func (p *peer) RequestAccountRange(root common.Hash, origin common.Hash, bytes uint64) error {
+	p.Log().Debug("Fetching range of accounts", "root", root, "origin", origin, "bytes", common.StorageSize(bytes))
+	return vntp2p.Send(p.rw, ProtocolName, GetAccountRangeMsg, &getAccountRangeData{Root: root, Origin: origin, Bytes: bytes})
+}
+
+// RequestStorageRanges fetches the slots of a batch of storage tries, starting
+// at origin for the first one.
+func (p *peer) RequestStorageRanges(roots []common.Hash, origin common.Hash, bytes uint64) error {
+	p.Log().Debug("Fetching ranges of storage slots", "count", len(roots), "origin", origin, "bytes", common.StorageSize(bytes))
+	return vntp2p.Send(p.rw, ProtocolName, GetStorageRangesMsg, &getStorageRangesData{Roots: roots, Origin: origin, Bytes: bytes})
+}
+
+// RequestByteCodes fetches a batch of contract codes corresponding to the hashes
+// specified.
+func (p *peer) RequestByteCodes(hashes []common.Hash, bytes uint64) error {
+	p.Log().Debug("Fetching batch of contract codes", "count", len(hashes), "bytes", common.StorageSize(bytes))
+	return vntp2p.Send(p.rw, ProtocolName, GetByteCodesMsg, &getByteCodesData{Hashes: hashes, Bytes: bytes})
+}
+
+// SendAccountRange sends a range of accounts along with its Merkle proof.
+func (p *peer) SendAccountRange(data *accountRangeData) error {
+	return vntp2p.Send(p.rw, ProtocolName, AccountRangeMsg, data)
+}
+
+// SendStorageRanges sends a batch of storage slot ranges.
+func (p *peer) SendStorageRanges(data *storageRangesData) error {
+	return vntp2p.Send(p.rw, ProtocolName, StorageRangesMsg, data)
+}
+
+// SendByteCodes sends a batch of contract codes.
+func (p *peer) SendByteCodes(codes [][]byte) error {
+	return vntp2p.Send(p.rw, ProtocolName, ByteCodesMsg, codes)
+}
+
+// RequestBeamNodeData fetches a batch of state entries read by RPC calls,
+// tagged with the id the response has to echo.
+func (p *peer) RequestBeamNodeData(id uint64, hashes []common.Hash) error {
+	log.Debug("Fetching batch of beam state data", "peer", p.id, "id", id, "count", len(hashes))
+	return vntp2p.Send(p.rw, ProtocolName, GetBeamNodeDataMsg, &getBeamNodeDataData{ID: id, Hashes: hashes})
+}
+
+// SendBeamNodeData sends the state entries answering a beam state data query.
+func (p *peer) SendBeamNodeData(id uint64, data [][]byte) error {
+	return vntp2p.Send(p.rw, ProtocolName, BeamNodeDataMsg, &beamNodeDataData{ID: id, Data: data})
+}
+
 // RequestReceipts fetches a batch of transaction receipts from a remote node.
 func (p *peer) RequestReceipts(hashes []common.Hash) error {
 	p.Log().Debug("Fetching batch of receipts", "count", len(hashes))
@@ -523,6 +572,27 @@ func (ps *peerSet) BestPeer() *peer {
 	return bestPeer
 }
 
+// BestBeamPeer retrieves the peer with the highest total difficulty able to
+// serve beam state data, skipping the given ones.
+func (ps *peerSet) BestBeamPeer(skip map[libp2p.ID]bool) *peer {
+	ps.lock.RLock()
+	defer ps.lock.RUnlock()
+
+	var (
+		bestPeer *peer
+		bestTd   *big.Int
+	)
+	for _, p := range ps.peers {
+		if p.version < vnt64 || skip[p.id] {
+			continue
+		}
+		if _, td := p.Head(); bestPeer == nil || td.Cmp(bestTd) > 0 {
+			bestPeer, bestTd = p, td
+		}
+	}
+	return bestPeer
+}
+
 // Close disconnects all peers.
 // No new peers can be registered after Close has returned.
 func (ps *peerSet) Close() {
@@ -32,16 +32,17 @@ import (
 const (
 	vnt62 = 62
 	vnt63 = 63
+	vnt64 = 64
 )
 
 // ProtocolName is the official short name of the protocol used during capability negotiation.
 var ProtocolName = "vnt"
 
 // ProtocolVersions are the upported versions of the vnt protocol (first is primary).
-var ProtocolVersions = []uint{vnt63, vnt62}
+var ProtocolVersions = []uint{vnt64, vnt63, vnt62}
 
 // ProtocolLengths are the number of implemented message corresponding to different protocol versions.
-var ProtocolLengths = []uint64{20, 8}
+var ProtocolLengths = []uint64{28, 20, 8}
 
 const ProtocolMaxMsgSize = 10 * 1024 * 1024 // Maximum cap on the size of a protocol message
 
@@ -65,6 +66,16 @@ const (
 	BftPreprepareMsg = 0x11
 	BftPrepareMsg    = 0x12
 	BftCommitMsg     = 0x13
+
+	// Protocol messages belonging to vnt/64, retrieving the state as flat ranges
+	GetAccountRangeMsg  = 0x14
+	AccountRangeMsg     = 0x15
+	GetStorageRangesMsg = 0x16
+	StorageRangesMsg    = 0x17
+	GetByteCodesMsg     = 0x18
+	ByteCodesMsg        = 0x19
+	GetBeamNodeDataMsg  = 0x1a
+	BeamNodeDataMsg     = 0x1b
 )
 
 type errCode int
@@ -187,3 +198,49 @@ type blockBody struct {
 
 // blockBodiesData is the network packet for block content distribution.
 type blockBodiesData []*blockBody
+
+// getAccountRangeData represents an account range query.
+type getAccountRangeData struct {
+	Root   common.Hash // State root to retrieve the accounts of
+	Origin common.Hash // Account hash from which to start the range
+	Bytes  uint64      // Soft limit on the size of the response
+}
+
+// accountRangeData is the network packet for account range distribution.
+type accountRangeData struct {
+	Hashes   []common.Hash // Hashes of the consecutive accounts
+	Accounts [][]byte      // RLP encoded bodies of the accounts
+	Proof    [][]byte      // Merkle proof of the last account (or the origin if none)
+}
+
+// getStorageRangesData represents a storage ranges query.
+type getStorageRangesData struct {
+	Roots  []common.Hash // Roots of the storage tries to retrieve the slots of
+	Origin common.Hash   // Slot hash from which to start the first storage range
+	Bytes  uint64        // Soft limit on the size of the response
+}
+
+// storageRangesData is the network packet for storage range distribution.
+type storageRangesData struct {
+	Hashes [][]common.Hash // Hashes of the consecutive slots, per storage trie
+	Slots  [][][]byte      // Values of the consecutive slots, per storage trie
+	More   bool            // Whether the last storage range was cut short
+}
+
+// getBeamNodeDataData represents a state data query of the beam fetcher.
+type getBeamNodeDataData struct {
+	ID     uint64        // Request id echoed in the response
+	Hashes []common.Hash // Hashes of the state entries to retrieve
+}
+
+// beamNodeDataData is the network packet for beam state data distribution.
+type beamNodeDataData struct {
+	ID   uint64   // Id of the request answered
+	Data [][]byte // State entries found of the requested ones
+}
+
+// getByteCodesData represents a contract code query.
+type getByteCodesData struct {
+	Hashes []common.Hash // Hashes of the contract codes to retrieve
+	Bytes  uint64        // Soft limit on the size of the response
+}
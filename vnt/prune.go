@@ -0,0 +1,173 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package vnt
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/log"
+)
+
+// PruneSchedule determines when the next scheduled state prune is due.
+type PruneSchedule interface {
+	Next(now time.Time) time.Time
+}
+
+// intervalSchedule prunes at a fixed interval.
+type intervalSchedule time.Duration
+
+func (s intervalSchedule) Next(now time.Time) time.Time {
+	return now.Add(time.Duration(s))
+}
+
+// dailySchedule prunes at fixed times of the day, stored as offsets from
+// midnight in ascending order.
+type dailySchedule []time.Duration
+
+func (s dailySchedule) Next(now time.Time) time.Time {
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	for _, offset := range s {
+		if at := midnight.Add(offset); at.After(now) {
+			return at
+		}
+	}
+	return midnight.AddDate(0, 0, 1).Add(s[0])
+}
+
+// ParsePruneSchedule parses a prune schedule, which is either an interval
+// (e.g. "6h", optionally prefixed with "@every ") or a comma separated list of
+// daily times (e.g. "03:00,15:30").
+func ParsePruneSchedule(spec string) (PruneSchedule, error) {
+	spec = strings.TrimSpace(spec)
+	if interval, err := time.ParseDuration(strings.TrimPrefix(spec, "@every ")); err == nil {
+		if interval <= 0 {
+			return nil, fmt.Errorf("non-positive prune interval %v", interval)
+		}
+		return intervalSchedule(interval), nil
+	}
+	var schedule dailySchedule
+	for _, field := range strings.Split(spec, ",") {
+		at, err := time.Parse("15:04", strings.TrimSpace(field))
+		if err != nil {
+			return nil, fmt.Errorf("invalid prune schedule %q: want an interval or daily HH:MM times", spec)
+		}
+		schedule = append(schedule, time.Duration(at.Hour())*time.Hour+time.Duration(at.Minute())*time.Minute)
+	}
+	sort.Slice(schedule, func(i, j int) bool { return schedule[i] < schedule[j] })
+	return schedule, nil
+}
+
+// pruneClock abstracts the passage of time for the prune scheduler.
+type pruneClock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// systemClock is the pruneClock backed by the wall clock.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// statePruner is the chain functionality driven by the prune scheduler.
+type statePruner interface {
+	PruneState(bloomSize uint64) (common.StorageSize, error)
+}
+
+// pruneScheduler triggers state pruning on a live chain at scheduled times. A
+// scheduled prune is skipped if the previous one is still running.
+type pruneScheduler struct {
+	chain     statePruner
+	schedule  PruneSchedule
+	bloomSize uint64 // Bytes of memory allocated to the bloom filter of a prune
+	clock     pruneClock
+	logger    log.Logger
+
+	running int32 // Whether a prune is in progress (atomic)
+	quit    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// newPruneScheduler creates a scheduler pruning the given chain with a bloom
+// filter of bloomSize bytes, which may be started with Start.
+func newPruneScheduler(chain statePruner, schedule PruneSchedule, bloomSize uint64, logger log.Logger) *pruneScheduler {
+	return &pruneScheduler{
+		chain:     chain,
+		schedule:  schedule,
+		bloomSize: bloomSize,
+		clock:     systemClock{},
+		logger:    logger,
+		quit:      make(chan struct{}),
+	}
+}
+
+// Start launches the scheduling loop in the background.
+func (s *pruneScheduler) Start() {
+	s.wg.Add(1)
+	go s.loop()
+}
+
+// Stop terminates the scheduling loop and waits for a running prune to finish.
+func (s *pruneScheduler) Stop() {
+	close(s.quit)
+	s.wg.Wait()
+}
+
+// loop waits for each scheduled time in turn and triggers a prune.
+func (s *pruneScheduler) loop() {
+	defer s.wg.Done()
+
+	for {
+		now := s.clock.Now()
+		next := s.schedule.Next(now)
+		s.logger.Debug("Scheduled next state prune", "at", next)
+
+		select {
+		case <-s.clock.After(next.Sub(now)):
+			s.trigger()
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// trigger starts a prune in the background unless one is already running.
+func (s *pruneScheduler) trigger() {
+	if !atomic.CompareAndSwapInt32(&s.running, 0, 1) {
+		s.logger.Warn("Skipping scheduled state prune, previous one still running")
+		return
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer atomic.StoreInt32(&s.running, 0)
+
+		start := time.Now()
+		freed, err := s.chain.PruneState(s.bloomSize)
+		if err != nil {
+			s.logger.Warn("Scheduled state prune failed", "err", err)
+			return
+		}
+		s.logger.Info("Pruned state", "freed", freed, "elapsed", common.PrettyDuration(time.Since(start)))
+	}()
+}
@@ -0,0 +1,243 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package vnt
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/log"
+)
+
+// fakeTimer is a pending timer of the fake clock.
+type fakeTimer struct {
+	at time.Time
+	ch chan time.Time
+}
+
+// fakeClock is a manually advanced pruneClock.
+type fakeClock struct {
+	lock   sync.Mutex
+	now    time.Time
+	timers []fakeTimer
+	added  chan struct{} // Signalled whenever a timer is created
+}
+
+func newFakeClock(now time.Time) *fakeClock {
+	return &fakeClock{now: now, added: make(chan struct{}, 16)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.lock.Lock()
+	ch := make(chan time.Time, 1)
+	c.timers = append(c.timers, fakeTimer{at: c.now.Add(d), ch: ch})
+	c.lock.Unlock()
+
+	c.added <- struct{}{}
+	return ch
+}
+
+// advance moves the clock forward, firing all the timers which expired.
+func (c *fakeClock) advance(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.now = c.now.Add(d)
+	pending := c.timers[:0]
+	for _, timer := range c.timers {
+		if timer.at.After(c.now) {
+			pending = append(pending, timer)
+			continue
+		}
+		timer.ch <- c.now
+	}
+	c.timers = pending
+}
+
+// waitTimer blocks until the scheduler arms its next timer.
+func (c *fakeClock) waitTimer(t *testing.T) {
+	t.Helper()
+	select {
+	case <-c.added:
+	case <-time.After(time.Second):
+		t.Fatalf("scheduler did not arm a timer")
+	}
+}
+
+// fakePruner records the prunes it is asked to do, optionally blocking each of
+// them until released.
+type fakePruner struct {
+	lock    sync.Mutex
+	active  int           // Number of prunes currently running
+	overlap bool          // Whether prunes ever ran concurrently
+	started chan struct{} // Signalled whenever a prune starts
+	release chan struct{} // Prunes block until this is readable, if non-nil
+}
+
+func newFakePruner(block bool) *fakePruner {
+	p := &fakePruner{started: make(chan struct{}, 16)}
+	if block {
+		p.release = make(chan struct{})
+	}
+	return p
+}
+
+func (p *fakePruner) PruneState(bloomSize uint64) (common.StorageSize, error) {
+	p.lock.Lock()
+	p.active++
+	p.overlap = p.overlap || p.active > 1
+	p.lock.Unlock()
+
+	p.started <- struct{}{}
+	if p.release != nil {
+		<-p.release
+	}
+	p.lock.Lock()
+	p.active--
+	p.lock.Unlock()
+	return 0, nil
+}
+
+// waitPrune checks whether a prune starts within a short timeout.
+func (p *fakePruner) waitPrune(t *testing.T, want bool) {
+	t.Helper()
+	select {
+	case <-p.started:
+		if !want {
+			t.Fatalf("unexpected prune triggered")
+		}
+	case <-time.After(100 * time.Millisecond):
+		if want {
+			t.Fatalf("scheduled prune not triggered")
+		}
+	}
+}
+
+// newTestPruneScheduler creates a scheduler driven by a fake clock.
+func newTestPruneScheduler(pruner statePruner, schedule PruneSchedule, clock *fakeClock) *pruneScheduler {
+	scheduler := newPruneScheduler(pruner, schedule, 1024, log.New())
+	scheduler.clock = clock
+	return scheduler
+}
+
+// Tests that prune schedules are parsed from intervals and daily times.
+func TestParsePruneSchedule(t *testing.T) {
+	tests := []struct {
+		spec  string
+		valid bool
+	}{
+		{"6h", true},
+		{"@every 30m", true},
+		{"03:00", true},
+		{"15:30, 03:00", true},
+		{"0h", false},
+		{"-1h", false},
+		{"25:00", false},
+		{"daily", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if _, err := ParsePruneSchedule(tt.spec); (err == nil) != tt.valid {
+			t.Errorf("schedule %q: validity mismatch: have %v, want %v", tt.spec, err, tt.valid)
+		}
+	}
+}
+
+// Tests that daily schedules pick the next time of day, rolling over midnight.
+func TestDailyPruneSchedule(t *testing.T) {
+	schedule, err := ParsePruneSchedule("15:00,03:00")
+	if err != nil {
+		t.Fatalf("failed to parse schedule: %v", err)
+	}
+	day := time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		now, want time.Time
+	}{
+		{day.Add(1 * time.Hour), day.Add(3 * time.Hour)},
+		{day.Add(3 * time.Hour), day.Add(15 * time.Hour)},
+		{day.Add(16 * time.Hour), day.Add(27 * time.Hour)},
+	}
+	for i, tt := range tests {
+		if next := schedule.Next(tt.now); !next.Equal(tt.want) {
+			t.Errorf("test %d: next prune mismatch: have %v, want %v", i, next, tt.want)
+		}
+	}
+}
+
+// Tests that the scheduler prunes at the scheduled times only.
+func TestPruneSchedulerTriggers(t *testing.T) {
+	clock := newFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	pruner := newFakePruner(false)
+
+	scheduler := newTestPruneScheduler(pruner, intervalSchedule(time.Hour), clock)
+	scheduler.Start()
+	defer scheduler.Stop()
+
+	clock.waitTimer(t)
+	clock.advance(30 * time.Minute)
+	pruner.waitPrune(t, false)
+
+	clock.advance(30 * time.Minute)
+	pruner.waitPrune(t, true)
+
+	clock.waitTimer(t)
+	clock.advance(time.Hour)
+	pruner.waitPrune(t, true)
+}
+
+// Tests that a scheduled prune is skipped while the previous one still runs.
+func TestPruneSchedulerNoOverlap(t *testing.T) {
+	clock := newFakeClock(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC))
+	pruner := newFakePruner(true)
+
+	scheduler := newTestPruneScheduler(pruner, intervalSchedule(time.Hour), clock)
+	scheduler.Start()
+	defer scheduler.Stop()
+
+	// Start a long running prune and ensure the next slot is skipped
+	clock.waitTimer(t)
+	clock.advance(time.Hour)
+	pruner.waitPrune(t, true)
+
+	clock.waitTimer(t)
+	clock.advance(time.Hour)
+	pruner.waitPrune(t, false)
+
+	// Finish the running prune and ensure scheduling resumes
+	pruner.release <- struct{}{}
+	for atomic.LoadInt32(&scheduler.running) != 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	clock.waitTimer(t)
+	clock.advance(time.Hour)
+	pruner.waitPrune(t, true)
+	pruner.release <- struct{}{}
+
+	if pruner.overlap {
+		t.Fatalf("prunes ran concurrently")
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package vnt
+
+import (
+	"time"
+
+	"github.com/vntchain/go-vnt/core/rawdb"
+	"github.com/vntchain/go-vnt/log"
+	"github.com/vntchain/go-vnt/node"
+	"github.com/vntchain/go-vnt/vntdb"
+)
+
+const (
+	replicaLagCheckInterval = time.Minute // Frequency of checking the read replica for staleness
+	replicaLagThreshold     = 64          // Number of blocks the read replica may trail the chain silently
+)
+
+// openReadReplica opens the configured read replica in read only mode, wrapping
+// it together with the chain database into one serving RPC state reads.
+func openReadReplica(ctx *node.ServiceContext, config *Config, chainDb vntdb.Database) (*vntdb.ReplicaDatabase, error) {
+	replica, err := vntdb.NewReadOnlyLDBDatabase(ctx.ResolvePath(config.ReadReplica), 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	log.Info("Serving RPC state reads from replica database", "path", replica.Path())
+	return vntdb.NewReplicaDatabase(chainDb, replica), nil
+}
+
+// replicaLag returns the number of blocks the head of the replica trails the
+// given chain head, or false if the replica has no known head at all.
+func replicaLag(replica vntdb.Database, head uint64) (uint64, bool) {
+	number := rawdb.ReadHeaderNumber(replica, rawdb.ReadHeadBlockHash(replica))
+	if number == nil {
+		return 0, false
+	}
+	if *number >= head {
+		return 0, true
+	}
+	return head - *number, true
+}
+
+// replicaLagLoop periodically warns if the read replica falls too far behind
+// the chain, as RPC reads served from it may then be inconsistent.
+func (s *VNT) replicaLagLoop(replica vntdb.Database) {
+	ticker := time.NewTicker(replicaLagCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			head := s.blockchain.CurrentBlock().NumberU64()
+			lag, ok := replicaLag(replica, head)
+			switch {
+			case !ok:
+				log.Warn("Read replica has no chain head, serving reads from primary")
+			case lag > replicaLagThreshold:
+				log.Warn("Read replica lagging behind chain, reads may be stale", "head", head, "lag", lag)
+			}
+		case <-s.shutdownChan:
+			return
+		}
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package vnt
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/consensus/mock"
+	"github.com/vntchain/go-vnt/core"
+	"github.com/vntchain/go-vnt/core/rawdb"
+	"github.com/vntchain/go-vnt/core/state"
+	"github.com/vntchain/go-vnt/core/types"
+	"github.com/vntchain/go-vnt/core/vm"
+	"github.com/vntchain/go-vnt/crypto"
+	"github.com/vntchain/go-vnt/params"
+	"github.com/vntchain/go-vnt/rpc"
+	"github.com/vntchain/go-vnt/vntdb"
+)
+
+// Tests that RPC state reads are routed through the read replica if one is
+// configured, falling back to the chain database for entries it lacks, while
+// any other chain data is read from the chain database.
+func TestReplicaReadRouting(t *testing.T) {
+	primary, replica := vntdb.NewMemDatabase(), vntdb.NewMemDatabase()
+
+	// Commit a state into the primary database only
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(primary))
+	statedb.AddBalance(common.Address{0x01}, big.NewInt(42))
+	root, _ := statedb.Commit(false)
+	statedb.Database().TrieDB().Commit(root, false)
+
+	// Write a marker into the replica only
+	replica.Put([]byte("marker"), []byte("replica"))
+
+	vnt := &VNT{chainDb: primary, replica: vntdb.NewReplicaDatabase(primary, replica)}
+	vnt.replicaState = state.NewDatabase(vnt.replica)
+	backend := &VntAPIBackend{vnt: vnt}
+
+	if _, err := backend.ChainDb().Get([]byte("marker")); err == nil {
+		t.Errorf("replica entry served as chain data")
+	}
+	replicated, err := state.New(root, vnt.replicaState)
+	if err != nil {
+		t.Fatalf("failed to open state through replica: %v", err)
+	}
+	if balance := replicated.GetBalance(common.Address{0x01}); balance.Cmp(big.NewInt(42)) != 0 {
+		t.Errorf("state fallback balance mismatch: have %v, want %v", balance, 42)
+	}
+	// State only present in the replica must be served from it
+	statedb, _ = state.New(common.Hash{}, state.NewDatabase(replica))
+	statedb.AddBalance(common.Address{0x02}, big.NewInt(24))
+	root, _ = statedb.Commit(false)
+	statedb.Database().TrieDB().Commit(root, false)
+
+	if _, err := state.New(root, state.NewDatabase(primary)); err == nil {
+		t.Fatalf("replica state present in chain database")
+	}
+	if replicated, err = state.New(root, vnt.replicaState); err != nil {
+		t.Fatalf("failed to open replica state: %v", err)
+	}
+	if balance := replicated.GetBalance(common.Address{0x02}); balance.Cmp(big.NewInt(24)) != 0 {
+		t.Errorf("replica balance mismatch: have %v, want %v", balance, 24)
+	}
+}
+
+// Tests that the lag of the read replica behind the chain head is detected.
+func TestReplicaLag(t *testing.T) {
+	replica := vntdb.NewMemDatabase()
+	if _, ok := replicaLag(replica, 100); ok {
+		t.Fatalf("lag reported for replica without head")
+	}
+	header := &types.Header{Number: big.NewInt(30)}
+	rawdb.WriteHeader(replica, header)
+	rawdb.WriteHeadBlockHash(replica, header.Hash())
+
+	tests := []struct {
+		head, lag uint64
+	}{
+		{100, 70},
+		{30, 0},
+		{20, 0},
+	}
+	for _, tt := range tests {
+		if lag, ok := replicaLag(replica, tt.head); !ok || lag != tt.lag {
+			t.Errorf("head %d: lag mismatch: have %d (%v), want %d", tt.head, lag, ok, tt.lag)
+		}
+	}
+}
+
+// Tests that states the read replica lacks, like the ones still held in memory
+// by the chain, are served from the chain instead.
+func TestReplicaStateFallback(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc:  core.GenesisAlloc{address: {Balance: big.NewInt(1000000000)}},
+		}
+		signer = types.NewHubbleSigner(gspec.Config.ChainID)
+		db     = vntdb.NewMemDatabase()
+	)
+	// Generate the chain elsewhere, so that its states are only held in memory
+	gspec.MustCommit(db)
+	gendb := vntdb.NewMemDatabase()
+	genesis := gspec.MustCommit(gendb)
+	blocks, _ := core.GenerateChain(gspec.Config, genesis, mock.NewMock(), gendb, 1, func(i int, block *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(address), common.Address{0x01}, big.NewInt(1000), params.TxGas, nil, nil), signer, key)
+		block.AddTx(tx)
+	})
+	chain, err := core.NewBlockChain(db, nil, gspec.Config, mock.NewMock(), vm.Config{})
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	vnt := &VNT{chainDb: db, blockchain: chain, replica: vntdb.NewReplicaDatabase(db, vntdb.NewMemDatabase())}
+	vnt.replicaState = state.NewDatabase(vnt.replica)
+	backend := &VntAPIBackend{vnt: vnt}
+
+	if _, err := state.New(blocks[0].Root(), vnt.replicaState); err == nil {
+		t.Fatalf("head state reachable through replica")
+	}
+	statedb, header, err := backend.StateAndHeaderByNumber(context.Background(), rpc.LatestBlockNumber)
+	if err != nil {
+		t.Fatalf("failed to retrieve head state: %v", err)
+	}
+	if header.Hash() != blocks[0].Hash() {
+		t.Errorf("header mismatch: have %x, want %x", header.Hash(), blocks[0].Hash())
+	}
+	if balance := statedb.GetBalance(common.Address{0x01}); balance.Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("balance mismatch: have %v, want %v", balance, 1000)
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package vnt
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/prometheus/util/flock"
+	"github.com/vntchain/go-vnt/common"
+	"github.com/vntchain/go-vnt/consensus/dpos"
+	"github.com/vntchain/go-vnt/core"
+	"github.com/vntchain/go-vnt/log"
+)
+
+// producerLock is a file lock held by the node currently producing the blocks
+// of a witness. Nodes sharing the lock file never produce at the same time.
+//
+// The lock is only advisory and not a fencing mechanism: a node that stalls, or
+// loses access to a lock on a network file system, keeps producing with blocks
+// already in flight. Double signing is only avoided as long as the lock and the
+// chain checks of the standby agree.
+type producerLock struct {
+	path    string
+	release flock.Releaser // nil if the lock is not held
+}
+
+// acquire takes the lock, failing if another node holds it. A nil lock, or one
+// that is already held, is always acquired.
+func (l *producerLock) acquire() error {
+	if l == nil || l.release != nil {
+		return nil
+	}
+	release, _, err := flock.New(l.path)
+	if err != nil {
+		return fmt.Errorf("producer lock %s unavailable: %v", l.path, err)
+	}
+	l.release = release
+	return nil
+}
+
+// unlock releases the lock if it is held.
+func (l *producerLock) unlock() {
+	if l == nil || l.release == nil {
+		return
+	}
+	if err := l.release.Release(); err != nil {
+		log.Warn("Failed to release producer lock", "path", l.path, "err", err)
+	}
+	l.release = nil
+}
+
+// witnessStandby runs a backup node of a witness. It keeps block producing off
+// while the primary node signing with the same key is healthy, takes over once
+// the primary missed too many consecutive rounds and steps back as soon as a
+// block of the primary shows up again.
+type witnessStandby struct {
+	vnt    *VNT
+	engine *dpos.Dpos
+	signer common.Address // Signing account shared with the primary
+	misses uint64         // Consecutive rounds the primary may miss before taking over
+	local  bool           // Whether producing was started locally
+
+	active bool // Whether the standby is producing, owned by the loop
+	quit   chan struct{}
+	done   chan struct{}
+}
+
+func newWitnessStandby(vnt *VNT, engine *dpos.Dpos, signer common.Address, local bool) *witnessStandby {
+	misses := vnt.config.StandbyMisses
+	if misses == 0 {
+		misses = DefaultConfig.StandbyMisses
+	}
+	return &witnessStandby{
+		vnt:    vnt,
+		engine: engine,
+		signer: signer,
+		misses: misses,
+		local:  local,
+		quit:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+}
+
+// loop watches the chain until the standby is stopped, checking the health of
+// the primary every block period.
+func (w *witnessStandby) loop() {
+	defer close(w.done)
+
+	period := time.Duration(w.vnt.chainConfig.Dpos.Period) * time.Second
+	if period == 0 {
+		period = time.Second
+	}
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	events := make(chan core.ChainEvent, 16)
+	sub := w.vnt.blockchain.SubscribeChainEvent(events)
+	defer sub.Unsubscribe()
+
+	log.Info("Standing by for the primary witness node", "signer", w.signer, "misses", w.misses)
+	for {
+		select {
+		case <-ticker.C:
+			if !w.active && w.primaryDown() {
+				w.takeOver()
+			}
+		case ev := <-events:
+			if w.active && ev.Block.Coinbase() == w.signer && !w.engine.SealedLocally(ev.Block.Hash()) {
+				log.Warn("Primary witness node is back, stepping down", "number", ev.Block.Number(), "hash", ev.Block.Hash())
+				w.stepDown()
+			}
+		case <-sub.Err():
+			w.stepDown()
+			return
+		case <-w.quit:
+			w.stepDown()
+			return
+		}
+	}
+}
+
+// primaryDown reports whether the primary missed enough consecutive rounds on
+// the local chain for the standby to take over.
+func (w *witnessStandby) primaryDown() bool {
+	if w.vnt.protocolManager.downloader.Synchronising() {
+		return false
+	}
+	head := w.vnt.blockchain.CurrentHeader()
+	return w.engine.MissedRounds(w.vnt.blockchain, head, w.signer, time.Now(), w.misses) >= w.misses
+}
+
+// takeOver starts producing in place of the primary, unless the primary still
+// holds the producer lock or produced a block in the meantime.
+func (w *witnessStandby) takeOver() {
+	if err := w.vnt.producerLock.acquire(); err != nil {
+		log.Warn("Primary witness node missed its rounds but holds the lock", "err", err)
+		return
+	}
+	// The lock may have been released by a primary that still produces, check
+	// the chain for its blocks again right before signing any
+	if !w.primaryDown() {
+		log.Warn("Primary witness node produced while taking over, standing by")
+		w.vnt.producerLock.unlock()
+		return
+	}
+	log.Warn("Primary witness node is down, taking over block producing", "signer", w.signer)
+	w.vnt.startProducing(w.signer, w.local)
+	w.active = true
+}
+
+// stepDown stops producing, leaving the blocks to the primary again.
+func (w *witnessStandby) stepDown() {
+	if !w.active {
+		return
+	}
+	w.vnt.miner.Stop()
+	w.vnt.producerLock.unlock()
+	w.active = false
+}
+
+// stop terminates the standby, stopping block producing if it took over.
+func (w *witnessStandby) stop() {
+	close(w.quit)
+	<-w.done
+}
@@ -0,0 +1,58 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package vnt
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Tests that only a single node of a witness can hold the producer lock.
+func TestProducerLock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "producer-lock")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "LOCK")
+	primary, standby := &producerLock{path: path}, &producerLock{path: path}
+
+	if err := primary.acquire(); err != nil {
+		t.Fatalf("primary failed to acquire the lock: %v", err)
+	}
+	if err := primary.acquire(); err != nil {
+		t.Fatalf("primary failed to reacquire its own lock: %v", err)
+	}
+	if err := standby.acquire(); err == nil {
+		t.Fatalf("standby acquired the lock held by the primary")
+	}
+	primary.unlock()
+	if err := standby.acquire(); err != nil {
+		t.Fatalf("standby failed to acquire the released lock: %v", err)
+	}
+	standby.unlock()
+
+	// A missing lock never blocks producing
+	var none *producerLock
+	if err := none.acquire(); err != nil {
+		t.Fatalf("unset lock failed to acquire: %v", err)
+	}
+	none.unlock()
+}
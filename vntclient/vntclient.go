@@ -24,6 +24,7 @@ import (
 	"fmt"
 	"math/big"
 	"strings"
+	"time"
 
 	hubble "github.com/vntchain/go-vnt"
 	"github.com/vntchain/go-vnt/accounts/abi"
@@ -252,6 +253,16 @@ type rpcProgress struct {
 	HighestBlock  hexutil.Uint64
 	PulledStates  hexutil.Uint64
 	KnownStates   hexutil.Uint64
+
+	Stage          string
+	PulledHeaders  hexutil.Uint64
+	PulledBodies   hexutil.Uint64
+	PulledReceipts hexutil.Uint64
+	HeaderRate     float64
+	BodyRate       float64
+	ReceiptRate    float64
+	StateRate      float64
+	Remaining      hexutil.Uint64
 }
 
 // SyncProgress retrieves the current progress of the sync algorithm. If there's
@@ -276,6 +287,16 @@ func (ec *Client) SyncProgress(ctx context.Context) (*hubble.SyncProgress, error
 		HighestBlock:  uint64(progress.HighestBlock),
 		PulledStates:  uint64(progress.PulledStates),
 		KnownStates:   uint64(progress.KnownStates),
+
+		Stage:          progress.Stage,
+		PulledHeaders:  uint64(progress.PulledHeaders),
+		PulledBodies:   uint64(progress.PulledBodies),
+		PulledReceipts: uint64(progress.PulledReceipts),
+		HeaderRate:     progress.HeaderRate,
+		BodyRate:       progress.BodyRate,
+		ReceiptRate:    progress.ReceiptRate,
+		StateRate:      progress.StateRate,
+		Remaining:      time.Duration(progress.Remaining) * time.Second,
 	}, nil
 }
 
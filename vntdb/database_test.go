@@ -25,6 +25,7 @@ import (
 	"sync"
 	"testing"
 
+	"github.com/syndtr/goleveldb/leveldb/iterator"
 	"github.com/vntchain/go-vnt/vntdb"
 )
 
@@ -56,6 +57,46 @@ func TestMemoryDB_PutGet(t *testing.T) {
 	testPutGet(vntdb.NewMemDatabase(), t)
 }
 
+func TestLDB_Iterator(t *testing.T) {
+	db, remove := newTestLDB()
+	defer remove()
+	testIterator(db, t)
+}
+
+func TestMemoryDB_Iterator(t *testing.T) {
+	testIterator(vntdb.NewMemDatabase(), t)
+}
+
+// testIterator checks that the database is iterated in key order, as a whole
+// or limited to a prefix.
+func testIterator(db interface {
+	vntdb.Database
+	NewIterator() iterator.Iterator
+	NewIteratorWithPrefix(prefix []byte) iterator.Iterator
+}, t *testing.T) {
+	for _, key := range []string{"b2", "a", "b1", "c"} {
+		if err := db.Put([]byte(key), []byte("v"+key)); err != nil {
+			t.Fatalf("put failed: %v", err)
+		}
+	}
+	collect := func(it iterator.Iterator) (keys []string) {
+		defer it.Release()
+		for it.Next() {
+			if value := string(it.Value()); value != "v"+string(it.Key()) {
+				t.Errorf("value mismatch for %q: have %q, want %q", it.Key(), value, "v"+string(it.Key()))
+			}
+			keys = append(keys, string(it.Key()))
+		}
+		return keys
+	}
+	if have, want := fmt.Sprint(collect(db.NewIterator())), "[a b1 b2 c]"; have != want {
+		t.Errorf("keys mismatch: have %s, want %s", have, want)
+	}
+	if have, want := fmt.Sprint(collect(db.NewIteratorWithPrefix([]byte("b")))), "[b1 b2]"; have != want {
+		t.Errorf("prefixed keys mismatch: have %s, want %s", have, want)
+	}
+}
+
 func testPutGet(db vntdb.Database, t *testing.T) {
 	t.Parallel()
 
@@ -17,15 +17,18 @@
 package vntdb
 
 import (
+	"bytes"
 	"errors"
+	"sort"
+	"strings"
 	"sync"
 
+	"github.com/syndtr/goleveldb/leveldb/iterator"
 	"github.com/vntchain/go-vnt/common"
 )
 
-/*
- * This is a test memory database. Do not use for any production it does not get persisted
- */
+// MemDatabase is a database kept entirely in memory, backing ephemeral nodes
+// and tests. Its content is lost once it's dropped.
 type MemDatabase struct {
 	db   map[string][]byte
 	lock sync.RWMutex
@@ -80,6 +83,28 @@ func (db *MemDatabase) Keys() [][]byte {
 	return keys
 }
 
+// NewIterator returns an iterator over a point-in-time copy of the whole
+// database content, in ascending key order.
+func (db *MemDatabase) NewIterator() iterator.Iterator {
+	return db.NewIteratorWithPrefix(nil)
+}
+
+// NewIteratorWithPrefix returns an iterator over a point-in-time copy of the
+// database content with a particular prefix, in ascending key order.
+func (db *MemDatabase) NewIteratorWithPrefix(prefix []byte) iterator.Iterator {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	var items memItems
+	for key, value := range db.db {
+		if strings.HasPrefix(key, string(prefix)) {
+			items = append(items, kv{[]byte(key), common.CopyBytes(value)})
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return bytes.Compare(items[i].k, items[j].k) < 0 })
+	return iterator.NewArrayIterator(items)
+}
+
 func (db *MemDatabase) Delete(key []byte) error {
 	db.lock.Lock()
 	defer db.lock.Unlock()
@@ -94,10 +119,26 @@ func (db *MemDatabase) NewBatch() Batch {
 	return &memBatch{db: db}
 }
 
-func (db *MemDatabase) Len() int { return len(db.db) }
+func (db *MemDatabase) Len() int {
+	db.lock.RLock()
+	defer db.lock.RUnlock()
+
+	return len(db.db)
+}
 
 type kv struct{ k, v []byte }
 
+// memItems is a sorted list of key-value pairs implementing iterator.Array.
+type memItems []kv
+
+func (items memItems) Len() int { return len(items) }
+
+func (items memItems) Search(key []byte) int {
+	return sort.Search(len(items), func(i int) bool { return bytes.Compare(items[i].k, key) >= 0 })
+}
+
+func (items memItems) Index(i int) (key, value []byte) { return items[i].k, items[i].v }
+
 type memBatch struct {
 	db     *MemDatabase
 	writes []kv
@@ -0,0 +1,69 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package vntdb
+
+// ReplicaDatabase serves reads from a secondary replica database, falling back
+// to the primary one for entries the replica lacks, e.g. because it lags behind.
+// Writes always go to the primary database.
+type ReplicaDatabase struct {
+	primary Database
+	replica Database
+}
+
+// NewReplicaDatabase wraps a primary database, routing reads to the replica.
+func NewReplicaDatabase(primary, replica Database) *ReplicaDatabase {
+	return &ReplicaDatabase{
+		primary: primary,
+		replica: replica,
+	}
+}
+
+// Replica returns the secondary database reads are served from.
+func (db *ReplicaDatabase) Replica() Database {
+	return db.replica
+}
+
+func (db *ReplicaDatabase) Put(key []byte, value []byte) error {
+	return db.primary.Put(key, value)
+}
+
+func (db *ReplicaDatabase) Has(key []byte) (bool, error) {
+	if ok, err := db.replica.Has(key); err == nil && ok {
+		return true, nil
+	}
+	return db.primary.Has(key)
+}
+
+func (db *ReplicaDatabase) Get(key []byte) ([]byte, error) {
+	if value, err := db.replica.Get(key); err == nil {
+		return value, nil
+	}
+	return db.primary.Get(key)
+}
+
+func (db *ReplicaDatabase) Delete(key []byte) error {
+	return db.primary.Delete(key)
+}
+
+// Close closes the replica database only, the primary one is owned by the chain.
+func (db *ReplicaDatabase) Close() {
+	db.replica.Close()
+}
+
+func (db *ReplicaDatabase) NewBatch() Batch {
+	return db.primary.NewBatch()
+}
@@ -0,0 +1,94 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package vntdb_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/vntchain/go-vnt/vntdb"
+)
+
+// Tests that reads are served from the replica and fall back to the primary
+// database if the replica lacks an entry, while writes hit the primary only.
+func TestReplicaDatabase(t *testing.T) {
+	primary, replica := vntdb.NewMemDatabase(), vntdb.NewMemDatabase()
+	db := vntdb.NewReplicaDatabase(primary, replica)
+
+	primary.Put([]byte("shared"), []byte("primary"))
+	replica.Put([]byte("shared"), []byte("replica"))
+	primary.Put([]byte("fresh"), []byte("primary"))
+
+	if value, err := db.Get([]byte("shared")); err != nil || !bytes.Equal(value, []byte("replica")) {
+		t.Errorf("shared entry not read from replica: %q, %v", value, err)
+	}
+	if value, err := db.Get([]byte("fresh")); err != nil || !bytes.Equal(value, []byte("primary")) {
+		t.Errorf("missing replica entry not read from primary: %q, %v", value, err)
+	}
+	if ok, _ := db.Has([]byte("fresh")); !ok {
+		t.Errorf("missing replica entry not found in primary")
+	}
+	if _, err := db.Get([]byte("missing")); err == nil {
+		t.Errorf("entry missing from both databases found")
+	}
+	if ok, _ := db.Has([]byte("missing")); ok {
+		t.Errorf("entry missing from both databases reported present")
+	}
+	if err := db.Put([]byte("written"), []byte("value")); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	if ok, _ := primary.Has([]byte("written")); !ok {
+		t.Errorf("write did not reach primary")
+	}
+	if ok, _ := replica.Has([]byte("written")); ok {
+		t.Errorf("write reached replica")
+	}
+}
+
+// Tests that a read only database rejects writes and refuses to be created.
+func TestReadOnlyLDB(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vntdb-readonly-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := vntdb.NewReadOnlyLDBDatabase(dir, 0, 0); err == nil {
+		t.Fatalf("missing read only database opened")
+	}
+	db, err := vntdb.NewLDBDatabase(dir, 0, 0)
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	db.Put([]byte("key"), []byte("value"))
+	db.Close()
+
+	db, err = vntdb.NewReadOnlyLDBDatabase(dir, 0, 0)
+	if err != nil {
+		t.Fatalf("failed to open read only database: %v", err)
+	}
+	defer db.Close()
+
+	if value, err := db.Get([]byte("key")); err != nil || !bytes.Equal(value, []byte("value")) {
+		t.Errorf("read mismatch: %q, %v", value, err)
+	}
+	if err := db.Put([]byte("key"), []byte("other")); err == nil {
+		t.Errorf("write to read only database accepted")
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package vntdb
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vntchain/go-vnt/log"
+	"github.com/vntchain/go-vnt/metrics"
+)
+
+// stater is implemented by databases able to report their internal statistics,
+// such as the LevelDB backed LDBDatabase.
+type stater interface {
+	Stat(property string) (string, error)
+}
+
+// gaugeStats are the numeric database properties exposed as metrics.
+var gaugeStats = []string{
+	"leveldb.cachedblock",
+	"leveldb.openedtables",
+	"leveldb.alivesnaps",
+	"leveldb.aliveiters",
+}
+
+// ReportStats periodically logs the internal statistics of the given database
+// and exposes the numeric ones as metrics under the given prefix. Reporting
+// stops once the database is closed. A zero interval disables it.
+func ReportStats(db Database, prefix string, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	sdb, ok := db.(stater)
+	if !ok {
+		log.Warn("Database does not support statistics reporting", "type", fmt.Sprintf("%T", db))
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		reportStats(sdb, prefix, ticker.C)
+	}()
+}
+
+// reportStats is the reporting loop of ReportStats, reporting on every tick
+// until the database fails to report its statistics (e.g. because it was
+// closed).
+func reportStats(db stater, prefix string, tick <-chan time.Time) {
+	gauges := make(map[string]metrics.Gauge)
+	for _, property := range gaugeStats {
+		gauges[property] = metrics.GetOrRegisterGauge(prefix+strings.TrimPrefix(property, "leveldb."), nil)
+	}
+	for range tick {
+		stats, err := db.Stat("leveldb.stats")
+		if err != nil {
+			log.Debug("Database statistics reporting stopped", "err", err)
+			return
+		}
+		ctx := []interface{}{"stats", "\n" + stats}
+		for _, property := range gaugeStats {
+			value, err := db.Stat(property)
+			if err != nil {
+				continue
+			}
+			n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			if err != nil {
+				continue
+			}
+			gauges[property].Update(n)
+			ctx = append(ctx, strings.TrimPrefix(property, "leveldb."), n)
+		}
+		log.Info("Database statistics", ctx...)
+	}
+}
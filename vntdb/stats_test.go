@@ -0,0 +1,107 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package vntdb
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeStatsDatabase is a memory database reporting fake statistics, counting
+// the reports and failing once closed.
+type fakeStatsDatabase struct {
+	*MemDatabase
+
+	lock     sync.Mutex
+	reports  int
+	reported chan struct{} // Signalled on every report, if set
+	closed   bool
+}
+
+func (db *fakeStatsDatabase) Stat(property string) (string, error) {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	if db.closed {
+		return "", errors.New("closed")
+	}
+	if property == "leveldb.stats" {
+		db.reports++
+		if db.reported != nil {
+			db.reported <- struct{}{}
+		}
+		return "Compactions", nil
+	}
+	return "42", nil
+}
+
+func (db *fakeStatsDatabase) close() int {
+	db.lock.Lock()
+	defer db.lock.Unlock()
+
+	db.closed = true
+	return db.reports
+}
+
+// Tests that database statistics are reported on every tick and that reporting
+// stops once the database is closed.
+func TestDatabaseStatsTicks(t *testing.T) {
+	db := &fakeStatsDatabase{MemDatabase: NewMemDatabase(), reported: make(chan struct{}, 1)}
+
+	tick := make(chan time.Time)
+	done := make(chan struct{})
+	go func() {
+		reportStats(db, "test/stats/", tick)
+		close(done)
+	}()
+	for i := 0; i < 3; i++ {
+		tick <- time.Now()
+		select {
+		case <-db.reported:
+		case <-time.After(time.Second):
+			t.Fatalf("tick %d: statistics not reported", i)
+		}
+	}
+	if reports := db.close(); reports != 3 {
+		t.Fatalf("report count mismatch: have %d, want %d", reports, 3)
+	}
+	tick <- time.Now()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("reporting not stopped after close")
+	}
+	db.lock.Lock()
+	defer db.lock.Unlock()
+	if db.reports != 3 {
+		t.Errorf("reports continued after close: have %d, want %d", db.reports, 3)
+	}
+}
+
+// Tests that a zero interval disables statistics reporting.
+func TestDatabaseStatsDisabled(t *testing.T) {
+	db := &fakeStatsDatabase{MemDatabase: NewMemDatabase()}
+
+	ReportStats(db, "test/stats/", 0)
+	time.Sleep(50 * time.Millisecond)
+
+	if reports := db.close(); reports != 0 {
+		t.Fatalf("reports emitted while disabled: %d", reports)
+	}
+}
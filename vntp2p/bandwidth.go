@@ -0,0 +1,129 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package vntp2p
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// BandwidthLimit caps the bandwidth used by peer connections in one direction.
+type BandwidthLimit struct {
+	Rate    uint64 `toml:",omitempty"` // Bytes per second, zero means unlimited
+	PerPeer bool   `toml:",omitempty"` // Whether the rate applies to each peer instead of all of them
+}
+
+// global returns a limiter shared by all peers, or nil if the limit is unset
+// or applies per peer.
+func (l BandwidthLimit) global() *rateLimiter {
+	if l.PerPeer {
+		return nil
+	}
+	return newRateLimiter(l.Rate)
+}
+
+// peer returns a limiter dedicated to a single peer, or nil if the limit is
+// unset or shared by all peers.
+func (l BandwidthLimit) peer() *rateLimiter {
+	if !l.PerPeer {
+		return nil
+	}
+	return newRateLimiter(l.Rate)
+}
+
+// rateLimiter is a token bucket throttling a byte stream to a fixed rate. Bytes
+// may be consumed ahead of time, in which case the next consumer waits until
+// the debt is paid off.
+type rateLimiter struct {
+	lock   sync.Mutex
+	rate   float64   // Bytes per second
+	burst  float64   // Maximum number of bytes that may be accumulated while idle
+	tokens float64   // Number of bytes available, negative if in debt
+	last   time.Time // Time the tokens were last refilled
+}
+
+// newRateLimiter creates a limiter allowing rate bytes per second, or nil if
+// the rate is unlimited.
+func newRateLimiter(rate uint64) *rateLimiter {
+	if rate == 0 {
+		return nil
+	}
+	burst := float64(rate) / 10
+	return &rateLimiter{
+		rate:   float64(rate),
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+// wait consumes n bytes from the limiter, blocking until the rate allows them.
+func (l *rateLimiter) wait(n int) {
+	if l == nil || n <= 0 {
+		return
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	l.tokens -= float64(n)
+	if l.tokens < 0 {
+		time.Sleep(time.Duration(-l.tokens / l.rate * float64(time.Second)))
+	}
+}
+
+// limitedReader throttles the reads of a stream with a set of rate limiters.
+type limitedReader struct {
+	r        io.Reader
+	limiters []*rateLimiter
+}
+
+func newLimitedReader(r io.Reader, limiters ...*rateLimiter) io.Reader {
+	return &limitedReader{r: r, limiters: limiters}
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	for _, l := range r.limiters {
+		l.wait(n)
+	}
+	return n, err
+}
+
+// limitedWriter throttles the writes of a stream with a set of rate limiters.
+type limitedWriter struct {
+	w        io.Writer
+	limiters []*rateLimiter
+}
+
+func newLimitedWriter(w io.Writer, limiters ...*rateLimiter) io.Writer {
+	return &limitedWriter{w: w, limiters: limiters}
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	for _, l := range w.limiters {
+		l.wait(len(p))
+	}
+	return w.w.Write(p)
+}
@@ -0,0 +1,142 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package vntp2p
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testBandwidthRate  = 100 * 1024 // Throttled rate in bytes per second
+	testBandwidthBytes = 50 * 1024  // Bytes to transfer through the throttled stream
+	testBandwidthChunk = 1024       // Size of the individual reads and writes
+)
+
+// expectedTransferTime returns how long transferring size bytes should take at
+// the test rate, accounting for the initial burst allowance.
+func expectedTransferTime(size int) time.Duration {
+	return time.Duration(float64(size-testBandwidthRate/10) / testBandwidthRate * float64(time.Second))
+}
+
+// checkTransferTime verifies that a transfer took roughly as long as the rate
+// limit dictates.
+func checkTransferTime(t *testing.T, elapsed, want time.Duration) {
+	t.Helper()
+	if elapsed < want*9/10 {
+		t.Errorf("transfer not throttled: took %v, want at least %v", elapsed, want*9/10)
+	}
+	if elapsed > want*2 {
+		t.Errorf("transfer throttled too much: took %v, want at most %v", elapsed, want*2)
+	}
+}
+
+// Tests that writes through a throttled stream are capped near the rate.
+func TestLimitedWriter(t *testing.T) {
+	var sink bytes.Buffer
+	w := newLimitedWriter(&sink, newRateLimiter(testBandwidthRate))
+
+	start := time.Now()
+	chunk := make([]byte, testBandwidthChunk)
+	for i := 0; i < testBandwidthBytes/testBandwidthChunk; i++ {
+		if _, err := w.Write(chunk); err != nil {
+			t.Fatalf("write %d failed: %v", i, err)
+		}
+	}
+	checkTransferTime(t, time.Since(start), expectedTransferTime(testBandwidthBytes))
+	if sink.Len() != testBandwidthBytes {
+		t.Fatalf("written bytes mismatch: have %d, want %d", sink.Len(), testBandwidthBytes)
+	}
+}
+
+// Tests that reads from a throttled stream are capped near the rate.
+func TestLimitedReader(t *testing.T) {
+	r := newLimitedReader(bytes.NewReader(make([]byte, testBandwidthBytes)), newRateLimiter(testBandwidthRate))
+
+	start := time.Now()
+	buf := make([]byte, testBandwidthChunk)
+	read := 0
+	for {
+		n, err := r.Read(buf)
+		read += n
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("read failed: %v", err)
+		}
+	}
+	checkTransferTime(t, time.Since(start), expectedTransferTime(testBandwidthBytes))
+	if read != testBandwidthBytes {
+		t.Fatalf("read bytes mismatch: have %d, want %d", read, testBandwidthBytes)
+	}
+}
+
+// Tests that a global limit is shared by all streams while a per-peer limit
+// throttles each of them separately.
+func TestBandwidthLimitSharing(t *testing.T) {
+	transfer := func(limiters func() *rateLimiter) time.Duration {
+		var (
+			wg    sync.WaitGroup
+			start = time.Now()
+		)
+		for i := 0; i < 2; i++ {
+			wg.Add(1)
+			go func(w io.Writer) {
+				defer wg.Done()
+				chunk := make([]byte, testBandwidthChunk)
+				for j := 0; j < testBandwidthBytes/testBandwidthChunk/2; j++ {
+					w.Write(chunk)
+				}
+			}(newLimitedWriter(ioutil.Discard, limiters()))
+		}
+		wg.Wait()
+		return time.Since(start)
+	}
+	shared := BandwidthLimit{Rate: testBandwidthRate}
+	if shared.peer() != nil {
+		t.Fatalf("per-peer limiter created for global limit")
+	}
+	global := shared.global()
+	checkTransferTime(t, transfer(func() *rateLimiter { return global }), expectedTransferTime(testBandwidthBytes))
+
+	perPeer := BandwidthLimit{Rate: testBandwidthRate, PerPeer: true}
+	if perPeer.global() != nil {
+		t.Fatalf("global limiter created for per-peer limit")
+	}
+	checkTransferTime(t, transfer(perPeer.peer), expectedTransferTime(testBandwidthBytes/2))
+}
+
+// Tests that a zero rate leaves streams unthrottled.
+func TestBandwidthUnlimited(t *testing.T) {
+	if l := (BandwidthLimit{}).global(); l != nil {
+		t.Fatalf("limiter created for zero rate")
+	}
+	w := newLimitedWriter(ioutil.Discard, newRateLimiter(0))
+
+	start := time.Now()
+	if _, err := w.Write(make([]byte, 10*testBandwidthRate)); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Fatalf("unlimited write throttled: took %v", elapsed)
+	}
+}
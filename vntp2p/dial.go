@@ -39,6 +39,7 @@ type taskstate struct {
 	maxDynDials int
 	table       DhtTable
 	bootnodes   []peer.ID
+	seeds       []peer.ID // Nodes of the node database to dial once
 	static      map[peer.ID]*dialTask
 	dailmap     map[peer.ID]dialFlag
 }
@@ -111,6 +112,15 @@ func (t *taskstate) newTasks(peers map[peer.ID]*Peer) []task {
 		}
 	}
 
+	// Dial the nodes known from previous runs while dial slots are left
+	for len(t.seeds) > 0 && needdail > 0 {
+		seed := t.seeds[0]
+		t.seeds = t.seeds[1:]
+		if addDial(dynDialedDail, seed) {
+			needdail--
+		}
+	}
+
 	randomDail := needdail / 2
 
 	if randomDail > 0 {
@@ -193,7 +203,9 @@ func (t *dialTask) Do(ctx context.Context, server *Server) {
 	// 直接连接
 	// fmt.Println("it's time to dial")
 	// log.Info("p2p-test", "DailTaskTarget", t.target)
-	t.dial(ctx, server, t.target, t.pid)
+	if err := t.dial(ctx, server, t.target, t.pid); err != nil && server.nodedb != nil {
+		server.nodedb.dialFailed(t.target, time.Now())
+	}
 }
 
 func (t *dialTask) checkTarget() bool {
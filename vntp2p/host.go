@@ -21,6 +21,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"path/filepath"
+	"strconv"
 	"time"
 	// "crypto/rand"
 	// "flag"
@@ -56,7 +57,10 @@ import (
 
 const (
 	// PID vnt protocol basic id
-	PID                 = "/p2p/1.0.0"
+	PID = "/p2p/1.0.0"
+	// PIDSnappy vnt protocol id of streams compressing large messages with snappy
+	PIDSnappy = "/p2p/1.1.0"
+
 	persistDataInterval = 10 * time.Second
 )
 
@@ -85,7 +89,7 @@ func recoverPersistentData(vdb *LevelDB) *dht.PersistentData {
 }
 
 // ConstructDHT create Kademlia DHT
-func ConstructDHT(ctx context.Context, listenstring string, nodekey *ecdsa.PrivateKey, datadir string, restrictList []*net.IPNet, natm libp2p.Option) (*dht.IpfsDHT, p2phost.Host, error) {
+func ConstructDHT(ctx context.Context, listenAddrs []string, nodekey *ecdsa.PrivateKey, datadir string, restrictList []*net.IPNet, natm libp2p.Option, proxy libp2p.Option) (*dht.IpfsDHT, p2phost.Host, error) {
 
 	var pd *dht.PersistentData
 	var vntp2pDB *LevelDB
@@ -116,7 +120,7 @@ func ConstructDHT(ctx context.Context, listenstring string, nodekey *ecdsa.Priva
 		nodekey = privKey
 	} // host private key recover finished
 
-	host, err := constructPeerHost(ctx, listenstring, nodekey, restrictList, natm)
+	host, err := constructPeerHost(ctx, listenAddrs, nodekey, restrictList, natm, proxy)
 	if err != nil {
 		log.Error("ConstructDHT", "constructPeerHost error", err)
 		return nil, nil, err
@@ -206,25 +210,93 @@ func recoverPeerInfosAndBuckets(ctx context.Context, pd *dht.PersistentData, hos
 	}
 }
 
-func constructPeerHost(ctx context.Context, listenstring string, nodekey *ecdsa.PrivateKey, restrictList []*net.IPNet, natm libp2p.Option) (p2phost.Host, error) {
+func constructPeerHost(ctx context.Context, listenAddrs []string, nodekey *ecdsa.PrivateKey, restrictList []*net.IPNet, natm libp2p.Option, proxy libp2p.Option) (p2phost.Host, error) {
 	var options []libp2p.Option
 	if nodekey != nil {
-		options = append(options, libp2p.ListenAddrStrings(listenstring), libp2p.Identity(nodekey))
+		options = append(options, libp2p.ListenAddrStrings(listenAddrs...), libp2p.Identity(nodekey))
 	} else {
-		options = append(options, libp2p.ListenAddrStrings(listenstring))
+		options = append(options, libp2p.ListenAddrStrings(listenAddrs...))
 	}
 
 	options = append(options, libp2p.FilterAddresses(restrictList))
 	if natm != nil {
 		options = append(options, natm)
 	}
+	if proxy != nil {
+		options = append(options, proxy)
+	}
 
 	return libp2p.New(ctx, options...)
 }
 
-func MakePort(port string) string {
+// MakePort returns the multiaddrs listening on the given port of all the IPv4
+// and IPv6 interfaces. Hosts lacking one of the families only listen on the
+// other one.
+func MakePort(port string) []string {
+	return []string{"/ip4/0.0.0.0/tcp/" + port, "/ip6/::/tcp/" + port}
+}
 
-	return "/ip4/0.0.0.0/tcp/" + port
+// ListenAddrs converts a host:port listen address into the multiaddrs to listen
+// on. An empty host listens on both IPv4 and IPv6, an IPv6 host, e.g. [::]:30303,
+// only on IPv6.
+func ListenAddrs(addr string) ([]string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		// Legacy listen addresses carry a bare port after a one byte prefix
+		if len(addr) < 2 {
+			return nil, err
+		}
+		host, port = "", addr[1:]
+	}
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return nil, fmt.Errorf("invalid listen port %q", port)
+	}
+	if host == "" {
+		return MakePort(port), nil
+	}
+	ip := net.ParseIP(host)
+	switch {
+	case ip == nil:
+		return nil, fmt.Errorf("invalid listen IP %q", host)
+	case ip.To4() != nil:
+		return []string{"/ip4/" + ip.String() + "/tcp/" + port}, nil
+	default:
+		return []string{"/ip6/" + ip.String() + "/tcp/" + port}, nil
+	}
+}
+
+// reachableFamilies returns the IP protocols (ma.P_IP4, ma.P_IP6) of the given
+// local addresses that other nodes may be reached over, leaving out loopback
+// and link-local addresses.
+func reachableFamilies(local []ma.Multiaddr) map[int]bool {
+	families := make(map[int]bool)
+	for _, addr := range local {
+		if manet.IsIPLoopback(addr) || manet.IsIP6LinkLocal(addr) {
+			continue
+		}
+		if protos := addr.Protocols(); len(protos) > 0 {
+			families[protos[0].Code] = true
+		}
+	}
+	return families
+}
+
+// splitReachable splits the addresses of a remote node into the ones of the
+// reachable IP families, or loopback ones, and the ones that can't be dialed.
+func splitReachable(families map[int]bool, addrs []ma.Multiaddr) (reachable, unreachable []ma.Multiaddr) {
+	for _, addr := range addrs {
+		protos := addr.Protocols()
+		if len(protos) == 0 || manet.IsIPLoopback(addr) || families[protos[0].Code] {
+			reachable = append(reachable, addr)
+			continue
+		}
+		if code := protos[0].Code; code == ma.P_IP4 || code == ma.P_IP6 {
+			unreachable = append(unreachable, addr)
+		} else {
+			reachable = append(reachable, addr)
+		}
+	}
+	return reachable, unreachable
 }
 
 func GetIPfromAddr(a ma.Multiaddr) string {
@@ -0,0 +1,106 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package vntp2p
+
+import (
+	"fmt"
+	"testing"
+
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// Tests that listen addresses are turned into the multiaddrs of the right IP
+// families.
+func TestListenAddrs(t *testing.T) {
+	tests := []struct {
+		addr  string
+		addrs []string
+		fails bool
+	}{
+		{addr: ":30303", addrs: []string{"/ip4/0.0.0.0/tcp/30303", "/ip6/::/tcp/30303"}},
+		{addr: "0.0.0.0:30303", addrs: []string{"/ip4/0.0.0.0/tcp/30303"}},
+		{addr: "[::]:30303", addrs: []string{"/ip6/::/tcp/30303"}},
+		{addr: "[2001:db8::1]:30303", addrs: []string{"/ip6/2001:db8::1/tcp/30303"}},
+		{addr: "0030304", addrs: []string{"/ip4/0.0.0.0/tcp/030304", "/ip6/::/tcp/030304"}},
+		{addr: "localhost:30303", fails: true},
+		{addr: ":port", fails: true},
+		{addr: ":70000", fails: true},
+	}
+	for _, tt := range tests {
+		addrs, err := ListenAddrs(tt.addr)
+		if tt.fails {
+			if err == nil {
+				t.Errorf("%q: expected error, have %v", tt.addr, addrs)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.addr, err)
+			continue
+		}
+		if fmt.Sprint(addrs) != fmt.Sprint(tt.addrs) {
+			t.Errorf("%q: addresses mismatch: have %v, want %v", tt.addr, addrs, tt.addrs)
+		}
+	}
+}
+
+// Tests that the addresses of remote nodes are split by the IP families the
+// local node can reach them over.
+func TestSplitReachable(t *testing.T) {
+	parse := func(addrs ...string) []ma.Multiaddr {
+		var res []ma.Multiaddr
+		for _, addr := range addrs {
+			res = append(res, ma.StringCast(addr))
+		}
+		return res
+	}
+	remote := parse("/ip4/10.0.0.1/tcp/30303", "/ip6/2001:db8::1/tcp/30303", "/ip4/127.0.0.1/tcp/30303")
+
+	tests := []struct {
+		local       []ma.Multiaddr
+		reachable   string
+		unreachable string
+	}{
+		// IPv4-only host, the IPv6 link-local and loopback addresses don't count
+		{
+			local:       parse("/ip4/10.0.0.2/tcp/30303", "/ip6/::1/tcp/30303", "/ip6/fe80::1/tcp/30303"),
+			reachable:   "[/ip4/10.0.0.1/tcp/30303 /ip4/127.0.0.1/tcp/30303]",
+			unreachable: "[/ip6/2001:db8::1/tcp/30303]",
+		},
+		// IPv6-only host, loopback addresses are still dialable
+		{
+			local:       parse("/ip4/127.0.0.1/tcp/30303", "/ip6/2001:db8::2/tcp/30303"),
+			reachable:   "[/ip6/2001:db8::1/tcp/30303 /ip4/127.0.0.1/tcp/30303]",
+			unreachable: "[/ip4/10.0.0.1/tcp/30303]",
+		},
+		// Dual-stack host
+		{
+			local:       parse("/ip4/10.0.0.2/tcp/30303", "/ip6/2001:db8::2/tcp/30303"),
+			reachable:   fmt.Sprint(remote),
+			unreachable: "[]",
+		},
+	}
+	for i, tt := range tests {
+		reachable, unreachable := splitReachable(reachableFamilies(tt.local), remote)
+		if fmt.Sprint(reachable) != tt.reachable {
+			t.Errorf("test %d: reachable mismatch: have %v, want %v", i, reachable, tt.reachable)
+		}
+		if fmt.Sprint(unreachable) != tt.unreachable {
+			t.Errorf("test %d: unreachable mismatch: have %v, want %v", i, unreachable, tt.unreachable)
+		}
+	}
+}
@@ -25,8 +25,9 @@ import (
 	"io/ioutil"
 	"time"
 
-	inet "github.com/libp2p/go-libp2p-net"
+	"github.com/golang/snappy"
 	"github.com/vntchain/go-vnt/log"
+	"github.com/vntchain/go-vnt/metrics"
 	"github.com/vntchain/go-vnt/rlp"
 )
 
@@ -46,6 +47,18 @@ type MsgWriter interface {
 // MessageHeaderLength define message header length
 const MessageHeaderLength = 5
 
+// msgFlagSnappy marks a message body compressed with snappy in the last byte of
+// the header, which is only set on streams negotiated with PIDSnappy.
+const msgFlagSnappy = 0x01
+
+// snappyThreshold is the body size above which messages are compressed on the
+// streams supporting it, smaller ones aren't worth the effort.
+const snappyThreshold = 1024
+
+// DefaultMaxMessageSize is the maximum inbound message body size accepted from
+// a remote peer unless configured otherwise.
+const DefaultMaxMessageSize = 16 * 1024 * 1024
+
 // MessageType define vnt p2p protocol message type
 type MessageType uint64
 
@@ -168,8 +181,13 @@ type VNTMessenger struct {
 	protocol    Protocol
 	in          chan Msg
 	err         chan error
-	w           inet.Stream
+	w           io.Writer
 	peerPointer *Peer
+	snappy      bool // Whether large messages are compressed with snappy
+
+	traffic      trafficCounter // Bytes exchanged over this protocol
+	ingressMeter metrics.Meter  // Meter for the inbound traffic of the protocol
+	egressMeter  metrics.Meter  // Meter for the outbound traffic of the protocol
 }
 
 // WriteMsg implement MsgReadWriter interface
@@ -185,6 +203,12 @@ func (rw *VNTMessenger) WriteMsg(msg Msg) (err error) {
 		log.Error("WriteMsg()", "marshal msgbody error", err)
 		return err
 	}
+	if rw.snappy && len(msgBodyByte) > snappyThreshold {
+		msgBodyByte = snappy.Encode(nil, msgBodyByte)
+		msgHeaderByte = make([]byte, MessageHeaderLength)
+		binary.LittleEndian.PutUint32(msgHeaderByte, uint32(len(msgBodyByte)))
+		msgHeaderByte[MessageHeaderLength-1] = msgFlagSnappy
+	}
 	m := append(msgHeaderByte, msgBodyByte...)
 	//log.Info("p2p-test", "MESSAGE", string(m))
 
@@ -198,6 +222,7 @@ func (rw *VNTMessenger) WriteMsg(msg Msg) (err error) {
 		log.Trace("WriteMsg() exit", "peer", rw.peerPointer.RemoteID())
 		return err
 	}
+	rw.peerPointer.meterEgress(rw, len(m))
 	return nil
 }
 
@@ -0,0 +1,113 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package vntp2p
+
+import (
+	"sync/atomic"
+
+	"github.com/vntchain/go-vnt/metrics"
+)
+
+const (
+	ingressMeterName = "p2p/InboundTraffic"
+	egressMeterName  = "p2p/OutboundTraffic"
+)
+
+var (
+	ingressTrafficMeter = metrics.NewRegisteredMeter(ingressMeterName, nil)
+	egressTrafficMeter  = metrics.NewRegisteredMeter(egressMeterName, nil)
+)
+
+// Traffic is the number of bytes exchanged with remote peers in each direction,
+// message framing included.
+type Traffic struct {
+	Ingress uint64 `json:"ingress"` // Bytes received
+	Egress  uint64 `json:"egress"`  // Bytes sent
+}
+
+// PeerTraffic is the traffic exchanged with a single peer, in total and split
+// by sub-protocol.
+type PeerTraffic struct {
+	Traffic
+	Protocols map[string]Traffic `json:"protocols"`
+}
+
+// trafficCounter accumulates the bytes exchanged over a connection or protocol.
+// It is safe for concurrent use.
+type trafficCounter struct {
+	ingress uint64 // Accessed atomically
+	egress  uint64 // Accessed atomically
+}
+
+func (c *trafficCounter) markIngress(n int) {
+	if c != nil {
+		atomic.AddUint64(&c.ingress, uint64(n))
+	}
+}
+
+func (c *trafficCounter) markEgress(n int) {
+	if c != nil {
+		atomic.AddUint64(&c.egress, uint64(n))
+	}
+}
+
+// traffic returns a snapshot of the bytes counted so far.
+func (c *trafficCounter) traffic() Traffic {
+	return Traffic{
+		Ingress: atomic.LoadUint64(&c.ingress),
+		Egress:  atomic.LoadUint64(&c.egress),
+	}
+}
+
+// protocolMeters returns the metered ingress and egress traffic of a protocol.
+func protocolMeters(name string) (metrics.Meter, metrics.Meter) {
+	return metrics.GetOrRegisterMeter(ingressMeterName+"/"+name, nil),
+		metrics.GetOrRegisterMeter(egressMeterName+"/"+name, nil)
+}
+
+// meterIngress accounts n bytes received from the peer, on the messenger of the
+// protocol they were addressed to if it is known.
+func (p *Peer) meterIngress(m *VNTMessenger, n int) {
+	ingressTrafficMeter.Mark(int64(n))
+	p.traffic.markIngress(n)
+	p.serverTraffic.markIngress(n)
+	if m != nil {
+		m.traffic.markIngress(n)
+		m.ingressMeter.Mark(int64(n))
+	}
+}
+
+// meterEgress accounts n bytes sent to the peer by the messenger of a protocol.
+func (p *Peer) meterEgress(m *VNTMessenger, n int) {
+	egressTrafficMeter.Mark(int64(n))
+	p.traffic.markEgress(n)
+	p.serverTraffic.markEgress(n)
+	m.traffic.markEgress(n)
+	m.egressMeter.Mark(int64(n))
+}
+
+// Traffic returns the bytes exchanged with the peer since it connected.
+func (p *Peer) Traffic() *PeerTraffic {
+	traffic := &PeerTraffic{
+		Traffic:   p.traffic.traffic(),
+		Protocols: make(map[string]Traffic),
+	}
+	for name, m := range p.messenger {
+		traffic.Protocols[name] = m.traffic.traffic()
+	}
+	return traffic
+}
@@ -0,0 +1,50 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package vntp2p
+
+import (
+	"reflect"
+	"testing"
+)
+
+// Tests that the traffic of a peer is accounted in total, per protocol and on
+// the server the peer belongs to.
+func TestPeerTraffic(t *testing.T) {
+	server := new(trafficCounter)
+	p := newPeer(&Stream{Protocols: []Protocol{{Name: "vnt"}, {Name: "les"}}})
+	p.serverTraffic = server
+
+	p.meterIngress(p.messenger["vnt"], 100)
+	p.meterIngress(p.messenger["les"], 20)
+	p.meterIngress(nil, 3) // Message for an unsupported protocol
+	p.meterEgress(p.messenger["vnt"], 40)
+	p.meterEgress(p.messenger["vnt"], 2)
+
+	want := &PeerTraffic{
+		Traffic: Traffic{Ingress: 123, Egress: 42},
+		Protocols: map[string]Traffic{
+			"vnt": {Ingress: 100, Egress: 42},
+			"les": {Ingress: 20},
+		},
+	}
+	if have := p.Traffic(); !reflect.DeepEqual(have, want) {
+		t.Errorf("peer traffic mismatch: have %+v, want %+v", have, want)
+	}
+	if have := server.traffic(); have != want.Traffic {
+		t.Errorf("server traffic mismatch: have %+v, want %+v", have, want.Traffic)
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package vntp2p
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	peer "github.com/libp2p/go-libp2p-peer"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/errors"
+	"github.com/syndtr/goleveldb/leveldb/storage"
+	"github.com/syndtr/goleveldb/leveldb/util"
+	"github.com/vntchain/go-vnt/log"
+)
+
+const (
+	nodeDBDir          = "nodes"            // Directory of the node database within the node database path
+	nodeDBPrefix       = "n:"               // Prefix of the node records in the database
+	nodeDBExpiration   = 7 * 24 * time.Hour // Time after which an unseen node is dropped
+	nodeDBMaxFailures  = 5                  // Consecutive dial failures after which a node is dropped
+	nodeDBSeedCount    = 30                 // Number of nodes to seed the dial scheduler with
+	nodeDBSeedMaxAge   = 5 * 24 * time.Hour // Maximum time since a seed node was last seen
+	nodeDBFailureDelay = 10 * time.Minute   // Time a failed node isn't used as a seed for
+)
+
+// nodeRecord is the history of a node stored in the node database.
+type nodeRecord struct {
+	ID       peer.ID  `json:"-"`
+	Addrs    []string `json:"addrs"`    // Addresses the node was reachable at
+	LastSeen int64    `json:"lastSeen"` // Unix time the node was last connected
+	LastDial int64    `json:"lastDial"` // Unix time the node was last dialed without success
+	Failures int      `json:"failures"` // Consecutive dial failures since it was last connected
+}
+
+// multiaddrs returns the parseable addresses of the node.
+func (r *nodeRecord) multiaddrs() []ma.Multiaddr {
+	var addrs []ma.Multiaddr
+	for _, s := range r.Addrs {
+		if addr, err := ma.NewMultiaddr(s); err == nil {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+// nodeDB stores the nodes the server connected to, when they were last seen and
+// their failed dials, so that a restarted server can dial known nodes again
+// instead of starting the peer discovery from scratch.
+type nodeDB struct {
+	db *leveldb.DB
+}
+
+// newNodeDB opens the node database at path, or an in-memory one if path is
+// empty, dropping the nodes that expired.
+func newNodeDB(path string) (*nodeDB, error) {
+	var (
+		db  *leveldb.DB
+		err error
+	)
+	if path == "" {
+		db, err = leveldb.Open(storage.NewMemStorage(), nil)
+	} else {
+		db, err = leveldb.OpenFile(path, nil)
+		if _, corrupted := err.(*errors.ErrCorrupted); corrupted {
+			db, err = leveldb.RecoverFile(path, nil)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	ndb := &nodeDB{db: db}
+	ndb.expire(time.Now())
+	return ndb, nil
+}
+
+// node retrieves the record of a node, or nil if it's unknown.
+func (db *nodeDB) node(id peer.ID) *nodeRecord {
+	blob, err := db.db.Get(nodeDBKey(id), nil)
+	if err != nil {
+		return nil
+	}
+	record := new(nodeRecord)
+	if err := json.Unmarshal(blob, record); err != nil {
+		return nil
+	}
+	record.ID = id
+	return record
+}
+
+// storeNode writes the record of a node.
+func (db *nodeDB) storeNode(record *nodeRecord) error {
+	blob, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return db.db.Put(nodeDBKey(record.ID), blob, nil)
+}
+
+// nodeSeen records a connection to the node, resetting its dial failures. The
+// known addresses are kept if no new ones are given.
+func (db *nodeDB) nodeSeen(id peer.ID, addrs []ma.Multiaddr, now time.Time) error {
+	record := db.node(id)
+	if record == nil {
+		record = &nodeRecord{ID: id}
+	}
+	if len(addrs) > 0 {
+		record.Addrs = record.Addrs[:0]
+		for _, addr := range addrs {
+			record.Addrs = append(record.Addrs, addr.String())
+		}
+	}
+	record.LastSeen, record.Failures = now.Unix(), 0
+	return db.storeNode(record)
+}
+
+// dialFailed records a failed dial of a known node, dropping it if it failed
+// too many times in a row.
+func (db *nodeDB) dialFailed(id peer.ID, now time.Time) error {
+	record := db.node(id)
+	if record == nil {
+		return nil
+	}
+	record.Failures++
+	record.LastDial = now.Unix()
+	if record.Failures >= nodeDBMaxFailures {
+		return db.db.Delete(nodeDBKey(id), nil)
+	}
+	return db.storeNode(record)
+}
+
+// seeds returns up to n nodes seen within maxAge, most recently seen first,
+// leaving out the ones that recently failed to be dialed.
+func (db *nodeDB) seeds(n int, maxAge time.Duration, now time.Time) []*nodeRecord {
+	var records []*nodeRecord
+	db.iterate(func(record *nodeRecord) {
+		switch {
+		case now.Sub(time.Unix(record.LastSeen, 0)) > maxAge:
+		case record.Failures > 0 && now.Sub(time.Unix(record.LastDial, 0)) < nodeDBFailureDelay:
+		case len(record.multiaddrs()) == 0:
+		default:
+			records = append(records, record)
+		}
+	})
+	sort.Slice(records, func(i, j int) bool { return records[i].LastSeen > records[j].LastSeen })
+	if len(records) > n {
+		records = records[:n]
+	}
+	return records
+}
+
+// expire drops the nodes not seen for longer than the expiration.
+func (db *nodeDB) expire(now time.Time) {
+	var dropped int
+	db.iterate(func(record *nodeRecord) {
+		if now.Sub(time.Unix(record.LastSeen, 0)) > nodeDBExpiration {
+			db.db.Delete(nodeDBKey(record.ID), nil)
+			dropped++
+		}
+	})
+	if dropped > 0 {
+		log.Debug("Expired nodes from the node database", "count", dropped)
+	}
+}
+
+// iterate calls fn for all the decodable node records.
+func (db *nodeDB) iterate(fn func(*nodeRecord)) {
+	it := db.db.NewIterator(util.BytesPrefix([]byte(nodeDBPrefix)), nil)
+	defer it.Release()
+
+	for it.Next() {
+		record := new(nodeRecord)
+		if err := json.Unmarshal(it.Value(), record); err != nil {
+			continue
+		}
+		record.ID = peer.ID(it.Key()[len(nodeDBPrefix):])
+		fn(record)
+	}
+}
+
+// close flushes and closes the database.
+func (db *nodeDB) close() error {
+	return db.db.Close()
+}
+
+// nodeDBKey returns the database key of the record of a node.
+func nodeDBKey(id peer.ID) []byte {
+	return append([]byte(nodeDBPrefix), id...)
+}
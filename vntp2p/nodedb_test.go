@@ -0,0 +1,113 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package vntp2p
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	peer "github.com/libp2p/go-libp2p-peer"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// Tests that the node database hands out the recently seen nodes as seeds, in
+// the order they were seen, and forgets the failing and expired ones.
+func TestNodeDBSeeds(t *testing.T) {
+	db, err := newNodeDB("")
+	if err != nil {
+		t.Fatalf("failed to open node database: %v", err)
+	}
+	defer db.close()
+
+	var (
+		now   = time.Now()
+		addr  = []ma.Multiaddr{ma.StringCast("/ip4/10.0.0.1/tcp/3001")}
+		fresh = peer.ID("fresh")
+		older = peer.ID("older")
+		stale = peer.ID("stale")
+		flaky = peer.ID("flaky")
+	)
+	db.nodeSeen(older, addr, now.Add(-time.Hour))
+	db.nodeSeen(fresh, addr, now.Add(-time.Minute))
+	db.nodeSeen(stale, addr, now.Add(-nodeDBExpiration-time.Hour))
+	db.nodeSeen(flaky, addr, now.Add(-time.Minute))
+	db.dialFailed(flaky, now)
+
+	seeds := db.seeds(nodeDBSeedCount, nodeDBSeedMaxAge, now)
+	if len(seeds) != 2 || seeds[0].ID != fresh || seeds[1].ID != older {
+		t.Fatalf("seeds mismatch: have %v, want [%v %v]", seeds, fresh, older)
+	}
+	if have := seeds[0].multiaddrs(); len(have) != 1 || !have[0].Equal(addr[0]) {
+		t.Errorf("seed addresses mismatch: have %v, want %v", have, addr)
+	}
+	if seeds := db.seeds(1, nodeDBSeedMaxAge, now); len(seeds) != 1 || seeds[0].ID != fresh {
+		t.Errorf("limited seeds mismatch: have %v, want [%v]", seeds, fresh)
+	}
+	// Failing nodes are retried after a while, and dropped if they keep failing
+	if seeds := db.seeds(nodeDBSeedCount, nodeDBSeedMaxAge, now.Add(nodeDBFailureDelay)); len(seeds) != 3 {
+		t.Errorf("seeds after failure delay mismatch: have %d, want %d", len(seeds), 3)
+	}
+	for i := 1; i < nodeDBMaxFailures; i++ {
+		db.dialFailed(flaky, now)
+	}
+	if record := db.node(flaky); record != nil {
+		t.Errorf("failing node not dropped: %+v", record)
+	}
+	// Connecting resets the failures but keeps the known addresses
+	db.dialFailed(older, now)
+	db.nodeSeen(older, nil, now)
+	if record := db.node(older); record == nil || record.Failures != 0 || len(record.Addrs) != 1 {
+		t.Errorf("reconnected node mismatch: have %+v", record)
+	}
+	// Expired nodes are dropped
+	db.expire(now)
+	if record := db.node(stale); record != nil {
+		t.Errorf("expired node not dropped: %+v", record)
+	}
+	if record := db.node(fresh); record == nil {
+		t.Errorf("recent node dropped")
+	}
+}
+
+// Tests that the node history survives reopening the database.
+func TestNodeDBPersistence(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vntp2p-nodedb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, nodeDBDir)
+	db, err := newNodeDB(path)
+	if err != nil {
+		t.Fatalf("failed to open node database: %v", err)
+	}
+	db.nodeSeen(peer.ID("node"), []ma.Multiaddr{ma.StringCast("/ip4/10.0.0.1/tcp/3001")}, time.Now())
+	db.close()
+
+	if db, err = newNodeDB(path); err != nil {
+		t.Fatalf("failed to reopen node database: %v", err)
+	}
+	defer db.close()
+
+	if seeds := db.seeds(nodeDBSeedCount, nodeDBSeedMaxAge, time.Now()); len(seeds) != 1 || seeds[0].ID != peer.ID("node") {
+		t.Errorf("seeds mismatch: have %v, want [node]", seeds)
+	}
+}
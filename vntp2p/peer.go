@@ -77,6 +77,7 @@ type PeerInfo struct {
 		Static        bool   `json:"static"`
 	} `json:"network"`
 	Protocols map[string]interface{} `json:"protocols"` // Sub-protocol specific metadata fields
+	Traffic   *PeerTraffic           `json:"traffic"`   // Bytes exchanged with the peer since it connected
 }
 
 type Peer struct {
@@ -88,17 +89,28 @@ type Peer struct {
 	messenger map[string]*VNTMessenger // protocolName - vntMessenger
 	wg        sync.WaitGroup
 	// need to add wg
+
+	downloadLimiter *rateLimiter // Limiter for reads from this peer, nil if unthrottled
+	uploadLimiter   *rateLimiter // Limiter for writes to this peer, nil if unthrottled
+
+	traffic       trafficCounter  // Bytes exchanged with this peer over all protocols
+	serverTraffic *trafficCounter // Bytes exchanged with all peers of the server, nil if untracked
 }
 
 func newPeer(conn *Stream) *Peer {
 	m := make(map[string]*VNTMessenger)
+	compress := conn.Conn != nil && conn.Conn.Protocol() == PIDSnappy
 	for i := range conn.Protocols {
 		proto := conn.Protocols[i]
+		ingress, egress := protocolMeters(proto.Name)
 		vntMessenger := &VNTMessenger{
-			protocol: proto,
-			in:       make(chan Msg),
-			err:      make(chan error),
-			w:        conn.Conn,
+			protocol:     proto,
+			in:           make(chan Msg),
+			err:          make(chan error),
+			w:            conn.Conn,
+			snappy:       compress,
+			ingressMeter: ingress,
+			egressMeter:  egress,
 		}
 		m[proto.Name] = vntMessenger
 	}
@@ -183,7 +195,8 @@ func (p *Peer) Disconnect(reason DiscReason) {
 
 func (p *Peer) Info() *PeerInfo {
 	info := &PeerInfo{
-		ID: p.RemoteID().String(),
+		ID:      p.RemoteID().String(),
+		Traffic: p.Traffic(),
 	}
 	info.Network.LocalAddress = p.rw.Conn().LocalMultiaddr().String()
 	info.Network.RemoteAddress = p.rw.Conn().RemoteMultiaddr().String()
@@ -22,6 +22,7 @@ import (
 	"io"
 	"time"
 
+	"github.com/golang/snappy"
 	inet "github.com/libp2p/go-libp2p-net"
 	libp2p "github.com/libp2p/go-libp2p-peer"
 	"github.com/vntchain/go-vnt/log"
@@ -48,41 +49,22 @@ func (server *Server) HandleStream(s inet.Stream) {
 			log.Info("HandleStream", "localPeerID", s.Conn().LocalPeer(), "remotePeerID", s.Conn().RemotePeer(), "this remote peer is nil, don't handle it")
 			return
 		}
-		msgHeaderByte := make([]byte, MessageHeaderLength)
-		_, err := io.ReadFull(s, msgHeaderByte)
-		if err != nil {
-			//log.Error("handleStream", "read error", err)
-			notifyError(peer.messenger, err)
-			return
-		}
-		bodySize := binary.LittleEndian.Uint32(msgHeaderByte)
-
-		msgBodyByte := make([]byte, bodySize)
-		_, err = io.ReadFull(s, msgBodyByte)
-		if err != nil {
-			log.Error("handleStream", "read msgBody error", err)
-			notifyError(peer.messenger, err)
-			return
+		var r io.Reader = s
+		if server.downloadLimiter != nil || peer.downloadLimiter != nil {
+			r = newLimitedReader(s, server.downloadLimiter, peer.downloadLimiter)
 		}
-		msgBody := &MsgBody{Payload: &rlp.EncReader{}}
-		err = json.Unmarshal(msgBodyByte, msgBody)
+		msg, size, err := readMsg(r, server.maxMessageSize(), s.Protocol() == PIDSnappy)
 		if err != nil {
-			log.Error("handleSteam", "unmarshal msgBody error", err)
+			if err == DiscMsgTooLarge {
+				log.Warn("Dropping peer sending oversized message", "peer", s.Conn().RemotePeer(), "limit", server.maxMessageSize())
+				peer.Disconnect(DiscMsgTooLarge)
+			}
 			notifyError(peer.messenger, err)
 			return
 		}
-		msgBody.ReceivedAt = time.Now()
-		//log.Info("p2p-test", "RECEIVED MESSAGE", msgBody)
-
-		var msgHeader MsgHeader
-		copy(msgHeader[:], msgHeaderByte)
-
-		msg := Msg{
-			Header: msgHeader,
-			Body:   *msgBody,
-		}
-
-		if messenger, ok := peer.messenger[msgBody.ProtocolID]; ok { // this node support protocolID
+		messenger := peer.messenger[msg.Body.ProtocolID]
+		peer.meterIngress(messenger, MessageHeaderLength+int(size))
+		if messenger != nil { // this node support protocolID
 			messenger.in <- msg
 		} else {
 			log.Warn("handleStream", "receive Unknown Message", msg)
@@ -97,6 +79,60 @@ func (server *Server) HandleStream(s inet.Stream) {
 	}
 }
 
+// readMsg reads a single framed message from r, returning it along with the
+// size of its body on the wire. Messages announcing a body larger than maxSize
+// are rejected with DiscMsgTooLarge before any room is allocated for them, the
+// same limit applying to the decompressed body of snappy messages, which are
+// only accepted if compression was negotiated on the stream.
+func readMsg(r io.Reader, maxSize uint32, snappyStream bool) (Msg, uint32, error) {
+	msgHeaderByte := make([]byte, MessageHeaderLength)
+	if _, err := io.ReadFull(r, msgHeaderByte); err != nil {
+		return Msg{}, 0, err
+	}
+	bodySize := binary.LittleEndian.Uint32(msgHeaderByte)
+	if bodySize > maxSize {
+		return Msg{}, 0, DiscMsgTooLarge
+	}
+
+	msgBodyByte := make([]byte, bodySize)
+	if _, err := io.ReadFull(r, msgBodyByte); err != nil {
+		log.Error("handleStream", "read msgBody error", err)
+		return Msg{}, 0, err
+	}
+	if msgHeaderByte[MessageHeaderLength-1]&msgFlagSnappy != 0 {
+		if !snappyStream {
+			return Msg{}, 0, newPeerError(errInvalidMsg, "snappy message on uncompressed stream")
+		}
+		size, err := snappy.DecodedLen(msgBodyByte)
+		if err != nil {
+			return Msg{}, 0, newPeerError(errInvalidMsg, "snappy: %v", err)
+		}
+		if size > int(maxSize) {
+			return Msg{}, 0, DiscMsgTooLarge
+		}
+		if msgBodyByte, err = snappy.Decode(nil, msgBodyByte); err != nil {
+			return Msg{}, 0, newPeerError(errInvalidMsg, "snappy: %v", err)
+		}
+		// Protocols see the message as if it was sent uncompressed
+		binary.LittleEndian.PutUint32(msgHeaderByte, uint32(len(msgBodyByte)))
+		msgHeaderByte[MessageHeaderLength-1] = 0
+	}
+	msgBody := &MsgBody{Payload: &rlp.EncReader{}}
+	if err := json.Unmarshal(msgBodyByte, msgBody); err != nil {
+		log.Error("handleSteam", "unmarshal msgBody error", err)
+		return Msg{}, 0, err
+	}
+	msgBody.ReceivedAt = time.Now()
+
+	var msgHeader MsgHeader
+	copy(msgHeader[:], msgHeaderByte)
+
+	return Msg{
+		Header: msgHeader,
+		Body:   *msgBody,
+	}, bodySize, nil
+}
+
 func notifyError(messengers map[string]*VNTMessenger, err error) {
 	for _, m := range messengers {
 		m.err <- err
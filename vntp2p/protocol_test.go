@@ -0,0 +1,139 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package vntp2p
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+// frameWriter is a fake remote peer writing framed messages onto a connection.
+type frameWriter struct {
+	w io.Writer
+}
+
+func (fw *frameWriter) WriteMsg(msg Msg) error {
+	body, err := json.Marshal(msg.Body)
+	if err != nil {
+		return err
+	}
+	_, err = fw.w.Write(append(msg.Header[:], body...))
+	return err
+}
+
+// Tests that messages within the size limit are read and decoded.
+func TestReadMsgWithinLimit(t *testing.T) {
+	local, remote := net.Pipe()
+	defer local.Close()
+	defer remote.Close()
+
+	go Send(&frameWriter{remote}, "test", GoodMorning, "hello")
+
+	msg, _, err := readMsg(local, DefaultMaxMessageSize, false)
+	if err != nil {
+		t.Fatalf("failed to read message: %v", err)
+	}
+	if msg.Body.ProtocolID != "test" || msg.Body.Type != GoodMorning {
+		t.Fatalf("message mismatch: have %s/%d, want test/%d", msg.Body.ProtocolID, msg.Body.Type, GoodMorning)
+	}
+	var greet string
+	if err := msg.Decode(&greet); err != nil || greet != "hello" {
+		t.Fatalf("payload mismatch: have %q (%v), want %q", greet, err, "hello")
+	}
+}
+
+// Tests that large messages are compressed on snappy streams, decompressed
+// transparently on the receiving side and rejected on plain streams.
+func TestReadMsgSnappy(t *testing.T) {
+	payload := strings.Repeat("hello", 1000)
+
+	for _, negotiated := range []bool{true, false} {
+		local, remote := net.Pipe()
+
+		p := newPeer(&Stream{Protocols: []Protocol{{Name: "test"}}})
+		messenger := p.messenger["test"]
+		messenger.w, messenger.snappy = remote, true
+		errc := make(chan error, 1)
+		go func() { errc <- Send(messenger, "test", GoodMorning, payload) }()
+
+		msg, size, err := readMsg(local, DefaultMaxMessageSize, negotiated)
+		if err := <-errc; err != nil {
+			t.Fatalf("failed to send message: %v", err)
+		}
+		local.Close()
+		remote.Close()
+
+		if !negotiated {
+			if err == nil {
+				t.Errorf("snappy message accepted on plain stream")
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("failed to read message: %v", err)
+		}
+		if size >= msg.GetBodySize() {
+			t.Errorf("wire size mismatch: have %d, want below %d", size, msg.GetBodySize())
+		}
+		if traffic := p.Traffic().Egress; traffic != uint64(MessageHeaderLength+size) {
+			t.Errorf("egress traffic mismatch: have %d, want %d", traffic, MessageHeaderLength+size)
+		}
+		var have string
+		if err := msg.Decode(&have); err != nil || have != payload {
+			t.Errorf("payload mismatch: have %d bytes (%v), want %d bytes", len(have), err, len(payload))
+		}
+	}
+}
+
+// Tests that a peer announcing an oversized frame is rejected with the
+// dedicated disconnect reason before its body is consumed.
+func TestReadMsgOversized(t *testing.T) {
+	local, remote := net.Pipe()
+	defer local.Close()
+
+	const limit = 1024
+	go func() {
+		defer remote.Close()
+
+		header := make([]byte, MessageHeaderLength)
+		binary.LittleEndian.PutUint32(header, limit+1)
+		remote.Write(header)
+		remote.Write(make([]byte, limit+1))
+	}()
+
+	_, _, err := readMsg(local, limit, false)
+	if err != DiscMsgTooLarge {
+		t.Fatalf("error mismatch: have %v, want %v", err, DiscMsgTooLarge)
+	}
+	if reason := discReasonForError(err); reason != DiscMsgTooLarge {
+		t.Fatalf("disconnect reason mismatch: have %v, want %v", reason, DiscMsgTooLarge)
+	}
+}
+
+// Tests that the configured message size limit falls back to the default.
+func TestMaxMessageSizeDefault(t *testing.T) {
+	if size := (&Config{}).maxMessageSize(); size != DefaultMaxMessageSize {
+		t.Errorf("default limit mismatch: have %d, want %d", size, DefaultMaxMessageSize)
+	}
+	if size := (&Config{MaxMessageSize: 4096}).maxMessageSize(); size != 4096 {
+		t.Errorf("configured limit mismatch: have %d, want %d", size, 4096)
+	}
+}
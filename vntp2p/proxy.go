@@ -0,0 +1,265 @@
+// Copyright 2019 The go-vnt Authors
+// This file is part of the go-vnt library.
+//
+// The go-vnt library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-vnt library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-vnt library. If not, see <http://www.gnu.org/licenses/>.
+
+package vntp2p
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	libp2p "github.com/libp2p/go-libp2p"
+	peer "github.com/libp2p/go-libp2p-peer"
+	tpt "github.com/libp2p/go-libp2p-transport"
+	tptu "github.com/libp2p/go-libp2p-transport-upgrader"
+	tcp "github.com/libp2p/go-tcp-transport"
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr-net"
+)
+
+const (
+	socks5Version      = 0x05
+	socks5NoAuth       = 0x00
+	socks5PasswordAuth = 0x02
+	socks5NoAcceptable = 0xff
+	socks5Connect      = 0x01
+	socks5IPv4         = 0x01
+	socks5Domain       = 0x03
+	socks5IPv6         = 0x04
+)
+
+// socks5Errors are the failures a proxy reports for a connect request.
+var socks5Errors = []string{
+	"",
+	"general failure",
+	"connection not allowed by ruleset",
+	"network unreachable",
+	"host unreachable",
+	"connection refused",
+	"TTL expired",
+	"command not supported",
+	"address type not supported",
+}
+
+// ProxyParse parses the address of a SOCKS5 proxy, given as
+// "[socks5://][user:password@]host:port", into an option making the host dial
+// all outbound TCP connections through it. Listening is not affected.
+func ProxyParse(spec string) (libp2p.Option, error) {
+	if spec == "" {
+		return nil, nil
+	}
+	if !strings.Contains(spec, "://") {
+		spec = "socks5://" + spec
+	}
+	u, err := url.Parse(spec)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "socks5" {
+		return nil, fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+	}
+	if u.Hostname() == "" || u.Port() == "" {
+		return nil, fmt.Errorf("proxy address %q must be host:port", u.Host)
+	}
+	dialer := &socks5Dialer{addr: u.Host}
+	if u.User != nil {
+		dialer.user = u.User.Username()
+		dialer.password, _ = u.User.Password()
+	}
+	return libp2p.Transport(func(upgrader *tptu.Upgrader) *proxyTransport {
+		return &proxyTransport{TcpTransport: tcp.NewTCPTransport(upgrader), dialer: dialer}
+	}), nil
+}
+
+// proxyTransport is a TCP transport dialing through a SOCKS5 proxy.
+type proxyTransport struct {
+	*tcp.TcpTransport
+	dialer *socks5Dialer
+}
+
+// Dial dials the peer at the remote address through the proxy.
+func (t *proxyTransport) Dial(ctx context.Context, raddr ma.Multiaddr, p peer.ID) (tpt.Conn, error) {
+	_, addr, err := manet.DialArgs(raddr)
+	if err != nil {
+		return nil, err
+	}
+	if t.ConnectTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.ConnectTimeout)
+		defer cancel()
+	}
+	conn, err := t.dialer.DialContext(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	laddr, err := manet.FromNetAddr(conn.LocalAddr())
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return t.Upgrader.UpgradeOutbound(ctx, t, &proxyConn{Conn: conn, laddr: laddr, raddr: raddr}, p)
+}
+
+func (t *proxyTransport) String() string {
+	return "TCP over SOCKS5"
+}
+
+// proxyConn is a connection established through a proxy, reporting the address
+// of the peer instead of the proxy as its remote end.
+type proxyConn struct {
+	net.Conn
+	laddr ma.Multiaddr
+	raddr ma.Multiaddr
+}
+
+func (c *proxyConn) LocalMultiaddr() ma.Multiaddr  { return c.laddr }
+func (c *proxyConn) RemoteMultiaddr() ma.Multiaddr { return c.raddr }
+
+// socks5Dialer opens TCP connections through a SOCKS5 proxy (RFC 1928), with
+// optional username/password authentication (RFC 1929).
+type socks5Dialer struct {
+	addr     string
+	user     string
+	password string
+}
+
+// DialContext connects to the given host:port address through the proxy.
+func (d *socks5Dialer) DialContext(ctx context.Context, addr string) (net.Conn, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", d.addr)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+	if err := d.connect(conn, addr); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s: %v", d.addr, err)
+	}
+	return conn, nil
+}
+
+// connect runs the SOCKS5 handshake on a connection to the proxy, asking it to
+// connect to the given address.
+func (d *socks5Dialer) connect(conn net.Conn, addr string) error {
+	host, portstr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.ParseUint(portstr, 10, 16)
+	if err != nil {
+		return fmt.Errorf("invalid port %q", portstr)
+	}
+	// Negotiate the authentication method
+	methods := []byte{socks5NoAuth}
+	if d.user != "" {
+		methods = append(methods, socks5PasswordAuth)
+	}
+	if _, err := conn.Write(append([]byte{socks5Version, byte(len(methods))}, methods...)); err != nil {
+		return err
+	}
+	reply := make([]byte, 2)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+	if reply[0] != socks5Version {
+		return fmt.Errorf("unexpected protocol version %d", reply[0])
+	}
+	switch reply[1] {
+	case socks5NoAuth:
+	case socks5PasswordAuth:
+		if d.user == "" {
+			return errors.New("authentication required")
+		}
+		if len(d.user) > 255 || len(d.password) > 255 {
+			return errors.New("credentials too long")
+		}
+		req := []byte{0x01, byte(len(d.user))}
+		req = append(req, d.user...)
+		req = append(req, byte(len(d.password)))
+		req = append(req, d.password...)
+		if _, err := conn.Write(req); err != nil {
+			return err
+		}
+		if _, err := io.ReadFull(conn, reply); err != nil {
+			return err
+		}
+		if reply[1] != 0x00 {
+			return errors.New("authentication failed")
+		}
+	case socks5NoAcceptable:
+		return errors.New("no acceptable authentication method")
+	default:
+		return fmt.Errorf("unsupported authentication method %d", reply[1])
+	}
+	// Request the connection to the target
+	req := []byte{socks5Version, socks5Connect, 0x00}
+	if ip := net.ParseIP(host); ip == nil {
+		if len(host) > 255 {
+			return fmt.Errorf("host name %q too long", host)
+		}
+		req = append(req, socks5Domain, byte(len(host)))
+		req = append(req, host...)
+	} else if ip4 := ip.To4(); ip4 != nil {
+		req = append(req, socks5IPv4)
+		req = append(req, ip4...)
+	} else {
+		req = append(req, socks5IPv6)
+		req = append(req, ip.To16()...)
+	}
+	req = append(req, byte(port>>8), byte(port))
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return err
+	}
+	if header[0] != socks5Version {
+		return fmt.Errorf("unexpected protocol version %d", header[0])
+	}
+	if code := int(header[1]); code != 0 {
+		if code < len(socks5Errors) {
+			return fmt.Errorf("connect to %s failed: %s", addr, socks5Errors[code])
+		}
+		return fmt.Errorf("connect to %s failed: error %d", addr, code)
+	}
+	// Skip the address the proxy bound for the connection
+	var skip int
+	switch header[3] {
+	case socks5IPv4:
+		skip = net.IPv4len
+	case socks5IPv6:
+		skip = net.IPv6len
+	case socks5Domain:
+		if _, err := io.ReadFull(conn, header[:1]); err != nil {
+			return err
+		}
+		skip = int(header[0])
+	default:
+		return fmt.Errorf("unexpected address type %d", header[3])
+	}
+	_, err = io.ReadFull(conn, make([]byte, skip+2))
+	return err
+}
@@ -22,7 +22,9 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"path/filepath"
 	"sync"
+	"time"
 
 	libp2p "github.com/libp2p/go-libp2p"
 	p2phost "github.com/libp2p/go-libp2p-host"
@@ -72,13 +74,36 @@ type Config struct {
 	Protocols    []Protocol   `toml:"-"`
 	ListenAddr   string
 	NAT          libp2p.Option `toml:"-"`
+	Proxy        libp2p.Option `toml:"-"` // Dials outbound connections through a proxy if set
 	// Dialer NodeDialer `toml:"-"`
 	// NoDial bool `toml:",omitempty"`
 
+	// MaxMessageSize is the maximum size of an inbound message body. Peers
+	// announcing a larger message are disconnected. Zero means
+	// DefaultMaxMessageSize.
+	MaxMessageSize uint32 `toml:",omitempty"`
+
+	// MaxDownload and MaxUpload throttle the bandwidth of peer connections,
+	// either in total or for each peer separately.
+	MaxDownload BandwidthLimit `toml:",omitempty"`
+	MaxUpload   BandwidthLimit `toml:",omitempty"`
+
+	// NoCompression disables the snappy compression of large messages, which is
+	// otherwise negotiated with the peers supporting it.
+	NoCompression bool `toml:",omitempty"`
+
 	EnableMsgEvents bool
 	Logger          log.Logger `toml:",omitempty"`
 }
 
+// maxMessageSize returns the effective inbound message size limit.
+func (c *Config) maxMessageSize() uint32 {
+	if c.MaxMessageSize == 0 {
+		return DefaultMaxMessageSize
+	}
+	return c.MaxMessageSize
+}
+
 type Server struct {
 	Config
 	table   DhtTable
@@ -91,9 +116,13 @@ type Server struct {
 
 	lock sync.Mutex
 
-	quit         chan struct{}
-	addstatic    chan *Node
-	removestatic chan *Node
+	quit          chan struct{}
+	addstatic     chan *Node
+	removestatic  chan *Node
+	addtrusted    chan *Node
+	removetrusted chan *Node
+
+	trusted map[peer.ID]bool // Peers allowed above the peer limit, owned by the run loop
 
 	addpeer chan *Stream
 	delpeer chan peerDrop
@@ -102,6 +131,13 @@ type Server struct {
 	peerOpDone chan struct{}
 
 	protomap map[string][]Protocol
+
+	downloadLimiter *rateLimiter // Limiter shared by all peer reads, nil if unthrottled
+	uploadLimiter   *rateLimiter // Limiter shared by all peer writes, nil if unthrottled
+
+	traffic trafficCounter // Bytes exchanged with all peers since the server started
+
+	nodedb *nodeDB // History of the connected nodes, seeding the dials after restarts
 }
 
 type peerOpFunc func(map[peer.ID]*Peer)
@@ -136,6 +172,8 @@ func (server *Server) Start() error {
 	server.delpeer = make(chan peerDrop)
 	server.addstatic = make(chan *Node)
 	server.removestatic = make(chan *Node)
+	server.addtrusted = make(chan *Node)
+	server.removetrusted = make(chan *Node)
 	server.quit = make(chan struct{})
 	server.peerOp = make(chan peerOpFunc)
 	server.peerOpDone = make(chan struct{})
@@ -145,19 +183,25 @@ func (server *Server) Start() error {
 
 	server.protomap[PID] = server.Protocols
 
+	server.downloadLimiter = server.MaxDownload.global()
+	server.uploadLimiter = server.MaxUpload.global()
+
 	// Listen
 	// run
 	if server.ListenAddr == "" {
 		return fmt.Errorf("P2P Server can't start for no listening")
 	}
 
-	listenPort := server.Config.ListenAddr[1:]
-	log.Info("startVNTNode()", "listenPort", listenPort)
+	listenAddrs, err := ListenAddrs(server.Config.ListenAddr)
+	if err != nil {
+		return fmt.Errorf("invalid listen address %q: %v", server.Config.ListenAddr, err)
+	}
+	log.Info("startVNTNode()", "listenAddrs", listenAddrs)
 	ctx, cancel := context.WithCancel(context.Background())
 	server.cancel = cancel
 
 	d := server.NodeDatabase
-	vdht, host, err := ConstructDHT(ctx, MakePort(listenPort), nil, d, server.Config.NetRestrict, server.Config.NAT)
+	vdht, host, err := ConstructDHT(ctx, listenAddrs, nil, d, server.Config.NetRestrict, server.Config.NAT, server.Config.Proxy)
 	if err != nil {
 		log.Error("startVNTNode()", "constructDHT error", err)
 		return err
@@ -166,6 +210,9 @@ func (server *Server) Start() error {
 	// setStreamHandler can only handle request message
 	// it can not hear response
 	host.SetStreamHandler(PID, server.HandleStream)
+	if !server.NoCompression {
+		host.SetStreamHandler(PIDSnappy, server.HandleStream)
+	}
 
 	log.Info("startVNTNode()", "own nodeID", host.ID())
 	server.table = NewDHTTable(vdht, host.ID())
@@ -173,9 +220,30 @@ func (server *Server) Start() error {
 
 	bootnodes := server.LoadConfig(ctx)
 
+	// Open the node history and seed the dials with the recently seen nodes
+	nodedbPath := ""
+	if d != "" {
+		nodedbPath = filepath.Join(d, nodeDBDir)
+	}
+	if server.nodedb, err = newNodeDB(nodedbPath); err != nil {
+		log.Error("Failed to open node database", "path", nodedbPath, "err", err)
+		return err
+	}
+	seeds := server.loadSeeds(ctx)
+
 	maxdails := server.maxDialedConns()
 
 	taskState := newTaskState(maxdails, bootnodes, server.table)
+	taskState.seeds = seeds
+	for _, node := range server.StaticNodes {
+		server.host.Peerstore().AddAddrs(node.Id, []ma.Multiaddr{node.Addr}, peerstore.PermanentAddrTTL)
+		server.table.Update(ctx, node.Id)
+		taskState.addStatic(node)
+	}
+	server.trusted = make(map[peer.ID]bool)
+	for _, node := range server.TrustedNodes {
+		server.trusted[node.Id] = true
+	}
 
 	server.loopWG.Add(1)
 	go server.run(ctx, taskState)
@@ -200,6 +268,24 @@ func (server *Server) LoadConfig(ctx context.Context) []peer.ID {
 
 }
 
+// loadSeeds adds the recently seen nodes of the node database to the peer store
+// and the routing table, returning them for dialing.
+func (server *Server) loadSeeds(ctx context.Context) []peer.ID {
+	var seeds []peer.ID
+	for _, record := range server.nodedb.seeds(nodeDBSeedCount, nodeDBSeedMaxAge, time.Now()) {
+		if record.ID == server.host.ID() {
+			continue
+		}
+		server.host.Peerstore().AddAddrs(record.ID, record.multiaddrs(), peerstore.AddressTTL)
+		server.table.Update(ctx, record.ID)
+		seeds = append(seeds, record.ID)
+	}
+	if len(seeds) > 0 {
+		log.Info("Loaded nodes from the node database", "count", len(seeds))
+	}
+	return seeds
+}
+
 func (server *Server) run(ctx context.Context, tasker taskworker) {
 	defer server.loopWG.Done()
 	server.table.Start(ctx)
@@ -249,13 +335,24 @@ func (server *Server) run(ctx context.Context, tasker taskworker) {
 			if _, ok := peers[remoteID]; ok { // this peer already exists
 				break
 			}
+			if server.MaxPeers > 0 && len(peers) >= server.MaxPeers && !server.trusted[remoteID] {
+				log.Debug("Rejecting peer above the peer limit", "peer", remoteID, "limit", server.MaxPeers)
+				t.Conn.Reset()
+				break
+			}
 			p := newPeer(t)
+			p.serverTraffic = &server.traffic
+			server.throttle(p)
 
 			if server.EnableMsgEvents {
 				p.events = &server.peerFeed
 			}
 			go server.runPeer(p)
 			peers[p.RemoteID()] = p
+
+			if err := server.nodedb.nodeSeen(remoteID, server.host.Peerstore().Addrs(remoteID), time.Now()); err != nil {
+				log.Debug("Failed to record node", "peer", remoteID, "err", err)
+			}
 			log.Info("p2p-test", "peers", peers)
 
 		case t := <-server.addstatic:
@@ -266,6 +363,12 @@ func (server *Server) run(ctx context.Context, tasker taskworker) {
 			if p, ok := peers[t.Id]; ok {
 				p.Disconnect(DiscRequested)
 			}
+		case t := <-server.addtrusted:
+			log.Debug("Adding trusted peer", "peer", t.Id)
+			server.trusted[t.Id] = true
+		case t := <-server.removetrusted:
+			log.Debug("Removing trusted peer", "peer", t.Id)
+			delete(server.trusted, t.Id)
 
 		case op := <-server.peerOp:
 			// This channel is used by Peers and PeerCount.
@@ -281,9 +384,24 @@ func (server *Server) run(ctx context.Context, tasker taskworker) {
 	}
 }
 
+// throttle sets up the bandwidth limits of a newly added peer.
+func (server *Server) throttle(p *Peer) {
+	p.downloadLimiter = server.MaxDownload.peer()
+	p.uploadLimiter = server.MaxUpload.peer()
+	if server.uploadLimiter == nil && p.uploadLimiter == nil {
+		return
+	}
+	for _, messenger := range p.messenger {
+		messenger.w = newLimitedWriter(messenger.w, server.uploadLimiter, p.uploadLimiter)
+	}
+}
+
 func (server *Server) Stop() {
 	log.Info("Server is Stopping!")
 	defer server.cancel()
+	if server.nodedb != nil {
+		server.nodedb.close()
+	}
 	return
 }
 
@@ -305,17 +423,40 @@ func (server *Server) RemovePeer(node *Node) {
 	}
 }
 
+// AddTrustedPeer adds the given node to a reserved set of nodes which are always
+// allowed to connect, even above the peer limit.
+func (server *Server) AddTrustedPeer(node *Node) {
+	select {
+	case server.addtrusted <- node:
+	case <-server.quit:
+	}
+}
+
+// RemoveTrustedPeer removes the given node from the trusted peer set. It doesn't
+// disconnect the peer.
+func (server *Server) RemoveTrustedPeer(node *Node) {
+	select {
+	case server.removetrusted <- node:
+	case <-server.quit:
+	}
+}
+
 func (server *Server) SubscribeEvents(ch chan *PeerEvent) event.Subscription {
 	return server.peerFeed.Subscribe(ch)
 }
 
 func (server *Server) PeersInfo() []*PeerInfo {
-	infos := make([]*PeerInfo, 0, server.PeerCount())
-
-	for _, peer := range server.Peers() {
-		if peer != nil {
-			infos = append(infos, peer.Info())
+	var infos []*PeerInfo
+	select {
+	case server.peerOp <- func(peers map[peer.ID]*Peer) {
+		for id, p := range peers {
+			info := p.Info()
+			info.Network.Trusted = server.trusted[id]
+			infos = append(infos, info)
 		}
+	}:
+		<-server.peerOpDone
+	case <-server.quit:
 	}
 	for i := 0; i < len(infos); i++ {
 		for j := i + 1; j < len(infos); j++ {
@@ -405,9 +546,29 @@ func (server *Server) maxDialedConns() int {
 	return server.MaxPeers / r
 }
 
+// dropUnreachableAddrs forgets the addresses of a node in the IP families this
+// node has no routable address in, so that dials go over the reachable family
+// instead of waiting for the others to fail. Nothing is dropped if no reachable
+// address would be left.
+func (server *Server) dropUnreachableAddrs(target peer.ID) {
+	ps := server.host.Peerstore()
+	reachable, unreachable := splitReachable(reachableFamilies(server.host.Addrs()), ps.Addrs(target))
+	if len(reachable) > 0 && len(unreachable) > 0 {
+		ps.SetAddrs(target, unreachable, 0)
+	}
+}
+
 func (server *Server) SetupStream(ctx context.Context, target peer.ID, pid string) error {
 	// log.Info("p2p-test", "SetupStream target", target, "pid", pid)
-	s, err := server.host.NewStream(ctx, target, protocol.ID(pid))
+	pids := []protocol.ID{protocol.ID(pid)}
+	if pid == PID && !server.NoCompression {
+		// Prefer compressed streams, falling back to plain ones for old peers
+		pids = append([]protocol.ID{PIDSnappy}, pids...)
+	}
+	if server.host.Network().Connectedness(target) != inet.Connected {
+		server.dropUnreachableAddrs(target)
+	}
+	s, err := server.host.NewStream(ctx, target, pids...)
 	if err != nil {
 		// fmt.Println("SetupStream NewStream Error: ", err)
 		return err
@@ -478,6 +639,7 @@ type NodeInfo struct {
 	} `json:"ports"`
 	ListenAddr string                 `json:"listenAddr"`
 	Protocols  map[string]interface{} `json:"protocols"`
+	Traffic    Traffic                `json:"traffic"` // Bytes exchanged with all peers since startup
 }
 
 func (server *Server) NodeInfo() *NodeInfo {
@@ -490,6 +652,7 @@ func (server *Server) NodeInfo() *NodeInfo {
 		IP:         GetIPfromAddr(node.Addr),
 		ListenAddr: server.ListenAddr,
 		Protocols:  make(map[string]interface{}),
+		Traffic:    server.traffic.traffic(),
 	}
 
 	// for _, proto := range server.Protocols {
@@ -19,6 +19,7 @@ package whisperv6
 import (
 	"context"
 	"crypto/ecdsa"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"sync"
@@ -40,6 +41,8 @@ var (
 	ErrInvalidSigningPubKey = errors.New("invalid signing public key")
 	ErrTooLowPoW            = errors.New("message rejected, PoW too low")
 	ErrNoTopics             = errors.New("missing topic(s)")
+	ErrNoSignature          = errors.New("missing signing key")
+	ErrInvalidTimeRange     = errors.New("invalid time range")
 )
 
 // PublicWhisperAPI provides the whisper RPC service that can be
@@ -197,14 +200,14 @@ func (api *PublicWhisperAPI) DeleteSymKey(ctx context.Context, id string) bool {
 // MakeLightClient turns the node into light client, which does not forward
 // any incoming messages, and sends only messages originated in this node.
 func (api *PublicWhisperAPI) MakeLightClient(ctx context.Context) bool {
-	api.w.lightClient = true
-	return api.w.lightClient
+	api.w.SetLightClientMode(true)
+	return api.w.LightClientMode()
 }
 
 // CancelLightClient cancels light client mode.
 func (api *PublicWhisperAPI) CancelLightClient(ctx context.Context) bool {
-	api.w.lightClient = false
-	return !api.w.lightClient
+	api.w.SetLightClientMode(false)
+	return !api.w.LightClientMode()
 }
 
 //go:generate gencodec -type NewMessage -field-override newMessageOverride -out gen_newmessage_json.go
@@ -320,6 +323,80 @@ func (api *PublicWhisperAPI) Post(ctx context.Context, req NewMessage) (hexutil.
 	return result, err
 }
 
+// HistoricMessagesRequest asks a mail server for the archived envelopes sent
+// within a time range, optionally restricted to a set of topics.
+type HistoricMessagesRequest struct {
+	MailServerPeer string      `json:"mailServerPeer"` // Mail server to ask, which must be a connected peer
+	SymKeyID       string      `json:"symKeyID"`       // Key derived from the password of the mail server
+	Sig            string      `json:"sig"`            // Key pair signing the request
+	From           uint32      `json:"from"`           // Lower bound of the sending time (inclusive)
+	To             uint32      `json:"to"`             // Upper bound of the sending time (exclusive, 0 = now)
+	Topics         []TopicType `json:"topics"`         // Topics of the envelopes (empty = all)
+	PowTime        uint32      `json:"powTime"`
+	PowTarget      float64     `json:"powTarget"`
+}
+
+// RequestHistoricMessages requests the archived envelopes from a mail server.
+// The mail server delivers them as peer-to-peer messages, which are passed to
+// the filters allowing them, so the mail server is marked trusted.
+func (api *PublicWhisperAPI) RequestHistoricMessages(ctx context.Context, req HistoricMessagesRequest) (bool, error) {
+	n, err := vntp2p.ParseNode(req.MailServerPeer)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse mail server peer: %s", err)
+	}
+	if req.To == 0 {
+		req.To = uint32(time.Now().Unix())
+	}
+	if req.From >= req.To {
+		return false, ErrInvalidTimeRange
+	}
+	if len(req.Sig) == 0 {
+		return false, ErrNoSignature
+	}
+
+	params := &MessageParams{
+		Payload:  historicRequestPayload(req.From, req.To, req.Topics),
+		WorkTime: req.PowTime,
+		PoW:      req.PowTarget,
+	}
+	if params.Src, err = api.w.GetPrivateKey(req.Sig); err != nil {
+		return false, err
+	}
+	if params.KeySym, err = api.w.GetSymKey(req.SymKeyID); err != nil {
+		return false, err
+	}
+	if !validateDataIntegrity(params.KeySym, aesKeyLength) {
+		return false, ErrInvalidSymmetricKey
+	}
+
+	msg, err := NewSentMessage(params)
+	if err != nil {
+		return false, err
+	}
+	env, err := msg.Wrap(params)
+	if err != nil {
+		return false, err
+	}
+	return true, api.w.RequestHistoricMessages(n.Id, env)
+}
+
+// historicRequestPayload encodes the time range and the bloom filter of the
+// topics the way mail servers expect. Without topics the bloom filter is left
+// out, requesting the envelopes of all topics.
+func historicRequestPayload(from, to uint32, topics []TopicType) []byte {
+	payload := make([]byte, 8, 8+BloomFilterSize)
+	binary.BigEndian.PutUint32(payload, from)
+	binary.BigEndian.PutUint32(payload[4:], to)
+	if len(topics) == 0 {
+		return payload
+	}
+	bloom := make([]byte, BloomFilterSize)
+	for _, topic := range topics {
+		bloom = addBloom(bloom, TopicToBloom(topic))
+	}
+	return append(payload, bloom...)
+}
+
 //go:generate gencodec -type Criteria -field-override criteriaOverride -out gen_criteria_json.go
 
 // Criteria holds various filter options for inbound messages.
@@ -19,6 +19,7 @@ package whisperv6
 import (
 	"bytes"
 	"crypto/ecdsa"
+	"encoding/binary"
 	"testing"
 	"time"
 
@@ -76,3 +77,30 @@ func TestMultipleTopicCopyInNewMessageFilter(t *testing.T) {
 		t.Fatalf("Could not find filter with both topics")
 	}
 }
+
+func TestHistoricRequestPayload(t *testing.T) {
+	t1 := TopicType{0xde, 0xea, 0xbe, 0xef}
+	t2 := TopicType{0xca, 0xfe, 0xde, 0xca}
+
+	payload := historicRequestPayload(100, 200, nil)
+	if len(payload) != 8 {
+		t.Fatalf("payload length mismatch: have %d, want %d", len(payload), 8)
+	}
+	if from, to := binary.BigEndian.Uint32(payload), binary.BigEndian.Uint32(payload[4:]); from != 100 || to != 200 {
+		t.Fatalf("time range mismatch: have [%d, %d), want [%d, %d)", from, to, 100, 200)
+	}
+
+	payload = historicRequestPayload(100, 200, []TopicType{t1, t2})
+	if len(payload) != 8+BloomFilterSize {
+		t.Fatalf("payload length mismatch: have %d, want %d", len(payload), 8+BloomFilterSize)
+	}
+	bloom := payload[8:]
+	for _, topic := range []TopicType{t1, t2} {
+		if !BloomFilterMatch(bloom, TopicToBloom(topic)) {
+			t.Errorf("topic %x not matched by request bloom", topic)
+		}
+	}
+	if bytes.Equal(bloom, MakeFullNodeBloom()) {
+		t.Errorf("request bloom matches all topics")
+	}
+}
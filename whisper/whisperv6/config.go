@@ -16,10 +16,24 @@
 
 package whisperv6
 
+import "github.com/vntchain/go-vnt/common/hexutil"
+
 // Config represents the configuration state of a whisper node.
 type Config struct {
-	MaxMessageSize     uint32  `toml:",omitempty"`
-	MinimumAcceptedPOW float64 `toml:",omitempty"`
+	MaxMessageSize     uint32        `toml:",omitempty"`
+	MinimumAcceptedPOW float64       `toml:",omitempty"`
+	BloomFilter        hexutil.Bytes `toml:",omitempty"` // Envelopes to receive (empty = all)
+
+	// Mail server archiving envelopes for peers which were offline, it is set
+	// up by the node registering the whisper service.
+	MailServer         bool    `toml:",omitempty"` // Whether to run a mail server
+	MailServerPassword string  `toml:",omitempty"` // Password of the symmetric key encrypting requests
+	MailServerPoW      float64 `toml:",omitempty"` // Minimum PoW of the history requests
+
+	// Light client mode, in which the node only sends and receives its own
+	// envelopes without relaying the traffic of others, for constrained nodes.
+	LightClient          bool `toml:",omitempty"` // Whether to run as a light client
+	RestrictLightClients bool `toml:",omitempty"` // Whether to refuse connections between light clients
 }
 
 // DefaultConfig represents (shocker!) the default configuration.
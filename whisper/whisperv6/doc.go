@@ -90,8 +90,10 @@ func (e unknownVersionError) Error() string {
 // to the peers. Any implementation must ensure that both
 // functions are thread-safe. Also, they must return ASAP.
 // DeliverMail should use directMessagesCode for delivery,
-// in order to bypass the expiry checks.
+// in order to bypass the expiry checks. Close is called
+// when the whisper service stops.
 type MailServer interface {
 	Archive(env *Envelope)
 	DeliverMail(whisperPeer *Peer, request *Envelope)
+	Close()
 }
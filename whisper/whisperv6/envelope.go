@@ -42,7 +42,8 @@ type Envelope struct {
 	Data   []byte
 	Nonce  uint64
 
-	pow float64 // Message-specific PoW as described in the Whisper specification.
+	pow   float64 // Message-specific PoW as described in the Whisper specification.
+	local bool    // Whether the envelope was posted by this node, rather than received from a peer.
 
 	// the following variables should not be accessed directly, use the corresponding function instead: Hash(), Bloom()
 	hash  common.Hash // Cached hash of the envelope to avoid rehashing every time.
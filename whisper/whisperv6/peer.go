@@ -84,7 +84,8 @@ func (peer *Peer) handshake() error {
 		pow := peer.host.MinPow()
 		powConverted := math.Float64bits(pow)
 		bloom := peer.host.BloomFilter()
-		errc <- vntp2p.SendItems(peer.ws, ProtocolName, statusCode, ProtocolVersion, powConverted, bloom)
+		light := peer.host.LightClientMode()
+		errc <- vntp2p.SendItems(peer.ws, ProtocolName, statusCode, ProtocolVersion, powConverted, bloom, light)
 	}()
 
 	// Fetch the remote status packet and verify protocol match
@@ -125,6 +126,11 @@ func (peer *Peer) handshake() error {
 				return fmt.Errorf("peer [%x] sent bad status message: wrong bloom filter size %d", peer.ID(), sz)
 			}
 			peer.setBloomFilter(bloom)
+
+			light, err := s.Bool()
+			if err == nil && light && peer.host.LightClientMode() && peer.host.RestrictLightClients() {
+				return fmt.Errorf("peer [%x] is useless: connections between light clients are restricted", peer.ID())
+			}
 		}
 	}
 
@@ -189,8 +195,12 @@ func (peer *Peer) expire() {
 // ones over the network.
 func (peer *Peer) broadcast() error {
 	envelopes := peer.host.Envelopes()
+	light := peer.host.LightClientMode()
 	bundle := make([]*Envelope, 0, len(envelopes))
 	for _, envelope := range envelopes {
+		if light && !envelope.local {
+			continue // Light clients don't relay foreign envelopes
+		}
 		if !peer.marked(envelope) && envelope.PoW() >= peer.powRequirement && peer.bloomMatch(envelope) {
 			bundle = append(bundle, envelope)
 		}
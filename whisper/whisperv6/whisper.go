@@ -55,6 +55,8 @@ const (
 	minPowToleranceIdx             // Minimal PoW tolerated by the whisper node for a limited time
 	bloomFilterIdx                 // Bloom filter for topics of interest for this node
 	bloomFilterToleranceIdx        // Bloom filter tolerated by the whisper node for a limited time
+	lightClientModeIdx             // Light client mode, in which foreign envelopes are not relayed
+	restrictLightClientsIdx        // Whether light clients refuse to connect to each other
 )
 
 // Whisper represents a dark communication interface through the VNT
@@ -82,8 +84,6 @@ type Whisper struct {
 
 	syncAllowance int // maximum time in seconds allowed to process the whisper-related messages
 
-	lightClient bool // indicates is this node is pure light client (does not forward any messages)
-
 	statsMu sync.Mutex // guard stats
 	stats   Statistics // Statistics of whisper node
 
@@ -113,6 +113,19 @@ func New(cfg *Config) *Whisper {
 	whisper.settings.Store(minPowIdx, cfg.MinimumAcceptedPOW)
 	whisper.settings.Store(maxMsgSizeIdx, cfg.MaxMessageSize)
 	whisper.settings.Store(overflowIdx, false)
+	whisper.settings.Store(lightClientModeIdx, cfg.LightClient)
+	whisper.settings.Store(restrictLightClientsIdx, cfg.RestrictLightClients)
+
+	if len(cfg.BloomFilter) > 0 {
+		if len(cfg.BloomFilter) != BloomFilterSize {
+			log.Error("Invalid whisper bloom filter size, receiving all envelopes", "size", len(cfg.BloomFilter))
+		} else {
+			bloom := make([]byte, BloomFilterSize)
+			copy(bloom, cfg.BloomFilter)
+			whisper.settings.Store(bloomFilterIdx, bloom)
+			whisper.settings.Store(bloomFilterToleranceIdx, bloom)
+		}
+	}
 
 	// p2p whisper sub protocol handler
 	whisper.protocol = vntp2p.Protocol{
@@ -157,6 +170,31 @@ func (whisper *Whisper) MinPowTolerance() float64 {
 	return val.(float64)
 }
 
+// LightClientMode returns whether the node is a light client, which only sends
+// and receives its own envelopes without relaying those of its peers.
+func (whisper *Whisper) LightClientMode() bool {
+	val, exist := whisper.settings.Load(lightClientModeIdx)
+	if !exist || val == nil {
+		return false
+	}
+	return val.(bool)
+}
+
+// SetLightClientMode switches the light client mode of the node on or off.
+func (whisper *Whisper) SetLightClientMode(v bool) {
+	whisper.settings.Store(lightClientModeIdx, v)
+}
+
+// RestrictLightClients returns whether a light client node refuses to connect
+// to other light clients, which can't relay any envelopes between each other.
+func (whisper *Whisper) RestrictLightClients() bool {
+	val, exist := whisper.settings.Load(restrictLightClientsIdx)
+	if !exist || val == nil {
+		return false
+	}
+	return val.(bool)
+}
+
 // BloomFilter returns the aggregated bloom filter for all the topics of interest.
 // The nodes are required to send only messages that match the advertised bloom filter.
 // If a message does not match the bloom, it will tantamount to spam, and the peer will
@@ -590,6 +628,7 @@ func (whisper *Whisper) Unsubscribe(id string) error {
 // Send injects a message into the whisper send queue, to be distributed in the
 // network in the coming cycles.
 func (whisper *Whisper) Send(envelope *Envelope) error {
+	envelope.local = true
 	ok, err := whisper.add(envelope, false)
 	if err == nil && !ok {
 		return fmt.Errorf("failed to add envelope")
@@ -615,6 +654,9 @@ func (whisper *Whisper) Start(*vntp2p.Server) error {
 // of the Whisper protocol.
 func (whisper *Whisper) Stop() error {
 	close(whisper.quit)
+	if whisper.mailServer != nil {
+		whisper.mailServer.Close()
+	}
 	log.Info("whisper stopped")
 	return nil
 }
@@ -673,7 +715,7 @@ func (whisper *Whisper) runMessageLoop(p *Peer, rw vntp2p.MsgReadWriter) error {
 
 			trouble := false
 			for _, env := range envelopes {
-				cached, err := whisper.add(env, whisper.lightClient)
+				cached, err := whisper.add(env, whisper.LightClientMode())
 				if err != nil {
 					trouble = true
 					log.Error("bad envelope received, peer will be disconnected", "peer", p.peer.RemoteID(), "err", err)
@@ -767,6 +809,17 @@ func (whisper *Whisper) add(envelope *Envelope, isP2P bool) (bool, error) {
 		return false, nil // drop envelope without error
 	}
 
+	// Check the bloom filter first, dropping unwanted envelopes before any PoW work
+	if !BloomFilterMatch(whisper.BloomFilter(), envelope.Bloom()) {
+		// maybe the value was recently changed, and the peers did not adjust yet.
+		// in this case the previous value is retrieved by BloomFilterTolerance()
+		// for a short period of peer synchronization.
+		if !BloomFilterMatch(whisper.BloomFilterTolerance(), envelope.Bloom()) {
+			return false, fmt.Errorf("envelope does not match bloom filter, hash=[%v], bloom: \n%x \n%x \n%x",
+				envelope.Hash().Hex(), whisper.BloomFilter(), envelope.Bloom(), envelope.Topic)
+		}
+	}
+
 	if uint32(envelope.size()) > whisper.MaxMessageSize() {
 		return false, fmt.Errorf("huge messages are not allowed [%x]", envelope.Hash())
 	}
@@ -780,16 +833,6 @@ func (whisper *Whisper) add(envelope *Envelope, isP2P bool) (bool, error) {
 		}
 	}
 
-	if !BloomFilterMatch(whisper.BloomFilter(), envelope.Bloom()) {
-		// maybe the value was recently changed, and the peers did not adjust yet.
-		// in this case the previous value is retrieved by BloomFilterTolerance()
-		// for a short period of peer synchronization.
-		if !BloomFilterMatch(whisper.BloomFilterTolerance(), envelope.Bloom()) {
-			return false, fmt.Errorf("envelope does not match bloom filter, hash=[%v], bloom: \n%x \n%x \n%x",
-				envelope.Hash().Hex(), whisper.BloomFilter(), envelope.Bloom(), envelope.Topic)
-		}
-	}
-
 	hash := envelope.Hash()
 
 	whisper.poolMu.Lock()
@@ -18,6 +18,7 @@ package whisperv6
 
 import (
 	"bytes"
+	"context"
 	"crypto/ecdsa"
 	"crypto/sha256"
 	mrand "math/rand"
@@ -884,3 +885,71 @@ func TestBloom(t *testing.T) {
 		t.Fatalf("retireved wrong bloom filter")
 	}
 }
+
+// Tests that a configured bloom filter only lets matching envelopes through.
+func TestConfiguredBloomFilter(t *testing.T) {
+	InitSingleTest()
+
+	wanted, unwanted := TopicType{0x01, 0x02, 0x03, 0x04}, TopicType{0xfe, 0xdc, 0xba, 0x98}
+	if BloomFilterMatch(TopicToBloom(wanted), TopicToBloom(unwanted)) {
+		t.Fatalf("test topics have colliding blooms")
+	}
+	cfg := DefaultConfig
+	cfg.BloomFilter = TopicToBloom(wanted)
+
+	w := New(&cfg)
+	w.Start(nil)
+	defer w.Stop()
+
+	if !bytes.Equal(w.BloomFilter(), cfg.BloomFilter) {
+		t.Fatalf("advertised bloom mismatch: have %x, want %x", w.BloomFilter(), cfg.BloomFilter)
+	}
+	send := func(topic TopicType) error {
+		params, err := generateMessageParams()
+		if err != nil {
+			t.Fatalf("failed generateMessageParams with seed %d: %s.", seed, err)
+		}
+		params.Topic = topic
+		params.PoW = DefaultMinimumPoW * 2
+		params.WorkTime = 10
+		params.TTL = 60
+		msg, err := NewSentMessage(params)
+		if err != nil {
+			t.Fatalf("failed to create new message with seed %d: %s.", seed, err)
+		}
+		env, err := msg.Wrap(params)
+		if err != nil {
+			t.Fatalf("failed Wrap with seed %d: %s.", seed, err)
+		}
+		return w.Send(env)
+	}
+	if err := send(wanted); err != nil {
+		t.Fatalf("matching envelope dropped: %v", err)
+	}
+	if err := send(unwanted); err == nil {
+		t.Fatalf("non-matching envelope accepted")
+	}
+}
+
+// Tests that the light client settings are taken from the config and that the
+// mode can be switched at runtime.
+func TestLightClientConfig(t *testing.T) {
+	cfg := DefaultConfig
+	cfg.LightClient = true
+	cfg.RestrictLightClients = true
+
+	w := New(&cfg)
+	if !w.LightClientMode() {
+		t.Fatalf("light client mode not enabled")
+	}
+	if !w.RestrictLightClients() {
+		t.Fatalf("light client restriction not enabled")
+	}
+	api := NewPublicWhisperAPI(w)
+	if !api.CancelLightClient(context.Background()) || w.LightClientMode() {
+		t.Fatalf("light client mode not disabled")
+	}
+	if New(&DefaultConfig).LightClientMode() {
+		t.Fatalf("light client mode enabled by default")
+	}
+}